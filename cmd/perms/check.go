@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+)
+
+// checkOptions holds the check subcommand's flags
+type checkOptions struct {
+	project string
+	format  string
+}
+
+// checkFlags defines the check subcommand's flags
+func checkFlags(o *checkOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	fs.StringVar(&o.project, "project", "", "project whose settings apply (default the working directory)")
+	fs.StringVar(&o.format, "format", "text", "output format: text or json")
+	return fs
+}
+
+// checkMatch is a matching rule in check --format json output
+type checkMatch struct {
+	Rule    string `json:"rule"`
+	List    string `json:"list"`
+	Layer   string `json:"layer"`
+	File    string `json:"file"`
+	Command string `json:"command,omitempty"`
+}
+
+// checkResult is check --format json output
+type checkResult struct {
+	Invocation string       `json:"invocation"`
+	Project    string       `json:"project"`
+	Decision   string       `json:"decision"`
+	Rule       *checkMatch  `json:"rule,omitempty"`
+	Reason     string       `json:"reason,omitempty"`
+	Matches    []checkMatch `json:"matches"`
+}
+
+// runCheck resolves a tool invocation against every settings layer and
+// prints whether it would run, prompt or be refused, and which rule decided.
+// Returns the process exit code.
+func runCheck(args []string) int {
+	var opts checkOptions
+	fs := checkFlags(&opts)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, `usage: perms check [--project path] [--format text|json] "Tool(invocation)"`)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil || fs.NArg() == 0 {
+		if err == nil {
+			fs.Usage()
+		}
+		return 2
+	}
+	if opts.format != "text" && opts.format != "json" {
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q (want text or json)\n", opts.format)
+		return 2
+	}
+
+	project := opts.project
+	if project == "" {
+		project, _ = os.Getwd()
+	}
+	if abs, err := filepath.Abs(project); err == nil {
+		project = abs
+	}
+
+	// Unquoted invocations arrive as several arguments
+	res, warns := parser.ResolvePermission(strings.Join(fs.Args(), " "), project)
+	for _, w := range warns {
+		fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", w.File, w.Reason)
+	}
+
+	if opts.format == "json" {
+		out := checkResult{
+			Invocation: res.Invocation,
+			Project:    project,
+			Decision:   string(res.Decision),
+			Reason:     res.Reason,
+			Matches:    []checkMatch{},
+		}
+		if res.Decider != nil {
+			m := toCheckMatch(*res.Decider)
+			out.Rule = &m
+		}
+		for _, m := range res.Matches {
+			out.Matches = append(out.Matches, toCheckMatch(m))
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Printf("%s  %s\n", strings.ToUpper(string(res.Decision)), res.Invocation)
+	if res.Decider != nil {
+		fmt.Printf("  decided by %s\n", describeMatch(*res.Decider))
+	} else {
+		fmt.Printf("  %s\n", res.Reason)
+	}
+	if len(res.Matches) > 1 || (len(res.Matches) == 1 && res.Decider == nil) {
+		fmt.Println("  matching rules:")
+		for _, m := range res.Matches {
+			fmt.Printf("    %-5s %s\n", m.List, describeMatch(m))
+		}
+	}
+	return 0
+}
+
+// toCheckMatch converts a rule match for JSON output
+func toCheckMatch(m parser.RuleMatch) checkMatch {
+	return checkMatch{Rule: m.Rule, List: string(m.List), Layer: m.Layer.Name, File: m.Layer.Path, Command: m.Command}
+}
+
+// describeMatch renders a rule match as "rule (layer: file)"
+func describeMatch(m parser.RuleMatch) string {
+	s := fmt.Sprintf("%s (%s: %s)", m.Rule, m.Layer.Name, m.Layer.Path)
+	if m.Command != "" {
+		s += " for " + m.Command
+	}
+	return s
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"flag"
+)
+
+// command is a perms subcommand. The table drives dispatch, usage, shell
+// completion and the man page, so a new subcommand only needs an entry here.
+type command struct {
+	name    string
+	args    string // Positional argument synopsis, e.g. "bash|zsh|fish"
+	summary string // One line, shown in usage and the man page
+	hidden  bool   // Left out of usage, completion and the man page
+
+	// flags returns the subcommand's flag set for completion and the man
+	// page; nil if it takes no flags
+	flags func() *flag.FlagSet
+
+	// values completes flag values by flag name; complete completes
+	// positional arguments
+	values   map[string]completer
+	complete completer
+
+	run func(args []string) int
+}
+
+// commands returns every subcommand in the order they are listed
+func commands() []command {
+	return []command{
+		{
+			name:    "doctor",
+			summary: "check the environment and print fixes for problems",
+			run:     runDoctor,
+		},
+		{
+			name:    "history",
+			args:    "[--json] [-n count] [--permission perm] [--project path]",
+			summary: "list the changes made with perms, oldest first",
+			flags:   func() *flag.FlagSet { return historyFlags(&historyOptions{}) },
+			values: map[string]completer{
+				"permission": completePermissions,
+				"project":    completeProjects,
+			},
+			run: runHistory,
+		},
+		{
+			name:    "serve",
+			args:    "[--addr host:port] [--projects-dir dir]",
+			summary: "serve a read-only web dashboard and JSON API",
+			flags:   func() *flag.FlagSet { return serveFlags(&serveOptions{}) },
+			values: map[string]completer{
+				"projects-dir": completeDirs,
+			},
+			run: runServe,
+		},
+		{
+			name:    "query",
+			args:    "[--type tool] [--scope prefix] [--project path] [--host label] [--agent type] [--since when] [--denied-only] [--events] [--format text|json]",
+			summary: "filter and total permission usage from the command line",
+			flags:   func() *flag.FlagSet { return queryFlags(&queryOptions{}) },
+			values: map[string]completer{
+				"type":     completePermissionTypes,
+				"project":  completeProjects,
+				"host":     completeHosts,
+				"agent":    completeAgentTypes,
+				"outcome":  fixedValues("approved", "denied", "failed", "unknown"),
+				"approval": fixedValues("user", "project", "none"),
+				"format":   fixedValues("text", "json"),
+			},
+			run: runQuery,
+		},
+		{
+			name:    "check",
+			args:    "[--project path] [--format text|json] Tool(invocation)",
+			summary: "show whether a tool call would run, prompt or be denied, and which rule decides",
+			flags:   func() *flag.FlagSet { return checkFlags(&checkOptions{}) },
+			values: map[string]completer{
+				"project": completeProjects,
+				"format":  fixedValues("text", "json"),
+			},
+			run: runCheck,
+		},
+		{
+			name:    "diff",
+			args:    "[--mine file | --project path] [--format text|json] (other-settings.json | --template name)",
+			summary: "compare my settings' rules with a teammate's settings file or a template",
+			flags:   func() *flag.FlagSet { return diffFlags(&diffOptions{}) },
+			values: map[string]completer{
+				"mine":     completeFiles,
+				"project":  completeProjects,
+				"template": completeTemplates,
+				"format":   fixedValues("text", "json"),
+			},
+			complete: completeFiles,
+			run:      runDiff,
+		},
+		{
+			name:    "plugins",
+			args:    "[--diff] [--from version] [--to version] [--format text|json] [name]",
+			summary: "list installed plugin versions, or diff what two versions declare",
+			flags:   func() *flag.FlagSet { return pluginsFlags(&pluginsOptions{}) },
+			values: map[string]completer{
+				"format": fixedValues("text", "json"),
+			},
+			complete: completePlugins,
+			run:      runPlugins,
+		},
+		{
+			name:    "report",
+			args:    "--html file | --markdown [--since when] [--project path] | --sarif file [--since when]",
+			summary: "write a shareable HTML or JSON report, a Markdown summary for a pull request, or SARIF findings",
+			flags:   func() *flag.FlagSet { return reportFlags(&reportOptions{}) },
+			values: map[string]completer{
+				"html":         completeFiles,
+				"json":         completeFiles,
+				"sarif":        completeFiles,
+				"project":      completeProjects,
+				"projects-dir": completeDirs,
+			},
+			run: runReport,
+		},
+		{
+			name:    "lint",
+			args:    "[--policy file] [--project path | --all] [--format text|json]",
+			summary: "check settings against a team policy file; exits 1 on violations",
+			flags:   func() *flag.FlagSet { return lintFlags(&lintOptions{}) },
+			values: map[string]completer{
+				"policy":       completeFiles,
+				"project":      completeProjects,
+				"format":       fixedValues("text", "json"),
+				"projects-dir": completeDirs,
+			},
+			run: runLint,
+		},
+		{
+			name:    "watch",
+			args:    "[--interval 5s] [--command cmd | --quiet] [--projects-dir dir]",
+			summary: "notify when a session uses a permission your settings would prompt for",
+			flags:   func() *flag.FlagSet { return watchFlags(&watchOptions{}) },
+			values: map[string]completer{
+				"projects-dir": completeDirs,
+			},
+			run: runWatch,
+		},
+		{
+			name:     "daemon",
+			args:     "[--interval 5s] [--notify] [--projects-dir dir] | status | stop",
+			summary:  "keep the logs parsed and answer the TUI and CLI over a unix socket",
+			flags:    func() *flag.FlagSet { return daemonFlags(&daemonOptions{}) },
+			complete: fixedValues("status", "stop"),
+			values: map[string]completer{
+				"projects-dir": completeDirs,
+			},
+			run: runDaemon,
+		},
+		{
+			name:     "store",
+			args:     "sync|info|reset",
+			summary:  "update or inspect the persistent event store",
+			complete: fixedValues("sync", "info", "reset"),
+			run:      runStore,
+		},
+		{
+			name:     "completion",
+			args:     "bash|zsh|fish",
+			summary:  "print a shell completion script",
+			complete: fixedValues("bash", "zsh", "fish"),
+			run:      runCompletion,
+		},
+		{
+			name:    "man",
+			summary: "print the man page (roff)",
+			run:     runMan,
+		},
+		{
+			name:   completeCommand,
+			hidden: true,
+			run:    runComplete,
+		},
+	}
+}
+
+// findCommand looks up a subcommand by name
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// rootCommand describes the TUI itself, for completion and the man page
+func rootCommand() command {
+	return command{
+		name: "perms",
+		flags: func() *flag.FlagSet {
+			fs := flag.NewFlagSet("perms", flag.ContinueOnError)
+			rootFlags(fs, &options{})
+			return fs
+		},
+		values: map[string]completer{
+			"theme":          fixedValues("dark", "light", "high-contrast", "none"),
+			"summary-file":   completeFiles,
+			"summary-format": fixedValues("text", "json"),
+			"projects-dir":   completeDirs,
+			"from":           completeFiles,
+			"host":           completeHosts,
+			"debug":          completeFiles,
+		},
+	}
+}
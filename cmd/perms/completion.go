@@ -0,0 +1,355 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/config"
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/templates"
+)
+
+// completeCommand is the hidden subcommand the completion scripts call
+// back into: perms __complete <words up to and including the cursor>
+const completeCommand = "__complete"
+
+// Directives a completer returns instead of candidates to hand path
+// completion back to the shell
+const (
+	directiveFiles = ":files"
+	directiveDirs  = ":dirs"
+)
+
+// completer lists candidate values for a flag or argument
+type completer func() []string
+
+func completeFiles() []string { return []string{directiveFiles} }
+func completeDirs() []string  { return []string{directiveDirs} }
+
+// fixedValues completes one of a fixed set of values
+func fixedValues(values ...string) completer {
+	return func() []string { return values }
+}
+
+// completePermissions lists the permission strings observed in session
+// logs. It reads the stats cache, so it is fast once the TUI has run.
+func completePermissions() []string {
+	loadCompletionConfig()
+	stats, err := parser.LoadAllPermissionStatsWithCache(nil)
+	if err != nil {
+		return nil
+	}
+	perms := make([]string, 0, len(stats))
+	for _, s := range stats {
+		perms = append(perms, s.Permission.Raw)
+	}
+	sort.Strings(perms)
+	return perms
+}
+
+// completePermissionTypes lists the tools seen in session logs
+func completePermissionTypes() []string {
+	seen := make(map[string]bool)
+	var tools []string
+	for _, perm := range completePermissions() {
+		if t := parser.ParsePermission(perm).Type; !seen[t] {
+			seen[t] = true
+			tools = append(tools, t)
+		}
+	}
+	sort.Strings(tools)
+	return tools
+}
+
+// completeAgentTypes lists the subagent types seen in agent logs, plus
+// "all"
+func completeAgentTypes() []string {
+	loadCompletionConfig()
+	usage, _ := parser.LoadAgentUsageStats(nil)
+	agents := []string{"all"}
+	for _, u := range usage {
+		agents = append(agents, u.AgentType)
+	}
+	sort.Strings(agents)
+	return agents
+}
+
+// completePlugins lists the installed plugins
+func completePlugins() []string {
+	var plugins []string
+	for _, p := range parser.ListInstalledPlugins() {
+		plugins = append(plugins, p.Name)
+	}
+	return plugins
+}
+
+// completeHosts lists the host labels configured for synced logs
+func completeHosts() []string {
+	loadCompletionConfig()
+	hosts := parser.Hosts()
+	if len(hosts) == 0 {
+		return nil
+	}
+	return append(hosts, parser.OtherHost)
+}
+
+// completeTemplates lists the names of the built-in and user templates
+func completeTemplates() []string {
+	all, _ := templates.Load(templates.Dir())
+	names := make([]string, len(all))
+	for i, t := range all {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// completeProjects lists the project directories found in session logs
+func completeProjects() []string {
+	loadCompletionConfig()
+	settings, _ := parser.LoadDiscoveredProjectSettings()
+	projects := make([]string, 0, len(settings))
+	for p := range settings {
+		projects = append(projects, p)
+	}
+	sort.Strings(projects)
+	return projects
+}
+
+// loadCompletionConfig applies the config settings that change what the
+// dynamic completers see
+func loadCompletionConfig() {
+	cfg, _ := config.Load()
+	parser.SetProjectsDir(cfg.ProjectsDir)
+	parser.SetNormalization(!cfg.Normalize.Disabled, cfg.Normalize.Aliases)
+	parser.SetProjectAliases(cfg.Projects.Aliases, cfg.Projects.GroupByRemote)
+	parser.SetHosts(cfg.Hosts)
+}
+
+// runComplete prints the completion candidates for the words typed after
+// "perms", the last being the word under the cursor. Returns the process
+// exit code.
+func runComplete(args []string) int {
+	for _, c := range complete(args) {
+		fmt.Println(c)
+	}
+	return 0
+}
+
+// complete returns the candidates for the last of words, filtered by what
+// has been typed so far, or a single path directive
+func complete(words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	cur := shellUnquote(words[len(words)-1])
+
+	cmd := rootCommand()
+	rest := words
+	if len(words) > 1 {
+		if sub, ok := findCommand(words[0]); ok && !sub.hidden {
+			cmd, rest = sub, words[1:]
+		}
+	}
+
+	var fs *flag.FlagSet
+	if cmd.flags != nil {
+		fs = cmd.flags()
+	}
+
+	// Value of the previous flag: --flag <cur>
+	if len(rest) > 1 && fs != nil {
+		if name, ok := flagName(rest[len(rest)-2]); ok && !strings.Contains(name, "=") {
+			if f := fs.Lookup(name); f != nil && !isBoolFlag(f) {
+				return filterCandidates(valueCandidates(cmd, name), "", cur)
+			}
+		}
+	}
+
+	if name, ok := flagName(cur); ok && fs != nil {
+		// Value in the same word: --flag=<value>
+		if i := strings.Index(name, "="); i >= 0 {
+			prefix := cur[:len(cur)-len(name)+i+1]
+			return filterCandidates(valueCandidates(cmd, name[:i]), prefix, cur)
+		}
+		var flags []string
+		fs.VisitAll(func(f *flag.Flag) {
+			flags = append(flags, flagSpelling(f.Name))
+		})
+		return filterCandidates(flags, "", cur)
+	}
+
+	// Subcommand names in the first word, else positional arguments
+	var candidates []string
+	if len(words) == 1 {
+		for _, c := range commands() {
+			if !c.hidden {
+				candidates = append(candidates, c.name)
+			}
+		}
+	} else if cmd.complete != nil {
+		candidates = cmd.complete()
+	}
+	return filterCandidates(candidates, "", cur)
+}
+
+// valueCandidates runs the completer for a flag's value, if it has one
+func valueCandidates(cmd command, name string) []string {
+	if c, ok := cmd.values[name]; ok {
+		return c()
+	}
+	return nil
+}
+
+// filterCandidates keeps the candidates that, after prefix, start with cur.
+// Path directives pass through for the shell to handle.
+func filterCandidates(candidates []string, prefix, cur string) []string {
+	if len(candidates) == 1 && (candidates[0] == directiveFiles || candidates[0] == directiveDirs) {
+		return candidates
+	}
+	var out []string
+	for _, c := range candidates {
+		if strings.HasPrefix(prefix+c, cur) {
+			out = append(out, prefix+c)
+		}
+	}
+	return out
+}
+
+// flagName strips the dashes from a word that looks like a flag
+func flagName(word string) (string, bool) {
+	if !strings.HasPrefix(word, "-") || word == "-" || word == "--" {
+		return "", false
+	}
+	return strings.TrimLeft(word, "-"), true
+}
+
+// flagSpelling writes a flag the way the help and README do: -n for
+// single letters, --name otherwise
+func flagSpelling(name string) string {
+	if len(name) == 1 {
+		return "-" + name
+	}
+	return "--" + name
+}
+
+// isBoolFlag reports whether a flag can be given without a value
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// shellUnquote undoes the quoting a shell passes through in the word under
+// the cursor: a leading quote and backslash escapes
+func shellUnquote(word string) string {
+	if strings.HasPrefix(word, "'") || strings.HasPrefix(word, `"`) {
+		return strings.Trim(word, `'"`)
+	}
+	var b strings.Builder
+	escaped := false
+	for _, r := range word {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// runCompletion prints the completion script for a shell. Returns the
+// process exit code.
+func runCompletion(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: perms completion bash|zsh|fish")
+		return 2
+	}
+
+	scripts := map[string]string{
+		"bash": bashCompletion,
+		"zsh":  zshCompletion,
+		"fish": fishCompletion,
+	}
+	script, ok := scripts[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown shell %q (want bash, zsh or fish)\n", args[0])
+		return 2
+	}
+	os.Stdout.WriteString(script)
+	return 0
+}
+
+// The scripts pass the words typed so far to perms __complete, which does
+// the work, so they stay in step with the commands and flags above.
+
+const bashCompletion = `# bash completion for perms
+# Load with: source <(perms completion bash)
+_perms_completion() {
+    # Split the line on unescaped spaces only; COMP_WORDS also breaks at = and :
+    local line=${COMP_LINE:0:COMP_POINT}
+    local -a words
+    read -a words <<< "$line"
+    [[ -z $line || $line == *[[:space:]] ]] && words+=("")
+    local cur=${words[${#words[@]}-1]}
+
+    local IFS=$'\n'
+    local -a out
+    out=($(perms __complete "${words[@]:1}" 2>/dev/null))
+
+    COMPREPLY=()
+    case ${out[0]} in
+    :files)
+        compopt -o filenames
+        COMPREPLY=($(compgen -f -- "${cur##*=}"))
+        ;;
+    :dirs)
+        compopt -o filenames
+        COMPREPLY=($(compgen -d -- "${cur##*=}"))
+        ;;
+    *)
+        # Readline only replaces the text after the last = or :, e.g. in
+        # Bash(git:*), so drop as many parts as the typed word has
+        local seps=${cur//[^:=]/} c i
+        for c in "${out[@]}"; do
+            for ((i = 0; i < ${#seps}; i++)); do c=${c#*[:=]}; done
+            COMPREPLY+=("$(printf '%q' "$c")")
+        done
+        ;;
+    esac
+}
+complete -F _perms_completion perms
+`
+
+const zshCompletion = `#compdef perms
+# zsh completion for perms
+# Load with: source <(perms completion zsh)
+_perms() {
+    local -a out
+    out=(${(f)"$(perms __complete "${(@)words[2,CURRENT]}" 2>/dev/null)"})
+    case $out[1] in
+    :files) _files ;;
+    :dirs) _files -/ ;;
+    *) compadd -a out ;;
+    esac
+}
+compdef _perms perms
+`
+
+const fishCompletion = `# fish completion for perms
+# Load with: perms completion fish | source
+function __perms_complete
+    set -l out (perms __complete (commandline -opc)[2..-1] (commandline -ct) 2>/dev/null)
+    switch "$out[1]"
+        case :files
+            __fish_complete_path (commandline -ct)
+        case :dirs
+            __fish_complete_directories (commandline -ct)
+        case '*'
+            printf '%s\n' $out
+    end
+end
+complete -c perms -f -a '(__perms_complete)'
+`
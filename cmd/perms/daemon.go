@@ -0,0 +1,214 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/config"
+	"github.com/b-open-io/claude-perms/internal/daemon"
+	"github.com/b-open-io/claude-perms/internal/notify"
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/watch"
+)
+
+// daemonOptions holds the daemon subcommand's flags
+type daemonOptions struct {
+	interval    time.Duration
+	notify      bool
+	projectsDir string
+}
+
+// daemonFlags defines the daemon subcommand's flags
+func daemonFlags(o *daemonOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	fs.DurationVar(&o.interval, "interval", defaultWatchInterval, "how often to check the logs for new sessions")
+	fs.BoolVar(&o.notify, "notify", false, "also notify about permissions your settings would prompt for, as perms watch does")
+	fs.StringVar(&o.projectsDir, "projects-dir", "", "index session logs in this directory instead of ~/.claude/projects")
+	return fs
+}
+
+// runDaemon runs the daemon in the foreground, or reports on or stops a
+// running one. Returns the process exit code.
+func runDaemon(args []string) int {
+	var opts daemonOptions
+	fs := daemonFlags(&opts)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: perms daemon [--interval 5s] [--notify] [--projects-dir dir]")
+		fmt.Fprintln(os.Stderr, "       perms daemon status|stop")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil || fs.NArg() > 1 {
+		if err == nil {
+			fs.Usage()
+		}
+		return 2
+	}
+
+	switch fs.Arg(0) {
+	case "":
+	case "status":
+		return daemonStatus()
+	case "stop":
+		return daemonStop()
+	default:
+		fs.Usage()
+		return 2
+	}
+	if opts.interval <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --interval must be positive")
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v (using defaults)\n", err)
+	}
+	if opts.projectsDir != "" {
+		cfg.ProjectsDir = opts.projectsDir
+	}
+	if err := configureParser(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	projectsDir, _ := filepath.Abs(parser.ProjectsDir())
+
+	index := daemon.NewIndex(projectsDir)
+	start := time.Now()
+	if _, err := index.Refresh(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	srv, err := daemon.Listen(daemon.SocketPath(), index)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer srv.Close()
+	go func() {
+		if err := srv.Serve(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+	}()
+
+	var w *watch.Watcher
+	notifyOpts := watchOptions{command: cfg.Notify.Command}
+	if opts.notify {
+		w = watch.New(projectsDir)
+		w.Poll()
+		if _, _, err := notify.Backend("", ""); err != nil && notifyOpts.command == "" {
+			fmt.Fprintf(os.Stderr, "Warning: %v; printing permissions only (set a notify command in the config)\n", err)
+			notifyOpts.quiet = true
+		}
+	}
+
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(opts.interval)
+	defer ticker.Stop()
+
+	logs, events, _ := index.Counts()
+	fmt.Fprintf(os.Stderr, "Indexed %d logs (%d tool uses) in %s; listening on %s (Ctrl+C to stop)\n",
+		logs, events, time.Since(start).Round(time.Millisecond), daemon.SocketPath())
+	for {
+		select {
+		case <-done:
+			return 0
+		case <-srv.Stopped():
+			return 0
+		case <-ticker.C:
+			if _, err := index.Refresh(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+			if w == nil {
+				continue
+			}
+			alerts, err := w.Poll()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+			for _, a := range alerts {
+				reportAlert(a, notifyOpts)
+			}
+		}
+	}
+}
+
+// daemonStatus prints what a running daemon has indexed
+func daemonStatus() int {
+	c, err := daemon.Dial(daemon.SocketPath())
+	if err != nil {
+		fmt.Println("Not running")
+		return 1
+	}
+	defer c.Close()
+	st, err := c.Status()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Running (pid %d) since %s\n", st.PID, st.Started.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("  Logs:      %s\n", st.ProjectsDir)
+	fmt.Printf("  Indexed:   %d logs, %d tool uses\n", st.Logs, st.Events)
+	fmt.Printf("  Refreshed: %s\n", st.Refreshed.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("  Socket:    %s\n", daemon.SocketPath())
+	return 0
+}
+
+// daemonStop asks a running daemon to exit
+func daemonStop() int {
+	c, err := daemon.Dial(daemon.SocketPath())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Not running")
+		return 1
+	}
+	defer c.Close()
+	if err := c.Stop(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// dialDaemon connects to a running daemon that indexes the configured
+// projects directory, or returns nil
+func dialDaemon() *daemon.Client {
+	c, _ := dialDaemonDir()
+	return c
+}
+
+// dialDaemonDir is dialDaemon, also returning the directory the daemon
+// indexes
+func dialDaemonDir() (*daemon.Client, string) {
+	c, err := daemon.Dial(daemon.SocketPath())
+	if err != nil {
+		return nil, ""
+	}
+	st, err := c.Status()
+	projectsDir, _ := filepath.Abs(parser.ProjectsDir())
+	if err != nil || st.ProjectsDir != projectsDir {
+		c.Close()
+		return nil, ""
+	}
+	return c, st.ProjectsDir
+}
+
+// useStatsSource points the loaders at a running daemon, or else at the
+// event store when cfg asks for it, and returns a func that releases it
+func useStatsSource(cfg *config.Config) func() {
+	if c, dir := dialDaemonDir(); c != nil {
+		parser.SetStatsSource(daemon.Source{Client: c, ProjectsDir: dir})
+		return func() {
+			parser.SetStatsSource(nil)
+			c.Close()
+		}
+	}
+	if cfg.Store {
+		return useStore()
+	}
+	return func() {}
+}
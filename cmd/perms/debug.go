@@ -0,0 +1,54 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+)
+
+// debugFlag implements --debug[=path]. A bare --debug logs to the default
+// path; nothing is written unless the flag is given.
+type debugFlag struct {
+	path string
+}
+
+func (f *debugFlag) String() string { return f.path }
+
+func (f *debugFlag) Set(v string) error {
+	switch v {
+	case "true":
+		f.path = defaultDebugLogPath()
+	case "false":
+		f.path = ""
+	default:
+		f.path = v
+	}
+	return nil
+}
+
+// IsBoolFlag lets --debug be given without a value
+func (f *debugFlag) IsBoolFlag() bool { return true }
+
+// defaultDebugLogPath keeps the log next to the cache and config, which is
+// private to the user (unlike a shared temp directory)
+func defaultDebugLogPath() string {
+	return filepath.Join(parser.ClaudeDir(), "perms-debug.log")
+}
+
+// openDebugLogger creates a debug-level logger writing to path. With an
+// empty path the logger discards everything and the returned closer is a no-op.
+func openDebugLogger(path string) (*slog.Logger, io.Closer, error) {
+	if path == "" {
+		return slog.New(slog.DiscardHandler), io.NopCloser(nil), nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, nil, err
+	}
+	handler := slog.NewTextHandler(file, &slog.HandlerOptions{Level: slog.LevelDebug})
+	return slog.New(handler), file, nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/templates"
+)
+
+// diffOptions holds the diff subcommand's flags
+type diffOptions struct {
+	mine     string
+	project  string
+	template string
+	format   string
+}
+
+// diffFlags defines the diff subcommand's flags
+func diffFlags(o *diffOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.StringVar(&o.mine, "mine", "", "settings file to compare (default ~/.claude/settings.local.json)")
+	fs.StringVar(&o.project, "project", "", "compare this project's settings.local.json instead of user settings")
+	fs.StringVar(&o.template, "template", "", "compare against the named template instead of a file")
+	fs.StringVar(&o.format, "format", "text", "output format: text or json")
+	return fs
+}
+
+// diffResult is diff --format json output
+type diffResult struct {
+	Mine        string                  `json:"mine"`
+	Theirs      string                  `json:"theirs"`
+	Differences []parser.RuleDifference `json:"differences"`
+}
+
+// runDiff compares the rules of my settings with a teammate's settings file
+// or a template and prints the rules only one has and the rules where both
+// differ only in their wildcards. Like diff(1), it returns 1 if they differ
+// and 2 on errors.
+func runDiff(args []string) int {
+	var opts diffOptions
+	fs := diffFlags(&opts)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: perms diff [--mine file | --project path] [--format text|json] (other-settings.json | --template name)")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil || (fs.NArg() == 1) == (opts.template != "") || fs.NArg() > 1 {
+		if err == nil {
+			fs.Usage()
+		}
+		return 2
+	}
+	if opts.format != "text" && opts.format != "json" {
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q (want text or json)\n", opts.format)
+		return 2
+	}
+	if opts.mine != "" && opts.project != "" {
+		fmt.Fprintln(os.Stderr, "Error: --mine can't be combined with --project")
+		return 2
+	}
+
+	minePath := opts.mine
+	switch {
+	case opts.project != "":
+		project, err := filepath.Abs(opts.project)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		minePath = parser.ProjectSettingsPath(project)
+	case minePath == "":
+		minePath = parser.UserSettingsPath()
+	}
+	allow, deny, err := parser.ReadSettingsRules(minePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", minePath, err)
+		return 2
+	}
+	mine := parser.RuleSet{Allow: allow, Deny: deny}
+
+	var theirs parser.RuleSet
+	theirsLabel := fs.Arg(0)
+	if opts.template != "" {
+		all, warns := templates.Load(templates.Dir())
+		for _, w := range warns {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", w.File, w.Reason)
+		}
+		t, ok := templates.Find(all, opts.template)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: unknown template %q\n", opts.template)
+			return 2
+		}
+		theirs, theirsLabel = t.Rules(), "template "+t.Name
+	} else {
+		// A missing file reads as having no rules, which is no comparison
+		if _, err := os.Stat(theirsLabel); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		allow, deny, err := parser.ReadSettingsRules(theirsLabel)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", theirsLabel, err)
+			return 2
+		}
+		theirs = parser.RuleSet{Allow: allow, Deny: deny}
+	}
+
+	diffs := parser.CompareRules(mine, theirs)
+	code := 0
+	if len(diffs) > 0 {
+		code = 1
+	}
+
+	if opts.format == "json" {
+		if diffs == nil {
+			diffs = []parser.RuleDifference{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(diffResult{Mine: minePath, Theirs: theirsLabel, Differences: diffs}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		return code
+	}
+
+	fmt.Printf("mine:   %s\ntheirs: %s\n", minePath, theirsLabel)
+	if len(diffs) == 0 {
+		fmt.Println("\nSame rules")
+		return 0
+	}
+	for _, section := range []struct {
+		kind  parser.DifferenceKind
+		title string
+	}{
+		{parser.OnlyMine, "Only in mine"},
+		{parser.OnlyTheirs, "Only in theirs"},
+		{parser.Overlap, "Different wildcards (mine ↔ theirs)"},
+	} {
+		printed := false
+		for _, d := range diffs {
+			if d.Kind != section.kind {
+				continue
+			}
+			if !printed {
+				fmt.Printf("\n%s:\n", section.title)
+				printed = true
+			}
+			switch d.Kind {
+			case parser.OnlyMine:
+				fmt.Printf("  %-5s %s\n", d.List, d.Mine)
+			case parser.OnlyTheirs:
+				fmt.Printf("  %-5s %s\n", d.List, d.Theirs)
+			default:
+				fmt.Printf("  %-5s %s ↔ %s\n", d.List, d.Mine, d.Theirs)
+			}
+		}
+	}
+	return code
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/b-open-io/claude-perms/internal/doctor"
+)
+
+// runDoctor checks the environment and prints actionable fixes.
+// Returns the process exit code.
+func runDoctor(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "usage: perms doctor")
+		return 2
+	}
+
+	fmt.Println("perms doctor")
+	fmt.Println()
+
+	results := doctor.Run()
+	for _, r := range results {
+		fmt.Printf("  %s %-20s %s\n", r.Status, r.Name, r.Detail)
+		if r.Status != doctor.StatusOK && r.Fix != "" {
+			fmt.Printf("      fix: %s\n", r.Fix)
+		}
+	}
+
+	warnings := 0
+	for _, r := range results {
+		if r.Status == doctor.StatusWarn {
+			warnings++
+		}
+	}
+
+	fmt.Println()
+	switch {
+	case doctor.Failed(results):
+		fmt.Println("Some checks failed.")
+		return 1
+	case warnings > 0:
+		fmt.Printf("No failures (%d warnings).\n", warnings)
+	default:
+		fmt.Println("All checks passed.")
+	}
+	return 0
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/audit"
+)
+
+// historyOptions holds the history subcommand's flags
+type historyOptions struct {
+	json       bool
+	limit      int
+	permission string
+	project    string
+}
+
+// historyFlags defines the history subcommand's flags
+func historyFlags(o *historyOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.BoolVar(&o.json, "json", false, "print entries as JSON lines")
+	fs.IntVar(&o.limit, "n", 0, "show only the most recent n entries (0 for all)")
+	fs.StringVar(&o.permission, "permission", "", "show only changes to this permission")
+	fs.StringVar(&o.project, "project", "", "show only changes to this project's settings")
+	return fs
+}
+
+// runHistory prints the audit log of changes made through perms.
+// Returns the process exit code.
+func runHistory(args []string) int {
+	var opts historyOptions
+	fs := historyFlags(&opts)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: perms history [--json] [-n count] [--permission perm] [--project path]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		if err == nil {
+			fs.Usage()
+		}
+		return 2
+	}
+
+	all, skipped, err := audit.Read()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: reading %s: %v\n", audit.Path(), err)
+		return 1
+	}
+	var entries []audit.Entry
+	for _, e := range all {
+		if opts.permission != "" && e.Permission != opts.permission {
+			continue
+		}
+		if opts.project != "" && !strings.HasPrefix(e.File, filepath.Clean(opts.project)+string(filepath.Separator)) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if opts.limit > 0 && len(entries) > opts.limit {
+		entries = entries[len(entries)-opts.limit:]
+	}
+
+	if opts.json {
+		enc := json.NewEncoder(os.Stdout)
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+		}
+		return 0
+	}
+
+	if len(entries) == 0 {
+		fmt.Printf("No changes recorded in %s\n", audit.Path())
+		return 0
+	}
+	for _, e := range entries {
+		status := "+"
+		if !e.Changed {
+			status = "="
+		}
+		what := e.Permission
+		if what == "" {
+			what = e.Note
+		}
+		fmt.Printf("%s %s  %-10s %-7s %-7s %-40s %s\n",
+			status, e.Time.Local().Format("2006-01-02 15:04:05"), e.User, e.Action, e.Scope, what, e.File)
+	}
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "perms: skipped %d unreadable lines in %s\n", skipped, audit.Path())
+	}
+	return 0
+}
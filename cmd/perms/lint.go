@@ -0,0 +1,148 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/b-open-io/claude-perms/internal/config"
+	"github.com/b-open-io/claude-perms/internal/insights"
+	"github.com/b-open-io/claude-perms/internal/parser"
+)
+
+// lintOptions holds the lint subcommand's flags
+type lintOptions struct {
+	policy      string
+	project     string
+	all         bool
+	format      string
+	projectsDir string
+}
+
+// lintFlags defines the lint subcommand's flags
+func lintFlags(o *lintOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fs.StringVar(&o.policy, "policy", "", "policy file to check against (default \"policy\" in the config)")
+	fs.StringVar(&o.project, "project", "", "project whose settings are checked (default the working directory)")
+	fs.BoolVar(&o.all, "all", false, "check every project with session logs instead of one")
+	fs.StringVar(&o.format, "format", "text", "output format: text or json")
+	fs.StringVar(&o.projectsDir, "projects-dir", "", "with --all, find projects in this directory instead of ~/.claude/projects")
+	return fs
+}
+
+// lintViolation is a violation in lint --format json output
+type lintViolation struct {
+	Kind     string `json:"kind"`
+	Severity string `json:"severity"`
+	Pattern  string `json:"pattern"`
+	Reason   string `json:"reason,omitempty"`
+	Rule     string `json:"rule,omitempty"`
+	List     string `json:"list,omitempty"`
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Project  string `json:"project,omitempty"`
+	Message  string `json:"message"`
+}
+
+// runLint checks the user and project settings against a team policy file
+// and prints each violation. Returns 1 if any violation is an error, so CI
+// can fail the build, and 2 if the policy can't be read.
+func runLint(args []string) int {
+	var opts lintOptions
+	fs := lintFlags(&opts)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: perms lint [--policy file] [--project path | --all] [--format text|json]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		if err == nil {
+			fs.Usage()
+		}
+		return 2
+	}
+	if opts.format != "text" && opts.format != "json" {
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q (want text or json)\n", opts.format)
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v (using defaults)\n", err)
+	}
+	if opts.projectsDir != "" {
+		cfg.ProjectsDir = opts.projectsDir
+	}
+	if err := configureParser(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if opts.policy == "" {
+		opts.policy = cfg.Policy
+	}
+	if opts.policy == "" {
+		fmt.Fprintln(os.Stderr, "Error: no policy file: pass --policy or set \"policy\" in the config")
+		return 2
+	}
+	policy, err := insights.LoadPolicy(opts.policy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	var projects []string
+	if opts.all {
+		settings, _ := parser.LoadDiscoveredProjectSettings()
+		for p := range settings {
+			projects = append(projects, p)
+		}
+	} else {
+		project := opts.project
+		if project == "" {
+			project, _ = os.Getwd()
+		}
+		if abs, err := filepath.Abs(project); err == nil {
+			project = abs
+		}
+		projects = []string{project}
+	}
+
+	rules, warns := insights.LoadSettingsRules(projects)
+	for _, w := range warns {
+		fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", w.File, w.Reason)
+	}
+	violations := policy.Lint(rules, projects)
+
+	code := 0
+	for _, v := range violations {
+		if v.Severity() == insights.SeverityError {
+			code = 1
+		}
+	}
+
+	if opts.format == "json" {
+		out := make([]lintViolation, 0, len(violations))
+		for _, v := range violations {
+			out = append(out, lintViolation{
+				Kind: string(v.Kind), Severity: string(v.Severity()), Pattern: v.Policy.Pattern, Reason: v.Policy.Reason,
+				Rule: v.Rule, List: v.List, File: v.File, Line: v.Line, Project: v.Project, Message: v.Message(),
+			})
+		}
+		if rc := printJSON(out); rc != 0 {
+			return rc
+		}
+		return code
+	}
+
+	for _, v := range violations {
+		loc := v.File
+		if v.Line > 0 {
+			loc = fmt.Sprintf("%s:%d", v.File, v.Line)
+		}
+		fmt.Printf("%s: %s: %s\n", loc, v.Severity(), v.Message())
+	}
+	if len(violations) == 0 {
+		fmt.Fprintf(os.Stderr, "No violations of %s\n", policy.Path)
+	}
+	return code
+}
@@ -1,41 +1,243 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"log"
 	"os"
 
 	"github.com/b-open-io/claude-perms/internal"
+	"github.com/b-open-io/claude-perms/internal/config"
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/snapshots"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func main() {
-	// Setup debug logging - write directly to ensure it works
-	logFile, err := os.OpenFile("/tmp/perms-debug.log", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	var opts options
+	rootFlags(flag.CommandLine, &opts)
+	flag.Usage = usage
+
+	// Subcommands
+	if len(os.Args) > 1 {
+		if cmd, ok := findCommand(os.Args[1]); ok {
+			os.Exit(cmd.run(os.Args[2:]))
+		}
+	}
+	flag.Parse()
+
+	logger, logCloser, err := openDebugLogger(opts.debug.path)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to open log: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: opening debug log: %v\n", err)
 		os.Exit(1)
 	}
-	defer logFile.Close()
+	defer logCloser.Close()
+	parser.SetLogger(logger)
 
-	// Write directly to file to ensure it works
-	logFile.WriteString("=== Starting perms TUI ===\n")
-	logFile.Sync()
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v (using defaults)\n", err)
+	}
+	if opts.theme != "" {
+		cfg.Theme = opts.theme
+	}
+	if opts.readOnly || opts.from != "" {
+		cfg.ReadOnly = true
+	}
+	if opts.dryRun {
+		cfg.DryRun = true
+	}
+	if err := applyTheme(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
 
-	log.SetOutput(logFile)
-	log.Println("Log initialized")
+	if opts.from != "" && (opts.projectsDir != "" || opts.store) {
+		fmt.Fprintln(os.Stderr, "Error: --from can't be combined with --projects-dir or --store")
+		os.Exit(2)
+	}
+	if err := internal.CheckSummaryFormat(opts.summaryFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if opts.projectsDir != "" {
+		cfg.ProjectsDir = opts.projectsDir
+	}
+	if opts.store {
+		cfg.Store = true
+	}
+	if opts.from != "" {
+		// The archive holds its own projects directory, and neither the
+		// store nor a daemon indexes it
+		cfg.ProjectsDir = ""
+		cfg.Store = false
+	}
+	if err := configureParser(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	closeArchive := func() {}
+	if opts.from != "" {
+		if closeArchive, err = parser.UseArchive(opts.from); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	defer closeArchive()
+	// exit runs the cleanup os.Exit would skip
+	exit := func(code int) {
+		closeArchive()
+		os.Exit(code)
+	}
+	if opts.host != "" {
+		hosts := parser.Hosts()
+		if len(hosts) == 0 || (!containsString(hosts, opts.host) && opts.host != parser.OtherHost) {
+			fmt.Fprintf(os.Stderr, "Error: unknown host %q (configure hosts in %s)\n", opts.host, config.Path())
+			exit(2)
+		}
+		parser.SetHostFilter(opts.host)
+	}
+	parser.SetWriteHook(snapshots.Record)
+	if opts.from == "" {
+		defer useStatsSource(cfg)()
+	}
+
+	model, err := internal.NewModelWithConfig(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(2)
+	}
+	if opts.demo {
+		if model, err = model.WithDemo(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+	}
 
 	p := tea.NewProgram(
-		internal.NewModel(),
+		model.WithLogger(logger),
 		tea.WithAltScreen(),
 		tea.WithMouseCellMotion(),
 	)
 
-	log.Println("Running program...")
-	if _, err := p.Run(); err != nil {
-		log.Printf("Error running program: %v", err)
+	logger.Debug("starting TUI")
+	final, err := p.Run()
+	if m, ok := final.(internal.Model); ok {
+		m.Cleanup()
+	}
+	if err != nil {
+		logger.Error("program failed", "err", err)
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		exit(1)
+	}
+	logger.Debug("program exited normally")
+
+	if opts.summary || opts.summaryFile != "" {
+		if err := writeSummary(final, opts.summaryFormat, opts.summaryFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+	}
+}
+
+// options holds the TUI's command-line flags
+type options struct {
+	theme         string
+	summary       bool
+	summaryFile   string
+	summaryFormat string
+	projectsDir   string
+	from          string
+	readOnly      bool
+	dryRun        bool
+	store         bool
+	host          string
+	demo          bool
+	debug         debugFlag
+}
+
+// rootFlags defines the TUI's flags on fs
+func rootFlags(fs *flag.FlagSet, o *options) {
+	fs.StringVar(&o.theme, "theme", "", "color theme: dark, light, high-contrast, none (overrides config)")
+	fs.BoolVar(&o.summary, "summary", false, "print a summary of changes made when the TUI exits")
+	fs.StringVar(&o.summaryFile, "summary-file", "", "write the exit summary to this file")
+	fs.StringVar(&o.summaryFormat, "summary-format", "text", "exit summary format: text or json")
+	fs.StringVar(&o.projectsDir, "projects-dir", "", "scan session logs in this directory instead of ~/.claude/projects")
+	fs.StringVar(&o.from, "from", "", "read a copy of ~/.claude from a .zip, .tar or .tar.gz archive (implies --read-only)")
+	fs.BoolVar(&o.readOnly, "read-only", false, "never write settings files; apply and deny are disabled")
+	fs.BoolVar(&o.dryRun, "dry-run", false, "start in dry-run mode: apply and deny report what would change without writing (toggle with D)")
+	fs.BoolVar(&o.store, "store", false, "load stats from the persistent event store instead of the JSON cache")
+	fs.StringVar(&o.host, "host", "", "only count activity from logs of this host (see \"hosts\" in the config)")
+	fs.BoolVar(&o.demo, "demo", false, "explore the TUI with bundled demo data (settings are not modified)")
+	fs.Var(&o.debug, "debug", "write a debug log to ~/.claude/perms-debug.log (or --debug=path)")
+}
+
+// usage prints the TUI's flags and the subcommands
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "usage: perms [flags]")
+	fmt.Fprintln(out, "       perms <command> [args]")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Commands:")
+	for _, cmd := range commands() {
+		if !cmd.hidden {
+			fmt.Fprintf(out, "  %-12s %s\n", cmd.name, cmd.summary)
+		}
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Flags:")
+	flag.PrintDefaults()
+}
+
+// writeSummary prints the session's changes to stdout, or to path if given
+func writeSummary(final tea.Model, format, path string) error {
+	m, ok := final.(internal.Model)
+	if !ok {
+		return nil
+	}
+
+	out, err := internal.FormatSummary(m.Actions(), format)
+	if err != nil {
+		return err
+	}
+
+	if path == "" {
+		fmt.Print(out)
+		return nil
+	}
+	return os.WriteFile(path, []byte(out), 0644)
+}
+
+// configureParser applies the config settings that affect how session logs
+// are read
+func configureParser(cfg *config.Config) error {
+	if err := parser.SetRejectionMarkers(cfg.Rejection.Markers, cfg.Rejection.Patterns); err != nil {
+		return err
+	}
+	if err := parser.SetRedactPatterns(cfg.Redact.Patterns); err != nil {
+		return err
+	}
+	parser.SetNormalization(!cfg.Normalize.Disabled, cfg.Normalize.Aliases)
+	parser.SetProjectAliases(cfg.Projects.Aliases, cfg.Projects.GroupByRemote)
+	parser.SetHosts(cfg.Hosts)
+	parser.SetProjectsDir(cfg.ProjectsDir)
+	return nil
+}
+
+// applyTheme selects the TUI theme from config, honoring NO_COLOR
+func applyTheme(cfg *config.Config) error {
+	if internal.NoColorRequested() {
+		internal.SetTheme(internal.NoColorTheme())
+		return nil
+	}
+
+	theme, ok := internal.ThemeByName(cfg.Theme)
+	if !ok {
+		return fmt.Errorf("unknown theme %q (want dark, light, high-contrast or none)", cfg.Theme)
+	}
+	theme, err := theme.WithOverrides(cfg.Colors)
+	if err != nil {
+		return fmt.Errorf("colors: %w", err)
 	}
-	log.Println("Program exited normally")
+	internal.SetTheme(theme)
+	return nil
 }
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// runMan prints the perms(1) man page in roff. Returns the process exit
+// code.
+func runMan(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "usage: perms man")
+		return 2
+	}
+	writeManPage(os.Stdout)
+	return 0
+}
+
+// writeManPage renders the man page from the same flag sets and command
+// table the CLI uses, so it can't drift from --help
+func writeManPage(w io.Writer) {
+	fmt.Fprintln(w, `.TH PERMS 1 "" "claude-perms" "User Commands"`)
+	fmt.Fprintln(w, ".SH NAME")
+	fmt.Fprintln(w, `perms \- analyze and manage Claude Code permission usage`)
+
+	fmt.Fprintln(w, ".SH SYNOPSIS")
+	fmt.Fprintln(w, `.B perms`)
+	fmt.Fprintln(w, `[\fIflags\fR]`)
+	fmt.Fprintln(w, ".br")
+	fmt.Fprintln(w, `.B perms`)
+	fmt.Fprintln(w, `\fIcommand\fR [\fIargs\fR]`)
+
+	fmt.Fprintln(w, ".SH DESCRIPTION")
+	fmt.Fprintln(w, "perms parses Claude Code session logs to show which tools are called, how often they are approved or denied,")
+	fmt.Fprintln(w, "and applies permissions to settings files with a live diff preview.")
+	fmt.Fprintln(w, "Without a command it opens the terminal UI; press ? inside it for the full keyboard help.")
+
+	fmt.Fprintln(w, ".SH OPTIONS")
+	writeManFlags(w, rootCommand().flags())
+
+	fmt.Fprintln(w, ".SH COMMANDS")
+	for _, cmd := range commands() {
+		if cmd.hidden {
+			continue
+		}
+		fmt.Fprintln(w, ".TP")
+		fmt.Fprintf(w, `.B perms %s`+"\n", roffEscape(cmd.name))
+		if cmd.args != "" {
+			fmt.Fprintln(w, ".I "+roffEscape(cmd.args))
+		}
+		fmt.Fprintln(w, roffEscape(sentence(cmd.summary)))
+		if cmd.flags != nil {
+			fmt.Fprintln(w, ".RS")
+			writeManFlags(w, cmd.flags())
+			fmt.Fprintln(w, ".RE")
+		}
+	}
+
+	fmt.Fprintln(w, ".SH FILES")
+	for _, f := range [][2]string{
+		{"~/.claude/settings.json", "User settings, where user-scope permissions are written."},
+		{"<project>/.claude/settings.local.json", "Project settings, where project-scope permissions are written."},
+		{"~/.claude/projects/", "Session logs that are scanned."},
+		{"~/.claude/perms-config.json", "Preferences: theme, colors, key bindings and more."},
+		{"~/.claude/perms-cache.json", "Parsed results, for fast subsequent launches."},
+		{"~/.claude/perms-events.db", "Persistent event store, used with --store or \"store\": true."},
+		{"~/.claude/perms-state.json", "Pins and notes on permissions and agents."},
+		{"~/.claude/perms-history/", "Snapshots of settings files taken on every write."},
+		{"~/.claude/perms-audit.jsonl", "Append-only log of the changes made with perms."},
+	} {
+		fmt.Fprintln(w, ".TP")
+		fmt.Fprintln(w, ".I "+roffEscape(f[0]))
+		fmt.Fprintln(w, roffEscape(f[1]))
+	}
+
+	fmt.Fprintln(w, ".SH ENVIRONMENT")
+	fmt.Fprintln(w, ".TP")
+	fmt.Fprintln(w, ".B NO_COLOR")
+	fmt.Fprintln(w, "Disables color entirely when set.")
+
+	fmt.Fprintln(w, ".SH EXIT STATUS")
+	fmt.Fprintln(w, "0 on success, 1 on failure (including failed doctor checks), 2 for invalid usage or configuration.")
+}
+
+// writeManFlags lists a flag set as tagged paragraphs
+func writeManFlags(w io.Writer, fs *flag.FlagSet) {
+	fs.VisitAll(func(f *flag.Flag) {
+		arg, usage := flag.UnquoteUsage(f)
+		fmt.Fprintln(w, ".TP")
+		if arg == "" || isBoolFlag(f) {
+			fmt.Fprintf(w, ".B %s\n", roffEscape(flagSpelling(f.Name)))
+		} else {
+			fmt.Fprintf(w, ".BI \"%s \" %s\n", roffEscape(flagSpelling(f.Name)), roffEscape(arg))
+		}
+		if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" {
+			usage += fmt.Sprintf(" (default %s)", f.DefValue)
+		}
+		fmt.Fprintln(w, roffEscape(sentence(usage)))
+	})
+}
+
+// sentence capitalizes a flag or command description and ends it with a
+// period
+func sentence(s string) string {
+	if s == "" {
+		return s
+	}
+	s = strings.ToUpper(s[:1]) + s[1:]
+	if !strings.HasSuffix(s, ".") {
+		s += "."
+	}
+	return s
+}
+
+// roffEscape escapes text for roff: backslashes, hyphens and a leading
+// control character
+func roffEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	s = strings.ReplaceAll(s, "-", `\-`)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+)
+
+// pluginsOptions holds the plugins subcommand's flags
+type pluginsOptions struct {
+	diff   bool
+	from   string
+	to     string
+	format string
+}
+
+// pluginsFlags defines the plugins subcommand's flags
+func pluginsFlags(o *pluginsOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("plugins", flag.ContinueOnError)
+	fs.BoolVar(&o.diff, "diff", false, "show how a plugin's declared permissions changed between two installed versions")
+	fs.StringVar(&o.from, "from", "", "older version to compare (default the one before --to)")
+	fs.StringVar(&o.to, "to", "", "newer version to compare (default the latest)")
+	fs.StringVar(&o.format, "format", "text", "output format: text or json")
+	return fs
+}
+
+// pluginListing is a plugin in plugins --format json output
+type pluginListing struct {
+	Name        string   `json:"name"`
+	Marketplace string   `json:"marketplace"`
+	Versions    []string `json:"versions"`
+	Latest      string   `json:"latest"`
+}
+
+// pluginChange is a changed declaration in plugins --diff --format json
+// output
+type pluginChange struct {
+	Member  string   `json:"member"`
+	List    string   `json:"list"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// pluginDiff is plugins --diff --format json output
+type pluginDiff struct {
+	Plugin  string         `json:"plugin"`
+	From    string         `json:"from"`
+	To      string         `json:"to"`
+	Changes []pluginChange `json:"changes"`
+}
+
+// runPlugins lists the installed plugins with every version in the plugin
+// cache, or with --diff compares what two versions of one plugin declare.
+// Returns the process exit code.
+func runPlugins(args []string) int {
+	var opts pluginsOptions
+	fs := pluginsFlags(&opts)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: perms plugins [--format text|json] [name]")
+		fmt.Fprintln(os.Stderr, "       perms plugins --diff [--from version] [--to version] [--format text|json] name")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil || fs.NArg() > 1 || (opts.diff && fs.NArg() == 0) {
+		if err == nil {
+			fs.Usage()
+		}
+		return 2
+	}
+	if opts.format != "text" && opts.format != "json" {
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q (want text or json)\n", opts.format)
+		return 2
+	}
+
+	var plugins []parser.InstalledPlugin
+	for _, p := range parser.ListInstalledPlugins() {
+		if fs.NArg() == 0 || p.Name == fs.Arg(0) {
+			plugins = append(plugins, p)
+		}
+	}
+	if fs.NArg() == 1 && len(plugins) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no installed plugin named %q\n", fs.Arg(0))
+		return 1
+	}
+
+	if opts.diff {
+		return runPluginDiff(plugins[0], opts)
+	}
+
+	if opts.format == "json" {
+		out := []pluginListing{}
+		for _, p := range plugins {
+			out = append(out, pluginListing{Name: p.Name, Marketplace: p.Marketplace, Versions: p.Versions, Latest: p.Latest()})
+		}
+		return printJSON(out)
+	}
+
+	if len(plugins) == 0 {
+		fmt.Println("No plugins installed")
+		return 0
+	}
+	for _, p := range plugins {
+		fmt.Printf("%-30s %-20s %s\n", p.Name, p.Marketplace, strings.Join(p.Versions, ", "))
+	}
+	return 0
+}
+
+// runPluginDiff prints the declared-permission changes between two versions
+// of a plugin
+func runPluginDiff(p parser.InstalledPlugin, opts pluginsOptions) int {
+	to := opts.to
+	if to == "" {
+		to = p.Latest()
+	}
+	from := opts.from
+	if from == "" {
+		from = p.Previous(to)
+	}
+	for _, v := range []string{from, to} {
+		if !containsString(p.Versions, v) {
+			if v == "" {
+				fmt.Fprintf(os.Stderr, "Error: %s has only version %s installed; nothing to compare\n", p.Name, to)
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: %s %s is not installed (installed: %s)\n", p.Name, v, strings.Join(p.Versions, ", "))
+			}
+			return 1
+		}
+	}
+
+	changes := parser.DiffPluginDeclarations(parser.LoadPluginVersion(p, from), parser.LoadPluginVersion(p, to))
+
+	if opts.format == "json" {
+		out := pluginDiff{Plugin: p.Name, From: from, To: to, Changes: []pluginChange{}}
+		for _, c := range changes {
+			out.Changes = append(out.Changes, pluginChange(c))
+		}
+		return printJSON(out)
+	}
+
+	fmt.Printf("%s %s → %s\n", p.Name, from, to)
+	if len(changes) == 0 {
+		fmt.Println("  no changes to declared permissions")
+		return 0
+	}
+	for _, c := range changes {
+		var parts []string
+		for _, a := range c.Added {
+			parts = append(parts, "+"+a)
+		}
+		for _, r := range c.Removed {
+			parts = append(parts, "-"+r)
+		}
+		fmt.Printf("  %-30s %-5s %s\n", c.Member, c.List, strings.Join(parts, " "))
+	}
+	return 0
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/config"
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/state"
+	"github.com/b-open-io/claude-perms/internal/store"
+	"github.com/b-open-io/claude-perms/internal/types"
+)
+
+// queryOptions holds the query subcommand's flags
+type queryOptions struct {
+	permType   string
+	scope      string
+	project    string
+	host       string
+	agent      string
+	since      string
+	until      string
+	deniedOnly bool
+	outcome    string
+	approval   string
+	events     bool
+	format     string
+	limit      int
+	store      bool
+}
+
+// queryFlags defines the query subcommand's flags
+func queryFlags(o *queryOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	fs.StringVar(&o.permType, "type", "", "only this tool, e.g. Bash or WebFetch")
+	fs.StringVar(&o.scope, "scope", "", "only scopes starting with this, e.g. git or domain:github.com")
+	fs.StringVar(&o.project, "project", "", "only projects whose path contains this")
+	fs.StringVar(&o.host, "host", "", "only logs from this host (see \"hosts\" in the config)")
+	fs.StringVar(&o.agent, "agent", "", "only this subagent type, or all for main sessions and every agent (default main sessions)")
+	fs.StringVar(&o.since, "since", "", "only uses after this: a date (2006-01-02) or an age such as 12h, 7d or 4w")
+	fs.StringVar(&o.until, "until", "", "only uses before this: a date or an age")
+	fs.BoolVar(&o.deniedOnly, "denied-only", false, "only uses the user denied (same as --outcome denied)")
+	fs.StringVar(&o.outcome, "outcome", "", "only uses with this outcome: approved, denied, failed or unknown")
+	fs.StringVar(&o.approval, "approval", "", "only permissions allowed at this level in settings: user, project or none")
+	fs.BoolVar(&o.events, "events", false, "list individual uses instead of totals per permission")
+	fs.StringVar(&o.format, "format", "text", "output format: text or json")
+	fs.IntVar(&o.limit, "n", 0, "show at most n rows (0 for all)")
+	fs.BoolVar(&o.store, "store", false, "query the persistent event store (synced first) instead of parsing the logs")
+	return fs
+}
+
+// queryRow is one permission's totals in query output
+type queryRow struct {
+	Permission string         `json:"permission"`
+	Count      int            `json:"count"`
+	Approved   int            `json:"approved"`
+	Denied     int            `json:"denied"`
+	Auto       int            `json:"auto_approved,omitempty"` // Run without a prompt by the session's permission mode
+	FirstSeen  time.Time      `json:"first_seen"`
+	LastSeen   time.Time      `json:"last_seen"`
+	Approval   string         `json:"approval"`
+	Projects   []string       `json:"projects"`
+	Hosts      map[string]int `json:"hosts,omitempty"` // Uses per host, when hosts are configured
+	Note       string         `json:"note,omitempty"`  // Set with N in the TUI
+}
+
+// queryEvent is one use in query --events output
+type queryEvent struct {
+	Time       time.Time      `json:"time"`
+	Permission string         `json:"permission"`
+	Outcome    parser.Outcome `json:"outcome,omitempty"`
+	Mode       string         `json:"mode,omitempty"` // Permission mode of the session, when its log records it
+	Auto       bool           `json:"auto_approved,omitempty"`
+	Project    string         `json:"project"`
+	Host       string         `json:"host,omitempty"`
+	Agent      string         `json:"agent,omitempty"`
+	Approval   string         `json:"approval"`
+	Sample     string         `json:"sample,omitempty"`
+}
+
+// runQuery answers ad-hoc questions about permission usage, e.g. which Bash
+// commands were denied in one project last week. Returns the process exit
+// code.
+func runQuery(args []string) int {
+	var opts queryOptions
+	fs := queryFlags(&opts)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: perms query [--type tool] [--scope prefix] [--project path] [--host label] [--agent type] [--since when] [--until when] [--denied-only] [--events] [--format text|json]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		if err == nil {
+			fs.Usage()
+		}
+		return 2
+	}
+
+	filter, err := opts.filter(time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if opts.deniedOnly {
+		opts.outcome = string(parser.OutcomeDenied)
+	}
+	switch opts.outcome {
+	case "", "approved", "denied", "failed", "unknown":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown outcome %q (want approved, denied, failed or unknown)\n", opts.outcome)
+		return 2
+	}
+	switch opts.approval {
+	case "", "user", "project", "none":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown approval %q (want user, project or none)\n", opts.approval)
+		return 2
+	}
+	if opts.format != "text" && opts.format != "json" {
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q (want text or json)\n", opts.format)
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v (using defaults)\n", err)
+	}
+	if err := configureParser(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	logs, err := opts.load(cfg.Store || opts.store, filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if warnings, dropped := parser.TakeWarnings(); len(warnings)+dropped > 0 {
+		fmt.Fprintf(os.Stderr, "perms: skipped %d unreadable logs or lines; perms doctor lists them\n", len(warnings)+dropped)
+	}
+
+	userApproved, _ := parser.LoadUserSettings()
+	projectSettings, _ := parser.LoadDiscoveredProjectSettings()
+	approvalOf := func(perm, project string) string {
+		return approvalName(parser.GetApprovalLevel(perm, userApproved, projectSettings[project]))
+	}
+
+	// Keep the matching events of each log, with normalized permissions so
+	// filters and totals see the same rows as the TUI
+	var matched []parser.LogEvents
+	events := []queryEvent{}
+	for _, l := range logs {
+		if !opts.matchesLog(l) {
+			continue
+		}
+		host := parser.HostOf(l.Path)
+		kept := l
+		kept.Events = nil
+		for _, e := range l.Events {
+			perm := parser.NormalizePermission(parser.ParsePermission(e.Permission))
+			e.Permission = perm.Raw
+			approval := approvalOf(perm.Raw, l.Project)
+			if !opts.matchesEvent(filter, perm, e, approval) {
+				continue
+			}
+			kept.Events = append(kept.Events, e)
+			events = append(events, queryEvent{
+				Time:       e.Time,
+				Permission: perm.Raw,
+				Outcome:    e.Outcome,
+				Mode:       e.Mode,
+				Auto:       e.AutoApproved(),
+				Project:    l.Project,
+				Host:       host,
+				Agent:      l.AgentType,
+				Approval:   approval,
+				Sample:     e.Sample,
+			})
+		}
+		if len(kept.Events) > 0 {
+			matched = append(matched, kept)
+		}
+	}
+
+	if opts.events {
+		return printQueryEvents(events, opts)
+	}
+
+	// Notes are best effort; a broken state file just leaves them out
+	st, _ := state.Load()
+	rows := []queryRow{}
+	for _, s := range parser.AggregatePermissionStats(matched) {
+		rows = append(rows, queryRow{
+			Permission: s.Permission.Raw,
+			Count:      s.Count,
+			Approved:   s.Approved,
+			Denied:     s.Denied,
+			Auto:       s.AutoApproved,
+			FirstSeen:  s.FirstSeen,
+			LastSeen:   s.LastSeen,
+			Approval:   approvalName(parser.GetProjectsApprovalLevel(s.Permission.Raw, s.Projects, userApproved, projectSettings)),
+			Projects:   s.Projects,
+			Hosts:      s.HostCounts,
+			Note:       st.Notes.Permission(s.Permission.Raw),
+		})
+	}
+	return printQueryRows(rows, opts)
+}
+
+// filter returns the time range and store filter the options select
+func (o queryOptions) filter(now time.Time) (store.Filter, error) {
+	var f store.Filter
+	var err error
+	if f.Since, err = parseWhen(o.since, now); err != nil {
+		return f, fmt.Errorf("--since: %w", err)
+	}
+	if f.Until, err = parseWhen(o.until, now); err != nil {
+		return f, fmt.Errorf("--until: %w", err)
+	}
+	if o.agent != "all" && o.agent != "" {
+		f.Agent = o.agent
+	}
+	return f, nil
+}
+
+// load returns the events to query, from a running daemon, the store or
+// by parsing the logs
+func (o queryOptions) load(useStore bool, f store.Filter) ([]parser.LogEvents, error) {
+	if c := dialDaemon(); c != nil {
+		defer c.Close()
+		logs, warns, err := c.Logs(f)
+		parser.RecordWarnings(warns)
+		return logs, err
+	}
+	if !useStore {
+		return parser.LoadLogEvents(parser.ProjectsDir())
+	}
+	s, err := store.Open(store.Path())
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	result, err := s.Sync(parser.ProjectsDir(), nil)
+	if err != nil {
+		return nil, err
+	}
+	parser.RecordWarnings(result.Warnings)
+	f.Dir = parser.ProjectsDir()
+	return s.Logs(f)
+}
+
+// matchesLog applies the filters that depend only on where a log came from
+func (o queryOptions) matchesLog(l parser.LogEvents) bool {
+	if o.project != "" && !strings.Contains(l.Project, o.project) {
+		return false
+	}
+	if o.host != "" && parser.HostOf(l.Path) != o.host {
+		return false
+	}
+	switch o.agent {
+	case "all":
+		return true
+	case "":
+		return l.AgentType == ""
+	default:
+		return l.AgentType == o.agent
+	}
+}
+
+// matchesEvent applies the per-use filters; f holds the parsed time range
+func (o queryOptions) matchesEvent(f store.Filter, perm types.Permission, e parser.ToolEvent, approval string) bool {
+	switch {
+	case o.permType != "" && !strings.EqualFold(perm.Type, o.permType):
+		return false
+	case o.scope != "" && !strings.HasPrefix(perm.Scope, o.scope):
+		return false
+	case !f.Since.IsZero() && e.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !e.Time.Before(f.Until):
+		return false
+	case o.outcome == "unknown" && e.Outcome != parser.OutcomeUnknown:
+		return false
+	case o.outcome != "" && o.outcome != "unknown" && string(e.Outcome) != o.outcome:
+		return false
+	case o.approval != "" && approval != o.approval:
+		return false
+	}
+	return true
+}
+
+// parseWhen reads a --since or --until value: a date, an RFC 3339 time, or
+// an age before now in hours (h), days (d) or weeks (w). Empty is zero.
+func parseWhen(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	unit := map[byte]time.Duration{'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}[s[len(s)-1]]
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if unit == 0 || err != nil || n < 0 {
+		return time.Time{}, fmt.Errorf("%q is not a date (2006-01-02) or an age such as 12h, 7d or 4w", s)
+	}
+	return now.Add(-time.Duration(n) * unit), nil
+}
+
+// approvalName names an approval level in query output
+func approvalName(level types.ApprovalLevel) string {
+	switch level {
+	case types.ApprovedUser:
+		return "user"
+	case types.ApprovedProject:
+		return "project"
+	default:
+		return "none"
+	}
+}
+
+func printQueryRows(rows []queryRow, opts queryOptions) int {
+	if opts.limit > 0 && len(rows) > opts.limit {
+		rows = rows[:opts.limit]
+	}
+	if opts.format == "json" {
+		return printJSON(rows)
+	}
+	if len(rows) == 0 {
+		fmt.Println("No permissions match")
+		return 0
+	}
+	fmt.Printf("%-50s %6s %8s %6s  %-16s %s\n", "PERMISSION", "USES", "APPROVED", "DENIED", "LAST USED", "ALLOWED")
+	for _, r := range rows {
+		fmt.Printf("%-50s %6d %8d %6d  %-16s %s\n",
+			r.Permission, r.Count, r.Approved, r.Denied, r.LastSeen.Local().Format("2006-01-02 15:04"), r.Approval)
+		if r.Note != "" {
+			fmt.Printf("  note: %s\n", r.Note)
+		}
+	}
+	return 0
+}
+
+func printQueryEvents(events []queryEvent, opts queryOptions) int {
+	// Newest first, so -n keeps the most recent uses
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.After(events[j].Time)
+	})
+	if opts.limit > 0 && len(events) > opts.limit {
+		events = events[:opts.limit]
+	}
+	if opts.format == "json" {
+		return printJSON(events)
+	}
+	if len(events) == 0 {
+		fmt.Println("No uses match")
+		return 0
+	}
+	for _, e := range events {
+		outcome := string(e.Outcome)
+		switch {
+		case e.Auto:
+			outcome = "auto"
+		case outcome == "":
+			outcome = "-"
+		}
+		fmt.Printf("%s  %-8s %-40s %-30s %s\n", e.Time.Local().Format("2006-01-02 15:04:05"), outcome, e.Permission, e.Project, e.Sample)
+	}
+	return 0
+}
+
+func printJSON(v any) int {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
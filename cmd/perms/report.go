@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/config"
+	"github.com/b-open-io/claude-perms/internal/insights"
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/report"
+	"github.com/b-open-io/claude-perms/internal/store"
+)
+
+// reportOptions holds the report subcommand's flags
+type reportOptions struct {
+	html        string
+	json        string
+	markdown    bool
+	sarif       string
+	anonymize   bool
+	salt        string
+	since       string
+	project     string
+	minUses     int
+	top         int
+	projectsDir string
+}
+
+// reportFlags defines the report subcommand's flags
+func reportFlags(o *reportOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	fs.StringVar(&o.html, "html", "", "write a self-contained HTML report to this file (- for stdout)")
+	fs.StringVar(&o.json, "json", "", "write the report data as JSON to this file (- for stdout)")
+	fs.BoolVar(&o.markdown, "markdown", false, "print a Markdown summary for a pull request that updates .claude/settings.json")
+	fs.StringVar(&o.sarif, "sarif", "", "write risky permission uses and allow rules as SARIF to this file (- for stdout)")
+	fs.BoolVar(&o.anonymize, "anonymize", false, "with --html or --json, replace paths and host names with hashes and drop samples and notes, for sharing")
+	fs.StringVar(&o.salt, "salt", "", "with --anonymize, hash with this salt instead of a random one, so exports can be compared")
+	fs.StringVar(&o.since, "since", "", "with --markdown, list permissions first used after this; with --sarif, only check uses after this: a date (2006-01-02) or an age such as 7d")
+	fs.StringVar(&o.project, "project", "", "with --markdown, propose allow rules for this project's .claude/settings.json (default the current directory)")
+	fs.IntVar(&o.minUses, "min-uses", 2, "with --markdown, uses in the project a permission needs before it is proposed")
+	fs.IntVar(&o.top, "n", 10, "with --markdown, how many of the most used permissions to list")
+	fs.StringVar(&o.projectsDir, "projects-dir", "", "read session logs from this directory instead of ~/.claude/projects")
+	return fs
+}
+
+// runReport writes a shareable report of permission usage, denials, agents
+// and settings coverage as HTML or JSON, optionally anonymized, or a
+// Markdown summary with proposed allow rules for a pull request, or the
+// risky uses and allow rules as SARIF for code-scanning dashboards. Returns
+// the process exit code.
+func runReport(args []string) int {
+	var opts reportOptions
+	fs := reportFlags(&opts)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: perms report --html file [--anonymize [--salt s]] [--projects-dir dir]")
+		fmt.Fprintln(os.Stderr, "       perms report --json file [--anonymize [--salt s]] [--projects-dir dir]")
+		fmt.Fprintln(os.Stderr, "       perms report --markdown [--since when] [--project path] [--min-uses n] [-n rows] [--projects-dir dir]")
+		fmt.Fprintln(os.Stderr, "       perms report --sarif file [--since when] [--projects-dir dir]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	outputs := 0
+	for _, set := range []bool{opts.html != "", opts.json != "", opts.markdown, opts.sarif != ""} {
+		if set {
+			outputs++
+		}
+	}
+	if fs.NArg() > 0 || outputs != 1 {
+		fs.Usage()
+		return 2
+	}
+	if opts.salt != "" && !opts.anonymize {
+		fmt.Fprintln(os.Stderr, "Error: --salt needs --anonymize")
+		return 2
+	}
+	if opts.anonymize && opts.html == "" && opts.json == "" {
+		fmt.Fprintln(os.Stderr, "Error: --anonymize only applies to --html and --json")
+		return 2
+	}
+	md := report.MarkdownOptions{Top: opts.top, Project: opts.project, MinUses: opts.minUses}
+	var err error
+	if md.Since, err = parseWhen(opts.since, time.Now()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --since: %v\n", err)
+		return 2
+	}
+	if md.Project == "" {
+		md.Project, _ = os.Getwd()
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v (using defaults)\n", err)
+	}
+	if opts.projectsDir != "" {
+		cfg.ProjectsDir = opts.projectsDir
+	}
+	if err := configureParser(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if opts.sarif == "" {
+		defer useStatsSource(cfg)()
+	}
+
+	var out bytes.Buffer
+	if opts.sarif != "" {
+		err = writeRisks(&out, cfg.Store, md.Since)
+	} else {
+		var data *report.Data
+		if data, err = report.Load(); err == nil {
+			if opts.anonymize {
+				data = report.Anonymize(data, anonymizeSalt(opts.salt))
+			}
+			switch {
+			case opts.markdown:
+				err = report.WriteMarkdown(&out, data, md)
+			case opts.json != "":
+				err = report.WriteJSON(&out, data)
+			default:
+				err = report.WriteHTML(&out, data)
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	file := opts.html + opts.json + opts.sarif
+	if opts.markdown || file == "-" {
+		os.Stdout.Write(out.Bytes())
+		return 0
+	}
+	if err := os.WriteFile(file, out.Bytes(), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s\n", file)
+	return 0
+}
+
+// writeRisks checks the tool uses since since and the allow rules of the
+// user and every known project's settings, and writes what it finds as SARIF
+func writeRisks(w io.Writer, fromStore bool, since time.Time) error {
+	logs, err := queryOptions{}.load(fromStore, store.Filter{Since: since})
+	if err != nil {
+		return err
+	}
+	wd, _ := os.Getwd()
+	settings, _ := parser.LoadDiscoveredProjectSettings(wd)
+	projects := make([]string, 0, len(settings))
+	for p := range settings {
+		projects = append(projects, p)
+	}
+	rules, _ := insights.LoadSettingsRules(projects)
+	return report.WriteSARIF(w, append(insights.ObservedRisks(logs, since), insights.SettingsRisks(rules)...))
+}
+
+// anonymizeSalt returns salt, or a random one so the hashes of an
+// anonymized report can't be reversed by hashing guessed paths
+func anonymizeSalt(salt string) string {
+	if salt != "" {
+		return salt
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
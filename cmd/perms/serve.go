@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/config"
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/server"
+)
+
+// serveOptions holds the serve subcommand's flags
+type serveOptions struct {
+	addr        string
+	projectsDir string
+}
+
+// serveFlags defines the serve subcommand's flags
+func serveFlags(o *serveOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.StringVar(&o.addr, "addr", "127.0.0.1:7878", "address to listen on")
+	fs.StringVar(&o.projectsDir, "projects-dir", "", "read session logs from this directory instead of ~/.claude/projects")
+	return fs
+}
+
+// runServe serves the web dashboard and JSON API until interrupted.
+// Returns the process exit code.
+func runServe(args []string) int {
+	var opts serveOptions
+	fs := serveFlags(&opts)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: perms serve [--addr host:port] [--projects-dir dir]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		if err == nil {
+			fs.Usage()
+		}
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v (using defaults)\n", err)
+	}
+	if opts.projectsDir != "" {
+		cfg.ProjectsDir = opts.projectsDir
+	}
+	if err := configureParser(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	defer useStatsSource(cfg)()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	parser.SetLogger(logger)
+
+	listener, err := net.Listen("tcp", opts.addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if !isLoopback(listener.Addr()) {
+		fmt.Fprintln(os.Stderr, "Warning: listening on a non-loopback address; anyone who can reach it can see your permission stats")
+	}
+
+	srv := &http.Server{
+		Handler:           server.New(server.Load, logger).Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-done
+		srv.Close()
+	}()
+
+	fmt.Fprintf(os.Stderr, "Serving permission stats on http://%s (Ctrl+C to stop)\n", listener.Addr())
+	if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// isLoopback reports whether a listener only accepts local connections
+func isLoopback(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	return ok && tcp.IP.IsLoopback()
+}
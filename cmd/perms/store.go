@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/config"
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/store"
+)
+
+// useStore points the loaders at the persistent event store and returns a
+// func that closes it. If the store can't be opened, loading falls back to
+// the JSON cache.
+func useStore() func() {
+	s, err := store.Open(store.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: event store unavailable, using the cache: %v\n", err)
+		return func() {}
+	}
+	parser.SetStatsSource(store.Source{Store: s})
+	return func() {
+		parser.SetStatsSource(nil)
+		s.Close()
+	}
+}
+
+// runStore manages the persistent event store. Returns the process exit
+// code.
+func runStore(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: perms store sync|info|reset")
+		return 2
+	}
+
+	switch args[0] {
+	case "reset":
+		if err := os.Remove(store.Path()); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Removed %s; it is rebuilt on the next sync\n", store.Path())
+		return 0
+	case "sync", "info":
+	default:
+		fmt.Fprintln(os.Stderr, "usage: perms store sync|info|reset")
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v (using defaults)\n", err)
+	}
+	if err := configureParser(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	s, err := store.Open(store.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer s.Close()
+
+	if args[0] == "sync" {
+		start := time.Now()
+		result, err := s.Sync(parser.ProjectsDir(), nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Synced %d logs in %s: %d parsed (%d events), %d removed, %d warnings\n",
+			result.Logs, time.Since(start).Round(time.Millisecond), result.Parsed, result.Events, result.Removed, len(result.Warnings))
+	}
+
+	info, err := s.Info()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	synced := "never"
+	if !info.Synced.IsZero() {
+		synced = info.Synced.Local().Format("2006-01-02 15:04")
+	}
+	fmt.Printf("%s: %d logs, %d events, %d KB, last synced %s\n", info.Path, info.Logs, info.Events, info.Size>>10, synced)
+	return 0
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/config"
+	"github.com/b-open-io/claude-perms/internal/notify"
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/watch"
+)
+
+// defaultWatchInterval is how often perms watch checks the logs
+const defaultWatchInterval = 5 * time.Second
+
+// cacheFlushInterval is how often perms watch writes the logs it parsed to
+// the unified cache, rather than on every change
+const cacheFlushInterval = time.Minute
+
+// watchOptions holds the watch subcommand's flags
+type watchOptions struct {
+	interval    time.Duration
+	command     string
+	quiet       bool
+	projectsDir string
+}
+
+// watchFlags defines the watch subcommand's flags
+func watchFlags(o *watchOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	fs.DurationVar(&o.interval, "interval", 0, "how often to check the logs (default \"interval\" in the notify config, or 5s)")
+	fs.StringVar(&o.command, "command", "", "run this shell command for each permission instead of a desktop notification (default \"command\" in the notify config)")
+	fs.BoolVar(&o.quiet, "quiet", false, "only print permissions; no desktop notification or command")
+	fs.StringVar(&o.projectsDir, "projects-dir", "", "watch session logs in this directory instead of ~/.claude/projects")
+	return fs
+}
+
+// runWatch checks the session logs for new tool uses until interrupted and
+// reports each permission the settings would prompt for with a desktop
+// notification or the configured command. Returns the process exit code.
+func runWatch(args []string) int {
+	var opts watchOptions
+	fs := watchFlags(&opts)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: perms watch [--interval 5s] [--command cmd | --quiet] [--projects-dir dir]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		if err == nil {
+			fs.Usage()
+		}
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v (using defaults)\n", err)
+	}
+	if opts.projectsDir != "" {
+		cfg.ProjectsDir = opts.projectsDir
+	}
+	if err := configureParser(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if opts.command == "" {
+		opts.command = cfg.Notify.Command
+	}
+	if opts.interval == 0 {
+		opts.interval = defaultWatchInterval
+		if cfg.Notify.Interval != "" {
+			if opts.interval, err = time.ParseDuration(cfg.Notify.Interval); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: notify interval: %v\n", err)
+				return 2
+			}
+		}
+	}
+	if opts.interval <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --interval must be positive")
+		return 2
+	}
+
+	if opts.command == "" && !opts.quiet {
+		if _, _, err := notify.Backend("", ""); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v; printing permissions only (set a notify command in the config)\n", err)
+			opts.quiet = true
+		}
+	}
+
+	w := watch.New(parser.ProjectsDir())
+	cache := parser.NewCacheWriter(cacheFlushInterval)
+	w.CacheTo(cache)
+	defer flushCache(cache)
+	if _, err := w.Poll(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(opts.interval)
+	defer ticker.Stop()
+
+	fmt.Fprintf(os.Stderr, "Watching %s for permissions your settings would prompt for (Ctrl+C to stop)\n", parser.ProjectsDir())
+	for {
+		select {
+		case <-done:
+			return 0
+		case <-ticker.C:
+			alerts, err := w.Poll()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				continue
+			}
+			for _, a := range alerts {
+				reportAlert(a, opts)
+			}
+			if err := cache.MaybeFlush(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: saving cache: %v\n", err)
+			}
+		}
+	}
+}
+
+// flushCache writes what is left of the parsed logs to the cache on exit
+func flushCache(cache *parser.CacheWriter) {
+	if err := cache.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: saving cache: %v\n", err)
+	}
+}
+
+// reportAlert prints an alert and sends it as a notification or to the
+// configured command
+func reportAlert(a watch.Alert, opts watchOptions) {
+	fmt.Printf("%s  %s  %s  %s\n", a.Time.Local().Format("15:04:05"), a.Permission, a.Project, a.Sample)
+	if opts.quiet {
+		return
+	}
+
+	var err error
+	if opts.command != "" {
+		err = notify.Run(opts.command, map[string]string{
+			"PERMS_PERMISSION": a.Permission,
+			"PERMS_PROJECT":    a.Project,
+			"PERMS_SAMPLE":     a.Sample,
+			"PERMS_LOG":        a.Log,
+		})
+	} else {
+		body := a.Project
+		if a.Sample != "" {
+			body = a.Sample + "\n" + a.Project
+		}
+		err = notify.Desktop("New permission: "+a.Permission, body)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: notify: %v\n", err)
+	}
+}
@@ -0,0 +1,351 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/types"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxSnippetRules bounds the settings snippet rules shown in the agent
+// modal; the copied snippet always has all of them
+const maxSnippetRules = 8
+
+// agentToolsPerms returns the permissions the tools line is generated from:
+// the selected ones, or every permission the agent used if none are
+// selected
+func (m Model) agentToolsPerms(agent types.AgentUsageStats) []types.PermissionStats {
+	var selected []types.PermissionStats
+	for i, perm := range agent.Permissions {
+		if i < len(m.agentModalSelected) && m.agentModalSelected[i] {
+			selected = append(selected, perm)
+		}
+	}
+	if len(selected) == 0 {
+		return agent.Permissions
+	}
+	return selected
+}
+
+// toolsFileKind names what a Matrix row's file defines, for messages
+func toolsFileKind(agentType string) string {
+	if isCommand(agentType) {
+		return "command"
+	}
+	return "agent"
+}
+
+// toolsKeyName returns the frontmatter key a Matrix row's tools go under
+func toolsKeyName(agentType string) string {
+	if isCommand(agentType) {
+		return "allowed-tools"
+	}
+	return "tools"
+}
+
+// article prefixes a noun with "a" or "an"
+func article(noun string) string {
+	if strings.ContainsRune("aeiou", rune(noun[0])) {
+		return "an " + noun
+	}
+	return "a " + noun
+}
+
+// agentSettingsSnippet renders the permissions as a settings.json allow
+// list, for agents without a file of their own
+func agentSettingsSnippet(perms []types.PermissionStats) string {
+	rules := make([]string, len(perms))
+	for i, p := range perms {
+		rules[i] = p.Permission.Raw
+	}
+	return settingsSnippet("allow", rules)
+}
+
+// handleAgentToolsKeys processes keys in the agent modal's tools mode
+func (m Model) handleAgentToolsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.selectedAgentIdx >= len(m.agentUsage) {
+		return m, nil
+	}
+	agent := m.agentUsage[m.selectedAgentIdx]
+
+	switch {
+	case key.Matches(msg, m.keys.Back):
+		m.agentModalMode = AgentModalModePermissions
+		return m, nil
+
+	case key.Matches(msg, m.keys.Select):
+		return m.writeAgentTools(agent.AgentType, parser.ToolsForPermissions(m.agentToolsPerms(agent)))
+
+	case key.Matches(msg, m.keys.Tools):
+		return m.copyText(agentSettingsSnippet(m.agentToolsPerms(agent)), "the settings snippet")
+
+	case key.Matches(msg, m.keys.ForceQuit):
+		return m, tea.Quit
+	}
+
+	return m, nil
+}
+
+// writeAgentTools sets the tools line of the agent's markdown file; in
+// dry-run mode it only reports what would change
+func (m Model) writeAgentTools(agentType string, tools []string) (tea.Model, tea.Cmd) {
+	if m.writeBlockedReason() != "" {
+		return m.writeBlocked()
+	}
+	if m.agentToolsFile == "" {
+		m.setLinkToast("%s has no %s file; copy the settings snippet instead", agentType, toolsFileKind(agentType))
+		return m, m.toastTickCmd()
+	}
+
+	write := parser.WriteAgentTools
+	if m.dryRun {
+		write = parser.PlanAgentTools
+	}
+	result, err := write(m.agentToolsFile, tools)
+	if err != nil {
+		return m, m.failWrite(m.agentToolsFile, err, func(m Model) (tea.Model, tea.Cmd) {
+			return m.writeAgentTools(agentType, tools)
+		})
+	}
+
+	if result.WasNew && !result.DryRun {
+		for i, a := range m.agents {
+			if a.FilePath == result.FilePath {
+				m.agents[i].Permissions = parser.ParsePermissions(tools)
+			}
+		}
+		found := false
+		for i, c := range m.commands {
+			if c.FilePath == result.FilePath {
+				m.commands[i].Permissions = parser.ParsePermissions(tools)
+				found = true
+			}
+		}
+		// A command that allowed no tools wasn't loaded
+		if !found && isCommand(agentType) {
+			plugin, name, ok := strings.Cut(strings.TrimPrefix(agentType, "/"), ":")
+			if !ok {
+				plugin, name = "", plugin
+			}
+			m.commands = append(m.commands, types.CommandPermissions{Name: name, Plugin: plugin, FilePath: result.FilePath, Permissions: parser.ParsePermissions(tools)})
+		}
+	}
+	m.record(SessionAction{
+		Time:       time.Now(),
+		Action:     "tools",
+		Permission: result.Permission,
+		Scope:      toolsFileKind(agentType),
+		File:       result.FilePath,
+		Line:       result.LineNumber,
+		Changed:    result.WasNew,
+		DryRun:     result.DryRun,
+	})
+
+	m.finishModal()
+	m.setApplyToast(result)
+	return m, m.toastTickCmd()
+}
+
+// renderToolsMode renders the tools line generated from the agent's usage,
+// with a diff of its file or, for built-in agents, a settings snippet
+func (m Model) renderToolsMode(agent types.AgentUsageStats) string {
+	var content strings.Builder
+
+	perms := m.agentToolsPerms(agent)
+	tools := parser.ToolsForPermissions(perms)
+
+	content.WriteString(m.renderWriteModeNotice())
+	source := fmt.Sprintf("all %d permissions used", len(perms))
+	if len(perms) < len(agent.Permissions) {
+		source = fmt.Sprintf("%d selected permissions", len(perms))
+	}
+	content.WriteString(fmt.Sprintf("  Tools line covering %s:\n\n", source))
+	content.WriteString("  " + truncateString(toolsKeyName(agent.AgentType)+": "+strings.Join(tools, ", "), 74) + "\n\n")
+
+	switch {
+	case m.agentToolsFile == "":
+		kind := toolsFileKind(agent.AgentType)
+		content.WriteString(styles.StatusPending.Render(fmt.Sprintf("  No %s file found: built-in %ss can't be given a tools line", kind, kind)) + "\n")
+	default:
+		if strings.Contains(m.agentToolsFile, "/plugins/cache/") {
+			content.WriteString(styles.StatusPending.Render("  Plugin file: updating the plugin replaces this change") + "\n")
+		}
+		diffLines, err := parser.PreviewAgentTools(m.agentToolsFile, tools)
+		if err != nil {
+			content.WriteString(renderDiffPreviewError(m.agentToolsFile, err))
+		} else {
+			content.WriteString(renderDiffPreview(m.agentToolsFile, diffLines, diffLines == nil, 74))
+		}
+	}
+
+	content.WriteString("\n  Or allow the scoped rules in settings:\n")
+	for i, line := range strings.Split(agentSettingsSnippet(perms), "\n") {
+		// The snippet opens with 3 lines before the rules
+		if i == 3+maxSnippetRules && len(perms) > maxSnippetRules {
+			content.WriteString(styles.HelpDesc.Render(fmt.Sprintf("        … %d more", len(perms)-maxSnippetRules)) + "\n")
+		}
+		if i >= 3+maxSnippetRules && i < 3+len(perms) {
+			continue
+		}
+		content.WriteString(styles.HelpDesc.Render("  "+truncateString(line, 72)) + "\n")
+	}
+
+	content.WriteString("\n  " + renderHints(m.contextBindings()))
+
+	return content.String()
+}
+
+// openEditTools lists the tools the agent's file declares, checked, followed
+// by the tools it used without declaring them
+func (m *Model) openEditTools(agent types.AgentUsageStats) error {
+	declared, err := parser.DeclaredTools(m.agentToolsFile)
+	if err != nil {
+		return err
+	}
+
+	m.agentEditTools = append([]string(nil), declared...)
+	m.agentEditDeclared = len(declared)
+	seen := make(map[string]bool)
+	for _, t := range declared {
+		seen[t] = true
+	}
+	for _, t := range parser.ToolsForPermissions(agent.Permissions) {
+		if !seen[t] {
+			seen[t] = true
+			m.agentEditTools = append(m.agentEditTools, t)
+		}
+	}
+
+	m.agentEditChecked = make([]bool, len(m.agentEditTools))
+	for i := range declared {
+		m.agentEditChecked[i] = true
+	}
+	m.agentEditCursor = 0
+	m.agentModalMode = AgentModalModeEditTools
+	return nil
+}
+
+// editedTools returns the checked tools, in list order
+func (m Model) editedTools() []string {
+	var tools []string
+	for i, t := range m.agentEditTools {
+		if m.agentEditChecked[i] {
+			tools = append(tools, t)
+		}
+	}
+	return tools
+}
+
+// handleAgentEditToolsKeys processes keys in the agent modal's edit tools
+// mode
+func (m Model) handleAgentEditToolsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.selectedAgentIdx >= len(m.agentUsage) {
+		return m, nil
+	}
+	agent := m.agentUsage[m.selectedAgentIdx]
+
+	switch {
+	case key.Matches(msg, m.keys.Back):
+		m.agentModalMode = AgentModalModePermissions
+		return m, nil
+
+	case key.Matches(msg, m.keys.Down):
+		if m.agentEditCursor < len(m.agentEditTools)-1 {
+			m.agentEditCursor++
+		}
+		return m, nil
+
+	case key.Matches(msg, m.keys.Up):
+		if m.agentEditCursor > 0 {
+			m.agentEditCursor--
+		}
+		return m, nil
+
+	case key.Matches(msg, m.keys.Toggle):
+		if m.agentEditCursor < len(m.agentEditChecked) {
+			m.agentEditChecked[m.agentEditCursor] = !m.agentEditChecked[m.agentEditCursor]
+		}
+		return m, nil
+
+	case key.Matches(msg, m.keys.Select):
+		tools := m.editedTools()
+		if len(tools) == 0 {
+			// Without a tools key an agent inherits every tool, the
+			// opposite of unchecking them all
+			m.setLinkToast("Keep at least one tool: %s without a tools line can use every tool", article(toolsFileKind(agent.AgentType)))
+			return m, m.toastTickCmd()
+		}
+		return m.writeAgentTools(agent.AgentType, tools)
+
+	case key.Matches(msg, m.keys.ForceQuit):
+		return m, tea.Quit
+	}
+
+	return m, nil
+}
+
+// renderEditToolsMode renders the tools checklist with a diff of the
+// agent's file
+func (m Model) renderEditToolsMode(agent types.AgentUsageStats) string {
+	var content strings.Builder
+
+	used := make(map[string]int)
+	for _, p := range agent.Permissions {
+		used[p.Permission.Type] += p.Count
+	}
+
+	content.WriteString(m.renderWriteModeNotice())
+	content.WriteString(fmt.Sprintf("  Tools in the %s's frontmatter:\n\n", toolsFileKind(agent.AgentType)))
+
+	for i, t := range m.agentEditTools {
+		checkbox := "[ ]"
+		if m.agentEditChecked[i] {
+			checkbox = "[x]"
+		}
+		cursor := "  "
+		if i == m.agentEditCursor {
+			cursor = "> "
+		}
+
+		var notes []string
+		if i >= m.agentEditDeclared {
+			notes = append(notes, "not declared")
+		}
+		if n := used[parser.ParsePermission(t).Type]; n > 0 {
+			notes = append(notes, fmt.Sprintf("%d calls", n))
+		} else {
+			notes = append(notes, "never used")
+		}
+
+		line := fmt.Sprintf("%s%s %s  %s", cursor, checkbox, padRight(truncateString(t, 30), 30), strings.Join(notes, ", "))
+		if i == m.agentEditCursor {
+			content.WriteString(styles.ListItemSelected.Render(line))
+		} else {
+			content.WriteString(line)
+		}
+		content.WriteString("\n")
+	}
+	content.WriteString("\n")
+
+	if strings.Contains(m.agentToolsFile, "/plugins/cache/") {
+		content.WriteString(styles.StatusPending.Render("  Plugin file: updating the plugin replaces this change") + "\n")
+	}
+	if tools := m.editedTools(); len(tools) == 0 {
+		content.WriteString(styles.Error.Render(fmt.Sprintf("  No tools checked: %s without a tools line can use every tool", article(toolsFileKind(agent.AgentType)))) + "\n")
+	} else {
+		diffLines, err := parser.PreviewAgentTools(m.agentToolsFile, tools)
+		if err != nil {
+			content.WriteString(renderDiffPreviewError(m.agentToolsFile, err))
+		} else {
+			content.WriteString(renderDiffPreview(m.agentToolsFile, diffLines, diffLines == nil, 74))
+		}
+	}
+
+	content.WriteString("\n  " + renderHints(m.contextBindings()))
+
+	return content.String()
+}
@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// openApplyHere opens the confirm step of applying the selected permission
+// to the current project's settings, skipping the option and project
+// steps. Opened from the detail modal, Esc returns there.
+func (m Model) openApplyHere() (tea.Model, tea.Cmd) {
+	if gi, ci := m.freqCursor(); gi < len(m.permissionGroups) && ci < 0 && len(m.permissionGroups[gi].Children) > 1 {
+		m.notify(toastInfo, "Expand the group and pick a permission to apply it here")
+		return m, m.toastTickCmd()
+	}
+	perm := m.selectedPermission()
+	if perm == nil {
+		return m, nil
+	}
+	if m.projectAllowsAll(m.projectPath, []string{perm.Permission.Raw}) {
+		m.setLinkToast("Already allowed in %s", parser.ProjectSettingsPath(m.projectPath))
+		return m, m.toastTickCmd()
+	}
+	if m.showDetail {
+		m.pushNav()
+		m.navStack[len(m.navStack)-1].modalChain = true
+		m.showDetail = false
+	}
+	m.showApplyHere = true
+	return m, nil
+}
+
+// handleApplyHereKeys confirms or cancels applying to the current project
+func (m Model) handleApplyHereKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.ForceQuit):
+		return m, tea.Quit
+
+	case key.Matches(msg, m.keys.Select, m.keys.ApplyHere):
+		return m.applyHere()
+
+	case key.Matches(msg, m.keys.Back, m.keys.Quit):
+		m.closeModal()
+	}
+	return m, nil
+}
+
+// applyHere writes the selected permission to the current project's
+// settings
+func (m Model) applyHere() (tea.Model, tea.Cmd) {
+	if m.writeBlockedReason() != "" {
+		return m.writeBlocked()
+	}
+	perm := m.selectedPermission()
+	if perm == nil {
+		return m, nil
+	}
+
+	path := parser.ProjectSettingsPath(m.projectPath)
+	result, err := m.writePermission(path, perm.Permission.Raw, false)
+	if err != nil {
+		return m, m.failWrite(path, err, Model.applyHere)
+	}
+
+	if !result.DryRun {
+		m.projectSettings[m.projectPath] = append(m.projectSettings[m.projectPath], perm.Permission.Raw)
+	}
+	m.recordApply("project", result)
+	m.rememberProject(perm.Permission.Type, m.projectPath)
+	m.finishModal()
+	m.setApplyToast(result)
+	return m, m.toastTickCmd()
+}
+
+// renderApplyHereModal renders the diff applying the selected permission
+// to the current project would make, for confirmation
+func (m Model) renderApplyHereModal() string {
+	perm := m.selectedPermission()
+	if perm == nil {
+		return ""
+	}
+	modalWidth := min(max(m.width*85/100, 50), 80)
+	inner := modalWidth - 8 // border + padding + indent
+
+	var b strings.Builder
+	b.WriteString(styles.ModalTitle.Render("Apply to This Project"))
+	b.WriteString("\n\n")
+	b.WriteString("  " + styles.HelpKey.Render(truncateString(perm.Permission.Raw, inner)) + "\n")
+	b.WriteString("  " + truncateString(m.projectPath, inner) + "\n")
+	if !slices.Contains(perm.Projects, m.projectPath) {
+		b.WriteString(styles.HelpDesc.Render(fmt.Sprintf("  Not used in this project yet (%d other project(s))", len(perm.Projects))) + "\n")
+	}
+	b.WriteString("\n" + m.renderWriteModeNotice())
+
+	filePath, diffLines, allExist, err := parser.PreviewProjectDiff(m.projectPath, []string{perm.Permission.Raw})
+	if err != nil {
+		b.WriteString(renderDiffPreviewError(filePath, err))
+	} else {
+		b.WriteString(renderDiffPreview(filePath, diffLines, allExist, 74))
+	}
+	b.WriteString(m.renderCoverage([]string{perm.Permission.Raw}, m.projectPath))
+
+	b.WriteString("\n" + renderHints(m.contextBindings()))
+	return styles.Modal.Width(modalWidth).Render(b.String())
+}
@@ -0,0 +1,109 @@
+// Package audit keeps an append-only log of the settings changes made
+// through perms, for reviewing who changed what and when.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+)
+
+// Entry is one change recorded in the audit log
+type Entry struct {
+	Time       time.Time `json:"time"`
+	User       string    `json:"user"`
+	Action     string    `json:"action"`               // "allow", "deny", "remove", "restore" or "tools"
+	Permission string    `json:"permission,omitempty"` // Raw permission string; empty for a restore
+	Scope      string    `json:"scope"`                // "user" or "project"
+	File       string    `json:"file"`                 // Settings file written
+	Changed    bool      `json:"changed"`              // False if the file already had the entry
+	Note       string    `json:"note,omitempty"`       // Extra detail, e.g. the snapshot restored
+}
+
+// pathOverride replaces the default log location when set
+var pathOverride string
+
+// Path returns the audit log location (~/.claude/perms-audit.jsonl)
+func Path() string {
+	if pathOverride != "" {
+		return pathOverride
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".claude", "perms-audit.jsonl")
+}
+
+// SetPath writes the audit log to path instead of the default. An empty
+// path restores the default.
+func SetPath(path string) {
+	pathOverride = path
+}
+
+// Append adds an entry to the log, filling in the time and user if unset
+func Append(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.User == "" {
+		e.User = currentUser()
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	path := Path()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create audit log directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("open audit log: %w", err)
+	}
+	defer f.Close()
+
+	// One write per entry, so concurrent sessions never interleave lines
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write audit log: %w", err)
+	}
+	return nil
+}
+
+// Read returns every entry in the log, oldest first. Lines that don't parse
+// are skipped and counted.
+func Read() (entries []Entry, skipped int, err error) {
+	f, err := os.Open(Path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, nil
+		}
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			skipped++
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, skipped, scanner.Err()
+}
+
+// currentUser names the account making changes
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/types"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxCheckMatches bounds the matching rules listed under a check result
+const maxCheckMatches = 6
+
+// checkInvocation builds a concrete invocation to prefill the check modal
+// with: the permission's first recorded input, or its scope as a command
+func checkInvocation(perm *types.PermissionStats) string {
+	if perm == nil {
+		return ""
+	}
+	p := perm.Permission
+	if len(perm.Samples) > 0 && !strings.HasSuffix(perm.Samples[0], "...") {
+		return p.Type + "(" + perm.Samples[0] + ")"
+	}
+	if scope, ok := strings.CutSuffix(p.Scope, ":*"); ok && p.Type == "Bash" {
+		return "Bash(" + scope + ")"
+	}
+	return p.Raw
+}
+
+// openCheck opens the check modal prefilled with an invocation. Opened from
+// the detail modal, closing it returns there.
+func (m *Model) openCheck(invocation string) tea.Cmd {
+	if m.showDetail {
+		m.pushNav()
+		m.navStack[len(m.navStack)-1].modalChain = true
+		m.showDetail = false
+	}
+	m.showCheck = true
+	m.checkResult = nil
+	m.checkInput.SetValue(invocation)
+	m.checkInput.CursorEnd()
+	return m.checkInput.Focus()
+}
+
+// handleCheckKeys processes keys in the check modal. Enter checks the
+// invocation against the settings layers; every other key edits it.
+func (m Model) handleCheckKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.ForceQuit):
+		return m, tea.Quit
+
+	case msg.Type == tea.KeyEsc:
+		m.checkInput.Blur()
+		m.closeModal()
+		return m, nil
+
+	case msg.Type == tea.KeyEnter:
+		if strings.TrimSpace(m.checkInput.Value()) == "" {
+			return m, nil
+		}
+		res, warns := parser.ResolvePermission(m.checkInput.Value(), m.projectPath)
+		for _, w := range warns {
+			m.logger.Debug("settings layer skipped", "file", w.File, "reason", w.Reason)
+		}
+		m.checkResult = &res
+		return m, nil
+	}
+
+	if msg.Paste {
+		msg.Runes = sanitizePaste(msg.Runes)
+	}
+
+	var cmd tea.Cmd
+	m.checkInput, cmd = m.checkInput.Update(msg)
+	return m, cmd
+}
+
+// renderCheckModal renders the check modal: the invocation being edited and
+// the decision for it once checked
+func (m Model) renderCheckModal() string {
+	modalWidth := min(max(m.width*85/100, 50), 90)
+	width := modalWidth - 6
+
+	var b strings.Builder
+	b.WriteString(styles.ModalTitle.Render("Will This Prompt?"))
+	b.WriteString("\n\n")
+	b.WriteString(styles.HelpDesc.Render("  Tool call, e.g. Bash(git push origin main) or Read(~/.ssh/id_rsa)") + "\n")
+	m.checkInput.Width = width - 4
+	b.WriteString("  " + m.checkInput.View() + "\n\n")
+
+	if res := m.checkResult; res != nil {
+		b.WriteString("  " + decisionLabel(res.Decision) + "  " + truncateString(res.Invocation, width-10) + "\n")
+		if res.Decider != nil {
+			b.WriteString("  " + truncateString("decided by "+res.Decider.Rule, width) + "\n")
+			b.WriteString(styles.HelpDesc.Render("  "+truncateString(ruleMatchSource(*res.Decider), width)) + "\n")
+		} else {
+			b.WriteString("  " + truncateString(res.Reason, width) + "\n")
+		}
+
+		if len(res.Matches) > 1 || (len(res.Matches) == 1 && res.Decider == nil) {
+			b.WriteString("\n" + styles.HelpDesc.Render("  Matching rules") + "\n")
+			for i, rm := range res.Matches {
+				if i == maxCheckMatches {
+					b.WriteString(styles.HelpDesc.Render(fmt.Sprintf("  … %d more", len(res.Matches)-i)) + "\n")
+					break
+				}
+				line := fmt.Sprintf("%-5s %s  %s", rm.List, rm.Rule, ruleMatchSource(rm))
+				b.WriteString("  " + truncateString(line, width) + "\n")
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("  " + renderHints(m.contextBindings()))
+	return styles.Modal.Width(modalWidth).Render(b.String())
+}
+
+// decisionLabel renders a decision in the color of its outcome
+func decisionLabel(d parser.Decision) string {
+	label := strings.ToUpper(string(d))
+	switch d {
+	case parser.DecisionAllow:
+		return styles.StatusApproved.Render(label)
+	case parser.DecisionDeny:
+		return styles.Error.Render(label)
+	default:
+		return styles.StatusPending.Render(label)
+	}
+}
+
+// ruleMatchSource describes where a matching rule is, e.g.
+// "user: ~/.claude/settings.json", plus the command part it matched
+func ruleMatchSource(rm parser.RuleMatch) string {
+	s := rm.Layer.Name + ": " + shortenPath(rm.Layer.Path)
+	if rm.Command != "" {
+		s += " (for " + rm.Command + ")"
+	}
+	return s
+}
@@ -0,0 +1,61 @@
+package clipboard
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// ErrUnavailable is returned when no clipboard backend is installed
+var ErrUnavailable = errors.New("no clipboard backend available")
+
+// Backend returns the command used to write to the system clipboard
+func Backend() (name string, args []string, err error) {
+	switch runtime.GOOS {
+	case "darwin":
+		if _, err := exec.LookPath("pbcopy"); err == nil {
+			return "pbcopy", nil, nil
+		}
+	case "linux":
+		// Try wl-copy on Wayland, then xclip, then xsel
+		if _, err := exec.LookPath("wl-copy"); err == nil {
+			return "wl-copy", nil, nil
+		}
+		if _, err := exec.LookPath("xclip"); err == nil {
+			return "xclip", []string{"-selection", "clipboard"}, nil
+		}
+		if _, err := exec.LookPath("xsel"); err == nil {
+			return "xsel", []string{"--clipboard", "--input"}, nil
+		}
+	case "windows":
+		if _, err := exec.LookPath("clip"); err == nil {
+			return "clip", nil, nil
+		}
+	}
+	return "", nil, ErrUnavailable
+}
+
+// Copy writes text to the system clipboard
+func Copy(text string) error {
+	name, args, err := Backend()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(name, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start %s: %w", name, err)
+	}
+
+	_, writeErr := stdin.Write([]byte(text))
+	_ = stdin.Close()
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return writeErr
+}
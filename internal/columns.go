@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/config"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// freqColumn is a column of the Frequency view that can be hidden. The
+// permission column is always shown, before the First column.
+type freqColumn int
+
+const (
+	colAllow freqColumn = iota
+	colAuto
+	colDeny
+	colRate
+	colProjects
+	colTokens
+	colFirst
+	colLast
+	colStatus
+	freqColumnCount
+)
+
+// freqColumnNames are the config names of the columns
+var freqColumnNames = [freqColumnCount]string{"allow", "auto", "deny", "rate", "projects", "tokens", "first", "last", "status"}
+
+// freqColumnTitles head the columns
+var freqColumnTitles = [freqColumnCount]string{"Allow", "Auto", "Deny", "Rate", "Projects", "Tokens", "First", "Last", "Status"}
+
+// freqColumnDescs explain the columns in the column picker
+var freqColumnDescs = [freqColumnCount]string{
+	"uses you approved",
+	"uses run unprompted by the permission mode",
+	"uses you denied",
+	"share of decided uses approved",
+	"projects that used it",
+	"tokens of the messages that made its calls",
+	"when it was first used",
+	"when it was last used",
+	"which settings file allows it",
+}
+
+// parseFreqColumns turns config names into the columns to show. No names
+// shows every column but Projects and Tokens.
+func parseFreqColumns(names []string) ([freqColumnCount]bool, error) {
+	var on [freqColumnCount]bool
+	if names == nil {
+		for c := range on {
+			on[c] = freqColumn(c) != colProjects && freqColumn(c) != colTokens
+		}
+		return on, nil
+	}
+	for _, name := range names {
+		found := false
+		for c, n := range freqColumnNames {
+			if n == name {
+				on[c] = true
+				found = true
+			}
+		}
+		if !found {
+			return on, fmt.Errorf("unknown column %q (want %s)", name, strings.Join(freqColumnNames[:], ", "))
+		}
+	}
+	return on, nil
+}
+
+// openColumns opens the column picker
+func (m Model) openColumns() (tea.Model, tea.Cmd) {
+	m.showColumns = true
+	m.columnsCursor = 0
+	m.columnsBefore = m.freqColumns
+	return m, nil
+}
+
+// handleColumnsKeys toggles the Frequency view's columns, which change as
+// they are toggled, and saves them to the config file on close
+func (m Model) handleColumnsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.ForceQuit):
+		return m, tea.Quit
+
+	case key.Matches(msg, m.keys.Back, m.keys.Quit, m.keys.Columns):
+		return m.closeColumns()
+
+	case key.Matches(msg, m.keys.Down):
+		if m.columnsCursor < int(freqColumnCount)-1 {
+			m.columnsCursor++
+		}
+
+	case key.Matches(msg, m.keys.Up):
+		if m.columnsCursor > 0 {
+			m.columnsCursor--
+		}
+
+	case key.Matches(msg, m.keys.Toggle, m.keys.Select):
+		m.freqColumns[m.columnsCursor] = !m.freqColumns[m.columnsCursor]
+	}
+	return m, nil
+}
+
+// closeColumns closes the column picker, saving the columns if they
+// changed. Like pins, they are not saved in demo mode.
+func (m Model) closeColumns() (tea.Model, tea.Cmd) {
+	m.showColumns = false
+	if m.freqColumns == m.columnsBefore || m.demo {
+		return m, nil
+	}
+	names := []string{}
+	for c, on := range m.freqColumns {
+		if on {
+			names = append(names, freqColumnNames[c])
+		}
+	}
+	if err := config.SetColumns(names); err != nil {
+		m.logger.Debug("saving columns failed", "err", err)
+		m.notify(toastError, "Could not save the columns: %v", err)
+	} else {
+		m.notify(toastSuccess, "Saved the columns to %s", shortenPath(config.Path()))
+	}
+	return m, m.toastTickCmd()
+}
+
+// renderColumnsModal renders the column picker
+func (m Model) renderColumnsModal() string {
+	modalWidth := min(max(m.width*85/100, 50), 64)
+
+	var b strings.Builder
+	b.WriteString(styles.ModalTitle.Render("Frequency Columns"))
+	b.WriteString("\n\n")
+
+	for c := range freqColumnCount {
+		check := "[ ]"
+		if m.freqColumns[c] {
+			check = "[x]"
+		}
+		line := fmt.Sprintf("%s %-9s %s", check, freqColumnTitles[c], styles.HelpDesc.Render(freqColumnDescs[c]))
+		if int(c) == m.columnsCursor {
+			b.WriteString(styles.ListItemSelected.Render("> "+line) + "\n")
+		} else {
+			b.WriteString(styles.ListItem.Render("  "+line) + "\n")
+		}
+	}
+	b.WriteString("\n" + styles.HelpDesc.Render("  The Permission column is always shown.") + "\n")
+
+	b.WriteString("\n" + renderHints(m.contextBindings()))
+	return styles.Modal.Width(modalWidth).Render(b.String())
+}
@@ -0,0 +1,243 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/templates"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxVisibleDifferences is how many differences the comparison modal lists
+// at once
+const maxVisibleDifferences = 12
+
+// openCompare opens the comparison modal, asking what to compare user
+// settings with
+func (m *Model) openCompare() tea.Cmd {
+	m.showCompare = true
+	m.compareTheirs = ""
+	m.compareErr = ""
+	m.compareInput.SetValue("")
+	return m.compareInput.Focus()
+}
+
+// comparisonRules reads the rules input names: a template's, or those of a
+// settings file. It returns them with a label for what they are.
+func comparisonRules(input string) (parser.RuleSet, string, error) {
+	all, _ := templates.Load(templates.Dir())
+	if t, ok := templates.Find(all, input); ok {
+		return t.Rules(), "template " + t.Name, nil
+	}
+
+	path := input
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return parser.RuleSet{}, "", err
+		}
+		path = filepath.Join(home, path[1:])
+	}
+	// A missing file reads as having no rules, which is no comparison
+	if _, err := os.Stat(path); err != nil {
+		return parser.RuleSet{}, "", fmt.Errorf("no template or file %s", input)
+	}
+	allow, deny, err := parser.ReadSettingsRules(path)
+	if err != nil {
+		return parser.RuleSet{}, "", err
+	}
+	return parser.RuleSet{Allow: allow, Deny: deny}, path, nil
+}
+
+// loadComparison compares user settings with what was typed
+func (m Model) loadComparison() (tea.Model, tea.Cmd) {
+	input := strings.TrimSpace(m.compareInput.Value())
+	if input == "" {
+		return m, nil
+	}
+	theirs, label, err := comparisonRules(input)
+	if err != nil {
+		m.compareErr = err.Error()
+		return m, nil
+	}
+	allow, deny, err := parser.ReadSettingsRules(parser.UserSettingsPath())
+	if err != nil {
+		m.compareErr = err.Error()
+		return m, nil
+	}
+
+	m.compareInput.Blur()
+	m.compareTheirs = label
+	m.compareErr = ""
+	m.compareDiffs = parser.CompareRules(parser.RuleSet{Allow: allow, Deny: deny}, theirs)
+	m.compareSelected = make(map[int]bool)
+	m.compareList.top()
+	return m, nil
+}
+
+// handleCompareKeys edits what to compare with until Enter, then moves
+// through the differences, marking theirs to adopt
+func (m Model) handleCompareKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if key.Matches(msg, m.keys.ForceQuit) {
+		return m, tea.Quit
+	}
+	if m.compareTheirs == "" {
+		switch msg.Type {
+		case tea.KeyEsc:
+			m.compareInput.Blur()
+			m.showCompare = false
+			return m, nil
+		case tea.KeyEnter:
+			return m.loadComparison()
+		}
+		if msg.Paste {
+			msg.Runes = sanitizePaste(msg.Runes)
+		}
+		var cmd tea.Cmd
+		m.compareInput, cmd = m.compareInput.Update(msg)
+		return m, cmd
+	}
+
+	n := len(m.compareDiffs)
+	switch {
+	case key.Matches(msg, m.keys.Back):
+		m.compareTheirs = ""
+		return m, m.compareInput.Focus()
+
+	case key.Matches(msg, m.keys.Quit, m.keys.Compare):
+		m.showCompare = false
+
+	case key.Matches(msg, m.keys.Toggle):
+		if i := m.compareList.cursor; i < n && m.compareDiffs[i].Theirs != "" {
+			m.compareSelected[i] = !m.compareSelected[i]
+		}
+
+	case key.Matches(msg, m.keys.Apply):
+		return m.adoptSelected()
+
+	case key.Matches(msg, m.keys.Down):
+		m.compareList.down(n, maxVisibleDifferences)
+
+	case key.Matches(msg, m.keys.Up):
+		m.compareList.up(n, maxVisibleDifferences)
+
+	case key.Matches(msg, m.keys.Top):
+		m.compareList.top()
+
+	case key.Matches(msg, m.keys.Bottom):
+		m.compareList.bottom(n, maxVisibleDifferences)
+	}
+	return m, nil
+}
+
+// adoptSelected writes the marked differences' rules of theirs to user
+// settings in one write, in place of the overlapping rules of mine
+func (m Model) adoptSelected() (tea.Model, tea.Cmd) {
+	var selected []parser.RuleDifference
+	for i, d := range m.compareDiffs {
+		if m.compareSelected[i] {
+			selected = append(selected, d)
+		}
+	}
+	if len(selected) == 0 {
+		m.notify(toastInfo, "Nothing marked: press %s on their rules to adopt them", primaryKey(m.keys.Toggle))
+		return m, m.toastTickCmd()
+	}
+	if m.writeBlockedReason() != "" {
+		return m.writeBlocked()
+	}
+
+	path := parser.UserSettingsPath()
+	result, err := m.writeRuleSet(path, parser.Adopt(selected), false)
+	if err != nil {
+		return m, m.failWrite(path, err, Model.adoptSelected)
+	}
+	m.recordRuleSet("user", result)
+	if !result.DryRun {
+		m.userApproved = withRuleSet(m.userApproved, result)
+	}
+
+	summary := fmt.Sprintf("%d allowed, %d denied, %d of mine replaced", len(result.Allowed), len(result.Denied), len(result.Removed))
+	if n := len(result.Conflicts); n > 0 {
+		summary += fmt.Sprintf(", %d skipped for conflicting with my rules", n)
+	}
+	switch {
+	case result.DryRun:
+		m.notify(toastInfo, "Dry run: adopting would change %s: %s", shortenPath(path), summary)
+	default:
+		m.notify(toastSuccess, "Adopted %d rule(s) from %s: %s", len(selected), m.compareTheirs, summary)
+	}
+	m.finishModal()
+	return m, m.toastTickCmd()
+}
+
+// differenceLine describes a difference in the comparison list
+func differenceLine(d parser.RuleDifference) string {
+	switch d.Kind {
+	case parser.OnlyMine:
+		return fmt.Sprintf("mine    %-5s %s", d.List, d.Mine)
+	case parser.OnlyTheirs:
+		return fmt.Sprintf("theirs  %-5s %s", d.List, d.Theirs)
+	}
+	return fmt.Sprintf("both    %-5s %s ↔ %s", d.List, d.Mine, d.Theirs)
+}
+
+// renderCompareModal renders what to compare user settings with or, once
+// compared, the rules only one side has and those with different wildcards
+func (m Model) renderCompareModal() string {
+	modalWidth := min(max(m.width*85/100, 50), 90)
+	inner := modalWidth - 6 // border + padding
+
+	var b strings.Builder
+	b.WriteString(styles.ModalTitle.Render("Compare Settings"))
+	b.WriteString("\n\n")
+
+	if m.compareTheirs == "" {
+		b.WriteString(styles.HelpDesc.Render(truncateString("  A teammate's settings file, or the name of a template", inner)) + "\n")
+		m.compareInput.Width = inner - 4
+		b.WriteString("  " + m.compareInput.View() + "\n\n")
+		if m.compareErr != "" {
+			b.WriteString(styles.Error.Render(truncateString("  "+m.compareErr, inner)) + "\n\n")
+		}
+		b.WriteString("  " + renderHints(m.contextBindings()))
+		return styles.Modal.Width(modalWidth).Render(b.String())
+	}
+
+	b.WriteString(truncateString("  mine:   "+parser.UserSettingsPath(), inner) + "\n")
+	b.WriteString(truncateString("  theirs: "+m.compareTheirs, inner) + "\n\n")
+	if len(m.compareDiffs) == 0 {
+		b.WriteString(styles.StatusApproved.Render("  Same rules") + "\n")
+	} else {
+		counts := make(map[parser.DifferenceKind]int)
+		for _, d := range m.compareDiffs {
+			counts[d.Kind]++
+		}
+		b.WriteString(styles.HelpDesc.Render(fmt.Sprintf("  %d only in mine, %d only in theirs, %d with different wildcards",
+			counts[parser.OnlyMine], counts[parser.OnlyTheirs], counts[parser.Overlap])) + "\n\n")
+	}
+	b.WriteString(m.renderWriteModeNotice())
+
+	start, end := m.compareList.window(len(m.compareDiffs), maxVisibleDifferences)
+	for i := start; i < end; i++ {
+		d := m.compareDiffs[i]
+		line := "  " + differenceLine(d)
+		if m.compareSelected[i] {
+			line = stagedMarker + differenceLine(d)
+		}
+		if i == m.compareList.cursor {
+			b.WriteString(styles.ListItemSelected.Render(truncateString("> "+line, inner)) + "\n")
+		} else {
+			b.WriteString(truncateString("  "+line, inner) + "\n")
+		}
+	}
+	if len(m.compareDiffs) > maxVisibleDifferences {
+		b.WriteString(styles.HelpDesc.Render(fmt.Sprintf("  %d of %d", m.compareList.cursor+1, len(m.compareDiffs))) + "\n")
+	}
+
+	b.WriteString("\n" + renderHints(m.contextBindings()))
+	return styles.Modal.Width(modalWidth).Render(b.String())
+}
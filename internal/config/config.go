@@ -0,0 +1,217 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Config holds user preferences read from ~/.claude/perms-config.json
+type Config struct {
+	// Theme selects the color palette: "dark" (default), "light", "high-contrast" or "none"
+	Theme string `json:"theme,omitempty"`
+
+	// Colors overrides individual theme colors by role name
+	// (primary, secondary, success, warning, error, muted, highlight, title)
+	Colors map[string]string `json:"colors,omitempty"`
+
+	// Keys remaps key bindings by name (up, down, top, bottom, select,
+	// next_view, prev_view, filter, sort, subagents, group, pin, note, check,
+	// back, help, jump, dry_run, quit, force_quit, toggle, apply, tools,
+	// edit_tools, invocations, deny, dismiss, snapshot, restore, remove,
+	// since_review, mark_reviewed, queue, allow_user, allow_project, skip,
+	// stage, apply_staged, retry, edit_file, notifications, apply_here,
+	// recent_only, unapproved_only, denied_only, columns, scroll_left,
+	// scroll_right, page_up, page_down, half_page_up, half_page_down, center,
+	// next_match, prev_match, copy, copy_snippet, outside_writes, templates,
+	// compare, tester), as the README lists them. An empty list disables the
+	// binding.
+	Keys map[string][]string `json:"keys,omitempty"`
+
+	// Filters turns quick filters of the Frequency view on at startup:
+	// "recent" (used in the last 30 days), "unapproved" and "denied"
+	// (denied at least once)
+	Filters []string `json:"filters,omitempty"`
+
+	// Columns chooses the Frequency view's columns, in their fixed order:
+	// "allow", "auto", "deny", "rate", "projects", "tokens", "first", "last"
+	// and "status" (default all but "projects" and "tokens"). The column
+	// picker (C) saves it.
+	Columns []string `json:"columns,omitempty"`
+
+	// SkipDetails makes Enter on a permission go straight to the apply flow
+	// instead of opening the detail view first
+	SkipDetails bool `json:"skip_details,omitempty"`
+
+	// ReadOnly disables every write to settings files (apply, deny), e.g. on
+	// shared machines or when reviewing someone else's logs
+	ReadOnly bool `json:"read_only,omitempty"`
+
+	// DryRun starts with dry-run mode on: apply and deny only report what
+	// they would change
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// StaleDays is how long an allow rule can go unmatched by any tool_use
+	// before the Stale view suggests removing it (default 90)
+	StaleDays int `json:"stale_days,omitempty"`
+
+	// ProjectsDir scans session logs from a non-standard location instead of ~/.claude/projects
+	ProjectsDir string `json:"projects_dir,omitempty"`
+
+	// Store loads stats from the persistent event store
+	// (~/.claude/perms-events.db) instead of the JSON cache. The store is
+	// updated incrementally and supports ad-hoc queries over time ranges,
+	// projects and agents.
+	Store bool `json:"store,omitempty"`
+
+	// Rejection adds denial markers for non-English locales or custom hook messages
+	Rejection RejectionConfig `json:"rejection"`
+
+	// Redact adds secrets to remove from the commands, paths and prompts
+	// kept as samples, on top of the keys and tokens always removed
+	Redact RedactConfig `json:"redact"`
+
+	// Normalize controls how equivalent permission scopes are merged into one row
+	Normalize NormalizeConfig `json:"normalize"`
+
+	// Projects merges project paths that are really one project, such as
+	// worktrees and renamed checkouts
+	Projects ProjectsConfig `json:"projects"`
+
+	// Hosts labels session logs synced from several machines by path
+	// prefix, e.g. "/Users/me": "laptop", so stats can be broken down and
+	// filtered by the machine that generated them
+	Hosts map[string]string `json:"hosts,omitempty"`
+
+	// Policy is a team policy file (YAML) of forbidden, required and
+	// ask-only patterns that perms lint and the Policy view check settings
+	// against
+	Policy string `json:"policy,omitempty"`
+
+	// Notify tunes how perms watch reports permissions the settings would
+	// prompt for
+	Notify NotifyConfig `json:"notify"`
+}
+
+// RejectionConfig lists extra markers that identify a user denial in tool_result text
+type RejectionConfig struct {
+	Markers  []string `json:"markers,omitempty"`  // Plain substrings, e.g. "abgelehnt"
+	Patterns []string `json:"patterns,omitempty"` // Go regular expressions, e.g. "(?i)refus[ée]"
+}
+
+// RedactConfig lists extra secrets to redact from samples and prompts
+type RedactConfig struct {
+	Patterns []string `json:"patterns,omitempty"` // Go regular expressions, e.g. "corp-[0-9a-f]{32}"; a group named "secret" limits it to that part
+}
+
+// NormalizeConfig tunes the merging of permissions that differ only in path style
+type NormalizeConfig struct {
+	Disabled bool              `json:"disabled,omitempty"` // Count every scope exactly as logged
+	Aliases  map[string]string `json:"aliases,omitempty"`  // Extra merges, e.g. "Bash(python3:*)": "Bash(python:*)"
+}
+
+// ProjectsConfig groups project paths in counts, the Projects column and
+// apply targets
+type ProjectsConfig struct {
+	GroupByRemote bool              `json:"group_by_remote,omitempty"` // Worktrees and checkouts of one origin remote count as one
+	Aliases       map[string]string `json:"aliases,omitempty"`         // Path or glob -> the project it counts as, e.g. "/code/app-*": "/code/app"
+}
+
+// NotifyConfig chooses how new unapproved permissions are reported
+type NotifyConfig struct {
+	// Command runs through the shell for each one instead of a desktop
+	// notification, with PERMS_PERMISSION, PERMS_PROJECT, PERMS_SAMPLE and
+	// PERMS_LOG set
+	Command string `json:"command,omitempty"`
+
+	// Interval is how often logs are checked for new tool uses, e.g. "10s"
+	// (default 5s)
+	Interval string `json:"interval,omitempty"`
+}
+
+// Default returns the configuration used when no config file exists
+func Default() *Config {
+	return &Config{
+		Theme: "dark",
+	}
+}
+
+// Path returns the path to the config file
+func Path() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".claude", "perms-config.json")
+}
+
+// Load reads the config file, returning defaults if it doesn't exist
+func Load() (*Config, error) {
+	return LoadFrom(Path())
+}
+
+// LoadFrom reads a config file from a specific path
+func LoadFrom(path string) (*Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return cfg, err
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return Default(), fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// SetColumns saves the Frequency view's columns to the config file, keeping
+// its other settings as written
+func SetColumns(columns []string) error {
+	return setKey(Path(), "columns", columns)
+}
+
+// setKey sets one top-level key of the config file at path, creating the
+// file if needed, and replaces it atomically. Other keys are kept as
+// written rather than round-tripped through Config, so unknown ones and
+// left-out defaults survive.
+func setKey(path, name string, value any) error {
+	doc := map[string]json.RawMessage{}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	doc[name] = raw
+	data, err = json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/b-open-io/claude-perms/internal/clipboard"
+	"github.com/b-open-io/claude-perms/internal/insights"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// settingsSnippet renders rules as a settings.local.json permissions list,
+// ready to paste or merge into the file
+func settingsSnippet(list string, rules []string) string {
+	snippet := map[string]map[string][]string{"permissions": {list: rules}}
+	data, _ := json.MarshalIndent(snippet, "", "  ")
+	return string(data)
+}
+
+// selectedRules returns the rules of the selected row and the settings list
+// a snippet of them belongs in: the permission in the Frequency view (every
+// variant on a group's row), the rule in the Stale and Reconcile views, and
+// the offending rule in the Policy view, in the list the policy wants it.
+func (m Model) selectedRules() (rules []string, list string) {
+	switch {
+	case m.showDetail:
+		if p := m.selectedPermission(); p != nil {
+			return []string{p.Permission.Raw}, "allow"
+		}
+
+	case m.activeView == ViewFrequency:
+		gi, ci := m.freqCursor()
+		if gi >= len(m.permissionGroups) {
+			return nil, ""
+		}
+		g := m.permissionGroups[gi]
+		if ci >= 0 && ci < len(g.Children) {
+			return []string{g.Children[ci].Permission.Raw}, "allow"
+		}
+		for _, p := range g.Children {
+			rules = append(rules, p.Permission.Raw)
+		}
+		return rules, "allow"
+
+	case m.activeView == ViewStale && m.staleList.cursor < len(m.staleAllows):
+		return []string{m.staleAllows[m.staleList.cursor].Rule}, "allow"
+
+	case m.activeView == ViewReconcile && m.reconList.cursor < len(m.reconciled):
+		r := m.reconciled[m.reconList.cursor]
+		return []string{r.Rule}, r.List
+
+	case m.activeView == ViewPolicy && m.policyList.cursor < len(m.violations):
+		v := m.violations[m.policyList.cursor]
+		rule := v.Rule
+		if rule == "" {
+			rule = v.Policy.Pattern
+		}
+		list = "deny"
+		if v.Kind == insights.PolicyAskOnly {
+			list = "ask"
+		}
+		return []string{rule}, list
+	}
+	return nil, ""
+}
+
+// copySelected copies the selected row's rule to the clipboard, or with
+// snippet a settings.local.json snippet listing it
+func (m Model) copySelected(snippet bool) (tea.Model, tea.Cmd) {
+	rules, list := m.selectedRules()
+	if len(rules) == 0 {
+		m.notify(toastInfo, "No permission selected to copy")
+		return m, m.toastTickCmd()
+	}
+	if snippet {
+		what := rules[0]
+		if len(rules) > 1 {
+			what = fmt.Sprintf("%d rules", len(rules))
+		}
+		return m.copyText(settingsSnippet(list, rules), fmt.Sprintf("the %s snippet for %s", list, what))
+	}
+	if len(rules) > 1 {
+		m.notify(toastInfo, "Expand the group to copy one permission, or %s to copy all %d", primaryKey(m.keys.CopySnippet), len(rules))
+		return m, m.toastTickCmd()
+	}
+	return m.copyText(rules[0], rules[0])
+}
+
+// copyText copies text to the system clipboard, naming what it copied in
+// the toast
+func (m Model) copyText(text, what string) (tea.Model, tea.Cmd) {
+	err := clipboard.Copy(text)
+	switch {
+	case errors.Is(err, clipboard.ErrUnavailable):
+		m.notify(toastWarn, "No clipboard: install pbcopy, wl-clipboard, xclip or xsel")
+	case err != nil:
+		m.logger.Debug("copying failed", "err", err)
+		m.notify(toastError, "Could not copy: %v", err)
+	default:
+		m.notify(toastSuccess, "Copied %s", what)
+	}
+	return m, m.toastTickCmd()
+}
@@ -0,0 +1,279 @@
+// Package daemon keeps the session logs parsed in a long-running process
+// and serves their events over a unix socket, so the TUI and CLI start
+// without parsing anything.
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/store"
+	"github.com/b-open-io/claude-perms/internal/types"
+)
+
+// serviceName is the RPC service the daemon registers
+const serviceName = "Perms"
+
+// Bounds on how long a client waits for the daemon, so a wedged or busy
+// daemon doesn't stall startup; the callers parse the logs themselves then
+const (
+	dialTimeout   = 200 * time.Millisecond
+	statusTimeout = time.Second      // Status answers from memory
+	logsTimeout   = 10 * time.Second // Logs first refreshes the index, parsing new logs
+)
+
+// ErrRunning is returned when another daemon already listens on the socket
+var ErrRunning = errors.New("a perms daemon is already running")
+
+// SocketPath returns the daemon's socket, ~/.claude/perms.sock
+func SocketPath() string {
+	return filepath.Join(parser.ClaudeDir(), "perms.sock")
+}
+
+// Status describes a running daemon
+type Status struct {
+	PID         int
+	ProjectsDir string
+	Started     time.Time
+	Refreshed   time.Time // Last time the logs were checked
+	Logs        int
+	Events      int
+}
+
+// LogsReply is the answer to a Logs call
+type LogsReply struct {
+	Logs     []parser.LogEvents
+	Warnings []parser.Warning
+}
+
+// service is the daemon's RPC interface
+type service struct {
+	index   *Index
+	started time.Time
+	stop    chan struct{}
+}
+
+// Status reports the daemon's state
+func (s *service) Status(_ struct{}, reply *Status) error {
+	*reply = Status{PID: os.Getpid(), ProjectsDir: s.index.dir, Started: s.started}
+	reply.Logs, reply.Events, reply.Refreshed = s.index.Counts()
+	return nil
+}
+
+// Logs refreshes the index and returns the events matching f
+func (s *service) Logs(f store.Filter, reply *LogsReply) error {
+	if _, err := s.index.Refresh(); err != nil {
+		return err
+	}
+	reply.Logs, reply.Warnings = s.index.Logs(f)
+	return nil
+}
+
+// Stop asks the daemon to exit
+func (s *service) Stop(_ struct{}, _ *struct{}) error {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	return nil
+}
+
+// Server answers queries about an index over a unix socket
+type Server struct {
+	listener net.Listener
+	unlock   func() // Releases the lock file beside the socket
+	svc      *service
+}
+
+// Listen creates the socket at path, readable only by the current user,
+// and returns a server for index. It first locks path + ".lock" for as long
+// as the server runs, so of two daemons starting at once only one gets
+// past: the other, like one finding a live daemon answering on the socket,
+// returns ErrRunning. A socket left behind by a daemon that died is
+// replaced.
+func Listen(path string, index *Index) (*Server, error) {
+	unlock, ok, err := parser.TryLockFile(path + ".lock")
+	if err != nil {
+		return nil, fmt.Errorf("lock %s: %w", path, err)
+	}
+	if !ok {
+		return nil, ErrRunning
+	}
+	if c, err := net.DialTimeout("unix", path, dialTimeout); err == nil {
+		c.Close()
+		unlock()
+		return nil, ErrRunning
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		unlock()
+		return nil, err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		unlock()
+		return nil, err
+	}
+	return &Server{
+		listener: listener,
+		unlock:   unlock,
+		svc:      &service{index: index, started: time.Now(), stop: make(chan struct{})},
+	}, nil
+}
+
+// Serve answers connections until the listener is closed
+func (s *Server) Serve() error {
+	srv := rpc.NewServer()
+	if err := srv.RegisterName(serviceName, s.svc); err != nil {
+		return err
+	}
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go srv.ServeCodec(jsonrpc.NewServerCodec(conn))
+	}
+}
+
+// Stopped is closed when a client asks the daemon to stop
+func (s *Server) Stopped() <-chan struct{} {
+	return s.svc.stop
+}
+
+// Close stops listening, removes the socket and releases its lock
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	os.Remove(s.listener.Addr().String())
+	s.unlock()
+	return err
+}
+
+// Client talks to a running daemon
+type Client struct {
+	rpc *rpc.Client
+}
+
+// Dial connects to the daemon listening on path
+func Dial(path string) (*Client, error) {
+	conn, err := net.DialTimeout("unix", path, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{rpc: jsonrpc.NewClient(conn)}, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.rpc.Close()
+}
+
+// call calls method, giving up on an answer after timeout
+func (c *Client) call(method string, args, reply any, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case call := <-c.rpc.Go(serviceName+"."+method, args, reply, make(chan *rpc.Call, 1)).Done:
+		return call.Error
+	case <-timer.C:
+		return fmt.Errorf("no answer to %s within %s", method, timeout)
+	}
+}
+
+// Status returns the daemon's state
+func (c *Client) Status() (Status, error) {
+	var st Status
+	err := c.call("Status", struct{}{}, &st, statusTimeout)
+	return st, err
+}
+
+// Logs returns the events matching f, up to date with the logs on disk
+func (c *Client) Logs(f store.Filter) ([]parser.LogEvents, []parser.Warning, error) {
+	var reply LogsReply
+	if err := c.call("Logs", f, &reply, logsTimeout); err != nil {
+		return nil, nil, fmt.Errorf("perms daemon: %w", err)
+	}
+	return reply.Logs, reply.Warnings, nil
+}
+
+// Stop asks the daemon to exit
+func (c *Client) Stop() error {
+	return c.call("Stop", struct{}{}, &struct{}{}, statusTimeout)
+}
+
+// Source adapts a daemon client to parser.StatsSource: every load fetches
+// the daemon's events and aggregates them here, so this process's
+// normalization, project grouping and host filter apply. Loads of another
+// directory than the daemon's, e.g. the demo's, and loads the daemon
+// doesn't answer in time use the cached loaders instead.
+type Source struct {
+	Client      *Client
+	ProjectsDir string // The absolute directory the daemon indexes
+}
+
+// PermissionStats implements parser.StatsSource
+func (src Source) PermissionStats(projectsDir string, progress chan<- parser.Progress) ([]types.PermissionStats, error) {
+	if src.serves(projectsDir) {
+		logs, err := src.logs(true)
+		if err == nil {
+			return parser.AggregatePermissionStats(logs), nil
+		}
+		parser.RecordWarnings([]parser.Warning{{File: SocketPath(), Reason: err.Error() + "; logs parsed here instead"}})
+	}
+	return parser.LoadPermissionStatsFromWithCache(projectsDir, progress)
+}
+
+// AgentUsageStats implements parser.StatsSource
+func (src Source) AgentUsageStats(projectsDir string, progress chan<- parser.Progress) ([]types.AgentUsageStats, error) {
+	if src.serves(projectsDir) {
+		logs, err := src.logs(false)
+		if err == nil {
+			return parser.AggregateAgentUsage(logs), nil
+		}
+	}
+	return parser.LoadAgentUsageStatsFrom(projectsDir, progress)
+}
+
+// serves reports whether the daemon indexes projectsDir
+func (src Source) serves(projectsDir string) bool {
+	dir, err := filepath.Abs(projectsDir)
+	return err == nil && dir == src.ProjectsDir
+}
+
+// logs fetches the session logs, or every log for agent usage, which takes
+// its invocations from the session logs. Warnings are recorded with the
+// session logs only, as they cover every log.
+func (src Source) logs(sessions bool) ([]parser.LogEvents, error) {
+	all, warns, err := src.Client.Logs(store.Filter{})
+	if err != nil {
+		return nil, err
+	}
+	if sessions {
+		parser.RecordWarnings(warns)
+	}
+	if !sessions {
+		return all, nil
+	}
+	var logs []parser.LogEvents
+	for _, l := range all {
+		if l.AgentType == "" {
+			logs = append(logs, l)
+		}
+	}
+	return logs, nil
+}
@@ -0,0 +1,120 @@
+package daemon
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/store"
+)
+
+func TestServeLogs(t *testing.T) {
+	projectsDir := "../../testdata/projects"
+	index := NewIndex(projectsDir)
+	if n, err := index.Refresh(); err != nil || n == 0 {
+		t.Fatalf("Refresh = %d, %v; want the test logs parsed", n, err)
+	}
+	if n, _ := index.Refresh(); n != 0 {
+		t.Errorf("unchanged Refresh parsed %d logs", n)
+	}
+
+	// Socket paths are limited to about 100 bytes, which t.TempDir can exceed
+	dir, err := os.MkdirTemp("", "perms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "perms.sock")
+	srv, err := Listen(sock, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	go srv.Serve()
+
+	if _, err := Listen(sock, index); !errors.Is(err, ErrRunning) {
+		t.Errorf("second Listen = %v, want ErrRunning", err)
+	}
+
+	c, err := Dial(sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	got, _, err := c.Logs(store.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := parser.LoadLogEvents(projectsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("daemon returned %d logs, loader %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Project != want[i].Project || len(got[i].Events) != len(want[i].Events) {
+			t.Errorf("log %d: daemon %s with %d events, loader %s with %d", i, got[i].Project, len(got[i].Events), want[i].Project, len(want[i].Events))
+		}
+	}
+
+	st, err := c.Status()
+	if err != nil || st.Logs != len(want) || st.PID != os.Getpid() {
+		t.Errorf("Status = %+v, %v", st, err)
+	}
+	if err := c.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	<-srv.Stopped()
+}
+
+func TestListenLocksOutASecondDaemon(t *testing.T) {
+	dir, err := os.MkdirTemp("", "perms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "perms.sock")
+	index := NewIndex(t.TempDir())
+
+	srv, err := Listen(sock, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without the socket a second daemon would take it for stale; the lock
+	// still stops it
+	if err := os.Remove(sock); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Listen(sock, index); !errors.Is(err, ErrRunning) {
+		t.Errorf("Listen while locked = %v, want ErrRunning", err)
+	}
+
+	srv.Close()
+	srv, err = Listen(sock, index)
+	if err != nil {
+		t.Fatalf("Listen after Close = %v, want the lock released", err)
+	}
+	srv.Close()
+}
+
+func TestSourceLoadsOtherDirsLocally(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	projectsDir := "../../testdata/projects"
+
+	// A daemon indexing elsewhere is never asked, so no client is needed
+	src := Source{ProjectsDir: "/elsewhere/projects"}
+	got, err := src.PermissionStats(projectsDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := parser.LoadPermissionStatsFromWithCache(projectsDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) == 0 || len(got) != len(want) {
+		t.Errorf("Source returned %d permissions for another dir, the loader %d", len(got), len(want))
+	}
+}
@@ -0,0 +1,162 @@
+package daemon
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/store"
+	"github.com/b-open-io/claude-perms/internal/types"
+)
+
+// indexedLog is a parsed log and what identified its contents
+type indexedLog struct {
+	hash        string // mtime:size when parsed
+	project     string
+	agent       bool
+	invocations []types.AgentInvocation // Task calls that started subagents, from session logs
+	events      []parser.ToolEvent
+	warnings    []parser.Warning
+}
+
+// Index keeps every log under a projects directory parsed in memory,
+// re-parsing only new and changed logs on each refresh, like the event
+// store does on disk
+type Index struct {
+	dir string
+
+	refreshing sync.Mutex // Held by Refresh, the only writer of logs
+	mu         sync.RWMutex
+	logs       map[string]*indexedLog
+	refreshed  time.Time
+}
+
+// NewIndex returns an empty index of the logs under projectsDir
+func NewIndex(projectsDir string) *Index {
+	return &Index{dir: projectsDir, logs: make(map[string]*indexedLog)}
+}
+
+// Refresh brings the index up to date with the logs on disk and returns how
+// many were (re-)parsed. The logs are parsed while queries are still
+// answered from the previous state, which is swapped for the new one at the
+// end.
+func (ix *Index) Refresh() (int, error) {
+	ix.refreshing.Lock()
+	defer ix.refreshing.Unlock()
+
+	files, err := parser.ListLogFiles(ix.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+
+	// Only Refresh writes ix.logs, so it can be read here without mu
+	parsed := make(map[string]*indexedLog)
+	seen := make(map[string]bool, len(files))
+	for _, f := range files {
+		seen[f.Path] = true
+		hash, err := fileHash(f.Path)
+		if err != nil {
+			continue
+		}
+		if l, ok := ix.logs[f.Path]; ok && l.hash == hash {
+			continue
+		}
+
+		l := &indexedLog{hash: hash, project: f.Project, agent: f.Agent}
+		if f.Agent {
+			l.events, l.warnings, err = parser.ParseAgentEvents(f.Path)
+			if err != nil {
+				l.warnings = []parser.Warning{{File: f.Path, Reason: "unreadable agent log: " + err.Error()}}
+			}
+		} else {
+			l.events, l.warnings, err = parser.ParseSessionEvents(f.Path, f.Time)
+			if err != nil {
+				l.warnings = []parser.Warning{{File: f.Path, Reason: "unreadable session log: " + err.Error()}}
+			}
+			l.invocations = parser.AgentInvocations(f.Path, f.Project)
+		}
+		parsed[f.Path] = l
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	maps.Copy(ix.logs, parsed)
+	for path := range ix.logs {
+		if !seen[path] {
+			delete(ix.logs, path)
+		}
+	}
+	ix.refreshed = time.Now()
+	return len(parsed), nil
+}
+
+// Logs returns the indexed events matching f grouped by log, in the form
+// parser.LoadLogEvents returns them, with the warnings of every log
+func (ix *Index) Logs(f store.Filter) ([]parser.LogEvents, []parser.Warning) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	agentTypes := make(map[string]string)
+	for _, l := range ix.logs {
+		for _, inv := range l.invocations {
+			if inv.AgentID != "" {
+				agentTypes[inv.AgentID] = inv.AgentType
+			}
+		}
+	}
+
+	var logs []parser.LogEvents
+	var warns []parser.Warning
+	for path, l := range ix.logs {
+		warns = append(warns, l.warnings...)
+		out := parser.LogEvents{Path: path, Project: parser.CanonicalProject(l.project)}
+		if l.agent {
+			out.AgentType = agentTypes[parser.AgentID(path)]
+			if out.AgentType == "" {
+				out.AgentType = "Unknown"
+			}
+		}
+		if (f.Project != "" && out.Project != f.Project) || (f.Agent != "" && out.AgentType != f.Agent) {
+			continue
+		}
+		out.Invocations = parser.FilterInvocations(l.invocations, out.Project, f.Since, f.Until)
+		for _, e := range l.events {
+			if (!f.Since.IsZero() && e.Time.Before(f.Since)) || (!f.Until.IsZero() && !e.Time.Before(f.Until)) {
+				continue
+			}
+			out.Events = append(out.Events, e)
+		}
+		if len(out.Events) > 0 {
+			logs = append(logs, out)
+		}
+	}
+	sort.Slice(logs, func(i, j int) bool {
+		return logs[i].Path < logs[j].Path
+	})
+	parser.DropRepeatedCalls(logs)
+	return logs, warns
+}
+
+// Counts returns how many logs and events are indexed and when it was last
+// refreshed
+func (ix *Index) Counts() (logs, events int, refreshed time.Time) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	for _, l := range ix.logs {
+		events += len(l.events)
+	}
+	return len(ix.logs), events, ix.refreshed
+}
+
+// fileHash identifies a log's contents by mtime and size
+func fileHash(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size()), nil
+}
@@ -0,0 +1,43 @@
+// Package demo bundles a small set of session logs so new users can explore
+// the TUI before they have any Claude Code history of their own.
+package demo
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+//go:embed projects
+var data embed.FS
+
+// Extract writes the bundled projects directory to a temporary location and
+// returns its path. The caller removes it with os.RemoveAll when done.
+func Extract() (string, error) {
+	root, err := os.MkdirTemp("", "perms-demo-")
+	if err != nil {
+		return "", err
+	}
+
+	err = fs.WalkDir(data, "projects", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(root, filepath.FromSlash(path))
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		content, err := data.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, content, 0644)
+	})
+	if err != nil {
+		os.RemoveAll(root)
+		return "", err
+	}
+
+	return filepath.Join(root, "projects"), nil
+}
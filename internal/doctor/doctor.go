@@ -0,0 +1,366 @@
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/clipboard"
+	"github.com/b-open-io/claude-perms/internal/config"
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/mattn/go-isatty"
+	"github.com/muesli/termenv"
+)
+
+// Status is the outcome of a single check
+type Status int
+
+const (
+	StatusOK Status = iota
+	StatusWarn
+	StatusFail
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusWarn:
+		return "!"
+	case StatusFail:
+		return "✗"
+	default:
+		return "✓"
+	}
+}
+
+// Result is the outcome of a check with an actionable fix on failure
+type Result struct {
+	Name   string
+	Status Status
+	Detail string // What was found
+	Fix    string // How to resolve a warning or failure
+}
+
+// Check is a single named environment check
+type Check struct {
+	Name string
+	Run  func() Result
+}
+
+// Checks returns all environment checks in the order they are reported
+func Checks() []Check {
+	return []Check{
+		{"Claude directory", checkClaudeDir},
+		{"Projects directory", checkProjectsDir},
+		{"Session logs", checkSessionLogs},
+		{"Sessions indexes", checkSessionIndexes},
+		{"Timestamps", checkClockSkew},
+		{"User settings", checkUserSettings},
+		{"Project settings", checkProjectSettings},
+		{"Config file", checkConfig},
+		{"Cache", checkCache},
+		{"Agents & skills", checkAgentsAndSkills},
+		{"Clipboard", checkClipboard},
+		{"Terminal", checkTerminal},
+	}
+}
+
+// Run executes every check and returns the results
+func Run() []Result {
+	checks := Checks()
+	results := make([]Result, 0, len(checks))
+	for _, c := range checks {
+		r := c.Run()
+		r.Name = c.Name
+		results = append(results, r)
+	}
+	return results
+}
+
+// Failed reports whether any result is a failure
+func Failed(results []Result) bool {
+	for _, r := range results {
+		if r.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+func checkClaudeDir() Result {
+	dir := parser.ClaudeDir()
+	info, err := os.Stat(dir)
+	if err != nil {
+		return Result{
+			Status: StatusFail,
+			Detail: err.Error(),
+			Fix:    "Run Claude Code at least once so it creates ~/.claude, or check that $HOME is set correctly.",
+		}
+	}
+	if !info.IsDir() {
+		return Result{
+			Status: StatusFail,
+			Detail: dir + " is not a directory",
+			Fix:    "Move the file out of the way; Claude Code expects ~/.claude to be a directory.",
+		}
+	}
+	if _, err := os.ReadDir(dir); err != nil {
+		return Result{
+			Status: StatusFail,
+			Detail: err.Error(),
+			Fix:    "Check permissions: chmod u+rwx " + dir,
+		}
+	}
+	return Result{Status: StatusOK, Detail: dir}
+}
+
+func checkProjectsDir() Result {
+	dir := parser.ProjectsDir()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Result{
+				Status: StatusWarn,
+				Detail: dir + " does not exist",
+				Fix:    "Use Claude Code in a project to generate session logs; there is nothing to analyze yet.",
+			}
+		}
+		return Result{
+			Status: StatusFail,
+			Detail: err.Error(),
+			Fix:    "Check permissions: chmod u+rx " + dir,
+		}
+	}
+
+	projects, logs := 0, 0
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		projects++
+		files, _ := filepath.Glob(filepath.Join(dir, e.Name(), "*.jsonl"))
+		logs += len(files)
+	}
+	return Result{Status: StatusOK, Detail: fmt.Sprintf("%d projects, %d session logs", projects, logs)}
+}
+
+func checkSessionLogs() Result {
+	stats, err := parser.LoadAllPermissionStatsFrom(parser.ProjectsDir())
+	if err != nil {
+		return Result{
+			Status: StatusFail,
+			Detail: err.Error(),
+			Fix:    "Check that the files under ~/.claude/projects are readable.",
+		}
+	}
+	if len(stats) == 0 {
+		return Result{
+			Status: StatusWarn,
+			Detail: "no tool_use events found",
+			Fix:    "Use Claude Code in a project; its tool calls are logged under ~/.claude/projects.",
+		}
+	}
+	calls := 0
+	for _, s := range stats {
+		calls += s.Count
+	}
+	return Result{Status: StatusOK, Detail: fmt.Sprintf("%d permissions, %d tool calls", len(stats), calls)}
+}
+
+func checkSessionIndexes() Result {
+	problems, err := parser.CheckSessionIndexes(parser.ProjectsDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Result{Status: StatusOK, Detail: "no projects yet"}
+		}
+		return Result{Status: StatusFail, Detail: err.Error(), Fix: "Check that ~/.claude/projects is readable."}
+	}
+	if len(problems) == 0 {
+		return Result{Status: StatusOK, Detail: "every index matches its session logs"}
+	}
+
+	var details []string
+	unreadable := false
+	for _, p := range problems {
+		var issues []string
+		switch {
+		case p.Err != nil:
+			unreadable = true
+			issues = append(issues, "unreadable: "+p.Err.Error())
+		case p.Missing:
+			issues = append(issues, "no sessions-index.json, logs read directly")
+		}
+		if n := len(p.Unindexed); n > 0 {
+			issues = append(issues, fmt.Sprintf("%d unindexed", n))
+		}
+		if n := len(p.Dangling); n > 0 {
+			issues = append(issues, fmt.Sprintf("%d missing logs", n))
+		}
+		if n := len(p.Stale); n > 0 {
+			issues = append(issues, fmt.Sprintf("%d stale", n))
+		}
+		details = append(details, fmt.Sprintf("%s (%s)", p.Project, strings.Join(issues, ", ")))
+	}
+	detail := fmt.Sprintf("%d projects out of sync: %s", len(problems), strings.Join(firstN(details, 3), "; "))
+	if len(details) > 3 {
+		detail += fmt.Sprintf("; and %d more", len(details)-3)
+	}
+
+	fix := "Open the project in Claude Code to refresh its index; logs missing from an existing index are not analyzed."
+	if unreadable {
+		fix = "Delete the unreadable sessions-index.json; Claude Code rebuilds it. " + fix
+	}
+	return Result{Status: StatusWarn, Detail: detail, Fix: fix}
+}
+
+func checkClockSkew() Result {
+	skew, err := parser.CheckClockSkew(parser.ProjectsDir(), time.Now())
+	if err != nil {
+		return Result{Status: StatusFail, Detail: err.Error(), Fix: "Check that ~/.claude/projects is readable."}
+	}
+	if len(skew.Future) == 0 && len(skew.Backwards) == 0 {
+		return Result{Status: StatusOK, Detail: fmt.Sprintf("%d session logs in order", skew.Logs)}
+	}
+
+	var details []string
+	if n := len(skew.Future); n > 0 {
+		f := skew.Future[0]
+		details = append(details, fmt.Sprintf("%d logs in the future (e.g. %s:%d, %s ahead)", n, f.Path, f.Line, f.Skew.Round(time.Minute)))
+	}
+	if n := len(skew.Backwards); n > 0 {
+		b := skew.Backwards[0]
+		details = append(details, fmt.Sprintf("%d logs jump back in time (e.g. %s:%d, %s back)", n, b.Path, b.Line, b.Skew.Round(time.Minute)))
+	}
+	return Result{
+		Status: StatusWarn,
+		Detail: strings.Join(details, "; "),
+		Fix:    "Logs synced from another machine may have a wrong clock; enable NTP there. First and last seen times are taken from these timestamps.",
+	}
+}
+
+// firstN returns at most the first n items
+func firstN(items []string, n int) []string {
+	return items[:min(n, len(items))]
+}
+
+func checkSettingsFile(path string) Result {
+	allow, deny, err := parser.ValidateSettingsFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Result{Status: StatusOK, Detail: path + " (not present, created on first apply)"}
+		}
+		return Result{
+			Status: StatusFail,
+			Detail: fmt.Sprintf("%s: %v", path, err),
+			Fix:    "Fix the JSON syntax in this file; applies will refuse to overwrite it until it parses.",
+		}
+	}
+	return Result{Status: StatusOK, Detail: fmt.Sprintf("%s (%d allow, %d deny)", path, allow, deny)}
+}
+
+func checkUserSettings() Result {
+	return checkSettingsFile(parser.UserSettingsPath())
+}
+
+func checkProjectSettings() Result {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return Result{Status: StatusWarn, Detail: err.Error(), Fix: "Run perms from inside a project directory."}
+	}
+	return checkSettingsFile(parser.ProjectSettingsPath(cwd))
+}
+
+func checkConfig() Result {
+	path := config.Path()
+	if _, err := config.LoadFrom(path); err != nil {
+		return Result{
+			Status: StatusFail,
+			Detail: err.Error(),
+			Fix:    "Fix or remove " + path + "; defaults are used until it parses.",
+		}
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return Result{Status: StatusOK, Detail: "not present, using defaults"}
+	}
+	return Result{Status: StatusOK, Detail: path}
+}
+
+func checkCache() Result {
+	info := parser.InspectCache()
+	switch {
+	case info.Err != nil:
+		return Result{
+			Status: StatusFail,
+			Detail: info.Err.Error(),
+			Fix:    "Delete " + info.Path + "; it is rebuilt on the next launch.",
+		}
+	case !info.Exists:
+		return Result{Status: StatusOK, Detail: "not built yet (first launch parses all logs)"}
+	case info.Version != parser.CacheVersion():
+		return Result{
+			Status: StatusWarn,
+			Detail: fmt.Sprintf("version %d, expected %d", info.Version, parser.CacheVersion()),
+			Fix:    "No action needed; the cache is rebuilt on the next launch.",
+		}
+	}
+	return Result{
+		Status: StatusOK,
+		Detail: fmt.Sprintf("%d sessions, %d agent files, %d KB", info.Sessions, info.AgentSessions, info.Size/1024),
+	}
+}
+
+func checkAgentsAndSkills() Result {
+	agents, _ := parser.LoadAllAgents()
+	skills, _ := parser.LoadAllSkills()
+	commands, _ := parser.LoadAllCommands()
+	return Result{
+		Status: StatusOK,
+		Detail: fmt.Sprintf("%d agents, %d skills, %d commands with declared tools or permissions", len(agents), len(skills), len(commands)),
+	}
+}
+
+func checkClipboard() Result {
+	name, _, err := clipboard.Backend()
+	if err != nil {
+		return Result{
+			Status: StatusWarn,
+			Detail: err.Error(),
+			Fix:    "Install pbcopy (macOS), wl-clipboard, xclip or xsel (Linux) to enable copy actions.",
+		}
+	}
+	return Result{Status: StatusOK, Detail: name}
+}
+
+func checkTerminal() Result {
+	if !isatty.IsTerminal(os.Stdout.Fd()) && !isatty.IsCygwinTerminal(os.Stdout.Fd()) {
+		return Result{
+			Status: StatusWarn,
+			Detail: "stdout is not a terminal",
+			Fix:    "The TUI needs an interactive terminal; run perms directly rather than through a pipe.",
+		}
+	}
+
+	var details []string
+	if term := os.Getenv("TERM"); term != "" {
+		details = append(details, "TERM="+term)
+	}
+
+	profile := termenv.NewOutput(os.Stdout).ColorProfile()
+	switch profile {
+	case termenv.TrueColor:
+		details = append(details, "truecolor")
+	case termenv.ANSI256:
+		details = append(details, "256 colors")
+	case termenv.ANSI:
+		details = append(details, "16 colors")
+	default:
+		details = append(details, "no color")
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		details = append(details, "NO_COLOR set")
+	}
+
+	return Result{Status: StatusOK, Detail: strings.Join(details, ", ")}
+}
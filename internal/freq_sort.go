@@ -0,0 +1,175 @@
+package internal
+
+import (
+	"sort"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/state"
+	"github.com/b-open-io/claude-perms/internal/types"
+)
+
+// freqSort is the row order of the Frequency view
+type freqSort int
+
+const (
+	sortByUses      freqSort = iota // Most used first
+	sortByLastSeen                  // Most recently used first
+	sortByFirstSeen                 // Newest first, so tools that only just appeared stand out
+	sortByApproval                  // Lowest approval rate first, so often-denied permissions stand out
+	freqSortCount
+)
+
+var freqSortNames = [freqSortCount]string{"uses", "last seen", "first seen", "approval rate"}
+
+// sortKey holds the fields a group or permission is ordered by
+type sortKey struct {
+	pinned      bool
+	count       int
+	first, last time.Time
+	rate        float64
+	decided     bool // Whether rate means anything: some uses were approved or denied
+	name        string
+}
+
+// before reports whether a sorts ahead of b. Pinned rows always come
+// first; ties fall back to usage, then name.
+func (a sortKey) before(b sortKey, s freqSort) bool {
+	if a.pinned != b.pinned {
+		return a.pinned
+	}
+	switch s {
+	case sortByLastSeen:
+		if !a.last.Equal(b.last) {
+			return a.last.After(b.last)
+		}
+	case sortByFirstSeen:
+		if !a.first.Equal(b.first) {
+			return a.first.After(b.first)
+		}
+	case sortByApproval:
+		if a.decided != b.decided {
+			return a.decided
+		}
+		if a.rate != b.rate {
+			return a.rate < b.rate
+		}
+	}
+	if a.count != b.count {
+		return a.count > b.count
+	}
+	return a.name < b.name
+}
+
+// sortGroups orders the groups and the permissions within each group. A
+// group counts as pinned if it or any of its permissions is pinned.
+func sortGroups(groups []types.PermissionGroup, s freqSort, pins state.Pins) {
+	groupPinned := make(map[string]bool, len(groups))
+	for i := range groups {
+		children := groups[i].Children
+		groupPinned[groups[i].Type] = pins.Group(groups[i].Type)
+		for _, child := range children {
+			if pins.Permission(child.Permission.Raw) {
+				groupPinned[groups[i].Type] = true
+			}
+		}
+		sort.Slice(children, func(a, b int) bool {
+			return permSortKey(children[a], pins).before(permSortKey(children[b], pins), s)
+		})
+	}
+	sort.Slice(groups, func(a, b int) bool {
+		return groupSortKey(groups[a], groupPinned[groups[a].Type]).before(groupSortKey(groups[b], groupPinned[groups[b].Type]), s)
+	})
+}
+
+// permSortKey returns the fields a permission is ordered by
+func permSortKey(p types.PermissionStats, pins state.Pins) sortKey {
+	rate, decided := p.ApprovalRate()
+	return sortKey{pins.Permission(p.Permission.Raw), p.Count, p.FirstSeen, p.LastSeen, rate, decided, p.Permission.Raw}
+}
+
+// groupSortKey returns the fields a group is ordered by
+func groupSortKey(g types.PermissionGroup, pinned bool) sortKey {
+	rate, decided := g.ApprovalRate()
+	return sortKey{pinned, g.TotalCount, g.FirstSeen, g.LastSeen, rate, decided, g.Type}
+}
+
+// cycleFreqSort switches the Frequency view to the next sort order
+func (m *Model) cycleFreqSort() {
+	m.freqSort = (m.freqSort + 1) % freqSortCount
+	m.resortGroups()
+}
+
+// resortGroups re-sorts the Frequency view, keeping the selected group or
+// permission selected
+func (m *Model) resortGroups() {
+	var groupType, childRaw string
+	if gi, ci := m.freqCursor(); gi < len(m.permissionGroups) {
+		g := m.permissionGroups[gi]
+		groupType = g.Type
+		if ci >= 0 && ci < len(g.Children) {
+			childRaw = g.Children[ci].Permission.Raw
+		}
+	}
+
+	sortGroups(m.permissionGroups, m.freqSort, m.state.Pins)
+
+	for gi, g := range m.permissionGroups {
+		if g.Type != groupType {
+			continue
+		}
+		row := -1
+		for ci, child := range g.Children {
+			if child.Permission.Raw == childRaw {
+				row = ci
+			}
+		}
+		m.selectFreqRow(gi, row)
+		break
+	}
+}
+
+// regroupPermissions rebuilds the Frequency view's groups from the loaded
+// permissions, adding subagent tool calls when they are included. With the
+// since-review filter on, only permissions that are new or whose counts
+// changed since the last review are kept, and with quick filters on, only
+// those passing them; either way their groups are expanded so what's left
+// is in view. Otherwise expanded groups stay expanded.
+func (m *Model) regroupPermissions() {
+	expanded := make(map[string]bool)
+	for _, g := range m.permissionGroups {
+		expanded[g.Type] = g.Expanded
+	}
+
+	perms := m.permissions
+	if m.withSubagents {
+		perms = parser.AttributePermissionStats(m.permissions, m.subagentUsage)
+		for i := range perms {
+			perms[i].ApprovedAt = m.approvalLevel(perms[i].Permission.Raw, perms[i].Projects)
+		}
+	}
+	if m.sinceReview {
+		var changed []types.PermissionStats
+		for _, p := range perms {
+			if _, ok := m.state.Review.Since(p.Permission.Raw, p.Approved, p.Denied); ok {
+				changed = append(changed, p)
+			}
+		}
+		perms = changed
+	}
+	if m.quickFiltering() {
+		var kept []types.PermissionStats
+		for _, p := range perms {
+			if m.keepQuick(p) {
+				kept = append(kept, p)
+			}
+		}
+		perms = kept
+	}
+	m.permissionGroups = parser.GroupPermissions(perms)
+	for i := range m.permissionGroups {
+		m.permissionGroups[i].Expanded = m.sinceReview || m.quickFiltering() || expanded[m.permissionGroups[i].Type]
+	}
+	sortGroups(m.permissionGroups, m.freqSort, m.state.Pins)
+	m.freqList.clamp(m.freqTotalLines(), m.freqViewportHeight())
+}
@@ -0,0 +1,258 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// withDesc returns a copy of a binding with a context-specific description
+func withDesc(b key.Binding, desc string) key.Binding {
+	b.SetHelp(b.Help().Key, desc)
+	return b
+}
+
+// navBinding combines Down and Up into a single "j/k nav" hint
+func (k KeyMap) navBinding() key.Binding {
+	keys := append(append([]string{}, k.Down.Keys()...), k.Up.Keys()...)
+	return key.NewBinding(key.WithKeys(keys...), key.WithHelp(k.navKeys(), "nav"))
+}
+
+// matchBinding is the compact "n/N" hint for moving between filter matches
+func (k KeyMap) matchBinding() key.Binding {
+	keys := append(append([]string{}, k.NextMatch.Keys()...), k.PrevMatch.Keys()...)
+	return key.NewBinding(key.WithKeys(keys...), key.WithHelp(primaryKey(k.NextMatch)+"/"+primaryKey(k.PrevMatch), "matches"))
+}
+
+// contextBindings returns the actions available in the current view or modal
+// mode, with descriptions specific to that context
+func (m Model) contextBindings() []key.Binding {
+	k := m.keys
+	nav := k.navBinding()
+
+	switch {
+	case m.filtering:
+		return []key.Binding{
+			key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "keep filter")),
+			key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "done")),
+		}
+
+	case m.editingNote:
+		return []key.Binding{
+			key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "save note (empty removes it)")),
+			key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "cancel")),
+		}
+
+	case m.showCheck:
+		return []key.Binding{
+			key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "check")),
+			key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "close")),
+		}
+
+	case m.showPluginModal:
+		return []key.Binding{withDesc(k.Back, "close")}
+
+	case m.showToastLog:
+		return []key.Binding{withDesc(nav, "scroll"), withDesc(k.Back, "close")}
+
+	case m.showOutside:
+		if m.outsideProject != "" {
+			return []key.Binding{nav, withDesc(k.Back, "back")}
+		}
+		return []key.Binding{nav, withDesc(k.Select, "list files"), withDesc(k.Back, "close")}
+
+	case m.showTester:
+		return []key.Binding{
+			key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "stage the rule")),
+			key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "close")),
+		}
+
+	case m.showCompare:
+		if m.compareTheirs == "" {
+			return []key.Binding{
+				key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "compare")),
+				key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "close")),
+			}
+		}
+		return []key.Binding{nav, withDesc(k.Toggle, "mark theirs to adopt"), withDesc(k.Apply, "adopt marked"), withDesc(k.Back, "back")}
+
+	case m.showTemplates:
+		if m.templatePicked {
+			replace := "replace conflicting rules"
+			if m.templateReplace {
+				replace = "skip conflicting rules"
+			}
+			return []key.Binding{withDesc(nav, "user / project"), withDesc(k.Toggle, replace), withDesc(k.Select, "apply"), withDesc(k.Back, "back")}
+		}
+		return []key.Binding{nav, withDesc(k.Select, "preview"), withDesc(k.Back, "close")}
+
+	case m.showQueue:
+		perm := m.queuePermission()
+		if perm == nil {
+			if m.queueTally["skipped"] > 0 {
+				return []key.Binding{withDesc(k.Select, "go through skipped"), withDesc(k.Back, "close")}
+			}
+			return []key.Binding{withDesc(k.Back, "close")}
+		}
+		return []key.Binding{withDesc(k.AllowUser, "allow (user)"), withDesc(k.AllowProject, "allow in "+shortenPath(m.queueProject(perm))),
+			withDesc(k.Deny, "deny"), withDesc(k.Dismiss, "ignore"), withDesc(k.Skip, "skip"), withDesc(k.Back, "close")}
+
+	case m.showStaged:
+		return []key.Binding{nav, withDesc(k.Select, "apply"), withDesc(k.Dismiss, "clear staging"), withDesc(k.Back, "close")}
+
+	case m.showAgentModal:
+		switch m.agentModalMode {
+		case AgentModalModeScope:
+			return []key.Binding{nav, withDesc(k.Select, "select"), withDesc(k.Back, "back")}
+		case AgentModalModeProject:
+			return m.projectListBindings()
+		case AgentModalModeTools:
+			bindings := []key.Binding{withDesc(k.Tools, "copy settings snippet"), withDesc(k.Back, "back")}
+			if m.agentToolsFile != "" {
+				bindings = append([]key.Binding{withDesc(k.Select, "write tools line")}, bindings...)
+			}
+			return bindings
+		case AgentModalModeEditTools:
+			return []key.Binding{nav, k.Toggle, withDesc(k.Select, "write"), withDesc(k.Back, "back")}
+		case AgentModalModeInvocations:
+			return []key.Binding{nav, withDesc(k.Back, "back")}
+		default:
+			return []key.Binding{nav, k.Toggle, k.Apply, withDesc(k.Tools, "tools line"), withDesc(k.EditTools, "edit tools"), withDesc(k.Invocations, m.invocationsHint()), withDesc(k.Jump, "go to permission"), withDesc(k.Note, "note"), withDesc(k.Back, "close")}
+		}
+
+	case m.showDetail:
+		bindings := []key.Binding{withDesc(k.Select, "apply…")}
+		if len(m.detailAgents(m.selectedPermission())) > 0 {
+			bindings = append(bindings, nav, withDesc(k.Jump, "go to agent"))
+		}
+		return append(bindings, withDesc(k.ApplyHere, "apply here…"), withDesc(k.Note, "note"), withDesc(k.Check, "will it prompt?"), withDesc(k.Back, "close"))
+
+	case m.showSnapDiff:
+		return []key.Binding{withDesc(nav, "scroll"), k.Restore, withDesc(k.Back, "close")}
+
+	case m.showApplyHere:
+		return []key.Binding{withDesc(k.Select, "apply"), withDesc(k.Back, "cancel")}
+
+	case m.showColumns:
+		return []key.Binding{nav, withDesc(k.Toggle, "show / hide"), withDesc(k.Back, "close")}
+
+	case m.showRemoveConfirm:
+		return []key.Binding{withDesc(k.Select, "remove"), withDesc(k.Back, "cancel")}
+
+	case m.showApplyModal:
+		if m.applyModalMode == ApplyModeProjectSelect {
+			return m.projectListBindings()
+		}
+		return []key.Binding{nav, withDesc(k.Select, "confirm"), withDesc(k.Back, "cancel")}
+	}
+
+	var bindings []key.Binding
+	switch m.activeView {
+	case ViewFrequency:
+		bindings = []key.Binding{nav, withDesc(k.Select, "details"), withDesc(k.Filter, "filter")}
+		if m.searching() {
+			bindings = append(bindings, k.matchBinding())
+		}
+		bindings = append(bindings, withDesc(k.Sort, "sort"), withDesc(k.Subagents, "subagents"), withDesc(k.Queue, "review queue"), withDesc(k.Stage, "stage"))
+		if n := len(m.staged); n > 0 {
+			bindings = append(bindings, withDesc(k.ApplyStaged, fmt.Sprintf("apply %d staged", n)))
+		}
+		switch {
+		case m.sinceReview:
+			bindings = append(bindings, withDesc(k.SinceReview, "show all"), withDesc(k.MarkReviewed, "mark reviewed"))
+		case m.state.Review.Done():
+			bindings = append(bindings, withDesc(k.SinceReview, "since review"))
+		}
+	case ViewMatrix:
+		if m.matrixByPlugin {
+			bindings = []key.Binding{nav, withDesc(k.Select, "plugin"), withDesc(k.Group, "agents")}
+		} else {
+			bindings = []key.Binding{nav, withDesc(k.Select, "agent"), withDesc(k.Group, "by plugin")}
+		}
+	case ViewDiagnostics, ViewHistory:
+		bindings = []key.Binding{nav}
+	case ViewSnapshots:
+		bindings = []key.Binding{nav, withDesc(k.Select, "diff"), withDesc(k.Toggle, "mark"), withDesc(k.Snapshot, "snapshot now")}
+	case ViewStale:
+		bindings = []key.Binding{nav, withDesc(k.Remove, "remove…")}
+	case ViewReconcile, ViewPolicy:
+		bindings = []key.Binding{nav, withDesc(k.Jump, "go to permission")}
+	}
+
+	// Esc clears the filter first, then returns from a followed link
+	switch {
+	case m.filterInput.Value() != "":
+		bindings = append(bindings, withDesc(k.Back, "clear filter"))
+	case len(m.navStack) > 0:
+		bindings = append(bindings, withDesc(k.Back, "back"))
+	}
+
+	return append(bindings, withDesc(k.NextView, "view"), withDesc(k.Help, "more"), k.Quit)
+}
+
+// hintsText formats bindings as plain "key: desc" pairs for the status bar
+func hintsText(bindings []key.Binding) string {
+	var parts []string
+	for _, b := range bindings {
+		if !b.Enabled() {
+			continue
+		}
+		h := b.Help()
+		parts = append(parts, h.Key+": "+h.Desc)
+	}
+	return strings.Join(parts, "  ")
+}
+
+// renderHints formats bindings with styled keys for modal footers
+func renderHints(bindings []key.Binding) string {
+	var parts []string
+	for _, b := range bindings {
+		if !b.Enabled() {
+			continue
+		}
+		h := b.Help()
+		parts = append(parts, styles.HelpKey.Render(h.Key)+" "+h.Desc)
+	}
+	return strings.Join(parts, "  ")
+}
+
+// renderFullHelp renders the expandable full-help overlay: the actions for the
+// current context first, then every binding grouped by section
+func (m Model) renderFullHelp() string {
+	modalWidth := m.width * 85 / 100
+	if modalWidth > 80 {
+		modalWidth = 80
+	}
+	if modalWidth < 50 {
+		modalWidth = 50
+	}
+
+	var b strings.Builder
+	b.WriteString(styles.ModalTitle.Render("Keyboard Shortcuts"))
+	b.WriteString("\n")
+
+	writeSection := func(title string, bindings []key.Binding) {
+		b.WriteString(styles.ListHeader.Render(title))
+		b.WriteString("\n")
+		for _, kb := range bindings {
+			if !kb.Enabled() {
+				continue
+			}
+			h := kb.Help()
+			b.WriteString(styles.HelpKey.Render(padLeft(h.Key, 12)))
+			b.WriteString(styles.HelpDesc.Render("  " + h.Desc))
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+
+	writeSection("Here", m.contextBindings())
+	for _, section := range m.keys.helpSections() {
+		writeSection(section.title, section.bindings)
+	}
+
+	b.WriteString(renderHints([]key.Binding{withDesc(m.keys.Help, "close")}))
+
+	return styles.Modal.Width(modalWidth).Render(b.String())
+}
@@ -0,0 +1,66 @@
+package insights
+
+import (
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/types"
+)
+
+// Drift compares what an agent's frontmatter declares with what the agent
+// actually used
+type Drift struct {
+	Undeclared []types.PermissionStats // Used, but covered by neither the tools list nor an allow rule
+	Denied     []types.PermissionStats // Used although a deny rule in the frontmatter matches
+	Unused     []string                // Declared tools and allow rules no recorded call matched
+}
+
+// Empty reports whether declarations and usage agree
+func (d Drift) Empty() bool {
+	return len(d.Undeclared)+len(d.Denied)+len(d.Unused) == 0
+}
+
+// AgentDrift works out where an agent's declared tools and permission rules
+// and its recorded usage disagree. A tools entry without a scope, such as
+// "Bash", covers every use of the tool. An agent without a tools list can use
+// every tool, so only its allow rules count as declared; with neither there
+// is nothing to drift from.
+func AgentDrift(declared types.AgentPermissions, usage types.AgentUsageStats) Drift {
+	var d Drift
+	if !declared.Declares() {
+		return d
+	}
+
+	var covering []string
+	seen := make(map[string]bool)
+	for _, p := range append(append([]types.Permission{}, declared.Permissions...), declared.Allow...) {
+		if !seen[p.Raw] {
+			seen[p.Raw] = true
+			covering = append(covering, p.Raw)
+		}
+	}
+	var denying []string
+	for _, p := range declared.Deny {
+		denying = append(denying, p.Raw)
+	}
+
+	matched := make(map[string]bool)
+	for _, st := range usage.Permissions {
+		raw := st.Permission.Raw
+		if len(parser.MatchingRules(raw, denying)) > 0 {
+			d.Denied = append(d.Denied, st)
+		}
+		rules := parser.MatchingRules(raw, covering)
+		for _, r := range rules {
+			matched[r] = true
+		}
+		if len(rules) == 0 && (len(declared.Permissions) > 0 || len(declared.Allow) > 0) {
+			d.Undeclared = append(d.Undeclared, st)
+		}
+	}
+
+	for _, r := range covering {
+		if !matched[r] {
+			d.Unused = append(d.Unused, r)
+		}
+	}
+	return d
+}
@@ -0,0 +1,140 @@
+// Package insights looks for patterns in permission usage that suggest a
+// settings change, such as a permission the user keeps denying or an allow
+// rule nothing uses any more.
+package insights
+
+import (
+	"sort"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/types"
+)
+
+// Defaults for deny streak detection: three denials within a week
+const (
+	DefaultWindow    = 7 * 24 * time.Hour
+	DefaultThreshold = 3
+)
+
+// DenyStreak is a permission the user denied repeatedly within a window
+type DenyStreak struct {
+	Permission string
+	Denials    int           // Denials inside the window
+	Window     time.Duration // The window the denials were counted over
+	Last       time.Time     // Most recent denial
+}
+
+// Period describes the streak's window for display, e.g. "this week"
+func (s DenyStreak) Period() string {
+	switch {
+	case s.Window <= 24*time.Hour:
+		return "today"
+	case s.Window <= 7*24*time.Hour:
+		return "this week"
+	case s.Window <= 31*24*time.Hour:
+		return "this month"
+	default:
+		return "recently"
+	}
+}
+
+// DenyStreaks returns the permissions denied at least threshold times in the
+// window ending at now, most denied first
+func DenyStreaks(stats []types.PermissionStats, now time.Time, window time.Duration, threshold int) []DenyStreak {
+	since := now.Add(-window)
+
+	var streaks []DenyStreak
+	for _, s := range stats {
+		streak := DenyStreak{Permission: s.Permission.Raw, Window: window}
+		for _, t := range s.DeniedAt {
+			if t.Before(since) || t.After(now) {
+				continue
+			}
+			streak.Denials++
+			if t.After(streak.Last) {
+				streak.Last = t
+			}
+		}
+		if streak.Denials >= threshold {
+			streaks = append(streaks, streak)
+		}
+	}
+
+	sort.Slice(streaks, func(i, j int) bool {
+		if streaks[i].Denials != streaks[j].Denials {
+			return streaks[i].Denials > streaks[j].Denials
+		}
+		return streaks[i].Last.After(streaks[j].Last)
+	})
+	return streaks
+}
+
+// DefaultStaleWindow is how long an allow rule can go unused before it is
+// flagged as stale
+const DefaultStaleWindow = 90 * 24 * time.Hour
+
+// AllowRule is an allow entry in a settings file
+type AllowRule struct {
+	Rule    string
+	File    string // Settings file the rule is in
+	Project string // Project the file belongs to, "" for user settings
+}
+
+// StaleAllow is an allow rule no recorded tool_use matched within the window
+type StaleAllow struct {
+	AllowRule
+	LastUsed time.Time // Most recent matching use, zero if it never matched
+}
+
+// StaleAllows returns the rules that no permission in stats matched in the
+// window ending at now, never-used rules first, then least recently used.
+// A window of zero or less flags only rules that never matched.
+// Rules are matched like the approval status: against each permission and
+// the raw forms merged into it. A project rule only counts uses in its
+// project; per-project times aren't recorded, so such a use counts at the
+// permission's last use anywhere, which errs towards keeping the rule.
+func StaleAllows(rules []AllowRule, stats []types.PermissionStats, now time.Time, window time.Duration) []StaleAllow {
+	since := now.Add(-window)
+	if window <= 0 {
+		since = time.Time{}
+	}
+
+	var stale []StaleAllow
+	for _, r := range rules {
+		s := StaleAllow{AllowRule: r}
+		for _, st := range stats {
+			if r.Project != "" && st.ProjectCounts[r.Project] == 0 {
+				continue
+			}
+			if !matchesAny(st, r.Rule) {
+				continue
+			}
+			if st.LastSeen.After(s.LastUsed) {
+				s.LastUsed = st.LastSeen
+			}
+		}
+		if s.LastUsed.IsZero() || s.LastUsed.Before(since) {
+			stale = append(stale, s)
+		}
+	}
+
+	sort.SliceStable(stale, func(i, j int) bool {
+		return stale[i].LastUsed.Before(stale[j].LastUsed)
+	})
+	return stale
+}
+
+// matchesAny reports whether rule allows a permission or any raw form
+// merged into it
+func matchesAny(st types.PermissionStats, rule string) bool {
+	if len(parser.MatchingRules(st.Permission.Raw, []string{rule})) > 0 {
+		return true
+	}
+	for raw := range st.Variants {
+		if len(parser.MatchingRules(raw, []string{rule})) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+package insights
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/types"
+)
+
+// PluginRollup sums up a plugin: what its agents, skills and commands
+// declare together and what they did
+type PluginRollup struct {
+	Name     string
+	Version  string
+	Agents   []string // Agent names, without the plugin prefix
+	Skills   []string
+	Commands []string
+	Declared types.AgentPermissions // Union of the members' tools, allow and deny rules
+	Usage    types.AgentUsageStats  // Merged usage of the members, with the plugin's name as agent type
+}
+
+// Drift compares the plugin's declarations with its usage as AgentDrift
+// does for a single agent
+func (p PluginRollup) Drift() Drift {
+	return AgentDrift(p.Declared, p.Usage)
+}
+
+// PluginRollups groups declared agents, skills and commands, and the usage
+// of agents ("plugin:agent") and commands ("/plugin:command"), by plugin.
+// Definitions outside plugins and built-in agents are left out. Plugins are
+// ordered by calls, then name.
+func PluginRollups(agents []types.AgentPermissions, skills []types.SkillPermissions, commands []types.CommandPermissions, usage []types.AgentUsageStats) []PluginRollup {
+	byName := make(map[string]*PluginRollup)
+	get := func(name, version string) *PluginRollup {
+		p, ok := byName[name]
+		if !ok {
+			p = &PluginRollup{Name: name}
+			p.Usage.AgentType = name
+			byName[name] = p
+		}
+		if version != "" {
+			p.Version = version
+		}
+		return p
+	}
+
+	for _, a := range agents {
+		if a.Plugin != "" {
+			p := get(a.Plugin, a.Version)
+			p.Agents = append(p.Agents, a.Name)
+			p.Declared = mergeDeclared(p.Declared, a.Permissions, a.Allow, a.Deny)
+		}
+	}
+	for _, s := range skills {
+		if s.Plugin != "" {
+			p := get(s.Plugin, s.Version)
+			p.Skills = append(p.Skills, s.Name)
+			p.Declared = mergeDeclared(p.Declared, s.Permissions, s.Allow, s.Deny)
+		}
+	}
+	for _, c := range commands {
+		if c.Plugin != "" {
+			p := get(c.Plugin, c.Version)
+			p.Commands = append(p.Commands, c.Name)
+			p.Declared = mergeDeclared(p.Declared, c.Permissions, nil, nil)
+		}
+	}
+
+	merged := make(map[string]map[string]*types.PermissionStats)
+	for _, u := range usage {
+		plugin, _, ok := strings.Cut(strings.TrimPrefix(u.AgentType, "/"), ":")
+		if !ok {
+			continue
+		}
+		p := get(plugin, "")
+		p.Usage.TotalCalls += u.TotalCalls
+		p.Usage.TotalTokens += u.TotalTokens
+		p.Usage.Slowest = types.AddSlowCalls(p.Usage.Slowest, u.Slowest...)
+		if u.LastSeen.After(p.Usage.LastSeen) {
+			p.Usage.LastSeen = u.LastSeen
+		}
+		p.Usage.Projects = appendUnique(p.Usage.Projects, u.Projects...)
+
+		if merged[plugin] == nil {
+			merged[plugin] = make(map[string]*types.PermissionStats)
+		}
+		for _, st := range u.Permissions {
+			m, ok := merged[plugin][st.Permission.Raw]
+			if !ok {
+				st.Projects = append([]string(nil), st.Projects...)
+				merged[plugin][st.Permission.Raw] = &st
+				continue
+			}
+			m.Count += st.Count
+			m.Tokens += st.Tokens
+			m.Outside += st.Outside
+			m.Slowest = types.AddSlowCalls(m.Slowest, st.Slowest...)
+			m.Approved += st.Approved
+			m.Denied += st.Denied
+			m.AutoApproved += st.AutoApproved
+			if m.FirstSeen.IsZero() || (!st.FirstSeen.IsZero() && st.FirstSeen.Before(m.FirstSeen)) {
+				m.FirstSeen = st.FirstSeen
+			}
+			if st.LastSeen.After(m.LastSeen) {
+				m.LastSeen = st.LastSeen
+			}
+			m.Projects = appendUnique(m.Projects, st.Projects...)
+		}
+	}
+
+	rollups := make([]PluginRollup, 0, len(byName))
+	for name, p := range byName {
+		for _, st := range merged[name] {
+			p.Usage.Permissions = append(p.Usage.Permissions, *st)
+		}
+		sort.Slice(p.Usage.Permissions, func(i, j int) bool {
+			a, b := p.Usage.Permissions[i], p.Usage.Permissions[j]
+			if a.Count != b.Count {
+				return a.Count > b.Count
+			}
+			return a.Permission.Raw < b.Permission.Raw
+		})
+		rollups = append(rollups, *p)
+	}
+	sort.Slice(rollups, func(i, j int) bool {
+		if rollups[i].Usage.TotalCalls != rollups[j].Usage.TotalCalls {
+			return rollups[i].Usage.TotalCalls > rollups[j].Usage.TotalCalls
+		}
+		return rollups[i].Name < rollups[j].Name
+	})
+	return rollups
+}
+
+// mergeDeclared adds tools and rules to a union, skipping ones already in it
+func mergeDeclared(d types.AgentPermissions, tools, allow, deny []types.Permission) types.AgentPermissions {
+	d.Permissions = appendUniquePerms(d.Permissions, tools)
+	d.Allow = appendUniquePerms(d.Allow, allow)
+	d.Deny = appendUniquePerms(d.Deny, deny)
+	return d
+}
+
+// appendUniquePerms appends the permissions not already in list
+func appendUniquePerms(list, perms []types.Permission) []types.Permission {
+	for _, p := range perms {
+		found := false
+		for _, q := range list {
+			if q.Raw == p.Raw {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
+// appendUnique appends the strings not already in list
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		found := false
+		for _, s := range list {
+			if s == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, v)
+		}
+	}
+	return list
+}
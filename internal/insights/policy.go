@@ -0,0 +1,211 @@
+package insights
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+)
+
+// Policy is a team's rules for what settings files may contain, read from a
+// YAML file such as:
+//
+//	forbidden:
+//	  - pattern: Bash(curl:*)
+//	    reason: download through the proxy with fetch-artifact instead
+//	  - WebFetch
+//	required:
+//	  - pattern: Bash(rm -rf:*)
+//	ask_only:
+//	  - pattern: Bash(git push:*)
+//	    severity: warning
+type Policy struct {
+	Path string `yaml:"-"`
+
+	// Forbidden patterns may not be allowed or asked for: settings must
+	// leave them denied
+	Forbidden []PolicyRule `yaml:"forbidden"`
+
+	// Required patterns must be denied in every project, by the user
+	// settings or the project's own
+	Required []PolicyRule `yaml:"required"`
+
+	// AskOnly patterns may be asked for but not allowed outright
+	AskOnly []PolicyRule `yaml:"ask_only"`
+}
+
+// PolicyRule is one pattern in a policy. It may be written as just the
+// pattern.
+type PolicyRule struct {
+	Pattern  string   `yaml:"pattern"`
+	Reason   string   `yaml:"reason,omitempty"`
+	Severity Severity `yaml:"severity,omitempty"` // "error" (default) or "warning"
+}
+
+// UnmarshalYAML accepts a bare pattern as well as a mapping
+func (r *PolicyRule) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		r.Pattern = n.Value
+		return nil
+	}
+	type plain PolicyRule
+	return n.Decode((*plain)(r))
+}
+
+// PolicyKind is which part of a policy a violation breaks
+type PolicyKind string
+
+const (
+	PolicyForbidden PolicyKind = "forbidden"
+	PolicyRequired  PolicyKind = "required"
+	PolicyAskOnly   PolicyKind = "ask_only"
+)
+
+// Violation is a settings rule that breaks a policy, or a project missing a
+// required deny rule
+type Violation struct {
+	Kind    PolicyKind
+	Policy  PolicyRule
+	Rule    string // The offending rule; empty for a missing required rule
+	List    string // List the offending rule is in
+	File    string // Settings file with the rule, or that should have it
+	Line    int    // Line of the rule in File; 0 if unknown or missing
+	Project string // Project the file belongs to, "" for user settings
+}
+
+// Message describes the violation in one line
+func (v Violation) Message() string {
+	var msg string
+	switch v.Kind {
+	case PolicyForbidden:
+		msg = fmt.Sprintf("%s %s is forbidden by policy %s", v.List, v.Rule, v.Policy.Pattern)
+	case PolicyAskOnly:
+		msg = fmt.Sprintf("allow %s skips the prompt policy %s requires", v.Rule, v.Policy.Pattern)
+	case PolicyRequired:
+		msg = fmt.Sprintf("%s is not denied", v.Policy.Pattern)
+		if v.Project != "" {
+			msg += " in " + v.Project
+		}
+	}
+	if v.Policy.Reason != "" {
+		msg += ": " + v.Policy.Reason
+	}
+	return msg
+}
+
+// Severity is the violation's severity, error unless the policy says otherwise
+func (v Violation) Severity() Severity {
+	if v.Policy.Severity == "" {
+		return SeverityError
+	}
+	return v.Policy.Severity
+}
+
+// LoadPolicy reads and validates a policy file. A leading "~" in path is
+// the home directory.
+func LoadPolicy(path string) (*Policy, error) {
+	if rest, ok := strings.CutPrefix(path, "~"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, rest)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &Policy{Path: path}
+	if err := yaml.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for _, list := range [][]PolicyRule{p.Forbidden, p.Required, p.AskOnly} {
+		for _, r := range list {
+			if strings.TrimSpace(r.Pattern) == "" {
+				return nil, fmt.Errorf("%s: empty pattern", path)
+			}
+			if r.Severity != "" && r.Severity != SeverityError && r.Severity != SeverityWarning {
+				return nil, fmt.Errorf("%s: %s: unknown severity %q (want error or warning)", path, r.Pattern, r.Severity)
+			}
+		}
+	}
+	return p, nil
+}
+
+// Lint checks rules against the policy. Every project in projects is
+// checked for the required deny rules; with none, the user settings alone
+// must deny them.
+func (p *Policy) Lint(rules []SettingsRule, projects []string) []Violation {
+	var violations []Violation
+	lines := make(map[string][]string) // File -> its lines, read once
+	line := func(file, rule string) int {
+		if _, ok := lines[file]; !ok {
+			data, _ := os.ReadFile(file)
+			lines[file] = strings.Split(string(data), "\n")
+		}
+		return ruleLine(lines[file], rule)
+	}
+
+	for _, r := range rules {
+		for _, check := range []struct {
+			kind  PolicyKind
+			rules []PolicyRule
+			lists []string
+		}{
+			{PolicyForbidden, p.Forbidden, []string{"allow", "ask"}},
+			{PolicyAskOnly, p.AskOnly, []string{"allow"}},
+		} {
+			if !slices.Contains(check.lists, r.List) {
+				continue
+			}
+			for _, pr := range check.rules {
+				if overlaps(r.Rule, pr.Pattern) {
+					violations = append(violations, Violation{
+						Kind: check.kind, Policy: pr, Rule: r.Rule, List: r.List,
+						File: r.File, Line: line(r.File, r.Rule), Project: r.Project,
+					})
+				}
+			}
+		}
+	}
+
+	scopes := append([]string(nil), projects...)
+	sort.Strings(scopes)
+	if len(scopes) == 0 {
+		scopes = []string{""}
+	}
+	for _, pr := range p.Required {
+		for _, project := range scopes {
+			if denied(pr.Pattern, rules, project) {
+				continue
+			}
+			file := parser.UserSettingsPath()
+			if project != "" {
+				file = filepath.Join(project, ".claude", "settings.json")
+			}
+			violations = append(violations, Violation{Kind: PolicyRequired, Policy: pr, File: file, Project: project})
+		}
+	}
+	return violations
+}
+
+// overlaps reports whether a settings rule and a policy pattern cover any
+// of the same tool uses: either one matches the other
+func overlaps(rule, pattern string) bool {
+	return parser.RuleCovers(rule, pattern) || parser.RuleCovers(pattern, rule)
+}
+
+// denied reports whether a deny rule in the user settings or project's
+// settings covers all of pattern
+func denied(pattern string, rules []SettingsRule, project string) bool {
+	for _, r := range rules {
+		if r.List == "deny" && (r.Project == "" || r.Project == project) && parser.RuleCovers(r.Rule, pattern) {
+			return true
+		}
+	}
+	return false
+}
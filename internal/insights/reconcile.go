@@ -0,0 +1,165 @@
+package insights
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/audit"
+	"github.com/b-open-io/claude-perms/internal/parser"
+)
+
+// Origin is where a settings rule came from
+type Origin string
+
+const (
+	OriginPerms   Origin = "perms"   // Written with perms, per the audit log
+	OriginSession Origin = "session" // Granted inside a Claude Code session
+	OriginOutside Origin = "outside" // Neither: edited by hand or another tool
+)
+
+// SettingsRule is an allow, ask or deny entry in a settings file
+type SettingsRule struct {
+	Rule    string
+	List    string // "allow", "ask" or "deny"
+	File    string
+	Project string // Project the file belongs to, "" for user settings
+}
+
+// LoadSettingsRules lists the rules of the user settings files and both
+// settings files of each project, in that order. Unreadable files are
+// returned as warnings.
+func LoadSettingsRules(projects []string) ([]SettingsRule, []parser.Warning) {
+	var rules []SettingsRule
+	var warns []parser.Warning
+	add := func(l parser.SettingsLayer, project string) {
+		lr, err := parser.ReadLayerRules(l)
+		if err != nil {
+			warns = append(warns, parser.Warning{File: l.Path, Reason: err.Error()})
+		}
+		for _, list := range []struct {
+			name  string
+			rules []string
+		}{{"allow", lr.Allow}, {"ask", lr.Ask}, {"deny", lr.Deny}} {
+			for _, r := range list.rules {
+				rules = append(rules, SettingsRule{Rule: r, List: list.name, File: l.Path, Project: project})
+			}
+		}
+	}
+	for _, l := range parser.SettingsLayers("") {
+		if l.Name != "managed" {
+			add(l, "")
+		}
+	}
+	projects = append([]string(nil), projects...)
+	sort.Strings(projects)
+	for _, p := range projects {
+		for _, l := range parser.SettingsLayers(p) {
+			if strings.HasPrefix(l.Name, "project") {
+				add(l, p)
+			}
+		}
+	}
+	return rules, warns
+}
+
+// Reconciled is a settings rule with where it came from, or a session
+// grant that is not in any settings file
+type Reconciled struct {
+	SettingsRule
+	Origin Origin
+	Grant  *parser.Grant // Latest session grant of the rule, if any
+	Logged string        // The rule as the session logged it, when the file spells it differently
+	Absent bool          // A session grant the settings file doesn't contain
+	Added  time.Time     // When perms or the session added it; zero if unknown
+}
+
+// Explained reports whether perms wrote the rule, i.e. it needs no review
+func (r Reconciled) Explained() bool {
+	return r.Origin == OriginPerms && !r.Absent
+}
+
+// Reconcile works out where every rule in the settings files came from by
+// comparing them with the audit log and the grants found in session logs.
+// Rules are compared by their normalized form, so a grant logged as
+// "Bash(npm  test:*)" accounts for "Bash(npm test:*)" in the file. Grants
+// whose rule isn't in the file they were saved to (or that were kept for the
+// session only) are returned as absent. Rules written by perms come last,
+// then rules are ordered by file and list.
+func Reconcile(rules []SettingsRule, grants []parser.Grant, entries []audit.Entry) []Reconciled {
+	type fileRule struct{ file, list, key string }
+
+	written := make(map[fileRule]time.Time)
+	for _, e := range entries {
+		if (e.Action == "allow" || e.Action == "deny") && e.Permission != "" {
+			written[fileRule{e.File, e.Action, ruleKey(e.Permission)}] = e.Time
+		}
+	}
+
+	// Latest grant per file and rule; a later removal cancels an addition
+	latest := make(map[fileRule]parser.Grant)
+	for _, g := range grants {
+		if g.List == "" {
+			continue
+		}
+		latest[fileRule{g.File(), g.List, ruleKey(g.Rule)}] = g
+	}
+
+	present := make(map[fileRule]bool)
+	var out []Reconciled
+	for _, r := range rules {
+		k := fileRule{r.File, r.List, ruleKey(r.Rule)}
+		present[k] = true
+
+		rec := Reconciled{SettingsRule: r, Origin: OriginOutside}
+		if t, ok := written[k]; ok {
+			rec.Origin, rec.Added = OriginPerms, t
+		} else if g, ok := latest[k]; ok && g.Action == "add" {
+			rec.Origin, rec.Added = OriginSession, g.Time
+			rec.Grant = &g
+			if g.Rule != r.Rule {
+				rec.Logged = g.Rule
+			}
+		}
+		out = append(out, rec)
+	}
+
+	for k, g := range latest {
+		if present[k] || g.Action != "add" {
+			continue
+		}
+		rec := Reconciled{
+			SettingsRule: SettingsRule{Rule: g.Rule, List: g.List, File: k.file, Project: g.Project},
+			Origin:       OriginSession,
+			Grant:        &g,
+			Absent:       true,
+			Added:        g.Time,
+		}
+		out = append(out, rec)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Explained() != b.Explained() {
+			return !a.Explained()
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.List != b.List {
+			return a.List < b.List
+		}
+		return a.Rule < b.Rule
+	})
+	return out
+}
+
+// ruleKey normalizes a rule for comparison: whitespace collapsed and the
+// same normalization as the stats
+func ruleKey(rule string) string {
+	rule = strings.Join(strings.Fields(rule), " ")
+	rule = strings.ReplaceAll(rule, "( ", "(")
+	rule = strings.ReplaceAll(rule, " )", ")")
+	rule = strings.ReplaceAll(rule, ": *)", ":*)")
+	return parser.NormalizePermission(parser.ParsePermission(rule)).Raw
+}
@@ -0,0 +1,247 @@
+package insights
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+)
+
+// Severity ranks a risk, with the level names SARIF uses
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityNote    Severity = "note"
+)
+
+// RiskRule is a kind of risky permission use or settings rule
+type RiskRule struct {
+	ID          string
+	Name        string
+	Severity    Severity
+	Description string
+}
+
+// The risks perms looks for in tool uses and settings rules
+var (
+	RulePipeToShell = RiskRule{
+		ID: "pipe-to-shell", Name: "DownloadPipedToShell", Severity: SeverityError,
+		Description: "A command downloaded a script and piped it straight into a shell, running code nobody reviewed.",
+	}
+	RuleDestructiveCommand = RiskRule{
+		ID: "destructive-command", Name: "DestructiveCommand", Severity: SeverityWarning,
+		Description: "A command that can destroy data or escalate privileges ran: a recursive delete of a home or root directory, sudo, a world-writable chmod, a force push or a raw disk write.",
+	}
+	RuleWriteOutsideProject = RiskRule{
+		ID: "write-outside-project", Name: "WriteOutsideProject", Severity: SeverityWarning,
+		Description: "A file outside the session's project (and outside temporary directories) was written or edited.",
+	}
+	RuleSecretAccess = RiskRule{
+		ID: "secret-access", Name: "SecretAccess", Severity: SeverityWarning,
+		Description: "A file that usually holds credentials was read or used: .env files, SSH keys, cloud credentials or private keys.",
+	}
+	RuleBroadAllow = RiskRule{
+		ID: "broad-allow", Name: "BroadAllowRule", Severity: SeverityError,
+		Description: "An allow rule lets a tool that runs commands, edits files or fetches URLs do so with any input, without ever prompting.",
+	}
+	RuleRiskyAllow = RiskRule{
+		ID: "risky-allow", Name: "RiskyAllowRule", Severity: SeverityWarning,
+		Description: "An allow rule lets a command that can download or run arbitrary code, delete files or escalate privileges run without prompting.",
+	}
+)
+
+// RiskRules lists every rule, in the order findings are reported
+func RiskRules() []RiskRule {
+	return []RiskRule{RulePipeToShell, RuleDestructiveCommand, RuleWriteOutsideProject, RuleSecretAccess, RuleBroadAllow, RuleRiskyAllow}
+}
+
+// Risk is a risky tool use, counted over every use with the same input in a
+// project, or a risky settings rule
+type Risk struct {
+	Rule       RiskRule
+	Permission string // Normalized permission of the use, or the settings rule
+	Sample     string // The command or path used; empty for settings rules
+	Project    string
+	File       string    // Session or agent log of the latest use, or the settings file
+	Line       int       // Line of the rule in the settings file; 0 for uses
+	Count      int       // Uses with this input
+	LastSeen   time.Time // Latest use; zero for settings rules
+}
+
+var (
+	pipeToShellPattern  = regexp.MustCompile(`\b(curl|wget)\b[^|;&]*\|\s*(sudo\s+)?(ba|z|da|k)?sh\b`)
+	destructivePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`\brm\s+(-[a-zA-Z]*[rR][a-zA-Z]*\s+)+(/|~|\$HOME)(/?\*?)?(\s|$)`),
+		regexp.MustCompile(`(^|[;&|]\s*)sudo\s`),
+		regexp.MustCompile(`\bchmod\s+(-R\s+)?0?777\b`),
+		regexp.MustCompile(`\bgit\s+push\b.*\s(--force|-f)\b`),
+		regexp.MustCompile(`\bmkfs(\.\w+)?\s`),
+		regexp.MustCompile(`\bdd\s.*\bof=/dev/`),
+	}
+	secretPattern = regexp.MustCompile(`(^|[/\s"'=])(\.env(\.[\w.-]+)?|id_(rsa|dsa|ecdsa|ed25519)|[\w.-]+\.pem|\.netrc)(\s|$|["'])|/\.ssh/|/\.aws/credentials|/\.config/gcloud/|/\.docker/config\.json`)
+)
+
+// fileTools write or edit the file named in their input
+var fileTools = map[string]bool{"Write": true, "Edit": true, "MultiEdit": true, "NotebookEdit": true}
+
+// ObservedRisks checks every tool use in logs at or after since against the
+// use rules. Uses with the same rule, project and input are reported once,
+// with their count.
+func ObservedRisks(logs []parser.LogEvents, since time.Time) []Risk {
+	type key struct{ rule, project, sample string }
+	found := make(map[key]*Risk)
+	for _, l := range logs {
+		for _, e := range l.Events {
+			if e.Time.Before(since) {
+				continue
+			}
+			perm := parser.NormalizePermission(parser.ParsePermission(e.Permission))
+			for _, rule := range useRisks(perm.Type, e.Sample, l.Project) {
+				k := key{rule.ID, l.Project, e.Sample}
+				r := found[k]
+				if r == nil {
+					r = &Risk{Rule: rule, Permission: perm.Raw, Sample: e.Sample, Project: l.Project}
+					found[k] = r
+				}
+				r.Count++
+				if !e.Time.Before(r.LastSeen) {
+					r.LastSeen, r.File = e.Time, l.Path
+				}
+			}
+		}
+	}
+
+	risks := make([]Risk, 0, len(found))
+	for _, r := range found {
+		risks = append(risks, *r)
+	}
+	sortRisks(risks)
+	return risks
+}
+
+// useRisks returns the rules a tool use with the given input breaks
+func useRisks(tool, sample, project string) []RiskRule {
+	if sample == "" {
+		return nil
+	}
+	var rules []RiskRule
+	switch {
+	case tool == "Bash":
+		if pipeToShellPattern.MatchString(sample) {
+			rules = append(rules, RulePipeToShell)
+		}
+		for _, p := range destructivePatterns {
+			if p.MatchString(sample) {
+				rules = append(rules, RuleDestructiveCommand)
+				break
+			}
+		}
+	case fileTools[tool]:
+		if outsideProject(sample, project) {
+			rules = append(rules, RuleWriteOutsideProject)
+		}
+	}
+	if (tool == "Bash" || tool == "Read" || fileTools[tool]) && secretPattern.MatchString(sample) {
+		rules = append(rules, RuleSecretAccess)
+	}
+	return rules
+}
+
+// outsideProject reports whether an absolute path written in a session is
+// outside its project and the temporary directories. Relative paths are
+// taken to be inside the project.
+func outsideProject(path, project string) bool {
+	if project == "" || !filepath.IsAbs(path) {
+		return false
+	}
+	path = filepath.Clean(path)
+	for _, dir := range []string{project, os.TempDir(), "/tmp", "/private/tmp", "/var/folders"} {
+		if rel, err := filepath.Rel(dir, path); err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+			return false
+		}
+	}
+	return true
+}
+
+// riskyCommands can download or run arbitrary code, delete files or
+// escalate privileges, so allowing them outright is risky
+var riskyCommands = map[string]bool{
+	"sudo": true, "su": true, "rm": true, "curl": true, "wget": true, "sh": true, "bash": true, "zsh": true,
+	"eval": true, "exec": true, "xargs": true, "dd": true, "chmod": true, "chown": true, "ssh": true, "scp": true,
+}
+
+// broadTools run commands, edit files or fetch URLs, so allowing them with
+// any input is risky
+var broadTools = map[string]bool{
+	"Bash": true, "Write": true, "Edit": true, "MultiEdit": true, "NotebookEdit": true, "WebFetch": true,
+}
+
+// SettingsRisks checks the allow rules among rules against the settings
+// rules
+func SettingsRisks(rules []SettingsRule) []Risk {
+	var risks []Risk
+	lines := make(map[string][]string) // File -> its lines, read once
+	for _, r := range rules {
+		if r.List != "allow" {
+			continue
+		}
+		perm := parser.ParsePermission(r.Rule)
+		var rule RiskRule
+		switch {
+		case broadTools[perm.Type] && (perm.Scope == "" || perm.Scope == "*" || perm.Scope == ":*"):
+			rule = RuleBroadAllow
+		case perm.Type == "Bash" && riskyCommands[strings.Fields(strings.TrimSuffix(perm.Scope, ":*") + " ")[0]]:
+			rule = RuleRiskyAllow
+		default:
+			continue
+		}
+		if _, ok := lines[r.File]; !ok {
+			data, _ := os.ReadFile(r.File)
+			lines[r.File] = strings.Split(string(data), "\n")
+		}
+		risks = append(risks, Risk{Rule: rule, Permission: r.Rule, Project: r.Project, File: r.File, Line: ruleLine(lines[r.File], r.Rule)})
+	}
+	sortRisks(risks)
+	return risks
+}
+
+// ruleLine returns the 1-based line a rule is written on in a settings
+// file's lines, 0 if it can't be found
+func ruleLine(lines []string, rule string) int {
+	quoted, _ := json.Marshal(rule)
+	for i, line := range lines {
+		if strings.Contains(line, string(quoted)) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// sortRisks orders risks by rule, most severe first, then by how often they
+// happened
+func sortRisks(risks []Risk) {
+	order := make(map[string]int)
+	for i, r := range RiskRules() {
+		order[r.ID] = i
+	}
+	sort.SliceStable(risks, func(i, j int) bool {
+		a, b := risks[i], risks[j]
+		if a.Rule.ID != b.Rule.ID {
+			return order[a.Rule.ID] < order[b.Rule.ID]
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Sample+a.Permission < b.Sample+b.Permission
+	})
+}
@@ -0,0 +1,234 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/types"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// maxVisibleInvocations is how many invocations the agent modal lists at once
+const maxVisibleInvocations = 8
+
+// maxPromptLines caps the wrapped prompt shown under the invocation list
+const maxPromptLines = 8
+
+// recentTaskCount is how many recent tasks the agent modal header lists
+const recentTaskCount = 3
+
+// openInvocations switches the agent modal to the sessions that invoked the
+// agent, or for "Unknown" to the agent logs no Task call was matched to
+func (m Model) openInvocations(agent types.AgentUsageStats) (tea.Model, tea.Cmd) {
+	if invocationRows(agent) == 0 {
+		m.setLinkToast("No session logs record a Task call that started %s", agent.AgentType)
+		return m, m.toastTickCmd()
+	}
+	m.agentModalMode = AgentModalModeInvocations
+	m.agentInvocations.top()
+	return m, nil
+}
+
+// invocationsHint names what the invocations key lists for the agent in the
+// modal
+func (m Model) invocationsHint() string {
+	if m.selectedAgentIdx < len(m.agentUsage) && len(m.agentUsage[m.selectedAgentIdx].Invocations) == 0 && len(m.agentUsage[m.selectedAgentIdx].Orphans) > 0 {
+		return "orphan logs"
+	}
+	return "invocations"
+}
+
+// handleAgentInvocationKeys moves through the agent's invocations
+func (m Model) handleAgentInvocationKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.selectedAgentIdx >= len(m.agentUsage) {
+		return m, nil
+	}
+	n := invocationRows(m.agentUsage[m.selectedAgentIdx])
+
+	switch {
+	case key.Matches(msg, m.keys.Back, m.keys.Invocations):
+		m.agentModalMode = AgentModalModePermissions
+		return m, nil
+
+	case key.Matches(msg, m.keys.Down):
+		m.agentInvocations.down(n, maxVisibleInvocations)
+		return m, nil
+
+	case key.Matches(msg, m.keys.Up):
+		m.agentInvocations.up(n, maxVisibleInvocations)
+		return m, nil
+
+	case key.Matches(msg, m.keys.Top):
+		m.agentInvocations.top()
+		return m, nil
+
+	case key.Matches(msg, m.keys.Bottom):
+		m.agentInvocations.bottom(n, maxVisibleInvocations)
+		return m, nil
+
+	case key.Matches(msg, m.keys.ForceQuit):
+		return m, tea.Quit
+	}
+
+	return m, nil
+}
+
+// renderInvocationsMode lists the Task calls that started the agent, newest
+// first, with the prompt of the one under the cursor
+func (m Model) renderInvocationsMode(agent types.AgentUsageStats, width int) string {
+	if len(agent.Invocations) == 0 {
+		return m.renderOrphansMode(agent, width)
+	}
+
+	var content strings.Builder
+
+	content.WriteString(fmt.Sprintf("  %d invocation(s), newest first:\n\n", len(agent.Invocations)))
+
+	start, end := m.agentInvocations.window(len(agent.Invocations), maxVisibleInvocations)
+	for i := start; i < end; i++ {
+		inv := agent.Invocations[i]
+		cursor := "  "
+		if i == m.agentInvocations.cursor {
+			cursor = "> "
+		}
+		line := fmt.Sprintf("%s%-9s %-8s  %s  %s", cursor, formatRelativeTime(inv.Time), shortSession(inv.Session), inv.Project, taskSummary(inv))
+		line = truncateString(line, width)
+		if i == m.agentInvocations.cursor {
+			content.WriteString(styles.ListItemSelected.Render(line))
+		} else {
+			content.WriteString(line)
+		}
+		content.WriteString("\n")
+	}
+	if len(agent.Invocations) > maxVisibleInvocations {
+		content.WriteString(styles.HelpDesc.Render(fmt.Sprintf("  %d of %d", m.agentInvocations.cursor+1, len(agent.Invocations))) + "\n")
+	}
+
+	if m.agentInvocations.cursor < len(agent.Invocations) {
+		inv := agent.Invocations[m.agentInvocations.cursor]
+		content.WriteString("\n")
+		content.WriteString(styles.HelpDesc.Render(truncateString(fmt.Sprintf("  Session %s, %s", inv.Session, inv.Time.Local().Format("2006-01-02 15:04")), width)) + "\n")
+		content.WriteString(renderPrompt(inv.Prompt, width))
+	}
+
+	content.WriteString("\n  " + renderHints(m.contextBindings()))
+
+	return content.String()
+}
+
+// renderOrphansMode lists the agent logs no Task call was matched to,
+// newest first, with the first message of the one under the cursor
+func (m Model) renderOrphansMode(agent types.AgentUsageStats, width int) string {
+	var content strings.Builder
+
+	content.WriteString(fmt.Sprintf("  %d agent log(s) no Task call could be matched to:\n\n", len(agent.Orphans)))
+
+	start, end := m.agentInvocations.window(len(agent.Orphans), maxVisibleInvocations)
+	for i := start; i < end; i++ {
+		o := agent.Orphans[i]
+		cursor := "  "
+		if i == m.agentInvocations.cursor {
+			cursor = "> "
+		}
+		line := truncateString(fmt.Sprintf("%s%-9s %-8s  %s", cursor, formatRelativeTime(o.Start), shortSession(o.Session), o.Project), width)
+		if i == m.agentInvocations.cursor {
+			content.WriteString(styles.ListItemSelected.Render(line))
+		} else {
+			content.WriteString(line)
+		}
+		content.WriteString("\n")
+	}
+	if len(agent.Orphans) > maxVisibleInvocations {
+		content.WriteString(styles.HelpDesc.Render(fmt.Sprintf("  %d of %d", m.agentInvocations.cursor+1, len(agent.Orphans))) + "\n")
+	}
+
+	if m.agentInvocations.cursor < len(agent.Orphans) {
+		o := agent.Orphans[m.agentInvocations.cursor]
+		content.WriteString("\n")
+		content.WriteString(styles.HelpDesc.Render(truncateString("  "+o.Path, width)) + "\n")
+		content.WriteString(renderPrompt(o.Prompt, width))
+	}
+
+	content.WriteString("\n  " + renderHints(m.contextBindings()))
+
+	return content.String()
+}
+
+// invocationRows is how many rows the invocations mode lists: the agent's
+// invocations, or its orphan logs when it has none
+func invocationRows(agent types.AgentUsageStats) int {
+	if len(agent.Invocations) > 0 {
+		return len(agent.Invocations)
+	}
+	return len(agent.Orphans)
+}
+
+// renderRecentTasks lists what the agent was last asked to do: the
+// descriptions of its latest invocations, without repeats, then its slowest
+// calls
+func renderRecentTasks(agent types.AgentUsageStats, width int) string {
+	var b strings.Builder
+	if n := len(agent.Orphans); n > 0 {
+		b.WriteString(styles.StatusPending.Render(truncateString(fmt.Sprintf("  %d agent log(s) could not be matched to a Task call", n), width)) + "\n")
+	}
+	seen := make(map[string]bool)
+	for _, inv := range agent.Invocations {
+		task := taskSummary(inv)
+		if task == "" || seen[task] {
+			continue
+		}
+		seen[task] = true
+		if len(seen) == 1 {
+			b.WriteString(styles.HelpDesc.Render("  Recent tasks:") + "\n")
+		}
+		b.WriteString(truncateString(fmt.Sprintf("    · %s  %s", task, styles.HelpDesc.Render(formatRelativeTime(inv.Time))), width) + "\n")
+		if len(seen) == recentTaskCount {
+			break
+		}
+	}
+	if len(agent.Slowest) > 0 {
+		b.WriteString(styles.HelpDesc.Render("  Slowest calls:") + "\n")
+		b.WriteString(renderSlowCalls(agent.Slowest[:min(len(agent.Slowest), recentTaskCount)], true, width))
+	}
+	return b.String()
+}
+
+// taskSummary is an invocation's description, or the first line of its
+// prompt when the Task call had none
+func taskSummary(inv types.AgentInvocation) string {
+	if inv.Description != "" {
+		return inv.Description
+	}
+	first, _, _ := strings.Cut(strings.TrimSpace(inv.Prompt), "\n")
+	return first
+}
+
+// renderPrompt renders an invocation's prompt wrapped to width and indented,
+// cut after maxPromptLines lines
+func renderPrompt(prompt string, width int) string {
+	if strings.TrimSpace(prompt) == "" {
+		return styles.HelpDesc.Render("    (no prompt recorded)") + "\n"
+	}
+	lines := strings.Split(ansi.Wordwrap(strings.TrimSpace(prompt), width-4, ""), "\n")
+	if len(lines) > maxPromptLines {
+		lines = append(lines[:maxPromptLines-1], "…")
+	}
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(truncateString("    "+line, width) + "\n")
+	}
+	return b.String()
+}
+
+// shortSession abbreviates a session ID for the invocation list
+func shortSession(id string) string {
+	if id == "" {
+		return "unknown"
+	}
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
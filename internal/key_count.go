@@ -0,0 +1,180 @@
+package internal
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// countTimeout is how long typed digits wait for the motion they count.
+// After it, digits bound to keys of their own (the quick filters) run as
+// those keys, as if no count had been meant.
+const countTimeout = 600 * time.Millisecond
+
+// countTimeoutMsg ends the wait for a count's motion. seq tells the latest
+// count from those typed before it.
+type countTimeoutMsg struct {
+	seq int
+}
+
+// keyDigit returns the digit a key press types, if it is one
+func keyDigit(msg tea.KeyMsg) (int, bool) {
+	if msg.Type != tea.KeyRunes || len(msg.Runes) != 1 || msg.Runes[0] < '0' || msg.Runes[0] > '9' {
+		return 0, false
+	}
+	return int(msg.Runes[0] - '0'), true
+}
+
+// keyCount returns the count typed so far, or 0 when there is none
+func (m Model) keyCount() int {
+	n := 0
+	for _, k := range m.countKeys {
+		d, _ := keyDigit(k)
+		n = n*10 + d
+	}
+	return n
+}
+
+// pendingKeysText shows a count or prefix key waiting for the rest of its
+// sequence, as vim's showcmd does, e.g. "12" or "z"
+func (m Model) pendingKeysText() string {
+	var b strings.Builder
+	if n := m.keyCount(); n > 0 {
+		b.WriteString(strconv.Itoa(n))
+	}
+	if m.centerPending {
+		b.WriteString(primaryKey(m.keys.Center))
+	}
+	return b.String()
+}
+
+// handleKeySequence handles the vim-style sequences of the list views: a
+// count before a motion (5j, 3ctrl+d, 12G), and zz to center the cursor
+// row. It reports false for a key that is not part of a sequence, which
+// is then handled as usual once the digits before it have been.
+func (m Model) handleKeySequence(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	if m.replayingCount {
+		return m, nil, false
+	}
+
+	if m.centerPending {
+		m.centerPending = false
+		if key.Matches(msg, m.keys.Center) {
+			m.countKeys = nil
+			m.centerCursor()
+			return m, nil, true
+		}
+	}
+
+	if d, ok := keyDigit(msg); ok && (d > 0 || len(m.countKeys) > 0) {
+		m.countKeys = append(m.countKeys, msg)
+		m.countSeq++
+		seq := m.countSeq
+		return m, tea.Tick(countTimeout, func(time.Time) tea.Msg {
+			return countTimeoutMsg{seq: seq}
+		}), true
+	}
+
+	if key.Matches(msg, m.keys.Center) {
+		m.centerPending = true
+		return m, nil, true
+	}
+
+	// A top or bottom motion after a count goes to that row; swallow the
+	// second g of 5gg
+	if m.topPending {
+		m.topPending = false
+		if key.Matches(msg, m.keys.Top) {
+			return m, nil, true
+		}
+	}
+
+	count := m.keyCount()
+	if count == 0 {
+		return m, nil, false
+	}
+	digits := m.countKeys
+	m.countKeys = nil
+
+	switch {
+	case key.Matches(msg, m.keys.Down):
+		m.page(count)
+	case key.Matches(msg, m.keys.Up):
+		m.page(-count)
+	case key.Matches(msg, m.keys.PageDown):
+		m.page(count * m.pageSize())
+	case key.Matches(msg, m.keys.PageUp):
+		m.page(-count * m.pageSize())
+	case key.Matches(msg, m.keys.HalfPageDown):
+		m.page(count * max(m.pageSize()/2, 1))
+	case key.Matches(msg, m.keys.HalfPageUp):
+		m.page(-count * max(m.pageSize()/2, 1))
+	case key.Matches(msg, m.keys.Top, m.keys.Bottom):
+		m.goToRow(count - 1)
+		m.topPending = key.Matches(msg, m.keys.Top)
+	case m.searching() && key.Matches(msg, m.keys.NextMatch, m.keys.PrevMatch):
+		model, cmd := m.nextMatch(key.Matches(msg, m.keys.NextMatch), count)
+		return model, cmd, true
+	case m.activeView == ViewFrequency && key.Matches(msg, m.keys.ScrollLeft, m.keys.ScrollRight):
+		step := count * nameScrollStep
+		if key.Matches(msg, m.keys.ScrollLeft) {
+			step = -step
+		}
+		model, cmd := m.scrollName(step)
+		return model, cmd, true
+	default:
+		// Not a motion: the digits were keys of their own
+		replayed, cmd := m.replayCount(digits)
+		model, next := replayed.handleKeyboard(msg)
+		return model, tea.Batch(cmd, next), true
+	}
+	return m, nil, true
+}
+
+// flushCount handles a count whose motion never came, running its digits
+// as the keys they are bound to
+func (m Model) flushCount(msg countTimeoutMsg) (tea.Model, tea.Cmd) {
+	if msg.seq != m.countSeq || len(m.countKeys) == 0 {
+		return m, nil
+	}
+	digits := m.countKeys
+	m.countKeys = nil
+	return m.replayCount(digits)
+}
+
+// replayCount handles digits typed as a count as plain key presses, each
+// after the first dismissing the toast the one before it raised
+func (m Model) replayCount(digits []tea.KeyMsg) (Model, tea.Cmd) {
+	var cmds []tea.Cmd
+	m.replayingCount = true
+	for i, d := range digits {
+		if i > 0 && m.currentToast() != nil {
+			m.dismissToast()
+			m.toastSeq++
+			cmds = append(cmds, m.toastTickCmd())
+		}
+		next, cmd := m.handleKeyboard(d)
+		m = next.(Model)
+		cmds = append(cmds, cmd)
+	}
+	m.replayingCount = false
+	return m, tea.Batch(cmds...)
+}
+
+// goToRow moves the cursor of the active view to row i, counting the
+// Frequency view's expanded permissions as rows
+func (m *Model) goToRow(i int) {
+	if l, n, height, ok := m.viewList(m.activeView); ok {
+		l.moveTo(i, n, height)
+	}
+}
+
+// centerCursor scrolls the active view so the cursor row is in the middle
+func (m *Model) centerCursor() {
+	if l, n, height, ok := m.viewList(m.activeView); ok {
+		l.recenter(n, height)
+	}
+}
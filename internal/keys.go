@@ -0,0 +1,580 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// KeyMap defines every key binding in the TUI. Handlers match against these
+// bindings and the Help view and status bar hints are generated from them,
+// so remapping a key in config updates the documentation too.
+type KeyMap struct {
+	// Navigation
+	Up           key.Binding
+	Down         key.Binding
+	PageUp       key.Binding
+	PageDown     key.Binding
+	HalfPageUp   key.Binding
+	HalfPageDown key.Binding
+	Top          key.Binding
+	Bottom       key.Binding
+	ScrollLeft   key.Binding
+	ScrollRight  key.Binding
+	Center       key.Binding
+	NextMatch    key.Binding
+	PrevMatch    key.Binding
+
+	// Actions
+	Select    key.Binding
+	NextView  key.Binding
+	PrevView  key.Binding
+	Filter    key.Binding
+	Sort      key.Binding
+	Columns   key.Binding
+	Subagents key.Binding
+	Group     key.Binding
+	Pin       key.Binding
+	Note      key.Binding
+	Check     key.Binding
+	ApplyHere key.Binding
+	Back      key.Binding
+	Help      key.Binding
+	Jump      key.Binding
+	DryRun    key.Binding
+	Quit      key.Binding
+	ForceQuit key.Binding
+
+	Notifications key.Binding
+	OutsideWrites key.Binding
+	Templates     key.Binding
+	Compare       key.Binding
+	Tester        key.Binding
+
+	// Clipboard
+	Copy        key.Binding
+	CopySnippet key.Binding
+
+	// Agent modal
+	Toggle      key.Binding
+	Apply       key.Binding
+	Tools       key.Binding
+	EditTools   key.Binding
+	Invocations key.Binding
+
+	// Suggestions
+	Deny    key.Binding
+	Dismiss key.Binding
+
+	// Snapshots
+	Snapshot key.Binding
+	Restore  key.Binding
+
+	// Stale allows
+	Remove key.Binding
+
+	// Reviews
+	SinceReview  key.Binding
+	MarkReviewed key.Binding
+	Queue        key.Binding
+
+	// Review queue
+	AllowUser    key.Binding
+	AllowProject key.Binding
+	Skip         key.Binding
+
+	// Quick filters
+	RecentOnly     key.Binding
+	UnapprovedOnly key.Binding
+	DeniedOnly     key.Binding
+
+	// Staging
+	Stage       key.Binding
+	ApplyStaged key.Binding
+
+	// Failed writes
+	Retry    key.Binding
+	EditFile key.Binding
+}
+
+// DefaultKeyMap returns the built-in key bindings
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		Up: key.NewBinding(
+			key.WithKeys("k", "up"),
+			key.WithHelp("k/↑", "move up"),
+		),
+		Down: key.NewBinding(
+			key.WithKeys("j", "down"),
+			key.WithHelp("j/↓", "move down"),
+		),
+		PageUp: key.NewBinding(
+			key.WithKeys("pgup"),
+			key.WithHelp("pgup", "page up"),
+		),
+		PageDown: key.NewBinding(
+			key.WithKeys("pgdown"),
+			key.WithHelp("pgdn", "page down"),
+		),
+		HalfPageUp: key.NewBinding(
+			key.WithKeys("ctrl+u"),
+			key.WithHelp("ctrl+u", "half a page up"),
+		),
+		HalfPageDown: key.NewBinding(
+			key.WithKeys("ctrl+d"),
+			key.WithHelp("ctrl+d", "half a page down"),
+		),
+		Top: key.NewBinding(
+			key.WithKeys("g", "home"),
+			key.WithHelp("g/home", "go to first"),
+		),
+		Bottom: key.NewBinding(
+			key.WithKeys("G", "end"),
+			key.WithHelp("G/end", "go to last"),
+		),
+		ScrollLeft: key.NewBinding(
+			key.WithKeys("h", "left"),
+			key.WithHelp("h/←", "scroll a long permission name back"),
+		),
+		ScrollRight: key.NewBinding(
+			key.WithKeys("l", "right"),
+			key.WithHelp("l/→", "scroll a long permission name on"),
+		),
+		Center: key.NewBinding(
+			key.WithKeys("z"),
+			key.WithHelp("zz", "center the cursor row"),
+		),
+		NextMatch: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "next filter match"),
+		),
+		PrevMatch: key.NewBinding(
+			key.WithKeys("N"),
+			key.WithHelp("N", "previous filter match"),
+		),
+		Select: key.NewBinding(
+			key.WithKeys("enter", " "),
+			key.WithHelp("enter", "expand group / open details"),
+		),
+		NextView: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "next view"),
+		),
+		PrevView: key.NewBinding(
+			key.WithKeys("shift+tab"),
+			key.WithHelp("shift+tab", "previous view"),
+		),
+		Filter: key.NewBinding(
+			key.WithKeys("/"),
+			key.WithHelp("/", "filter permissions"),
+		),
+		Sort: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "cycle sort: uses / last / first seen / approval"),
+		),
+		Columns: key.NewBinding(
+			key.WithKeys("C"),
+			key.WithHelp("C", "choose the Frequency columns"),
+		),
+		Subagents: key.NewBinding(
+			key.WithKeys("i"),
+			key.WithHelp("i", "include / exclude subagent tool calls"),
+		),
+		Group: key.NewBinding(
+			key.WithKeys("P"),
+			key.WithHelp("P", "group the Matrix by plugin / list agents"),
+		),
+		Pin: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("p", "pin / unpin to the top of the list"),
+		),
+		Note: key.NewBinding(
+			key.WithKeys("N"),
+			key.WithHelp("N", "add / edit a note on a permission or agent"),
+		),
+		Check: key.NewBinding(
+			key.WithKeys("c"),
+			key.WithHelp("c", "check whether a tool call would prompt"),
+		),
+		Copy: key.NewBinding(
+			key.WithKeys("y"),
+			key.WithHelp("y", "copy the permission"),
+		),
+		CopySnippet: key.NewBinding(
+			key.WithKeys("Y"),
+			key.WithHelp("Y", "copy a settings.local.json snippet for it"),
+		),
+		ApplyHere: key.NewBinding(
+			key.WithKeys("P"),
+			key.WithHelp("P", "apply a permission to the current project…"),
+		),
+		Back: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "clear filter / close / back"),
+		),
+		Help: key.NewBinding(
+			key.WithKeys("?"),
+			key.WithHelp("?", "toggle full help"),
+		),
+		Jump: key.NewBinding(
+			key.WithKeys("o"),
+			key.WithHelp("o", "go to linked agent / permission"),
+		),
+		DryRun: key.NewBinding(
+			key.WithKeys("D"),
+			key.WithHelp("D", "toggle dry run (apply writes nothing)"),
+		),
+		Quit: key.NewBinding(
+			key.WithKeys("q"),
+			key.WithHelp("q", "quit"),
+		),
+		ForceQuit: key.NewBinding(
+			key.WithKeys("ctrl+c"),
+			key.WithHelp("ctrl+c", "quit immediately"),
+		),
+		Notifications: key.NewBinding(
+			key.WithKeys("ctrl+l"),
+			key.WithHelp("ctrl+l", "show this session's notifications"),
+		),
+		OutsideWrites: key.NewBinding(
+			key.WithKeys("W"),
+			key.WithHelp("W", "list files modified outside their workspace"),
+		),
+		Templates: key.NewBinding(
+			key.WithKeys("T"),
+			key.WithHelp("T", "apply a settings template"),
+		),
+		Compare: key.NewBinding(
+			key.WithKeys("X"),
+			key.WithHelp("X", "compare settings with a teammate's or a template"),
+		),
+		Tester: key.NewBinding(
+			key.WithKeys("w"),
+			key.WithHelp("w", "test a candidate rule against recorded calls"),
+		),
+		Toggle: key.NewBinding(
+			key.WithKeys(" "),
+			key.WithHelp("space", "toggle selection"),
+		),
+		Apply: key.NewBinding(
+			key.WithKeys("a", "A"),
+			key.WithHelp("a", "apply selected"),
+		),
+		Tools: key.NewBinding(
+			key.WithKeys("t"),
+			key.WithHelp("t", "generate the agent's tools line from its usage"),
+		),
+		EditTools: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", "add / remove tools in the agent's frontmatter"),
+		),
+		Invocations: key.NewBinding(
+			key.WithKeys("S"),
+			key.WithHelp("S", "list the sessions that invoked the agent"),
+		),
+		Deny: key.NewBinding(
+			key.WithKeys("d"),
+			key.WithHelp("d", "add suggested permission to deny list"),
+		),
+		Dismiss: key.NewBinding(
+			key.WithKeys("x"),
+			key.WithHelp("x", "dismiss suggestion"),
+		),
+		Snapshot: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "snapshot settings now"),
+		),
+		Restore: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "restore this version"),
+		),
+		Remove: key.NewBinding(
+			key.WithKeys("R", "delete"),
+			key.WithHelp("R/del", "remove stale allow rule from settings…"),
+		),
+		SinceReview: key.NewBinding(
+			key.WithKeys("v"),
+			key.WithHelp("v", "show only what changed since the last review"),
+		),
+		MarkReviewed: key.NewBinding(
+			key.WithKeys("V"),
+			key.WithHelp("V", "mark the current counts as reviewed"),
+		),
+		Queue: key.NewBinding(
+			key.WithKeys("I"),
+			key.WithHelp("I", "review unapproved permissions one at a time"),
+		),
+		AllowUser: key.NewBinding(
+			key.WithKeys("u"),
+			key.WithHelp("u", "allow in user settings"),
+		),
+		AllowProject: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("p", "allow in the project's settings"),
+		),
+		Skip: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "skip for now"),
+		),
+		RecentOnly: key.NewBinding(
+			key.WithKeys("1"),
+			key.WithHelp("1", "only permissions used in the last 30 days"),
+		),
+		UnapprovedOnly: key.NewBinding(
+			key.WithKeys("2"),
+			key.WithHelp("2", "only permissions no settings file allows"),
+		),
+		DeniedOnly: key.NewBinding(
+			key.WithKeys("3"),
+			key.WithHelp("3", "only permissions denied at least once"),
+		),
+		Stage: key.NewBinding(
+			key.WithKeys("m"),
+			key.WithHelp("m", "stage / unstage a permission or group for a batch apply"),
+		),
+		ApplyStaged: key.NewBinding(
+			key.WithKeys("M"),
+			key.WithHelp("M", "apply the staged permissions…"),
+		),
+		Retry: key.NewBinding(
+			key.WithKeys("ctrl+r"),
+			key.WithHelp("ctrl+r", "retry a failed settings write"),
+		),
+		EditFile: key.NewBinding(
+			key.WithKeys("E"),
+			key.WithHelp("E", "open the settings file of a failed write in $EDITOR"),
+		),
+	}
+}
+
+// bindingsByName maps config names to the bindings they remap
+func (k *KeyMap) bindingsByName() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"up":          &k.Up,
+		"down":        &k.Down,
+		"top":         &k.Top,
+		"bottom":      &k.Bottom,
+		"select":      &k.Select,
+		"next_view":   &k.NextView,
+		"prev_view":   &k.PrevView,
+		"filter":      &k.Filter,
+		"sort":        &k.Sort,
+		"subagents":   &k.Subagents,
+		"group":       &k.Group,
+		"pin":         &k.Pin,
+		"note":        &k.Note,
+		"check":       &k.Check,
+		"back":        &k.Back,
+		"help":        &k.Help,
+		"jump":        &k.Jump,
+		"dry_run":     &k.DryRun,
+		"quit":        &k.Quit,
+		"force_quit":  &k.ForceQuit,
+		"toggle":      &k.Toggle,
+		"apply":       &k.Apply,
+		"tools":       &k.Tools,
+		"edit_tools":  &k.EditTools,
+		"invocations": &k.Invocations,
+		"deny":        &k.Deny,
+		"dismiss":     &k.Dismiss,
+		"snapshot":    &k.Snapshot,
+		"restore":     &k.Restore,
+		"remove":      &k.Remove,
+
+		"since_review":  &k.SinceReview,
+		"mark_reviewed": &k.MarkReviewed,
+		"queue":         &k.Queue,
+		"allow_user":    &k.AllowUser,
+		"allow_project": &k.AllowProject,
+		"skip":          &k.Skip,
+		"stage":         &k.Stage,
+		"apply_staged":  &k.ApplyStaged,
+		"retry":         &k.Retry,
+		"edit_file":     &k.EditFile,
+		"notifications": &k.Notifications,
+		"apply_here":    &k.ApplyHere,
+
+		"recent_only":     &k.RecentOnly,
+		"unapproved_only": &k.UnapprovedOnly,
+		"denied_only":     &k.DeniedOnly,
+		"columns":         &k.Columns,
+		"scroll_left":     &k.ScrollLeft,
+		"scroll_right":    &k.ScrollRight,
+		"page_up":         &k.PageUp,
+		"page_down":       &k.PageDown,
+		"half_page_up":    &k.HalfPageUp,
+		"half_page_down":  &k.HalfPageDown,
+		"center":          &k.Center,
+		"next_match":      &k.NextMatch,
+		"prev_match":      &k.PrevMatch,
+		"copy":            &k.Copy,
+		"copy_snippet":    &k.CopySnippet,
+		"outside_writes":  &k.OutsideWrites,
+		"templates":       &k.Templates,
+		"compare":         &k.Compare,
+		"tester":          &k.Tester,
+	}
+}
+
+// WithOverrides returns a copy of the key map with bindings replaced by the
+// keys configured under their names (e.g. {"down": ["j", "n"]}). A key
+// remapped onto another binding of the same view or modal is an error.
+func (k KeyMap) WithOverrides(overrides map[string][]string) (KeyMap, error) {
+	bindings := k.bindingsByName()
+	for name, keys := range overrides {
+		b, ok := bindings[name]
+		if !ok {
+			return k, fmt.Errorf("unknown key binding %q", name)
+		}
+		if len(keys) == 0 {
+			b.SetEnabled(false)
+			continue
+		}
+		b.SetKeys(keys...)
+		b.SetHelp(keysLabel(keys), b.Help().Desc)
+	}
+	if err := checkKeyConflicts(bindings, overrides); err != nil {
+		return k, err
+	}
+	return k, nil
+}
+
+// keyContext names the bindings one view or modal handles, whose keys must
+// differ
+type keyContext struct {
+	name     string
+	bindings []string
+}
+
+// listKeys are the bindings every list view handles
+var listKeys = []string{
+	"up", "down", "page_up", "page_down", "half_page_up", "half_page_down", "top", "bottom", "center",
+	"select", "next_view", "prev_view", "sort", "pin", "check", "copy", "copy_snippet",
+	"outside_writes", "templates", "compare", "tester", "apply", "deny", "dismiss",
+	"dry_run", "filter", "back", "help", "notifications", "quit", "force_quit",
+}
+
+// frequencyKeys are the bindings only the Frequency view handles
+var frequencyKeys = []string{
+	"scroll_left", "scroll_right", "columns", "subagents", "since_review", "mark_reviewed", "queue",
+	"stage", "apply_staged", "apply_here", "recent_only", "unapproved_only", "denied_only",
+}
+
+// keyContexts lists where bindings are handled together. Bindings that
+// share a key on purpose are apart: next_match and prev_match take n and N
+// from snapshot and note only while a filter is applied, and retry and
+// edit_file take their keys only while a failed write's toast shows.
+var keyContexts = []keyContext{
+	{"the Frequency view", slices.Concat(listKeys, frequencyKeys, []string{"note"})},
+	{"the filtered Frequency view", slices.Concat(listKeys, frequencyKeys, []string{"next_match", "prev_match"})},
+	{"the Matrix view", slices.Concat(listKeys, []string{"group", "note"})},
+	{"the Snapshots view", slices.Concat(listKeys, []string{"toggle", "snapshot", "note"})},
+	{"the Stale view", slices.Concat(listKeys, []string{"remove", "note"})},
+	{"the Reconcile and Policy views", slices.Concat(listKeys, []string{"jump", "note"})},
+	{"the permission details", []string{"up", "down", "select", "jump", "note", "check", "copy", "copy_snippet", "apply_here", "back", "force_quit"}},
+	{"the apply modal", []string{"up", "down", "select", "filter", "back", "quit", "force_quit"}},
+	{"the agent modal", []string{"up", "down", "toggle", "apply", "tools", "edit_tools", "invocations", "jump", "note", "back", "quit", "force_quit"}},
+	{"the agent's tools editor", []string{"up", "down", "toggle", "select", "back", "force_quit"}},
+	{"the review queue", []string{"select", "allow_user", "allow_project", "deny", "dismiss", "skip", "back", "force_quit"}},
+	{"the staging area", []string{"up", "down", "select", "dismiss", "back", "force_quit"}},
+	{"the snapshot diff", []string{"up", "down", "restore", "back", "force_quit"}},
+	{"the settings comparison", []string{"up", "down", "top", "bottom", "toggle", "apply", "back", "quit", "force_quit"}},
+	{"the template picker", []string{"up", "down", "top", "bottom", "toggle", "select", "back", "force_quit"}},
+	{"the column picker", []string{"up", "down", "toggle", "back", "force_quit"}},
+	{"a failed write's toast", []string{"retry", "edit_file"}},
+}
+
+// checkKeyConflicts returns an error naming two bindings of a context bound
+// to the same key, one of them by overrides. Overlaps between the defaults
+// are left alone: where they exist, one binding takes precedence by design,
+// like toggle's space over select's in the Snapshots view.
+func checkKeyConflicts(bindings map[string]*key.Binding, overrides map[string][]string) error {
+	for _, c := range keyContexts {
+		boundTo := make(map[string]string) // Binding name by key
+		for _, name := range c.bindings {
+			b := bindings[name]
+			if !b.Enabled() {
+				continue
+			}
+			for _, k := range b.Keys() {
+				other, ok := boundTo[k]
+				if !ok || other == name {
+					boundTo[k] = name
+					continue
+				}
+				_, remapped := overrides[name]
+				_, otherRemapped := overrides[other]
+				if remapped || otherRemapped {
+					return fmt.Errorf("key %q is bound to both %s and %s in %s", keysLabel([]string{k}), other, name, c.name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// keysLabel formats a key list for display ("space" instead of " ")
+func keysLabel(keys []string) string {
+	labels := make([]string, len(keys))
+	for i, k := range keys {
+		if k == " " {
+			k = "space"
+		}
+		labels[i] = k
+	}
+	return strings.Join(labels, "/")
+}
+
+// primaryKey returns the first key of a binding, for compact hints
+func primaryKey(b key.Binding) string {
+	keys := b.Keys()
+	if len(keys) == 0 {
+		return ""
+	}
+	return keysLabel(keys[:1])
+}
+
+// navKeys returns the compact "down/up" hint, e.g. "j/k"
+func (k KeyMap) navKeys() string {
+	return primaryKey(k.Down) + "/" + primaryKey(k.Up)
+}
+
+// helpSection is a titled group of bindings shown in the Help view
+type helpSection struct {
+	title    string
+	bindings []key.Binding
+}
+
+// helpSections groups the bindings for the Help view
+func (k KeyMap) helpSections() []helpSection {
+	return []helpSection{
+		{"Navigation", []key.Binding{k.Up, k.Down, k.PageUp, k.PageDown, k.HalfPageUp, k.HalfPageDown, k.Top, k.Bottom, k.ScrollLeft, k.ScrollRight, k.Center, k.NextMatch, k.PrevMatch}},
+		{"Actions", []key.Binding{k.Select, k.NextView, k.PrevView, k.Filter, k.Sort, k.Columns, k.Subagents, k.Group, k.Pin, k.Note, k.Check, k.Copy, k.CopySnippet, k.ApplyHere, k.Back, k.Jump, k.DryRun, k.Notifications, k.OutsideWrites, k.Templates, k.Compare, k.Tester, k.Help, k.Quit, k.ForceQuit}},
+		{"Agent modal", []key.Binding{k.Toggle, k.Apply, k.Tools, k.EditTools, k.Invocations}},
+		{"Suggestions", []key.Binding{withDesc(k.Apply, "allow suggested permission…"), k.Deny, k.Dismiss}},
+		{"Snapshots", []key.Binding{withDesc(k.Toggle, "mark snapshot to compare from"), k.Snapshot, k.Restore}},
+		{"Stale allows", []key.Binding{k.Remove}},
+		{"Reviews", []key.Binding{k.SinceReview, k.MarkReviewed, k.Queue}},
+		{"Review queue", []key.Binding{k.AllowUser, k.AllowProject, withDesc(k.Deny, "add to the user deny list"), withDesc(k.Dismiss, "ignore from now on"), k.Skip}},
+		{"Quick filters", []key.Binding{k.RecentOnly, k.UnapprovedOnly, k.DeniedOnly}},
+		{"Staging", []key.Binding{k.Stage, k.ApplyStaged, withDesc(k.Dismiss, "clear the staging area")}},
+		{"Failed writes", []key.Binding{k.Retry, k.EditFile}},
+	}
+}
+
+// ShortHelp implements help.KeyMap
+func (k KeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.navBinding(), k.Select, k.NextView, k.Filter, k.Help, k.Quit}
+}
+
+// FullHelp implements help.KeyMap
+func (k KeyMap) FullHelp() [][]key.Binding {
+	sections := k.helpSections()
+	groups := make([][]key.Binding, len(sections))
+	for i, s := range sections {
+		groups[i] = s.bindings
+	}
+	return groups
+}
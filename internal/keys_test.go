@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeyContextsNameBindings(t *testing.T) {
+	k := DefaultKeyMap()
+	bindings := k.bindingsByName()
+	for _, c := range keyContexts {
+		for _, name := range c.bindings {
+			if _, ok := bindings[name]; !ok {
+				t.Errorf("%s lists unknown binding %q", c.name, name)
+			}
+		}
+	}
+}
+
+func TestWithOverridesRejectsDuplicateKeys(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string][]string
+		want      string // Substring of the error, "" for none
+	}{
+		{"defaults", nil, ""},
+		{"same view", map[string][]string{"note": {"d"}}, `key "d" is bound to both deny and note in the Frequency view`},
+		{"remapped binding first", map[string][]string{"down": {"r"}}, `key "r" is bound to both down and restore in the snapshot diff`},
+		{"modal", map[string][]string{"skip": {"u"}}, `key "u" is bound to both allow_user and skip in the review queue`},
+		{"space", map[string][]string{"apply": {" "}}, `key "space" is bound to both select and apply in the Frequency view`},
+		{"different views", map[string][]string{"group": {"m"}}, ""},
+		{"disabled", map[string][]string{"deny": {}, "note": {"d"}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DefaultKeyMap().WithOverrides(tt.overrides)
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("WithOverrides = %v, want no error", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("WithOverrides = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
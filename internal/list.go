@@ -0,0 +1,88 @@
+package internal
+
+// listState is the cursor and scroll offset of a list that shows a
+// viewport's height of rows at a time. Its methods take the list's length
+// and the viewport's height, which change as data reloads and the terminal
+// resizes, and leave the cursor within the list and on screen.
+type listState struct {
+	cursor int // Index of the selected row
+	scroll int // Index of the first row on screen
+}
+
+// down moves the cursor to the next row
+func (l *listState) down(n, height int) {
+	l.moveTo(l.cursor+1, n, height)
+}
+
+// up moves the cursor to the previous row
+func (l *listState) up(n, height int) {
+	l.moveTo(l.cursor-1, n, height)
+}
+
+// top moves the cursor to the first row
+func (l *listState) top() {
+	*l = listState{}
+}
+
+// bottom moves the cursor to the last row, shown at the bottom
+func (l *listState) bottom(n, height int) {
+	l.moveTo(n-1, n, height)
+}
+
+// moveTo selects row i, or the nearest row there is, scrolling as little
+// as needed to show it
+func (l *listState) moveTo(i, n, height int) {
+	l.cursor = min(max(i, 0), max(n-1, 0))
+	l.scroll = min(scrollToShow(l.scroll, l.cursor, height), max(n-height, 0))
+}
+
+// center selects row i, scrolling it to the middle of the viewport if it
+// is off screen
+func (l *listState) center(i, n, height int) {
+	l.cursor = min(max(i, 0), max(n-1, 0))
+	if l.cursor < l.scroll || l.cursor >= l.scroll+height {
+		l.scroll = max(l.cursor-height/2, 0)
+	}
+}
+
+// recenter scrolls the cursor row to the middle of the viewport, or as
+// near as the ends of the list allow
+func (l *listState) recenter(n, height int) {
+	l.scroll = min(max(l.cursor-height/2, 0), max(n-height, 0))
+}
+
+// clamp keeps the cursor within a list that may have shrunk and on screen
+// in a viewport that may have too
+func (l *listState) clamp(n, height int) {
+	l.moveTo(l.cursor, n, height)
+}
+
+// window returns the rows on screen, from start up to but not including
+// end
+func (l listState) window(n, height int) (start, end int) {
+	start = min(l.scroll, n)
+	return start, min(start+height, n)
+}
+
+// rowAt returns the index of the row shown row lines below the top of the
+// viewport, and whether there is one
+func (l listState) rowAt(row, n, height int) (int, bool) {
+	idx := l.scroll + row
+	if row < 0 || row >= height || idx >= n {
+		return 0, false
+	}
+	return idx, true
+}
+
+// scrollToShow returns the scroll offset closest to scroll that shows line
+// in a viewport of height lines
+func scrollToShow(scroll, line, height int) int {
+	height = max(height, 1)
+	if line >= scroll+height {
+		scroll = line - height + 1
+	}
+	if line < scroll {
+		scroll = line
+	}
+	return max(scroll, 0)
+}
@@ -0,0 +1,139 @@
+package internal
+
+import "testing"
+
+func TestListStateMovesWithinBounds(t *testing.T) {
+	var l listState
+	l.up(10, 4)
+	if l != (listState{}) {
+		t.Errorf("up at the top = %+v, want unchanged", l)
+	}
+	for range 5 {
+		l.down(10, 4)
+	}
+	if l != (listState{cursor: 5, scroll: 2}) {
+		t.Errorf("after 5 downs = %+v, want cursor 5 scrolled to show it at the bottom", l)
+	}
+	l.bottom(10, 4)
+	l.down(10, 4)
+	if l != (listState{cursor: 9, scroll: 6}) {
+		t.Errorf("down at the bottom = %+v, want the last row shown", l)
+	}
+	for range 4 {
+		l.up(10, 4)
+	}
+	if l != (listState{cursor: 5, scroll: 5}) {
+		t.Errorf("after 4 ups = %+v, want cursor 5 scrolled to show it at the top", l)
+	}
+
+	var empty listState
+	empty.down(0, 4)
+	empty.bottom(0, 4)
+	if empty != (listState{}) {
+		t.Errorf("moves in an empty list = %+v, want the zero state", empty)
+	}
+}
+
+func TestListStateCenters(t *testing.T) {
+	var l listState
+	l.center(50, 100, 10)
+	if l != (listState{cursor: 50, scroll: 45}) {
+		t.Errorf("center off screen = %+v, want row 50 in the middle", l)
+	}
+	l.center(52, 100, 10)
+	if l.scroll != 45 {
+		t.Errorf("center on screen scrolled to %d, want it left at 45", l.scroll)
+	}
+	l.center(200, 100, 10)
+	if l.cursor != 99 {
+		t.Errorf("center past the end selected %d, want the last row", l.cursor)
+	}
+
+	l = listState{cursor: 97, scroll: 90}
+	l.recenter(100, 10)
+	if l.scroll != 90 {
+		t.Errorf("recenter near the end scrolled to %d, want 90 so the viewport stays full", l.scroll)
+	}
+	l = listState{cursor: 40, scroll: 38}
+	l.recenter(100, 10)
+	if l.scroll != 35 {
+		t.Errorf("recenter scrolled to %d, want 35", l.scroll)
+	}
+	l = listState{cursor: 2, scroll: 2}
+	l.recenter(100, 10)
+	if l.scroll != 0 {
+		t.Errorf("recenter near the top scrolled to %d, want 0", l.scroll)
+	}
+}
+
+func TestListStateClampsAfterShrink(t *testing.T) {
+	l := listState{cursor: 18, scroll: 12}
+	l.clamp(8, 5)
+	if l != (listState{cursor: 7, scroll: 3}) {
+		t.Errorf("clamp to 8 rows = %+v, want the last row shown at the bottom", l)
+	}
+	l.clamp(3, 5)
+	if l != (listState{cursor: 2, scroll: 0}) {
+		t.Errorf("clamp to 3 rows = %+v, want no scroll", l)
+	}
+	l.clamp(0, 5)
+	if l != (listState{}) {
+		t.Errorf("clamp to no rows = %+v, want the zero state", l)
+	}
+}
+
+func TestListStateWindowAndRowAt(t *testing.T) {
+	l := listState{cursor: 12, scroll: 10}
+	if start, end := l.window(14, 6); start != 10 || end != 14 {
+		t.Errorf("window = %d..%d, want 10..14", start, end)
+	}
+	if start, end := l.window(30, 6); start != 10 || end != 16 {
+		t.Errorf("window = %d..%d, want 10..16", start, end)
+	}
+
+	tests := []struct {
+		row    int
+		want   int
+		wantOK bool
+	}{
+		{0, 10, true},
+		{3, 13, true},
+		{4, 0, false}, // Past the end of the list
+		{-1, 0, false},
+		{6, 0, false}, // Below the viewport
+	}
+	for _, tt := range tests {
+		got, ok := l.rowAt(tt.row, 14, 6)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("rowAt(%d) = %d, %v; want %d, %v", tt.row, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestFrequencyRowsMapToGroupsAndPermissions(t *testing.T) {
+	m := newTestModel(t, 80, 24, false)
+	m = send(m, keys("enter")[0]) // Expand the first group
+	children := len(m.permissionGroups[0].Children)
+	if children == 0 || len(m.permissionGroups) < 2 {
+		t.Fatalf("fixture needs an expandable first group and a second group")
+	}
+
+	m.goToRow(children)
+	if gi, ci := m.freqCursor(); gi != 0 || ci != children-1 {
+		t.Errorf("row %d = group %d, permission %d; want the first group's last permission", children, gi, ci)
+	}
+	m.moveDown()
+	if gi, ci := m.freqCursor(); gi != 1 || ci != -1 {
+		t.Errorf("down from the last permission = group %d, permission %d; want the second group", gi, ci)
+	}
+	if line := m.freqLine(1, -1); line != m.freqList.cursor {
+		t.Errorf("freqLine of the second group = %d, want the cursor's %d", line, m.freqList.cursor)
+	}
+
+	m.moveTop()
+	next, _ := m.handleFreqClick(1)
+	m = next.(Model)
+	if gi, ci := m.freqCursor(); gi != 0 || ci != 0 {
+		t.Errorf("click on row 1 = group %d, permission %d; want the first group's first permission", gi, ci)
+	}
+}
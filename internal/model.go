@@ -2,24 +2,93 @@ package internal
 
 import (
 	"fmt"
+	"log/slog"
+	"maps"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/b-open-io/claude-perms/internal/config"
+	"github.com/b-open-io/claude-perms/internal/insights"
 	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/state"
 	"github.com/b-open-io/claude-perms/internal/types"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-// NewModel creates and initializes a new Model
+// now is the clock ages and time windows are measured against. Golden-file
+// tests pin it so relative times render the same on every run.
+var now = time.Now
+
+// NewModel creates and initializes a new Model with default configuration
 func NewModel() Model {
+	m, _ := NewModelWithConfig(config.Default())
+	return m
+}
+
+// NewModelWithConfig creates a Model using user configuration
+func NewModelWithConfig(cfg *config.Config) (Model, error) {
+	keys, err := DefaultKeyMap().WithOverrides(cfg.Keys)
+	if err != nil {
+		return Model{}, err
+	}
+	quickFilters, err := parseQuickFilters(cfg.Filters)
+	if err != nil {
+		return Model{}, err
+	}
+	freqColumns, err := parseFreqColumns(cfg.Columns)
+	if err != nil {
+		return Model{}, err
+	}
+
+	sp := spinner.New()
+	sp.Spinner = spinner.Dot
+
 	ti := textinput.New()
 	ti.Placeholder = "Filter..."
-	ti.CharLimit = 50
+	ti.CharLimit = 256 // Room for pasted scoped permissions
+
+	ni := textinput.New()
+	ni.Placeholder = "e.g. approved for release tooling only, revisit Q3"
+	ni.CharLimit = 500
+
+	ci := textinput.New()
+	ci.Placeholder = "Bash(git push origin main)"
+	ci.CharLimit = 1024
+
+	pt := textinput.New()
+	pt.Placeholder = "Bash(npm run *)"
+	pt.CharLimit = 1024
+
+	xi := textinput.New()
+	xi.Placeholder = "~/teammate-settings.json or Go development"
+	xi.CharLimit = 1024
+
+	di := textinput.New()
+	di.Placeholder = "~/path/to/projects"
+	di.CharLimit = 1024
+
+	pi := textinput.New()
+	pi.Prompt = "/ " // Not "> ", which marks the selected row
+	pi.Placeholder = "part of a project path"
+	pi.CharLimit = 256
 
 	// Get current working directory for project context
 	cwd, _ := os.Getwd()
 
+	// Pins are a convenience; a broken state file starts empty and is
+	// replaced on the next change
+	st, _ := state.Load()
+
+	staleWindow := insights.DefaultStaleWindow
+	if cfg.StaleDays > 0 {
+		staleWindow = time.Duration(cfg.StaleDays) * 24 * time.Hour
+	}
+
 	return Model{
 		activeView:       ViewFrequency,
 		showApplyModal:   false,
@@ -29,24 +98,48 @@ func NewModel() Model {
 		agents:           nil,
 		skills:           nil,
 		userApproved:     nil,
-		projectApproved:  nil,
+		projectSettings:  nil,
 		projectPath:      cwd,
 		cursor:           0,
-		groupCursor:      0,
-		childCursor:      -1, // Start on group, not child
 		filterInput:      ti,
+		dirInput:         di,
+		noteInput:        ni,
+		checkInput:       ci,
+		compareInput:     xi,
+		testerInput:      pt,
+		projectFilter:    pi,
+		spinner:          sp,
 		filtering:        false,
 		filteredIndices:  nil,
+		keys:             keys,
+		skipDetails:      cfg.SkipDetails,
+		quickFilters:     quickFilters,
+		freqColumns:      freqColumns,
+		readOnly:         cfg.ReadOnly,
+		dryRun:           cfg.DryRun,
+		state:            st,
+		staleWindow:      staleWindow,
+		policyPath:       cfg.Policy,
+		logger:           slog.New(slog.DiscardHandler),
 		width:            80,
 		height:           24,
 		err:              nil,
+	}, nil
+}
+
+// WithLogger returns a copy of the model that writes debug output to l
+func (m Model) WithLogger(l *slog.Logger) Model {
+	if l != nil {
+		m.logger = l
 	}
+	return m
 }
 
 // Init implements tea.Model
 func (m Model) Init() tea.Cmd {
 	return tea.Batch(
 		loadDataCmd,
+		m.spinner.Tick,
 		tea.EnterAltScreen,
 	)
 }
@@ -56,35 +149,66 @@ func loadDataCmd() tea.Msg {
 	return loadDataMsg{}
 }
 
-// toastTickMsg is sent to count down the toast display timer
-type toastTickMsg struct{}
-
-// toastTickCmd returns a command that sends a toastTickMsg after a delay
-func toastTickCmd() tea.Cmd {
-	return tea.Tick(time.Second, func(time.Time) tea.Msg {
-		return toastTickMsg{}
-	})
-}
-
 // setApplyToast sets the toast message from an apply result
 func (m *Model) setApplyToast(result *parser.ApplyResult) {
+	if result.DryRun {
+		m.setDryRunToast(result)
+		return
+	}
 	if !result.WasNew {
-		m.toastMessage = fmt.Sprintf("Already exists in %s", result.FilePath)
-		m.toastTicks = 3
+		m.notify(toastInfo, "Applied: Already exists in %s", result.FilePath)
 		return
 	}
 	if result.LineNumber > 0 {
-		m.toastMessage = fmt.Sprintf("Written to %s:%d", result.FilePath, result.LineNumber)
+		m.notify(toastSuccess, "Applied: Written to %s:%d", result.FilePath, result.LineNumber)
 	} else {
-		m.toastMessage = fmt.Sprintf("Written to %s", result.FilePath)
+		m.notify(toastSuccess, "Applied: Written to %s", result.FilePath)
 	}
-	m.toastTicks = 4
 }
 
-// setApplyToastBatch sets a toast for batch apply
-func (m *Model) setApplyToastBatch(filePath string, count int) {
-	m.toastMessage = fmt.Sprintf("%d permissions written to %s", count, filePath)
-	m.toastTicks = 4
+// setApplyToastBatch sets the toast for a batch written to one settings
+// file, listing the lines of the permissions it added
+func (m *Model) setApplyToastBatch(result *parser.ApplyResult) {
+	if len(result.LineNumbers)+result.Existing <= 1 {
+		m.setApplyToast(result)
+		return
+	}
+
+	lines := make([]string, len(result.LineNumbers))
+	for i, n := range result.LineNumbers {
+		lines[i] = strconv.Itoa(n)
+	}
+	where := result.FilePath
+	if len(lines) > 0 {
+		where += ":" + strings.Join(lines, ",")
+	}
+	existing := ""
+	if result.Existing > 0 && len(lines) > 0 {
+		existing = fmt.Sprintf(" (%d already there)", result.Existing)
+	}
+
+	switch {
+	case len(lines) == 0 && result.DryRun:
+		m.notify(toastInfo, "Dry run: already in %s, nothing would change", result.FilePath)
+	case len(lines) == 0:
+		m.notify(toastInfo, "Applied: Already exists in %s", result.FilePath)
+	case result.DryRun:
+		m.notify(toastInfo, "Dry run: %d permissions would be written to %s%s", len(lines), where, existing)
+	default:
+		m.notify(toastSuccess, "Applied: %d permissions written to %s%s", len(lines), where, existing)
+	}
+}
+
+// setDryRunToast reports what an apply would have written
+func (m *Model) setDryRunToast(result *parser.ApplyResult) {
+	switch {
+	case !result.WasNew:
+		m.notify(toastInfo, "Dry run: already in %s, nothing would change", result.FilePath)
+	case result.LineNumber > 0:
+		m.notify(toastInfo, "Dry run: would write to %s:%d", result.FilePath, result.LineNumber)
+	default:
+		m.notify(toastInfo, "Dry run: would write to %s", result.FilePath)
+	}
 }
 
 // loadDataMsg triggers data loading
@@ -92,7 +216,7 @@ type loadDataMsg struct{}
 
 // loadingProgressMsg updates the loading status display
 type loadingProgressMsg struct {
-	status string
+	progress parser.Progress
 }
 
 // dataLoadedMsg contains loaded data
@@ -101,76 +225,130 @@ type dataLoadedMsg struct {
 	permissionGroups []types.PermissionGroup
 	agents           []types.AgentPermissions
 	skills           []types.SkillPermissions
+	commands         []types.CommandPermissions
 	agentUsage       []types.AgentUsageStats
+	commandUsage     []types.AgentUsageStats
 	userApproved     []string
-	projectApproved  []string
+	projectSettings  map[string][]string // Allow rules per project path, the working directory included
+	permissionModes  map[string]parser.PermissionMode
+	denyStreaks      []insights.DenyStreak
+	grants           []parser.Grant
+	warnings         []parser.Warning
+	warningsDropped  int
+	sessionCount     int
 	err              error
 }
 
 // LoadData loads all permission data from disk with progress updates
-func LoadData(projectPath string, progress chan<- string) dataLoadedMsg {
+func LoadData(projectPath string, progress chan<- parser.Progress) dataLoadedMsg {
+	// Discard warnings left over from an earlier load
+	parser.TakeWarnings()
+
+	// An empty or missing projects directory shows the onboarding screen
+	sessionCount, err := parser.CountSessions(parser.ProjectsDir())
+	if err != nil {
+		return dataLoadedMsg{err: err}
+	}
+
 	// Load permission stats from session logs with caching
 	permissions, err := parser.LoadAllPermissionStatsWithCache(progress)
 	if err != nil {
 		return dataLoadedMsg{err: err}
 	}
 
+	var settingsWarnings []parser.Warning
+
 	// Load approved permissions
-	if progress != nil {
-		progress <- "Loading user settings..."
+	reportStage(progress, "Loading user settings")
+	userApproved, err := parser.LoadUserSettings()
+	if err != nil {
+		settingsWarnings = append(settingsWarnings, parser.Warning{File: parser.UserSettingsPath(), Reason: "unreadable settings: " + err.Error()})
 	}
-	userApproved, _ := parser.LoadUserSettings()
-	projectApproved, _ := parser.LoadProjectSettings(projectPath)
+	reportStage(progress, "Loading project settings")
+	projectSettings, warns := parser.LoadDiscoveredProjectSettings(projectPath)
+	settingsWarnings = append(settingsWarnings, warns...)
+	permissionModes := parser.LoadPermissionModes(slices.Collect(maps.Keys(projectSettings)))
 
-	// Update approval status for each permission
+	// Update approval status for each permission from the settings of the
+	// projects it was used in
 	for i := range permissions {
-		permissions[i].ApprovedAt = parser.GetApprovalLevel(
+		permissions[i].ApprovedAt = parser.GetProjectsApprovalLevel(
 			permissions[i].Permission.Raw,
+			permissions[i].Projects,
 			userApproved,
-			projectApproved,
+			projectSettings,
 		)
 	}
 
 	// Group permissions by type
-	if progress != nil {
-		progress <- "Grouping permissions..."
-	}
+	reportStage(progress, "Grouping permissions")
 	groups := parser.GroupPermissions(permissions)
 
 	// Load agents and skills
-	if progress != nil {
-		progress <- "Loading agents..."
-	}
+	reportStage(progress, "Loading agents")
 	agents, _ := parser.LoadAllAgents()
 
-	if progress != nil {
-		progress <- "Loading skills..."
-	}
+	reportStage(progress, "Loading skills")
 	skills, _ := parser.LoadAllSkills()
 
+	reportStage(progress, "Loading commands")
+	commands, _ := parser.LoadAllCommands()
+
 	// Load agent usage stats from session logs
 	agentUsage, _ := parser.LoadAgentUsageStats(progress)
 
+	reportStage(progress, "Attributing command usage")
+	commandUsage, _ := parser.LoadCommandUsage(parser.ProjectsDir())
+
+	// Permission changes made inside sessions, for the Reconcile view
+	reportStage(progress, "Finding permission grants")
+	grants, _ := parser.LoadGrants(parser.ProjectsDir())
+
+	// Collect non-fatal problems found along the way
+	warnings, dropped := parser.TakeWarnings()
+	warnings = append(settingsWarnings, warnings...)
+
 	return dataLoadedMsg{
 		permissions:      permissions,
 		permissionGroups: groups,
 		agents:           agents,
 		skills:           skills,
+		commands:         commands,
 		agentUsage:       agentUsage,
+		commandUsage:     commandUsage,
 		userApproved:     userApproved,
-		projectApproved:  projectApproved,
+		projectSettings:  projectSettings,
+		permissionModes:  permissionModes,
+		denyStreaks:      insights.DenyStreaks(permissions, now(), insights.DefaultWindow, insights.DefaultThreshold),
+		grants:           grants,
+		warnings:         warnings,
+		warningsDropped:  dropped,
+		sessionCount:     sessionCount,
 		err:              nil,
 	}
 }
 
-// progressReader returns a command that reads from the progress channel
-func progressReader(ch <-chan string) tea.Cmd {
+// reportStage sends a stage-only progress update without blocking the loader
+func reportStage(progress chan<- parser.Progress, stage string) {
+	if progress == nil {
+		return
+	}
+	select {
+	case progress <- parser.Progress{Stage: stage}:
+	default:
+	}
+}
+
+// progressReader returns a command that reads from the progress channel.
+// Each loadingProgressMsg re-issues the reader, so updates stream into the
+// Bubble Tea loop one at a time until the loader closes the channel.
+func progressReader(ch <-chan parser.Progress) tea.Cmd {
 	return func() tea.Msg {
-		status, ok := <-ch
+		p, ok := <-ch
 		if !ok {
 			return nil // Channel closed, ignore
 		}
-		return loadingProgressMsg{status: status}
+		return loadingProgressMsg{progress: p}
 	}
 }
 
@@ -210,15 +388,16 @@ func (m Model) selectedPermission() *types.PermissionStats {
 	if len(m.permissionGroups) == 0 {
 		return nil
 	}
-	if m.groupCursor >= len(m.permissionGroups) {
+	gi, ci := m.freqCursor()
+	if gi >= len(m.permissionGroups) {
 		return nil
 	}
 
-	group := &m.permissionGroups[m.groupCursor]
+	group := &m.permissionGroups[gi]
 
-	if m.childCursor >= 0 && m.childCursor < len(group.Children) {
+	if ci >= 0 && ci < len(group.Children) {
 		// Return specific child
-		return &group.Children[m.childCursor]
+		return &group.Children[ci]
 	}
 
 	// Return first child of group (or nil if no children)
@@ -228,6 +407,26 @@ func (m Model) selectedPermission() *types.PermissionStats {
 	return nil
 }
 
+// approvalLevel returns where raw is approved, given the projects it was
+// used in
+func (m Model) approvalLevel(raw string, projects []string) types.ApprovalLevel {
+	return parser.GetProjectsApprovalLevel(raw, projects, m.userApproved, m.projectSettings)
+}
+
+// projectAllowsAll reports whether project's settings already allow every
+// one of perms, so applying them there would change nothing
+func (m Model) projectAllowsAll(project string, perms []string) bool {
+	if len(perms) == 0 {
+		return false
+	}
+	for _, p := range perms {
+		if !parser.IsApprovedProject(p, m.projectSettings[project]) {
+			return false
+		}
+	}
+	return true
+}
+
 // clampCursor ensures cursor is within valid bounds
 func (m *Model) clampCursor() {
 	perms := m.visiblePermissions()
@@ -242,121 +441,39 @@ func (m *Model) clampCursor() {
 	}
 }
 
-func (m *Model) navigateDown() {
-	if len(m.permissionGroups) == 0 {
-		return
-	}
-
-	group := &m.permissionGroups[m.groupCursor]
-
-	if m.childCursor == -1 {
-		if group.Expanded && len(group.Children) > 0 {
-			m.childCursor = 0
-		} else {
-			if m.groupCursor < len(m.permissionGroups)-1 {
-				m.groupCursor++
-			}
-		}
-	} else {
-		if m.childCursor < len(group.Children)-1 {
-			m.childCursor++
-		} else {
-			if m.groupCursor < len(m.permissionGroups)-1 {
-				m.groupCursor++
-				m.childCursor = -1
-			}
-		}
-	}
-
-	m.updateFreqScroll()
-}
-
-func (m *Model) navigateUp() {
-	if len(m.permissionGroups) == 0 {
-		return
-	}
-
-	if m.childCursor == -1 {
-		if m.groupCursor > 0 {
-			m.groupCursor--
-			prevGroup := &m.permissionGroups[m.groupCursor]
-			if prevGroup.Expanded && len(prevGroup.Children) > 0 {
-				m.childCursor = len(prevGroup.Children) - 1
-			}
-		}
-	} else {
-		if m.childCursor > 0 {
-			m.childCursor--
-		} else {
-			m.childCursor = -1
-		}
-	}
-
-	m.updateFreqScroll()
-}
-
-// updateFreqScroll ensures the cursor is visible within the frequency viewport.
-func (m *Model) updateFreqScroll() {
+// freqViewportHeight returns how many rows of the Frequency list are visible
+// under the suggestion banner, header and separator
+func (m Model) freqViewportHeight() int {
 	_, contentHeight := m.calculateLayout()
-	viewportHeight := contentHeight - 2 // header + separator
-	if viewportHeight < 1 {
-		viewportHeight = 1
-	}
-
-	cursorLine := m.freqVisualLine()
-
-	// Scroll down if cursor is below viewport
-	if cursorLine >= m.freqScroll+viewportHeight {
-		m.freqScroll = cursorLine - viewportHeight + 1
-	}
-	// Scroll up if cursor is above viewport
-	if cursorLine < m.freqScroll {
-		m.freqScroll = cursorLine
+	h := contentHeight - 2 - m.freqBannerLines()
+	if h < 1 {
+		h = 1
 	}
+	return h
 }
 
 // resetApplyModalState resets modal to initial state
 func (m *Model) resetApplyModalState() {
 	m.applyModalMode = ApplyModeOptionSelect
 	m.applyOptionCursor = 0
-	m.projectListCursor = 0
+	m.projectList.top()
+	m.clearProjectFilter()
 }
 
-// navigateMatrixDown moves cursor down in Matrix view
-func (m *Model) navigateMatrixDown() {
-	maxIdx := m.matrixListLen() - 1
-	if maxIdx < 0 {
-		return
-	}
-
-	if m.matrixCursor < maxIdx {
-		m.matrixCursor++
-
-		// Scroll if needed
-		_, contentHeight := m.calculateLayout()
-		viewportHeight := contentHeight - 5
-		if m.matrixCursor >= m.matrixScroll+viewportHeight {
-			m.matrixScroll++
-		}
-	}
+// matrixViewportHeight returns how many rows of the Matrix list are visible
+// under its headers and scroll indicators
+func (m Model) matrixViewportHeight() int {
+	_, contentHeight := m.calculateLayout()
+	return max(contentHeight-5, 1)
 }
 
 // matrixListLen returns the length of the active matrix list
 func (m *Model) matrixListLen() int {
+	if m.matrixByPlugin {
+		return len(m.plugins)
+	}
 	if len(m.agentUsage) > 0 {
 		return len(m.agentUsage)
 	}
 	return len(m.agents)
 }
-
-// navigateMatrixUp moves cursor up in Matrix view
-func (m *Model) navigateMatrixUp() {
-	if m.matrixCursor > 0 {
-		m.matrixCursor--
-
-		// Scroll if needed
-		if m.matrixCursor < m.matrixScroll {
-			m.matrixScroll = m.matrixCursor
-		}
-	}
-}
@@ -0,0 +1,47 @@
+package internal
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// nameScrollStep is how many columns h and l scroll a long permission name
+const nameScrollStep = 8
+
+// selectedRowName returns the permission column text of the Frequency
+// view's selected row, or "" when there is none
+func (m Model) selectedRowName() string {
+	gi, ci := m.freqCursor()
+	if gi >= len(m.permissionGroups) {
+		return ""
+	}
+	g := m.permissionGroups[gi]
+	if ci >= 0 && ci < len(g.Children) {
+		return m.childRowName(g.Children[ci])
+	}
+	return m.groupRowName(g, expandMarker(g))
+}
+
+// scrollName scrolls the selected row's permission name by delta columns,
+// so the end of a name too long for its column can be read. Scrolling
+// stops once the end is in view, and moving to another row resets it.
+func (m Model) scrollName(delta int) (tea.Model, tea.Cmd) {
+	name := m.selectedRowName()
+	_, permWidth := m.calculateFreqColumns()
+	if m.nameScrollRow != name {
+		m.nameScroll = 0
+	}
+	maxScroll := max(ansi.StringWidth(name)-permWidth, 0)
+	m.nameScroll = min(max(m.nameScroll+delta, 0), maxScroll)
+	m.nameScrollRow = name
+	return m, nil
+}
+
+// scrolledName returns name cut by the horizontal scroll when it is the
+// row that was scrolled, marked "…" where it was cut
+func (m Model) scrolledName(name string) string {
+	if m.nameScroll == 0 || name != m.nameScrollRow {
+		return name
+	}
+	return ansi.TruncateLeft(name, m.nameScroll+1, "…")
+}
@@ -0,0 +1,356 @@
+package internal
+
+import (
+	"github.com/b-open-io/claude-perms/internal/types"
+)
+
+// maxNavDepth bounds the back stack; the oldest contexts are forgotten first
+const maxNavDepth = 50
+
+// navEntry captures a UI context that Esc can return to: the view with its
+// cursors and scroll offsets, and any open modals
+type navEntry struct {
+	view ViewType
+
+	// modalChain marks a context saved when one modal opened another
+	// (details → apply); closing the inner modal returns to it
+	modalChain bool
+
+	// freqGroup and freqChild are the row under freqList's cursor, kept
+	// because its index moves as groups above it expand and collapse
+	freqList  listState
+	freqGroup int
+	freqChild int
+	groupOpen bool // Whether the selected group was expanded
+
+	matrixList     listState
+	matrixByPlugin bool
+
+	snapList     listState
+	showSnapDiff bool
+
+	histList   listState
+	staleList  listState
+	reconList  listState
+	policyList listState
+
+	showDetail   bool
+	detailCursor int
+
+	showAgentModal     bool
+	selectedAgentIdx   int
+	agentModalList     listState
+	agentModalSelected []bool
+
+	showApplyModal    bool
+	applyModalMode    ApplyModalMode
+	applyOptionCursor int
+	projectList       listState
+}
+
+// snapshot records the current context
+func (m Model) snapshot() navEntry {
+	e := navEntry{
+		view:               m.activeView,
+		freqList:           m.freqList,
+		matrixList:         m.matrixList,
+		matrixByPlugin:     m.matrixByPlugin,
+		snapList:           m.snapList,
+		showSnapDiff:       m.showSnapDiff,
+		histList:           m.histList,
+		staleList:          m.staleList,
+		reconList:          m.reconList,
+		policyList:         m.policyList,
+		showDetail:         m.showDetail,
+		detailCursor:       m.detailCursor,
+		showAgentModal:     m.showAgentModal,
+		selectedAgentIdx:   m.selectedAgentIdx,
+		agentModalList:     m.agentModalList,
+		agentModalSelected: append([]bool(nil), m.agentModalSelected...),
+		showApplyModal:     m.showApplyModal,
+		applyModalMode:     m.applyModalMode,
+		applyOptionCursor:  m.applyOptionCursor,
+		projectList:        m.projectList,
+	}
+	e.freqGroup, e.freqChild = m.freqCursor()
+	if e.freqGroup < len(m.permissionGroups) {
+		e.groupOpen = m.permissionGroups[e.freqGroup].Expanded
+	}
+	return e
+}
+
+// restore returns to a recorded context
+func (m *Model) restore(e navEntry) {
+	m.activeView = e.view
+	m.freqList = e.freqList
+	if e.freqGroup < len(m.permissionGroups) {
+		m.permissionGroups[e.freqGroup].Expanded = e.groupOpen
+		m.selectFreqRow(e.freqGroup, e.freqChild)
+	}
+	m.matrixList = e.matrixList
+	m.matrixByPlugin = e.matrixByPlugin
+	m.snapList = e.snapList
+	m.showSnapDiff = e.showSnapDiff
+	m.histList = e.histList
+	m.staleList = e.staleList
+	m.reconList = e.reconList
+	m.policyList = e.policyList
+	m.clampLists() // The lists may have changed since
+	m.showDetail = e.showDetail
+	m.detailCursor = e.detailCursor
+	m.showAgentModal = e.showAgentModal
+	m.selectedAgentIdx = e.selectedAgentIdx
+	m.agentModalList = e.agentModalList
+	m.agentModalSelected = e.agentModalSelected
+	m.agentModalMode = AgentModalModePermissions
+	m.showApplyModal = e.showApplyModal
+	m.applyModalMode = e.applyModalMode
+	m.applyOptionCursor = e.applyOptionCursor
+	m.projectList = e.projectList
+}
+
+// pushNav saves the current context before moving to another one
+func (m *Model) pushNav() {
+	if len(m.navStack) >= maxNavDepth {
+		m.navStack = m.navStack[1:]
+	}
+	m.navStack = append(m.navStack, m.snapshot())
+}
+
+// switchView changes the active view, remembering where we came from
+func (m *Model) switchView(v ViewType) {
+	if v == m.activeView {
+		return
+	}
+	m.pushNav()
+	m.activeView = v
+	switch v {
+	case ViewSnapshots:
+		m.loadSnapshots()
+	case ViewHistory:
+		m.loadHistory()
+	case ViewReconcile:
+		m.loadReconcile()
+	case ViewPolicy:
+		m.loadPolicy()
+	}
+}
+
+// openApplyFromDetail moves from the detail modal to the apply flow; Esc in
+// the apply modal returns to the details
+func (m *Model) openApplyFromDetail() {
+	m.pushNav()
+	m.navStack[len(m.navStack)-1].modalChain = true
+	m.showDetail = false
+	m.resetApplyModalState()
+	m.showApplyModal = true
+}
+
+// closeModal leaves the current modal, returning to the modal it was
+// opened from if any
+func (m *Model) closeModal() {
+	m.dismissModals()
+	if n := len(m.navStack); n > 0 && m.navStack[n-1].modalChain {
+		m.popNav()
+	}
+}
+
+// finishModal closes every modal after a completed action, discarding the
+// modal contexts that led to it
+func (m *Model) finishModal() {
+	m.dismissModals()
+	for n := len(m.navStack); n > 0 && m.navStack[n-1].modalChain; n-- {
+		m.navStack = m.navStack[:n-1]
+	}
+}
+
+// dismissModals hides all modals and resets their state
+func (m *Model) dismissModals() {
+	m.showDetail = false
+	m.showApplyModal = false
+	m.resetApplyModalState()
+	m.showAgentModal = false
+	m.resetAgentModalState()
+	m.showPluginModal = false
+	m.showSnapDiff = false
+	m.showRemoveConfirm = false
+	m.showCheck = false
+	m.checkResult = nil
+	m.showQueue = false
+	m.showStaged = false
+	m.showApplyHere = false
+	m.showColumns = false
+	m.showToastLog = false
+	m.showOutside = false
+	m.showTemplates = false
+	m.showCompare = false
+	m.showTester = false
+}
+
+// popNav returns to the most recently saved context, if any
+func (m *Model) popNav() bool {
+	if len(m.navStack) == 0 {
+		return false
+	}
+	e := m.navStack[len(m.navStack)-1]
+	m.navStack = m.navStack[:len(m.navStack)-1]
+	m.restore(e)
+	return true
+}
+
+// jumpToAgent switches to the Matrix view with agentType selected
+func (m *Model) jumpToAgent(agentType string) bool {
+	idx := -1
+	for i, a := range m.agentUsage {
+		if a.AgentType == agentType {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return false
+	}
+
+	m.pushNav()
+	m.dismissModals()
+	m.activeView = ViewMatrix
+	m.matrixByPlugin = false
+	m.matrixList.center(idx, m.matrixListLen(), m.matrixViewportHeight())
+	return true
+}
+
+// jumpToPermission switches to the Frequency view with the permission's
+// group expanded and the permission selected
+func (m *Model) jumpToPermission(raw string) bool {
+	for gi, g := range m.permissionGroups {
+		for ci, child := range g.Children {
+			if child.Permission.Raw != raw {
+				continue
+			}
+			m.pushNav()
+			m.dismissModals()
+			m.activeView = ViewFrequency
+			m.permissionGroups[gi].Expanded = true
+			m.selectFreqRow(gi, ci)
+			return true
+		}
+	}
+	return false
+}
+
+// breadcrumb describes the current context for the title bar,
+// e.g. ["Matrix", "devops-specialist", "Apply"]
+func (m Model) breadcrumb() []string {
+	crumbs := []string{viewNames[m.activeView]}
+
+	switch m.activeView {
+	case ViewFrequency:
+		if m.showQueue {
+			crumbs = append(crumbs, "Review Queue")
+			break
+		}
+		if m.showStaged {
+			crumbs = append(crumbs, "Staged")
+			break
+		}
+		gi, ci := m.freqCursor()
+		if gi >= len(m.permissionGroups) {
+			break
+		}
+		group := m.permissionGroups[gi]
+		crumbs = append(crumbs, group.Type)
+		if ci >= 0 && ci < len(group.Children) {
+			if raw := group.Children[ci].Permission.Raw; raw != group.Type {
+				crumbs = append(crumbs, raw)
+			}
+		}
+		if m.showDetail {
+			crumbs = append(crumbs, "Details")
+		}
+		if m.showApplyModal {
+			crumbs = append(crumbs, "Apply")
+			if m.applyModalMode == ApplyModeProjectSelect {
+				crumbs = append(crumbs, "Project")
+			}
+		}
+		if m.showApplyHere {
+			crumbs = append(crumbs, "Apply Here")
+		}
+		if m.showColumns {
+			crumbs = append(crumbs, "Columns")
+		}
+
+	case ViewMatrix:
+		if p, ok := m.selectedPlugin(); ok && m.showPluginModal {
+			crumbs = append(crumbs, p.Name)
+			break
+		}
+		if !m.showAgentModal || m.selectedAgentIdx >= len(m.agentUsage) {
+			break
+		}
+		crumbs = append(crumbs, m.agentUsage[m.selectedAgentIdx].AgentType)
+		switch m.agentModalMode {
+		case AgentModalModeScope:
+			crumbs = append(crumbs, "Apply")
+		case AgentModalModeProject:
+			crumbs = append(crumbs, "Apply", "Project")
+		case AgentModalModeTools:
+			crumbs = append(crumbs, "Tools")
+		case AgentModalModeEditTools:
+			crumbs = append(crumbs, "Edit Tools")
+		case AgentModalModeInvocations:
+			if m.invocationsHint() == "orphan logs" {
+				crumbs = append(crumbs, "Orphan Logs")
+			} else {
+				crumbs = append(crumbs, "Invocations")
+			}
+		}
+
+	case ViewSnapshots:
+		if m.showSnapDiff {
+			crumbs = append(crumbs, "Diff")
+		}
+
+	case ViewStale:
+		if m.showRemoveConfirm {
+			crumbs = append(crumbs, "Remove")
+		}
+	}
+	if m.showCheck {
+		crumbs = append(crumbs, "Check")
+	}
+	if m.showCompare {
+		crumbs = append(crumbs, "Compare")
+	}
+	if m.showTester {
+		crumbs = append(crumbs, "Pattern Tester")
+	}
+	if m.showTemplates {
+		crumbs = append(crumbs, "Templates")
+		if t, ok := m.selectedTemplate(); ok && m.templatePicked {
+			crumbs = append(crumbs, t.Name)
+		}
+	}
+	if m.showOutside {
+		crumbs = append(crumbs, "Outside Writes")
+		if m.outsideProject != "" {
+			crumbs = append(crumbs, shortenPath(m.outsideProject))
+		}
+	}
+
+	return crumbs
+}
+
+// detailAgents returns the agents that used a permission, most active first,
+// in the order the detail modal lists them
+func (m Model) detailAgents(perm *types.PermissionStats) []countRow {
+	if perm == nil {
+		return nil
+	}
+	return sortedCounts(m.agentCounts(perm.Permission.Raw))
+}
+
+// setLinkToast reports a link that has nowhere to go
+func (m *Model) setLinkToast(format string, args ...any) {
+	m.notify(toastInfo, format, args...)
+}
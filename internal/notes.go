@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// startNote opens the note editor for a permission or, if agent is set, an
+// agent type, prefilled with its current note
+func (m *Model) startNote(agent bool, name string) tea.Cmd {
+	m.editingNote = true
+	m.noteAgent = agent
+	m.noteTarget = name
+	if agent {
+		m.noteInput.SetValue(m.state.Notes.Agent(name))
+	} else {
+		m.noteInput.SetValue(m.state.Notes.Permission(name))
+	}
+	m.noteInput.CursorEnd()
+	return m.noteInput.Focus()
+}
+
+// startNoteFromList opens the note editor for the selected permission or
+// agent in the Frequency or Matrix view
+func (m Model) startNoteFromList() (tea.Model, tea.Cmd) {
+	switch m.activeView {
+	case ViewFrequency:
+		gi, ci := m.freqCursor()
+		if gi >= len(m.permissionGroups) {
+			return m, nil
+		}
+		g := m.permissionGroups[gi]
+		if ci < 0 || ci >= len(g.Children) {
+			m.notify(toastInfo, "Expand the group and select a permission to add a note")
+			return m, m.toastTickCmd()
+		}
+		return m, m.startNote(false, g.Children[ci].Permission.Raw)
+	case ViewMatrix:
+		if m.matrixByPlugin {
+			m.setLinkToast("Notes are per agent: press %s to list agents", primaryKey(m.keys.Group))
+			return m, m.toastTickCmd()
+		}
+		if m.matrixList.cursor >= len(m.agentUsage) {
+			return m, nil
+		}
+		return m, m.startNote(true, m.agentUsage[m.matrixList.cursor].AgentType)
+	}
+	return m, nil
+}
+
+// handleNoteKeys processes keys while editing a note. Enter saves (a blank
+// note removes it), Esc discards the edit.
+func (m Model) handleNoteKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.ForceQuit):
+		return m, tea.Quit
+
+	case msg.Type == tea.KeyEsc:
+		m.editingNote = false
+		m.noteInput.Blur()
+		return m, nil
+
+	case msg.Type == tea.KeyEnter:
+		m.editingNote = false
+		m.noteInput.Blur()
+		if m.noteAgent {
+			m.state.Notes.SetAgent(m.noteTarget, m.noteInput.Value())
+		} else {
+			m.state.Notes.SetPermission(m.noteTarget, m.noteInput.Value())
+		}
+		if m.noteInput.Value() == "" {
+			m.notify(toastSuccess, "Removed note for %s", m.noteTarget)
+		} else {
+			m.notify(toastSuccess, "Saved note for %s", m.noteTarget)
+		}
+		if !m.demo {
+			if err := m.state.Save(); err != nil {
+				m.logger.Debug("saving state failed", "err", err)
+				m.notify(toastError, "Could not save notes: %v", err)
+			}
+		}
+		return m, m.toastTickCmd()
+	}
+
+	if msg.Paste {
+		msg.Runes = sanitizePaste(msg.Runes)
+	}
+
+	var cmd tea.Cmd
+	m.noteInput, cmd = m.noteInput.Update(msg)
+	return m, cmd
+}
+
+// renderNoteField renders the Note line of a detail modal: the editor while
+// editing, the note if there is one, nothing otherwise
+func (m Model) renderNoteField(agent bool, name string, width int) string {
+	label := styles.HelpDesc.Render("Note       ")
+	if m.editingNote && m.noteAgent == agent && m.noteTarget == name {
+		m.noteInput.Width = width - 14
+		return "  " + label + m.noteInput.View() + "\n"
+	}
+	note := m.state.Notes.Permission(name)
+	if agent {
+		note = m.state.Notes.Agent(name)
+	}
+	if note == "" {
+		return ""
+	}
+	return "  " + label + truncateString(note, width-11) + "\n"
+}
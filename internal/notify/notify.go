@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ErrUnavailable is returned when no desktop notification backend is installed
+var ErrUnavailable = errors.New("no desktop notification backend available")
+
+// Backend returns the command that shows a desktop notification with title
+// and body
+func Backend(title, body string) (name string, args []string, err error) {
+	switch runtime.GOOS {
+	case "darwin":
+		if _, err := exec.LookPath("osascript"); err == nil {
+			script := fmt.Sprintf("display notification %s with title %s", appleString(body), appleString(title))
+			return "osascript", []string{"-e", script}, nil
+		}
+	case "linux", "freebsd", "openbsd":
+		if _, err := exec.LookPath("notify-send"); err == nil {
+			return "notify-send", []string{"--app-name=perms", title, body}, nil
+		}
+	}
+	return "", nil, ErrUnavailable
+}
+
+// Desktop shows a desktop notification
+func Desktop(title, body string) error {
+	name, args, err := Backend(title, body)
+	if err != nil {
+		return err
+	}
+	if out, err := exec.Command(name, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Run runs command through the shell with env added to the environment,
+// e.g. {"PERMS_PERMISSION": "Bash(curl:*)"}, so a hook can route
+// notifications anywhere
+func Run(command string, env map[string]string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Env = os.Environ()
+	for k, v := range env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", command, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// appleString quotes s as an AppleScript string literal
+func appleString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,251 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/demo"
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Empty-state options, in display order
+const (
+	onboardingChooseDir = iota
+	onboardingDemo
+	onboardingQuit
+	onboardingOptionCount
+)
+
+var onboardingOptions = [onboardingOptionCount]string{
+	"Choose another directory",
+	"Explore with demo data",
+	"Quit",
+}
+
+// WithDemo returns a copy of the model that loads the bundled demo data
+// instead of the user's session logs. Settings files are never written in
+// demo mode.
+func (m Model) WithDemo() (Model, error) {
+	if err := m.enterDemo(); err != nil {
+		return m, err
+	}
+	return m, nil
+}
+
+// enterDemo extracts the demo data and points the parser at it
+func (m *Model) enterDemo() error {
+	dir, err := demo.Extract()
+	if err != nil {
+		return fmt.Errorf("extracting demo data: %w", err)
+	}
+	parser.SetProjectsDir(dir)
+	parser.SetCacheEnabled(false)
+	m.demo = true
+	m.demoDir = filepath.Dir(dir)
+	return nil
+}
+
+// writeBlockedReason explains why settings files can't be written this
+// session, or returns "" if they can. Dry runs never write, so they are
+// allowed even in demo and read-only mode.
+func (m Model) writeBlockedReason() string {
+	switch {
+	case m.dryRun:
+		return ""
+	case m.demo:
+		return "Demo mode: settings files are not modified"
+	case m.readOnly:
+		return "Read-only mode: settings files are not modified"
+	}
+	return ""
+}
+
+// writeBlocked closes the apply flow without touching settings files
+func (m Model) writeBlocked() (tea.Model, tea.Cmd) {
+	m.finishModal()
+	m.notify(toastWarn, "%s", m.writeBlockedReason())
+	return m, m.toastTickCmd()
+}
+
+// Cleanup removes temporary files created during the session (demo data)
+func (m Model) Cleanup() {
+	if m.demoDir != "" {
+		os.RemoveAll(m.demoDir)
+	}
+}
+
+// reload rescans the session logs, showing the loading screen again
+func (m Model) reload() (tea.Model, tea.Cmd) {
+	m.onboarding = false
+	m.choosingDir = false
+	m.onboardingErr = ""
+	m.isLoading = true
+	m.loading = parser.Progress{}
+	return m, tea.Batch(loadDataCmd, m.spinner.Tick)
+}
+
+// handleOnboardingKeys processes keys on the empty-state screen
+func (m Model) handleOnboardingKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.choosingDir {
+		return m.handleDirInputKeys(msg)
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.Quit, m.keys.ForceQuit):
+		return m, tea.Quit
+
+	case key.Matches(msg, m.keys.Down):
+		if m.onboardingCursor < onboardingOptionCount-1 {
+			m.onboardingCursor++
+		}
+
+	case key.Matches(msg, m.keys.Up):
+		if m.onboardingCursor > 0 {
+			m.onboardingCursor--
+		}
+
+	case key.Matches(msg, m.keys.Select):
+		switch m.onboardingCursor {
+		case onboardingChooseDir:
+			m.choosingDir = true
+			m.onboardingErr = ""
+			m.dirInput.SetValue("")
+			m.dirInput.Focus()
+		case onboardingDemo:
+			if err := m.enterDemo(); err != nil {
+				m.onboardingErr = err.Error()
+				return m, nil
+			}
+			return m.reload()
+		case onboardingQuit:
+			return m, tea.Quit
+		}
+	}
+
+	return m, nil
+}
+
+// handleDirInputKeys processes keys while typing a projects directory
+func (m Model) handleDirInputKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.ForceQuit):
+		return m, tea.Quit
+
+	case msg.Type == tea.KeyEsc:
+		m.choosingDir = false
+		m.onboardingErr = ""
+		m.dirInput.Blur()
+		return m, nil
+
+	case msg.Type == tea.KeyEnter:
+		dir, err := resolveProjectsDir(m.dirInput.Value())
+		if err != nil {
+			m.onboardingErr = err.Error()
+			return m, nil
+		}
+		m.dirInput.Blur()
+		parser.SetProjectsDir(dir)
+		return m.reload()
+	}
+
+	if msg.Paste {
+		msg.Runes = sanitizePaste(msg.Runes)
+	}
+
+	var cmd tea.Cmd
+	m.dirInput, cmd = m.dirInput.Update(msg)
+	return m, cmd
+}
+
+// resolveProjectsDir expands ~ and checks that path is a directory
+func resolveProjectsDir(path string) (string, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return "", fmt.Errorf("enter a directory path")
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, path[1:])
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", path)
+	}
+	return filepath.Abs(path)
+}
+
+// onboardingBindings returns the key hints for the empty-state screen
+func (m Model) onboardingBindings() []key.Binding {
+	if m.choosingDir {
+		return []key.Binding{
+			key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "scan")),
+			key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "back")),
+		}
+	}
+	return []key.Binding{m.keys.navBinding(), withDesc(m.keys.Select, "choose"), m.keys.Quit}
+}
+
+// renderOnboarding renders the empty-state screen shown when no session
+// logs were found
+func (m Model) renderOnboarding() string {
+	width := m.width - 8
+	if width > 72 {
+		width = 72
+	}
+	if width < 30 {
+		width = 30
+	}
+	wrap := lipgloss.NewStyle().Width(width)
+
+	var b strings.Builder
+	b.WriteString(styles.LoadingTitle.Render("Welcome to perms"))
+	b.WriteString("\n\n")
+	b.WriteString("No Claude Code session logs were found in:\n")
+	b.WriteString(styles.DiffPath.Render("  " + truncateString(parser.ProjectsDir(), width-2)))
+	b.WriteString("\n\n")
+	b.WriteString(wrap.Render("perms scans the JSONL logs Claude Code writes for every session, " +
+		"counts the tools each one used (shell commands, file reads and edits, web fetches, agents) " +
+		"and shows which you keep approving, so you can add them to a settings.local.json allow list."))
+	b.WriteString("\n\n")
+	b.WriteString(wrap.Render("Run Claude Code in a project to create session logs, or:"))
+	b.WriteString("\n\n")
+
+	if m.choosingDir {
+		b.WriteString("Projects directory: " + m.dirInput.View())
+		b.WriteString("\n")
+	} else {
+		for i, opt := range onboardingOptions {
+			if i == m.onboardingCursor {
+				b.WriteString(styles.ListItemSelected.Render("> " + opt))
+			} else {
+				b.WriteString(styles.ListItem.Render(opt))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if m.onboardingErr != "" {
+		b.WriteString("\n")
+		b.WriteString(styles.Error.Render(truncateString(m.onboardingErr, width)))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(renderHints(m.onboardingBindings()))
+
+	return lipgloss.Place(m.width, m.height,
+		lipgloss.Center, lipgloss.Center,
+		b.String())
+}
@@ -0,0 +1,179 @@
+package internal
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/types"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxVisibleWrites is how many projects or files the outside-writes modal
+// lists at once
+const maxVisibleWrites = 10
+
+// projectWrites is the files a project's sessions modified outside their
+// workspace
+type projectWrites struct {
+	project string
+	files   []types.OutsideWrite // Most modified first
+	count   int                  // Calls that modified them
+}
+
+// outsideWritesByProject groups the files Write and Edit calls modified
+// outside their workspace by project, main sessions' and subagents' alike,
+// the projects with the most such calls first
+func (m Model) outsideWritesByProject() []projectWrites {
+	var writes []types.OutsideWrite
+	for _, p := range m.permissions {
+		writes = types.AddOutsideWrites(writes, p.OutsideWrites...)
+	}
+	for _, a := range m.subagentUsage {
+		for _, p := range a.Permissions {
+			writes = types.AddOutsideWrites(writes, p.OutsideWrites...)
+		}
+	}
+
+	byProject := make(map[string]*projectWrites)
+	var projects []*projectWrites
+	for _, w := range writes {
+		pw := byProject[w.Project]
+		if pw == nil {
+			pw = &projectWrites{project: w.Project}
+			byProject[w.Project] = pw
+			projects = append(projects, pw)
+		}
+		pw.files = append(pw.files, w)
+		pw.count += w.Count
+	}
+
+	rows := make([]projectWrites, len(projects))
+	for i, pw := range projects {
+		slices.SortFunc(pw.files, func(a, b types.OutsideWrite) int {
+			return cmp.Or(b.Count-a.Count, strings.Compare(a.File, b.File))
+		})
+		rows[i] = *pw
+	}
+	slices.SortFunc(rows, func(a, b projectWrites) int {
+		return cmp.Or(b.count-a.count, strings.Compare(a.project, b.project))
+	})
+	return rows
+}
+
+// outsideProjectWrites returns the project the modal drilled down into
+func (m Model) outsideProjectWrites() (projectWrites, bool) {
+	for _, pw := range m.outsideWritesByProject() {
+		if pw.project == m.outsideProject {
+			return pw, true
+		}
+	}
+	return projectWrites{}, false
+}
+
+// openOutsideWrites opens the modal listing the projects whose sessions
+// modified files outside their workspace
+func (m Model) openOutsideWrites() (tea.Model, tea.Cmd) {
+	if len(m.outsideWritesByProject()) == 0 {
+		m.notify(toastInfo, "No Write or Edit call modified a file outside its workspace")
+		return m, m.toastTickCmd()
+	}
+	m.showOutside = true
+	m.outsideProject = ""
+	m.outsideList.top()
+	return m, nil
+}
+
+// handleOutsideWritesKeys moves through the projects and, after Enter on
+// one, the files it modified
+func (m Model) handleOutsideWritesKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	l, n := &m.outsideList, len(m.outsideWritesByProject())
+	if m.outsideProject != "" {
+		pw, _ := m.outsideProjectWrites()
+		l, n = &m.outsideFiles, len(pw.files)
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.ForceQuit):
+		return m, tea.Quit
+
+	case m.outsideProject != "" && key.Matches(msg, m.keys.Back):
+		m.outsideProject = ""
+
+	case key.Matches(msg, m.keys.Back, m.keys.Quit, m.keys.OutsideWrites):
+		m.showOutside = false
+
+	case key.Matches(msg, m.keys.Down):
+		l.down(n, maxVisibleWrites)
+
+	case key.Matches(msg, m.keys.Up):
+		l.up(n, maxVisibleWrites)
+
+	case key.Matches(msg, m.keys.Top):
+		l.top()
+
+	case key.Matches(msg, m.keys.Bottom):
+		l.bottom(n, maxVisibleWrites)
+
+	case m.outsideProject == "" && key.Matches(msg, m.keys.Select):
+		if rows := m.outsideWritesByProject(); m.outsideList.cursor < len(rows) {
+			m.outsideProject = rows[m.outsideList.cursor].project
+			m.outsideFiles.top()
+		}
+	}
+	return m, nil
+}
+
+// outsideRows is how many rows the outside-writes modal lists
+func (m Model) outsideRows() int {
+	if m.outsideProject != "" {
+		pw, _ := m.outsideProjectWrites()
+		return len(pw.files)
+	}
+	return len(m.outsideWritesByProject())
+}
+
+// renderOutsideWritesModal renders the projects whose sessions modified
+// files outside their workspace, or the files one of them modified
+func (m Model) renderOutsideWritesModal() string {
+	modalWidth := min(max(m.width*85/100, 50), 90)
+	inner := modalWidth - 6 // border + padding
+
+	var b strings.Builder
+	b.WriteString(styles.ModalTitle.Render("Writes Outside the Workspace"))
+	b.WriteString("\n\n")
+
+	var lines []string
+	list := m.outsideList
+	if m.outsideProject != "" {
+		pw, _ := m.outsideProjectWrites()
+		b.WriteString(truncateString(fmt.Sprintf("  %s: %d call(s) modified %d file(s) outside it:", pw.project, pw.count, len(pw.files)), inner) + "\n\n")
+		for _, w := range pw.files {
+			lines = append(lines, fmt.Sprintf("%5d  %s", w.Count, w.File))
+		}
+		list = m.outsideFiles
+	} else {
+		rows := m.outsideWritesByProject()
+		b.WriteString(styles.HelpDesc.Render(truncateString("  Write and Edit calls outside each project's workspace:", inner)) + "\n\n")
+		for _, pw := range rows {
+			lines = append(lines, fmt.Sprintf("%5d  %3d file(s)  %s", pw.count, len(pw.files), pw.project))
+		}
+	}
+
+	start, end := list.window(len(lines), maxVisibleWrites)
+	for i := start; i < end; i++ {
+		if i == list.cursor {
+			b.WriteString(styles.ListItemSelected.Render(truncateString("> "+lines[i], inner)) + "\n")
+		} else {
+			b.WriteString(truncateString("  "+lines[i], inner) + "\n")
+		}
+	}
+	if len(lines) > maxVisibleWrites {
+		b.WriteString(styles.HelpDesc.Render(fmt.Sprintf("  %d of %d", list.cursor+1, len(lines))) + "\n")
+	}
+
+	b.WriteString("\n" + renderHints(m.contextBindings()))
+	return styles.Modal.Width(modalWidth).Render(b.String())
+}
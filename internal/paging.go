@@ -0,0 +1,81 @@
+package internal
+
+// viewList returns the cursor and scroll offset of a view's list with its
+// length and viewport height. The Frequency view's rows are its groups
+// with the permissions of the expanded ones under them.
+func (m *Model) viewList(v ViewType) (l *listState, n, height int, ok bool) {
+	switch v {
+	case ViewFrequency:
+		return &m.freqList, m.freqTotalLines(), m.freqViewportHeight(), true
+	case ViewMatrix:
+		return &m.matrixList, m.matrixListLen(), m.matrixViewportHeight(), true
+	case ViewDiagnostics:
+		return &m.diagList, len(m.warnings), m.diagViewportHeight(), true
+	case ViewSnapshots:
+		return &m.snapList, len(m.snapshots), m.snapViewportHeight(), true
+	case ViewHistory:
+		return &m.histList, len(m.history), m.histViewportHeight(), true
+	case ViewStale:
+		return &m.staleList, len(m.staleAllows), m.staleViewportHeight(), true
+	case ViewReconcile:
+		return &m.reconList, len(m.reconciled), m.reconViewportHeight(), true
+	case ViewPolicy:
+		return &m.policyList, len(m.violations), m.policyViewportHeight(), true
+	}
+	return nil, 0, 0, false
+}
+
+// moveDown moves the cursor of the active view down one row
+func (m *Model) moveDown() {
+	if l, n, height, ok := m.viewList(m.activeView); ok {
+		l.down(n, height)
+	}
+}
+
+// moveUp moves the cursor of the active view up one row
+func (m *Model) moveUp() {
+	if l, n, height, ok := m.viewList(m.activeView); ok {
+		l.up(n, height)
+	}
+}
+
+// moveTop moves the cursor of the active view to its first row
+func (m *Model) moveTop() {
+	if l, _, _, ok := m.viewList(m.activeView); ok {
+		l.top()
+	}
+}
+
+// moveBottom moves the cursor of the active view to its last row
+func (m *Model) moveBottom() {
+	if l, n, height, ok := m.viewList(m.activeView); ok {
+		l.bottom(n, height)
+	}
+}
+
+// clampLists keeps every list view's cursor in range and on screen after
+// its data reloads or the terminal resizes
+func (m *Model) clampLists() {
+	for v := range ViewType(viewCount) {
+		if l, n, height, ok := m.viewList(v); ok {
+			l.clamp(n, height)
+		}
+	}
+}
+
+// pageSize returns how many rows of the active view's list are visible,
+// the distance PgUp and PgDn move
+func (m Model) pageSize() int {
+	if _, _, height, ok := m.viewList(m.activeView); ok {
+		return height
+	}
+	return 1
+}
+
+// page moves the cursor of the active view by rows, down when positive
+// and up when negative
+func (m *Model) page(rows int) {
+	if l, n, height, ok := m.viewList(m.activeView); ok {
+		l.moveTo(l.cursor+rows, n, height)
+	}
+}
@@ -0,0 +1,189 @@
+package parser
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/types"
+)
+
+// maxAgentStartDelay bounds how long after a Task call its agent log may
+// start for the two to be matched by time alone
+const maxAgentStartDelay = 2 * time.Minute
+
+// agentLogHeadLines is how many lines of an agent log are read for its
+// metadata
+const agentLogHeadLines = 20
+
+// agentLogInfo is what an agent log says about where it came from
+type agentLogInfo struct {
+	AgentID string    // agentId field of its entries, which may differ from the file name
+	Session string    // sessionId field: the session that started the agent
+	Slug    string    // Slug shared with the entries of that session
+	Start   time.Time // Timestamp of its first entry
+	Prompt  string    // Its first user message, the Task prompt
+}
+
+// readAgentLogInfo reads the metadata at the head of an agent log
+func readAgentLogInfo(agentFile string) agentLogInfo {
+	var info agentLogInfo
+	file, err := openFile(agentFile)
+	if err != nil {
+		return info
+	}
+	defer file.Close()
+
+	lines := newLineReader(file)
+	for n := 0; n < agentLogHeadLines && lines.Next(); n++ {
+		var entry struct {
+			Type      string `json:"type"`
+			Timestamp string `json:"timestamp"`
+			AgentID   string `json:"agentId"`
+			SessionID string `json:"sessionId"`
+			Slug      string `json:"slug"`
+			Message   struct {
+				Content rawJSON `json:"content"`
+			} `json:"message"`
+		}
+		if err := json.Unmarshal(lines.Bytes(), &entry); err != nil {
+			continue
+		}
+		if info.AgentID == "" {
+			info.AgentID = entry.AgentID
+		}
+		if info.Session == "" {
+			info.Session = entry.SessionID
+		}
+		if info.Slug == "" {
+			info.Slug = entry.Slug
+		}
+		if info.Start.IsZero() {
+			info.Start, _ = time.Parse(time.RFC3339, entry.Timestamp)
+		}
+		if info.Prompt == "" && entry.Type == "user" {
+			info.Prompt = truncatePrompt(messageText(entry.Message.Content))
+		}
+		if info.AgentID != "" && info.Session != "" && info.Slug != "" && !info.Start.IsZero() && info.Prompt != "" {
+			break
+		}
+	}
+	return info
+}
+
+// messageText returns a message's content as text: a plain string, or its
+// text items joined
+func messageText(content rawJSON) string {
+	var s string
+	if err := json.Unmarshal(content, &s); err == nil {
+		return s
+	}
+	var items []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(content, &items); err != nil {
+		return ""
+	}
+	var texts []string
+	for _, item := range items {
+		if item.Type == "text" {
+			texts = append(texts, item.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// agentResolver matches agent logs to the Task calls that started them
+type agentResolver struct {
+	byID      map[string]types.AgentInvocation // agentId -> invocation, from Task results
+	unmatched []types.AgentInvocation          // Invocations no result named an agent for, oldest first
+}
+
+// newAgentResolver indexes invocations for resolve
+func newAgentResolver(invocations []types.AgentInvocation) *agentResolver {
+	r := &agentResolver{byID: make(map[string]types.AgentInvocation)}
+	for _, inv := range invocations {
+		if inv.AgentID != "" {
+			r.byID[inv.AgentID] = inv
+		} else {
+			r.unmatched = append(r.unmatched, inv)
+		}
+	}
+	sort.SliceStable(r.unmatched, func(i, j int) bool {
+		return r.unmatched[i].Time.Before(r.unmatched[j].Time)
+	})
+	return r
+}
+
+// resolve returns the invocation that started agentFile, a log of project.
+// The Task result naming its agentId decides; failing that, the log's own
+// agentId, and then the latest unmatched Task call of the same session (by
+// sessionId, slug or subagents directory) or, without any, of the same
+// project that shortly precedes the log. A Task call matched this way is
+// not matched again.
+func (r *agentResolver) resolve(agentFile, project string) (types.AgentInvocation, bool) {
+	if inv, ok := r.byID[AgentID(agentFile)]; ok {
+		return inv, true
+	}
+
+	info := readAgentLogInfo(agentFile)
+	if inv, ok := r.byID[info.AgentID]; ok && info.AgentID != "" {
+		return inv, true
+	}
+	if info.Session == "" {
+		info.Session = parentSession(agentFile, types.AgentInvocation{})
+	}
+
+	best := -1
+	for i, inv := range r.unmatched {
+		if info.Start.IsZero() || inv.Time.After(info.Start) || info.Start.Sub(inv.Time) > maxAgentStartDelay {
+			continue
+		}
+		switch {
+		case info.Session != "" || info.Slug != "":
+			if (info.Session == "" || inv.Session != info.Session) && (info.Slug == "" || inv.Slug != info.Slug) {
+				continue
+			}
+		case inv.Project != project:
+			continue
+		}
+		best = i
+	}
+	if best < 0 {
+		return types.AgentInvocation{}, false
+	}
+	inv := r.unmatched[best]
+	r.unmatched = append(r.unmatched[:best], r.unmatched[best+1:]...)
+	return inv, true
+}
+
+// orphanAgent describes an agent log no Task call could be matched to
+func orphanAgent(agentFile, project string) types.OrphanAgent {
+	info := readAgentLogInfo(agentFile)
+	if info.Session == "" {
+		info.Session = parentSession(agentFile, types.AgentInvocation{})
+	}
+	return types.OrphanAgent{
+		Path:    agentFile,
+		Project: project,
+		Session: info.Session,
+		Start:   info.Start,
+		Prompt:  info.Prompt,
+	}
+}
+
+// parentSession returns the ID of the session that started the agent log
+// agentFile: the one that made its invocation, or else the session
+// directory a */subagents/ log is kept in. Returns "" if neither is known.
+func parentSession(agentFile string, inv types.AgentInvocation) string {
+	if inv.Session != "" {
+		return inv.Session
+	}
+	if dir := filepath.Dir(agentFile); filepath.Base(dir) == "subagents" {
+		return filepath.Base(filepath.Dir(dir))
+	}
+	return ""
+}
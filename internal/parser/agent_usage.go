@@ -1,26 +1,17 @@
 package parser
 
 import (
-	"bufio"
+	"bytes"
 	"encoding/json"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/b-open-io/claude-perms/internal/types"
 )
 
-// AgentEntry represents a JSONL entry with agent-specific fields
-type AgentEntry struct {
-	Type      string          `json:"type"`
-	Message   json.RawMessage `json:"message"`
-	Timestamp string          `json:"timestamp"`
-	Slug      string          `json:"slug"`
-	AgentID   string          `json:"agentId"`
-}
-
 // TaskInput represents the input structure for Task tool_use
 type TaskInput struct {
 	SubagentType string `json:"subagent_type"`
@@ -28,109 +19,130 @@ type TaskInput struct {
 	Description  string `json:"description"`
 }
 
-// LoadAgentUsageStats loads permission usage stats grouped by agent type
-func LoadAgentUsageStats(progress chan<- string) ([]types.AgentUsageStats, error) {
-	return LoadAgentUsageStatsFrom(filepath.Join(claudeDir(), "projects"), progress)
+// LoadAgentUsageStats loads permission usage stats grouped by agent type,
+// from the stats source if one is set
+func LoadAgentUsageStats(progress chan<- Progress) ([]types.AgentUsageStats, error) {
+	if statsSource != nil {
+		return statsSource.AgentUsageStats(ProjectsDir(), progress)
+	}
+	return LoadAgentUsageStatsFrom(ProjectsDir(), progress)
 }
 
-// LoadAgentUsageStatsFrom loads agent usage stats from a specific projects directory
-func LoadAgentUsageStatsFrom(projectsDir string, progress chan<- string) ([]types.AgentUsageStats, error) {
-	// Walk project directories
-	entries, err := os.ReadDir(projectsDir)
+// agentProjectFiles lists the session and agent logs of one project directory
+type agentProjectFiles struct {
+	name         string   // Decoded project path
+	sessionFiles []string // Main session logs (Task invocations)
+	agentFiles   []string // agent-*.jsonl at the root and in */subagents/
+}
+
+// collectAgentProjectFiles globs every project's logs up front so both passes
+// can report progress against a known total
+func collectAgentProjectFiles(projectsDir string) ([]agentProjectFiles, int, int, error) {
+	entries, err := readDir(projectsDir)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil
-		}
-		return nil, err
+		return nil, 0, 0, err
 	}
 
-	// Use the unified cache from cache.go
-	cache := loadCache()
-	cacheDirty := false
-
-	// Maps for aggregation
-	agentIdToAgentType := make(map[string]string)
-	agentStats := make(map[string]*agentStatsBuilder)
-
-	// First pass: scan all non-agent session files to build agentId->agentType mapping
+	var projects []agentProjectFiles
+	totalSessions, totalAgents := 0, 0
 	for _, entry := range entries {
 		if !entry.IsDir() {
 			continue
 		}
 
 		projectPath := filepath.Join(projectsDir, entry.Name())
-
-		allFiles, err := filepath.Glob(filepath.Join(projectPath, "*.jsonl"))
+		allFiles, err := glob(filepath.Join(projectPath, "*.jsonl"))
 		if err != nil {
 			continue
 		}
 
-		projectName := decodeProjectPath(entry.Name())
-		if progress != nil {
-			progress <- projectName
-		}
-
-		for _, sessionFile := range allFiles {
-			baseName := filepath.Base(sessionFile)
-			if strings.HasPrefix(baseName, "agent-") {
-				continue
-			}
-
-			// Try cache first
-			if cached, hit := getCachedAgentMappings(cache, sessionFile); hit {
-				for k, v := range cached {
-					agentIdToAgentType[k] = v
-				}
-				continue
-			}
-
-			if progress != nil {
-				sessionID := strings.TrimSuffix(baseName, ".jsonl")
-				if len(sessionID) > 12 {
-					sessionID = sessionID[:12] + "..."
-				}
-				progress <- "session:" + sessionID
-			}
-
-			// Parse and cache
-			localMap := make(map[string]string)
-			extractAgentIdMappings(sessionFile, localMap)
-			for k, v := range localMap {
-				agentIdToAgentType[k] = v
+		files := agentProjectFiles{name: CanonicalProject(projectDirPath(projectPath))}
+		for _, f := range allFiles {
+			if strings.HasPrefix(filepath.Base(f), "agent-") {
+				files.agentFiles = append(files.agentFiles, f)
+			} else {
+				files.sessionFiles = append(files.sessionFiles, f)
 			}
-			setCachedAgentMappings(cache, sessionFile, localMap)
-			cacheDirty = true
 		}
-	}
 
-	// Second pass: scan agent-*.jsonl files to extract tool_uses
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
+		// Also find agent files in session subagent directories
+		subagentFiles, err := glob(filepath.Join(projectPath, "*/subagents/agent-*.jsonl"))
+		if err == nil {
+			files.agentFiles = append(files.agentFiles, subagentFiles...)
 		}
 
-		projectPath := filepath.Join(projectsDir, entry.Name())
-		projectName := decodeProjectPath(entry.Name())
+		projects = append(projects, files)
+		totalSessions += len(files.sessionFiles)
+		totalAgents += len(files.agentFiles)
+	}
+	return projects, totalSessions, totalAgents, nil
+}
 
-		if progress != nil {
-			progress <- projectName
+// LoadAgentUsageStatsFrom loads agent usage stats from a specific projects directory
+func LoadAgentUsageStatsFrom(projectsDir string, progress chan<- Progress) ([]types.AgentUsageStats, error) {
+	// Walk project directories
+	projects, totalSessions, totalAgents, err := collectAgentProjectFiles(projectsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
 		}
+		return nil, err
+	}
 
-		// Find agent-*.jsonl files at project root
-		agentFiles, err := filepath.Glob(filepath.Join(projectPath, "agent-*.jsonl"))
-		if err != nil {
-			continue
-		}
+	// Use the unified cache from cache.go
+	cache := loadCache()
+	parsed := newCache() // The entries to save
+	cacheDirty := false
 
-		// Also find agent files in session subagent directories
-		subagentFiles, err := filepath.Glob(filepath.Join(projectPath, "*/subagents/agent-*.jsonl"))
-		if err == nil {
-			agentFiles = append(agentFiles, subagentFiles...)
+	// Maps for aggregation
+	var invocations []types.AgentInvocation
+	agents := make(agentAggregator)
+
+	// First pass: scan all non-agent session files for the Task calls that
+	// started subagents, which map agentIds to agent types
+	done := 0
+	for _, project := range projects {
+		for _, sessionFile := range project.sessionFiles {
+			sendProgress(progress, Progress{
+				Stage:   "Mapping agent invocations",
+				Project: project.name,
+				Session: shortID(strings.TrimSuffix(filepath.Base(sessionFile), ".jsonl")),
+				Done:    done,
+				Total:   totalSessions,
+			})
+			done++
+
+			// Try cache first
+			found, hit := getCachedAgentInvocations(cache, sessionFile)
+			if !hit {
+				found = extractAgentInvocations(sessionFile)
+				setCachedAgentInvocations(parsed, sessionFile, found)
+				cacheDirty = true
+			}
+			for _, inv := range found {
+				inv.Project = project.name
+				invocations = append(invocations, inv)
+			}
 		}
+	}
+	resolver := newAgentResolver(invocations)
 
-		for _, agentFile := range agentFiles {
-			baseName := filepath.Base(agentFile)
-			agentId := strings.TrimSuffix(strings.TrimPrefix(baseName, "agent-"), ".jsonl")
+	// Second pass: scan agent-*.jsonl files to extract tool_uses
+	done = 0
+	for _, project := range projects {
+		projectName := project.name
+
+		for _, agentFile := range project.agentFiles {
+			agentId := AgentID(agentFile)
+
+			sendProgress(progress, Progress{
+				Stage:   "Scanning agent sessions",
+				Project: projectName,
+				Session: shortID(agentId),
+				Done:    done,
+				Total:   totalAgents,
+			})
+			done++
 
 			// Try cache first
 			var perms []types.PermissionStats
@@ -140,125 +152,91 @@ func LoadAgentUsageStatsFrom(projectsDir string, progress chan<- string) ([]type
 				perms = cachedPerms
 				sessionTime = cachedTime
 			} else {
-				perms, sessionTime = parseAgentSession(agentFile)
-				setCachedAgentSession(cache, agentFile, perms, sessionTime)
+				var warns []Warning
+				perms, sessionTime, warns = parseAgentSession(agentFile)
+				recordWarnings(warns)
+				setCachedAgentSession(parsed, agentFile, perms, sessionTime, warns)
 				cacheDirty = true
 			}
 
-			agentType, ok := agentIdToAgentType[agentId]
+			inv, ok := resolver.resolve(agentFile, projectName)
 			if !ok {
-				agentType = "Unknown"
-			}
-
-			if _, exists := agentStats[agentType]; !exists {
-				agentStats[agentType] = &agentStatsBuilder{
-					agentType:   agentType,
-					permissions: make(map[string]*types.PermissionStats),
-					sessions:    make(map[string]bool),
-					projects:    make(map[string]bool),
-					lastSeen:    time.Time{},
-				}
+				inv.AgentType = "Unknown"
 			}
-
-			builder := agentStats[agentType]
-			builder.sessions[agentFile] = true
-			builder.projects[projectName] = true
-
-			for _, p := range perms {
-				key := PermissionKey(p.Permission)
-				if _, exists := builder.permissions[key]; !exists {
-					builder.permissions[key] = &types.PermissionStats{
-						Permission: p.Permission,
-						Count:      0,
-						LastSeen:   time.Time{},
-					}
-				}
-				builder.permissions[key].Count += p.Count
-				if p.LastSeen.After(builder.permissions[key].LastSeen) {
-					builder.permissions[key].LastSeen = p.LastSeen
-				}
-			}
-
-			if sessionTime.After(builder.lastSeen) {
-				builder.lastSeen = sessionTime
+			agents.add(inv.AgentType, agentFile, parentSession(agentFile, inv), projectName, perms, sessionTime)
+			if !ok {
+				agents.addOrphan(inv.AgentType, orphanAgent(agentFile, projectName))
 			}
 		}
 	}
+	agents.addInvocations(invocations)
 
 	// Save unified cache if anything changed
 	if cacheDirty {
-		_ = saveCache(cache)
-	}
-
-	// Convert to output slice
-	result := make([]types.AgentUsageStats, 0, len(agentStats))
-	for agentType, builder := range agentStats {
-		perms := make([]types.PermissionStats, 0, len(builder.permissions))
-		totalCalls := 0
-		for _, p := range builder.permissions {
-			perms = append(perms, *p)
-			totalCalls += p.Count
+		if err := saveCache(parsed); err != nil {
+			logger.Warn("saving cache failed", "err", err)
 		}
+	}
 
-		sort.Slice(perms, func(i, j int) bool {
-			return perms[i].Count > perms[j].Count
-		})
+	return agents.stats(), nil
+}
 
-		projects := make([]string, 0, len(builder.projects))
-		for proj := range builder.projects {
-			projects = append(projects, proj)
-		}
-		sort.Strings(projects)
-
-		result = append(result, types.AgentUsageStats{
-			AgentType:   agentType,
-			Permissions: perms,
-			TotalCalls:  totalCalls,
-			LastSeen:    builder.lastSeen,
-			Sessions:    len(builder.sessions),
-			Projects:    projects,
-		})
-	}
+// AgentID returns the agent ID of an agent-<id>.jsonl log
+func AgentID(agentFile string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(filepath.Base(agentFile), "agent-"), ".jsonl")
+}
 
-	sort.Slice(result, func(i, j int) bool {
-		return result[i].TotalCalls > result[j].TotalCalls
-	})
+// maxPromptLen caps the prompt kept per invocation, in bytes
+const maxPromptLen = 2000
 
-	return result, nil
+// AgentInvocations returns the Task calls in a session log that started
+// subagents, attributed to project
+func AgentInvocations(sessionPath, project string) []types.AgentInvocation {
+	invocations := extractAgentInvocations(sessionPath)
+	for i := range invocations {
+		invocations[i].Project = project
+	}
+	return invocations
 }
 
-// agentStatsBuilder accumulates stats for a single agent type
-type agentStatsBuilder struct {
-	agentType   string
-	permissions map[string]*types.PermissionStats
-	sessions    map[string]bool
-	projects    map[string]bool
-	lastSeen    time.Time
+// FilterInvocations returns the invocations started in [since, until),
+// either bound zero for none, attributed to project
+func FilterInvocations(invocations []types.AgentInvocation, project string, since, until time.Time) []types.AgentInvocation {
+	var out []types.AgentInvocation
+	for _, inv := range invocations {
+		if (!since.IsZero() && inv.Time.Before(since)) || (!until.IsZero() && !inv.Time.Before(until)) {
+			continue
+		}
+		inv.Project = project
+		out = append(out, inv)
+	}
+	return out
 }
 
-// extractAgentIdMappings scans a session file for Task tool_uses and extracts agentId->agentType mappings
-func extractAgentIdMappings(sessionPath string, agentIdToAgentType map[string]string) {
-	file, err := os.Open(sessionPath)
+// extractAgentInvocations scans a session file for Task tool_uses that start
+// subagents, matching each to the agentId its tool_result reports
+func extractAgentInvocations(sessionPath string) []types.AgentInvocation {
+	file, err := openFile(sessionPath)
 	if err != nil {
-		return
+		return nil
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 1024*1024)
-
-	// Track pending Task tool_uses: tool_use_id -> agentType
-	pendingTasks := make(map[string]string)
+	session := strings.TrimSuffix(filepath.Base(sessionPath), ".jsonl")
+	var invocations []types.AgentInvocation
+	pendingTasks := make(map[string]int) // tool_use_id -> index in invocations
 
-	for scanner.Scan() {
-		line := scanner.Bytes()
+	// Lines too long to read are reported by parseSessionLog, which reads
+	// the same file
+	lines := newLineReader(file)
+	for lines.Next() {
+		line := lines.Bytes()
 
 		// Quick check for relevant content
-		hasTask := strings.Contains(string(line), `"Task"`)
-		hasSubagent := strings.Contains(string(line), `"subagent_type"`)
-		hasToolResult := strings.Contains(string(line), `"tool_result"`)
-		hasToolUseResult := strings.Contains(string(line), `"toolUseResult"`)
+		hasTask := bytes.Contains(line, []byte(`"Task"`))
+		hasSubagent := bytes.Contains(line, []byte(`"subagent_type"`))
+		hasToolResult := bytes.Contains(line, toolResultMarker)
+		hasToolUseResult := bytes.Contains(line, []byte(`"toolUseResult"`))
 
 		if !hasTask && !hasSubagent && !hasToolResult && !hasToolUseResult {
 			continue
@@ -266,8 +244,10 @@ func extractAgentIdMappings(sessionPath string, agentIdToAgentType map[string]st
 
 		// Parse entry with toolUseResult field
 		var rawEntry struct {
-			Type          string          `json:"type"`
-			Message       json.RawMessage `json:"message"`
+			Type          string  `json:"type"`
+			Timestamp     string  `json:"timestamp"`
+			Slug          string  `json:"slug"`
+			Message       rawJSON `json:"message"`
 			ToolUseResult struct {
 				AgentID string `json:"agentId"`
 			} `json:"toolUseResult"`
@@ -276,7 +256,7 @@ func extractAgentIdMappings(sessionPath string, agentIdToAgentType map[string]st
 			continue
 		}
 
-		// If this is an assistant message with Task tool_use, extract tool_use_id and subagent_type
+		// If this is an assistant message with Task tool_use, record the invocation
 		if rawEntry.Type == "assistant" && hasTask && hasSubagent {
 			// Parse the full message structure to get tool_use ID and subagent_type
 			type ToolUseItem struct {
@@ -293,14 +273,23 @@ func extractAgentIdMappings(sessionPath string, agentIdToAgentType map[string]st
 				continue
 			}
 
+			t, _ := time.Parse(time.RFC3339, rawEntry.Timestamp)
 			for _, item := range fullMsg.Content {
 				if item.Type == "tool_use" && item.Name == "Task" && item.Input.SubagentType != "" {
-					pendingTasks[item.ID] = item.Input.SubagentType
+					pendingTasks[item.ID] = len(invocations)
+					invocations = append(invocations, types.AgentInvocation{
+						AgentType:   item.Input.SubagentType,
+						Session:     session,
+						Slug:        rawEntry.Slug,
+						Time:        t,
+						Description: item.Input.Description,
+						Prompt:      truncatePrompt(item.Input.Prompt),
+					})
 				}
 			}
 		}
 
-		// If user message with tool_result AND toolUseResult.agentId, map agentId to agent type
+		// If user message with tool_result AND toolUseResult.agentId, map agentId to the invocation
 		if rawEntry.Type == "user" && hasToolResult && rawEntry.ToolUseResult.AgentID != "" {
 			// Parse to find the tool_use_id this result is for
 			type ToolResultItem struct {
@@ -317,99 +306,39 @@ func extractAgentIdMappings(sessionPath string, agentIdToAgentType map[string]st
 
 			for _, item := range userMsg.Content {
 				if item.Type == "tool_result" {
-					if agentType, ok := pendingTasks[item.ToolUseID]; ok {
-						agentIdToAgentType[rawEntry.ToolUseResult.AgentID] = agentType
+					if i, ok := pendingTasks[item.ToolUseID]; ok {
+						invocations[i].AgentID = rawEntry.ToolUseResult.AgentID
 						delete(pendingTasks, item.ToolUseID)
 					}
 				}
 			}
 		}
 	}
+	return invocations
 }
 
-// parseAgentSession parses an agent-*.jsonl file and extracts tool_uses
-func parseAgentSession(agentPath string) (perms []types.PermissionStats, lastSeen time.Time) {
-	file, err := os.Open(agentPath)
-	if err != nil {
-		return nil, time.Time{}
+// truncatePrompt shortens a prompt to maxPromptLen, on a rune boundary,
+// with its secrets redacted
+func truncatePrompt(prompt string) string {
+	prompt = redactor.Redact(prompt)
+	if len(prompt) <= maxPromptLen {
+		return prompt
 	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 1024*1024)
-
-	counts := make(map[string]int)
-	lastSeenMap := make(map[string]time.Time)
-
-	for scanner.Scan() {
-		line := scanner.Bytes()
-
-		// Quick check for tool_use
-		if !strings.Contains(string(line), `"tool_use"`) {
-			continue
-		}
-
-		var entry AgentEntry
-		if err := json.Unmarshal(line, &entry); err != nil {
-			continue
-		}
-
-		// Only process assistant messages
-		if entry.Type != "assistant" {
-			continue
-		}
-
-		// Parse message
-		var msg AssistantMessage
-		if err := json.Unmarshal(entry.Message, &msg); err != nil {
-			continue
-		}
-
-		// Extract tool_uses
-		for _, item := range msg.Content {
-			if item.Type == "tool_use" && item.Name != "" {
-				// Skip Task tool itself - we want to track what the agent uses
-				if item.Name == "Task" {
-					continue
-				}
-
-				// Extract full permission with scope
-				permString := ExtractPermissionScope(item.Name, item.Input)
-				perm := ParsePermission(permString)
-				key := PermissionKey(perm)
-
-				counts[key]++
-
-				// Parse entry timestamp
-				entryTime := time.Time{}
-				if entry.Timestamp != "" {
-					if t, err := time.Parse(time.RFC3339, entry.Timestamp); err == nil {
-						entryTime = t
-					}
-				}
-
-				if _, exists := lastSeenMap[key]; !exists || entryTime.After(lastSeenMap[key]) {
-					lastSeenMap[key] = entryTime
-				}
-
-				if entryTime.After(lastSeen) {
-					lastSeen = entryTime
-				}
-			}
-		}
+	cut := maxPromptLen
+	for cut > 0 && !utf8.RuneStart(prompt[cut]) {
+		cut--
 	}
+	return prompt[:cut] + "…"
+}
 
-	// Convert to stats
-	perms = make([]types.PermissionStats, 0, len(counts))
-	for key, count := range counts {
-		perm := ParsePermission(key)
-		perms = append(perms, types.PermissionStats{
-			Permission: perm,
-			Count:      count,
-			LastSeen:   lastSeenMap[key],
-		})
+// parseAgentSession parses an agent-*.jsonl file into per-permission stats
+// and the time of its latest tool_use. Malformed lines are skipped and
+// returned as warnings.
+func parseAgentSession(agentPath string) (perms []types.PermissionStats, lastSeen time.Time, warns []Warning) {
+	events, warns, err := ParseAgentEvents(agentPath)
+	if err != nil {
+		return nil, time.Time{}, []Warning{{File: agentPath, Reason: "unreadable agent log: " + err.Error()}}
 	}
-
-	return perms, lastSeen
+	perms, lastSeen = agentStats(events)
+	return perms, lastSeen, warns
 }
@@ -0,0 +1,148 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/types"
+)
+
+func TestAgentInvocations(t *testing.T) {
+	log := `{"type":"assistant","timestamp":"2026-10-10T09:00:00Z","message":{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Task","input":{"subagent_type":"code-reviewer","description":"Review the diff","prompt":"Look at the staged changes"}}]}}
+{"type":"user","timestamp":"2026-10-10T09:05:00Z","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"done"}]},"toolUseResult":{"agentId":"a1"}}
+{"type":"assistant","timestamp":"2026-10-10T10:00:00Z","message":{"role":"assistant","content":[{"type":"tool_use","id":"t2","name":"Task","input":{"subagent_type":"code-reviewer","description":"Review again","prompt":"And now the fix"}}]}}
+`
+	dir := t.TempDir()
+	session := filepath.Join(dir, "s1.jsonl")
+	if err := os.WriteFile(session, []byte(log), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	invocations := AgentInvocations(session, "/work/app")
+	if len(invocations) != 2 {
+		t.Fatalf("got %d invocations, want 2: %+v", len(invocations), invocations)
+	}
+	first := invocations[0]
+	if first.AgentID != "a1" || first.AgentType != "code-reviewer" || first.Session != "s1" || first.Project != "/work/app" ||
+		first.Description != "Review the diff" || first.Prompt != "Look at the staged changes" ||
+		!first.Time.Equal(time.Date(2026, 10, 10, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("first invocation = %+v", first)
+	}
+	if invocations[1].AgentID != "" {
+		t.Errorf("second invocation has agentId %q, want none without a result", invocations[1].AgentID)
+	}
+
+	// The agent named by the first result, and one kept under the second
+	// session's directory without a result naming it
+	events := []ToolEvent{{Time: time.Date(2026, 10, 10, 9, 1, 0, 0, time.UTC), Permission: "Read"}}
+	logs := []LogEvents{
+		{Path: session, Project: "/work/app", Invocations: invocations},
+		{Path: filepath.Join(dir, "agent-a1.jsonl"), Project: "/work/app", AgentType: "code-reviewer", Events: events},
+		{Path: filepath.Join(dir, "s2", "subagents", "agent-a2.jsonl"), Project: "/work/app", AgentType: "code-reviewer", Events: events},
+	}
+	agents := AggregateAgentUsage(logs)
+	if len(agents) != 1 {
+		t.Fatalf("got %d agent types, want 1: %+v", len(agents), agents)
+	}
+	got := agents[0]
+	if got.Sessions != 2 {
+		t.Errorf("Sessions = %d, want 2 (s1 and s2)", got.Sessions)
+	}
+	if len(got.Invocations) != 2 || got.Invocations[0].Description != "Review again" {
+		t.Errorf("Invocations = %+v, want both, newest first", got.Invocations)
+	}
+}
+
+func TestAgentInvocationsBounded(t *testing.T) {
+	start := time.Date(2026, 10, 10, 9, 0, 0, 0, time.UTC)
+	var invocations []types.AgentInvocation
+	for i := 0; i < maxAgentInvocations+10; i++ {
+		invocations = append(invocations, types.AgentInvocation{AgentType: "Explore", Session: "s1", Time: start.Add(time.Duration(i) * time.Minute)})
+	}
+	logs := []LogEvents{
+		{Path: "/p/s1.jsonl", Invocations: invocations},
+		{Path: "/p/s1/subagents/agent-a1.jsonl", AgentType: "Explore", Events: []ToolEvent{{Time: start, Permission: "Read"}}},
+	}
+
+	got := AggregateAgentUsage(logs)[0].Invocations
+	if len(got) != maxAgentInvocations {
+		t.Fatalf("kept %d invocations, want %d", len(got), maxAgentInvocations)
+	}
+	if want := invocations[len(invocations)-1].Time; !got[0].Time.Equal(want) {
+		t.Errorf("first invocation at %v, want the latest, %v", got[0].Time, want)
+	}
+}
+
+func TestResolveAgents(t *testing.T) {
+	at := func(min int) time.Time { return time.Date(2026, 10, 10, 9, min, 0, 0, time.UTC) }
+	invocations := []types.AgentInvocation{
+		{AgentType: "named", AgentID: "inner", Session: "s1", Project: "/app", Time: at(0)},
+		{AgentType: "by-slug", Session: "s1", Slug: "quiet-fox", Project: "/app", Time: at(10)},
+		{AgentType: "by-time", Session: "s2", Project: "/app", Time: at(20)},
+		{AgentType: "too-early", Session: "s3", Project: "/app", Time: at(30)},
+	}
+
+	dir := t.TempDir()
+	agentLog := func(name, line string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(line+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	cases := []struct {
+		path string
+		want string
+	}{
+		{agentLog("agent-file.jsonl", `{"type":"user","agentId":"inner","timestamp":"2026-10-10T09:00:05Z","message":{"role":"user","content":"go"}}`), "named"},
+		{agentLog("agent-b.jsonl", `{"type":"user","slug":"quiet-fox","timestamp":"2026-10-10T09:10:05Z","message":{"role":"user","content":"go"}}`), "by-slug"},
+		{agentLog("agent-c.jsonl", `{"type":"user","timestamp":"2026-10-10T09:20:30Z","message":{"role":"user","content":"go"}}`), "by-time"},
+		{agentLog("agent-d.jsonl", `{"type":"user","timestamp":"2026-10-10T09:45:00Z","message":{"role":"user","content":"why me?"}}`), ""},
+	}
+
+	r := newAgentResolver(invocations)
+	for _, c := range cases {
+		inv, ok := r.resolve(c.path, "/app")
+		if got := inv.AgentType; ok != (c.want != "") || got != c.want {
+			t.Errorf("resolve(%s) = %q, %v; want %q", filepath.Base(c.path), got, ok, c.want)
+		}
+	}
+
+	orphan := orphanAgent(cases[3].path, "/app")
+	if orphan.Prompt != "why me?" || !orphan.Start.Equal(at(45)) {
+		t.Errorf("orphan = %+v, want its first message and start", orphan)
+	}
+}
+
+func TestTaskCallsCountPerSubagentType(t *testing.T) {
+	dir := t.TempDir()
+	session := filepath.Join(dir, "s1.jsonl")
+	agent := filepath.Join(dir, "agent-a1.jsonl")
+	call := func(id, input string) string {
+		return `{"type":"assistant","timestamp":"2026-10-10T09:00:00Z","message":{"role":"assistant","content":[{"type":"tool_use","id":"` + id + `","name":"Task","input":` + input + `}]}}` + "\n"
+	}
+	if err := os.WriteFile(session, []byte(call("t1", `{"subagent_type":"Explore","description":"Look around"}`)+call("t2", `{"description":"Old log"}`)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(agent, []byte(call("t3", `{"subagent_type":"code-reviewer","description":"Review it"}`)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	events, _, err := ParseSessionEvents(session, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Permission != "Task(Explore)" || events[1].Permission != "Task" {
+		t.Errorf("session events = %+v, want Task(Explore) and a bare Task without a type", events)
+	}
+
+	events, _, err = ParseAgentEvents(agent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Permission != "Task(code-reviewer)" || events[0].Sample != "code-reviewer: Review it" {
+		t.Errorf("agent events = %+v, want the Task call it made", events)
+	}
+}
@@ -2,7 +2,6 @@ package parser
 
 import (
 	"bufio"
-	"os"
 	"path/filepath"
 	"strings"
 
@@ -36,7 +35,7 @@ func loadAgentsFromDir(dir, pluginName string) ([]types.AgentPermissions, error)
 
 // loadAgentsFromDirWithVersion loads all agents from a directory with version info
 func loadAgentsFromDirWithVersion(dir, pluginName, version string) ([]types.AgentPermissions, error) {
-	entries, err := os.ReadDir(dir)
+	entries, err := readDir(dir)
 	if err != nil {
 		return nil, err
 	}
@@ -55,10 +54,11 @@ func loadAgentsFromDirWithVersion(dir, pluginName, version string) ([]types.Agen
 		path := filepath.Join(dir, entry.Name())
 		agent, err := parseAgentFile(path, pluginName, version)
 		if err != nil {
+			addWarning(path, 0, "invalid agent frontmatter: %v", err)
 			continue
 		}
 
-		if len(agent.Permissions) > 0 {
+		if agent.Declares() {
 			agents = append(agents, agent)
 		}
 	}
@@ -66,69 +66,22 @@ func loadAgentsFromDirWithVersion(dir, pluginName, version string) ([]types.Agen
 	return agents, nil
 }
 
-// loadAgentsFromPlugins loads agents from installed plugins
+// loadAgentsFromPlugins loads agents from the latest installed version of
+// every plugin
 func loadAgentsFromPlugins() ([]types.AgentPermissions, error) {
-	cacheDir := filepath.Join(claudeDir(), "plugins", "cache")
-
 	var agents []types.AgentPermissions
-
-	// Walk the cache directory structure: cache/<marketplace>/<plugin>/<version>/
-	marketplaces, err := os.ReadDir(cacheDir)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, marketplace := range marketplaces {
-		if !marketplace.IsDir() {
-			continue
-		}
-
-		marketplacePath := filepath.Join(cacheDir, marketplace.Name())
-		plugins, err := os.ReadDir(marketplacePath)
-		if err != nil {
-			continue
-		}
-
-		for _, plugin := range plugins {
-			if !plugin.IsDir() {
-				continue
-			}
-
-			pluginPath := filepath.Join(marketplacePath, plugin.Name())
-			versions, err := os.ReadDir(pluginPath)
-			if err != nil {
-				continue
-			}
-
-			// Find the latest version (highest semver string)
-			var latestVersion string
-			for _, version := range versions {
-				if !version.IsDir() {
-					continue
-				}
-				if version.Name() > latestVersion {
-					latestVersion = version.Name()
-				}
-			}
-
-			if latestVersion != "" {
-				versionPath := filepath.Join(pluginPath, latestVersion)
-				agentsDir := filepath.Join(versionPath, "agents")
-
-				pluginAgents, err := loadAgentsFromDirWithVersion(agentsDir, plugin.Name(), latestVersion)
-				if err == nil {
-					agents = append(agents, pluginAgents...)
-				}
-			}
+	for _, p := range latestPluginVersions() {
+		pluginAgents, err := loadAgentsFromDirWithVersion(filepath.Join(p.Dir, "agents"), p.Name, p.Version)
+		if err == nil {
+			agents = append(agents, pluginAgents...)
 		}
 	}
-
 	return agents, nil
 }
 
 // parseAgentFile parses an agent markdown file and extracts frontmatter
 func parseAgentFile(path, pluginName, version string) (types.AgentPermissions, error) {
-	file, err := os.Open(path)
+	file, err := openFile(path)
 	if err != nil {
 		return types.AgentPermissions{}, err
 	}
@@ -181,6 +134,8 @@ func parseAgentFile(path, pluginName, version string) (types.AgentPermissions, e
 		Version:     version,
 		FilePath:    path,
 		Permissions: ParsePermissions(parseToolsField(frontmatter.Tools)),
+		Allow:       ParsePermissions(parseToolsField(frontmatter.Permissions.Allow)),
+		Deny:        ParsePermissions(parseToolsField(frontmatter.Permissions.Deny)),
 	}, nil
 }
 
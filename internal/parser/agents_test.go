@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseAgentFilePermissions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deployer.md")
+	content := `---
+name: deployer
+tools: Read, Bash
+permissions:
+  allow:
+    - Bash(git push:*)
+    - Bash(npm run deploy:*)
+  deny:
+    - Bash(rm -rf:*)
+---
+
+Deploys things.
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	agent, err := parseAgentFile(path, "", "")
+	if err != nil {
+		t.Fatalf("parseAgentFile: %v", err)
+	}
+	var tools, allow, deny []string
+	for _, p := range agent.Permissions {
+		tools = append(tools, p.Raw)
+	}
+	for _, p := range agent.Allow {
+		allow = append(allow, p.Raw)
+	}
+	for _, p := range agent.Deny {
+		deny = append(deny, p.Raw)
+	}
+	if want := []string{"Read", "Bash"}; !reflect.DeepEqual(tools, want) {
+		t.Errorf("tools = %v, want %v", tools, want)
+	}
+	if want := []string{"Bash(git push:*)", "Bash(npm run deploy:*)"}; !reflect.DeepEqual(allow, want) {
+		t.Errorf("allow = %v, want %v", allow, want)
+	}
+	if want := []string{"Bash(rm -rf:*)"}; !reflect.DeepEqual(deny, want) {
+		t.Errorf("deny = %v, want %v", deny, want)
+	}
+}
@@ -0,0 +1,317 @@
+package parser
+
+import (
+	"maps"
+	"sort"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/types"
+)
+
+// permissionAggregator merges per-session stats into totals per normalized
+// permission
+type permissionAggregator map[string]*types.PermissionStats
+
+// add merges the stats of the session log at logPath, attributing them to
+// project and the log's host. Logs from hosts the host filter excludes are
+// left out.
+func (a permissionAggregator) add(perms []types.PermissionStats, logPath, project string) {
+	host := HostOf(logPath)
+	if !hostSelected(host) {
+		return
+	}
+	for _, p := range perms {
+		perm := NormalizePermission(p.Permission)
+		key := PermissionKey(perm)
+
+		if _, exists := a[key]; !exists {
+			a[key] = &types.PermissionStats{
+				Permission:    perm,
+				ProjectCounts: make(map[string]int),
+				HostCounts:    make(map[string]int),
+				Variants:      make(map[string]int),
+				ByAttribution: make(map[types.Attribution]int),
+			}
+		}
+		mergeSessionStats(a[key], p, project, host)
+		a[key].ByAttribution[types.AttributedMain] += p.Count
+	}
+}
+
+// stats returns the totals, most used first
+func (a permissionAggregator) stats() []types.PermissionStats {
+	stats := make([]types.PermissionStats, 0, len(a))
+	for _, s := range a {
+		s.Projects = sortedProjects(s.ProjectCounts)
+		stats = append(stats, *s)
+	}
+	sortStats(stats)
+	return stats
+}
+
+// agentStatsBuilder accumulates stats for a single agent type
+type agentStatsBuilder struct {
+	agentType   string
+	permissions map[string]*types.PermissionStats
+	sessions    map[string]bool // Parent session IDs, or agent log paths where unknown
+	projects    map[string]bool
+	lastSeen    time.Time
+	invocations []types.AgentInvocation
+	orphans     []types.OrphanAgent
+}
+
+// agentAggregator merges per-log agent stats into totals per agent type
+type agentAggregator map[string]*agentStatsBuilder
+
+// add merges the stats of one agent log, started by the session with ID
+// session if known, unless the host filter excludes its host
+func (a agentAggregator) add(agentType, agentFile, session, project string, perms []types.PermissionStats, sessionTime time.Time) {
+	if !hostSelected(HostOf(agentFile)) {
+		return
+	}
+	if _, exists := a[agentType]; !exists {
+		a[agentType] = &agentStatsBuilder{
+			agentType:   agentType,
+			permissions: make(map[string]*types.PermissionStats),
+			sessions:    make(map[string]bool),
+			projects:    make(map[string]bool),
+		}
+	}
+
+	builder := a[agentType]
+	if session == "" {
+		session = agentFile
+	}
+	builder.sessions[session] = true
+	builder.projects[project] = true
+
+	for _, p := range perms {
+		perm := NormalizePermission(p.Permission)
+		key := PermissionKey(perm)
+		if _, exists := builder.permissions[key]; !exists {
+			builder.permissions[key] = &types.PermissionStats{
+				Permission:    perm,
+				ProjectCounts: make(map[string]int),
+				HostCounts:    make(map[string]int),
+				Variants:      make(map[string]int),
+			}
+		}
+		mergeSessionStats(builder.permissions[key], p, project, HostOf(agentFile))
+	}
+
+	if sessionTime.After(builder.lastSeen) {
+		builder.lastSeen = sessionTime
+	}
+}
+
+// addOrphan records an agent log of agentType that no Task call could be
+// matched to, if add kept the log
+func (a agentAggregator) addOrphan(agentType string, o types.OrphanAgent) {
+	if builder, ok := a[agentType]; ok && hostSelected(HostOf(o.Path)) {
+		builder.orphans = append(builder.orphans, o)
+	}
+}
+
+// maxAgentInvocations bounds the invocations kept per agent type, newest
+// first
+const maxAgentInvocations = 50
+
+// addInvocations attaches the Task calls that started each agent type with
+// a log
+func (a agentAggregator) addInvocations(invocations []types.AgentInvocation) {
+	for _, inv := range invocations {
+		if builder, ok := a[inv.AgentType]; ok {
+			builder.invocations = append(builder.invocations, inv)
+		}
+	}
+}
+
+// stats returns the totals, most active agent first
+func (a agentAggregator) stats() []types.AgentUsageStats {
+	result := make([]types.AgentUsageStats, 0, len(a))
+	for agentType, builder := range a {
+		perms := make([]types.PermissionStats, 0, len(builder.permissions))
+		totalCalls, totalTokens := 0, 0
+		var slowest []types.SlowCall
+		for _, p := range builder.permissions {
+			p.Projects = sortedProjects(p.ProjectCounts)
+			perms = append(perms, *p)
+			totalCalls += p.Count
+			totalTokens += p.Tokens
+			slowest = types.AddSlowCalls(slowest, p.Slowest...)
+		}
+
+		sort.Slice(perms, func(i, j int) bool {
+			return perms[i].Count > perms[j].Count
+		})
+
+		projects := make([]string, 0, len(builder.projects))
+		for proj := range builder.projects {
+			projects = append(projects, proj)
+		}
+		sort.Strings(projects)
+
+		sort.SliceStable(builder.invocations, func(i, j int) bool {
+			return builder.invocations[i].Time.After(builder.invocations[j].Time)
+		})
+		if len(builder.invocations) > maxAgentInvocations {
+			builder.invocations = builder.invocations[:maxAgentInvocations]
+		}
+		sort.Slice(builder.orphans, func(i, j int) bool {
+			return builder.orphans[i].Start.After(builder.orphans[j].Start)
+		})
+
+		result = append(result, types.AgentUsageStats{
+			AgentType:   agentType,
+			Permissions: perms,
+			TotalCalls:  totalCalls,
+			TotalTokens: totalTokens,
+			Slowest:     slowest,
+			LastSeen:    builder.lastSeen,
+			Sessions:    len(builder.sessions),
+			Projects:    projects,
+			Invocations: builder.invocations,
+			Orphans:     builder.orphans,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].TotalCalls > result[j].TotalCalls
+	})
+
+	return result
+}
+
+// LogEvents is the tool events of one session or agent log
+type LogEvents struct {
+	Path      string
+	Project   string // Decoded project path
+	AgentType string // Subagent type for agent logs ("Unknown" if unmapped), empty for sessions
+	Events    []ToolEvent
+
+	Invocations []types.AgentInvocation `json:",omitempty"` // Task calls that started subagents, for session logs
+}
+
+// DropRepeatedCalls removes from logs the tool calls an earlier log has, as
+// the log of a resumed or forked session repeats the calls of the one it
+// picks up
+func DropRepeatedCalls(logs []LogEvents) {
+	counted := make(toolUseSet)
+	for i := range logs {
+		logs[i].Events = counted.fresh(logs[i].Events)
+	}
+}
+
+// AggregatePermissionStats totals session log events per normalized
+// permission, as the session loaders do, counting a call repeated in
+// several logs once
+func AggregatePermissionStats(logs []LogEvents) []types.PermissionStats {
+	stats := make(permissionAggregator)
+	counted := make(toolUseSet)
+	for _, l := range logs {
+		stats.add(sessionStats(counted.fresh(l.Events)), l.Path, l.Project)
+	}
+	return stats.stats()
+}
+
+// AggregateAgentUsage totals agent log events per agent type, as
+// LoadAgentUsageStats does. Session logs only contribute the invocations
+// that started the agents.
+func AggregateAgentUsage(logs []LogEvents) []types.AgentUsageStats {
+	var invocations []types.AgentInvocation
+	for _, l := range logs {
+		if l.AgentType == "" {
+			invocations = append(invocations, l.Invocations...)
+		}
+	}
+	resolver := newAgentResolver(invocations)
+
+	agents := make(agentAggregator)
+	for _, l := range logs {
+		if l.AgentType == "" {
+			continue
+		}
+		inv, ok := resolver.resolve(l.Path, l.Project)
+		if !ok {
+			inv.AgentType = l.AgentType
+		}
+		perms, lastSeen := agentStats(l.Events)
+		agents.add(inv.AgentType, l.Path, parentSession(l.Path, inv), l.Project, perms, lastSeen)
+		if !ok {
+			agents.addOrphan(inv.AgentType, orphanAgent(l.Path, l.Project))
+		}
+	}
+	agents.addInvocations(invocations)
+	return agents.stats()
+}
+
+// AttributePermissionStats adds the subagent tool calls of agents to the
+// main-session stats main, per normalized permission, with ByAttribution
+// telling who made them. Agent logs carry no outcomes, so Approved and
+// Denied stay the main session's. Neither input is modified.
+func AttributePermissionStats(main []types.PermissionStats, agents []types.AgentUsageStats) []types.PermissionStats {
+	stats := make(permissionAggregator, len(main))
+	for _, p := range main {
+		p.ProjectCounts = maps.Clone(p.ProjectCounts)
+		p.HostCounts = maps.Clone(p.HostCounts)
+		p.Variants = maps.Clone(p.Variants)
+		p.Inputs = maps.Clone(p.Inputs)
+		p.ByAttribution = maps.Clone(p.ByAttribution)
+		stats[PermissionKey(p.Permission)] = &p
+	}
+
+	for _, a := range agents {
+		who := types.AttributedAgent
+		if a.AgentType == "Unknown" {
+			who = types.AttributedUnknown
+		}
+		for _, p := range a.Permissions {
+			key := PermissionKey(p.Permission)
+			dst, exists := stats[key]
+			if !exists {
+				dst = &types.PermissionStats{Permission: p.Permission}
+				stats[key] = dst
+			}
+			mergeAgentStats(dst, p)
+			if dst.ByAttribution == nil {
+				dst.ByAttribution = make(map[types.Attribution]int)
+			}
+			dst.ByAttribution[who] += p.Count
+		}
+	}
+	return stats.stats()
+}
+
+// mergeAgentStats adds an agent's totals for a permission into dst, which
+// may have nil count maps
+func mergeAgentStats(dst *types.PermissionStats, p types.PermissionStats) {
+	dst.Count += p.Count
+	dst.Tokens += p.Tokens
+	if p.LastSeen.After(dst.LastSeen) {
+		dst.LastSeen = p.LastSeen
+	}
+	if !p.FirstSeen.IsZero() && (dst.FirstSeen.IsZero() || p.FirstSeen.Before(dst.FirstSeen)) {
+		dst.FirstSeen = p.FirstSeen
+	}
+	dst.ProjectCounts = addCounts(dst.ProjectCounts, p.ProjectCounts)
+	dst.HostCounts = addCounts(dst.HostCounts, p.HostCounts)
+	dst.Variants = addCounts(dst.Variants, p.Variants)
+	for _, s := range p.Samples {
+		dst.Samples = addSample(dst.Samples, s)
+	}
+	dst.Inputs = addInputs(dst.Inputs, p.Inputs)
+	dst.Slowest = types.AddSlowCalls(dst.Slowest, p.Slowest...)
+	addOutside(dst, p)
+	dst.OutsideWrites = types.AddOutsideWrites(dst.OutsideWrites, p.OutsideWrites...)
+}
+
+// addCounts adds src into dst, allocating dst if needed
+func addCounts(dst, src map[string]int) map[string]int {
+	if dst == nil {
+		dst = make(map[string]int, len(src))
+	}
+	for k, n := range src {
+		dst[k] += n
+	}
+	return dst
+}
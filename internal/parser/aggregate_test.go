@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/b-open-io/claude-perms/internal/types"
+)
+
+func TestAttributePermissionStats(t *testing.T) {
+	main := AggregatePermissionStats([]LogEvents{{
+		Path:    "/logs/session.jsonl",
+		Project: "/work/app",
+		Events: []ToolEvent{
+			{Permission: "Bash(git:*)", Outcome: OutcomeApproved},
+			{Permission: "Bash(git:*)", Outcome: OutcomeDenied},
+		},
+	}})
+	agents := AggregateAgentUsage([]LogEvents{
+		{Path: "/logs/agent-a.jsonl", Project: "/work/lib", AgentType: "reviewer", Events: []ToolEvent{
+			{Permission: "Bash(git:*)"},
+			{Permission: "Read"},
+		}},
+		{Path: "/logs/agent-b.jsonl", Project: "/work/app", AgentType: "Unknown", Events: []ToolEvent{
+			{Permission: "Bash(git:*)"},
+		}},
+	})
+
+	stats := AttributePermissionStats(main, agents)
+	byRaw := make(map[string]types.PermissionStats)
+	for _, s := range stats {
+		byRaw[s.Permission.Raw] = s
+	}
+
+	git := byRaw["Bash(git:*)"]
+	if git.Count != 4 || git.Approved != 1 || git.Denied != 1 {
+		t.Errorf("git counts = %d/%d/%d, expected 4 uses, 1 approved, 1 denied", git.Count, git.Approved, git.Denied)
+	}
+	want := map[types.Attribution]int{types.AttributedMain: 2, types.AttributedAgent: 1, types.AttributedUnknown: 1}
+	for who, n := range want {
+		if git.ByAttribution[who] != n {
+			t.Errorf("git %s uses = %d, expected %d", who, git.ByAttribution[who], n)
+		}
+	}
+	if len(git.Projects) != 2 {
+		t.Errorf("git projects = %v, expected both", git.Projects)
+	}
+	if read := byRaw["Read"]; read.Count != 1 || read.SubagentCount() != 1 {
+		t.Errorf("subagent-only Read = %+v", read)
+	}
+
+	if main[0].ByAttribution[types.AttributedAgent] != 0 || len(main[0].ProjectCounts) != 1 {
+		t.Error("main stats were modified")
+	}
+}
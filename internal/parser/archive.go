@@ -0,0 +1,219 @@
+package parser
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// archivePath is the archive the loaders read from (see UseArchive)
+var archivePath string
+
+// Archive returns the path of the archive passed to UseArchive, or "" when
+// the loaders read the local disk
+func Archive() string {
+	return archivePath
+}
+
+// UseArchive makes the loaders read ~/.claude from a packaged copy of it: a
+// zip file, or a tar file that may be gzipped, such as a backup or someone
+// else's export. The archive's Claude directory is the shallowest one with a
+// projects directory, so .claude/projects/..., home/me/.claude/projects/...
+// and projects/... all work. Nothing outside ~/.claude can be read while it
+// is in use, and the parse cache is off so the archive's logs never replace
+// the entries of the local ones at the same paths.
+//
+// Zip files are read in place; tar files are unpacked into a temporary
+// directory. The returned function removes it and restores the local disk.
+func UseArchive(archive string) (func(), error) {
+	root, cleanup, err := openArchive(archive)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := findClaudeDir(root)
+	if err != nil {
+		cleanup()
+		return nil, fmt.Errorf("%s: %w", archive, err)
+	}
+	sub, err := fs.Sub(root, dir)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	cached := !cacheDisabled
+	SetFS(mountFS{dir: fsName(claudeDir()), fsys: sub})
+	SetCacheEnabled(false)
+	archivePath = archive
+	resetProjectGroups()
+	return func() {
+		SetFS(nil)
+		SetCacheEnabled(cached)
+		archivePath = ""
+		resetProjectGroups()
+		cleanup()
+	}, nil
+}
+
+// openArchive opens the zip or (gzipped) tar file at archive as a
+// filesystem, telling them apart by their first bytes
+func openArchive(archive string) (fs.FS, func(), error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, nil, err
+	}
+	header := make([]byte, 4)
+	n, _ := io.ReadFull(f, header)
+	header = header[:n]
+
+	if bytes.HasPrefix(header, []byte("PK\x03\x04")) || bytes.HasPrefix(header, []byte("PK\x05\x06")) {
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		zr, err := zip.NewReader(f, info.Size())
+		if err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("%s: %w", archive, err)
+		}
+		return zr, func() { f.Close() }, nil
+	}
+
+	defer f.Close()
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+	var r io.Reader = bufio.NewReader(f)
+	if bytes.HasPrefix(header, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", archive, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tmp, err := os.MkdirTemp("", "perms-archive-")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { os.RemoveAll(tmp) }
+	if err := extractTar(r, tmp); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("%s: %w", archive, err)
+	}
+	return os.DirFS(tmp), cleanup, nil
+}
+
+// extractTar unpacks the directories and regular files of a tar stream into
+// dir, keeping their modification times, which the parse cache and session
+// times rely on. Links and entries that would land outside dir are skipped.
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	found := false
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if !found {
+				return fmt.Errorf("not a zip or tar archive: %w", err)
+			}
+			return err
+		}
+		found = true
+
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "/"))
+		if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+			continue
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(out, tr)
+			if cerr := out.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+		default:
+			continue
+		}
+		os.Chtimes(target, hdr.ModTime, hdr.ModTime)
+	}
+	if !found {
+		return errors.New("archive is empty")
+	}
+	return nil
+}
+
+// findClaudeDir returns the shallowest directory of root holding a projects
+// directory
+func findClaudeDir(root fs.FS) (string, error) {
+	best, bestDepth := "", -1
+	err := fs.WalkDir(root, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() || d.Name() != "projects" {
+			return err
+		}
+		parent := path.Dir(name)
+		depth := 0
+		if parent != "." {
+			depth = strings.Count(parent, "/") + 1
+		}
+		if bestDepth < 0 || depth < bestDepth {
+			best, bestDepth = parent, depth
+		}
+		return fs.SkipDir
+	})
+	if err != nil {
+		return "", err
+	}
+	if bestDepth < 0 {
+		return "", errors.New("no projects directory found (expected a copy of ~/.claude)")
+	}
+	return best, nil
+}
+
+// mountFS serves fsys at dir and nothing anywhere else
+type mountFS struct {
+	dir  string
+	fsys fs.FS
+}
+
+func (m mountFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == m.dir {
+		return m.fsys.Open(".")
+	}
+	if rel, ok := strings.CutPrefix(name, m.dir+"/"); ok {
+		return m.fsys.Open(rel)
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUseArchive(t *testing.T) {
+	files := map[string]string{
+		"backup/.claude/projects/-work-app/s1.jsonl": `{"type":"assistant","timestamp":"2026-10-10T09:00:00Z","message":{"role":"assistant","content":[{"type":"tool_use","id":"r1","name":"Read","input":{"file_path":"/work/app/go.mod"}}]}}` + "\n",
+		"backup/.claude/settings.json":               `{"permissions":{"allow":["Read"]}}`,
+		// A deeper projects directory is not the Claude directory
+		"backup/.claude/plugins/x/projects/readme.md": "not logs",
+	}
+	modTime := time.Date(2026, 10, 10, 9, 30, 0, 0, time.UTC)
+	dir := t.TempDir()
+
+	tgz := filepath.Join(dir, "backup.tar.gz")
+	out, err := os.Create(tgz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	for name, data := range files {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: modTime, Typeflag: tar.TypeReg})
+		tw.Write([]byte(data))
+	}
+	// Entries escaping the extraction directory are skipped
+	tw.WriteHeader(&tar.Header{Name: "../escape.txt", Mode: 0644, Size: 1, Typeflag: tar.TypeReg})
+	tw.Write([]byte("x"))
+	tw.Close()
+	gz.Close()
+	out.Close()
+
+	zipPath := filepath.Join(dir, "backup.zip")
+	out, err = os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(out)
+	for name, data := range files {
+		w, _ := zw.CreateHeader(&zip.FileHeader{Name: name, Modified: modTime})
+		w.Write([]byte(data))
+	}
+	zw.Close()
+	out.Close()
+
+	t.Setenv("HOME", "/home/me")
+	for _, archive := range []string{tgz, zipPath} {
+		t.Run(filepath.Base(archive), func(t *testing.T) {
+			closeArchive, err := UseArchive(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer closeArchive()
+			if Archive() != archive {
+				t.Errorf("Archive() = %q, want %q", Archive(), archive)
+			}
+
+			logs, err := LoadLogEvents(ProjectsDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(logs) != 1 || len(logs[0].Events) != 1 || logs[0].Events[0].Permission != "Read" {
+				t.Errorf("logs = %+v, want the archived session's Read call", logs)
+			}
+			allow, _, err := ReadSettingsRules(filepath.Join(ClaudeDir(), "settings.json"))
+			if err != nil || len(allow) != 1 {
+				t.Errorf("allow = %v, %v; want the archived rule", allow, err)
+			}
+			if _, err := statFile(filepath.Join(dir, "backup.zip")); err == nil {
+				t.Error("a file outside the Claude directory is readable from the archive")
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(os.TempDir(), "escape.txt")); err == nil {
+		t.Error("a tar entry was extracted outside its directory")
+	}
+	if Archive() != "" {
+		t.Errorf("Archive() = %q after closing, want none", Archive())
+	}
+
+	if _, err := UseArchive(filepath.Join(dir, "missing.zip")); err == nil {
+		t.Error("UseArchive succeeded on a missing file")
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"time"
@@ -16,12 +18,15 @@ import (
 type CacheEntry struct {
 	FileHash string                  `json:"hash"` // mtime:size as cache key
 	Stats    []types.PermissionStats `json:"stats"`
+	ToolUses []string                `json:"toolUses,omitempty"` // tool_use IDs of the calls in Stats
+	Warnings []Warning               `json:"warnings,omitempty"` // Replayed on cache hits
 }
 
-// AgentMappingEntry caches agentId->agentType mappings extracted from a session file
+// AgentMappingEntry caches the subagent invocations extracted from a session
+// file, which map agentIds to agent types
 type AgentMappingEntry struct {
-	FileHash string            `json:"hash"`
-	Mappings map[string]string `json:"mappings"` // agentId -> agentType
+	FileHash    string                  `json:"hash"`
+	Invocations []types.AgentInvocation `json:"invocations"`
 }
 
 // AgentSessionEntry caches parsed tool_use stats from an agent file
@@ -29,32 +34,111 @@ type AgentSessionEntry struct {
 	FileHash string                  `json:"hash"`
 	Perms    []types.PermissionStats `json:"perms"`
 	LastSeen time.Time               `json:"lastSeen"`
+	Warnings []Warning               `json:"warnings,omitempty"`
 }
 
 // PermsCache holds all cached data for the permission analyzer
 type PermsCache struct {
 	Version       int                          `json:"version"`
+	Rejection     string                       `json:"rejection"`     // rejection matcher fingerprint used for Sessions
+	Redaction     string                       `json:"redaction"`     // redactor fingerprint used for every entry
 	Sessions      map[string]CacheEntry        `json:"sessions"`      // session path -> permission stats
-	AgentMappings map[string]AgentMappingEntry `json:"agentMappings"` // session path -> agentId mappings
+	AgentMappings map[string]AgentMappingEntry `json:"agentMappings"` // session path -> subagent invocations
 	AgentSessions map[string]AgentSessionEntry `json:"agentSessions"` // agent file path -> parsed stats
 }
 
-const cacheVersion = 3
+const cacheVersion = 23
+
+// cacheFile is the cache on disk: the cache's JSON with a checksum, which
+// tells a file cut short or corrupted on disk from one to use
+type cacheFile struct {
+	Version  int             `json:"version"`
+	Checksum string          `json:"checksum"` // Hex SHA-256 of Cache
+	Cache    json.RawMessage `json:"cache"`
+}
+
+// errCacheChecksum is the error of a cache file whose contents don't match
+// its checksum
+var errCacheChecksum = errors.New("cache checksum mismatch: the file is truncated or corrupted")
+
+// encodeCache encodes cache as a cache file
+func encodeCache(cache *PermsCache) ([]byte, error) {
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	return json.Marshal(cacheFile{Version: cache.Version, Checksum: hex.EncodeToString(sum[:]), Cache: data})
+}
+
+// decodeCache decodes a cache file. A file of another version, whose layout
+// may differ, decodes as far as its version.
+func decodeCache(data []byte) (*PermsCache, error) {
+	var f cacheFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse cache: %w", err)
+	}
+	if f.Version != cacheVersion {
+		return &PermsCache{Version: f.Version}, nil
+	}
+	sum := sha256.Sum256(f.Cache)
+	if hex.EncodeToString(sum[:]) != f.Checksum {
+		return nil, errCacheChecksum
+	}
+	var cache PermsCache
+	if err := json.Unmarshal(f.Cache, &cache); err != nil {
+		return nil, fmt.Errorf("parse cache: %w", err)
+	}
+	return &cache, nil
+}
 
 // cachePath returns the path to the cache file
 func cachePath() string {
 	return filepath.Join(claudeDir(), "perms-cache.json")
 }
 
+// cacheDisabled makes loadCache start empty and saveCache a no-op, so
+// throwaway data (e.g. demo mode) never reaches the on-disk cache
+var cacheDisabled bool
+
+// SetCacheEnabled turns the on-disk cache on or off
+func SetCacheEnabled(enabled bool) {
+	cacheDisabled = !enabled
+}
+
 // loadCache loads the unified cache from disk
 func loadCache() *PermsCache {
+	if cacheDisabled {
+		return newCache()
+	}
+
 	data, err := os.ReadFile(cachePath())
 	if err != nil {
 		return newCache()
 	}
 
-	var cache PermsCache
-	if err := json.Unmarshal(data, &cache); err != nil || cache.Version != cacheVersion {
+	cache, err := decodeCache(data)
+	if err != nil {
+		// An interrupted write can't cause this, as the cache is replaced
+		// atomically, so the file was damaged some other way
+		addWarning(cachePath(), 0, "cache discarded, session logs are parsed again: %v", err)
+		return newCache()
+	}
+	if cache.Version != cacheVersion {
+		logger.Debug("discarding cache", "path", cachePath(), "version", cache.Version)
+		return newCache()
+	}
+
+	// Denial counts depend on the configured rejection markers
+	if cache.Rejection != rejectionMatcher.fingerprint() {
+		logger.Debug("rejection markers changed, discarding cached sessions")
+		cache.Rejection = rejectionMatcher.fingerprint()
+		cache.Sessions = nil
+	}
+
+	// Samples and prompts depend on the redaction patterns
+	if cache.Redaction != redactor.fingerprint() {
+		logger.Debug("redaction patterns changed, discarding cache")
 		return newCache()
 	}
 
@@ -69,31 +153,132 @@ func loadCache() *PermsCache {
 		cache.AgentSessions = make(map[string]AgentSessionEntry)
 	}
 
-	return &cache
+	return cache
+}
+
+// CacheInfo describes the on-disk cache for diagnostics
+type CacheInfo struct {
+	Path          string
+	Exists        bool
+	Size          int64
+	Version       int
+	Sessions      int
+	AgentSessions int
+	Err           error // Read or parse failure
+}
+
+// InspectCache reads the cache file without modifying it
+func InspectCache() CacheInfo {
+	info := CacheInfo{Path: cachePath()}
+
+	stat, err := os.Stat(info.Path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			info.Err = err
+		}
+		return info
+	}
+	info.Exists = true
+	info.Size = stat.Size()
+
+	data, err := os.ReadFile(info.Path)
+	if err != nil {
+		info.Err = err
+		return info
+	}
+
+	cache, err := decodeCache(data)
+	if err != nil {
+		info.Err = err
+		return info
+	}
+	info.Version = cache.Version
+	info.Sessions = len(cache.Sessions)
+	info.AgentSessions = len(cache.AgentSessions)
+	return info
+}
+
+// CacheVersion returns the cache format version this build reads and writes
+func CacheVersion() int {
+	return cacheVersion
 }
 
 func newCache() *PermsCache {
 	return &PermsCache{
 		Version:       cacheVersion,
+		Rejection:     rejectionMatcher.fingerprint(),
+		Redaction:     redactor.fingerprint(),
 		Sessions:      make(map[string]CacheEntry),
 		AgentMappings: make(map[string]AgentMappingEntry),
 		AgentSessions: make(map[string]AgentSessionEntry),
 	}
 }
 
-// saveCache writes the cache to disk
-func saveCache(cache *PermsCache) error {
-	data, err := json.Marshal(cache)
+// lockCache takes the cache's lock, so that perms processes saving the
+// cache at the same time, e.g. the TUI and perms watch, don't interleave
+func lockCache() (func(), error) {
+	if cacheDisabled {
+		return func() {}, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(cachePath()), 0755); err != nil {
+		return nil, err
+	}
+	return acquireFileLock(cachePath() + ".lock")
+}
+
+// saveCache merges the entries of parsed, those a load just parsed, into
+// the cache on disk. The cache is read again under its lock first, so the
+// entries other processes saved since the load started are kept.
+func saveCache(parsed *PermsCache) error {
+	releaseLock, err := lockCache()
 	if err != nil {
 		return err
 	}
-	return writeFileAtomic(cachePath(), data, 0644)
+	defer releaseLock()
+
+	cache := loadCache()
+	maps.Copy(cache.Sessions, parsed.Sessions)
+	maps.Copy(cache.AgentMappings, parsed.AgentMappings)
+	maps.Copy(cache.AgentSessions, parsed.AgentSessions)
+	return writeCache(cache)
+}
+
+// writeCache writes the cache to disk; the caller holds its lock
+func writeCache(cache *PermsCache) error {
+	if cacheDisabled {
+		return nil
+	}
+
+	data, err := encodeCache(cache)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(cachePath(), data, 0644); err != nil {
+		return err
+	}
+	removeStaleTemps(cachePath())
+	return nil
+}
+
+// staleTempAge is how old a temp file of a cache write must be to be left
+// over from a process that was killed, rather than a write in progress
+const staleTempAge = time.Hour
+
+// removeStaleTemps removes the temp files that writes of path killed before
+// they could rename or remove them left behind
+func removeStaleTemps(path string) {
+	temps, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*"))
+	for _, t := range temps {
+		if info, err := os.Stat(t); err == nil && time.Since(info.ModTime()) > staleTempAge {
+			_ = os.Remove(t)
+		}
+	}
 }
 
 // fileHash generates a hash from file metadata (mtime + size)
 // This is an OS-level trick that doesn't read file contents
 func fileHash(path string) (string, error) {
-	info, err := os.Stat(path)
+	info, err := statFile(path)
 	if err != nil {
 		return "", err
 	}
@@ -104,23 +289,25 @@ func fileHash(path string) (string, error) {
 	return hex.EncodeToString(hash[:8]), nil // Use first 8 bytes (16 hex chars)
 }
 
-// getCachedStats returns cached permission stats if the file hasn't changed
-func getCachedStats(cache *PermsCache, path string) ([]types.PermissionStats, bool) {
+// getCachedStats returns cached permission stats, their tool_use IDs and
+// parse warnings if the file hasn't changed
+func getCachedStats(cache *PermsCache, path string) ([]types.PermissionStats, []string, []Warning, bool) {
 	hash, err := fileHash(path)
 	if err != nil {
-		return nil, false
+		return nil, nil, nil, false
 	}
 
 	entry, exists := cache.Sessions[path]
 	if !exists || entry.FileHash != hash {
-		return nil, false
+		return nil, nil, nil, false
 	}
 
-	return entry.Stats, true
+	return entry.Stats, entry.ToolUses, entry.Warnings, true
 }
 
-// setCachedStats stores permission stats in the cache
-func setCachedStats(cache *PermsCache, path string, stats []types.PermissionStats) {
+// setCachedStats stores permission stats, their tool_use IDs and parse
+// warnings in the cache
+func setCachedStats(cache *PermsCache, path string, stats []types.PermissionStats, ids []string, warns []Warning) {
 	hash, err := fileHash(path)
 	if err != nil {
 		return
@@ -129,11 +316,13 @@ func setCachedStats(cache *PermsCache, path string, stats []types.PermissionStat
 	cache.Sessions[path] = CacheEntry{
 		FileHash: hash,
 		Stats:    stats,
+		ToolUses: ids,
+		Warnings: warns,
 	}
 }
 
-// getCachedAgentMappings returns cached agentId->agentType mappings if the file hasn't changed
-func getCachedAgentMappings(cache *PermsCache, path string) (map[string]string, bool) {
+// getCachedAgentInvocations returns cached subagent invocations if the file hasn't changed
+func getCachedAgentInvocations(cache *PermsCache, path string) ([]types.AgentInvocation, bool) {
 	hash, err := fileHash(path)
 	if err != nil {
 		return nil, false
@@ -144,23 +333,24 @@ func getCachedAgentMappings(cache *PermsCache, path string) (map[string]string,
 		return nil, false
 	}
 
-	return entry.Mappings, true
+	return entry.Invocations, true
 }
 
-// setCachedAgentMappings stores agentId mappings in the cache
-func setCachedAgentMappings(cache *PermsCache, path string, mappings map[string]string) {
+// setCachedAgentInvocations stores subagent invocations in the cache
+func setCachedAgentInvocations(cache *PermsCache, path string, invocations []types.AgentInvocation) {
 	hash, err := fileHash(path)
 	if err != nil {
 		return
 	}
 
 	cache.AgentMappings[path] = AgentMappingEntry{
-		FileHash: hash,
-		Mappings: mappings,
+		FileHash:    hash,
+		Invocations: invocations,
 	}
 }
 
-// getCachedAgentSession returns cached agent session stats if the file hasn't changed
+// getCachedAgentSession returns cached agent session stats if the file hasn't changed.
+// Warnings recorded when the file was parsed are replayed.
 func getCachedAgentSession(cache *PermsCache, path string) ([]types.PermissionStats, time.Time, bool) {
 	hash, err := fileHash(path)
 	if err != nil {
@@ -172,11 +362,12 @@ func getCachedAgentSession(cache *PermsCache, path string) ([]types.PermissionSt
 		return nil, time.Time{}, false
 	}
 
+	recordWarnings(entry.Warnings)
 	return entry.Perms, entry.LastSeen, true
 }
 
 // setCachedAgentSession stores agent session stats in the cache
-func setCachedAgentSession(cache *PermsCache, path string, perms []types.PermissionStats, lastSeen time.Time) {
+func setCachedAgentSession(cache *PermsCache, path string, perms []types.PermissionStats, lastSeen time.Time, warns []Warning) {
 	hash, err := fileHash(path)
 	if err != nil {
 		return
@@ -186,25 +377,52 @@ func setCachedAgentSession(cache *PermsCache, path string, perms []types.Permiss
 		FileHash: hash,
 		Perms:    perms,
 		LastSeen: lastSeen,
+		Warnings: warns,
+	}
+}
+
+// StatsSource loads stats from somewhere other than the JSONL cache, e.g. a
+// persistent event store that is updated incrementally
+type StatsSource interface {
+	PermissionStats(projectsDir string, progress chan<- Progress) ([]types.PermissionStats, error)
+	AgentUsageStats(projectsDir string, progress chan<- Progress) ([]types.AgentUsageStats, error)
+}
+
+// statsSource replaces the cached loaders when set (see SetStatsSource)
+var statsSource StatsSource
+
+// SetStatsSource makes LoadAllPermissionStatsWithCache and
+// LoadAgentUsageStats load from s. A nil source restores the JSON cache.
+func SetStatsSource(s StatsSource) {
+	statsSource = s
+}
+
+// LoadAllPermissionStatsWithCache loads stats with caching support, or
+// from the stats source if one is set
+func LoadAllPermissionStatsWithCache(progress chan<- Progress) ([]types.PermissionStats, error) {
+	if statsSource != nil {
+		return statsSource.PermissionStats(ProjectsDir(), progress)
 	}
+	return loadPermissionStatsWithCache(ProjectsDir(), progress)
 }
 
-// LoadAllPermissionStatsWithCache loads stats with caching support
-func LoadAllPermissionStatsWithCache(progress chan<- string) ([]types.PermissionStats, error) {
-	projectsDir := filepath.Join(claudeDir(), "projects")
+// LoadPermissionStatsFromWithCache loads the stats of the logs under
+// projectsDir with the JSON cache, whatever the stats source. Sources fall
+// back to it for directories they don't cover.
+func LoadPermissionStatsFromWithCache(projectsDir string, progress chan<- Progress) ([]types.PermissionStats, error) {
 	return loadPermissionStatsWithCache(projectsDir, progress)
 }
 
-func loadPermissionStatsWithCache(projectsDir string, progress chan<- string) ([]types.PermissionStats, error) {
+func loadPermissionStatsWithCache(projectsDir string, progress chan<- Progress) ([]types.PermissionStats, error) {
 	cache := loadCache()
+	parsed := newCache() // The entries to save
 	cacheHits := 0
 	cacheMisses := 0
 
-	// Map to aggregate stats by permission
-	statsMap := make(map[string]*types.PermissionStats)
-	projectsMap := make(map[string]map[string]bool)
+	stats := make(permissionAggregator)
+	counted := make(toolUseSet)
 
-	entries, err := os.ReadDir(projectsDir)
+	projects, total, err := collectProjectSessions(projectsDir)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, nil
@@ -212,113 +430,67 @@ func loadPermissionStatsWithCache(projectsDir string, progress chan<- string) ([
 		return nil, err
 	}
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
+	done := 0
+	for _, project := range projects {
+		projectName := project.name
 
-		projectPath := filepath.Join(projectsDir, entry.Name())
-		projectName := decodeProjectPath(entry.Name())
+		for _, session := range project.sessions {
+			sendProgress(progress, Progress{
+				Stage:   "Scanning sessions",
+				Project: projectName,
+				Session: shortID(session.SessionID),
+				Done:    done,
+				Total:   total,
+			})
+			done++
 
-		if progress != nil {
-			progress <- projectName
-		}
-
-		indexPath := filepath.Join(projectPath, "sessions-index.json")
-		sessions, err := loadSessionsIndex(indexPath)
-		if err != nil {
-			continue
-		}
-
-		for _, session := range sessions {
-			// Send session ID update (prefix with "session:" for parsing)
-			if progress != nil {
-				sessionID := session.SessionID
-				if len(sessionID) > 12 {
-					sessionID = sessionID[:12]
-				}
-				progress <- "session:" + sessionID + "..."
-			}
-
-			sessionPath := filepath.Join(projectPath, session.SessionID+".jsonl")
-
-			var sessionTime time.Time
-			if session.Modified != "" {
-				sessionTime, _ = time.Parse(time.RFC3339, session.Modified)
-			}
-			if sessionTime.IsZero() {
-				sessionTime = time.Unix(session.FileMtime/1000, 0)
-			}
+			sessionPath := filepath.Join(project.dir, session.SessionID+".jsonl")
+			sessionTime := session.sessionTime()
 
 			// Try cache first
 			var perms []types.PermissionStats
-			if cached, hit := getCachedStats(cache, sessionPath); hit {
-				perms = cached
+			var ids []string
+			if cached, cachedIDs, warns, hit := getCachedStats(cache, sessionPath); hit {
+				perms, ids = cached, cachedIDs
+				recordWarnings(warns)
 				cacheHits++
 			} else {
 				// Parse and cache
+				var warns []Warning
 				var err error
-				perms, err = parseSessionLog(sessionPath, sessionTime)
+				perms, ids, warns, err = parseSessionLog(sessionPath, sessionTime)
 				if err != nil {
+					addWarning(sessionPath, 0, "unreadable session log: %v", err)
 					continue
 				}
-				setCachedStats(cache, sessionPath, perms)
+				recordWarnings(warns)
+				setCachedStats(parsed, sessionPath, perms, ids, warns)
 				cacheMisses++
 			}
 
-			// Aggregate stats
-			for _, p := range perms {
-				key := PermissionKey(p.Permission)
-
-				if _, exists := statsMap[key]; !exists {
-					statsMap[key] = &types.PermissionStats{
-						Permission: p.Permission,
-						Count:      0,
-						LastSeen:   time.Time{},
-						Projects:   nil,
-					}
-					projectsMap[key] = make(map[string]bool)
-				}
-
-				statsMap[key].Count += p.Count
-				statsMap[key].Approved += p.Approved
-				statsMap[key].Denied += p.Denied
-				if p.LastSeen.After(statsMap[key].LastSeen) {
-					statsMap[key].LastSeen = p.LastSeen
-				}
-				projectsMap[key][projectName] = true
+			// Leave out the calls a resumed session repeats
+			perms, err := counted.claim(sessionPath, sessionTime, perms, ids)
+			if err != nil {
+				addWarning(sessionPath, 0, "unreadable session log: %v", err)
+				continue
 			}
+			stats.add(perms, sessionPath, projectName)
 		}
 	}
 
 	// Save cache
+	logger.Debug("session stats loaded", "hits", cacheHits, "misses", cacheMisses)
 	if cacheMisses > 0 {
-		_ = saveCache(cache)
-	}
-
-	if progress != nil {
-		progress <- fmt.Sprintf("Cache: %d hits, %d misses", cacheHits, cacheMisses)
-	}
-
-	// Convert and sort
-	stats := make([]types.PermissionStats, 0, len(statsMap))
-	for key, s := range statsMap {
-		projects := make([]string, 0, len(projectsMap[key]))
-		for proj := range projectsMap[key] {
-			projects = append(projects, proj)
+		if err := saveCache(parsed); err != nil {
+			logger.Warn("saving cache failed", "err", err)
 		}
-		s.Projects = projects
-		stats = append(stats, *s)
 	}
 
-	// Sort by count descending
-	for i := 0; i < len(stats); i++ {
-		for j := i + 1; j < len(stats); j++ {
-			if stats[j].Count > stats[i].Count {
-				stats[i], stats[j] = stats[j], stats[i]
-			}
-		}
-	}
+	sendProgress(progress, Progress{
+		Stage: fmt.Sprintf("Cache: %d hits, %d misses", cacheHits, cacheMisses),
+		Done:  total,
+		Total: total,
+	})
 
-	return stats, nil
+	return stats.stats(), nil
 }
@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCorruptedCacheIsDiscarded(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	TakeWarnings()
+
+	cache := newCache()
+	cache.Sessions["/logs/session.jsonl"] = CacheEntry{FileHash: "abc", ToolUses: []string{"toolu_1"}}
+	if err := saveCache(cache); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadCache(); len(got.Sessions) != 1 {
+		t.Fatalf("loadCache read %d sessions, want the 1 saved", len(got.Sessions))
+	}
+
+	// Damage the cache without breaking its JSON
+	data, err := os.ReadFile(cachePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cachePath(), bytes.Replace(data, []byte("toolu_1"), []byte("toolu_2"), 1), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadCache(); len(got.Sessions) != 0 {
+		t.Errorf("loadCache read %d sessions from a corrupted cache, want a clean cache", len(got.Sessions))
+	}
+	warns, _ := TakeWarnings()
+	if len(warns) != 1 || !strings.Contains(warns[0].Reason, "checksum") {
+		t.Errorf("warnings = %v, want the checksum mismatch reported", warns)
+	}
+	if info := InspectCache(); info.Err != errCacheChecksum {
+		t.Errorf("InspectCache error = %v, want %v", info.Err, errCacheChecksum)
+	}
+}
+
+func TestSaveCacheRemovesStaleTempFiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := filepath.Dir(cachePath())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	stale := filepath.Join(dir, ".perms-cache.json.tmp-1")
+	fresh := filepath.Join(dir, ".perms-cache.json.tmp-2")
+	for _, p := range []string{stale, fresh} {
+		if err := os.WriteFile(p, []byte(`{"vers`), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-2 * staleTempAge)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := saveCache(newCache()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale temp file left behind: %v", err)
+	}
+	// A write in progress may still rename its temp file
+	if _, err := os.Stat(fresh); err != nil {
+		t.Errorf("recent temp file removed: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package parser
+
+import "time"
+
+// CacheWriter keeps the unified cache up to date as logs change, e.g. in
+// watch mode, without rewriting it on every change. Logs parsed since the
+// last flush are kept in memory as dirty entries; Flush merges them into the
+// cache on disk, and MaybeFlush does so at most once per interval. Call
+// Flush before exiting so nothing parsed is lost. A CacheWriter is not safe
+// for concurrent use.
+type CacheWriter struct {
+	interval  time.Duration
+	sessions  map[string]CacheEntry        // Dirty session logs, by path
+	agents    map[string]AgentSessionEntry // Dirty agent logs, by path
+	lastFlush time.Time
+}
+
+// NewCacheWriter returns a cache writer that flushes at most once per
+// interval
+func NewCacheWriter(interval time.Duration) *CacheWriter {
+	return &CacheWriter{
+		interval:  interval,
+		sessions:  make(map[string]CacheEntry),
+		agents:    make(map[string]AgentSessionEntry),
+		lastFlush: time.Now(),
+	}
+}
+
+// Record marks the log f dirty with the events and warnings it was just
+// parsed into. A log recorded again before a flush replaces its entry.
+func (w *CacheWriter) Record(f LogFile, events []ToolEvent, warns []Warning) {
+	hash, err := fileHash(f.Path)
+	if err != nil {
+		return
+	}
+	if f.Agent {
+		perms, lastSeen := agentStats(events)
+		w.agents[f.Path] = AgentSessionEntry{FileHash: hash, Perms: perms, LastSeen: lastSeen, Warnings: warns}
+		return
+	}
+	w.sessions[f.Path] = CacheEntry{FileHash: hash, Stats: sessionStats(events), ToolUses: eventIDs(events), Warnings: warns}
+}
+
+// Dirty returns how many logs are waiting for a flush
+func (w *CacheWriter) Dirty() int {
+	return len(w.sessions) + len(w.agents)
+}
+
+// MaybeFlush flushes if logs are dirty and the interval has passed since
+// the last flush
+func (w *CacheWriter) MaybeFlush() error {
+	if w.Dirty() == 0 || time.Since(w.lastFlush) < w.interval {
+		return nil
+	}
+	return w.Flush()
+}
+
+// Flush writes the dirty entries into the cache on disk, which is read
+// again first under its lock so entries another process saved in the
+// meantime are kept
+func (w *CacheWriter) Flush() error {
+	if w.Dirty() == 0 {
+		return nil
+	}
+	if err := saveCache(&PermsCache{Sessions: w.sessions, AgentSessions: w.agents}); err != nil {
+		return err
+	}
+	logger.Debug("cache flushed", "sessions", len(w.sessions), "agents", len(w.agents))
+	clear(w.sessions)
+	clear(w.agents)
+	w.lastFlush = time.Now()
+	return nil
+}
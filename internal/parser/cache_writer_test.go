@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheWriterFlushesDirtyLogs(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".claude"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	content := `{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"make test"}}]}}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	events, warns, err := ParseSessionEvents(path, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewCacheWriter(time.Hour)
+	w.Record(LogFile{Path: path}, events, warns)
+	w.Record(LogFile{Path: path}, events, warns)
+	if w.Dirty() != 1 {
+		t.Errorf("Dirty = %d after recording a log twice, want 1", w.Dirty())
+	}
+
+	// Within the interval nothing is written
+	if err := w.MaybeFlush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cachePath()); !os.IsNotExist(err) {
+		t.Fatalf("cache written before the interval passed: %v", err)
+	}
+
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if w.Dirty() != 0 {
+		t.Errorf("Dirty = %d after a flush, want 0", w.Dirty())
+	}
+	stats, ids, _, hit := getCachedStats(loadCache(), path)
+	if !hit || len(stats) != 1 || stats[0].Permission.Raw != "Bash(make:*)" || len(ids) != 1 {
+		t.Errorf("cached = %v, %v, hit %v; want the flushed session", stats, ids, hit)
+	}
+}
+
+func TestSaveCacheKeepsEntriesFlushedDuringLoad(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".claude"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	logs := t.TempDir()
+	loaded := filepath.Join(logs, "loaded.jsonl")
+	flushed := filepath.Join(logs, "flushed.jsonl")
+	content := `{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"make test"}}]}}
+`
+	for _, p := range []string{loaded, flushed} {
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	events, warns, err := ParseSessionEvents(flushed, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A load reads the cache and parses a log, as loadPermissionStatsWithCache does
+	cache := loadCache()
+	if _, _, _, hit := getCachedStats(cache, loaded); hit {
+		t.Fatal("empty cache hit")
+	}
+	parsed := newCache()
+	perms, ids, parseWarns, err := parseSessionLog(loaded, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	setCachedStats(parsed, loaded, perms, ids, parseWarns)
+
+	// Meanwhile another instance, e.g. perms watch, flushes another log
+	w := NewCacheWriter(time.Hour)
+	w.Record(LogFile{Path: flushed}, events, warns)
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := saveCache(parsed); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{loaded, flushed} {
+		if _, _, _, hit := getCachedStats(loadCache(), p); !hit {
+			t.Errorf("%s missing from the cache", filepath.Base(p))
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// commandNamePattern matches the marker Claude Code logs when a slash
+// command is run, e.g. "<command-name>/review</command-name>"
+var commandNamePattern = regexp.MustCompile(`<command-name>\s*/?([^<\s]+)\s*</command-name>`)
+
+// commandNameMarker is the substring a line must contain to start a command
+var commandNameMarker = []byte("<command-name>")
+
+// LoadAllCommands loads the allowed tools of the slash commands in
+// ~/.claude/commands and installed plugins. Only commands that allow tools
+// are returned.
+func LoadAllCommands() ([]types.CommandPermissions, error) {
+	commands := loadCommandsFromDir(filepath.Join(claudeDir(), "commands"), "", "")
+	for _, p := range latestPluginVersions() {
+		commands = append(commands, loadCommandsFromDir(filepath.Join(p.Dir, "commands"), p.Name, p.Version)...)
+	}
+	sortCommands(commands)
+	return commands, nil
+}
+
+// loadCommandsFromDir loads the commands under dir, including those in
+// subdirectories, which Claude Code uses to group commands
+func loadCommandsFromDir(dir, pluginName, version string) []types.CommandPermissions {
+	var commands []types.CommandPermissions
+	walkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(d.Name(), ".md") {
+			return nil
+		}
+		cmd, err := parseCommandFile(path, pluginName, version)
+		if err != nil {
+			addWarning(path, 0, "invalid command frontmatter: %v", err)
+			return nil
+		}
+		if len(cmd.Permissions) > 0 {
+			commands = append(commands, cmd)
+		}
+		return nil
+	})
+	return commands
+}
+
+// parseCommandFile parses a command markdown file's frontmatter. The
+// command is named after its file.
+func parseCommandFile(path, pluginName, version string) (types.CommandPermissions, error) {
+	cmd := types.CommandPermissions{
+		Name:     strings.TrimSuffix(filepath.Base(path), ".md"),
+		Plugin:   pluginName,
+		Version:  version,
+		FilePath: path,
+	}
+
+	data, err := readFile(path)
+	if err != nil {
+		return cmd, err
+	}
+	fm, ok := frontmatterBlock(data)
+	if !ok {
+		return cmd, nil
+	}
+
+	var frontmatter types.CommandFrontmatter
+	if err := yaml.Unmarshal(fm, &frontmatter); err != nil {
+		return cmd, err
+	}
+	cmd.Permissions = ParsePermissions(parseToolsField(frontmatter.AllowedTools))
+	return cmd, nil
+}
+
+// frontmatterBlock returns the YAML between a file's opening and closing
+// "---" lines
+func frontmatterBlock(data []byte) ([]byte, bool) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return nil, false
+	}
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "---" {
+			return []byte(strings.Join(lines[1:i], "\n")), true
+		}
+	}
+	return nil, false
+}
+
+// FindCommandFile returns the markdown file defining a slash command, e.g.
+// "/review" in ~/.claude/commands or "/my-plugin:review" in the plugin's
+// latest version; "" for built-in commands
+func FindCommandFile(invocation string) string {
+	name := strings.TrimPrefix(invocation, "/")
+	var commands []types.CommandPermissions
+	if plugin, cmd, ok := strings.Cut(name, ":"); ok {
+		for _, p := range latestPluginVersions() {
+			if p.Name == plugin {
+				commands = loadAllCommandFiles(filepath.Join(p.Dir, "commands"), plugin)
+			}
+		}
+		name = cmd
+	} else {
+		commands = loadAllCommandFiles(filepath.Join(claudeDir(), "commands"), "")
+	}
+	for _, c := range commands {
+		if c.Name == name {
+			return c.FilePath
+		}
+	}
+	return ""
+}
+
+// loadAllCommandFiles lists the command files under dir, whether or not
+// they allow tools
+func loadAllCommandFiles(dir, pluginName string) []types.CommandPermissions {
+	var commands []types.CommandPermissions
+	walkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && strings.HasSuffix(d.Name(), ".md") {
+			commands = append(commands, types.CommandPermissions{
+				Name:     strings.TrimSuffix(d.Name(), ".md"),
+				Plugin:   pluginName,
+				FilePath: path,
+			})
+		}
+		return nil
+	})
+	return commands
+}
+
+// IsCommandFile reports whether path is a slash command file, i.e. lies in
+// a commands directory, whose tools are listed under allowed-tools
+func IsCommandFile(path string) bool {
+	for dir := filepath.Dir(path); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		switch filepath.Base(dir) {
+		case "commands":
+			return true
+		case "agents", "skills":
+			return false
+		}
+	}
+	return false
+}
+
+// LoadCommandUsage attributes tool calls in the session logs under
+// projectsDir to the slash commands that led to them. The stats use
+// AgentUsageStats with the command, e.g. "/review", as the agent type;
+// commands that led to no tool calls are left out. Logs that can't be read
+// are skipped; the stats loaders already report them.
+func LoadCommandUsage(projectsDir string) ([]types.AgentUsageStats, error) {
+	files, err := ListLogFiles(projectsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	commands := make(agentAggregator)
+	for _, f := range files {
+		if f.Agent {
+			continue
+		}
+		byCommand, err := ParseSessionCommands(f.Path, f.Time)
+		if err != nil {
+			logger.Debug("skipping session log", "path", f.Path, "err", err)
+			continue
+		}
+		for command, events := range byCommand {
+			perms, lastSeen := agentStats(events)
+			commands.add(command, f.Path, "", f.Project, perms, lastSeen)
+		}
+	}
+	return commands.stats(), nil
+}
+
+// ParseSessionCommands returns the tool calls of one session log per slash
+// command. A command's calls are those made after it was run and before
+// the user's next prompt; tool results and the expanded command prompt,
+// which Claude Code logs as meta entries, don't end it.
+func ParseSessionCommands(path string, sessionTime time.Time) (map[string][]ToolEvent, error) {
+	file, err := openFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	byCommand := make(map[string][]ToolEvent)
+	current := ""
+	lines := newLineReader(file)
+	for lines.Next() {
+		line := lines.Bytes()
+		hasCommand := bytes.Contains(line, commandNameMarker)
+		if current == "" && !hasCommand {
+			continue
+		}
+
+		var entry struct {
+			Type      string `json:"type"`
+			IsMeta    bool   `json:"isMeta"`
+			Timestamp string `json:"timestamp"`
+			Message   struct {
+				Content rawJSON `json:"content"`
+			} `json:"message"`
+		}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			continue
+		}
+
+		switch entry.Type {
+		case "user":
+			if onlyToolResults(entry.Message.Content) {
+				continue
+			}
+			if m := commandNamePattern.FindSubmatch(line); hasCommand && m != nil {
+				current = "/" + string(m[1])
+			} else if !entry.IsMeta {
+				current = ""
+			}
+
+		case "assistant":
+			if current == "" || !bytes.Contains(line, toolUseMarker) {
+				continue
+			}
+			var items []ContentItem
+			if err := json.Unmarshal(entry.Message.Content, &items); err != nil {
+				continue
+			}
+			entryTime := sessionTime
+			if t, err := time.Parse(time.RFC3339, entry.Timestamp); err == nil {
+				entryTime = t
+			}
+			for _, item := range items {
+				if item.Type == "tool_use" && item.Name != "" {
+					permString, sample := scopeAndSample(item.Name, item.Input)
+					byCommand[current] = append(byCommand[current], ToolEvent{Time: entryTime, Permission: permString, Sample: sample})
+				}
+			}
+		}
+	}
+	return byCommand, lines.Err()
+}
+
+// onlyToolResults reports whether a user message's content is a list of
+// tool results, i.e. not a new prompt
+func onlyToolResults(content []byte) bool {
+	var items []struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(content, &items); err != nil || len(items) == 0 {
+		return false
+	}
+	for _, item := range items {
+		if item.Type != "tool_result" {
+			return false
+		}
+	}
+	return true
+}
+
+// sortCommands orders commands by invocation
+func sortCommands(commands []types.CommandPermissions) {
+	sort.Slice(commands, func(i, j int) bool {
+		return commands[i].Invocation() < commands[j].Invocation()
+	})
+}
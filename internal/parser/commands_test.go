@@ -0,0 +1,36 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseSessionCommands(t *testing.T) {
+	log := `{"type":"assistant","timestamp":"2026-10-10T09:00:00Z","message":{"role":"assistant","content":[{"type":"tool_use","id":"t0","name":"Read","input":{"file_path":"/a.go"}}]}}
+{"type":"user","timestamp":"2026-10-10T10:00:00Z","message":{"role":"user","content":"<command-message>review is running…</command-message>\n<command-name>/review</command-name>"}}
+{"type":"user","isMeta":true,"timestamp":"2026-10-10T10:00:00Z","message":{"role":"user","content":[{"type":"text","text":"Review the current diff"}]}}
+{"type":"assistant","timestamp":"2026-10-10T10:00:01Z","message":{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"git diff"}}]}}
+{"type":"user","timestamp":"2026-10-10T10:00:02Z","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"..."}]}}
+{"type":"assistant","timestamp":"2026-10-10T10:00:03Z","message":{"role":"assistant","content":[{"type":"tool_use","id":"t2","name":"Read","input":{"file_path":"/b.go"}}]}}
+{"type":"user","timestamp":"2026-10-10T10:01:00Z","message":{"role":"user","content":"thanks, now fix it"}}
+{"type":"assistant","timestamp":"2026-10-10T10:01:01Z","message":{"role":"assistant","content":[{"type":"tool_use","id":"t3","name":"Edit","input":{"file_path":"/b.go"}}]}}
+`
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(path, []byte(log), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	byCommand, err := ParseSessionCommands(path, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byCommand) != 1 {
+		t.Fatalf("got commands %v, want only /review", byCommand)
+	}
+	events := byCommand["/review"]
+	if len(events) != 2 || events[0].Permission != "Bash(git diff:*)" || events[1].Permission != "Read" {
+		t.Errorf("/review events = %+v, want Bash(git diff:*) then Read", events)
+	}
+}
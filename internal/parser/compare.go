@@ -0,0 +1,98 @@
+package parser
+
+import "slices"
+
+// DifferenceKind says how a rule of one settings file differs from another's
+type DifferenceKind string
+
+const (
+	OnlyMine   DifferenceKind = "only-mine"   // Only my file has the rule
+	OnlyTheirs DifferenceKind = "only-theirs" // Only their file has the rule
+	Overlap    DifferenceKind = "overlap"     // Both files have a rule for some of the same calls, with different wildcards
+)
+
+// RuleDifference is a rule two settings files don't share, e.g. mine and a
+// teammate's
+type RuleDifference struct {
+	Kind   DifferenceKind `json:"kind"`
+	List   Decision       `json:"list"`             // allow or deny
+	Mine   string         `json:"mine,omitempty"`   // My rule; "" for OnlyTheirs
+	Theirs string         `json:"theirs,omitempty"` // Their rule; "" for OnlyMine
+}
+
+// CompareRules compares my rules with theirs list by list, leaving out the
+// rules both have. Of the rest, a rule of mine and one of theirs where either
+// covers the other, e.g. "Bash(git:*)" and "Bash(git status:*)", are an
+// Overlap; a rule that overlaps none is only in its file. Differences are in
+// list order, allow first, mine before theirs.
+func CompareRules(mine, theirs RuleSet) []RuleDifference {
+	var diffs []RuleDifference
+	diffs = append(diffs, compareList(DecisionAllow, mine.Allow, theirs.Allow)...)
+	diffs = append(diffs, compareList(DecisionDeny, mine.Deny, theirs.Deny)...)
+	return diffs
+}
+
+// compareList compares one list of my rules with the same list of theirs
+func compareList(list Decision, mine, theirs []string) []RuleDifference {
+	var onlyMine, onlyTheirs []string
+	for _, r := range mine {
+		if !slices.Contains(theirs, r) && !slices.Contains(onlyMine, r) {
+			onlyMine = append(onlyMine, r)
+		}
+	}
+	for _, r := range theirs {
+		if !slices.Contains(mine, r) && !slices.Contains(onlyTheirs, r) {
+			onlyTheirs = append(onlyTheirs, r)
+		}
+	}
+
+	var diffs []RuleDifference
+	overlapped := make(map[string]bool)
+	for _, m := range onlyMine {
+		found := false
+		for _, t := range onlyTheirs {
+			if RuleCovers(m, t) || RuleCovers(t, m) {
+				diffs = append(diffs, RuleDifference{Kind: Overlap, List: list, Mine: m, Theirs: t})
+				overlapped[t], found = true, true
+			}
+		}
+		if !found {
+			diffs = append(diffs, RuleDifference{Kind: OnlyMine, List: list, Mine: m})
+		}
+	}
+	for _, t := range onlyTheirs {
+		if !overlapped[t] {
+			diffs = append(diffs, RuleDifference{Kind: OnlyTheirs, List: list, Theirs: t})
+		}
+	}
+	return diffs
+}
+
+// Adopt returns the rule set that takes their side of diffs: their rules
+// added to the same list, replacing my overlapping rules. Rules only in my
+// file are kept as they are.
+func Adopt(diffs []RuleDifference) RuleSet {
+	var set RuleSet
+	for _, d := range diffs {
+		if d.Theirs == "" {
+			continue
+		}
+		if d.List == DecisionDeny {
+			set.Deny = appendUnique(set.Deny, d.Theirs)
+		} else {
+			set.Allow = appendUnique(set.Allow, d.Theirs)
+		}
+		if d.Mine != "" {
+			set.Replaces = appendUnique(set.Replaces, d.Mine)
+		}
+	}
+	return set
+}
+
+// appendUnique appends s to list unless it is already there
+func appendUnique(list []string, s string) []string {
+	if slices.Contains(list, s) {
+		return list
+	}
+	return append(list, s)
+}
@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompareRules(t *testing.T) {
+	mine := RuleSet{
+		Allow: []string{"Read", "Bash(git:*)", "Bash(make:*)"},
+		Deny:  []string{"Bash(rm:*)"},
+	}
+	theirs := RuleSet{
+		Allow: []string{"Read", "Bash(git status:*)", "Bash(git diff:*)", "WebSearch"},
+		Deny:  []string{"Bash(rm:*)", "Read(./.env)"},
+	}
+
+	want := []RuleDifference{
+		{Kind: Overlap, List: DecisionAllow, Mine: "Bash(git:*)", Theirs: "Bash(git status:*)"},
+		{Kind: Overlap, List: DecisionAllow, Mine: "Bash(git:*)", Theirs: "Bash(git diff:*)"},
+		{Kind: OnlyMine, List: DecisionAllow, Mine: "Bash(make:*)"},
+		{Kind: OnlyTheirs, List: DecisionAllow, Theirs: "WebSearch"},
+		{Kind: OnlyTheirs, List: DecisionDeny, Theirs: "Read(./.env)"},
+	}
+	diffs := CompareRules(mine, theirs)
+	if !reflect.DeepEqual(diffs, want) {
+		t.Errorf("CompareRules =\n%+v\nwant\n%+v", diffs, want)
+	}
+	if len(CompareRules(mine, mine)) != 0 {
+		t.Error("expected no differences comparing a rule set with itself")
+	}
+}
+
+func TestAdoptReplacesOverlappingRules(t *testing.T) {
+	settingsPath := filepath.Join(t.TempDir(), "settings.local.json")
+	if err := os.WriteFile(settingsPath, []byte(`{"permissions":{"allow":["Bash(git:*)","Bash(make:*)"]}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	diffs := []RuleDifference{
+		{Kind: Overlap, List: DecisionAllow, Mine: "Bash(git:*)", Theirs: "Bash(git status:*)"},
+		{Kind: OnlyTheirs, List: DecisionDeny, Theirs: "Read(./.env)"},
+	}
+
+	if _, err := WriteRuleSet(settingsPath, Adopt(diffs), false); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	allow, deny, err := ReadSettingsRules(settingsPath)
+	if err != nil {
+		t.Fatalf("read settings: %v", err)
+	}
+	if strings.Join(allow, " ") != "Bash(make:*) Bash(git status:*)" || strings.Join(deny, " ") != "Read(./.env)" {
+		t.Errorf("allow = %v, deny = %v; want their rules in place of mine", allow, deny)
+	}
+}
@@ -0,0 +1,598 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/types"
+)
+
+// Outcome is what became of a tool_use, judged from its tool_result
+type Outcome string
+
+const (
+	OutcomeUnknown  Outcome = ""         // No result was logged
+	OutcomeApproved Outcome = "approved" // The tool ran
+	OutcomeDenied   Outcome = "denied"   // The user rejected it
+	OutcomeFailed   Outcome = "failed"   // It ran and returned an error
+)
+
+// ToolEvent is a single tool_use in a session or agent log. The per-
+// permission stats are aggregated from these; persistent stores keep them
+// as-is so they can be re-aggregated over any time range or project.
+type ToolEvent struct {
+	Time       time.Time `json:"time"`
+	ID         string    `json:"id,omitempty"`     // The tool_use ID, which the logs of resumed sessions repeat
+	Permission string    `json:"permission"`       // Raw permission string, before normalization
+	Sample     string    `json:"sample,omitempty"` // Example input: a command, path or URL
+	Outcome    Outcome   `json:"outcome,omitempty"`
+	ResultTime time.Time `json:"result_time,omitzero"` // When the tool_result was logged
+	Tokens     int       `json:"tokens,omitempty"`     // Its share of the tokens of the message that made it
+	Cwd        string    `json:"cwd,omitempty"`        // Where a Bash call ran, if outside the directory its log started in
+	Written    string    `json:"written,omitempty"`    // The file a write tool modified, if outside that directory
+	Mode       string    `json:"mode,omitempty"`       // Permission mode the session was in; "" if its log doesn't say
+	Prompted   bool      `json:"prompted,omitempty"`   // A permission prompt record shows the user was asked
+}
+
+// AutoApproved reports whether the call ran without a prompt because of the
+// session's permission mode, such as an edit under acceptEdits, rather than
+// being approved at a prompt or by a rule
+func (e ToolEvent) AutoApproved() bool {
+	return e.Outcome == OutcomeApproved && !e.Prompted && modeSkipsPrompt(e.Mode, ParsePermission(e.Permission).Type)
+}
+
+// outsideCwd returns where a tool call logged in cwd ran, if it's a Bash
+// call outside root, the directory its log started in. Bash runs in the
+// shell's working directory, which a cd moves for the rest of the session.
+func outsideCwd(tool, cwd, root string) string {
+	if tool != "Bash" || withinDir(cwd, root) {
+		return ""
+	}
+	return cwd
+}
+
+// outsideTarget returns the file a write tool modified, if it's outside
+// root. Relative paths are taken to be inside.
+func outsideTarget(target, root string) string {
+	if !filepath.IsAbs(target) || withinDir(target, root) {
+		return ""
+	}
+	return target
+}
+
+// withinDir reports whether path is dir or under it, or either is unknown
+func withinDir(path, dir string) bool {
+	return path == "" || dir == "" || path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, "/")+"/")
+}
+
+// Duration returns how long the tool ran, from its tool_use to its
+// tool_result, and false if that's unknown or it was denied
+func (e ToolEvent) Duration() (time.Duration, bool) {
+	if e.Time.IsZero() || e.ResultTime.IsZero() || e.Outcome == OutcomeDenied {
+		return 0, false
+	}
+	d := e.ResultTime.Sub(e.Time)
+	return d, d > 0
+}
+
+// slowCall returns the event as a slow call, if it has a duration
+func (e ToolEvent) slowCall() (types.SlowCall, bool) {
+	d, ok := e.Duration()
+	return types.SlowCall{Permission: e.Permission, Sample: e.Sample, Duration: d, Time: e.Time}, ok
+}
+
+// messageTokens shares each assistant message's token usage between the
+// tool_uses it made. Claude Code logs a message with several content items
+// as a line per item, each repeating the message's usage, so the lines are
+// grouped by message ID and the usage counted once.
+type messageTokens struct {
+	order  []string
+	usage  map[string]int
+	events map[string][]int // Indexes of the message's tool_uses in the events
+}
+
+// add records that events[event] was made by message id, which used tokens.
+// A message without an ID is told apart by line.
+func (t *messageTokens) add(id string, line, tokens, event int) {
+	if id == "" {
+		id = fmt.Sprintf("line %d", line)
+	}
+	if t.usage == nil {
+		t.usage = make(map[string]int)
+		t.events = make(map[string][]int)
+	}
+	if _, ok := t.usage[id]; !ok {
+		t.order = append(t.order, id)
+	}
+	t.usage[id] = max(t.usage[id], tokens)
+	t.events[id] = append(t.events[id], event)
+}
+
+// assign gives each tool_use an even share of its message's tokens, the
+// first the remainder
+func (t *messageTokens) assign(events []ToolEvent) {
+	for _, id := range t.order {
+		idx := t.events[id]
+		share := t.usage[id] / len(idx)
+		for _, i := range idx {
+			events[i].Tokens = share
+		}
+		events[idx[0]].Tokens += t.usage[id] - share*len(idx)
+	}
+}
+
+// LogFile is a session or agent log under a projects directory
+type LogFile struct {
+	Path    string
+	Project string    // Decoded project path
+	Agent   bool      // An agent-*.jsonl subagent log rather than a session
+	Time    time.Time // Session time for entries without a timestamp; zero for agent logs
+}
+
+// ListLogFiles returns the logs the loaders read: the indexed sessions of
+// every project (or all its logs, without an index) and every agent log
+func ListLogFiles(projectsDir string) ([]LogFile, error) {
+	projects, _, err := collectProjectSessions(projectsDir)
+	if err != nil {
+		return nil, err
+	}
+	agentProjects, _, _, err := collectAgentProjectFiles(projectsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []LogFile
+	for _, project := range projects {
+		for _, session := range project.sessions {
+			files = append(files, LogFile{
+				Path:    filepath.Join(project.dir, session.SessionID+".jsonl"),
+				Project: project.name,
+				Time:    session.sessionTime(),
+			})
+		}
+	}
+	for _, project := range agentProjects {
+		for _, agentFile := range project.agentFiles {
+			files = append(files, LogFile{Path: agentFile, Project: project.name, Agent: true})
+		}
+	}
+	return files, nil
+}
+
+// LoadLogEvents parses every log under projectsDir into events, attributing
+// agent logs to their subagent type through the Task calls in the session
+// logs. Unlike the stats loaders it keeps every event, a call a resumed
+// session repeats only in the first log that has it, so it reads all logs
+// without a cache; a persistent store is the fast path for repeated queries.
+func LoadLogEvents(projectsDir string) ([]LogEvents, error) {
+	files, err := ListLogFiles(projectsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	logs := make([]LogEvents, 0, len(files))
+	var invocations []types.AgentInvocation
+	var agentLogs []int // Indexes in logs of the agent logs
+	for _, f := range files {
+		l := LogEvents{Path: f.Path, Project: f.Project}
+		var warns []Warning
+		if f.Agent {
+			l.Events, warns, err = ParseAgentEvents(f.Path)
+			if err != nil {
+				addWarning(f.Path, 0, "unreadable agent log: %v", err)
+				continue
+			}
+			agentLogs = append(agentLogs, len(logs)) // Resolved below, once every invocation is known
+		} else {
+			l.Events, warns, err = ParseSessionEvents(f.Path, f.Time)
+			if err != nil {
+				addWarning(f.Path, 0, "unreadable session log: %v", err)
+				continue
+			}
+			l.Invocations = AgentInvocations(f.Path, f.Project)
+			invocations = append(invocations, l.Invocations...)
+		}
+		recordWarnings(warns)
+		logs = append(logs, l)
+	}
+
+	resolver := newAgentResolver(invocations)
+	for _, i := range agentLogs {
+		logs[i].AgentType = "Unknown"
+		if inv, ok := resolver.resolve(logs[i].Path, logs[i].Project); ok {
+			logs[i].AgentType = inv.AgentType
+		}
+	}
+	DropRepeatedCalls(logs)
+	return logs, nil
+}
+
+// RejectionFingerprint identifies the configured rejection markers. Event
+// outcomes depend on them, so stored events must be re-parsed when it
+// changes.
+func RejectionFingerprint() string {
+	return rejectionMatcher.fingerprint()
+}
+
+// ParseSessionEvents returns every tool_use in a JSONL session log with its
+// outcome: the one a permission record gives it in logs that have them, or
+// else the one judged from its tool_result. Each call has the permission
+// mode its session was in, which user entries record as the user switches
+// it; approving the plan of ExitPlanMode leaves plan mode. Entries without a
+// timestamp get sessionTime.
+// Malformed lines are skipped and reported as warnings.
+func ParseSessionEvents(path string, sessionTime time.Time) ([]ToolEvent, []Warning, error) {
+	file, err := openFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var events []ToolEvent
+	var warns []Warning
+	lineNum := 0
+
+	// Map tool_use ID -> index in events for correlating results
+	toolUseIDToEvent := make(map[string]int)
+	explicit := make(map[string]Outcome) // tool_use ID -> outcome a permission record gives it
+	prompted := make(map[string]bool)    // tool_use IDs a permission prompt record was logged for
+	var mode string                      // The session's permission mode, once an entry records it
+	var tokens messageTokens
+	var decoder schemaDecoder
+	var root string // The session's first working directory
+
+	lines := newLineReader(file)
+	for lines.Next() {
+		lineNum = lines.Line()
+		if tooLong, size := lines.TooLong(); tooLong {
+			warns = append(warns, tooLongWarning(path, lineNum, size))
+			continue
+		}
+		line := lines.Bytes()
+
+		// Quick check: skip lines that don't contain tool_use, tool_result,
+		// a permission record or the permission mode
+		if !bytes.Contains(line, toolUseMarker) && !bytes.Contains(line, toolResultMarker) &&
+			!bytes.Contains(line, permissionMarker) && !bytes.Contains(line, modeMarker) {
+			continue
+		}
+
+		var entry JSONLEntry
+		ok, err := decoder.decode(line, &entry)
+		// A message typed by the user holds no tool calls, but says which
+		// mode the session is in
+		if entry.PermissionMode != "" {
+			mode = entry.PermissionMode
+		}
+		if err != nil || !ok {
+			if err != nil && !isTypeError(err) {
+				warns = append(warns, Warning{File: path, Line: lineNum, Reason: "malformed JSON: " + err.Error()})
+			}
+			continue
+		}
+
+		// Parse entry timestamp
+		entryTime := sessionTime
+		if entry.Timestamp != "" {
+			if t, err := time.Parse(time.RFC3339, entry.Timestamp); err == nil {
+				entryTime = t
+			}
+		}
+
+		if root == "" {
+			root = entry.Cwd
+		}
+
+		if o, ok := promptOutcome(entry); ok {
+			explicit[entry.ToolUseID] = o
+			prompted[entry.ToolUseID] = true
+		}
+		for _, d := range entry.PermissionDenials {
+			explicit[d.ToolUseID] = OutcomeDenied
+		}
+
+		interrupt := interrupted(entry.Message.Content)
+		for _, item := range entry.Message.Content {
+			if item.Type == "tool_use" && item.Name != "" {
+				// A tool_use logged again, e.g. around a compaction, is
+				// the same call
+				if _, dup := toolUseIDToEvent[item.ID]; dup {
+					continue
+				}
+				// Extract full permission with scope from input
+				permString, sample, target := describeToolUse(item.Name, item.Input)
+				if item.ID != "" {
+					toolUseIDToEvent[item.ID] = len(events)
+				}
+				tokens.add(entry.Message.ID, lineNum, entry.Message.Usage.Total(), len(events))
+				events = append(events, ToolEvent{
+					Time:       entryTime,
+					ID:         item.ID,
+					Permission: permString,
+					Sample:     sample,
+					Cwd:        outsideCwd(item.Name, entry.Cwd, root),
+					Written:    outsideTarget(target, root),
+					Mode:       mode,
+				})
+			} else if item.Type == "tool_result" && item.ToolUseID != "" {
+				i, exists := toolUseIDToEvent[item.ToolUseID]
+				if !exists {
+					continue
+				}
+
+				switch {
+				case !item.IsError:
+					events[i].Outcome = OutcomeApproved
+				case interrupt, toolResultContainsRejection(item.Content):
+					events[i].Outcome = OutcomeDenied
+				default:
+					// is_error == true but not rejected = command failure, not a denial
+					events[i].Outcome = OutcomeFailed
+				}
+				events[i].ResultTime = entryTime
+
+				// The user approved the plan, so the session is out of plan
+				// mode. Which mode it's in now, the next user entry says.
+				if mode == ModePlan && entry.PermissionMode == "" && events[i].Outcome == OutcomeApproved &&
+					events[i].Permission == "ExitPlanMode" {
+					mode = ModeDefault
+				}
+			}
+		}
+	}
+
+	if err := lines.Err(); err != nil {
+		warns = append(warns, Warning{File: path, Line: lineNum + 1, Reason: "rest of file skipped: " + err.Error()})
+	}
+	warns = append(warns, decoder.warning(path)...)
+	for id, o := range explicit {
+		if i, ok := toolUseIDToEvent[id]; ok {
+			events[i].Outcome = preferExplicit(events[i].Outcome, o)
+			events[i].Prompted = prompted[id]
+		}
+	}
+	tokens.assign(events)
+	return events, warns, nil
+}
+
+// promptOutcome returns the outcome a permission prompt record gives its
+// tool call, if entry is one
+func promptOutcome(entry JSONLEntry) (Outcome, bool) {
+	if entry.Subtype != "permission_prompt" || entry.ToolUseID == "" {
+		return OutcomeUnknown, false
+	}
+	switch entry.Decision {
+	case "allow":
+		return OutcomeApproved, true
+	case "deny":
+		return OutcomeDenied, true
+	}
+	return OutcomeUnknown, false
+}
+
+// preferExplicit returns a tool call's outcome given the one judged from
+// its tool_result and the one a permission record gives it. The record
+// wins, except that an allowed call whose result was an error still ran and
+// failed.
+func preferExplicit(judged, recorded Outcome) Outcome {
+	if recorded == OutcomeApproved && judged == OutcomeFailed {
+		return OutcomeFailed
+	}
+	return recorded
+}
+
+// ParseAgentEvents returns the tool_uses in an agent-*.jsonl log, the Task
+// calls that start further agents as Task(type). Agent logs carry no
+// outcomes, only when each tool_result came back. Malformed lines are
+// skipped and reported as warnings.
+func ParseAgentEvents(agentPath string) ([]ToolEvent, []Warning, error) {
+	file, err := openFile(agentPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var events []ToolEvent
+	var warns []Warning
+	var tokens messageTokens
+	toolUseIDToEvent := make(map[string]int)
+	var decoder schemaDecoder
+	var root string // The agent's first working directory
+	lineNum := 0
+
+	lines := newLineReader(file)
+	for lines.Next() {
+		lineNum = lines.Line()
+		if tooLong, size := lines.TooLong(); tooLong {
+			warns = append(warns, tooLongWarning(agentPath, lineNum, size))
+			continue
+		}
+		line := lines.Bytes()
+
+		// Quick check for tool_use or tool_result
+		if !bytes.Contains(line, toolUseMarker) && !bytes.Contains(line, toolResultMarker) {
+			continue
+		}
+
+		var entry JSONLEntry
+		if ok, err := decoder.decode(line, &entry); err != nil || !ok {
+			if err != nil && !isTypeError(err) {
+				warns = append(warns, Warning{File: agentPath, Line: lineNum, Reason: "malformed JSON: " + err.Error()})
+			}
+			continue
+		}
+
+		entryTime := time.Time{}
+		if entry.Timestamp != "" {
+			if t, err := time.Parse(time.RFC3339, entry.Timestamp); err == nil {
+				entryTime = t
+			}
+		}
+
+		if root == "" {
+			root = entry.Cwd
+		}
+
+		// Results come back in user messages
+		if entry.Type == "user" {
+			for _, item := range entry.Message.Content {
+				if i, ok := toolUseIDToEvent[item.ToolUseID]; ok && item.Type == "tool_result" {
+					events[i].ResultTime = entryTime
+				}
+			}
+			continue
+		}
+
+		// Only process assistant messages
+		if entry.Type != "assistant" {
+			continue
+		}
+
+		for _, item := range entry.Message.Content {
+			if item.Type != "tool_use" || item.Name == "" {
+				continue
+			}
+			if _, dup := toolUseIDToEvent[item.ID]; dup {
+				continue
+			}
+			if item.ID != "" {
+				toolUseIDToEvent[item.ID] = len(events)
+			}
+			permString, sample, target := describeToolUse(item.Name, item.Input)
+			tokens.add(entry.Message.ID, lineNum, entry.Message.Usage.Total(), len(events))
+			events = append(events, ToolEvent{
+				Time:       entryTime,
+				ID:         item.ID,
+				Permission: permString,
+				Sample:     sample,
+				Cwd:        outsideCwd(item.Name, entry.Cwd, root),
+				Written:    outsideTarget(target, root),
+			})
+		}
+	}
+
+	if err := lines.Err(); err != nil {
+		warns = append(warns, Warning{File: agentPath, Line: lineNum + 1, Reason: "rest of file skipped: " + err.Error()})
+	}
+	warns = append(warns, decoder.warning(agentPath)...)
+	tokens.assign(events)
+	return events, warns, nil
+}
+
+// sessionStats aggregates one session's events into per-permission stats,
+// keyed by the raw permission (normalization happens when sessions are
+// merged)
+func sessionStats(events []ToolEvent) []types.PermissionStats {
+	byKey := make(map[string]*types.PermissionStats)
+	var order []string
+	for _, e := range events {
+		s, exists := byKey[e.Permission]
+		if !exists {
+			s = &types.PermissionStats{
+				Permission: ParsePermission(e.Permission),
+				FirstSeen:  e.Time,
+				LastSeen:   e.Time,
+			}
+			byKey[e.Permission] = s
+			order = append(order, e.Permission)
+		}
+
+		s.Count++
+		s.Tokens += e.Tokens
+		if e.Time.After(s.LastSeen) {
+			s.LastSeen = e.Time
+		}
+		if e.Time.Before(s.FirstSeen) {
+			s.FirstSeen = e.Time
+		}
+		s.Samples = addSample(s.Samples, e.Sample)
+		if contextTools[s.Permission.Type] && e.Sample != "" {
+			if s.Inputs == nil {
+				s.Inputs = make(map[string]int)
+			}
+			s.Inputs[e.Sample]++
+		}
+		if c, ok := e.slowCall(); ok {
+			s.Slowest = types.AddSlowCalls(s.Slowest, c)
+		}
+		if e.Cwd != "" {
+			s.Outside++
+			s.OutsideDirs = addSample(s.OutsideDirs, e.Cwd)
+		}
+		if e.Written != "" {
+			s.OutsideWrites = types.AddOutsideWrites(s.OutsideWrites, types.OutsideWrite{File: e.Written, Count: 1})
+		}
+
+		switch {
+		case e.AutoApproved():
+			s.AutoApproved++
+		case e.Outcome == OutcomeApproved:
+			s.Approved++
+		case e.Outcome == OutcomeDenied:
+			s.Denied++
+			s.DeniedAt = append(s.DeniedAt, e.ResultTime)
+		}
+	}
+
+	stats := make([]types.PermissionStats, 0, len(order))
+	for _, key := range order {
+		stats = append(stats, *byKey[key])
+	}
+	return stats
+}
+
+// agentStats aggregates one agent log's events into per-permission counts
+// and the time of its latest tool_use
+func agentStats(events []ToolEvent) (perms []types.PermissionStats, lastSeen time.Time) {
+	byKey := make(map[string]*types.PermissionStats)
+	var order []string
+	for _, e := range events {
+		s, exists := byKey[e.Permission]
+		if !exists {
+			s = &types.PermissionStats{Permission: ParsePermission(e.Permission), FirstSeen: e.Time, LastSeen: e.Time}
+			byKey[e.Permission] = s
+			order = append(order, e.Permission)
+		}
+		s.Count++
+		s.Tokens += e.Tokens
+		if e.Time.After(s.LastSeen) {
+			s.LastSeen = e.Time
+		}
+		if e.Time.Before(s.FirstSeen) {
+			s.FirstSeen = e.Time
+		}
+		s.Samples = addSample(s.Samples, e.Sample)
+		if contextTools[s.Permission.Type] && e.Sample != "" {
+			if s.Inputs == nil {
+				s.Inputs = make(map[string]int)
+			}
+			s.Inputs[e.Sample]++
+		}
+		if c, ok := e.slowCall(); ok {
+			s.Slowest = types.AddSlowCalls(s.Slowest, c)
+		}
+		if e.Cwd != "" {
+			s.Outside++
+			s.OutsideDirs = addSample(s.OutsideDirs, e.Cwd)
+		}
+		if e.Written != "" {
+			s.OutsideWrites = types.AddOutsideWrites(s.OutsideWrites, types.OutsideWrite{File: e.Written, Count: 1})
+		}
+		if e.Time.After(lastSeen) {
+			lastSeen = e.Time
+		}
+	}
+
+	perms = make([]types.PermissionStats, 0, len(order))
+	for _, key := range order {
+		perms = append(perms, *byKey[key])
+	}
+	return perms, lastSeen
+}
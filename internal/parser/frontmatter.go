@@ -0,0 +1,262 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/types"
+)
+
+// frontmatterKeyPattern matches a top-level key of a frontmatter block,
+// capturing any inline value
+func frontmatterKeyPattern(key string) *regexp.Regexp {
+	return regexp.MustCompile(`^` + regexp.QuoteMeta(key) + `\s*:\s*(.*)$`)
+}
+
+// toolsKey returns the frontmatter key a file lists its tools under:
+// allowed-tools for slash commands, tools for agents and skills
+func toolsKey(path string) string {
+	if IsCommandFile(path) {
+		return "allowed-tools"
+	}
+	return "tools"
+}
+
+// ToolsForPermissions returns the tool names an agent needs for a set of
+// observed permissions, in the order given: a frontmatter tools list names
+// tools, not scoped rules, so "Bash(git:*)" and "Bash(go test:*)" both
+// need "Bash"
+func ToolsForPermissions(perms []types.PermissionStats) []string {
+	var tools []string
+	seen := make(map[string]bool)
+	for _, p := range perms {
+		if t := p.Permission.Type; t != "" && !seen[t] {
+			seen[t] = true
+			tools = append(tools, t)
+		}
+	}
+	return tools
+}
+
+// FindAgentFile returns the markdown file defining an agent type, e.g.
+// "code-reviewer" in ~/.claude/agents or "my-plugin:code-reviewer" in the
+// plugin's latest version, or a slash command such as "/review". Built-in
+// agents such as Explore have none, so the result is "".
+func FindAgentFile(agentType string) string {
+	if strings.HasPrefix(agentType, "/") {
+		return FindCommandFile(agentType)
+	}
+	plugin, name, ok := strings.Cut(agentType, ":")
+	if !ok {
+		plugin, name = "", agentType
+	}
+
+	var dirs []string
+	if plugin == "" {
+		dirs = append(dirs, filepath.Join(claudeDir(), "agents"))
+	} else {
+		// Latest version first, the one loadAgentsFromPlugins reads
+		for _, p := range ListInstalledPlugins() {
+			if p.Name != plugin {
+				continue
+			}
+			for i := len(p.Versions) - 1; i >= 0; i-- {
+				dirs = append(dirs, filepath.Join(p.Dir, p.Versions[i], "agents"))
+			}
+		}
+	}
+
+	for _, dir := range dirs {
+		entries, err := readDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if strings.TrimSuffix(entry.Name(), ".md") == name {
+				return path
+			}
+			if agent, err := parseAgentFile(path, plugin, ""); err == nil && agent.Name == name {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// SetFrontmatterTools rewrites the tools key of a markdown file's YAML
+// frontmatter; see SetFrontmatterList
+func SetFrontmatterTools(data []byte, tools []string) ([]byte, error) {
+	return SetFrontmatterList(data, "tools", tools)
+}
+
+// SetFrontmatterList rewrites a list-valued key of a markdown file's YAML
+// frontmatter, leaving every other line as it was. An inline value stays
+// inline ("tools: Read, Bash") and a block list stays a block list with its
+// indentation. Without the key it is added at the end of the frontmatter;
+// without frontmatter, a block holding only the key is added.
+func SetFrontmatterList(data []byte, key string, tools []string) ([]byte, error) {
+	newline := "\n"
+	if bytes.Contains(data, []byte("\r\n")) {
+		newline = "\r\n"
+	}
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	inline := key + ": " + strings.Join(tools, ", ")
+	pattern := frontmatterKeyPattern(key)
+
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		out := "---" + newline + inline + newline + "---" + newline + string(data)
+		return []byte(out), nil
+	}
+
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "---" {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return nil, fmt.Errorf("frontmatter has no closing ---")
+	}
+
+	var replaced []string
+	for i := 1; i < end; i++ {
+		m := pattern.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+
+		// A block list continues with indented or "- " lines
+		j := i + 1
+		for j < end && (strings.HasPrefix(lines[j], " ") || strings.HasPrefix(lines[j], "\t") || strings.HasPrefix(lines[j], "-")) {
+			j++
+		}
+
+		if strings.TrimSpace(m[1]) == "" && j > i+1 {
+			indent := lines[i+1][:len(lines[i+1])-len(strings.TrimLeft(lines[i+1], " \t"))]
+			replaced = append(replaced, key+":")
+			for _, t := range tools {
+				replaced = append(replaced, indent+"- "+t)
+			}
+		} else {
+			replaced = append(replaced, inline)
+		}
+		out := append(append(append([]string{}, lines[:i]...), replaced...), lines[j:]...)
+		return []byte(strings.Join(out, newline)), nil
+	}
+
+	out := append(append(append([]string{}, lines[:end]...), inline), lines[end:]...)
+	return []byte(strings.Join(out, newline)), nil
+}
+
+// PreviewAgentTools generates a diff preview for setting an agent file's
+// tools. The diff is nil if the file already lists exactly these tools.
+func PreviewAgentTools(path string, tools []string) ([]DiffLine, error) {
+	data, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	output, err := SetFrontmatterList(data, toolsKey(path), tools)
+	if err != nil || bytes.Equal(data, output) {
+		return nil, err
+	}
+	return DiffContents(data, output), nil
+}
+
+// WriteAgentTools sets the tools in an agent or skill file's frontmatter.
+// The result's Permission is the new tools value; WasNew is false if the
+// file already had it.
+func WriteAgentTools(path string, tools []string) (*ApplyResult, error) {
+	releaseLock, err := acquireFileLock(path + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	defer releaseLock()
+
+	result, data, output, err := planAgentTools(path, tools)
+	if err != nil || !result.WasNew {
+		return result, err
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := statFile(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := writeFileAtomic(path, output, mode); err != nil {
+		return nil, err
+	}
+	runWriteHook(path, data, output, "tools "+result.Permission)
+	return result, nil
+}
+
+// PlanAgentTools reports what WriteAgentTools would do, without writing
+// anything. The result is marked DryRun.
+func PlanAgentTools(path string, tools []string) (*ApplyResult, error) {
+	result, _, _, err := planAgentTools(path, tools)
+	if result != nil {
+		result.DryRun = true
+	}
+	return result, err
+}
+
+// planAgentTools computes the new contents of an agent file along with the
+// result describing the change
+func planAgentTools(path string, tools []string) (*ApplyResult, []byte, []byte, error) {
+	data, err := readFile(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	output, err := SetFrontmatterList(data, toolsKey(path), tools)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	result := &ApplyResult{FilePath: path, Permission: strings.Join(tools, ", "), WasNew: !bytes.Equal(data, output)}
+	if result.WasNew {
+		result.LineNumber = frontmatterKeyLine(output, toolsKey(path))
+	}
+	return result, data, output, nil
+}
+
+// frontmatterKeyLine returns the 1-based line of a frontmatter key
+func frontmatterKeyLine(data []byte, key string) int {
+	pattern := frontmatterKeyPattern(key)
+	for i, line := range strings.Split(string(data), "\n") {
+		if i > 0 && pattern.MatchString(strings.TrimRight(line, "\r")) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// DeclaredTools returns the tools listed in an agent, skill or command
+// file's frontmatter, as written
+func DeclaredTools(path string) ([]string, error) {
+	var perms []types.Permission
+	if IsCommandFile(path) {
+		cmd, err := parseCommandFile(path, "", "")
+		if err != nil {
+			return nil, err
+		}
+		perms = cmd.Permissions
+	} else {
+		agent, err := parseAgentFile(path, "", "")
+		if err != nil {
+			return nil, err
+		}
+		perms = agent.Permissions
+	}
+	tools := make([]string, len(perms))
+	for i, p := range perms {
+		tools[i] = p.Raw
+	}
+	return tools, nil
+}
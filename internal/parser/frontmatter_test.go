@@ -0,0 +1,51 @@
+package parser
+
+import "testing"
+
+func TestSetFrontmatterTools(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "inline",
+			in:   "---\nname: reviewer\ntools: Read, Grep\nmodel: sonnet\n---\n\nBody tools: here\n",
+			want: "---\nname: reviewer\ntools: Read, Bash\nmodel: sonnet\n---\n\nBody tools: here\n",
+		},
+		{
+			name: "block list keeps its indent",
+			in:   "---\nname: reviewer\ntools:\n    - Read\n    - Grep\ncolor: red\n---\nBody\n",
+			want: "---\nname: reviewer\ntools:\n    - Read\n    - Bash\ncolor: red\n---\nBody\n",
+		},
+		{
+			name: "missing key",
+			in:   "---\nname: reviewer\n---\nBody\n",
+			want: "---\nname: reviewer\ntools: Read, Bash\n---\nBody\n",
+		},
+		{
+			name: "no frontmatter",
+			in:   "Body\n",
+			want: "---\ntools: Read, Bash\n---\nBody\n",
+		},
+		{
+			name: "crlf",
+			in:   "---\r\nname: reviewer\r\ntools: Read\r\n---\r\nBody\r\n",
+			want: "---\r\nname: reviewer\r\ntools: Read, Bash\r\n---\r\nBody\r\n",
+		},
+	}
+	for _, tt := range tests {
+		got, err := SetFrontmatterTools([]byte(tt.in), []string{"Read", "Bash"})
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := SetFrontmatterTools([]byte("---\nname: x\n"), []string{"Read"}); err == nil {
+		t.Error("unterminated frontmatter: want an error")
+	}
+}
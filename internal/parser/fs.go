@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fsys is the filesystem the loaders read session logs, agents, skills,
+// commands, plugins and settings from (see SetFS)
+var fsys fs.FS = os.DirFS("/")
+
+// SetFS makes the loaders read from f instead of the local disk; nil
+// restores the disk. Paths stay absolute, as ProjectsDir and ClaudeDir
+// return them, and are looked up in f without their leading slash the way
+// os.DirFS("/") does, so f lays files out as they would be under the root:
+// home/me/.claude/projects/... for /home/me/.claude/projects/...
+//
+// Writes to settings files, the reads that plan them and the parse cache
+// stay on the local disk.
+func SetFS(f fs.FS) {
+	if f == nil {
+		f = os.DirFS("/")
+	}
+	fsys = f
+}
+
+// fsName converts an OS path to its name in fsys
+func fsName(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	path = strings.TrimPrefix(filepath.ToSlash(path), filepath.ToSlash(filepath.VolumeName(path)))
+	if path = strings.TrimPrefix(path, "/"); path == "" {
+		return "."
+	}
+	return path
+}
+
+// osPath converts a name in fsys back to an OS path
+func osPath(name string) string {
+	return filepath.FromSlash("/" + name)
+}
+
+// openFile opens path in fsys for reading
+func openFile(path string) (fs.File, error) {
+	return fsys.Open(fsName(path))
+}
+
+// readFile reads path from fsys
+func readFile(path string) ([]byte, error) {
+	return fs.ReadFile(fsys, fsName(path))
+}
+
+// readDir lists the directory path in fsys, sorted by name
+func readDir(path string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(fsys, fsName(path))
+}
+
+// statFile describes path in fsys
+func statFile(path string) (fs.FileInfo, error) {
+	return fs.Stat(fsys, fsName(path))
+}
+
+// glob returns the paths in fsys matching pattern, as OS paths
+func glob(pattern string) ([]string, error) {
+	names, err := fs.Glob(fsys, fsName(pattern))
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, len(names))
+	for i, name := range names {
+		paths[i] = osPath(name)
+	}
+	return paths, nil
+}
+
+// walkDir walks the tree at root in fsys like filepath.WalkDir, calling fn
+// with OS paths
+func walkDir(root string, fn fs.WalkDirFunc) error {
+	return fs.WalkDir(fsys, fsName(root), func(name string, d fs.DirEntry, err error) error {
+		return fn(osPath(name), d, err)
+	})
+}
@@ -0,0 +1,44 @@
+package parser
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestSetFS(t *testing.T) {
+	session := `{"type":"assistant","timestamp":"2026-10-10T09:00:00Z","message":{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Task","input":{"subagent_type":"Explore","description":"Look around","prompt":"Find the config"}}]}}
+{"type":"user","timestamp":"2026-10-10T09:05:00Z","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"done"}]},"toolUseResult":{"agentId":"a1"}}
+`
+	agent := `{"type":"assistant","timestamp":"2026-10-10T09:01:00Z","message":{"role":"assistant","content":[{"type":"tool_use","id":"r1","name":"Read","input":{"file_path":"/work/app/go.mod"}}]}}
+`
+	SetFS(fstest.MapFS{
+		"home/me/.claude/projects/-work-app/s1.jsonl":                    {Data: []byte(session)},
+		"home/me/.claude/projects/-work-app/s1/subagents/agent-a1.jsonl": {Data: []byte(agent)},
+		"home/me/.claude/settings.json":                                  {Data: []byte(`{"permissions":{"allow":["Read","Bash(git:*)"]}}`)},
+	})
+	defer SetFS(nil)
+
+	logs, err := LoadLogEvents("/home/me/.claude/projects")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var agentType string
+	var invocations int
+	for _, l := range logs {
+		if l.AgentType != "" {
+			agentType = l.AgentType
+		}
+		invocations += len(l.Invocations)
+	}
+	if len(logs) != 2 || agentType != "Explore" || invocations != 1 {
+		t.Errorf("read %d logs, agent type %q, %d invocations; want 2, Explore, 1: %+v", len(logs), agentType, invocations, logs)
+	}
+
+	allow, _, err := ReadSettingsRules("/home/me/.claude/settings.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(allow) != 2 {
+		t.Errorf("allow = %v, want the two rules of the in-memory settings", allow)
+	}
+}
@@ -0,0 +1,232 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Grant is a permission rule change made from inside a session rather than
+// with perms: a "don't ask again" answer to a prompt or an edit in the
+// /permissions command
+type Grant struct {
+	Time        time.Time
+	Project     string // Decoded project path of the session
+	Session     string // Path of the session log the change was found in
+	Action      string // "add" or "remove"
+	List        string // "allow", "deny" or "ask"
+	Rule        string // As logged, e.g. "Bash(npm run test:*)"
+	Destination string // "localSettings", "projectSettings", "userSettings" or "session"; "" if not logged
+}
+
+// File returns the settings file a grant was written to, "" if it was kept
+// for the session only. Grants without a destination are assumed to be
+// "don't ask again" answers, which Claude Code saves to the project's local
+// settings.
+func (g Grant) File() string {
+	switch g.Destination {
+	case "userSettings":
+		return filepath.Join(claudeDir(), "settings.json")
+	case "projectSettings":
+		return filepath.Join(g.Project, ".claude", "settings.json")
+	case "localSettings", "":
+		return ProjectSettingsPath(g.Project)
+	}
+	return ""
+}
+
+// Substrings a line must contain to hold a permission change
+var grantMarkers = [][]byte{
+	[]byte(`"addRules"`), []byte(`"replaceRules"`), []byte(`"removeRules"`),
+	[]byte(`local-command-stdout`),
+}
+
+// permissionsOutputPattern matches the /permissions command's confirmation
+// lines, e.g. "Added allow rule Bash(npm test:*) to local settings"
+var permissionsOutputPattern = regexp.MustCompile(`(?i)^\s*(added|removed)\s+(allow|deny|ask)\s+rules?\s+(.+?)(?:\s+(?:to|from)\s+(local|project|user)\s+settings)?\.?\s*$`)
+
+// LoadGrants scans every session log under projectsDir for permission
+// changes made inside sessions, oldest first. Logs that can't be read are
+// skipped; the stats loaders already report them.
+func LoadGrants(projectsDir string) ([]Grant, error) {
+	files, err := ListLogFiles(projectsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var grants []Grant
+	for _, f := range files {
+		if f.Agent {
+			continue
+		}
+		// A grant was saved to its own checkout's settings, not to those of
+		// the project it is grouped under
+		project := projectDirPath(filepath.Dir(f.Path))
+		g, err := ParseSessionGrants(f.Path, project, f.Time)
+		if err != nil {
+			logger.Debug("skipping session log", "path", f.Path, "err", err)
+			continue
+		}
+		grants = append(grants, g...)
+	}
+	sort.SliceStable(grants, func(i, j int) bool {
+		return grants[i].Time.Before(grants[j].Time)
+	})
+	return grants, nil
+}
+
+// ParseSessionGrants returns the permission changes logged in one session:
+// structured permission updates ({"type": "addRules", "rules": [...],
+// "behavior": "allow", "destination": "localSettings"}) anywhere in an
+// entry, and the output of the /permissions command
+func ParseSessionGrants(path, project string, sessionTime time.Time) ([]Grant, error) {
+	file, err := openFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var grants []Grant
+	lines := newLineReader(file)
+	for lines.Next() {
+		line := lines.Bytes()
+		if !containsAny(line, grantMarkers) {
+			continue
+		}
+
+		var entry any
+		if err := json.Unmarshal(line, &entry); err != nil {
+			continue
+		}
+		entryTime := sessionTime
+		if obj, ok := entry.(map[string]any); ok {
+			if ts, ok := obj["timestamp"].(string); ok {
+				if t, err := time.Parse(time.RFC3339, ts); err == nil {
+					entryTime = t
+				}
+			}
+		}
+
+		seen := make(map[Grant]bool)
+		for _, g := range grantsIn(entry) {
+			g.Time, g.Project, g.Session = entryTime, project, path
+			if !seen[g] {
+				seen[g] = true
+				grants = append(grants, g)
+			}
+		}
+	}
+	return grants, lines.Err()
+}
+
+// containsAny reports whether line contains any of the markers
+func containsAny(line []byte, markers [][]byte) bool {
+	for _, m := range markers {
+		if bytes.Contains(line, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// grantsIn walks a decoded entry for permission updates and /permissions
+// output
+func grantsIn(v any) []Grant {
+	var grants []Grant
+	switch v := v.(type) {
+	case map[string]any:
+		grants = append(grants, permissionUpdate(v)...)
+		for _, child := range v {
+			grants = append(grants, grantsIn(child)...)
+		}
+	case []any:
+		for _, child := range v {
+			grants = append(grants, grantsIn(child)...)
+		}
+	case string:
+		if strings.Contains(v, "local-command-stdout") {
+			grants = append(grants, permissionsOutput(v)...)
+		}
+	}
+	return grants
+}
+
+// permissionUpdate converts a structured permission update to grants
+func permissionUpdate(obj map[string]any) []Grant {
+	action := ""
+	switch obj["type"] {
+	case "addRules", "replaceRules":
+		action = "add"
+	case "removeRules":
+		action = "remove"
+	default:
+		return nil
+	}
+	rules, _ := obj["rules"].([]any)
+	list, _ := obj["behavior"].(string)
+	dest, _ := obj["destination"].(string)
+
+	var grants []Grant
+	for _, r := range rules {
+		rule, _ := r.(map[string]any)
+		tool, _ := rule["toolName"].(string)
+		if tool == "" {
+			continue
+		}
+		raw := tool
+		if content, _ := rule["ruleContent"].(string); content != "" {
+			raw = tool + "(" + content + ")"
+		}
+		grants = append(grants, Grant{Action: action, List: list, Rule: raw, Destination: dest})
+	}
+	return grants
+}
+
+// permissionsOutput extracts grants from the /permissions command's output
+func permissionsOutput(text string) []Grant {
+	text = strings.NewReplacer("<local-command-stdout>", "\n", "</local-command-stdout>", "\n").Replace(text)
+
+	var grants []Grant
+	for _, line := range strings.Split(text, "\n") {
+		m := permissionsOutputPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		action := "add"
+		if strings.EqualFold(m[1], "removed") {
+			action = "remove"
+		}
+		dest := ""
+		if m[4] != "" {
+			dest = strings.ToLower(m[4]) + "Settings"
+		}
+		for _, rule := range splitRuleList(m[3]) {
+			grants = append(grants, Grant{Action: action, List: strings.ToLower(m[2]), Rule: rule, Destination: dest})
+		}
+	}
+	return grants
+}
+
+// splitRuleList splits "`Bash(a:*)`, `Read`" into its rules. Without
+// backticks the text is taken as one rule, since rules may contain commas.
+func splitRuleList(s string) []string {
+	if !strings.Contains(s, "`") {
+		return []string{strings.TrimSpace(s)}
+	}
+	var rules []string
+	parts := strings.Split(s, "`")
+	for i := 1; i < len(parts); i += 2 {
+		if r := strings.TrimSpace(parts[i]); r != "" {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
@@ -0,0 +1,45 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseSessionGrants(t *testing.T) {
+	log := `{"type":"user","timestamp":"2026-10-10T10:00:00Z","toolUseResult":{"permissionUpdates":[{"type":"addRules","rules":[{"toolName":"Bash","ruleContent":"npm test:*"},{"toolName":"Read"}],"behavior":"allow","destination":"localSettings"}]}}
+{"type":"user","timestamp":"2026-10-11T10:00:00Z","message":{"role":"user","content":"<local-command-stdout>Removed deny rule ` + "`Bash(rm:*)`" + ` from user settings</local-command-stdout>"}}
+{"type":"assistant","timestamp":"2026-10-12T10:00:00Z","message":{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"ls"}}]}}
+not json "addRules"
+`
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(path, []byte(log), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	grants, err := ParseSessionGrants(path, "/proj", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Grant{
+		{Action: "add", List: "allow", Rule: "Bash(npm test:*)", Destination: "localSettings"},
+		{Action: "add", List: "allow", Rule: "Read", Destination: "localSettings"},
+		{Action: "remove", List: "deny", Rule: "Bash(rm:*)", Destination: "userSettings"},
+	}
+	if len(grants) != len(want) {
+		t.Fatalf("got %d grants, want %d: %+v", len(grants), len(want), grants)
+	}
+	for i, w := range want {
+		g := grants[i]
+		if g.Action != w.Action || g.List != w.List || g.Rule != w.Rule || g.Destination != w.Destination {
+			t.Errorf("grant %d = %+v, want %+v", i, g, w)
+		}
+		if g.Project != "/proj" || g.Session != path || g.Time.IsZero() {
+			t.Errorf("grant %d missing its session context: %+v", i, g)
+		}
+	}
+	if got := grants[0].File(); got != ProjectSettingsPath("/proj") {
+		t.Errorf("local grant file = %s, want %s", got, ProjectSettingsPath("/proj"))
+	}
+}
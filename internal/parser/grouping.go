@@ -18,23 +18,31 @@ func GroupPermissions(stats []types.PermissionStats) []types.PermissionGroup {
 			group.TotalCount += stat.Count
 			group.TotalApproved += stat.Approved
 			group.TotalDenied += stat.Denied
+			group.TotalAutoApproved += stat.AutoApproved
+			group.TotalTokens += stat.Tokens
 			group.Children = append(group.Children, stat)
 			if stat.LastSeen.After(group.LastSeen) {
 				group.LastSeen = stat.LastSeen
 			}
+			if !stat.FirstSeen.IsZero() && (group.FirstSeen.IsZero() || stat.FirstSeen.Before(group.FirstSeen)) {
+				group.FirstSeen = stat.FirstSeen
+			}
 			if stat.ApprovedAt > group.ApprovedAt {
 				group.ApprovedAt = stat.ApprovedAt
 			}
 		} else {
 			groupMap[baseType] = &types.PermissionGroup{
-				Type:          baseType,
-				TotalCount:    stat.Count,
-				TotalApproved: stat.Approved,
-				TotalDenied:   stat.Denied,
-				LastSeen:      stat.LastSeen,
-				Children:      []types.PermissionStats{stat},
-				Expanded:      false,
-				ApprovedAt:    stat.ApprovedAt,
+				Type:              baseType,
+				TotalCount:        stat.Count,
+				TotalApproved:     stat.Approved,
+				TotalDenied:       stat.Denied,
+				TotalAutoApproved: stat.AutoApproved,
+				TotalTokens:       stat.Tokens,
+				FirstSeen:         stat.FirstSeen,
+				LastSeen:          stat.LastSeen,
+				Children:          []types.PermissionStats{stat},
+				Expanded:          false,
+				ApprovedAt:        stat.ApprovedAt,
 			}
 		}
 	}
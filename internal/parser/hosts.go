@@ -0,0 +1,110 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// OtherHost labels logs that match none of the roots configured with SetHosts
+const OtherHost = "other"
+
+// Host attribution configured with SetHosts and SetHostFilter
+var (
+	hostRoots  map[string]string // Path prefix -> host label
+	hostFilter string
+)
+
+// SetHosts labels logs with the machine they came from, for session logs
+// synced from several machines. roots maps a path prefix, such as
+// "/Users/me" on a laptop and "/home/me" on a desktop, to a label; a log
+// belongs to the host of the longest root its project path or log path
+// starts with. A leading "~" is the home directory. Nil turns host
+// attribution off.
+func SetHosts(roots map[string]string) {
+	hostRoots = make(map[string]string, len(roots))
+	home, _ := os.UserHomeDir()
+	for root, label := range roots {
+		if rest, ok := strings.CutPrefix(root, "~"); ok && home != "" {
+			root = home + rest
+		}
+		hostRoots[filepath.Clean(root)] = label
+	}
+}
+
+// SetHostFilter restricts the stats loaders to logs from one host; "" loads
+// every host
+func SetHostFilter(label string) {
+	hostFilter = label
+}
+
+// HostFilter returns the host the stats loaders are restricted to, "" for all
+func HostFilter() string {
+	return hostFilter
+}
+
+// Hosts returns the configured host labels, sorted
+func Hosts() []string {
+	seen := make(map[string]bool)
+	var labels []string
+	for _, label := range hostRoots {
+		if !seen[label] {
+			seen[label] = true
+			labels = append(labels, label)
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// HostOf returns the host a session or agent log came from: the label of
+// the longest configured root its project path or the log itself is under,
+// OtherHost if none is, and "" when no hosts are configured
+func HostOf(logPath string) string {
+	if len(hostRoots) == 0 {
+		return ""
+	}
+	// The project path before grouping, since grouped checkouts may live on
+	// different machines
+	project := projectDirPath(logProjectDir(logPath))
+
+	best, label := "", OtherHost
+	for root, l := range hostRoots {
+		if !underRoot(project, root) && !underRoot(logPath, root) {
+			continue
+		}
+		if len(root) > len(best) || (len(root) == len(best) && root < best) {
+			best, label = root, l
+		}
+	}
+	return label
+}
+
+// hostSelected reports whether logs from host pass the host filter
+func hostSelected(host string) bool {
+	return hostFilter == "" || host == hostFilter
+}
+
+// logProjectDir returns the encoded project directory a log is in: the
+// ancestor directly under the projects directory or, for logs read from
+// elsewhere, the directory holding the session (<project>/<session>/subagents
+// for agent logs)
+func logProjectDir(logPath string) string {
+	projectsDir := filepath.Clean(ProjectsDir())
+	for dir := filepath.Dir(logPath); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if filepath.Dir(dir) == projectsDir {
+			return dir
+		}
+	}
+	dir := filepath.Dir(logPath)
+	if filepath.Base(dir) == "subagents" {
+		dir = filepath.Dir(filepath.Dir(dir))
+	}
+	return dir
+}
+
+// underRoot reports whether path is root or inside it
+func underRoot(path, root string) bool {
+	return path == root || strings.HasPrefix(path, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator))
+}
@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHostOf(t *testing.T) {
+	SetHosts(map[string]string{
+		"/Users/me":         "laptop",
+		"/home/me":          "desktop",
+		"/home/me/work/ci":  "ci",
+		"/srv/sync/desktop": "desktop",
+	})
+	defer SetHosts(nil)
+
+	tests := []struct {
+		logPath, want string
+	}{
+		{"/p/-Users-me-code-app/s1.jsonl", "laptop"},
+		{"/p/-home-me-code-app/s1.jsonl", "desktop"},
+		{"/p/-home-me-work-ci-app/s1.jsonl", "ci"},                     // Longest root wins
+		{"/p/-home-me-code-app/s1/subagents/agent-a.jsonl", "desktop"}, // Agent logs belong to their project
+		{"/srv/sync/desktop/-opt-tool/s1.jsonl", "desktop"},            // Matched by where the log is
+		{"/p/-opt-tool/s1.jsonl", OtherHost},
+	}
+	for _, tt := range tests {
+		if got := HostOf(tt.logPath); got != tt.want {
+			t.Errorf("HostOf(%q) = %q, want %q", tt.logPath, got, tt.want)
+		}
+	}
+
+	SetHosts(nil)
+	if got := HostOf("/p/-Users-me-code-app/s1.jsonl"); got != "" {
+		t.Errorf("HostOf without hosts = %q, want \"\"", got)
+	}
+}
+
+func TestLoadStatsByHost(t *testing.T) {
+	projectsDir := t.TempDir()
+	for _, project := range []string{"-Users-me-app", "-home-me-app"} {
+		content := `{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t` + project + `","name":"Read","input":{"file_path":"/a"}}]}}
+`
+		dir := filepath.Join(projectsDir, project)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "session-001.jsonl"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	SetHosts(map[string]string{"/Users/me": "laptop", "/home/me": "desktop"})
+	defer SetHosts(nil)
+
+	stats, err := LoadAllPermissionStatsFrom(projectsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{"laptop": 1, "desktop": 1}; len(stats) != 1 || !reflect.DeepEqual(stats[0].HostCounts, want) {
+		t.Fatalf("Expected one use per host, got %+v", stats)
+	}
+
+	SetHostFilter("laptop")
+	defer SetHostFilter("")
+	stats, err = LoadAllPermissionStatsFrom(projectsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0].Count != 1 || !reflect.DeepEqual(stats[0].Projects, []string{"/Users/me/app"}) {
+		t.Errorf("Expected only the laptop's use, got %+v", stats)
+	}
+}
@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// maxLineSize caps how much of a single JSONL line is held in memory. Longer
+// lines (e.g. a Write of a huge file) are skipped and reported, and reading
+// carries on with the next line. A variable so tests can lower it.
+var maxLineSize = 64 << 20
+
+// lineReader reads JSONL lines of any length up to maxLineSize, growing its
+// buffer as needed. Unlike bufio.Scanner it never gives up on the rest of
+// the file because of one long line.
+type lineReader struct {
+	r       *bufio.Reader
+	buf     []byte
+	line    int  // 1-based number of the current line
+	size    int  // Length of the current line in bytes
+	tooLong bool // The current line exceeded maxLineSize; Bytes is empty
+	err     error
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{r: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// Next advances to the next line. It returns false at the end of the input
+// or on a read error, which Err then reports.
+func (lr *lineReader) Next() bool {
+	lr.buf = lr.buf[:0]
+	lr.size = 0
+	lr.tooLong = false
+
+	for {
+		chunk, err := lr.r.ReadSlice('\n')
+		lr.size += len(chunk)
+		if !lr.tooLong {
+			if len(lr.buf)+len(chunk) > maxLineSize {
+				lr.tooLong = true
+				lr.buf = lr.buf[:0]
+			} else {
+				lr.buf = append(lr.buf, chunk...)
+			}
+		}
+
+		switch err {
+		case bufio.ErrBufferFull:
+			continue
+		case nil:
+			lr.line++
+			return true
+		case io.EOF:
+			if lr.size == 0 {
+				return false
+			}
+			lr.line++
+			return true
+		default:
+			lr.err = err
+			return false
+		}
+	}
+}
+
+// Bytes returns the current line without its line ending. The slice is
+// reused by the next call to Next.
+func (lr *lineReader) Bytes() []byte {
+	return bytes.TrimRight(lr.buf, "\r\n")
+}
+
+// Line returns the 1-based number of the current line
+func (lr *lineReader) Line() int {
+	return lr.line
+}
+
+// TooLong reports whether the current line was skipped for exceeding
+// maxLineSize, and its length
+func (lr *lineReader) TooLong() (bool, int) {
+	return lr.tooLong, lr.size
+}
+
+// Err returns the read error that stopped Next, if any
+func (lr *lineReader) Err() error {
+	return lr.err
+}
+
+// tooLongWarning reports a line skipped for exceeding maxLineSize
+func tooLongWarning(path string, line, size int) Warning {
+	return Warning{File: path, Line: line, Reason: fmt.Sprintf("line too long (%d MB, limit %d MB), skipped", size>>20, maxLineSize>>20)}
+}
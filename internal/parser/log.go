@@ -0,0 +1,15 @@
+package parser
+
+import "log/slog"
+
+// logger receives debug output from the loaders. It discards everything
+// unless the CLI enables debug logging.
+var logger = slog.New(slog.DiscardHandler)
+
+// SetLogger sets the logger used by the parser. A nil logger disables logging.
+func SetLogger(l *slog.Logger) {
+	if l == nil {
+		l = slog.New(slog.DiscardHandler)
+	}
+	logger = l
+}
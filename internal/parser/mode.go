@@ -0,0 +1,67 @@
+package parser
+
+import "slices"
+
+// Permission modes Claude Code starts sessions in, set by defaultMode
+const (
+	ModeDefault           = "default"           // Prompts for anything no rule allows
+	ModeAcceptEdits       = "acceptEdits"       // Also runs file edits without prompting
+	ModePlan              = "plan"              // Read-only: nothing is edited or run
+	ModeBypassPermissions = "bypassPermissions" // Runs everything no rule denies without prompting
+)
+
+// PermissionMode is the permission mode a project's sessions start in, which
+// changes what its allow rules matter for: under acceptEdits, Edit rules are
+// moot, and under bypassPermissions only deny rules are.
+type PermissionMode struct {
+	Mode  string        // One of the Mode constants, or an unknown value as set
+	Layer SettingsLayer // The layer that set it; zero for the default
+
+	BypassDisabled bool          // A layer disables bypassPermissions mode
+	DisabledBy     SettingsLayer // The highest such layer
+}
+
+// EffectiveMode returns the permission mode layers put sessions in: the
+// defaultMode of the highest-precedence layer that sets one. A
+// bypassPermissions mode that any layer disables falls back to the default,
+// as Claude Code refuses to start in it.
+func EffectiveMode(layers []LayerRules) PermissionMode {
+	mode := PermissionMode{Mode: ModeDefault}
+	for _, l := range layers {
+		if l.DisableBypass && !mode.BypassDisabled {
+			mode.BypassDisabled, mode.DisabledBy = true, l.SettingsLayer
+		}
+		if l.DefaultMode != "" && mode.Layer.Path == "" {
+			mode.Mode, mode.Layer = l.DefaultMode, l.SettingsLayer
+		}
+	}
+	if mode.Mode == ModeBypassPermissions && mode.BypassDisabled {
+		mode.Mode, mode.Layer = ModeDefault, SettingsLayer{}
+	}
+	return mode
+}
+
+// modeSkipsPrompt reports whether a session in mode runs a call of tool
+// without prompting when no rule allows it: edits under acceptEdits, and
+// everything under bypassPermissions
+func modeSkipsPrompt(mode, tool string) bool {
+	switch mode {
+	case ModeBypassPermissions:
+		return true
+	case ModeAcceptEdits:
+		return slices.Contains(ruleFamilies["Edit"], tool)
+	}
+	return false
+}
+
+// LoadPermissionModes returns the effective permission mode of each project.
+// Unreadable settings files count as setting nothing; the settings loaders
+// report them.
+func LoadPermissionModes(projects []string) map[string]PermissionMode {
+	modes := make(map[string]PermissionMode, len(projects))
+	for _, p := range projects {
+		layers, _ := LoadSettingsLayers(p)
+		modes[p] = EffectiveMode(layers)
+	}
+	return modes
+}
@@ -0,0 +1,48 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEffectiveMode(t *testing.T) {
+	managed := SettingsLayer{Name: "managed", Path: "/etc/claude-code/managed-settings.json"}
+	project := SettingsLayer{Name: "project", Path: "/proj/.claude/settings.json"}
+	user := SettingsLayer{Name: "user", Path: "/home/u/.claude/settings.json"}
+
+	tests := []struct {
+		name   string
+		layers []LayerRules
+		want   string
+		layer  SettingsLayer
+	}{
+		{"unset", []LayerRules{{SettingsLayer: user}}, ModeDefault, SettingsLayer{}},
+		{"user", []LayerRules{{SettingsLayer: project}, {SettingsLayer: user, DefaultMode: ModeAcceptEdits}}, ModeAcceptEdits, user},
+		{"project over user", []LayerRules{{SettingsLayer: project, DefaultMode: ModePlan}, {SettingsLayer: user, DefaultMode: ModeAcceptEdits}}, ModePlan, project},
+		{"bypass disabled", []LayerRules{{SettingsLayer: managed, DisableBypass: true}, {SettingsLayer: user, DefaultMode: ModeBypassPermissions}}, ModeDefault, SettingsLayer{}},
+		{"bypass", []LayerRules{{SettingsLayer: user, DefaultMode: ModeBypassPermissions}}, ModeBypassPermissions, user},
+	}
+	for _, tt := range tests {
+		got := EffectiveMode(tt.layers)
+		if got.Mode != tt.want || got.Layer != tt.layer {
+			t.Errorf("%s: EffectiveMode = %s from %q, want %s from %q", tt.name, got.Mode, got.Layer.Path, tt.want, tt.layer.Path)
+		}
+	}
+}
+
+func TestReadLayerRulesReadsModeSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	content := `{"permissions": {"allow": ["Read"], "defaultMode": "acceptEdits", "disableBypassPermissionsMode": "disable"}}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := ReadLayerRules(SettingsLayer{Name: "user", Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules.DefaultMode != ModeAcceptEdits || !rules.DisableBypass || len(rules.Allow) != 1 {
+		t.Errorf("rules = %+v, want acceptEdits with bypass disabled", rules)
+	}
+}
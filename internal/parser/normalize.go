@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"os"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/types"
+)
+
+// Scope normalization merges permissions that differ only in how a path was
+// written ("bash deploy.sh" vs "./deploy.sh", "/Users/me/bin/x" vs "~/bin/x")
+// so they aggregate into one row. It runs when stats are merged, after the
+// cache, so changing it never requires a rescan.
+var (
+	normalizeDisabled bool
+	scopeAliases      map[string]string
+)
+
+// SetNormalization configures scope normalization. aliases maps a
+// permission string to the one it should be counted as, applied after the
+// built-in rules.
+func SetNormalization(enabled bool, aliases map[string]string) {
+	normalizeDisabled = !enabled
+	scopeAliases = aliases
+}
+
+// NormalizePermission returns the permission p is counted as
+func NormalizePermission(p types.Permission) types.Permission {
+	if normalizeDisabled {
+		return p
+	}
+
+	raw := p.Raw
+	if p.Type == "Bash" && strings.HasSuffix(p.Scope, ":*") {
+		raw = "Bash(" + normalizeCommand(strings.TrimSuffix(p.Scope, ":*")) + ":*)"
+	}
+	if alias, ok := scopeAliases[raw]; ok {
+		raw = alias
+	}
+
+	if raw == p.Raw {
+		return p
+	}
+	return ParsePermission(raw)
+}
+
+// normalizeCommand canonicalizes the command of a Bash scope
+// "bash deploy.sh" -> "./deploy.sh"
+// "/Users/me/bin/tool" -> "~/bin/tool"
+func normalizeCommand(cmd string) string {
+	// Running a script through its interpreter is the same as running it
+	if fields := strings.Fields(cmd); len(fields) == 2 && shellInterpreters[fields[0]] {
+		cmd = fields[1]
+		if !strings.HasPrefix(cmd, "/") && !strings.HasPrefix(cmd, "~") &&
+			!strings.HasPrefix(cmd, "./") && !strings.HasPrefix(cmd, "../") &&
+			!strings.HasPrefix(cmd, "$") {
+			cmd = "./" + cmd
+		}
+	}
+	return collapseHome(cmd)
+}
+
+// collapseHome rewrites paths under the home directory to start with ~
+func collapseHome(path string) string {
+	for _, prefix := range []string{"$HOME", "${HOME}"} {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return "~" + path[len(prefix):]
+		}
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" || home == "/" {
+		return path
+	}
+	if path == home || strings.HasPrefix(path, home+"/") {
+		return "~" + path[len(home):]
+	}
+	return path
+}
@@ -0,0 +1,40 @@
+package parser
+
+import (
+	"os"
+	"testing"
+)
+
+func TestNormalizePermission(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "/" {
+		t.Skip("no home directory")
+	}
+	t.Cleanup(func() { SetNormalization(true, nil) })
+	SetNormalization(true, map[string]string{"Bash(python3:*)": "Bash(python:*)"})
+
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"Bash(bash deploy.sh:*)", "Bash(./deploy.sh:*)"},
+		{"Bash(sh scripts/build.sh:*)", "Bash(./scripts/build.sh:*)"},
+		{"Bash(./deploy.sh:*)", "Bash(./deploy.sh:*)"},
+		{"Bash(" + home + "/bin/tool:*)", "Bash(~/bin/tool:*)"},
+		{"Bash($HOME/bin/tool:*)", "Bash(~/bin/tool:*)"},
+		{"Bash(bash ~/bin/tool:*)", "Bash(~/bin/tool:*)"},
+		{"Bash(python3:*)", "Bash(python:*)"},
+		{"Bash(go build:*)", "Bash(go build:*)"},
+		{"Read", "Read"},
+	}
+	for _, tt := range tests {
+		if got := NormalizePermission(ParsePermission(tt.raw)).Raw; got != tt.want {
+			t.Errorf("NormalizePermission(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+
+	SetNormalization(false, nil)
+	if got := NormalizePermission(ParsePermission("Bash(bash deploy.sh:*)")).Raw; got != "Bash(bash deploy.sh:*)" {
+		t.Errorf("disabled normalization changed scope to %q", got)
+	}
+}
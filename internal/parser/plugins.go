@@ -0,0 +1,275 @@
+package parser
+
+import (
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/types"
+)
+
+// InstalledPlugin is a plugin in the plugin cache with every version of it
+// that is installed
+type InstalledPlugin struct {
+	Name        string
+	Marketplace string
+	Dir         string   // cache/<marketplace>/<plugin>
+	Versions    []string // Oldest first, as ordered by CompareVersions
+}
+
+// Latest returns the newest installed version
+func (p InstalledPlugin) Latest() string {
+	if len(p.Versions) == 0 {
+		return ""
+	}
+	return p.Versions[len(p.Versions)-1]
+}
+
+// Previous returns the version installed before v, "" if v is the oldest
+func (p InstalledPlugin) Previous(v string) string {
+	for i, version := range p.Versions {
+		if version == v && i > 0 {
+			return p.Versions[i-1]
+		}
+	}
+	return ""
+}
+
+// ListInstalledPlugins lists the plugins in the plugin cache
+// (cache/<marketplace>/<plugin>/<version>), ordered by name
+func ListInstalledPlugins() []InstalledPlugin {
+	var plugins []InstalledPlugin
+	cacheDir := filepath.Join(claudeDir(), "plugins", "cache")
+	marketplaces, _ := readDir(cacheDir)
+	for _, marketplace := range marketplaces {
+		if !marketplace.IsDir() {
+			continue
+		}
+		entries, _ := readDir(filepath.Join(cacheDir, marketplace.Name()))
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			p := InstalledPlugin{
+				Name:        entry.Name(),
+				Marketplace: marketplace.Name(),
+				Dir:         filepath.Join(cacheDir, marketplace.Name(), entry.Name()),
+			}
+			versions, _ := readDir(p.Dir)
+			for _, v := range versions {
+				if v.IsDir() {
+					p.Versions = append(p.Versions, v.Name())
+				}
+			}
+			if len(p.Versions) == 0 {
+				continue
+			}
+			sort.SliceStable(p.Versions, func(i, j int) bool {
+				return CompareVersions(p.Versions[i], p.Versions[j]) < 0
+			})
+			plugins = append(plugins, p)
+		}
+	}
+	sort.SliceStable(plugins, func(i, j int) bool {
+		if plugins[i].Name != plugins[j].Name {
+			return plugins[i].Name < plugins[j].Name
+		}
+		return plugins[i].Marketplace < plugins[j].Marketplace
+	})
+	return plugins
+}
+
+// FindInstalledPlugin returns the installed plugin with the given name
+func FindInstalledPlugin(name string) (InstalledPlugin, bool) {
+	for _, p := range ListInstalledPlugins() {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return InstalledPlugin{}, false
+}
+
+// pluginVersion is the latest installed version of a plugin
+type pluginVersion struct {
+	Name    string
+	Version string
+	Dir     string // cache/<marketplace>/<plugin>/<version>
+}
+
+// latestPluginVersions lists the latest version of every plugin in the
+// plugin cache, which is the one the loaders read
+func latestPluginVersions() []pluginVersion {
+	var plugins []pluginVersion
+	for _, p := range ListInstalledPlugins() {
+		v := p.Latest()
+		plugins = append(plugins, pluginVersion{Name: p.Name, Version: v, Dir: filepath.Join(p.Dir, v)})
+	}
+	return plugins
+}
+
+// CompareVersions orders plugin version directory names the way semantic
+// versioning does, so 1.0.10 is newer than 1.0.9 and 1.1.0-beta older than
+// 1.1.0. A leading "v" and build metadata are ignored, missing parts count
+// as 0, and parts that aren't numbers compare as text. Returns -1, 0 or 1.
+func CompareVersions(a, b string) int {
+	a, b = strings.TrimPrefix(strings.TrimPrefix(a, "v"), "V"), strings.TrimPrefix(strings.TrimPrefix(b, "v"), "V")
+	a, _, _ = strings.Cut(a, "+")
+	b, _, _ = strings.Cut(b, "+")
+	aCore, aPre, aHasPre := strings.Cut(a, "-")
+	bCore, bPre, bHasPre := strings.Cut(b, "-")
+
+	aParts, bParts := strings.Split(aCore, "."), strings.Split(bCore, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		ap, bp := "0", "0"
+		if i < len(aParts) {
+			ap = aParts[i]
+		}
+		if i < len(bParts) {
+			bp = bParts[i]
+		}
+		if c := compareVersionPart(ap, bp); c != 0 {
+			return c
+		}
+	}
+
+	// A pre-release comes before its release
+	switch {
+	case aHasPre && !bHasPre:
+		return -1
+	case !aHasPre && bHasPre:
+		return 1
+	case !aHasPre && !bHasPre:
+		return 0
+	}
+	aIDs, bIDs := strings.Split(aPre, "."), strings.Split(bPre, ".")
+	for i := 0; i < len(aIDs) && i < len(bIDs); i++ {
+		if c := compareVersionPart(aIDs[i], bIDs[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(aIDs), len(bIDs))
+}
+
+// compareVersionPart compares one dot-separated part of a version: numbers
+// numerically, and below text, which compares lexically
+func compareVersionPart(a, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		if an < bn {
+			return -1
+		}
+		if an > bn {
+			return 1
+		}
+		return 0
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// compareInts returns -1, 0 or 1 as a is less than, equal to or greater than b
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// PluginDeclarations is what one installed version of a plugin declares
+type PluginDeclarations struct {
+	Plugin   string
+	Version  string
+	Agents   []types.AgentPermissions
+	Skills   []types.SkillPermissions
+	Commands []types.CommandPermissions
+}
+
+// LoadPluginVersion loads the agents, skills and commands of one installed
+// version of a plugin
+func LoadPluginVersion(p InstalledPlugin, version string) PluginDeclarations {
+	dir := filepath.Join(p.Dir, version)
+	d := PluginDeclarations{Plugin: p.Name, Version: version}
+	d.Agents, _ = loadAgentsFromDirWithVersion(filepath.Join(dir, "agents"), p.Name, version)
+	d.Skills, _ = loadSkillsFromDirWithVersion(filepath.Join(dir, "skills"), p.Name, version)
+	d.Commands = loadCommandsFromDir(filepath.Join(dir, "commands"), p.Name, version)
+	return d
+}
+
+// DeclarationChange is a difference in what one member of a plugin declares
+// between two versions
+type DeclarationChange struct {
+	Member  string   // "agent reviewer", "skill pdf" or "command /deploy"
+	List    string   // "tools", "allow" or "deny"
+	Added   []string // Declared in the newer version only
+	Removed []string // Declared in the older version only
+}
+
+// DiffPluginDeclarations compares the tools and rules every agent, skill and
+// command declares in two versions of a plugin. Members added or removed
+// between the versions show all their declarations as added or removed.
+// Changes are ordered by member, then list.
+func DiffPluginDeclarations(from, to PluginDeclarations) []DeclarationChange {
+	type lists map[string][]types.Permission // list name -> declarations
+	collect := func(d PluginDeclarations) map[string]lists {
+		members := make(map[string]lists)
+		for _, a := range d.Agents {
+			members["agent "+a.Name] = lists{"tools": a.Permissions, "allow": a.Allow, "deny": a.Deny}
+		}
+		for _, s := range d.Skills {
+			members["skill "+s.Name] = lists{"tools": s.Permissions, "allow": s.Allow, "deny": s.Deny}
+		}
+		for _, c := range d.Commands {
+			members["command /"+c.Name] = lists{"tools": c.Permissions}
+		}
+		return members
+	}
+	before, after := collect(from), collect(to)
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, m := range []map[string]lists{before, after} {
+		for name := range m {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	var changes []DeclarationChange
+	for _, name := range names {
+		for _, list := range []string{"tools", "allow", "deny"} {
+			added := missingRaws(after[name][list], before[name][list])
+			removed := missingRaws(before[name][list], after[name][list])
+			if len(added) > 0 || len(removed) > 0 {
+				changes = append(changes, DeclarationChange{Member: name, List: list, Added: added, Removed: removed})
+			}
+		}
+	}
+	return changes
+}
+
+// missingRaws returns the raw strings of perms that aren't in other
+func missingRaws(perms, other []types.Permission) []string {
+	have := make(map[string]bool)
+	for _, p := range other {
+		have[p.Raw] = true
+	}
+	var missing []string
+	for _, p := range perms {
+		if !have[p.Raw] {
+			missing = append(missing, p.Raw)
+		}
+	}
+	return missing
+}
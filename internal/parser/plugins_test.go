@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/b-open-io/claude-perms/internal/types"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.9", "1.0.10", -1},
+		{"1.0.10", "1.0.9", 1},
+		{"1.2.0", "1.2.0", 0},
+		{"v1.2.0", "1.2.0", 0},
+		{"1.2", "1.2.0", 0},
+		{"1.2.0+build.5", "1.2.0", 0},
+		{"2.0.0", "10.0.0", -1},
+		{"1.1.0-beta", "1.1.0", -1},
+		{"1.1.0-alpha", "1.1.0-beta", -1},
+		{"1.1.0-rc.2", "1.1.0-rc.10", -1},
+		{"1.1.0-rc", "1.1.0-rc.1", -1},
+		{"abc123", "1.0.0", 1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestDiffPluginDeclarations(t *testing.T) {
+	from := PluginDeclarations{
+		Agents: []types.AgentPermissions{
+			{Name: "reviewer", Permissions: ParsePermissions([]string{"Read", "Grep"})},
+			{Name: "old", Permissions: ParsePermissions([]string{"Bash"})},
+		},
+	}
+	to := PluginDeclarations{
+		Agents: []types.AgentPermissions{
+			{Name: "reviewer", Permissions: ParsePermissions([]string{"Read", "Bash"}), Deny: ParsePermissions([]string{"Bash(rm:*)"})},
+		},
+		Commands: []types.CommandPermissions{
+			{Name: "deploy", Permissions: ParsePermissions([]string{"Bash(git push:*)"})},
+		},
+	}
+
+	got := DiffPluginDeclarations(from, to)
+	want := []DeclarationChange{
+		{Member: "agent old", List: "tools", Removed: []string{"Bash"}},
+		{Member: "agent reviewer", List: "tools", Added: []string{"Bash"}, Removed: []string{"Grep"}},
+		{Member: "agent reviewer", List: "deny", Added: []string{"Bash(rm:*)"}},
+		{Member: "command /deploy", List: "tools", Added: []string{"Bash(git push:*)"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffPluginDeclarations =\n%+v\nwant\n%+v", got, want)
+	}
+}
@@ -0,0 +1,30 @@
+package parser
+
+// Progress is a loading status update streamed to the UI while scanning
+type Progress struct {
+	Stage   string // Human-readable phase, e.g. "Scanning sessions"
+	Project string // Decoded project path currently being scanned
+	Session string // Truncated session or agent ID currently being parsed
+	Done    int    // Files processed so far in this stage
+	Total   int    // Total files in this stage (0 if unknown)
+}
+
+// sendProgress delivers an update without blocking the loader. If the UI is
+// behind, intermediate updates are dropped; only the latest state matters.
+func sendProgress(progress chan<- Progress, p Progress) {
+	if progress == nil {
+		return
+	}
+	select {
+	case progress <- p:
+	default:
+	}
+}
+
+// shortID truncates a session or agent ID for display
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12] + "..."
+	}
+	return id
+}
@@ -0,0 +1,258 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Project grouping configured with SetProjectAliases
+var (
+	projectAliases map[string]string
+	groupByRemote  bool
+
+	projectMu      sync.Mutex
+	canonicalCache map[string]string
+	remoteIndex    map[string]string // git remote URL -> canonical project, built on first use
+)
+
+// SetProjectAliases configures which project paths count as one project.
+// aliases maps a project path, or a filepath.Match pattern such as
+// "/Users/me/code/app-*", to the path it is counted as. With byRemote,
+// git worktrees count as their main checkout and checkouts with the same
+// origin remote count as the first of them.
+func SetProjectAliases(aliases map[string]string, byRemote bool) {
+	projectMu.Lock()
+	defer projectMu.Unlock()
+	projectAliases = aliases
+	groupByRemote = byRemote
+	canonicalCache = nil
+	remoteIndex = nil
+}
+
+// resetProjectGroups forgets the canonical paths worked out so far, e.g.
+// after the projects directory changed
+func resetProjectGroups() {
+	projectMu.Lock()
+	defer projectMu.Unlock()
+	canonicalCache = nil
+	remoteIndex = nil
+}
+
+// CanonicalProject returns the project path a decoded project path is
+// counted as: the target of a matching alias, the main checkout of its git
+// repository when grouping by remote, or the path itself
+func CanonicalProject(path string) string {
+	if path == "" {
+		return path
+	}
+	projectMu.Lock()
+	defer projectMu.Unlock()
+	if len(projectAliases) == 0 && !groupByRemote {
+		return path
+	}
+	if c, ok := canonicalCache[path]; ok {
+		return c
+	}
+	if canonicalCache == nil {
+		canonicalCache = make(map[string]string)
+	}
+	c := resolveProject(path)
+	canonicalCache[path] = c
+	return c
+}
+
+// resolveProject works out a project's canonical path; the caller holds
+// projectMu
+func resolveProject(path string) string {
+	if alias, ok := projectAliases[path]; ok {
+		return alias
+	}
+	patterns := make([]string, 0, len(projectAliases))
+	for pattern := range projectAliases {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return projectAliases[pattern]
+		}
+	}
+
+	if !groupByRemote {
+		return path
+	}
+	if main := mainWorktree(path); main != "" {
+		path = main
+	}
+	if remote := gitRemote(path); remote != "" {
+		if remoteIndex == nil {
+			remoteIndex = buildRemoteIndex()
+		}
+		if first, ok := remoteIndex[remote]; ok {
+			return first
+		}
+	}
+	return path
+}
+
+// buildRemoteIndex maps the origin remote of every project under the
+// projects directory that exists on disk to the first such project, in path
+// order, so renamed or duplicate checkouts of a repository count as one
+func buildRemoteIndex() map[string]string {
+	index := make(map[string]string)
+	entries, err := readDir(ProjectsDir())
+	if err != nil {
+		return index
+	}
+	var paths []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			path := projectDirPath(filepath.Join(ProjectsDir(), entry.Name()))
+			if main := mainWorktree(path); main != "" {
+				path = main
+			}
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if remote := gitRemote(path); remote != "" {
+			if _, ok := index[remote]; !ok {
+				index[remote] = path
+			}
+		}
+	}
+	return index
+}
+
+// mainWorktree returns the main checkout of a linked git worktree, whose
+// .git is a file pointing into the main checkout's .git/worktrees; "" for
+// anything else
+func mainWorktree(path string) string {
+	data, err := readFile(filepath.Join(path, ".git"))
+	if err != nil {
+		return ""
+	}
+	gitdir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return ""
+	}
+	gitdir = strings.TrimSpace(gitdir)
+	if !filepath.IsAbs(gitdir) {
+		gitdir = filepath.Join(path, gitdir)
+	}
+	// <main>/.git/worktrees/<name>
+	if filepath.Base(filepath.Dir(gitdir)) != "worktrees" {
+		return ""
+	}
+	return filepath.Dir(filepath.Dir(filepath.Dir(gitdir)))
+}
+
+// gitRemote returns the URL of a checkout's origin remote, "" if it has none
+func gitRemote(path string) string {
+	file, err := openFile(filepath.Join(path, ".git", "config"))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	inOrigin := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inOrigin = line == `[remote "origin"]`
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok && inOrigin && strings.TrimSpace(key) == "url" {
+			return normalizeRemote(strings.TrimSpace(value))
+		}
+	}
+	return ""
+}
+
+// normalizeRemote makes the SSH and HTTPS URLs of a repository compare equal:
+// "git@github.com:me/app.git" and "https://github.com/me/app" both become
+// "github.com/me/app"
+func normalizeRemote(url string) string {
+	url = strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+	if i := strings.Index(url, "://"); i >= 0 {
+		url = url[i+3:]
+		if at := strings.Index(url, "@"); at >= 0 && at < strings.Index(url+"/", "/") {
+			url = url[at+1:]
+		}
+	} else if at := strings.Index(url, "@"); at >= 0 {
+		url = strings.Replace(url[at+1:], ":", "/", 1)
+	}
+	return strings.ToLower(url)
+}
+
+// projectDirPaths caches projectDirPath's answers by project directory
+var projectDirPaths sync.Map
+
+// projectDirScanLines caps how many lines of each session log projectDirPath
+// reads looking for a working directory
+const projectDirScanLines = 50
+
+// projectDirPath returns the path of the project an encoded project
+// directory (e.g. ~/.claude/projects/-Users-me-code-my-app) holds sessions
+// for. The encoding turns every "/", "." and "-" into "-", so it is read from
+// the cwd session log entries record, taking the first whose encoding is the
+// directory's name; the lossy decodeProjectPath is the fallback when no log
+// has one.
+func projectDirPath(dir string) string {
+	if path, ok := projectDirPaths.Load(dir); ok {
+		return path.(string)
+	}
+	encoded := filepath.Base(dir)
+	logs, _ := glob(filepath.Join(dir, "*.jsonl"))
+	for _, log := range logs {
+		if cwd := sessionCwd(log, encoded); cwd != "" {
+			projectDirPaths.Store(dir, cwd)
+			return cwd
+		}
+	}
+	// Not cached: a later session may record the real path
+	return decodeProjectPath(encoded)
+}
+
+// sessionCwd returns the first cwd recorded in the opening lines of a
+// session log that encodes to encoded, "" if there is none
+func sessionCwd(path, encoded string) string {
+	file, err := openFile(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	lr := newLineReader(file)
+	for i := 0; i < projectDirScanLines && lr.Next(); i++ {
+		var entry struct {
+			Cwd string `json:"cwd"`
+		}
+		if json.Unmarshal(lr.Bytes(), &entry) != nil || entry.Cwd == "" {
+			continue
+		}
+		// A session that moved to a subdirectory records that instead
+		if encodeProjectPath(entry.Cwd) == encoded {
+			return entry.Cwd
+		}
+	}
+	return ""
+}
+
+// encodeProjectPath encodes a project path the way Claude names its
+// directory under the projects directory: every character other than a
+// letter or digit becomes "-"
+func encodeProjectPath(path string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '-'
+	}, path)
+}
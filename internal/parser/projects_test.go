@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalProject(t *testing.T) {
+	root := t.TempDir()
+	gitRepo := func(dir, remote string) string {
+		path := filepath.Join(root, dir)
+		if err := os.MkdirAll(filepath.Join(path, ".git", "worktrees", "wt"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		config := "[core]\n\tbare = false\n[remote \"origin\"]\n\turl = " + remote + "\n"
+		if err := os.WriteFile(filepath.Join(path, ".git", "config"), []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	main := gitRepo("app", "git@github.com:me/app.git")
+	clone := gitRepo("clone", "https://github.com/me/app")
+	other := gitRepo("other", "git@github.com:me/other.git")
+	worktree := filepath.Join(root, "wt")
+	if err := os.MkdirAll(worktree, 0755); err != nil {
+		t.Fatal(err)
+	}
+	gitdir := "gitdir: " + filepath.Join(main, ".git", "worktrees", "wt") + "\n"
+	if err := os.WriteFile(filepath.Join(worktree, ".git"), []byte(gitdir), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The projects directory lists every checkout, encoded
+	projectsDir := t.TempDir()
+	for _, p := range []string{main, clone, other, worktree} {
+		if err := os.Mkdir(filepath.Join(projectsDir, strings.ReplaceAll(p, "/", "-")), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	SetProjectsDir(projectsDir)
+	defer SetProjectsDir("")
+	defer SetProjectAliases(nil, false)
+
+	SetProjectAliases(map[string]string{
+		"/code/foo-worktree-*": "/code/foo",
+		"/code/old-name":       "/code/new-name",
+	}, true)
+
+	tests := []struct {
+		path, want string
+	}{
+		{"/code/foo-worktree-2", "/code/foo"},
+		{"/code/old-name", "/code/new-name"},
+		{"/code/unrelated", "/code/unrelated"},
+		{worktree, main},
+		{clone, main}, // Same remote; "app" sorts before "clone"
+		{other, other},
+	}
+	for _, tt := range tests {
+		if got := CanonicalProject(tt.path); got != tt.want {
+			t.Errorf("CanonicalProject(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	SetProjectAliases(nil, false)
+	if got := CanonicalProject(worktree); got != worktree {
+		t.Errorf("without grouping CanonicalProject(%q) = %q", worktree, got)
+	}
+}
+
+func TestProjectDirPath(t *testing.T) {
+	projectsDir := t.TempDir()
+	write := func(encoded, log string) string {
+		dir := filepath.Join(projectsDir, encoded)
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if log != "" {
+			if err := os.WriteFile(filepath.Join(dir, "s1.jsonl"), []byte(log), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return dir
+	}
+
+	tests := []struct {
+		name, encoded, log, want string
+	}{
+		{"cwd", "-Users-me-my-app", `{"type":"summary"}` + "\n" + `{"type":"user","cwd":"/Users/me/my-app"}` + "\n", "/Users/me/my-app"},
+		{"dots", "-Users-me-site-example-com", `{"type":"user","cwd":"/Users/me/site.example.com"}` + "\n", "/Users/me/site.example.com"},
+		{"subdirectory", "-Users-me-tool", `{"type":"user","cwd":"/Users/me/tool/src"}` + "\n" + `{"type":"user","cwd":"/Users/me/tool"}` + "\n", "/Users/me/tool"},
+		{"no cwd", "-Users-me-old-app", `{"type":"user"}` + "\n", "/Users/me/old/app"},
+		{"no logs", "-Users-me-empty", "", "/Users/me/empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := projectDirPath(write(tt.encoded, tt.log)); got != tt.want {
+				t.Errorf("projectDirPath(%q) = %q, want %q", tt.encoded, got, tt.want)
+			}
+		})
+	}
+}
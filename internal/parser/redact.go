@@ -0,0 +1,131 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// redacted replaces a secret in a stored sample or prompt
+const redacted = "[REDACTED]"
+
+// maxRedactLen caps the input scanned for secrets. Samples and prompts are
+// cut shorter than this afterwards, so nothing past it is ever kept.
+const maxRedactLen = 8192
+
+// secretFormats are the secrets always redacted: cloud and API keys with a
+// recognizable shape, credentials in headers, URLs and flags, and values
+// assigned to names that say they're secret. Where a pattern has a group
+// named "secret" only it is replaced, so "Authorization: Bearer x" keeps
+// the header's name.
+var secretFormats = []*regexp.Regexp{
+	regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?(-----END [A-Z ]*PRIVATE KEY-----|$)`),
+	regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`),
+	regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`),
+	regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`),
+	regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`),
+	regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`),
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`),
+	regexp.MustCompile(`(?i)\b(proxy-)?authorization:\s*((basic|bearer|token)\s+)?(?P<secret>[^\s'"]+)`),
+	regexp.MustCompile(`(?i)\bbearer\s+(?P<secret>[A-Za-z0-9._~+/=-]{8,})`),
+	regexp.MustCompile(`://[^/\s:@]+:(?P<secret>[^/\s@]+)@`),
+	regexp.MustCompile(`(?i)--[\w-]*(password|passwd|token|secret|api-key)[\w-]*(=|\s+)(?P<secret>[^\s'"]+)`),
+	regexp.MustCompile(`(?i)\b[\w-]*(secret|token|passw(or)?d|api[_-]?key|access[_-]?key)[\w-]*["']?\s*[=:]\s*(?P<secret>"[^"]*"|'[^']*'|[^\s'"&;,]+)`),
+}
+
+// Redactor removes secrets from the inputs kept as samples and prompts,
+// before they reach the cache, the event store or an export
+type Redactor struct {
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor builds a redactor that also removes matches of patterns, Go
+// regular expressions. Claude Code's logs hold secrets in the shapes of
+// secretFormats most often, so those are always removed.
+func NewRedactor(patterns []string) (*Redactor, error) {
+	r := &Redactor{}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// Redact returns s, cut to maxRedactLen bytes, with every secret replaced
+func (r *Redactor) Redact(s string) string {
+	if len(s) > maxRedactLen {
+		s = s[:maxRedactLen]
+	}
+	for _, re := range secretFormats {
+		s = redactMatches(re, s)
+	}
+	for _, re := range r.patterns {
+		s = redactMatches(re, s)
+	}
+	return s
+}
+
+// redactMatches replaces re's matches in s, or their "secret" group
+func redactMatches(re *regexp.Regexp, s string) string {
+	group := re.SubexpIndex("secret")
+	if group < 0 {
+		return re.ReplaceAllLiteralString(s, redacted)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range re.FindAllStringSubmatchIndex(s, -1) {
+		start, end := m[2*group], m[2*group+1]
+		if start < 0 {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(redacted)
+		last = end
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// fingerprint identifies the redactor configuration so samples kept with
+// different patterns are not reused
+func (r *Redactor) fingerprint() string {
+	var b strings.Builder
+	for _, re := range secretFormats {
+		b.WriteString("f:" + re.String() + "\n")
+	}
+	for _, re := range r.patterns {
+		b.WriteString("p:" + re.String() + "\n")
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// redactor is the active redactor used by the parsers
+var redactor, _ = NewRedactor(nil)
+
+// SetRedactPatterns configures additional secrets to redact from samples
+// and prompts, as Go regular expressions
+func SetRedactPatterns(patterns []string) error {
+	r, err := NewRedactor(patterns)
+	if err != nil {
+		return err
+	}
+	redactor = r
+	return nil
+}
+
+// RedactionFingerprint identifies the configured redaction patterns. Stored
+// samples and prompts depend on them, so logs must be re-parsed when it
+// changes.
+func RedactionFingerprint() string {
+	return redactor.fingerprint()
+}
@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// rejectionFormats are the messages Claude Code writes as the tool_result of
+// a call that didn't run because it was refused: denied at the permission
+// prompt (with or without feedback for Claude), dismissed with Esc, refused
+// by a deny rule, or interrupted. They are matched at the start of the
+// result, so command output that merely mentions "rejected" (a git push
+// refused by the remote, say) stays a failure.
+var rejectionFormats = []*regexp.Regexp{
+	regexp.MustCompile(`^The user doesn't want to proceed with this tool use\.`),
+	regexp.MustCompile(`^The user doesn't want to take this action right now\.`),
+	regexp.MustCompile(`^Permission to use \S+ (with .* )?has been denied`),
+	regexp.MustCompile(`^\[Request interrupted by user( for tool use)?\]`),
+}
+
+// interruptMarker is the text item Claude Code adds beside the tool_result
+// when the user interrupts a call, as a JSON string
+var interruptMarker = []byte(`"[Request interrupted by user`)
+
+// RejectionMatcher decides whether tool_result text represents a user denial
+type RejectionMatcher struct {
+	markers  []string
+	patterns []*regexp.Regexp
+}
+
+// NewRejectionMatcher builds a matcher from plain substrings and regular
+// expressions. Claude Code's own rejection messages are always recognized.
+func NewRejectionMatcher(markers, patterns []string) (*RejectionMatcher, error) {
+	rm := &RejectionMatcher{}
+
+	for _, m := range markers {
+		if m = strings.TrimSpace(m); m != "" {
+			rm.markers = append(rm.markers, m)
+		}
+	}
+
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rejection pattern %q: %w", p, err)
+		}
+		rm.patterns = append(rm.patterns, re)
+	}
+
+	return rm, nil
+}
+
+// Matches reports whether text is one of Claude Code's rejection messages or
+// contains any configured marker or pattern
+func (rm *RejectionMatcher) Matches(text string) bool {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(text), "Error: ")
+	for _, re := range rejectionFormats {
+		if re.MatchString(trimmed) {
+			return true
+		}
+	}
+	for _, m := range rm.markers {
+		if strings.Contains(text, m) {
+			return true
+		}
+	}
+	for _, re := range rm.patterns {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// fingerprint identifies the matcher configuration so cached denial counts
+// computed with different markers are not reused
+func (rm *RejectionMatcher) fingerprint() string {
+	var b strings.Builder
+	for _, re := range rejectionFormats {
+		b.WriteString("f:" + re.String() + "\n")
+	}
+	for _, m := range rm.markers {
+		b.WriteString("m:" + m + "\n")
+	}
+	for _, re := range rm.patterns {
+		b.WriteString("p:" + re.String() + "\n")
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// rejectionMatcher is the active matcher used by the session parsers
+var rejectionMatcher, _ = NewRejectionMatcher(nil, nil)
+
+// SetRejectionMarkers configures additional denial markers for users running
+// Claude in other locales or with custom hook messages. markers are plain
+// substrings; patterns are Go regular expressions.
+func SetRejectionMarkers(markers, patterns []string) error {
+	rm, err := NewRejectionMatcher(markers, patterns)
+	if err != nil {
+		return err
+	}
+	rejectionMatcher = rm
+	return nil
+}
+
+// interrupted reports whether a message carries the text item Claude Code
+// adds when the user interrupts a tool call, which makes the errored
+// tool_results beside it refusals whatever their text
+func interrupted(content []ContentItem) bool {
+	for _, item := range content {
+		if item.Type == "text" && bytes.HasPrefix(item.Text, interruptMarker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package parser
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRejectionMatcher(t *testing.T) {
+	rm, err := NewRejectionMatcher([]string{"abgelehnt"}, []string{`(?i)refus[ée]`})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+
+	tests := []struct {
+		text     string
+		expected bool
+	}{
+		{"The user doesn't want to proceed with this tool use. The tool use was rejected", true},
+		{"Error: The user doesn't want to take this action right now. STOP", true},
+		{"Permission to use Bash with command rm -rf / has been denied.", true},
+		{" ! [rejected]        main -> main (fetch first)", false},
+		{"Der Benutzer hat die Ausführung abgelehnt.", true},
+		{"L'utilisateur a Refusé l'exécution", true},
+		{"exit status 1", false},
+	}
+
+	for _, tc := range tests {
+		if got := rm.Matches(tc.text); got != tc.expected {
+			t.Errorf("Matches(%q) = %v, expected %v", tc.text, got, tc.expected)
+		}
+	}
+}
+
+func TestNewRejectionMatcherInvalidPattern(t *testing.T) {
+	if _, err := NewRejectionMatcher(nil, []string{"("}); err == nil {
+		t.Fatal("expected error for invalid pattern")
+	}
+}
+
+func TestSetRejectionMarkersAffectsDenials(t *testing.T) {
+	t.Cleanup(func() { _ = SetRejectionMarkers(nil, nil) })
+
+	denials := func() (curl, jq int) {
+		stats, err := LoadAllPermissionStatsFrom("../../testdata/projects")
+		if err != nil {
+			t.Fatalf("load stats: %v", err)
+		}
+		for _, s := range stats {
+			switch s.Permission.Raw {
+			case "Bash(curl:*)":
+				curl = s.Denied
+			case "Bash(jq:*)":
+				jq = s.Denied
+			}
+		}
+		return curl, jq
+	}
+
+	curl, jq := denials()
+	if curl != 1 || jq != 0 {
+		t.Fatalf("default markers: curl=%d jq=%d, expected 1 and 0", curl, jq)
+	}
+
+	if err := SetRejectionMarkers([]string{"abgelehnt"}, nil); err != nil {
+		t.Fatalf("set markers: %v", err)
+	}
+	curl, jq = denials()
+	if curl != 1 || jq != 1 {
+		t.Fatalf("german markers: curl=%d jq=%d, expected 1 and 1", curl, jq)
+	}
+
+	raw, _ := json.Marshal("nothing to see")
+	if toolResultContainsRejection(raw) {
+		t.Fatal("plain output should not count as a rejection")
+	}
+}
+
+func TestSessionOutcomes(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    Outcome
+	}{
+		{"denied-prompt", OutcomeDenied},
+		{"denied-feedback", OutcomeDenied},
+		{"denied-esc", OutcomeDenied},
+		{"denied-rule", OutcomeDenied},
+		{"interrupted", OutcomeDenied},
+		{"failed-git-push", OutcomeFailed},
+		{"failed-output", OutcomeFailed},
+		{"approved-output", OutcomeApproved},
+		{"denied-record", OutcomeDenied},
+		{"approved-prompt", OutcomeApproved},
+		{"failed-approved", OutcomeFailed},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.fixture, func(t *testing.T) {
+			path := filepath.Join("../../testdata/rejections", tc.fixture+".jsonl")
+			events, warns, err := ParseSessionEvents(path, time.Time{})
+			if err != nil || len(warns) > 0 {
+				t.Fatalf("parse: %v %v", err, warns)
+			}
+			if len(events) != 1 {
+				t.Fatalf("got %d events, expected 1", len(events))
+			}
+			if events[0].Outcome != tc.want {
+				t.Errorf("outcome = %q, expected %q", events[0].Outcome, tc.want)
+			}
+		})
+	}
+}
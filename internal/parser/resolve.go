@@ -0,0 +1,528 @@
+package parser
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Decision is the outcome of a permission check
+type Decision string
+
+const (
+	DecisionAllow Decision = "allow" // Runs without prompting
+	DecisionAsk   Decision = "ask"   // Prompts the user
+	DecisionDeny  Decision = "deny"  // Refused outright
+)
+
+// SettingsLayer is one settings file Claude Code reads permission rules from
+type SettingsLayer struct {
+	Name string // "managed", "project local", "project", "user local" or "user"
+	Path string
+}
+
+// LayerRules are the allow, ask and deny rules of one settings layer, and
+// the permission mode settings it makes
+type LayerRules struct {
+	SettingsLayer
+	Allow []string
+	Ask   []string
+	Deny  []string
+
+	DefaultMode   string // permissions.defaultMode, "" if unset
+	DisableBypass bool   // permissions.disableBypassPermissionsMode is "disable"
+}
+
+// managedSettingsPath is the enterprise policy file, which overrides every
+// other layer
+func managedSettingsPath() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "/Library/Application Support/ClaudeCode/managed-settings.json"
+	case "windows":
+		return `C:\ProgramData\ClaudeCode\managed-settings.json`
+	default:
+		return "/etc/claude-code/managed-settings.json"
+	}
+}
+
+// SettingsLayers returns the settings files consulted for a project, highest
+// precedence first. An empty projectPath leaves out the project layers.
+func SettingsLayers(projectPath string) []SettingsLayer {
+	layers := []SettingsLayer{{Name: "managed", Path: managedSettingsPath()}}
+	if projectPath != "" {
+		layers = append(layers,
+			SettingsLayer{Name: "project local", Path: ProjectSettingsPath(projectPath)},
+			SettingsLayer{Name: "project", Path: filepath.Join(projectPath, ".claude", "settings.json")},
+		)
+	}
+	return append(layers,
+		SettingsLayer{Name: "user local", Path: UserSettingsPath()},
+		SettingsLayer{Name: "user", Path: filepath.Join(claudeDir(), "settings.json")},
+	)
+}
+
+// LoadSettingsLayers reads the rules of every settings layer for a project.
+// Missing files have no rules; unreadable ones are returned as warnings and
+// skipped.
+func LoadSettingsLayers(projectPath string) ([]LayerRules, []Warning) {
+	var layers []LayerRules
+	var warns []Warning
+	for _, l := range SettingsLayers(projectPath) {
+		rules, err := ReadLayerRules(l)
+		if err != nil {
+			warns = append(warns, Warning{File: l.Path, Reason: "unreadable settings: " + err.Error()})
+		}
+		layers = append(layers, rules)
+	}
+	return layers, warns
+}
+
+// ReadLayerRules reads one settings layer's rules. A missing file has no
+// rules; an unreadable one returns the error and no rules.
+func ReadLayerRules(l SettingsLayer) (LayerRules, error) {
+	rules := LayerRules{SettingsLayer: l}
+	data, err := readFile(l.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return rules, nil
+		}
+		return rules, err
+	}
+	doc, err := parseSettingsDocument(data)
+	if err != nil {
+		return rules, err
+	}
+	rules.Allow, rules.Ask, rules.Deny = doc.allow, doc.ask, doc.deny
+	rules.DefaultMode, rules.DisableBypass = doc.defaultMode, doc.disableBypass
+	return rules, nil
+}
+
+// RuleMatch is a settings rule that matched an invocation
+type RuleMatch struct {
+	Rule    string
+	List    Decision // The list the rule is in
+	Layer   SettingsLayer
+	Command string // For compound Bash commands, the part the rule matched
+}
+
+// Resolution is the result of checking a tool invocation against the
+// settings layers
+type Resolution struct {
+	Invocation string
+	Decision   Decision
+	Decider    *RuleMatch  // The rule that decided, nil if the default applied
+	Reason     string      // Why the default applied, when no rule decided
+	Matches    []RuleMatch // Every matching rule, deny first, then ask, then allow
+}
+
+// readOnlyTools don't prompt by default inside the project
+var readOnlyTools = map[string]bool{
+	"Read": true, "Glob": true, "Grep": true, "LS": true,
+	"NotebookRead": true, "TodoWrite": true,
+}
+
+// ruleFamilies lists the tools a rule type covers: Edit rules apply to
+// every file-editing tool and Read rules to every file-reading tool
+var ruleFamilies = map[string][]string{
+	"Edit": {"Edit", "Write", "MultiEdit", "NotebookEdit"},
+	"Read": {"Read", "Glob", "Grep", "LS", "NotebookRead"},
+}
+
+// Resolve decides whether a concrete tool invocation such as
+// "Bash(curl -X POST https://example.com)" or "Read(/etc/hosts)" would run,
+// prompt or be refused, approximating Claude Code's permission check: a
+// matching deny rule in any layer wins, then an ask rule, then an allow
+// rule; with no match read-only tools run inside the project and everything
+// else prompts. A compound Bash command runs only if every part is allowed.
+// projectPath anchors relative paths and project-relative rules.
+func Resolve(invocation string, layers []LayerRules, projectPath string) Resolution {
+	invocation = strings.TrimSpace(invocation)
+	res := Resolution{Invocation: invocation}
+	tool, arg := splitInvocation(invocation)
+
+	commands := []string{arg}
+	if tool == "Bash" {
+		if parts := splitShellCommand(arg); len(parts) > 1 {
+			commands = parts
+		}
+	}
+
+	// Each part is decided on its own; the strictest part decides the whole
+	type part struct {
+		command  string
+		decision Decision
+		decider  *RuleMatch
+	}
+	var parts []part
+	for _, cmd := range commands {
+		p := part{command: cmd, decision: defaultDecision(tool, cmd, projectPath)}
+		for _, list := range []Decision{DecisionDeny, DecisionAsk, DecisionAllow} {
+			matches := ruleMatchesFor(layers, list, tool, cmd, projectPath)
+			if len(commands) > 1 {
+				for i := range matches {
+					matches[i].Command = cmd
+				}
+			}
+			if len(matches) > 0 && p.decider == nil {
+				p.decider = &matches[0]
+				p.decision = list
+			}
+			res.Matches = append(res.Matches, matches...)
+		}
+		parts = append(parts, p)
+	}
+	sortMatches(res.Matches)
+
+	for _, want := range []Decision{DecisionDeny, DecisionAsk, DecisionAllow} {
+		for _, p := range parts {
+			if p.decision != want {
+				continue
+			}
+			res.Decision = want
+			res.Decider = p.decider
+			switch {
+			case p.decider != nil:
+			case want == DecisionAllow:
+				res.Reason = tool + " doesn't prompt inside the project"
+			case len(commands) > 1:
+				res.Reason = "no rule matches " + p.command
+			default:
+				res.Reason = "no rule matches"
+			}
+			if want == DecisionAllow && len(commands) > 1 {
+				res.Decider = nil
+				res.Reason = "every part of the command is allowed"
+			}
+			return res
+		}
+	}
+	return res
+}
+
+// ResolvePermission loads the settings layers for a project and resolves an
+// invocation against them
+func ResolvePermission(invocation, projectPath string) (Resolution, []Warning) {
+	layers, warns := LoadSettingsLayers(projectPath)
+	return Resolve(invocation, layers, projectPath), warns
+}
+
+// sortMatches orders matches deny, ask, allow, keeping each list's order
+func sortMatches(matches []RuleMatch) {
+	rank := map[Decision]int{DecisionDeny: 0, DecisionAsk: 1, DecisionAllow: 2}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return rank[matches[i].List] < rank[matches[j].List]
+	})
+}
+
+// ruleMatchesFor returns the rules in one list of every layer that match a
+// tool call, highest precedence layer first
+func ruleMatchesFor(layers []LayerRules, list Decision, tool, arg, projectPath string) []RuleMatch {
+	var matches []RuleMatch
+	for _, l := range layers {
+		rules := l.Allow
+		switch list {
+		case DecisionDeny:
+			rules = l.Deny
+		case DecisionAsk:
+			rules = l.Ask
+		}
+		matches = append(matches, matchRules(rules, list, l.SettingsLayer, tool, arg, projectPath)...)
+	}
+	return matches
+}
+
+// matchRules returns the rules that match a tool call
+func matchRules(rules []string, list Decision, layer SettingsLayer, tool, arg, projectPath string) []RuleMatch {
+	var matches []RuleMatch
+	for _, rule := range rules {
+		if ruleMatches(rule, tool, arg, projectPath, layer) {
+			matches = append(matches, RuleMatch{Rule: rule, List: list, Layer: layer})
+		}
+	}
+	return matches
+}
+
+// defaultDecision is what happens when no rule matches: read-only tools run
+// inside the project, everything else prompts
+func defaultDecision(tool, arg, projectPath string) Decision {
+	if !readOnlyTools[tool] {
+		return DecisionAsk
+	}
+	path := invocationPath(tool, arg)
+	if path == "" || projectPath == "" {
+		return DecisionAllow
+	}
+	path = absPath(path, projectPath)
+	if path == projectPath || strings.HasPrefix(path, strings.TrimSuffix(projectPath, "/")+"/") {
+		return DecisionAllow
+	}
+	return DecisionAsk
+}
+
+// splitInvocation splits "Tool(argument)" into its tool and argument. The
+// argument runs to the last parenthesis, so it may contain parentheses.
+func splitInvocation(invocation string) (tool, arg string) {
+	open := strings.Index(invocation, "(")
+	if open < 0 || !strings.HasSuffix(invocation, ")") {
+		return invocation, ""
+	}
+	return invocation[:open], strings.TrimSpace(invocation[open+1 : len(invocation)-1])
+}
+
+// splitShellCommand splits a command line on &&, ||, ;, | and newlines
+// outside quotes, dropping empty parts
+func splitShellCommand(command string) []string {
+	var parts []string
+	var cur strings.Builder
+	var quote rune
+	flush := func() {
+		if p := strings.TrimSpace(cur.String()); p != "" {
+			parts = append(parts, p)
+		}
+		cur.Reset()
+	}
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else if r == '\\' && quote == '"' && i+1 < len(runes) {
+				cur.WriteRune(r)
+				i++
+				r = runes[i]
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '\\' && i+1 < len(runes):
+			cur.WriteRune(r)
+			i++
+			r = runes[i]
+		case r == ';' || r == '\n':
+			flush()
+			continue
+		case r == '&' && i+1 < len(runes) && runes[i+1] == '&',
+			r == '|':
+			if i+1 < len(runes) && runes[i+1] == r {
+				i++
+			}
+			flush()
+			continue
+		}
+		cur.WriteRune(r)
+	}
+	flush()
+	return parts
+}
+
+// ruleMatches reports whether a settings rule covers a tool call
+func ruleMatches(rule, tool, arg, projectPath string, layer SettingsLayer) bool {
+	ruleTool, scope := splitInvocation(strings.TrimSpace(rule))
+
+	// MCP rules name a server or a single tool; neither takes a scope
+	if strings.HasPrefix(ruleTool, "mcp__") {
+		if ruleTool == tool {
+			return true
+		}
+		server := strings.TrimSuffix(strings.TrimSuffix(ruleTool, "*"), "__")
+		return strings.Count(server, "__") == 1 && strings.HasPrefix(tool, server+"__")
+	}
+
+	if !coversTool(ruleTool, tool) {
+		return false
+	}
+	if scope == "" || scope == "*" {
+		return true
+	}
+
+	switch {
+	case tool == "Bash":
+		return bashScopeMatches(scope, arg)
+	case tool == "WebFetch":
+		return fetchScopeMatches(scope, arg)
+	case ruleFamilies[ruleTool] != nil:
+		path := invocationPath(tool, arg)
+		return path != "" && pathScopeMatches(scope, absPath(path, projectPath), layer, projectPath)
+	default:
+		return scope == arg
+	}
+}
+
+// RuleCovers reports whether a settings rule covers every tool call another
+// rule (pattern) does, e.g. "Bash(rm:*)" covers "Bash(rm -rf:*)" but not the
+// other way round
+func RuleCovers(rule, pattern string) bool {
+	rule, pattern = strings.TrimSpace(rule), strings.TrimSpace(pattern)
+	if rule == pattern {
+		return true
+	}
+	ruleTool, ruleScope := splitInvocation(rule)
+	tool, scope := splitInvocation(pattern)
+	if strings.HasPrefix(ruleTool, "mcp__") || strings.HasPrefix(tool, "mcp__") {
+		return ruleMatches(rule, tool, "", "", SettingsLayer{})
+	}
+	if !coversTool(ruleTool, tool) {
+		return false
+	}
+	if ruleScope == "" || ruleScope == "*" {
+		return true
+	}
+	if scope == "" || scope == "*" {
+		return false
+	}
+
+	if tool == "Bash" {
+		// A prefix pattern is covered by rules that match its prefix and
+		// anything after it
+		if prefix, ok := strings.CutSuffix(scope, ":*"); ok {
+			return (strings.HasSuffix(ruleScope, ":*") || strings.HasSuffix(ruleScope, "*")) &&
+				bashScopeMatches(ruleScope, prefix)
+		}
+		if strings.Contains(scope, "*") {
+			return false
+		}
+	}
+	return ruleMatches(rule, tool, scope, "", SettingsLayer{})
+}
+
+// coversTool reports whether rules of ruleTool's type apply to tool
+func coversTool(ruleTool, tool string) bool {
+	if ruleTool == tool {
+		return true
+	}
+	for _, t := range ruleFamilies[ruleTool] {
+		if t == tool {
+			return true
+		}
+	}
+	return false
+}
+
+// bashScopeMatches matches a command against a Bash rule's scope:
+// "npm run test:*" matches the command and anything after it,
+// a scope containing * treats it as a wildcard, anything else must match
+// the whole command
+func bashScopeMatches(scope, command string) bool {
+	command = strings.Join(strings.Fields(command), " ")
+	if prefix, ok := strings.CutSuffix(scope, ":*"); ok {
+		prefix = strings.Join(strings.Fields(prefix), " ")
+		return command == prefix || strings.HasPrefix(command, prefix+" ")
+	}
+	if strings.Contains(scope, "*") {
+		return wildcardRegexp(scope).MatchString(command)
+	}
+	return command == strings.Join(strings.Fields(scope), " ")
+}
+
+// wildcardRegexp turns a pattern where * matches anything into a regexp
+func wildcardRegexp(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
+
+// fetchScopeMatches matches a URL against a WebFetch rule's
+// "domain:example.com" scope. The invocation may itself be written as a
+// domain: scope.
+func fetchScopeMatches(scope, arg string) bool {
+	domain, ok := strings.CutPrefix(scope, "domain:")
+	if !ok {
+		return scope == arg
+	}
+	host, isDomain := strings.CutPrefix(arg, "domain:")
+	if !isDomain {
+		u, err := url.Parse(arg)
+		if err != nil || u.Hostname() == "" {
+			return false
+		}
+		host = u.Hostname()
+	}
+	if rest, wild := strings.CutPrefix(domain, "*."); wild {
+		return strings.HasSuffix(host, "."+rest)
+	}
+	return strings.EqualFold(host, domain)
+}
+
+// invocationPath returns the file path a file tool's argument refers to:
+// the path itself, or for Grep and Glob the path after " in "
+func invocationPath(tool, arg string) string {
+	if tool == "Grep" || tool == "Glob" {
+		if _, path, ok := strings.Cut(arg, " in "); ok {
+			return path
+		}
+		return ""
+	}
+	return arg
+}
+
+// absPath resolves ~ and paths relative to the project
+func absPath(path, projectPath string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, rest)
+	}
+	if !filepath.IsAbs(path) && projectPath != "" {
+		return filepath.Join(projectPath, path)
+	}
+	return filepath.Clean(path)
+}
+
+// pathScopeMatches matches an absolute path against a Read or Edit rule's
+// gitignore-style scope: "//abs" is absolute, "~/x" is under the home
+// directory, "/x" is relative to the settings file's project (or the
+// project being checked, for user settings) and a bare pattern matches at
+// any depth. A pattern without wildcards also covers everything below it.
+func pathScopeMatches(scope, path string, layer SettingsLayer, projectPath string) bool {
+	var pattern string
+	switch {
+	case strings.HasPrefix(scope, "//"):
+		pattern = scope[1:]
+	case strings.HasPrefix(scope, "~/"):
+		home, _ := os.UserHomeDir()
+		pattern = filepath.Join(home, scope[2:])
+	case strings.HasPrefix(scope, "/"):
+		root := projectPath
+		if strings.HasPrefix(layer.Name, "project") {
+			root = filepath.Dir(filepath.Dir(layer.Path))
+		}
+		pattern = filepath.Join(root, scope)
+	default:
+		pattern = "**/" + strings.TrimPrefix(scope, "./")
+	}
+	return globRegexp(pattern).MatchString(path)
+}
+
+// globRegexp turns a gitignore-style glob into a regexp: ** crosses
+// directories, * and ? don't, and a match also covers paths beneath it
+func globRegexp(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("(?:/.*)?$")
+	return regexp.MustCompile(b.String())
+}
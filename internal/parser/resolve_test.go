@@ -0,0 +1,103 @@
+package parser
+
+import "testing"
+
+func TestResolve(t *testing.T) {
+	layers := []LayerRules{
+		{
+			SettingsLayer: SettingsLayer{Name: "managed", Path: "/etc/claude-code/managed-settings.json"},
+			Deny:          []string{"Bash(rm -rf:*)"},
+		},
+		{
+			SettingsLayer: SettingsLayer{Name: "project local", Path: "/proj/.claude/settings.local.json"},
+			Allow:         []string{"Bash(npm run test:*)", "Edit(/src/**)"},
+			Ask:           []string{"Bash(git push:*)"},
+			Deny:          []string{"Read(.env)"},
+		},
+		{
+			SettingsLayer: SettingsLayer{Name: "user local", Path: "/home/u/.claude/settings.local.json"},
+			Allow:         []string{"Bash(git:*)", "WebFetch(domain:example.com)", "mcp__github", "Task(Explore)"},
+		},
+	}
+
+	tests := []struct {
+		invocation string
+		want       Decision
+		rule       string // Deciding rule, "" for the default
+	}{
+		{"Bash(npm run test)", DecisionAllow, "Bash(npm run test:*)"},
+		{"Bash(npm run test -- --watch)", DecisionAllow, "Bash(npm run test:*)"},
+		{"Bash(npm run testing)", DecisionAsk, ""},
+		{"Bash(git push origin main)", DecisionAsk, "Bash(git push:*)"},
+		{"Bash(git status)", DecisionAllow, "Bash(git:*)"},
+		{"Bash(rm -rf /)", DecisionDeny, "Bash(rm -rf:*)"},
+		{"Bash(git status && rm -rf build)", DecisionDeny, "Bash(rm -rf:*)"},
+		{"Bash(git status && curl -X POST https://x.io)", DecisionAsk, ""},
+		{"Bash(git log | npm run test)", DecisionAllow, ""},
+		{"Bash(echo 'a && b')", DecisionAsk, ""},
+		{"Edit(/proj/src/main.go)", DecisionAllow, "Edit(/src/**)"},
+		{"Write(src/app/x.ts)", DecisionAllow, "Edit(/src/**)"},
+		{"Edit(/proj/docs/x.md)", DecisionAsk, ""},
+		{"Read(/proj/config/.env)", DecisionDeny, "Read(.env)"},
+		{"Read(/proj/README.md)", DecisionAllow, ""},
+		{"Read(/etc/hosts)", DecisionAsk, ""},
+		{"WebFetch(https://example.com/page)", DecisionAllow, "WebFetch(domain:example.com)"},
+		{"WebFetch(https://evil.com/example.com)", DecisionAsk, ""},
+		{"mcp__github__create_issue", DecisionAllow, "mcp__github"},
+		{"mcp__slack__post", DecisionAsk, ""},
+		{"Task(Explore)", DecisionAllow, "Task(Explore)"},
+		{"Task(general-purpose)", DecisionAsk, ""},
+	}
+	for _, tt := range tests {
+		res := Resolve(tt.invocation, layers, "/proj")
+		var rule string
+		if res.Decider != nil {
+			rule = res.Decider.Rule
+		}
+		if res.Decision != tt.want || rule != tt.rule {
+			t.Errorf("Resolve(%q) = %s by %q, want %s by %q", tt.invocation, res.Decision, rule, tt.want, tt.rule)
+		}
+		if res.Decider == nil && res.Reason == "" {
+			t.Errorf("Resolve(%q) has neither a deciding rule nor a reason", tt.invocation)
+		}
+	}
+}
+
+func TestSplitShellCommand(t *testing.T) {
+	got := splitShellCommand(`git add . && git commit -m "a; b" || echo 'x | y'; ls | wc -l`)
+	want := []string{"git add .", `git commit -m "a; b"`, "echo 'x | y'", "ls", "wc -l"}
+	if len(got) != len(want) {
+		t.Fatalf("splitShellCommand = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("part %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRuleCovers(t *testing.T) {
+	tests := []struct {
+		rule, pattern string
+		want          bool
+	}{
+		{"Bash", "Bash(rm -rf:*)", true},
+		{"Bash(rm:*)", "Bash(rm -rf:*)", true},
+		{"Bash(rm -rf:*)", "Bash(rm:*)", false},
+		{"Bash(rm -rf /tmp/x)", "Bash(rm -rf:*)", false},
+		{"Bash(rm -rf:*)", "Bash(rm -rf /tmp/x)", true},
+		{"Bash(git *)", "Bash(git push:*)", true},
+		{"Bash(curl:*)", "Bash(wget:*)", false},
+		{"WebFetch", "WebFetch(domain:example.com)", true},
+		{"WebFetch(domain:example.com)", "WebFetch", false},
+		{"Edit", "Write", true},
+		{"mcp__github", "mcp__github__create_issue", true},
+		{"Task", "Task(Explore)", true},
+		{"Task(Explore)", "Task(Plan)", false},
+	}
+	for _, tt := range tests {
+		if got := RuleCovers(tt.rule, tt.pattern); got != tt.want {
+			t.Errorf("RuleCovers(%q, %q) = %v, want %v", tt.rule, tt.pattern, got, tt.want)
+		}
+	}
+}
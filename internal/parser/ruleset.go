@@ -0,0 +1,195 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// RuleSet is allow and deny rules applied to a settings file together, e.g.
+// a template
+type RuleSet struct {
+	Allow []string
+	Deny  []string
+
+	Replaces []string // The file's rules removed from either list in favour of the set's
+}
+
+// RuleConflict is a rule of a set that contradicts rules already in the
+// settings file: an allow rule a file's deny rule covers, which would never
+// take effect, or a deny rule covering a file's allow rule, which it would
+// overrule
+type RuleConflict struct {
+	Rule     string   // The set's rule
+	Deny     bool     // Rule is one of the set's deny rules
+	Existing []string // The file's rules it contradicts
+}
+
+// RuleSetResult describes applying a rule set to a settings file
+type RuleSetResult struct {
+	FilePath  string
+	Allowed   []string // Allow rules added
+	Denied    []string // Deny rules added
+	Removed   []string // The file's rules removed for the set's: those it replaces, or conflicting ones
+	Conflicts []RuleConflict
+	Replaced  bool // Conflicts were resolved for the set; otherwise its conflicting rules were skipped
+	DryRun    bool
+}
+
+// Changed reports whether applying the set changed the file
+func (r *RuleSetResult) Changed() bool {
+	return len(r.Allowed)+len(r.Denied)+len(r.Removed) > 0
+}
+
+// ruleConflicts returns the rules of set that contradict doc's rules
+func ruleConflicts(doc *settingsDocument, set RuleSet) []RuleConflict {
+	var conflicts []RuleConflict
+	for _, a := range set.Allow {
+		var existing []string
+		for _, d := range doc.deny {
+			if RuleCovers(d, a) {
+				existing = append(existing, d)
+			}
+		}
+		if len(existing) > 0 {
+			conflicts = append(conflicts, RuleConflict{Rule: a, Existing: existing})
+		}
+	}
+	for _, d := range set.Deny {
+		var existing []string
+		for _, a := range doc.allow {
+			if RuleCovers(d, a) {
+				existing = append(existing, a)
+			}
+		}
+		if len(existing) > 0 {
+			conflicts = append(conflicts, RuleConflict{Rule: d, Deny: true, Existing: existing})
+		}
+	}
+	return conflicts
+}
+
+// mergeRuleSet adds set's rules to a settings document, returning the
+// formatted result. A rule that conflicts with the file's is skipped, or
+// with replace set, added in place of the file's rules it contradicts.
+func mergeRuleSet(data []byte, set RuleSet, replace bool) ([]byte, *RuleSetResult, error) {
+	doc, err := parseSettingsDocument(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse settings: %w", err)
+	}
+
+	result := &RuleSetResult{Replaced: replace}
+	for _, r := range set.Replaces {
+		for _, list := range []*[]string{&doc.allow, &doc.deny} {
+			if i := slices.Index(*list, r); i >= 0 {
+				*list = slices.Delete(*list, i, i+1)
+				result.Removed = append(result.Removed, r)
+			}
+		}
+	}
+	result.Conflicts = ruleConflicts(doc, set)
+	skip := make(map[string]bool)
+	for _, c := range result.Conflicts {
+		if !replace {
+			skip[c.Rule] = true
+			continue
+		}
+		from := &doc.deny
+		if c.Deny {
+			from = &doc.allow
+		}
+		for _, e := range c.Existing {
+			if i := slices.Index(*from, e); i >= 0 {
+				*from = slices.Delete(*from, i, i+1)
+				result.Removed = append(result.Removed, e)
+			}
+		}
+	}
+
+	for _, a := range set.Allow {
+		if !skip[a] && !doc.hasPermission(a) {
+			doc.allow = append(doc.allow, a)
+			result.Allowed = append(result.Allowed, a)
+		}
+	}
+	for _, d := range set.Deny {
+		if !skip[d] && !doc.hasDenial(d) {
+			doc.deny = append(doc.deny, d)
+			result.Denied = append(result.Denied, d)
+		}
+	}
+	if !result.Changed() {
+		return data, result, nil
+	}
+
+	output, err := doc.marshalIndent()
+	if err != nil {
+		return nil, nil, err
+	}
+	return output, result, nil
+}
+
+// PlanRuleSet returns the settings file at path as it is and as it would be
+// with set applied, and what applying it would do, without writing
+// anything. A missing file is empty before.
+func PlanRuleSet(path string, set RuleSet, replace bool) (before, after []byte, result *RuleSetResult, err error) {
+	before, err = os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, nil, err
+	}
+	after, result, err = mergeRuleSet(before, set, replace)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	result.FilePath = path
+	result.DryRun = true
+	return before, after, result, nil
+}
+
+// WriteRuleSet applies set to the settings file at path in a single write,
+// so the whole set makes one snapshot and one write hook call
+func WriteRuleSet(path string, set RuleSet, replace bool) (*RuleSetResult, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("create settings directory: %w", err)
+	}
+	releaseLock, err := acquireFileLock(path + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	defer releaseLock()
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	output, result, err := mergeRuleSet(data, set, replace)
+	if err != nil {
+		return nil, err
+	}
+	result.FilePath = path
+	if result.Changed() {
+		if err := writeFileAtomic(path, output, 0644); err != nil {
+			return nil, err
+		}
+		runWriteHook(path, data, output, result.action())
+	}
+	return result, nil
+}
+
+// action describes the change for the write hook, e.g. "allow Read, deny
+// Bash(rm:*)"
+func (r *RuleSetResult) action() string {
+	var parts []string
+	if len(r.Allowed) > 0 {
+		parts = append(parts, "allow "+strings.Join(r.Allowed, ", "))
+	}
+	if len(r.Denied) > 0 {
+		parts = append(parts, "deny "+strings.Join(r.Denied, ", "))
+	}
+	if len(r.Removed) > 0 {
+		parts = append(parts, "remove "+strings.Join(r.Removed, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
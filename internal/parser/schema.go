@@ -0,0 +1,116 @@
+package parser
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// logSchema is a layout of Claude Code's JSONL logs. Claude Code has changed
+// how it writes entries between releases, and an entry the current layout
+// can't decode is skipped, so each layout has a decoder that turns its lines
+// into a JSONLEntry and the parsers only deal with that.
+type logSchema int
+
+const (
+	schemaUnknown logSchema = iota
+	schemaMessage           // Current: tool_use and tool_result items in message.content, RFC 3339 timestamps
+	schemaEpoch             // Older: the same, with timestamps in Unix milliseconds
+	schemaFlat              // Oldest: each tool_use and tool_result an entry of its own
+)
+
+// detectSchema tells the layout of a log from one of its lines, or returns
+// schemaUnknown if the line doesn't show it
+func detectSchema(line []byte) logSchema {
+	var probe struct {
+		Type      string          `json:"type"`
+		Timestamp json.RawMessage `json:"timestamp"`
+		Message   json.RawMessage `json:"message"`
+	}
+	if json.Unmarshal(line, &probe) != nil {
+		return schemaUnknown
+	}
+	switch {
+	case probe.Type == "tool_use" || probe.Type == "tool_result":
+		return schemaFlat
+	case len(probe.Timestamp) > 0 && probe.Timestamp[0] >= '0' && probe.Timestamp[0] <= '9':
+		return schemaEpoch
+	case len(probe.Message) > 0 || len(probe.Timestamp) > 0:
+		return schemaMessage
+	}
+	return schemaUnknown
+}
+
+// decode decodes a line of a log in the layout into entry. As with
+// json.Unmarshal, a *json.UnmarshalTypeError means the line holds no tool
+// calls, e.g. a message whose content is text.
+func (s logSchema) decode(line []byte, entry *JSONLEntry) error {
+	switch s {
+	case schemaEpoch:
+		var e struct {
+			JSONLEntry
+			Timestamp int64 `json:"timestamp"`
+		}
+		if err := json.Unmarshal(line, &e); err != nil {
+			return err
+		}
+		*entry = e.JSONLEntry
+		if e.Timestamp > 0 {
+			entry.Timestamp = time.UnixMilli(e.Timestamp).UTC().Format(time.RFC3339Nano)
+		}
+		return nil
+
+	case schemaFlat:
+		var e struct {
+			ContentItem
+			Timestamp string `json:"timestamp"`
+			Cwd       string `json:"cwd"`
+		}
+		if err := json.Unmarshal(line, &e); err != nil {
+			return err
+		}
+		role := "assistant"
+		if e.Type == "tool_result" {
+			role = "user"
+		}
+		*entry = JSONLEntry{
+			Type:      role,
+			Message:   AssistantMessage{Role: role, Content: []ContentItem{e.ContentItem}},
+			Timestamp: e.Timestamp,
+			Cwd:       e.Cwd,
+		}
+		return nil
+	}
+	return json.Unmarshal(line, entry)
+}
+
+// schemaDecoder decodes the lines of one log, telling its layout from the
+// first line that shows it
+type schemaDecoder struct {
+	schema  logSchema
+	unknown int // Lines skipped before the layout was known
+}
+
+// decode decodes line into entry. It reports false, with a nil error, for a
+// line skipped because the log's layout isn't known yet.
+func (d *schemaDecoder) decode(line []byte, entry *JSONLEntry) (bool, error) {
+	if d.schema == schemaUnknown {
+		d.schema = detectSchema(line)
+		if d.schema == schemaUnknown {
+			if json.Valid(line) {
+				d.unknown++
+				return false, nil
+			}
+			return false, json.Unmarshal(line, entry)
+		}
+	}
+	return true, d.schema.decode(line, entry)
+}
+
+// warning reports a log none of whose lines with tool calls could be
+// decoded in a known layout, so that it doesn't silently count nothing
+func (d *schemaDecoder) warning(path string) []Warning {
+	if d.schema != schemaUnknown || d.unknown == 0 {
+		return nil
+	}
+	return []Warning{{File: path, Reason: "unrecognized log format: no tool calls read"}}
+}
@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSessionEventsReadsEverySchema(t *testing.T) {
+	at := func(sec int) time.Time {
+		return time.Date(2026, 1, 28, 10, 0, sec, 0, time.UTC)
+	}
+	want := []ToolEvent{
+		{Time: at(0), ID: "toolu_01", Permission: "Bash(ls:*)", Sample: "ls -la", Outcome: OutcomeApproved, ResultTime: at(2)},
+		{Time: at(5), ID: "toolu_02", Permission: "Bash(curl:*)", Sample: "curl https://example.com", Outcome: OutcomeDenied, ResultTime: at(9)},
+		{Time: at(12), ID: "toolu_03", Permission: "Read", Sample: "/test/project/README.md", Outcome: OutcomeApproved, ResultTime: at(13)},
+	}
+
+	for _, fixture := range []string{"message", "epoch", "flat"} {
+		t.Run(fixture, func(t *testing.T) {
+			path := filepath.Join("../../testdata/schemas", fixture+".jsonl")
+			events, warns, err := ParseSessionEvents(path, time.Time{})
+			if err != nil || len(warns) > 0 {
+				t.Fatalf("parse: %v %v", err, warns)
+			}
+			for i := range events {
+				events[i].Time = events[i].Time.UTC()
+				events[i].ResultTime = events[i].ResultTime.UTC()
+			}
+			if !reflect.DeepEqual(events, want) {
+				t.Errorf("events = %+v\nwant %+v", events, want)
+			}
+		})
+	}
+}
+
+func TestParseSessionEventsWarnsOnUnknownSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	content := `{"kind":"call","call":{"type":"tool_use","name":"Read","input":{"file_path":"/a"}}}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	events, warns, err := ParseSessionEvents(path, time.Time{})
+	if err != nil {
+		t.Fatalf("ParseSessionEvents: %v", err)
+	}
+	if len(events) != 0 || len(warns) != 1 || warns[0].Line != 0 {
+		t.Errorf("Expected no events and a warning for the file, got %v and %v", events, warns)
+	}
+}
@@ -5,14 +5,19 @@ import (
 	"strings"
 )
 
-// BashInput represents the input structure for Bash tool_use
-type BashInput struct {
-	Command string `json:"command"`
-}
-
-// SkillInput represents the input structure for Skill tool_use
-type SkillInput struct {
-	Skill string `json:"skill"`
+// toolInput holds the tool_use input fields that permission scopes and
+// samples are built from
+type toolInput struct {
+	Command      string `json:"command"`
+	FilePath     string `json:"file_path"`
+	NotebookPath string `json:"notebook_path"`
+	Path         string `json:"path"`
+	URL          string `json:"url"`
+	Query        string `json:"query"`
+	Pattern      string `json:"pattern"`
+	Skill        string `json:"skill"`
+	SubagentType string `json:"subagent_type"`
+	Description  string `json:"description"`
 }
 
 // ExtractPermissionScope extracts the full permission string from tool_use data
@@ -22,30 +27,140 @@ func ExtractPermissionScope(toolName string, inputJSON json.RawMessage) string {
 		return toolName
 	}
 
+	switch toolName {
+	case "Bash", "Skill", "Task":
+		var input toolInput
+		_ = json.Unmarshal(inputJSON, &input) // A mistyped field elsewhere doesn't hide the scope
+		return input.scope(toolName)
+		// Read, Write, Edit, Glob, Grep, etc. don't have scopes in settings.json format
+	}
+
+	return toolName
+}
+
+// maxSampleLen truncates long example inputs (heredocs, inline scripts)
+const maxSampleLen = 200
+
+// SampleInput returns a short human-readable example of what a tool_use did:
+// the command for Bash, the path for file tools, the URL for WebFetch, etc.
+// It returns "" for tools without an obvious summary.
+func SampleInput(toolName string, inputJSON json.RawMessage) string {
+	if len(inputJSON) == 0 {
+		return ""
+	}
+
+	var input toolInput
+	if err := json.Unmarshal(inputJSON, &input); err != nil {
+		return ""
+	}
+	return input.sample(toolName)
+}
+
+// scopeAndSample returns ExtractPermissionScope and SampleInput for one
+// tool_use, decoding its input (which may be a large Write) only once
+func scopeAndSample(toolName string, inputJSON []byte) (scope, sample string) {
+	scope, sample, _ = describeToolUse(toolName, inputJSON)
+	return scope, sample
+}
+
+// describeToolUse is scopeAndSample that also returns the file a Write,
+// Edit, MultiEdit or NotebookEdit call modifies, in full
+func describeToolUse(toolName string, inputJSON []byte) (scope, sample, target string) {
+	if len(inputJSON) == 0 {
+		return toolName, "", ""
+	}
+
+	var input toolInput
+	err := json.Unmarshal(inputJSON, &input)
+	scope = input.scope(toolName)
+	if err == nil {
+		sample = input.sample(toolName)
+		target = input.target(toolName)
+	}
+	return scope, sample, target
+}
+
+// target returns the file a tool_use modifies, if it's a file-writing tool
+func (input toolInput) target(toolName string) string {
+	switch toolName {
+	case "Write", "Edit", "MultiEdit":
+		return input.FilePath
+	case "NotebookEdit":
+		return input.NotebookPath
+	}
+	return ""
+}
+
+// scope builds the permission string for a tool_use from its input
+func (input toolInput) scope(toolName string) string {
 	switch toolName {
 	case "Bash":
-		var input BashInput
-		if err := json.Unmarshal(inputJSON, &input); err == nil && input.Command != "" {
-			cmd := extractBashCommand(input.Command)
-			if cmd != "" {
-				return "Bash(" + cmd + ":*)"
-			}
+		if cmd := extractBashCommand(input.Command); cmd != "" {
+			return "Bash(" + cmd + ":*)"
 		}
 	case "Skill":
-		var input SkillInput
-		if err := json.Unmarshal(inputJSON, &input); err == nil && input.Skill != "" {
+		if input.Skill != "" {
 			return "Skill(" + input.Skill + ")"
 		}
-		// Read, Write, Edit, Glob, Grep, etc. don't have scopes in settings.json format
+	case "Task":
+		// Rules allow or deny subagents by type, e.g. Task(Explore)
+		if input.SubagentType != "" {
+			return "Task(" + input.SubagentType + ")"
+		}
 	}
-
 	return toolName
 }
 
+// sample summarizes a tool_use's input for SampleInput
+func (input toolInput) sample(toolName string) string {
+	var sample string
+	switch toolName {
+	case "Bash":
+		sample = input.Command
+	case "Read", "Write", "Edit", "MultiEdit":
+		sample = input.FilePath
+	case "NotebookEdit":
+		sample = input.NotebookPath
+	case "Grep", "Glob":
+		sample = input.Pattern
+		if input.Path != "" {
+			sample += " in " + input.Path
+		}
+	case "WebFetch":
+		sample = input.URL
+	case "WebSearch":
+		sample = input.Query
+	case "Skill":
+		sample = input.Skill
+	case "Task":
+		sample = input.Description
+		if input.SubagentType != "" {
+			sample = input.SubagentType + ": " + sample
+		}
+	}
+
+	// Keep samples on one line, without secrets
+	sample = strings.Join(strings.Fields(redactor.Redact(sample)), " ")
+	if runes := []rune(sample); len(runes) > maxSampleLen {
+		sample = string(runes[:maxSampleLen-3]) + "..."
+	}
+	return sample
+}
+
+// shellInterpreters run the script named by their first argument
+var shellInterpreters = map[string]bool{"bash": true, "sh": true, "zsh": true}
+
+// compoundCommands are identified by their first two words: "go build"
+var compoundCommands = map[string]bool{
+	"go": true, "npm": true, "bun": true, "yarn": true,
+	"cargo": true, "git": true, "docker": true,
+}
+
 // extractBashCommand extracts the command name from a bash command string
 // "curl https://api.example.com" -> "curl"
 // "git -C /path status" -> "git"
 // "go build ./..." -> "go build"
+// "bash deploy.sh" -> "bash deploy.sh"
 func extractBashCommand(command string) string {
 	command = strings.TrimSpace(command)
 	if command == "" {
@@ -60,12 +175,13 @@ func extractBashCommand(command string) string {
 
 	firstWord := parts[0]
 
-	// Handle compound commands: "go build", "npm run", "bun run", etc.
-	compoundCommands := map[string]bool{
-		"go": true, "npm": true, "bun": true, "yarn": true,
-		"cargo": true, "git": true, "docker": true,
+	// A script run through its interpreter is identified by the script:
+	// "bash deploy.sh" -> "bash deploy.sh", not "bash"
+	if shellInterpreters[firstWord] && len(parts) >= 2 && !strings.HasPrefix(parts[1], "-") {
+		return firstWord + " " + parts[1]
 	}
 
+	// Handle compound commands: "go build", "npm run", "bun run", etc.
 	if compoundCommands[firstWord] && len(parts) >= 2 {
 		// Don't include flags as part of compound command
 		secondWord := parts[1]
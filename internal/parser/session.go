@@ -1,8 +1,8 @@
 package parser
 
 import (
-	"bufio"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"sort"
@@ -18,6 +18,40 @@ func claudeDir() string {
 	return filepath.Join(home, ".claude")
 }
 
+// ClaudeDir returns the path to the Claude configuration directory (~/.claude)
+func ClaudeDir() string {
+	return claudeDir()
+}
+
+// projectsDirOverride replaces ~/.claude/projects when set (see SetProjectsDir)
+var projectsDirOverride string
+
+// ProjectsDir returns the path to the session logs directory
+// (~/.claude/projects unless overridden with SetProjectsDir)
+func ProjectsDir() string {
+	if projectsDirOverride != "" {
+		return projectsDirOverride
+	}
+	return filepath.Join(claudeDir(), "projects")
+}
+
+// SetProjectsDir points the loaders at a non-standard session logs
+// directory. An empty dir restores the default.
+func SetProjectsDir(dir string) {
+	projectsDirOverride = dir
+	resetProjectGroups()
+}
+
+// CountSessions returns the number of sessions under projectsDir.
+// A missing directory counts as zero sessions.
+func CountSessions(projectsDir string) (int, error) {
+	_, total, err := collectProjectSessions(projectsDir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	return total, err
+}
+
 // SessionsIndex represents the actual sessions-index.json structure
 type SessionsIndex struct {
 	Version int            `json:"version"`
@@ -38,8 +72,8 @@ func LoadAllPermissionStats() ([]types.PermissionStats, error) {
 }
 
 // LoadAllPermissionStatsWithProgress loads permission stats with progress updates
-func LoadAllPermissionStatsWithProgress(progress chan<- string) ([]types.PermissionStats, error) {
-	return LoadAllPermissionStatsFromWithProgress(filepath.Join(claudeDir(), "projects"), progress)
+func LoadAllPermissionStatsWithProgress(progress chan<- Progress) ([]types.PermissionStats, error) {
+	return LoadAllPermissionStatsFromWithProgress(ProjectsDir(), progress)
 }
 
 // LoadAllPermissionStatsFrom loads permission stats from a specific projects directory
@@ -47,118 +81,247 @@ func LoadAllPermissionStatsFrom(projectsDir string) ([]types.PermissionStats, er
 	return LoadAllPermissionStatsFromWithProgress(projectsDir, nil)
 }
 
-// LoadAllPermissionStatsFromWithProgress loads permission stats with progress updates
-func LoadAllPermissionStatsFromWithProgress(projectsDir string, progress chan<- string) ([]types.PermissionStats, error) {
-	// Map to aggregate stats by permission
-	statsMap := make(map[string]*types.PermissionStats)
-	projectsMap := make(map[string]map[string]bool) // permission -> set of projects
+// projectSessions is a project directory with its indexed sessions
+type projectSessions struct {
+	dir      string // Absolute path of the encoded project directory
+	name     string // Decoded project path
+	sessions []SessionEntry
+}
 
-	// Walk all project directories
-	entries, err := os.ReadDir(projectsDir)
+// collectProjectSessions reads every project's sessions index up front so
+// loaders can report progress against a known total. Projects without a
+// readable index fall back to the session logs in their directory.
+func collectProjectSessions(projectsDir string) ([]projectSessions, int, error) {
+	entries, err := readDir(projectsDir)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil
-		}
-		return nil, err
+		return nil, 0, err
 	}
 
+	var projects []projectSessions
+	total := 0
 	for _, entry := range entries {
 		if !entry.IsDir() {
 			continue
 		}
 
 		projectPath := filepath.Join(projectsDir, entry.Name())
-		projectName := decodeProjectPath(entry.Name())
-
-		// Send progress update for project
-		if progress != nil {
-			progress <- projectName
-		}
-
-		// Read sessions index
 		indexPath := filepath.Join(projectPath, "sessions-index.json")
 		sessions, err := loadSessionsIndex(indexPath)
 		if err != nil {
-			continue // Skip projects without valid index
+			if !os.IsNotExist(err) {
+				addWarning(indexPath, 0, "invalid sessions index, reading session logs directly: %v", err)
+			}
+			sessions = globSessions(projectPath)
 		}
 
-		// Process each session
-		for _, session := range sessions {
-			// Send progress update for session
-			if progress != nil {
-				sessionID := session.SessionID
-				if len(sessionID) > 12 {
-					sessionID = sessionID[:12]
-				}
-				progress <- sessionID + "..." // Show truncated session ID
-			}
+		projects = append(projects, projectSessions{
+			dir:      projectPath,
+			name:     CanonicalProject(projectDirPath(projectPath)),
+			sessions: sessions,
+		})
+		total += len(sessions)
+	}
+	return projects, total, nil
+}
+
+// globSessions lists a project's session logs without a sessions index,
+// taking each session's time from its file's mtime. Agent logs
+// (agent-*.jsonl) are not sessions and are left to the agent loader.
+func globSessions(projectPath string) []SessionEntry {
+	logs, _ := glob(filepath.Join(projectPath, "*.jsonl"))
+	sessions := make([]SessionEntry, 0, len(logs))
+	for _, log := range logs {
+		if strings.HasPrefix(filepath.Base(log), "agent-") {
+			continue
+		}
+		info, err := statFile(log)
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, SessionEntry{
+			SessionID: strings.TrimSuffix(filepath.Base(log), ".jsonl"),
+			FullPath:  log,
+			FileMtime: info.ModTime().UnixMilli(),
+		})
+	}
+	return sessions
+}
 
-			sessionPath := filepath.Join(projectPath, session.SessionID+".jsonl")
+// sessionTime returns the best-known modification time of an indexed session
+func (s SessionEntry) sessionTime() time.Time {
+	var t time.Time
+	if s.Modified != "" {
+		t, _ = time.Parse(time.RFC3339, s.Modified)
+	}
+	if t.IsZero() {
+		t = time.Unix(s.FileMtime/1000, 0)
+	}
+	return t
+}
 
-			// Parse modified time
-			var sessionTime time.Time
-			if session.Modified != "" {
-				sessionTime, _ = time.Parse(time.RFC3339, session.Modified)
-			}
-			if sessionTime.IsZero() {
-				sessionTime = time.Unix(session.FileMtime/1000, 0)
-			}
+// LoadAllPermissionStatsFromWithProgress loads permission stats with progress updates
+func LoadAllPermissionStatsFromWithProgress(projectsDir string, progress chan<- Progress) ([]types.PermissionStats, error) {
+	stats := make(permissionAggregator)
 
-			perms, err := parseSessionLog(sessionPath, sessionTime)
+	// Walk all project directories
+	projects, total, err := collectProjectSessions(projectsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	counted := make(toolUseSet)
+	done := 0
+	for _, project := range projects {
+		projectName := project.name
+
+		// Process each session
+		for _, session := range project.sessions {
+			sendProgress(progress, Progress{
+				Stage:   "Scanning sessions",
+				Project: projectName,
+				Session: shortID(session.SessionID),
+				Done:    done,
+				Total:   total,
+			})
+			done++
+
+			sessionPath := filepath.Join(project.dir, session.SessionID+".jsonl")
+
+			perms, ids, warns, err := parseSessionLog(sessionPath, session.sessionTime())
 			if err != nil {
+				addWarning(sessionPath, 0, "unreadable session log: %v", err)
 				continue
 			}
-
-			// Aggregate stats
-			for _, p := range perms {
-				key := PermissionKey(p.Permission)
-
-				if _, exists := statsMap[key]; !exists {
-					statsMap[key] = &types.PermissionStats{
-						Permission: p.Permission,
-						Count:      0,
-						LastSeen:   time.Time{},
-						Projects:   nil,
-					}
-					projectsMap[key] = make(map[string]bool)
-				}
-
-				statsMap[key].Count += p.Count
-				statsMap[key].Approved += p.Approved
-				statsMap[key].Denied += p.Denied
-				if p.LastSeen.After(statsMap[key].LastSeen) {
-					statsMap[key].LastSeen = p.LastSeen
-				}
-				projectsMap[key][projectName] = true
+			recordWarnings(warns)
+			if perms, err = counted.claim(sessionPath, session.sessionTime(), perms, ids); err != nil {
+				addWarning(sessionPath, 0, "unreadable session log: %v", err)
+				continue
 			}
+
+			stats.add(perms, sessionPath, projectName)
 		}
 	}
 
-	// Convert map to slice
-	stats := make([]types.PermissionStats, 0, len(statsMap))
-	for key, s := range statsMap {
-		// Build projects list
-		projects := make([]string, 0, len(projectsMap[key]))
-		for proj := range projectsMap[key] {
-			projects = append(projects, proj)
+	return stats.stats(), nil
+}
+
+// sortStats orders stats by count, most used first, breaking ties by
+// permission so the order is the same on every load
+func sortStats(stats []types.PermissionStats) {
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return stats[i].Permission.Raw < stats[j].Permission.Raw
+	})
+}
+
+// maxSamples caps the example inputs kept per permission
+const maxSamples = 5
+
+// contextTools are the tools whose rules take no scope, so a rule allowing
+// one says nothing of what it was used for. Their inputs are counted, for
+// the detail view to show what the bare rule would allow.
+var contextTools = map[string]bool{"WebSearch": true, "Glob": true, "Grep": true}
+
+// maxInputs caps the distinct inputs counted per permission. Past twice as
+// many, the least used are dropped down to it.
+const maxInputs = 50
+
+// addInputs adds the input counts src into dst, allocating dst if needed
+func addInputs(dst, src map[string]int) map[string]int {
+	if len(src) == 0 {
+		return dst
+	}
+	dst = addCounts(dst, src)
+	if len(dst) > 2*maxInputs {
+		keys := make([]string, 0, len(dst))
+		for k := range dst {
+			keys = append(keys, k)
 		}
-		sort.Strings(projects)
-		s.Projects = projects
+		sort.Slice(keys, func(i, j int) bool {
+			if dst[keys[i]] != dst[keys[j]] {
+				return dst[keys[i]] > dst[keys[j]]
+			}
+			return keys[i] < keys[j]
+		})
+		for _, k := range keys[maxInputs:] {
+			delete(dst, k)
+		}
+	}
+	return dst
+}
 
-		stats = append(stats, *s)
+// mergeSessionStats adds one session's stats for a permission into the
+// running total for the permission it normalizes to
+func mergeSessionStats(dst *types.PermissionStats, p types.PermissionStats, project, host string) {
+	dst.Count += p.Count
+	dst.Tokens += p.Tokens
+	dst.Approved += p.Approved
+	dst.AutoApproved += p.AutoApproved
+	dst.Denied += p.Denied
+	dst.DeniedAt = append(dst.DeniedAt, p.DeniedAt...)
+	if p.LastSeen.After(dst.LastSeen) {
+		dst.LastSeen = p.LastSeen
+	}
+	if !p.FirstSeen.IsZero() && (dst.FirstSeen.IsZero() || p.FirstSeen.Before(dst.FirstSeen)) {
+		dst.FirstSeen = p.FirstSeen
+	}
+	dst.ProjectCounts[project] += p.Count
+	if host != "" {
+		dst.HostCounts[host] += p.Count
 	}
+	dst.Variants[p.Permission.Raw] += p.Count
+	for _, s := range p.Samples {
+		dst.Samples = addSample(dst.Samples, s)
+	}
+	dst.Inputs = addInputs(dst.Inputs, p.Inputs)
+	dst.Slowest = types.AddSlowCalls(dst.Slowest, p.Slowest...)
+	addOutside(dst, p)
+	for _, w := range p.OutsideWrites {
+		w.Project = project
+		dst.OutsideWrites = types.AddOutsideWrites(dst.OutsideWrites, w)
+	}
+}
 
-	// Sort by count (descending)
-	sort.Slice(stats, func(i, j int) bool {
-		return stats[i].Count > stats[j].Count
-	})
+// addOutside adds p's calls that ran outside the project directory to dst
+func addOutside(dst *types.PermissionStats, p types.PermissionStats) {
+	dst.Outside += p.Outside
+	for _, dir := range p.OutsideDirs {
+		dst.OutsideDirs = addSample(dst.OutsideDirs, dir)
+	}
+}
 
-	return stats, nil
+// addSample appends s to samples unless it's empty, already present, or the
+// list is full
+func addSample(samples []string, s string) []string {
+	if s == "" || len(samples) >= maxSamples {
+		return samples
+	}
+	for _, existing := range samples {
+		if existing == s {
+			return samples
+		}
+	}
+	return append(samples, s)
+}
+
+// sortedProjects returns the project paths of a per-project count map
+func sortedProjects(counts map[string]int) []string {
+	projects := make([]string, 0, len(counts))
+	for proj := range counts {
+		projects = append(projects, proj)
+	}
+	sort.Strings(projects)
+	return projects
 }
 
 // loadSessionsIndex reads and parses sessions-index.json
 func loadSessionsIndex(path string) ([]SessionEntry, error) {
-	data, err := os.ReadFile(path)
+	data, err := readFile(path)
 	if err != nil {
 		return nil, err
 	}
@@ -171,132 +334,166 @@ func loadSessionsIndex(path string) ([]SessionEntry, error) {
 	return index.Entries, nil
 }
 
-// JSONLEntry represents a line in the JSONL session log
+// JSONLEntry represents a line in the JSONL session log. The message is
+// decoded in the same pass as the line.
 type JSONLEntry struct {
-	Type      string          `json:"type"`
-	Message   json.RawMessage `json:"message"`
-	Timestamp string          `json:"timestamp"`
+	Type      string           `json:"type"`
+	Message   AssistantMessage `json:"message"`
+	Timestamp string           `json:"timestamp"`
+	Cwd       string           `json:"cwd"` // Working directory when the entry was logged
+
+	// Explicit permission records, which newer logs write besides the
+	// tool_result text: a system entry with subtype "permission_prompt" for
+	// each prompt answered, and a result entry listing the denied calls
+	Subtype           string             `json:"subtype"`
+	ToolUseID         string             `json:"toolUseID"` // The tool_use a prompt was for
+	Decision          string             `json:"decision"`  // The prompt's answer, "allow" or "deny"
+	PermissionDenials []PermissionDenial `json:"permission_denials"`
+
+	// The permission mode a session is in, which user entries of newer logs
+	// record: "default", "acceptEdits", "plan" or "bypassPermissions"
+	PermissionMode string `json:"permissionMode"`
+}
+
+// PermissionDenial is a tool call a result entry records as denied
+type PermissionDenial struct {
+	ToolName  string `json:"tool_name"`
+	ToolUseID string `json:"tool_use_id"`
 }
 
-// AssistantMessage represents the message field for assistant entries
+// AssistantMessage represents the message field for assistant entries.
+// Plain-text messages, whose content is a string, fail to decode with a
+// *json.UnmarshalTypeError; callers skip those lines.
 type AssistantMessage struct {
+	ID      string        `json:"id"`
 	Role    string        `json:"role"`
 	Content []ContentItem `json:"content"`
+	Usage   Usage         `json:"usage"`
+}
+
+// Usage is the token usage the API reported for an assistant message
+type Usage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// Total returns every token the message cost: its context, cached or not,
+// and its output
+func (u Usage) Total() int {
+	return u.InputTokens + u.OutputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
 }
 
 // ContentItem represents an item in the content array
 type ContentItem struct {
-	Type      string          `json:"type"`
-	ID        string          `json:"id,omitempty"`          // tool_use ID
-	Name      string          `json:"name"`                  // Tool name for tool_use
-	Input     json.RawMessage `json:"input"`                 // Captures raw input JSON
-	ToolUseID string          `json:"tool_use_id,omitempty"` // For tool_result entries
-	IsError   bool            `json:"is_error,omitempty"`    // For tool_result entries
-	Content   json.RawMessage `json:"content,omitempty"`     // For tool_result entries (string or array)
-}
-
-// parseSessionLog parses a JSONL session log and extracts tool_use events
-func parseSessionLog(path string, sessionTime time.Time) ([]types.PermissionStats, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
+	Type      string  `json:"type"`
+	ID        string  `json:"id,omitempty"`          // tool_use ID
+	Name      string  `json:"name"`                  // Tool name for tool_use
+	Input     rawJSON `json:"input"`                 // Captures raw input JSON
+	ToolUseID string  `json:"tool_use_id,omitempty"` // For tool_result entries
+	IsError   bool    `json:"is_error,omitempty"`    // For tool_result entries
+	Content   rawJSON `json:"content,omitempty"`     // For tool_result entries (string or array)
+	Text      rawJSON `json:"text,omitempty"`        // For text entries, as a JSON string
+}
 
-	// Map to count permissions in this session
-	counts := make(map[string]int)
-	approved := make(map[string]int)
-	denied := make(map[string]int)
-	lastSeen := make(map[string]time.Time)
+// rawJSON is like json.RawMessage but references the decoded line instead
+// of copying it, which matters for large Write inputs and tool results. It
+// is only valid until the line buffer is reused for the next line.
+type rawJSON []byte
 
-	// Map tool_use ID -> permission key for correlating results
-	toolUseIDToKey := make(map[string]string)
+func (r *rawJSON) UnmarshalJSON(data []byte) error {
+	*r = data
+	return nil
+}
 
-	scanner := bufio.NewScanner(file)
-	// Increase buffer size for large lines
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 1024*1024)
+// isTypeError reports whether a decode failed only because a value had an
+// unexpected type, e.g. a message whose content is text rather than a list
+func isTypeError(err error) bool {
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &typeErr)
+}
 
-	for scanner.Scan() {
-		line := scanner.Bytes()
-		lineStr := string(line)
+// Substrings a line must contain to be worth decoding
+var (
+	toolUseMarker    = []byte(`"tool_use"`)
+	toolResultMarker = []byte(`"tool_result"`)
+	permissionMarker = []byte(`"permission_`) // permission_prompt or permission_denials
+	modeMarker       = []byte(`"permissionMode"`)
+)
 
-		// Quick check: skip lines that don't contain tool_use or tool_result
-		if !strings.Contains(lineStr, `"tool_use"`) && !strings.Contains(lineStr, `"tool_result"`) {
-			continue
-		}
+// parseSessionLog parses a JSONL session log into per-permission stats and
+// the tool_use IDs of the calls they count. Malformed lines are skipped and
+// reported as warnings.
+func parseSessionLog(path string, sessionTime time.Time) ([]types.PermissionStats, []string, []Warning, error) {
+	events, warns, err := ParseSessionEvents(path, sessionTime)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return sessionStats(events), eventIDs(events), warns, nil
+}
 
-		var entry JSONLEntry
-		if err := json.Unmarshal(line, &entry); err != nil {
-			continue
+// eventIDs returns the tool_use IDs of events that have one
+func eventIDs(events []ToolEvent) []string {
+	ids := make([]string, 0, len(events))
+	for _, e := range events {
+		if e.ID != "" {
+			ids = append(ids, e.ID)
 		}
+	}
+	return ids
+}
 
-		// Parse the message
-		var msg AssistantMessage
-		if err := json.Unmarshal(entry.Message, &msg); err != nil {
+// toolUseSet is the tool calls counted so far, by tool_use ID. A session
+// resumed with --resume or --continue, or forked from another, starts a new
+// log with a copy of the conversation it picks up, tool calls and all, so a
+// call can be in several logs; it's counted in the first one read. The IDs
+// are unique across sessions and projects, so one set covers every log.
+type toolUseSet map[string]bool
+
+// fresh returns the events of a log not counted yet and marks them counted
+func (s toolUseSet) fresh(events []ToolEvent) []ToolEvent {
+	var out []ToolEvent
+	for _, e := range events {
+		if e.ID != "" && s[e.ID] {
 			continue
 		}
-
-		for _, item := range msg.Content {
-			if item.Type == "tool_use" && item.Name != "" {
-				// Extract full permission with scope from input
-				permString := ExtractPermissionScope(item.Name, item.Input)
-				perm := ParsePermission(permString)
-				key := PermissionKey(perm)
-
-				counts[key]++
-
-				// Record mapping from tool_use ID to permission key
-				if item.ID != "" {
-					toolUseIDToKey[item.ID] = key
-				}
-
-				// Parse entry timestamp
-				entryTime := sessionTime
-				if entry.Timestamp != "" {
-					if t, err := time.Parse(time.RFC3339, entry.Timestamp); err == nil {
-						entryTime = t
-					}
-				}
-
-				if _, exists := lastSeen[key]; !exists || entryTime.After(lastSeen[key]) {
-					lastSeen[key] = entryTime
-				}
-			} else if item.Type == "tool_result" && item.ToolUseID != "" {
-				key, exists := toolUseIDToKey[item.ToolUseID]
-				if !exists {
-					continue
-				}
-
-				if item.IsError && toolResultContainsRejection(item.Content) {
-					denied[key]++
-				} else if !item.IsError {
-					approved[key]++
-				}
-				// is_error == true but not rejected = command failure, don't count as denied
-			}
+		if e.ID != "" {
+			s[e.ID] = true
 		}
+		out = append(out, e)
 	}
+	return out
+}
 
-	// Convert to stats
-	stats := make([]types.PermissionStats, 0, len(counts))
-	for key, count := range counts {
-		perm := ParsePermission(key)
-		stats = append(stats, types.PermissionStats{
-			Permission: perm,
-			Count:      count,
-			Approved:   approved[key],
-			Denied:     denied[key],
-			LastSeen:   lastSeen[key],
-		})
+// claim returns the stats of the session log at path, whose calls have the
+// tool_use IDs ids and the stats perms, without the calls counted in logs
+// claimed before it, and marks its calls counted. Only a log that repeats
+// calls is parsed again.
+func (s toolUseSet) claim(path string, sessionTime time.Time, perms []types.PermissionStats, ids []string) ([]types.PermissionStats, error) {
+	repeats := false
+	for _, id := range ids {
+		repeats = repeats || s[id]
+	}
+	if !repeats {
+		for _, id := range ids {
+			s[id] = true
+		}
+		return perms, nil
 	}
 
-	return stats, nil
+	events, _, err := ParseSessionEvents(path, sessionTime)
+	if err != nil {
+		return nil, err
+	}
+	return sessionStats(s.fresh(events)), nil
 }
 
 // toolResultContainsRejection checks if a tool_result content indicates user rejection.
-// Content can be a string or an array of objects with "text" fields.
-func toolResultContainsRejection(raw json.RawMessage) bool {
+// Content can be a string or an array of objects with "text" fields. Claude Code's
+// rejection messages are always recognized; extra markers are configurable via
+// SetRejectionMarkers.
+func toolResultContainsRejection(raw []byte) bool {
 	if len(raw) == 0 {
 		return false
 	}
@@ -304,7 +501,7 @@ func toolResultContainsRejection(raw json.RawMessage) bool {
 	// Try as plain string first
 	var s string
 	if err := json.Unmarshal(raw, &s); err == nil {
-		return strings.Contains(s, "rejected")
+		return rejectionMatcher.Matches(s)
 	}
 
 	// Try as array of content items
@@ -313,18 +510,20 @@ func toolResultContainsRejection(raw json.RawMessage) bool {
 	}
 	if err := json.Unmarshal(raw, &items); err == nil {
 		for _, item := range items {
-			if strings.Contains(item.Text, "rejected") {
+			if rejectionMatcher.Matches(item.Text) {
 				return true
 			}
 		}
 	}
 
 	// Fallback: check raw bytes
-	return strings.Contains(string(raw), "rejected")
+	return rejectionMatcher.Matches(string(raw))
 }
 
 // decodeProjectPath converts encoded project path back to readable form
-// e.g., "-Users-satchmo-code-myproject" -> "/Users/satchmo/code/myproject"
+// e.g., "-Users-satchmo-code-myproject" -> "/Users/satchmo/code/myproject".
+// The encoding is lossy, so "my-project" decodes as "my/project"; prefer
+// projectDirPath, which only falls back to this.
 func decodeProjectPath(encoded string) string {
 	if strings.HasPrefix(encoded, "-") {
 		return "/" + strings.ReplaceAll(encoded[1:], "-", "/")
@@ -0,0 +1,178 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// IndexProblem describes a project whose sessions-index.json doesn't match
+// the session logs in its directory
+type IndexProblem struct {
+	Project   string   // Decoded project path
+	Index     string   // Path of sessions-index.json
+	Missing   bool     // The directory has session logs but no index; they are read directly
+	Err       error    // The index could not be read or parsed
+	Unindexed []string // Session logs the index doesn't list
+	Dangling  []string // Indexed sessions whose log no longer exists
+	Stale     []string // Indexed sessions whose recorded mtime is out of date
+}
+
+// CheckSessionIndexes compares every project's sessions index with the
+// session logs beside it and returns the projects that disagree
+func CheckSessionIndexes(projectsDir string) ([]IndexProblem, error) {
+	entries, err := readDir(projectsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []IndexProblem
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(projectsDir, entry.Name())
+		logs, _ := glob(filepath.Join(dir, "*.jsonl"))
+		p := IndexProblem{
+			Project: projectDirPath(dir),
+			Index:   filepath.Join(dir, "sessions-index.json"),
+		}
+
+		sessions, err := loadSessionsIndex(p.Index)
+		switch {
+		case os.IsNotExist(err):
+			if len(logs) > 0 {
+				p.Missing = true
+				problems = append(problems, p)
+			}
+			continue
+		case err != nil:
+			p.Err = err
+			problems = append(problems, p)
+			continue
+		}
+
+		indexed := make(map[string]bool, len(sessions))
+		for _, s := range sessions {
+			path := s.FullPath
+			if path == "" {
+				path = filepath.Join(dir, s.SessionID+".jsonl")
+			}
+			indexed[path] = true
+
+			info, err := statFile(path)
+			switch {
+			case err != nil:
+				p.Dangling = append(p.Dangling, path)
+			case s.FileMtime != 0 && absDuration(info.ModTime().Sub(time.UnixMilli(s.FileMtime))) > time.Second:
+				p.Stale = append(p.Stale, path)
+			}
+		}
+		for _, log := range logs {
+			if !indexed[log] && !strings.HasPrefix(filepath.Base(log), "agent-") {
+				p.Unindexed = append(p.Unindexed, log)
+			}
+		}
+
+		if len(p.Unindexed) > 0 || len(p.Dangling) > 0 || len(p.Stale) > 0 {
+			problems = append(problems, p)
+		}
+	}
+	return problems, nil
+}
+
+// clockSkewTolerance is how far a timestamp may run ahead of the clock, or
+// behind an earlier line of the same log, before it counts as skew
+const clockSkewTolerance = 5 * time.Minute
+
+// SkewedLog is the first line of a session log with an implausible timestamp
+type SkewedLog struct {
+	Path string
+	Line int
+	Skew time.Duration // How far ahead of now, or behind the previous line
+}
+
+// ClockSkew summarizes session log timestamps that can't be right, which
+// usually means logs were synced from a machine with a wrong clock
+type ClockSkew struct {
+	Logs      int         // Session logs scanned
+	Future    []SkewedLog // Logs with timestamps ahead of now
+	Backwards []SkewedLog // Logs whose timestamps jump back in time
+}
+
+// CheckClockSkew scans the timestamps in every session log under
+// projectsDir for entries in the future or out of order
+func CheckClockSkew(projectsDir string, now time.Time) (ClockSkew, error) {
+	var report ClockSkew
+	logs, err := glob(filepath.Join(projectsDir, "*", "*.jsonl"))
+	if err != nil {
+		return report, err
+	}
+	sort.Strings(logs)
+
+	for _, path := range logs {
+		future, backwards, err := scanTimestamps(path, now)
+		if err != nil {
+			continue
+		}
+		report.Logs++
+		if future.Line > 0 {
+			report.Future = append(report.Future, future)
+		}
+		if backwards.Line > 0 {
+			report.Backwards = append(report.Backwards, backwards)
+		}
+	}
+	return report, nil
+}
+
+// scanTimestamps returns the first future and the first out-of-order
+// timestamp in a session log; a zero Line means none was found
+func scanTimestamps(path string, now time.Time) (future, backwards SkewedLog, err error) {
+	file, err := openFile(path)
+	if err != nil {
+		return future, backwards, err
+	}
+	defer file.Close()
+
+	var latest time.Time
+	lines := newLineReader(file)
+	for lines.Next() {
+		lineNum := lines.Line()
+		if !bytes.Contains(lines.Bytes(), []byte(`"timestamp"`)) {
+			continue
+		}
+		var entry struct {
+			Timestamp string `json:"timestamp"`
+		}
+		if json.Unmarshal(lines.Bytes(), &entry) != nil || entry.Timestamp == "" {
+			continue
+		}
+		ts, err := time.Parse(time.RFC3339, entry.Timestamp)
+		if err != nil {
+			continue
+		}
+
+		if future.Line == 0 && ts.Sub(now) > clockSkewTolerance {
+			future = SkewedLog{Path: path, Line: lineNum, Skew: ts.Sub(now)}
+		}
+		if backwards.Line == 0 && latest.Sub(ts) > clockSkewTolerance {
+			backwards = SkewedLog{Path: path, Line: lineNum, Skew: latest.Sub(ts)}
+		}
+		if ts.After(latest) {
+			latest = ts
+		}
+	}
+	return future, backwards, lines.Err()
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,96 @@
+package parser
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckSessionIndexes(t *testing.T) {
+	projectsDir := t.TempDir()
+	dir := filepath.Join(projectsDir, "-test-project")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	indexed := filepath.Join(dir, "indexed.jsonl")
+	unindexed := filepath.Join(dir, "unindexed.jsonl")
+	for _, path := range []string{indexed, unindexed} {
+		if err := os.WriteFile(path, []byte("{}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info, err := os.Stat(indexed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index, _ := json.Marshal(SessionsIndex{Version: 1, Entries: []SessionEntry{
+		{SessionID: "indexed", FullPath: indexed, FileMtime: info.ModTime().UnixMilli()},
+		{SessionID: "gone", FullPath: filepath.Join(dir, "gone.jsonl")},
+	}})
+	if err := os.WriteFile(filepath.Join(dir, "sessions-index.json"), index, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second project with logs but no index
+	if err := os.MkdirAll(filepath.Join(projectsDir, "-other"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projectsDir, "-other", "s.jsonl"), []byte("{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err := CheckSessionIndexes(projectsDir)
+	if err != nil {
+		t.Fatalf("CheckSessionIndexes: %v", err)
+	}
+	if len(problems) != 2 {
+		t.Fatalf("Expected 2 problems, got %+v", problems)
+	}
+	byProject := map[string]IndexProblem{}
+	for _, p := range problems {
+		byProject[p.Project] = p
+	}
+	if !byProject["/other"].Missing {
+		t.Errorf("Expected /other to be missing its index, got %+v", byProject["/other"])
+	}
+	p := byProject["/test/project"]
+	if len(p.Unindexed) != 1 || p.Unindexed[0] != unindexed {
+		t.Errorf("Expected %s to be unindexed, got %v", unindexed, p.Unindexed)
+	}
+	if len(p.Dangling) != 1 || len(p.Stale) != 0 {
+		t.Errorf("Expected 1 dangling and no stale entries, got %v and %v", p.Dangling, p.Stale)
+	}
+}
+
+func TestCheckClockSkew(t *testing.T) {
+	projectsDir := t.TempDir()
+	dir := filepath.Join(projectsDir, "-test-project")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := `{"type":"user","timestamp":"2026-01-01T10:00:00Z"}
+{"type":"assistant","timestamp":"2026-01-01T09:00:00Z"}
+{"type":"assistant","timestamp":"2026-01-03T00:00:00Z"}
+`
+	if err := os.WriteFile(filepath.Join(dir, "s.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	skew, err := CheckClockSkew(projectsDir, now)
+	if err != nil {
+		t.Fatalf("CheckClockSkew: %v", err)
+	}
+	if skew.Logs != 1 {
+		t.Errorf("Expected 1 log scanned, got %d", skew.Logs)
+	}
+	if len(skew.Backwards) != 1 || skew.Backwards[0].Line != 2 || skew.Backwards[0].Skew != time.Hour {
+		t.Errorf("Expected line 2 to jump back an hour, got %+v", skew.Backwards)
+	}
+	if len(skew.Future) != 1 || skew.Future[0].Line != 3 || skew.Future[0].Skew != 24*time.Hour {
+		t.Errorf("Expected line 3 to be a day in the future, got %+v", skew.Future)
+	}
+}
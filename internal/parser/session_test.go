@@ -1,7 +1,15 @@
 package parser
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/types"
 )
 
 func TestLoadAllPermissionStatsFrom(t *testing.T) {
@@ -67,6 +75,93 @@ func TestLoadAllPermissionStatsFrom(t *testing.T) {
 	}
 }
 
+func TestCachedLoaderMatchesUncachedOrder(t *testing.T) {
+	t.Setenv("HOME", t.TempDir()) // Keep the cache out of the real ~/.claude
+
+	want, err := LoadAllPermissionStatsFrom("../../testdata/projects")
+	if err != nil {
+		t.Fatalf("LoadAllPermissionStatsFrom: %v", err)
+	}
+
+	// Once to build the cache, once to read from it
+	for _, pass := range []string{"cold", "warm"} {
+		got, err := loadPermissionStatsWithCache("../../testdata/projects", nil)
+		if err != nil {
+			t.Fatalf("%s cache: %v", pass, err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("%s cache: expected %d permissions, got %d", pass, len(want), len(got))
+		}
+		for i := range want {
+			if got[i].Permission.Raw != want[i].Permission.Raw || got[i].Count != want[i].Count {
+				t.Errorf("%s cache: position %d is %s (%d), expected %s (%d)",
+					pass, i, got[i].Permission.Raw, got[i].Count, want[i].Permission.Raw, want[i].Count)
+			}
+		}
+	}
+
+	for i := 1; i < len(want); i++ {
+		a, b := want[i-1], want[i]
+		if a.Count < b.Count || (a.Count == b.Count && a.Permission.Raw > b.Permission.Raw) {
+			t.Errorf("Expected count then name order, got %s (%d) before %s (%d)", a.Permission.Raw, a.Count, b.Permission.Raw, b.Count)
+		}
+	}
+}
+
+func TestResumedSessionCountsRepeatedCallsOnce(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	projectsDir := t.TempDir()
+	dir := filepath.Join(projectsDir, "-test-project")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	use := func(id, command string) string {
+		return `{"type":"assistant","timestamp":"2026-10-10T10:00:00Z","message":{"content":[{"type":"tool_use","id":"` + id + `","name":"Bash","input":{"command":"` + command + `"}}]}}` + "\n"
+	}
+	result := func(id string) string {
+		return `{"type":"user","timestamp":"2026-10-10T10:00:01Z","message":{"content":[{"type":"tool_result","tool_use_id":"` + id + `","content":"ok"}]}}` + "\n"
+	}
+	// The resumed session's log starts with a summary and the calls of the
+	// one it continues; one of them is logged twice around a compaction
+	first := use("t1", "npm test") + result("t1") + use("t2", "npm test") + result("t2")
+	resumed := `{"type":"summary","summary":"Ran the tests","leafUuid":"u2"}` + "\n" + first + use("t2", "npm test") + use("t3", "npm test") + result("t3")
+	for name, content := range map[string]string{"session-001.jsonl": first, "session-002.jsonl": resumed} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check := func(name string, stats []types.PermissionStats) {
+		t.Helper()
+		if len(stats) != 1 || stats[0].Count != 3 || stats[0].Approved != 3 {
+			t.Errorf("%s: expected 3 approved calls, got %+v", name, stats)
+		}
+	}
+	stats, err := LoadAllPermissionStatsFrom(projectsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("uncached", stats)
+	for _, pass := range []string{"cold cache", "warm cache"} {
+		stats, err := loadPermissionStatsWithCache(projectsDir, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(pass, stats)
+	}
+
+	var logs []LogEvents
+	for _, name := range []string{"session-001.jsonl", "session-002.jsonl"} {
+		events, _, err := ParseSessionEvents(filepath.Join(dir, name), time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		logs = append(logs, LogEvents{Path: name, Project: "/test/project", Events: events})
+	}
+	check("events", AggregatePermissionStats(logs))
+}
+
 func TestDecodeProjectPath(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -84,3 +179,341 @@ func TestDecodeProjectPath(t *testing.T) {
 		}
 	}
 }
+
+func TestParseSessionLogReportsMalformedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	content := `{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/a"}}]}}
+{"type":"assistant","message":{"content":[{"type":"tool_use", truncated
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, _, warns, err := parseSessionLog(path, time.Time{})
+	if err != nil {
+		t.Fatalf("parseSessionLog: %v", err)
+	}
+	if len(stats) != 1 {
+		t.Errorf("Expected 1 permission from the valid line, got %d", len(stats))
+	}
+	if len(warns) != 1 {
+		t.Fatalf("Expected 1 warning, got %d: %v", len(warns), warns)
+	}
+	if warns[0].File != path || warns[0].Line != 2 {
+		t.Errorf("Expected warning at %s:2, got %s", path, warns[0])
+	}
+}
+
+func TestParseSessionEventsSharesMessageTokens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	// One message logged as a line per tool_use, each repeating its usage,
+	// then a message of its own
+	usage := `"usage":{"input_tokens":10,"cache_read_input_tokens":980,"output_tokens":11}`
+	content := `{"type":"assistant","message":{"id":"m1","content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/a"}}],` + usage + `}}
+{"type":"assistant","message":{"id":"m1","content":[{"type":"tool_use","id":"t2","name":"Read","input":{"file_path":"/b"}}],` + usage + `}}
+{"type":"assistant","message":{"id":"m2","content":[{"type":"tool_use","id":"t3","name":"Glob","input":{"pattern":"*"}}],"usage":{"input_tokens":5,"output_tokens":7}}}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	events, _, err := ParseSessionEvents(path, time.Time{})
+	if err != nil {
+		t.Fatalf("ParseSessionEvents: %v", err)
+	}
+	var got []int
+	for _, e := range events {
+		got = append(got, e.Tokens)
+	}
+	if want := []int{501, 500, 12}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Tokens = %v, want %v", got, want)
+	}
+
+	stats := sessionStats(events)
+	if len(stats) != 2 || stats[0].Tokens != 1001 {
+		t.Errorf("Expected Read to total the message's 1001 tokens once, got %+v", stats)
+	}
+}
+
+func TestSlowestCalls(t *testing.T) {
+	dir := t.TempDir()
+	session := filepath.Join(dir, "session.jsonl")
+	content := `{"type":"assistant","timestamp":"2026-01-02T10:00:00.000Z","message":{"content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"npm test"}}]}}
+{"type":"user","timestamp":"2026-01-02T10:01:30.500Z","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"ok"}]}}
+{"type":"assistant","timestamp":"2026-01-02T10:02:00.000Z","message":{"content":[{"type":"tool_use","id":"t2","name":"Bash","input":{"command":"npm run build"}}]}}
+{"type":"user","timestamp":"2026-01-02T10:02:04.000Z","message":{"content":[{"type":"tool_result","tool_use_id":"t2","content":"ok"}]}}
+{"type":"assistant","timestamp":"2026-01-02T10:03:00.000Z","message":{"content":[{"type":"tool_use","id":"t3","name":"Bash","input":{"command":"npm publish"}}]}}
+{"type":"user","timestamp":"2026-01-02T10:09:00.000Z","message":{"content":[{"type":"tool_result","tool_use_id":"t3","is_error":true,"content":"The user doesn't want to proceed with this tool use."}]}}
+`
+	if err := os.WriteFile(session, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stats, _, _, err := parseSessionLog(session, time.Time{})
+	if err != nil {
+		t.Fatalf("parseSessionLog: %v", err)
+	}
+	var slowest []types.SlowCall
+	for _, s := range stats {
+		slowest = types.AddSlowCalls(slowest, s.Slowest...)
+	}
+	// The denied call waited on the user, so it isn't timed
+	var got []string
+	for _, c := range slowest {
+		got = append(got, fmt.Sprintf("%s %s", c.Sample, c.Duration))
+	}
+	if want := []string{"npm test 1m30.5s", "npm run build 4s"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Slowest = %v, want %v", got, want)
+	}
+
+	// Agent logs time their calls by the tool_results in user messages
+	agent := filepath.Join(dir, "agent-a1.jsonl")
+	if err := os.WriteFile(agent, []byte(content[:strings.Index(content, `{"type":"assistant","timestamp":"2026-01-02T10:02`)]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	events, _, err := ParseAgentEvents(agent)
+	if err != nil {
+		t.Fatalf("ParseAgentEvents: %v", err)
+	}
+	if d, ok := events[0].Duration(); len(events) != 1 || !ok || d != 90500*time.Millisecond {
+		t.Errorf("Expected the agent's call to take 1m30.5s, got %+v", events)
+	}
+}
+
+func TestParseSessionEventsFlagsBashOutsideProject(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	content := `{"type":"assistant","cwd":"/work/app","message":{"content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"make"}}]}}
+{"type":"assistant","cwd":"/work/app/web","message":{"content":[{"type":"tool_use","id":"t2","name":"Bash","input":{"command":"make"}}]}}
+{"type":"assistant","cwd":"/work/application","message":{"content":[{"type":"tool_use","id":"t3","name":"Bash","input":{"command":"make"}}]}}
+{"type":"assistant","cwd":"/","message":{"content":[{"type":"tool_use","id":"t4","name":"Read","input":{"file_path":"/etc/hosts"}}]}}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	events, _, err := ParseSessionEvents(path, time.Time{})
+	if err != nil {
+		t.Fatalf("ParseSessionEvents: %v", err)
+	}
+	var got []string
+	for _, e := range events {
+		got = append(got, e.Cwd)
+	}
+	// Only Bash runs in the working directory; a sibling with the
+	// project's name as a prefix is still outside it
+	if want := []string{"", "", "/work/application", ""}; fmt.Sprintf("%q", got) != fmt.Sprintf("%q", want) {
+		t.Errorf("Cwd = %q, want %q", got, want)
+	}
+
+	stats := sessionStats(events)
+	if stats[0].Outside != 1 || fmt.Sprint(stats[0].OutsideDirs) != "[/work/application]" {
+		t.Errorf("Expected one call outside the project, got %d in %v", stats[0].Outside, stats[0].OutsideDirs)
+	}
+}
+
+func TestParseSessionEventsRecordsWritesOutsideProject(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	content := `{"type":"assistant","cwd":"/work/app","message":{"content":[{"type":"tool_use","id":"t1","name":"Edit","input":{"file_path":"/work/app/main.go"}}]}}
+{"type":"assistant","cwd":"/work/app","message":{"content":[{"type":"tool_use","id":"t2","name":"Edit","input":{"file_path":"/home/me/.zshrc"}}]}}
+{"type":"assistant","cwd":"/work/app","message":{"content":[{"type":"tool_use","id":"t3","name":"Edit","input":{"file_path":"/home/me/.zshrc"}}]}}
+{"type":"assistant","cwd":"/work/app","message":{"content":[{"type":"tool_use","id":"t4","name":"Read","input":{"file_path":"/etc/hosts"}}]}}
+{"type":"assistant","cwd":"/work/app","message":{"content":[{"type":"tool_use","id":"t5","name":"NotebookEdit","input":{"notebook_path":"/tmp/scratch.ipynb"}}]}}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, _, _, err := parseSessionLog(path, time.Time{})
+	if err != nil {
+		t.Fatalf("parseSessionLog: %v", err)
+	}
+	agg := make(permissionAggregator)
+	agg.add(stats, path, "/work/app")
+	var got []string
+	for _, s := range agg.stats() {
+		for _, w := range s.OutsideWrites {
+			got = append(got, fmt.Sprintf("%s %s %s×%d", s.Permission.Raw, w.Project, w.File, w.Count))
+		}
+	}
+	// Reads never modify anything
+	want := []string{"Edit /work/app /home/me/.zshrc×2", "NotebookEdit /work/app /tmp/scratch.ipynb×1"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("OutsideWrites = %v, want %v", got, want)
+	}
+}
+
+func TestParseSessionEventsTracksPermissionMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	content := `{"type":"user","permissionMode":"plan","message":{"role":"user","content":"plan the refactor"}}
+{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"ExitPlanMode","input":{"plan":"..."}}]}}
+{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"User has approved your plan."}]}}
+{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t2","name":"Edit","input":{"file_path":"/work/app/a.go"}}]}}
+{"type":"user","permissionMode":"default","message":{"content":[{"type":"tool_result","tool_use_id":"t2","content":"ok"}]}}
+{"type":"user","permissionMode":"acceptEdits","message":{"role":"user","content":"go ahead"}}
+{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t3","name":"Edit","input":{"file_path":"/work/app/b.go"}}]}}
+{"type":"user","permissionMode":"acceptEdits","message":{"content":[{"type":"tool_result","tool_use_id":"t3","content":"ok"}]}}
+{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t4","name":"Bash","input":{"command":"go test ./..."}}]}}
+{"type":"user","permissionMode":"acceptEdits","message":{"content":[{"type":"tool_result","tool_use_id":"t4","content":"ok"}]}}
+{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t5","name":"Edit","input":{"file_path":"/etc/hosts"}}]}}
+{"type":"system","subtype":"permission_prompt","toolUseID":"t5","decision":"allow"}
+{"type":"user","permissionMode":"acceptEdits","message":{"content":[{"type":"tool_result","tool_use_id":"t5","content":"ok"}]}}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	events, _, err := ParseSessionEvents(path, time.Time{})
+	if err != nil {
+		t.Fatalf("ParseSessionEvents: %v", err)
+	}
+	var modes []string
+	for _, e := range events {
+		modes = append(modes, e.Mode)
+	}
+	// Approving the plan leaves plan mode before the next entry says so
+	if want := []string{"plan", "default", "acceptEdits", "acceptEdits", "acceptEdits"}; fmt.Sprint(modes) != fmt.Sprint(want) {
+		t.Errorf("modes = %v, want %v", modes, want)
+	}
+
+	// Only the edit acceptEdits ran unprompted is auto-approved: Bash still
+	// prompts, and so did the edit a prompt record was logged for
+	got := make(map[string]string)
+	for _, s := range sessionStats(events) {
+		got[s.Permission.Raw] = fmt.Sprintf("%d approved, %d auto", s.Approved, s.AutoApproved)
+	}
+	want := map[string]string{
+		"ExitPlanMode":    "1 approved, 0 auto",
+		"Edit":            "2 approved, 1 auto",
+		"Bash(go test:*)": "1 approved, 0 auto",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("stats = %v, want %v", got, want)
+	}
+}
+
+func TestLoadAllPermissionStatsWithoutIndex(t *testing.T) {
+	projectsDir := t.TempDir()
+	dir := filepath.Join(projectsDir, "-test-project")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := `{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/a"}}]}}
+`
+	logPath := filepath.Join(dir, "session-001.jsonl")
+	if err := os.WriteFile(logPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(logPath, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	// Agent logs are not sessions
+	if err := os.WriteFile(filepath.Join(dir, "agent-abc.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := LoadAllPermissionStatsFrom(projectsDir)
+	if err != nil {
+		t.Fatalf("LoadAllPermissionStatsFrom: %v", err)
+	}
+	if len(stats) != 1 || stats[0].Count != 1 {
+		t.Fatalf("Expected 1 Read call from the unindexed session, got %+v", stats)
+	}
+	if !stats[0].LastSeen.Equal(mtime) {
+		t.Errorf("Expected the session time to come from the file mtime %v, got %v", mtime, stats[0].LastSeen)
+	}
+}
+
+func TestParseSessionLogHandlesLongLines(t *testing.T) {
+	defer func(limit int) { maxLineSize = limit }(maxLineSize)
+	maxLineSize = 4 << 20
+
+	// A 2MB Write is read in full; a 5MB one is skipped without dropping
+	// the lines after it
+	write := func(size int) string {
+		content := strings.Repeat("x", size)
+		return `{"type":"assistant","message":{"content":[{"type":"tool_use","id":"w","name":"Write","input":{"file_path":"/big","content":"` + content + `"}}]}}`
+	}
+	read := `{"type":"assistant","message":{"content":[{"type":"tool_use","id":"r","name":"Read","input":{"file_path":"/a"}}]}}`
+	content := write(2<<20) + "\n" + write(5<<20) + "\n" + read + "\n"
+
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, _, warns, err := parseSessionLog(path, time.Time{})
+	if err != nil {
+		t.Fatalf("parseSessionLog: %v", err)
+	}
+	counts := map[string]int{}
+	for _, s := range stats {
+		counts[s.Permission.Type] += s.Count
+	}
+	if counts["Write"] != 1 || counts["Read"] != 1 {
+		t.Errorf("Expected 1 Write and 1 Read, got %v", counts)
+	}
+	if len(warns) != 1 || warns[0].Line != 2 || !strings.Contains(warns[0].Reason, "too long") {
+		t.Errorf("Expected a too-long warning for line 2, got %v", warns)
+	}
+}
+
+// writeBenchmarkLog writes a session log shaped like a real one: chat
+// lines without tools, Bash calls with results, and large Writes
+func writeBenchmarkLog(b *testing.B) string {
+	b.Helper()
+	var sb strings.Builder
+	output := strings.Repeat("output line\\n", 500)
+	contents := strings.Repeat("package main\\n", 4000)
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&sb, `{"type":"user","timestamp":"2026-01-01T10:00:00Z","message":{"role":"user","content":"please run the tests %d"}}`+"\n", i)
+		fmt.Fprintf(&sb, `{"type":"assistant","timestamp":"2026-01-01T10:00:01Z","message":{"role":"assistant","content":[{"type":"text","text":"Running"},{"type":"tool_use","id":"b%d","name":"Bash","input":{"command":"go test ./... -run Test%d"}}]}}`+"\n", i, i)
+		fmt.Fprintf(&sb, `{"type":"user","timestamp":"2026-01-01T10:00:02Z","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"b%d","is_error":%t,"content":"%s"}]}}`+"\n", i, i%10 == 0, output)
+		fmt.Fprintf(&sb, `{"type":"assistant","timestamp":"2026-01-01T10:00:03Z","message":{"role":"assistant","content":[{"type":"tool_use","id":"w%d","name":"Write","input":{"file_path":"/src/f%d.go","content":"%s"}}]}}`+"\n", i, i, contents)
+	}
+	path := filepath.Join(b.TempDir(), "session.jsonl")
+	if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+		b.Fatal(err)
+	}
+	return path
+}
+
+func BenchmarkParseSessionLog(b *testing.B) {
+	path := writeBenchmarkLog(b)
+	info, err := os.Stat(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(info.Size())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, _, err := parseSessionLog(path, time.Time{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestContextToolInputsAreCounted(t *testing.T) {
+	events := []ToolEvent{
+		{Permission: "WebSearch", Sample: "go 1.24 release notes"},
+		{Permission: "WebSearch", Sample: "go 1.24 release notes"},
+		{Permission: "WebSearch", Sample: "bubbletea mouse events"},
+		{Permission: "Bash(ls:*)", Sample: "ls -la"},
+	}
+	stats := sessionStats(events)
+	if want := map[string]int{"go 1.24 release notes": 2, "bubbletea mouse events": 1}; !reflect.DeepEqual(stats[0].Inputs, want) {
+		t.Errorf("WebSearch inputs = %v, want %v", stats[0].Inputs, want)
+	}
+	if stats[1].Inputs != nil {
+		t.Errorf("Bash inputs = %v, want none as its rules have scopes", stats[1].Inputs)
+	}
+
+	// Past twice the cap, the least used inputs are dropped
+	inputs := map[string]int{"kept": 100}
+	for i := range 2 * maxInputs {
+		inputs = addInputs(inputs, map[string]int{fmt.Sprintf("pattern %d", i): 1})
+	}
+	if len(inputs) != maxInputs || inputs["kept"] != 100 {
+		t.Errorf("capped inputs have %d entries, kept = %d", len(inputs), inputs["kept"])
+	}
+}
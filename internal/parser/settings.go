@@ -1,7 +1,9 @@
 package parser
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -14,14 +16,42 @@ import (
 const (
 	lockAcquireTimeout = 5 * time.Second
 	lockPollInterval   = 50 * time.Millisecond
-	staleLockMaxAge    = 30 * time.Second
 )
 
+// WriteHook is called after a settings file is written with its contents
+// before and after the write (nil if the file did not exist) and a short
+// description of the change, e.g. "allow Bash(git:*)"
+type WriteHook func(path string, before, after []byte, action string) error
+
+// writeHook observes settings writes, e.g. to snapshot them; nil if unset
+var writeHook WriteHook
+
+// SetWriteHook registers a hook run after every settings file write. A nil
+// hook disables it.
+func SetWriteHook(h WriteHook) {
+	writeHook = h
+}
+
+// runWriteHook reports a completed write to the hook. Hook failures are
+// logged, not returned: the settings change itself succeeded.
+func runWriteHook(path string, before, after []byte, action string) {
+	if writeHook == nil {
+		return
+	}
+	if err := writeHook(path, before, after, action); err != nil {
+		logger.Warn("settings write hook failed", "path", path, "err", err)
+	}
+}
+
 type settingsDocument struct {
 	root        map[string]json.RawMessage
 	permissions map[string]json.RawMessage
 	allow       []string
 	deny        []string
+	ask         []string // Read only; marshalIndent leaves the raw list as is
+
+	defaultMode   string // permissions.defaultMode; read only
+	disableBypass bool   // permissions.disableBypassPermissionsMode is "disable"; read only
 }
 
 func newSettingsDocument() *settingsDocument {
@@ -38,7 +68,7 @@ func parseSettingsDocument(data []byte) (*settingsDocument, error) {
 	}
 
 	if err := json.Unmarshal(data, &doc.root); err != nil {
-		return nil, fmt.Errorf("parse settings document: %w", err)
+		return nil, fmt.Errorf("parse settings document: %w", withJSONPosition(data, err))
 	}
 	if doc.root == nil {
 		doc.root = make(map[string]json.RawMessage)
@@ -65,9 +95,48 @@ func parseSettingsDocument(data []byte) (*settingsDocument, error) {
 		}
 	}
 
+	if rawAsk, ok := doc.permissions["ask"]; ok && len(rawAsk) > 0 {
+		if err := json.Unmarshal(rawAsk, &doc.ask); err != nil {
+			return nil, fmt.Errorf("parse permissions.ask: %w", err)
+		}
+	}
+
+	// The mode settings don't affect the rules, so a mistyped one is ignored
+	// rather than making the file unreadable
+	if raw, ok := doc.permissions["defaultMode"]; ok {
+		_ = json.Unmarshal(raw, &doc.defaultMode)
+	}
+	if raw, ok := doc.permissions["disableBypassPermissionsMode"]; ok {
+		var disable string
+		_ = json.Unmarshal(raw, &disable)
+		doc.disableBypass = disable == "disable"
+	}
+
 	return doc, nil
 }
 
+// withJSONPosition prefixes a JSON syntax or type error with the line and
+// column it occurred at in data
+func withJSONPosition(data []byte, err error) error {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		return err
+	}
+
+	// Offset counts the bytes read, including the one that failed
+	before := data[:max(min(offset, int64(len(data)))-1, 0)]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := len(before) - bytes.LastIndexByte(before, '\n')
+	return fmt.Errorf("line %d, column %d: %w", line, col, err)
+}
+
 func (d *settingsDocument) marshalIndent() ([]byte, error) {
 	if d.root == nil {
 		d.root = make(map[string]json.RawMessage)
@@ -100,7 +169,15 @@ func (d *settingsDocument) marshalIndent() ([]byte, error) {
 }
 
 func (d *settingsDocument) hasPermission(permission string) bool {
-	for _, existing := range d.allow {
+	return containsRule(d.allow, permission)
+}
+
+func (d *settingsDocument) hasDenial(permission string) bool {
+	return containsRule(d.deny, permission)
+}
+
+func containsRule(rules []string, permission string) bool {
+	for _, existing := range rules {
 		if existing == permission {
 			return true
 		}
@@ -108,33 +185,90 @@ func (d *settingsDocument) hasPermission(permission string) bool {
 	return false
 }
 
+// LockedError is the error of a write whose file another perms process,
+// e.g. a second TUI or perms watch, held the lock of for too long
+type LockedError struct {
+	Path string // The file being written
+	PID  int    // The process holding its lock; 0 if unknown
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s is writing %s; try again once it's done", e.Holder(), e.Path)
+}
+
+// Holder describes the process holding the lock, e.g. "another perms
+// instance (pid 4242)"
+func (e *LockedError) Holder() string {
+	if e.PID > 0 {
+		return fmt.Sprintf("another perms instance (pid %d)", e.PID)
+	}
+	return "another perms instance"
+}
+
+// lockHolder returns the process the contents of a lock file name, or 0 if
+// they name none
+func lockHolder(lock []byte) int {
+	var pid int
+	fmt.Sscanf(string(lock), "pid=%d", &pid)
+	return pid
+}
+
+// acquireFileLock takes the lock file lockPath, waiting for another process
+// holding it to release it. The lock is an OS file lock (flock on Unix), so
+// the OS releases it when its process exits, however that happens, and two
+// processes can't both take it. The file itself stays, naming the process
+// that last held it for the error. It returns a *LockedError if the lock is
+// still held after lockAcquireTimeout.
 func acquireFileLock(lockPath string) (func(), error) {
-	deadline := time.Now().Add(lockAcquireTimeout)
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("acquire lock %s: %w", lockPath, err)
+	}
 
+	deadline := time.Now().Add(lockAcquireTimeout)
 	for {
-		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
-		if err == nil {
-			_, _ = fmt.Fprintf(f, "pid=%d time=%s\n", os.Getpid(), time.Now().UTC().Format(time.RFC3339Nano))
-			_ = f.Close()
-			return func() {
-				_ = os.Remove(lockPath)
-			}, nil
-		}
-
-		if !os.IsExist(err) {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
 			return nil, fmt.Errorf("acquire lock %s: %w", lockPath, err)
 		}
-
-		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > staleLockMaxAge {
-			_ = os.Remove(lockPath)
-			continue
+		if locked {
+			break
 		}
-
 		if time.Now().After(deadline) {
-			return nil, fmt.Errorf("timed out waiting for file lock: %s", lockPath)
+			holder, _ := os.ReadFile(lockPath)
+			f.Close()
+			return nil, &LockedError{Path: strings.TrimSuffix(lockPath, ".lock"), PID: lockHolder(holder)}
 		}
 		time.Sleep(lockPollInterval)
 	}
+
+	if f.Truncate(0) == nil {
+		_, _ = f.WriteAt([]byte(fmt.Sprintf("pid=%d time=%s\n", os.Getpid(), time.Now().UTC().Format(time.RFC3339Nano))), 0)
+	}
+	return func() {
+		// Closing the file releases the lock
+		_ = unlockFile(f)
+		_ = f.Close()
+	}, nil
+}
+
+// TryLockFile takes an exclusive OS lock on the file at path, creating it,
+// without waiting, and reports false if another process holds it. The lock
+// is held until release is called or the process exits.
+func TryLockFile(path string) (release func(), ok bool, err error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, false, err
+	}
+	if ok, err = tryLockFile(f); !ok {
+		f.Close()
+		return nil, false, err
+	}
+	return func() {
+		_ = unlockFile(f)
+		_ = f.Close()
+	}, true, nil
 }
 
 func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
@@ -179,21 +313,130 @@ func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
 	return nil
 }
 
+// RestoreSettingsFile replaces a settings file with earlier contents, or
+// removes it if data is nil (the file did not exist then)
+func RestoreSettingsFile(path string, data []byte, action string) error {
+	releaseLock, err := acquireFileLock(path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer releaseLock()
+
+	before, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		before = nil
+	}
+
+	if data == nil {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove settings: %w", err)
+		}
+	} else if err := writeFileAtomic(path, data, 0644); err != nil {
+		return err
+	}
+
+	runWriteHook(path, before, data, action)
+	return nil
+}
+
+// UserSettingsPath returns the path to ~/.claude/settings.local.json
+func UserSettingsPath() string {
+	return filepath.Join(claudeDir(), "settings.local.json")
+}
+
+// ProjectSettingsPath returns the path to <project>/.claude/settings.local.json
+func ProjectSettingsPath(projectPath string) string {
+	return filepath.Join(projectPath, ".claude", "settings.local.json")
+}
+
 // LoadUserSettings loads permissions from ~/.claude/settings.local.json
 func LoadUserSettings() ([]string, error) {
-	path := filepath.Join(claudeDir(), "settings.local.json")
-	return loadSettingsPermissions(path)
+	return loadSettingsPermissions(UserSettingsPath())
 }
 
 // LoadProjectSettings loads permissions from .claude/settings.local.json in project
 func LoadProjectSettings(projectPath string) ([]string, error) {
-	path := filepath.Join(projectPath, ".claude", "settings.local.json")
-	return loadSettingsPermissions(path)
+	return loadSettingsPermissions(ProjectSettingsPath(projectPath))
+}
+
+// LoadDiscoveredProjectSettings loads .claude/settings.local.json for every
+// project under the projects directory whose decoded path exists on disk,
+// plus any extra project paths (such as the working directory), keyed by
+// project path. Projects grouped with SetProjectAliases are keyed by the
+// path they count as. Existing projects without a settings file map to nil rules.
+// Unreadable settings are returned as warnings.
+func LoadDiscoveredProjectSettings(extra ...string) (map[string][]string, []Warning) {
+	var paths []string
+	if entries, err := readDir(ProjectsDir()); err == nil {
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			// Projects without a recorded cwd are decoded lossily (a "-"
+			// may have been a "/"), so only trust paths that resolve to a
+			// real directory
+			projectPath := CanonicalProject(projectDirPath(filepath.Join(ProjectsDir(), entry.Name())))
+			if info, err := statFile(projectPath); err == nil && info.IsDir() {
+				paths = append(paths, projectPath)
+			}
+		}
+	}
+	for _, projectPath := range extra {
+		paths = append(paths, CanonicalProject(projectPath))
+	}
+
+	settings := make(map[string][]string)
+	var warns []Warning
+	for _, projectPath := range paths {
+		if _, done := settings[projectPath]; done {
+			continue
+		}
+		rules, err := LoadProjectSettings(projectPath)
+		if err != nil {
+			warns = append(warns, Warning{File: ProjectSettingsPath(projectPath), Reason: "unreadable settings: " + err.Error()})
+		}
+		settings[projectPath] = rules
+	}
+	return settings, warns
+}
+
+// ValidateSettingsFile parses a settings file and returns the number of allow
+// and deny entries. A missing file is reported as os.ErrNotExist.
+func ValidateSettingsFile(path string) (allow, deny int, err error) {
+	data, err := readFile(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	doc, err := parseSettingsDocument(data)
+	if err != nil {
+		return 0, 0, err
+	}
+	return len(doc.allow), len(doc.deny), nil
+}
+
+// ReadSettingsRules returns a settings file's allow and deny rules. A
+// missing file has no rules.
+func ReadSettingsRules(path string) (allow, deny []string, err error) {
+	data, err := readFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	doc, err := parseSettingsDocument(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return doc.allow, doc.deny, nil
 }
 
 // loadSettingsPermissions reads a settings file and returns allowed permissions
 func loadSettingsPermissions(path string) ([]string, error) {
-	data, err := os.ReadFile(path)
+	data, err := readFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, nil
@@ -212,24 +455,90 @@ func loadSettingsPermissions(path string) ([]string, error) {
 type ApplyResult struct {
 	FilePath   string
 	Permission string
-	LineNumber int  // Line where the permission was added
-	WasNew     bool // False if already existed (idempotent)
+	LineNumber int  // Line where the permission was added, or for a removal, where it was
+	WasNew     bool // False if nothing changed: already existed, or for a removal, already gone
+	DryRun     bool // Nothing was written; the result describes what would change
+
+	// For a batch, Permission lists every permission and LineNumber is the
+	// first line added
+	LineNumbers []int // Line of each permission the batch added
+	Existing    int   // How many of the batch's permissions were already there
 }
 
 // WritePermissionToUserSettings adds a permission to user settings
 func WritePermissionToUserSettings(permission string) (*ApplyResult, error) {
 	path := filepath.Join(claudeDir(), "settings.local.json")
-	return writePermissionToSettings(path, permission)
+	return writePermissionToSettings(path, permission, false)
+}
+
+// WriteDenyToUserSettings adds a permission to the user settings deny list
+func WriteDenyToUserSettings(permission string) (*ApplyResult, error) {
+	path := filepath.Join(claudeDir(), "settings.local.json")
+	return writePermissionToSettings(path, permission, true)
 }
 
 // WritePermissionToProjectSettings adds a permission to project settings
 func WritePermissionToProjectSettings(projectPath, permission string) (*ApplyResult, error) {
 	path := filepath.Join(projectPath, ".claude", "settings.local.json")
-	return writePermissionToSettings(path, permission)
+	return writePermissionToSettings(path, permission, false)
 }
 
-// writePermissionToSettings reads, merges, and writes back settings
-func writePermissionToSettings(path, permission string) (*ApplyResult, error) {
+// WritePermissionsToUserSettings adds permissions to user settings in one
+// write, with one result for the batch
+func WritePermissionsToUserSettings(permissions []string) (*ApplyResult, error) {
+	path := UserSettingsPath()
+	results, err := WritePermissionsToSettingsFile(path, permissions)
+	if err != nil {
+		return nil, err
+	}
+	return BatchResult(path, results), nil
+}
+
+// WritePermissionsToProjectSettings adds permissions to project settings in
+// one write, with one result for the batch
+func WritePermissionsToProjectSettings(projectPath string, permissions []string) (*ApplyResult, error) {
+	path := ProjectSettingsPath(projectPath)
+	results, err := WritePermissionsToSettingsFile(path, permissions)
+	if err != nil {
+		return nil, err
+	}
+	return BatchResult(path, results), nil
+}
+
+// WritePermissionToSettingsFile adds a permission to the allow list, or if
+// deny is set the deny list, of the settings file at path
+func WritePermissionToSettingsFile(path, permission string, deny bool) (*ApplyResult, error) {
+	return writePermissionToSettings(path, permission, deny)
+}
+
+// PlanPermissionWrite reports what WritePermissionToSettingsFile would do,
+// without writing anything. The result is marked DryRun.
+func PlanPermissionWrite(path, permission string, deny bool) (*ApplyResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	output, wasNew, err := mergePermission(data, permission, deny)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ApplyResult{FilePath: path, Permission: permission, WasNew: wasNew, DryRun: true}
+	if wasNew {
+		result.LineNumber = findPermissionLine(output, permission)
+	}
+	return result, nil
+}
+
+// writePermissionToSettings reads, merges, and writes back settings, adding
+// permission to the allow list or, if deny is set, the deny list
+func writePermissionToSettings(path, permission string, deny bool) (*ApplyResult, error) {
+	// The lock file lives beside the settings, so e.g. a project without a
+	// .claude directory needs one first
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("create settings directory: %w", err)
+	}
+
 	lockPath := path + ".lock"
 	releaseLock, err := acquireFileLock(lockPath)
 	if err != nil {
@@ -245,13 +554,12 @@ func writePermissionToSettings(path, permission string) (*ApplyResult, error) {
 		data = nil
 	}
 
-	doc, err := parseSettingsDocument(data)
+	output, wasNew, err := mergePermission(data, permission, deny)
 	if err != nil {
-		return nil, fmt.Errorf("parse settings: %w", err)
+		return nil, err
 	}
-
-	// Check if already exists (idempotent)
-	if doc.hasPermission(permission) {
+	// Already there (idempotent)
+	if !wasNew {
 		return &ApplyResult{
 			FilePath:   path,
 			Permission: permission,
@@ -259,18 +567,14 @@ func writePermissionToSettings(path, permission string) (*ApplyResult, error) {
 		}, nil
 	}
 
-	// Append new permission
-	doc.allow = append(doc.allow, permission)
-
-	// Write with pretty formatting
-	output, err := doc.marshalIndent()
-	if err != nil {
-		return nil, err
-	}
-
 	if err := writeFileAtomic(path, output, 0644); err != nil {
 		return nil, err
 	}
+	action := "allow " + permission
+	if deny {
+		action = "deny " + permission
+	}
+	runWriteHook(path, data, output, action)
 
 	// Find the line number of the permission we just wrote
 	lineNumber := findPermissionLine(output, permission)
@@ -283,6 +587,259 @@ func writePermissionToSettings(path, permission string) (*ApplyResult, error) {
 	}, nil
 }
 
+// mergePermission adds permission to the allow or deny list of a settings
+// document, returning the formatted result. It reports false, with no
+// output, if the list already has the permission.
+func mergePermission(data []byte, permission string, deny bool) ([]byte, bool, error) {
+	doc, err := parseSettingsDocument(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("parse settings: %w", err)
+	}
+
+	exists := doc.hasPermission(permission)
+	if deny {
+		exists = doc.hasDenial(permission)
+	}
+	if exists {
+		return nil, false, nil
+	}
+
+	if deny {
+		doc.deny = append(doc.deny, permission)
+	} else {
+		doc.allow = append(doc.allow, permission)
+	}
+
+	// Write with pretty formatting
+	output, err := doc.marshalIndent()
+	if err != nil {
+		return nil, false, err
+	}
+	return output, true, nil
+}
+
+// MergePermissions returns the settings file at path as it is and as it
+// would be with permissions added to its allow list, without writing
+// anything. A missing file is empty before.
+func MergePermissions(path string, permissions []string) (before, after []byte, err error) {
+	before, err = os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+	after, _, err = mergeAllows(before, permissions)
+	if err != nil {
+		return nil, nil, err
+	}
+	return before, after, nil
+}
+
+// mergeAllows adds permissions to the allow list of a settings document,
+// returning the result and the permissions that were not already there
+func mergeAllows(data []byte, permissions []string) ([]byte, []string, error) {
+	var added []string
+	for _, p := range permissions {
+		output, wasNew, err := mergePermission(data, p, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		if wasNew {
+			data = output
+			added = append(added, p)
+		}
+	}
+	return data, added, nil
+}
+
+// WritePermissionsToSettingsFile adds permissions to the allow list of the
+// settings file at path in a single write, so a batch makes one snapshot
+// and one write hook call. There is a result per permission, in order.
+func WritePermissionsToSettingsFile(path string, permissions []string) ([]*ApplyResult, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("create settings directory: %w", err)
+	}
+	releaseLock, err := acquireFileLock(path + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	defer releaseLock()
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	output, added, err := mergeAllows(data, permissions)
+	if err != nil {
+		return nil, err
+	}
+	if len(added) > 0 {
+		if err := writeFileAtomic(path, output, 0644); err != nil {
+			return nil, err
+		}
+		runWriteHook(path, data, output, "allow "+strings.Join(added, ", "))
+	}
+	return batchResults(path, output, permissions, added, false), nil
+}
+
+// PlanPermissionsWrite reports what WritePermissionsToSettingsFile would do,
+// without writing anything. The results are marked DryRun.
+func PlanPermissionsWrite(path string, permissions []string) ([]*ApplyResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	output, added, err := mergeAllows(data, permissions)
+	if err != nil {
+		return nil, err
+	}
+	return batchResults(path, output, permissions, added, true), nil
+}
+
+// batchResults describes a batch write of permissions that left output at
+// path, adding the added ones
+func batchResults(path string, output []byte, permissions, added []string, dryRun bool) []*ApplyResult {
+	isNew := make(map[string]bool, len(added))
+	for _, p := range added {
+		isNew[p] = true
+	}
+	results := make([]*ApplyResult, len(permissions))
+	for i, p := range permissions {
+		result := &ApplyResult{FilePath: path, Permission: p, WasNew: isNew[p], DryRun: dryRun}
+		if result.WasNew {
+			result.LineNumber = findPermissionLine(output, p)
+			delete(isNew, p) // A repeated permission is only new once
+		}
+		results[i] = result
+	}
+	return results
+}
+
+// BatchResult combines the results of a batch written to the settings file
+// at path into one, listing the line of every permission it added
+func BatchResult(path string, results []*ApplyResult) *ApplyResult {
+	batch := &ApplyResult{FilePath: path}
+	perms := make([]string, len(results))
+	for i, r := range results {
+		perms[i] = r.Permission
+		batch.DryRun = r.DryRun
+		if !r.WasNew {
+			batch.Existing++
+			continue
+		}
+		batch.WasNew = true
+		if r.LineNumber > 0 {
+			batch.LineNumbers = append(batch.LineNumbers, r.LineNumber)
+		}
+	}
+	batch.Permission = strings.Join(perms, ", ")
+	if len(batch.LineNumbers) > 0 {
+		batch.LineNumber = batch.LineNumbers[0]
+	}
+	return batch
+}
+
+// RemoveAllowFromSettingsFile removes an allow rule from the settings file
+// at path, e.g. to prune a grant that is no longer used. Removing a rule
+// that isn't there changes nothing.
+func RemoveAllowFromSettingsFile(path, rule string) (*ApplyResult, error) {
+	releaseLock, err := acquireFileLock(path + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	defer releaseLock()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &ApplyResult{FilePath: path, Permission: rule}, nil
+		}
+		return nil, err
+	}
+
+	line := findPermissionLine(data, rule)
+	output, removed, err := removeAllow(data, rule)
+	if err != nil {
+		return nil, err
+	}
+	if !removed {
+		return &ApplyResult{FilePath: path, Permission: rule}, nil
+	}
+
+	if err := writeFileAtomic(path, output, 0644); err != nil {
+		return nil, err
+	}
+	runWriteHook(path, data, output, "remove allow "+rule)
+
+	return &ApplyResult{FilePath: path, Permission: rule, LineNumber: line, WasNew: true}, nil
+}
+
+// PlanAllowRemoval reports what RemoveAllowFromSettingsFile would do,
+// without writing anything. The result is marked DryRun.
+func PlanAllowRemoval(path, rule string) (*ApplyResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	_, removed, err := removeAllow(data, rule)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ApplyResult{FilePath: path, Permission: rule, WasNew: removed, DryRun: true}
+	if removed {
+		result.LineNumber = findPermissionLine(data, rule)
+	}
+	return result, nil
+}
+
+// PreviewAllowRemoval generates a diff preview for removing an allow rule
+// from a settings file
+func PreviewAllowRemoval(path, rule string) ([]DiffLine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	doc, err := parseSettingsDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	oldOutput, err := doc.marshalIndent()
+	if err != nil {
+		return nil, err
+	}
+	newOutput, _, err := removeAllow(data, rule)
+	if err != nil || newOutput == nil {
+		return nil, err
+	}
+	return DiffContents(oldOutput, newOutput), nil
+}
+
+// removeAllow drops every copy of rule from the allow list of a settings
+// document, returning the formatted result. It reports false, with no
+// output, if the list doesn't have the rule.
+func removeAllow(data []byte, rule string) ([]byte, bool, error) {
+	doc, err := parseSettingsDocument(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("parse settings: %w", err)
+	}
+	if !doc.hasPermission(rule) {
+		return nil, false, nil
+	}
+
+	kept := doc.allow[:0]
+	for _, existing := range doc.allow {
+		if existing != rule {
+			kept = append(kept, existing)
+		}
+	}
+	doc.allow = kept
+
+	output, err := doc.marshalIndent()
+	if err != nil {
+		return nil, false, err
+	}
+	return output, true, nil
+}
+
 // findPermissionLine scans formatted JSON output for the line containing the permission string
 func findPermissionLine(output []byte, permission string) int {
 	lines := strings.Split(string(output), "\n")
@@ -376,6 +933,20 @@ func PreviewProjectDiff(projectPath string, permissions []string) (string, []Dif
 	return path, diff, allExist, err
 }
 
+// DiffContents diffs two versions of a file, showing context around the
+// changed lines. A nil version is treated as empty.
+func DiffContents(old, new []byte) []DiffLine {
+	return buildContextDiff(splitLines(old), splitLines(new))
+}
+
+// splitLines splits file contents into lines; empty contents have none
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
 // buildContextDiff compares old and new line slices and produces a unified-style diff
 // with context lines around changes.
 func buildContextDiff(oldLines, newLines []string) []DiffLine {
@@ -466,6 +1037,17 @@ func IsApprovedProject(perm string, projectApproved []string) bool {
 	return false
 }
 
+// MatchingRules returns the allow rules that approve a permission
+func MatchingRules(perm string, approved []string) []string {
+	var rules []string
+	for _, rule := range approved {
+		if matchesPermission(perm, rule) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
 // matchesPermission checks if a permission matches an approval pattern
 // Supports wildcards like "Bash(*)" matching "Bash(curl:*)"
 func matchesPermission(perm, pattern string) bool {
@@ -501,3 +1083,29 @@ func GetApprovalLevel(perm string, userApproved, projectApproved []string) types
 	}
 	return types.NotApproved
 }
+
+// GetProjectsApprovalLevel returns the approval level for a permission used in
+// the given projects. It is approved at project level only when every one of
+// those projects with known settings allows it; projects missing from
+// projectSettings (e.g. deleted since) are ignored.
+func GetProjectsApprovalLevel(perm string, projects []string, userApproved []string, projectSettings map[string][]string) types.ApprovalLevel {
+	if IsApprovedUser(perm, userApproved) {
+		return types.ApprovedUser
+	}
+
+	known := 0
+	for _, project := range projects {
+		rules, ok := projectSettings[project]
+		if !ok {
+			continue
+		}
+		if !IsApprovedProject(perm, rules) {
+			return types.NotApproved
+		}
+		known++
+	}
+	if known > 0 {
+		return types.ApprovedProject
+	}
+	return types.NotApproved
+}
@@ -2,10 +2,18 @@ package parser
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/types"
 )
 
 func TestWritePermissionPreservesUnknownKeys(t *testing.T) {
@@ -137,6 +145,76 @@ func TestConcurrentWritePermissionToProjectSettings(t *testing.T) {
 	}
 }
 
+func TestLockOfExitedProcessIsTakenOver(t *testing.T) {
+	// A process that has exited, as one killed mid-write would have
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	settingsPath := filepath.Join(t.TempDir(), "settings.local.json")
+	lock := fmt.Sprintf("pid=%d time=%s\n", cmd.Process.Pid, time.Now().UTC().Format(time.RFC3339Nano))
+	if err := os.WriteFile(settingsPath+".lock", []byte(lock), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if _, err := WritePermissionsToSettingsFile(settingsPath, []string{"Bash(make:*)"}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if waited := time.Since(start); waited >= lockAcquireTimeout {
+		t.Errorf("waited %s for the lock of an exited process", waited)
+	}
+}
+
+func TestConcurrentTakeoverOfStaleLock(t *testing.T) {
+	// A lock file left by a process that exited, which every goroutine
+	// below finds at once
+	lockPath := filepath.Join(t.TempDir(), "settings.local.json.lock")
+	if err := os.WriteFile(lockPath, []byte("pid=999999 time=2026-01-01T00:00:00Z\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var holders atomic.Int32
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 5 {
+				release, err := acquireFileLock(lockPath)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if n := holders.Add(1); n > 1 {
+					t.Errorf("%d goroutines hold the lock at once", n)
+				}
+				time.Sleep(time.Millisecond)
+				holders.Add(-1)
+				release()
+			}
+		}()
+	}
+	wg.Wait()
+	if held, err := os.ReadFile(lockPath); err != nil || lockHolder(held) != os.Getpid() {
+		t.Errorf("lock file = %q, %v; want this process named as the last holder", held, err)
+	}
+}
+
+func TestLockedErrorNamesHolder(t *testing.T) {
+	err := error(&LockedError{Path: "/work/api/.claude/settings.local.json", PID: 42})
+	if want := "another perms instance (pid 42) is writing /work/api/.claude/settings.local.json; try again once it's done"; err.Error() != want {
+		t.Errorf("LockedError = %q, want %q", err, want)
+	}
+	var locked *LockedError
+	if !errors.As(fmt.Errorf("save: %w", err), &locked) || locked.PID != 42 {
+		t.Errorf("errors.As = %+v, want the holder's PID", locked)
+	}
+	if got := (&LockedError{Path: "x"}).Holder(); got != "another perms instance" {
+		t.Errorf("Holder with no PID = %q", got)
+	}
+}
+
 func TestPreviewProjectDiffReturnsParseError(t *testing.T) {
 	projectPath := t.TempDir()
 	settingsPath := filepath.Join(projectPath, ".claude", "settings.local.json")
@@ -152,3 +230,212 @@ func TestPreviewProjectDiffReturnsParseError(t *testing.T) {
 		t.Fatal("expected preview to return parse error")
 	}
 }
+
+func TestGetProjectsApprovalLevel(t *testing.T) {
+	settings := map[string][]string{
+		"/a": {"Bash(git:*)"},
+		"/b": {"Bash(*)"},
+		"/c": nil,
+	}
+
+	tests := []struct {
+		perm     string
+		projects []string
+		user     []string
+		want     types.ApprovalLevel
+	}{
+		{"Bash(git:*)", []string{"/a", "/b"}, nil, types.ApprovedProject},
+		{"Bash(git:*)", []string{"/a", "/c"}, nil, types.NotApproved},
+		{"Bash(git:*)", []string{"/a", "/gone"}, nil, types.ApprovedProject},
+		{"Bash(git:*)", []string{"/gone"}, nil, types.NotApproved},
+		{"Bash(git:*)", []string{"/c"}, []string{"Bash(git:*)"}, types.ApprovedUser},
+	}
+
+	for _, tc := range tests {
+		got := GetProjectsApprovalLevel(tc.perm, tc.projects, tc.user, settings)
+		if got != tc.want {
+			t.Errorf("GetProjectsApprovalLevel(%q, %v) = %v, want %v", tc.perm, tc.projects, got, tc.want)
+		}
+	}
+}
+
+func TestValidateSettingsFileReportsPosition(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	content := "{\n  \"permissions\": {\n    \"allow\": [\"Read\",]\n  }\n}\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err := ValidateSettingsFile(path)
+	if err == nil {
+		t.Fatal("Expected a parse error")
+	}
+	if !strings.Contains(err.Error(), "line 3, column 22") {
+		t.Errorf("Expected the error to point at line 3, column 22, got %v", err)
+	}
+}
+
+func TestRemoveAllowFromSettingsFile(t *testing.T) {
+	settingsPath := filepath.Join(t.TempDir(), "settings.local.json")
+	initial := `{"model": "opus", "permissions": {"allow": ["Read", "Bash(make:*)", "Read"], "deny": ["Bash(rm:*)"]}}`
+	if err := os.WriteFile(settingsPath, []byte(initial), 0644); err != nil {
+		t.Fatalf("write initial settings: %v", err)
+	}
+
+	plan, err := PlanAllowRemoval(settingsPath, "Read")
+	if err != nil || !plan.WasNew || !plan.DryRun {
+		t.Fatalf("plan = %+v, %v; want a dry-run removal", plan, err)
+	}
+	if data, _ := os.ReadFile(settingsPath); string(data) != initial {
+		t.Fatalf("planning changed the file: %s", data)
+	}
+
+	result, err := RemoveAllowFromSettingsFile(settingsPath, "Read")
+	if err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if !result.WasNew {
+		t.Errorf("result = %+v, want a change", result)
+	}
+
+	allow, deny, err := ReadSettingsRules(settingsPath)
+	if err != nil {
+		t.Fatalf("read settings: %v", err)
+	}
+	if len(allow) != 1 || allow[0] != "Bash(make:*)" || len(deny) != 1 {
+		t.Errorf("allow = %v, deny = %v; want every copy of Read gone and deny kept", allow, deny)
+	}
+
+	if result, err := RemoveAllowFromSettingsFile(settingsPath, "Read"); err != nil || result.WasNew {
+		t.Errorf("second remove = %+v, %v; want no change", result, err)
+	}
+}
+
+func TestWritePermissionsToSettingsFile(t *testing.T) {
+	settingsPath := filepath.Join(t.TempDir(), ".claude", "settings.local.json")
+
+	var writes []string
+	SetWriteHook(func(path string, before, after []byte, action string) error {
+		writes = append(writes, action)
+		return nil
+	})
+	defer SetWriteHook(nil)
+
+	perms := []string{"Read", "Bash(make:*)", "Read"}
+	plan, err := PlanPermissionsWrite(settingsPath, perms)
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if len(plan) != 3 || !plan[0].WasNew || !plan[0].DryRun || plan[2].WasNew {
+		t.Errorf("plan = %+v, want Read and Bash(make:*) new once", plan)
+	}
+	if _, err := os.Stat(settingsPath); !os.IsNotExist(err) {
+		t.Fatalf("planning created the file: %v", err)
+	}
+
+	results, err := WritePermissionsToSettingsFile(settingsPath, perms)
+	if err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if len(writes) != 1 || writes[0] != "allow Read, Bash(make:*)" {
+		t.Errorf("writes = %q, want one write adding both", writes)
+	}
+	if !results[1].WasNew || results[1].LineNumber == 0 || results[2].WasNew {
+		t.Errorf("results = %+v, want Bash(make:*) new with its line", results)
+	}
+
+	allow, _, err := ReadSettingsRules(settingsPath)
+	if err != nil {
+		t.Fatalf("read settings: %v", err)
+	}
+	if strings.Join(allow, " ") != "Read Bash(make:*)" {
+		t.Errorf("allow = %v, want Read and Bash(make:*)", allow)
+	}
+
+	results, err = WritePermissionsToSettingsFile(settingsPath, []string{"Read"})
+	if err != nil || results[0].WasNew || len(writes) != 1 {
+		t.Errorf("rewrite = %+v, %v, %d writes; want no change and no write", results, err, len(writes))
+	}
+}
+
+func TestWritePermissionsToUserAndProjectSettings(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	existing := "{\n  \"permissions\": {\n    \"allow\": [\n      \"Read\"\n    ]\n  }\n}\n"
+	if err := os.MkdirAll(filepath.Dir(UserSettingsPath()), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(UserSettingsPath(), []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := WritePermissionsToUserSettings([]string{"Read", "Bash(make:*)", "Bash(go test:*)"})
+	if err != nil {
+		t.Fatalf("write user: %v", err)
+	}
+	want := "{\n  \"permissions\": {\n    \"allow\": [\n      \"Read\",\n      \"Bash(make:*)\",\n      \"Bash(go test:*)\"\n    ],\n    \"deny\": []\n  }\n}"
+	if data, _ := os.ReadFile(UserSettingsPath()); string(data) != want {
+		t.Errorf("user settings = %q, want %q", data, want)
+	}
+	if !result.WasNew || result.Existing != 1 || fmt.Sprint(result.LineNumbers) != "[5 6]" || result.LineNumber != 5 {
+		t.Errorf("user result = %+v, want lines 5 and 6 added and Read already there", result)
+	}
+	if result.Permission != "Read, Bash(make:*), Bash(go test:*)" {
+		t.Errorf("user result permission = %q, want every permission", result.Permission)
+	}
+
+	project := t.TempDir()
+	result, err = WritePermissionsToProjectSettings(project, []string{"Edit", "Write"})
+	if err != nil {
+		t.Fatalf("write project: %v", err)
+	}
+	if result.FilePath != ProjectSettingsPath(project) || fmt.Sprint(result.LineNumbers) != "[4 5]" || result.Existing != 0 {
+		t.Errorf("project result = %+v, want lines 4 and 5 of the project settings", result)
+	}
+	allow, _, err := ReadSettingsRules(ProjectSettingsPath(project))
+	if err != nil || strings.Join(allow, " ") != "Edit Write" {
+		t.Errorf("project allow = %v, %v; want Edit and Write", allow, err)
+	}
+}
+
+func TestWriteRuleSetHandlesConflicts(t *testing.T) {
+	settingsPath := filepath.Join(t.TempDir(), "settings.json")
+	existing := `{"permissions":{"allow":["Bash(terraform destroy:*)","Read"],"deny":["Bash(git push:*)"]}}`
+	if err := os.WriteFile(settingsPath, []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+	set := RuleSet{
+		Allow: []string{"Read", "Bash(git push:*)", "Bash(terraform plan:*)"},
+		Deny:  []string{"Bash(terraform destroy:*)"},
+	}
+
+	_, _, plan, err := PlanRuleSet(settingsPath, set, false)
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if len(plan.Conflicts) != 2 || plan.Conflicts[0].Rule != "Bash(git push:*)" || !plan.Conflicts[1].Deny {
+		t.Errorf("conflicts = %+v, want the git push allow and terraform destroy deny", plan.Conflicts)
+	}
+
+	result, err := WriteRuleSet(settingsPath, set, false)
+	if err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if strings.Join(result.Allowed, " ") != "Bash(terraform plan:*)" || len(result.Denied) != 0 || len(result.Removed) != 0 {
+		t.Errorf("skipping result = %+v, want only the terraform plan allow added", result)
+	}
+
+	result, err = WriteRuleSet(settingsPath, set, true)
+	if err != nil {
+		t.Fatalf("write replacing: %v", err)
+	}
+	if len(result.Removed) != 2 || strings.Join(result.Denied, " ") != "Bash(terraform destroy:*)" {
+		t.Errorf("replacing result = %+v, want both conflicts resolved for the set", result)
+	}
+	allow, deny, err := ReadSettingsRules(settingsPath)
+	if err != nil {
+		t.Fatalf("read settings: %v", err)
+	}
+	if strings.Join(allow, " ") != "Read Bash(terraform plan:*) Bash(git push:*)" || strings.Join(deny, " ") != "Bash(terraform destroy:*)" {
+		t.Errorf("allow = %v, deny = %v after replacing", allow, deny)
+	}
+}
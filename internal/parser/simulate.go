@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/types"
+)
+
+// Coverage is what a set of allow rules would have changed had they been in
+// place for the recorded history
+type Coverage struct {
+	Calls       int // Recorded tool_uses the rules match
+	Prompts     int // Matching calls no existing rule allowed, i.e. prompts the rules would have skipped
+	Projects    int // Distinct projects with matching calls
+	Permissions int // Distinct permissions matched
+}
+
+// SimulateAllow replays stats against rules using the same matcher as the
+// approval status. If project is non-empty only calls made in that project
+// count, as for a project-level settings file.
+func SimulateAllow(rules []string, stats []types.PermissionStats, project string) Coverage {
+	var c Coverage
+	projects := make(map[string]bool)
+
+	for _, s := range stats {
+		if len(MatchingRules(s.Permission.Raw, rules)) == 0 {
+			continue
+		}
+
+		calls := s.Count
+		if project != "" {
+			calls = s.ProjectCounts[project]
+		}
+		if calls == 0 {
+			continue
+		}
+
+		c.Calls += calls
+		c.Permissions++
+		if s.ApprovedAt == types.NotApproved {
+			c.Prompts += calls
+		}
+
+		if project != "" {
+			projects[project] = true
+			continue
+		}
+		for p, n := range s.ProjectCounts {
+			if n > 0 {
+				projects[p] = true
+			}
+		}
+	}
+
+	c.Projects = len(projects)
+	return c
+}
+
+// PatternCall is a recorded permission a candidate rule was tried on
+type PatternCall struct {
+	Permission string // e.g. "Bash(npm run test:*)"
+	Input      string // The recorded input tried, e.g. "npm run test -- --watch"
+	Count      int    // Calls of the permission
+}
+
+// PatternTrial is how a candidate rule fares against the recorded history:
+// the calls it matches, and the calls of its tool it misses, most made first
+type PatternTrial struct {
+	Matched      []PatternCall
+	Missed       []PatternCall
+	MatchedCalls int
+	MissedCalls  int
+}
+
+// TryPattern matches a candidate rule against every recorded permission of
+// its tool with the matcher check uses, before the rule goes in a settings
+// file. A permission is tried on its first complete sample input, or on its
+// scope if it has none; paths resolve against the first project it was
+// used in.
+func TryPattern(rule string, stats []types.PermissionStats) PatternTrial {
+	var trial PatternTrial
+	ruleTool, _ := splitInvocation(strings.TrimSpace(rule))
+	for _, s := range stats {
+		tool := s.Permission.Type
+		input := strings.TrimSuffix(s.Permission.Scope, ":*")
+		for _, sample := range s.Samples {
+			if !strings.HasSuffix(sample, "...") {
+				input = sample
+				break
+			}
+		}
+		project := ""
+		if len(s.Projects) > 0 {
+			project = s.Projects[0]
+		}
+
+		call := PatternCall{Permission: s.Permission.Raw, Input: input, Count: s.Count}
+		switch {
+		case ruleMatches(rule, tool, input, project, SettingsLayer{}):
+			trial.Matched = append(trial.Matched, call)
+			trial.MatchedCalls += s.Count
+		case coversTool(ruleTool, tool):
+			trial.Missed = append(trial.Missed, call)
+			trial.MissedCalls += s.Count
+		}
+	}
+
+	byCount := func(calls []PatternCall) {
+		sort.SliceStable(calls, func(i, j int) bool { return calls[i].Count > calls[j].Count })
+	}
+	byCount(trial.Matched)
+	byCount(trial.Missed)
+	return trial
+}
@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/b-open-io/claude-perms/internal/types"
+)
+
+func TestSimulateAllow(t *testing.T) {
+	stats := []types.PermissionStats{
+		{
+			Permission:    ParsePermission("Bash(git status:*)"),
+			Count:         5,
+			ProjectCounts: map[string]int{"/a": 3, "/b": 2},
+		},
+		{
+			Permission:    ParsePermission("Bash(go test:*)"),
+			Count:         4,
+			ApprovedAt:    types.ApprovedUser,
+			ProjectCounts: map[string]int{"/a": 4},
+		},
+		{
+			Permission:    ParsePermission("Read"),
+			Count:         9,
+			ProjectCounts: map[string]int{"/c": 9},
+		},
+	}
+
+	got := SimulateAllow([]string{"Bash(*)"}, stats, "")
+	want := Coverage{Calls: 9, Prompts: 5, Projects: 2, Permissions: 2}
+	if got != want {
+		t.Errorf("user-level coverage = %+v, want %+v", got, want)
+	}
+
+	got = SimulateAllow([]string{"Bash(git status:*)"}, stats, "/b")
+	want = Coverage{Calls: 2, Prompts: 2, Projects: 1, Permissions: 1}
+	if got != want {
+		t.Errorf("project-level coverage = %+v, want %+v", got, want)
+	}
+
+	if got := SimulateAllow([]string{"WebFetch"}, stats, ""); got != (Coverage{}) {
+		t.Errorf("unmatched rule coverage = %+v, want zero", got)
+	}
+}
+
+func TestTryPattern(t *testing.T) {
+	stats := []types.PermissionStats{
+		{Permission: ParsePermission("Bash(npm run test:*)"), Count: 7, Samples: []string{"npm run test -- --watch"}},
+		{Permission: ParsePermission("Bash(npm run build:*)"), Count: 3},
+		{Permission: ParsePermission("Bash(npm install:*)"), Count: 2, Samples: []string{"npm install lodash"}},
+		{Permission: ParsePermission("Read"), Count: 9, Samples: []string{"/a/main.go"}},
+	}
+
+	trial := TryPattern("Bash(npm run *)", stats)
+	if trial.MatchedCalls != 10 || len(trial.Matched) != 2 || trial.Matched[0].Input != "npm run test -- --watch" {
+		t.Errorf("matched = %+v (%d calls), want both npm run permissions", trial.Matched, trial.MatchedCalls)
+	}
+	if trial.MissedCalls != 2 || len(trial.Missed) != 1 || trial.Missed[0].Permission != "Bash(npm install:*)" {
+		t.Errorf("missed = %+v (%d calls), want only npm install: Read is another tool", trial.Missed, trial.MissedCalls)
+	}
+
+	if trial := TryPattern("Bash(npm run *:*)", stats); trial.MatchedCalls != 0 || trial.MissedCalls != 12 {
+		t.Errorf("prefix rule with a wildcard = %+v, want every Bash call missed", trial)
+	}
+}
@@ -2,7 +2,6 @@ package parser
 
 import (
 	"bufio"
-	"os"
 	"path/filepath"
 	"strings"
 
@@ -23,63 +22,16 @@ func LoadAllSkills() ([]types.SkillPermissions, error) {
 	return skills, nil
 }
 
-// loadSkillsFromPlugins loads skills from installed plugins
+// loadSkillsFromPlugins loads skills from the latest installed version of
+// every plugin
 func loadSkillsFromPlugins() ([]types.SkillPermissions, error) {
-	cacheDir := filepath.Join(claudeDir(), "plugins", "cache")
-
 	var skills []types.SkillPermissions
-
-	// Walk the cache directory structure
-	marketplaces, err := os.ReadDir(cacheDir)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, marketplace := range marketplaces {
-		if !marketplace.IsDir() {
-			continue
-		}
-
-		marketplacePath := filepath.Join(cacheDir, marketplace.Name())
-		plugins, err := os.ReadDir(marketplacePath)
-		if err != nil {
-			continue
-		}
-
-		for _, plugin := range plugins {
-			if !plugin.IsDir() {
-				continue
-			}
-
-			pluginPath := filepath.Join(marketplacePath, plugin.Name())
-			versions, err := os.ReadDir(pluginPath)
-			if err != nil {
-				continue
-			}
-
-			// Find the latest version (highest semver string)
-			var latestVersion string
-			for _, version := range versions {
-				if !version.IsDir() {
-					continue
-				}
-				if version.Name() > latestVersion {
-					latestVersion = version.Name()
-				}
-			}
-
-			if latestVersion != "" {
-				versionPath := filepath.Join(pluginPath, latestVersion)
-				skillsDir := filepath.Join(versionPath, "skills")
-
-				pluginSkills, err := loadSkillsFromDirWithVersion(skillsDir, plugin.Name(), latestVersion)
-				if err == nil {
-					skills = append(skills, pluginSkills...)
-				}
-			}
+	for _, p := range latestPluginVersions() {
+		pluginSkills, err := loadSkillsFromDirWithVersion(filepath.Join(p.Dir, "skills"), p.Name, p.Version)
+		if err == nil {
+			skills = append(skills, pluginSkills...)
 		}
 	}
-
 	return skills, nil
 }
 
@@ -90,7 +42,7 @@ func loadSkillsFromDir(dir, pluginName string) ([]types.SkillPermissions, error)
 
 // loadSkillsFromDirWithVersion loads all skills from a directory with version info
 func loadSkillsFromDirWithVersion(dir, pluginName, version string) ([]types.SkillPermissions, error) {
-	entries, err := os.ReadDir(dir)
+	entries, err := readDir(dir)
 	if err != nil {
 		return nil, err
 	}
@@ -106,10 +58,11 @@ func loadSkillsFromDirWithVersion(dir, pluginName, version string) ([]types.Skil
 		skillPath := filepath.Join(dir, entry.Name(), "SKILL.md")
 		skill, err := parseSkillFile(skillPath, pluginName, version)
 		if err != nil {
+			addWarning(skillPath, 0, "invalid skill frontmatter: %v", err)
 			continue
 		}
 
-		if len(skill.Permissions) > 0 {
+		if skill.Declares() {
 			skills = append(skills, skill)
 		}
 	}
@@ -119,7 +72,7 @@ func loadSkillsFromDirWithVersion(dir, pluginName, version string) ([]types.Skil
 
 // parseSkillFile parses a skill markdown file and extracts frontmatter
 func parseSkillFile(path, pluginName, version string) (types.SkillPermissions, error) {
-	file, err := os.Open(path)
+	file, err := openFile(path)
 	if err != nil {
 		return types.SkillPermissions{}, err
 	}
@@ -172,5 +125,7 @@ func parseSkillFile(path, pluginName, version string) (types.SkillPermissions, e
 		Version:     version,
 		FilePath:    path,
 		Permissions: ParsePermissions(parseToolsField(frontmatter.Tools)),
+		Allow:       ParsePermissions(parseToolsField(frontmatter.Permissions.Allow)),
+		Deny:        ParsePermissions(parseToolsField(frontmatter.Permissions.Deny)),
 	}, nil
 }
@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Warning is a non-fatal problem encountered while loading data. The load
+// continues, but the affected file or line didn't contribute to the stats.
+type Warning struct {
+	File   string `json:"file"`
+	Line   int    `json:"line,omitempty"` // 1-based line number, 0 if not line-specific
+	Reason string `json:"reason"`
+}
+
+func (w Warning) String() string {
+	if w.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", w.File, w.Line, w.Reason)
+	}
+	return fmt.Sprintf("%s: %s", w.File, w.Reason)
+}
+
+// maxWarnings bounds memory use when a large tree is badly corrupted
+const maxWarnings = 1000
+
+var (
+	warningsMu sync.Mutex
+	warnings   []Warning
+	dropped    int
+)
+
+// addWarning records a non-fatal load problem
+func addWarning(file string, line int, format string, args ...any) {
+	recordWarnings([]Warning{{File: file, Line: line, Reason: fmt.Sprintf(format, args...)}})
+}
+
+// recordWarnings records several warnings at once (e.g. replayed from cache)
+func recordWarnings(ws []Warning) {
+	if len(ws) == 0 {
+		return
+	}
+	warningsMu.Lock()
+	defer warningsMu.Unlock()
+	for _, w := range ws {
+		logger.Debug("load warning", "file", w.File, "line", w.Line, "reason", w.Reason)
+		if len(warnings) >= maxWarnings {
+			dropped++
+			continue
+		}
+		warnings = append(warnings, w)
+	}
+}
+
+// RecordWarnings records warnings found outside the parser's own loaders,
+// e.g. replayed from a persistent event store, for the next TakeWarnings
+func RecordWarnings(ws []Warning) {
+	recordWarnings(ws)
+}
+
+// TakeWarnings returns the warnings recorded since the last call, plus the
+// number that were dropped after the limit was reached, and clears them
+func TakeWarnings() ([]Warning, int) {
+	warningsMu.Lock()
+	defer warningsMu.Unlock()
+	ws, n := warnings, dropped
+	warnings, dropped = nil, 0
+	return ws, n
+}
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxTesterCalls bounds the matched and the missed calls the pattern tester
+// lists
+const maxTesterCalls = 6
+
+// openTester opens the pattern tester prefilled with a rule
+func (m *Model) openTester(rule string) tea.Cmd {
+	m.showTester = true
+	m.testerInput.SetValue(rule)
+	m.testerInput.CursorEnd()
+	return m.testerInput.Focus()
+}
+
+// handleTesterKeys processes keys in the pattern tester. Every key edits the
+// rule, which is tried on each change; Enter stages it.
+func (m Model) handleTesterKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.ForceQuit):
+		return m, tea.Quit
+
+	case msg.Type == tea.KeyEsc:
+		m.testerInput.Blur()
+		m.showTester = false
+		return m, nil
+
+	case msg.Type == tea.KeyEnter:
+		rule := strings.TrimSpace(m.testerInput.Value())
+		if rule == "" {
+			return m, nil
+		}
+		if !m.isStaged(rule) {
+			m.staged = append(slices.Clone(m.staged), rule)
+		}
+		m.testerInput.Blur()
+		m.showTester = false
+		m.notify(toastSuccess, "Staged %s: press %s to apply the staging area", rule, primaryKey(m.keys.ApplyStaged))
+		return m, m.toastTickCmd()
+	}
+
+	if msg.Paste {
+		msg.Runes = sanitizePaste(msg.Runes)
+	}
+
+	var cmd tea.Cmd
+	m.testerInput, cmd = m.testerInput.Update(msg)
+	return m, cmd
+}
+
+// renderTesterModal renders the rule being edited and the recorded calls it
+// matches and misses
+func (m Model) renderTesterModal() string {
+	modalWidth := min(max(m.width*85/100, 50), 90)
+	width := modalWidth - 6
+
+	var b strings.Builder
+	b.WriteString(styles.ModalTitle.Render("Pattern Tester"))
+	b.WriteString("\n\n")
+	b.WriteString(styles.HelpDesc.Render(truncateString("  Candidate rule, e.g. Bash(npm run test:*) or Read(src/**)", width)) + "\n")
+	m.testerInput.Width = width - 4
+	b.WriteString("  " + m.testerInput.View() + "\n\n")
+
+	if rule := strings.TrimSpace(m.testerInput.Value()); rule != "" {
+		trial := parser.TryPattern(rule, m.permissions)
+		b.WriteString(styles.StatusApproved.Render(fmt.Sprintf("  Matches %d call(s) of %d permission(s)", trial.MatchedCalls, len(trial.Matched))) + "\n")
+		b.WriteString(renderPatternCalls(trial.Matched, width))
+		b.WriteString("\n" + styles.StatusPending.Render(fmt.Sprintf("  Misses %d call(s) of the same tool", trial.MissedCalls)) + "\n")
+		b.WriteString(renderPatternCalls(trial.Missed, width))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("  " + renderHints(m.contextBindings()))
+	return styles.Modal.Width(modalWidth).Render(b.String())
+}
+
+// renderPatternCalls lists the most made of calls with their inputs
+func renderPatternCalls(calls []parser.PatternCall, width int) string {
+	var b strings.Builder
+	for i, c := range calls {
+		if i == maxTesterCalls {
+			b.WriteString(styles.HelpDesc.Render(fmt.Sprintf("    … %d more", len(calls)-i)) + "\n")
+			break
+		}
+		b.WriteString(truncateString(fmt.Sprintf("  %5d  %s", c.Count, c.Input), width) + "\n")
+	}
+	return b.String()
+}
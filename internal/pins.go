@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"sort"
+
+	"github.com/b-open-io/claude-perms/internal/state"
+	"github.com/b-open-io/claude-perms/internal/types"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// pinMarker prefixes the names of pinned rows
+const pinMarker = "★ "
+
+// sortAgentUsage puts pinned agents first, then orders by calls
+func sortAgentUsage(agents []types.AgentUsageStats, pins state.Pins) {
+	sort.SliceStable(agents, func(i, j int) bool {
+		pi, pj := pins.Agent(agents[i].AgentType), pins.Agent(agents[j].AgentType)
+		if pi != pj {
+			return pi
+		}
+		return agents[i].TotalCalls > agents[j].TotalCalls
+	})
+}
+
+// togglePin pins or unpins the selected group, permission or agent, keeps it
+// selected after re-sorting and saves the change. Pins are not saved in demo
+// mode, so exploring the demo leaves your real pins alone.
+func (m Model) togglePin() (tea.Model, tea.Cmd) {
+	var name string
+	var pinned bool
+
+	switch m.activeView {
+	case ViewFrequency:
+		gi, ci := m.freqCursor()
+		if gi >= len(m.permissionGroups) {
+			return m, nil
+		}
+		g := m.permissionGroups[gi]
+		if ci >= 0 && ci < len(g.Children) {
+			name = g.Children[ci].Permission.Raw
+			pinned = m.state.Pins.TogglePermission(name)
+		} else {
+			name = g.Type
+			pinned = m.state.Pins.ToggleGroup(name)
+		}
+		m.resortGroups()
+	case ViewMatrix:
+		if m.matrixByPlugin {
+			m.setLinkToast("Pins are per agent: press %s to list agents", primaryKey(m.keys.Group))
+			return m, m.toastTickCmd()
+		}
+		if m.matrixList.cursor >= len(m.agentUsage) {
+			return m, nil
+		}
+		name = m.agentUsage[m.matrixList.cursor].AgentType
+		pinned = m.state.Pins.ToggleAgent(name)
+		sortAgentUsage(m.agentUsage, m.state.Pins)
+		for i, a := range m.agentUsage {
+			if a.AgentType == name {
+				m.matrixList.center(i, m.matrixListLen(), m.matrixViewportHeight())
+				break
+			}
+		}
+	default:
+		return m, nil
+	}
+
+	if pinned {
+		m.notify(toastSuccess, "Pinned %s", name)
+	} else {
+		m.notify(toastSuccess, "Unpinned %s", name)
+	}
+	if !m.demo {
+		if err := m.state.Save(); err != nil {
+			m.logger.Debug("saving state failed", "err", err)
+			m.notify(toastError, "Could not save pins: %v", err)
+		}
+	}
+	return m, m.toastTickCmd()
+}
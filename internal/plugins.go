@@ -0,0 +1,216 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/insights"
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// loadPlugins rolls the loaded agents, skills, commands and their usage up
+// by plugin
+func (m *Model) loadPlugins() {
+	m.plugins = insights.PluginRollups(m.agents, m.skills, m.commands, m.agentUsage)
+}
+
+// togglePluginGrouping switches the Matrix between agents and commands and
+// their plugins
+func (m Model) togglePluginGrouping() (tea.Model, tea.Cmd) {
+	m.matrixByPlugin = !m.matrixByPlugin
+	if m.matrixByPlugin {
+		m.loadPlugins()
+	}
+	m.matrixList.top()
+	return m, nil
+}
+
+// selectedPlugin returns the plugin under the Matrix cursor
+func (m Model) selectedPlugin() (insights.PluginRollup, bool) {
+	if !m.matrixByPlugin || m.matrixList.cursor >= len(m.plugins) {
+		return insights.PluginRollup{}, false
+	}
+	return m.plugins[m.matrixList.cursor], true
+}
+
+// openPluginModal shows the selected plugin's rollup, with what its latest
+// installed version changed in its declarations
+func (m *Model) openPluginModal() {
+	p, ok := m.selectedPlugin()
+	if !ok {
+		return
+	}
+	m.showPluginModal = true
+	m.pluginInstall, _ = parser.FindInstalledPlugin(p.Name)
+	m.pluginChanges = nil
+	latest := m.pluginInstall.Latest()
+	if previous := m.pluginInstall.Previous(latest); previous != "" {
+		m.pluginChanges = parser.DiffPluginDeclarations(
+			parser.LoadPluginVersion(m.pluginInstall, previous),
+			parser.LoadPluginVersion(m.pluginInstall, latest))
+	}
+}
+
+// handlePluginModalKeys processes keys in the plugin modal
+func (m Model) handlePluginModalKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.ForceQuit):
+		return m, tea.Quit
+	case key.Matches(msg, m.keys.Back, m.keys.Select, m.keys.Quit):
+		m.closeModal()
+	}
+	return m, nil
+}
+
+// renderPluginRow renders a plugin in the Matrix columns: declared
+// permissions are the union of its members', calls and status cover the
+// usage of its agents and commands
+func (m Model) renderPluginRow(p insights.PluginRollup, selected bool) string {
+	nameWidth, declWidth, callsWidth, lastWidth, statusWidth := m.calculateMatrixColumns()
+
+	name := padRight(truncateString(p.Name, nameWidth), nameWidth)
+	decl := padLeft(fmt.Sprintf("%d", len(p.Declared.Permissions)+len(p.Declared.Allow)+len(p.Declared.Deny)), declWidth)
+	calls := padLeft(fmt.Sprintf("%d", p.Usage.TotalCalls), callsWidth)
+	last := padLeft(formatRelativeTime(p.Usage.LastSeen), lastWidth)
+
+	statusText := "-"
+	if n := len(p.Usage.Permissions); n > 0 {
+		if approved := m.countApprovedPerms(p.Usage); approved == n {
+			statusText = "all"
+		} else {
+			statusText = fmt.Sprintf("%d/%d", approved, n)
+		}
+	}
+	status := padLeft(statusText, statusWidth)
+
+	cursor := "  "
+	if selected {
+		cursor = "> "
+	}
+	row := fmt.Sprintf("%s%s %s %s %s %s", cursor, name, decl, calls, last, status)
+
+	maxWidth := m.width - 2
+	row = padRight(truncateString(row, maxWidth), maxWidth)
+	if selected {
+		return styles.ListItemSelected.Render(row)
+	}
+	return row
+}
+
+// renderPluginModal renders what a plugin's members declare together and
+// every permission they used
+func (m Model) renderPluginModal() string {
+	p, ok := m.selectedPlugin()
+	if !ok {
+		return ""
+	}
+
+	modalWidth := m.width * 85 / 100
+	if modalWidth > 80 {
+		modalWidth = 80
+	}
+	if modalWidth < 50 {
+		modalWidth = 50
+	}
+	width := modalWidth - 8
+
+	var content strings.Builder
+	title := p.Name
+	if p.Version != "" {
+		title += " " + p.Version
+	}
+	content.WriteString(styles.ModalTitle.Render(title))
+	content.WriteString("\n")
+	content.WriteString(fmt.Sprintf("  %d agents, %d skills, %d commands · %d calls\n",
+		len(p.Agents), len(p.Skills), len(p.Commands), p.Usage.TotalCalls))
+
+	members := func(label string, names []string, prefix string) {
+		if len(names) == 0 {
+			return
+		}
+		shown := make([]string, len(names))
+		for i, n := range names {
+			shown[i] = prefix + n
+		}
+		content.WriteString(styles.HelpDesc.Render(truncateString(fmt.Sprintf("  %-16s %s", label, strings.Join(shown, ", ")), width)) + "\n")
+	}
+	members("Agents:", p.Agents, "")
+	members("Skills:", p.Skills, "")
+	members("Commands:", p.Commands, "/")
+	content.WriteString(renderDeclared(p.Declared, p.Drift(), width))
+	content.WriteString(m.renderPluginVersions(width))
+	content.WriteString("\n")
+
+	if len(p.Usage.Permissions) == 0 {
+		content.WriteString("  No recorded usage by the plugin's agents or commands\n")
+	} else {
+		drift := p.Drift()
+		tags := make(map[string]string)
+		for _, st := range drift.Undeclared {
+			tags[st.Permission.Raw] = "undeclared"
+		}
+		for _, st := range drift.Denied {
+			tags[st.Permission.Raw] = "plugin denies"
+		}
+
+		content.WriteString("  Permissions used by the plugin:\n\n")
+		limit := max(m.height-22, 3)
+		for i, st := range p.Usage.Permissions {
+			if i == limit {
+				content.WriteString(styles.HelpDesc.Render(fmt.Sprintf("    … %d more", len(p.Usage.Permissions)-limit)) + "\n")
+				break
+			}
+			status := m.getPermissionApprovalStatus(st.Permission.Raw, p.Usage.Projects)
+			line := fmt.Sprintf("    %s %10s  %s", padRight(truncateString(st.Permission.Raw, 34), 34), fmt.Sprintf("%d calls", st.Count), status)
+			if tag := tags[st.Permission.Raw]; tag != "" {
+				line += "  " + styles.StatusPending.Render(tag)
+			}
+			content.WriteString(line + "\n")
+		}
+	}
+
+	content.WriteString("\n  " + renderHints(m.contextBindings()))
+	return styles.Modal.Width(modalWidth).Render(content.String())
+}
+
+// renderPluginVersions lists the plugin's installed versions and, when more
+// than one is installed, what the latest changed in its declarations
+func (m Model) renderPluginVersions(width int) string {
+	versions := m.pluginInstall.Versions
+	if len(versions) < 2 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(styles.HelpDesc.Render(truncateString(fmt.Sprintf("  %-16s %s", "Installed:", strings.Join(versions, ", ")), width)) + "\n")
+	latest := m.pluginInstall.Latest()
+	previous := m.pluginInstall.Previous(latest)
+	if len(m.pluginChanges) == 0 {
+		b.WriteString(styles.HelpDesc.Render(fmt.Sprintf("  %s declares the same permissions as %s", latest, previous)) + "\n")
+		return b.String()
+	}
+
+	b.WriteString(fmt.Sprintf("  Changed since %s:\n", previous))
+	const maxChanges = 4
+	for i, c := range m.pluginChanges {
+		if i == maxChanges {
+			b.WriteString(styles.HelpDesc.Render(fmt.Sprintf("    … %d more (perms plugins --diff %s)", len(m.pluginChanges)-maxChanges, m.pluginInstall.Name)) + "\n")
+			break
+		}
+		var parts []string
+		for _, a := range c.Added {
+			parts = append(parts, "+"+a)
+		}
+		for _, r := range c.Removed {
+			parts = append(parts, "-"+r)
+		}
+		line := truncateString(fmt.Sprintf("    %s %s: %s", c.Member, c.List, strings.Join(parts, " ")), width)
+		if len(c.Added) > 0 {
+			line = styles.StatusPending.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
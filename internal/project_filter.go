@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// filterProjects returns the projects whose path contains query, ignoring
+// case
+func filterProjects(projects []string, query string) []string {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return projects
+	}
+	var matched []string
+	for _, p := range projects {
+		if strings.Contains(strings.ToLower(p), query) {
+			matched = append(matched, p)
+		}
+	}
+	return matched
+}
+
+// visibleProjects returns the projects a modal's project list shows under
+// the current filter; its cursor indexes into them
+func (m Model) visibleProjects(projects []string) []string {
+	return filterProjects(projects, m.projectFilter.Value())
+}
+
+// startProjectFilter focuses the project list's filter
+func (m *Model) startProjectFilter() tea.Cmd {
+	m.filteringProjects = true
+	return m.projectFilter.Focus()
+}
+
+// clearProjectFilter empties the project list's filter and stops typing
+// into it
+func (m *Model) clearProjectFilter() {
+	m.filteringProjects = false
+	m.projectFilter.Blur()
+	m.projectFilter.SetValue("")
+}
+
+// handleProjectFilterKeys edits the filter of a project list while it is
+// being typed into, moving cursor back to the first match as it changes.
+// It reports false for the keys the list handles itself: the arrows, Enter
+// and ctrl+c.
+func (m *Model) handleProjectFilterKeys(msg tea.KeyMsg, list *listState) (tea.Cmd, bool) {
+	if !m.filteringProjects {
+		return nil, false
+	}
+	switch {
+	case msg.Type == tea.KeyUp, msg.Type == tea.KeyDown, msg.Type == tea.KeyEnter,
+		key.Matches(msg, m.keys.ForceQuit):
+		return nil, false
+
+	case msg.Type == tea.KeyEsc:
+		m.clearProjectFilter()
+		list.top()
+		return nil, true
+	}
+
+	if msg.Paste {
+		msg.Runes = sanitizePaste(msg.Runes)
+	}
+	before := m.projectFilter.Value()
+	var cmd tea.Cmd
+	m.projectFilter, cmd = m.projectFilter.Update(msg)
+	if m.projectFilter.Value() != before {
+		list.top()
+	}
+	return cmd, true
+}
+
+// lastProjectIndex returns the position in projects of the project
+// permType was last applied to, so the project list can start on it, or 0
+func (m Model) lastProjectIndex(projects []string, permType string) int {
+	return max(slices.Index(projects, m.state.Targets.Project(permType)), 0)
+}
+
+// rememberProject records project as where permType was last applied. Like
+// pins, it is not saved in demo mode.
+func (m *Model) rememberProject(permType, project string) {
+	if m.state.Targets.Project(permType) == project {
+		return
+	}
+	m.state.Targets.Remember(permType, project)
+	if m.demo {
+		return
+	}
+	if err := m.state.Save(); err != nil {
+		m.logger.Debug("saving state failed", "err", err)
+	}
+}
+
+// selectionType is the permission type the agent modal's selection is
+// remembered under: that of its first permission
+func selectionType(perms []string) string {
+	if len(perms) == 0 {
+		return ""
+	}
+	return parser.ParsePermission(perms[0]).Type
+}
+
+// renderProjectFilter renders the filter line above a project list of
+// total projects, with how many match, while it is in use
+func (m Model) renderProjectFilter(matched, total int) string {
+	if !m.filteringProjects && m.projectFilter.Value() == "" {
+		return ""
+	}
+	line := "  " + m.projectFilter.View()
+	if m.projectFilter.Value() != "" {
+		line += styles.HelpDesc.Render(fmt.Sprintf("  %d of %d", matched, total))
+	}
+	line += "\n\n"
+	if matched == 0 {
+		line += styles.HelpDesc.Render("  No project matches") + "\n"
+	}
+	return line
+}
+
+// projectListBindings returns the hints of a modal's project list, which
+// while its filter is typed into only the arrows move
+func (m Model) projectListBindings() []key.Binding {
+	k := m.keys
+	if m.filteringProjects {
+		return []key.Binding{
+			key.NewBinding(key.WithKeys("up", "down"), key.WithHelp("↑/↓", "nav")),
+			withDesc(k.Select, "apply"),
+			key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "clear filter")),
+		}
+	}
+	return []key.Binding{k.navBinding(), withDesc(k.Select, "apply"), withDesc(k.Filter, "filter projects"), withDesc(k.Back, "back")}
+}
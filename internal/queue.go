@@ -0,0 +1,292 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/state"
+	"github.com/b-open-io/claude-perms/internal/types"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// queueListLimit caps the projects, agents and samples the review queue
+// shows for a permission
+const queueListLimit = 3
+
+// openQueue starts a pass of the review queue over the permissions no
+// settings file allows and no earlier pass decided, most used first. When
+// only skipped ones are left, they come round again.
+func (m Model) openQueue() (tea.Model, tea.Cmd) {
+	queue := m.pendingQueue()
+	if len(queue) == 0 {
+		if n := m.state.Queue.ClearSkipped(); n > 0 {
+			m.saveQueue()
+			queue = m.pendingQueue()
+		}
+	}
+	if len(queue) == 0 {
+		m.notify(toastInfo, "Nothing to review: every permission is allowed, denied or ignored")
+		return m, m.toastTickCmd()
+	}
+
+	m.showQueue = true
+	m.queue = queue
+	m.queueIdx = 0
+	m.queueTally = make(map[string]int)
+	return m, nil
+}
+
+// pendingQueue returns the raw permissions the review queue still has to
+// show, most used first
+func (m Model) pendingQueue() []string {
+	var perms []types.PermissionStats
+	for _, p := range m.permissions {
+		if m.state.Queue.Decision(p.Permission.Raw) != "" {
+			continue
+		}
+		if m.approvalLevel(p.Permission.Raw, p.Projects) != types.NotApproved {
+			continue
+		}
+		perms = append(perms, p)
+	}
+	sort.SliceStable(perms, func(i, j int) bool {
+		if perms[i].Count != perms[j].Count {
+			return perms[i].Count > perms[j].Count
+		}
+		return perms[i].Permission.Raw < perms[j].Permission.Raw
+	})
+	queue := make([]string, len(perms))
+	for i, p := range perms {
+		queue[i] = p.Permission.Raw
+	}
+	return queue
+}
+
+// queuePermission returns the permission the review queue is showing, or
+// nil once the pass is done
+func (m Model) queuePermission() *types.PermissionStats {
+	if m.queueIdx >= len(m.queue) {
+		return nil
+	}
+	for i := range m.permissions {
+		if m.permissions[i].Permission.Raw == m.queue[m.queueIdx] {
+			return &m.permissions[i]
+		}
+	}
+	return nil
+}
+
+// queueProject is the project the review queue allows a permission in: the
+// current directory if the permission was used there, else the project
+// that used it most
+func (m Model) queueProject(perm *types.PermissionStats) string {
+	for _, p := range perm.Projects {
+		if p == m.projectPath {
+			return p
+		}
+	}
+	if rows := sortedCounts(perm.ProjectCounts); len(rows) > 0 {
+		return rows[0].label
+	}
+	if len(perm.Projects) > 0 {
+		return perm.Projects[0]
+	}
+	return ""
+}
+
+// handleQueueKeys acts on the permission the review queue is showing
+func (m Model) handleQueueKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	perm := m.queuePermission()
+
+	switch {
+	case key.Matches(msg, m.keys.ForceQuit):
+		return m, tea.Quit
+
+	case key.Matches(msg, m.keys.Back, m.keys.Quit):
+		m.showQueue = false
+		return m, nil
+
+	case perm == nil:
+		// The pass is done: Enter goes through the skipped ones again
+		if key.Matches(msg, m.keys.Select) && m.queueTally["skipped"] > 0 {
+			m.showQueue = false
+			return m.openQueue()
+		}
+		return m, nil
+
+	case key.Matches(msg, m.keys.AllowUser):
+		return m.queueWrite(perm, parser.UserSettingsPath(), "user", false)
+
+	case key.Matches(msg, m.keys.AllowProject):
+		project := m.queueProject(perm)
+		if project == "" {
+			m.setLinkToast("No project recorded for %s", perm.Permission.Raw)
+			return m, m.toastTickCmd()
+		}
+		return m.queueWrite(perm, parser.ProjectSettingsPath(project), project, false)
+
+	case key.Matches(msg, m.keys.Deny):
+		return m.queueWrite(perm, parser.UserSettingsPath(), "user", true)
+
+	case key.Matches(msg, m.keys.Dismiss):
+		m.queueDecide(perm.Permission.Raw, state.QueueIgnore, "ignored")
+
+	case key.Matches(msg, m.keys.Skip):
+		m.queueDecide(perm.Permission.Raw, state.QueueSkip, "skipped")
+	}
+	return m, nil
+}
+
+// queueWrite allows perm in the settings file at path, or denies it, and
+// moves to the next permission. scope is "user" or the project allowed in.
+// Read-only and demo mode refuse and stay put; a dry run moves on without
+// recording a decision, so the permission comes up again next time.
+func (m Model) queueWrite(perm *types.PermissionStats, path, scope string, deny bool) (tea.Model, tea.Cmd) {
+	if reason := m.writeBlockedReason(); reason != "" {
+		m.notify(toastWarn, "%s", reason)
+		return m, m.toastTickCmd()
+	}
+	raw := perm.Permission.Raw
+	result, err := m.writePermission(path, raw, deny)
+	if err != nil {
+		return m, m.failWrite(path, err, func(m Model) (tea.Model, tea.Cmd) {
+			return m.queueWrite(perm, path, scope, deny)
+		})
+	}
+
+	switch {
+	case deny:
+		m.recordDeny(result)
+		if result.DryRun {
+			m.notify(toastInfo, "Dry run: would deny %s in %s", raw, result.FilePath)
+		} else {
+			m.notify(toastSuccess, "Denied: %s in %s", raw, result.FilePath)
+		}
+	case scope == "user":
+		if !result.DryRun {
+			m.userApproved = append(m.userApproved, raw)
+		}
+		m.recordApply("user", result)
+		m.setApplyToast(result)
+	default:
+		if !result.DryRun {
+			m.projectSettings[scope] = append(m.projectSettings[scope], raw)
+		}
+		m.recordApply("project", result)
+		m.setApplyToast(result)
+	}
+
+	switch {
+	case result.DryRun:
+		m.queueIdx++
+	case deny:
+		m.queueDecide(raw, state.QueueDeny, "denied")
+	default:
+		m.queueTally["allowed"]++
+		m.queueIdx++
+	}
+	return m, m.toastTickCmd()
+}
+
+// queueDecide records a decision for raw, saves it so the next run resumes
+// after it, and moves to the next permission
+func (m *Model) queueDecide(raw, decision, outcome string) {
+	m.state.Queue.Decide(raw, decision)
+	m.saveQueue()
+	m.queueTally[outcome]++
+	m.queueIdx++
+}
+
+// saveQueue saves the queue's progress. Like pins, it is not saved in demo
+// mode.
+func (m *Model) saveQueue() {
+	if m.demo {
+		return
+	}
+	if err := m.state.Save(); err != nil {
+		m.logger.Debug("saving state failed", "err", err)
+		m.notify(toastError, "Could not save review progress: %v", err)
+	}
+}
+
+// renderQueueModal renders the permission the review queue is showing, or
+// a summary once the pass is done
+func (m Model) renderQueueModal() string {
+	modalWidth := min(max(m.width*85/100, 50), 80)
+	inner := modalWidth - 8 // border + padding + indent
+
+	var b strings.Builder
+	b.WriteString(styles.ModalTitle.Render("Review Queue"))
+	b.WriteString("\n\n")
+
+	perm := m.queuePermission()
+	if perm == nil {
+		b.WriteString("  Done: " + m.queueTallyText() + "\n")
+		if m.queueTally["skipped"] > 0 {
+			b.WriteString(styles.HelpDesc.Render("  Skipped permissions come up again once nothing else is left.") + "\n")
+		}
+		b.WriteString("\n" + renderHints(m.contextBindings()))
+		return styles.Modal.Width(modalWidth).Render(b.String())
+	}
+
+	progress := fmt.Sprintf("  %d of %d", m.queueIdx+1, len(m.queue))
+	if tally := m.queueTallyText(); tally != "" {
+		progress += " · " + tally
+	}
+	b.WriteString(styles.HelpDesc.Render(truncateString(progress, inner+2)) + "\n\n")
+	b.WriteString("  " + styles.HelpKey.Render(truncateString(perm.Permission.Raw, inner)) + "\n\n")
+
+	field := func(label, value string) {
+		b.WriteString("  " + styles.HelpDesc.Render(fmt.Sprintf("%-11s", label)) + value + "\n")
+	}
+	field("Uses", fmt.Sprintf("%d  (%s approved, %s denied)", perm.Count,
+		styles.StatusApproved.Render(fmt.Sprint(perm.Approved)),
+		styles.Error.Render(fmt.Sprint(perm.Denied))))
+	field("Seen", formatRelativeTime(perm.FirstSeen)+" – "+formatRelativeTime(perm.LastSeen))
+	list := func(label string, rows []countRow) {
+		if len(rows) == 0 {
+			return
+		}
+		var parts []string
+		for _, r := range rows[:min(len(rows), queueListLimit)] {
+			parts = append(parts, fmt.Sprintf("%s (%d)", shortenPath(r.label), r.count))
+		}
+		if len(rows) > queueListLimit {
+			parts = append(parts, fmt.Sprintf("+%d more", len(rows)-queueListLimit))
+		}
+		field(label, truncateString(strings.Join(parts, ", "), inner-11))
+	}
+	list("Projects", sortedCounts(perm.ProjectCounts))
+	list("Agents", m.detailAgents(perm))
+	b.WriteString(m.renderNoteField(false, perm.Permission.Raw, inner))
+
+	if len(perm.Samples) > 0 {
+		b.WriteString("\n  " + styles.ListHeader.UnsetPaddingLeft().Render("Sample inputs") + "\n")
+		for _, s := range perm.Samples[:min(len(perm.Samples), queueListLimit)] {
+			b.WriteString("    " + truncateString(s, inner) + "\n")
+		}
+	}
+
+	b.WriteString("\n" + m.renderWriteModeNotice())
+	b.WriteString(renderHints(m.contextBindings()))
+
+	return styles.Modal.Width(modalWidth).Render(b.String())
+}
+
+// queueTallyText summarizes what this pass of the review queue did, e.g.
+// "2 allowed, 1 skipped"
+func (m Model) queueTallyText() string {
+	var parts []string
+	for _, outcome := range []string{"allowed", "denied", "ignored", "skipped"} {
+		if n := m.queueTally[outcome]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, outcome))
+		}
+	}
+	if len(parts) == 0 && m.queueIdx >= len(m.queue) {
+		return "nothing decided"
+	}
+	return strings.Join(parts, ", ")
+}
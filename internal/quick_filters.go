@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/types"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// quickFilter narrows the Frequency view to the permissions most reviews
+// are about, on top of the text filter
+type quickFilter int
+
+const (
+	quickRecent     quickFilter = iota // Used in the last recentWindow
+	quickUnapproved                    // No settings file allows it
+	quickDenied                        // Denied at least once
+	quickFilterCount
+)
+
+// recentWindow is how recently a permission must have been used for the
+// recent quick filter to keep it
+const recentWindow = 30 * 24 * time.Hour
+
+// quickFilterNames are the config names of the quick filters
+var quickFilterNames = [quickFilterCount]string{"recent", "unapproved", "denied"}
+
+// quickFilterLabels describe the quick filters in the filter bar and the
+// status bar
+var quickFilterLabels = [quickFilterCount]string{"last 30 days", "unapproved", "denied"}
+
+// parseQuickFilters turns config names into the quick filters to start with
+func parseQuickFilters(names []string) ([quickFilterCount]bool, error) {
+	var on [quickFilterCount]bool
+	for _, name := range names {
+		found := false
+		for f, n := range quickFilterNames {
+			if n == name {
+				on[f] = true
+				found = true
+			}
+		}
+		if !found {
+			return on, fmt.Errorf("unknown filter %q (want %s)", name, strings.Join(quickFilterNames[:], ", "))
+		}
+	}
+	return on, nil
+}
+
+// quickFilterKey returns the binding that toggles a quick filter
+func (k KeyMap) quickFilterKey(f quickFilter) key.Binding {
+	return [quickFilterCount]key.Binding{k.RecentOnly, k.UnapprovedOnly, k.DeniedOnly}[f]
+}
+
+// quickFiltering reports whether any quick filter is on
+func (m Model) quickFiltering() bool {
+	for _, on := range m.quickFilters {
+		if on {
+			return true
+		}
+	}
+	return false
+}
+
+// keepQuick reports whether p passes every quick filter that is on
+func (m Model) keepQuick(p types.PermissionStats) bool {
+	if m.quickFilters[quickRecent] && now().Sub(p.LastSeen) > recentWindow {
+		return false
+	}
+	if m.quickFilters[quickUnapproved] && p.ApprovedAt != types.NotApproved {
+		return false
+	}
+	if m.quickFilters[quickDenied] && p.Denied == 0 {
+		return false
+	}
+	return true
+}
+
+// toggleQuickFilter turns a quick filter on or off and regroups the
+// Frequency view
+func (m Model) toggleQuickFilter(f quickFilter) (tea.Model, tea.Cmd) {
+	m.quickFilters[f] = !m.quickFilters[f]
+	m.freqList.top()
+	m.regroupPermissions()
+
+	if m.quickFiltering() {
+		m.notify(toastInfo, "Showing %d permission(s): %s", m.listedPermissions(), m.quickFilterText())
+	} else {
+		m.notify(toastInfo, "Showing all permissions")
+	}
+	return m, m.toastTickCmd()
+}
+
+// quickFilterText lists the quick filters that are on, e.g. "last 30 days,
+// unapproved"
+func (m Model) quickFilterText() string {
+	var on []string
+	for f, label := range quickFilterLabels {
+		if m.quickFilters[f] {
+			on = append(on, label)
+		}
+	}
+	return strings.Join(on, ", ")
+}
+
+// quickFilterChip is one quick filter's toggle in the filter bar and the
+// columns it takes
+type quickFilterChip struct {
+	filter     quickFilter
+	text       string
+	start, end int
+}
+
+// quickFilterChips lays out the filter bar's toggles after its label
+func (m Model) quickFilterChips() []quickFilterChip {
+	x := ansi.StringWidth("  Only: ")
+	chips := make([]quickFilterChip, quickFilterCount)
+	for f := range quickFilterCount {
+		text := fmt.Sprintf("[%s] %s", primaryKey(m.keys.quickFilterKey(f)), quickFilterLabels[f])
+		chips[f] = quickFilterChip{filter: f, text: text, start: x, end: x + ansi.StringWidth(text)}
+		x += ansi.StringWidth(text) + 2
+	}
+	return chips
+}
+
+// renderQuickFilterBar renders the quick filters above the Frequency list
+// while any is on, the ones on highlighted. Clicking one toggles it.
+func (m Model) renderQuickFilterBar() string {
+	if !m.quickFiltering() {
+		return ""
+	}
+	var chips []string
+	for _, c := range m.quickFilterChips() {
+		if m.quickFilters[c.filter] {
+			chips = append(chips, styles.HelpKey.Render(c.text))
+		} else {
+			chips = append(chips, styles.HelpDesc.Render(c.text))
+		}
+	}
+	return styles.HelpDesc.Render("  Only:") + " " + strings.Join(chips, "  ")
+}
+
+// handleQuickFilterClick toggles the quick filter clicked in the filter bar
+func (m Model) handleQuickFilterClick(x int) (tea.Model, tea.Cmd) {
+	for _, c := range m.quickFilterChips() {
+		if x >= c.start && x < c.end {
+			return m.toggleQuickFilter(c.filter)
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,254 @@
+package internal
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/types"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/muesli/termenv"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// fixtureNow is the pinned clock of the rendering tests
+var fixtureNow = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+func TestMain(m *testing.M) {
+	// Render with colors, so styles that leak past the end of a line show up
+	// in the golden files, and with a clock and time zone that don't move
+	lipgloss.SetColorProfile(termenv.ANSI)
+	lipgloss.SetHasDarkBackground(true)
+	time.Local = time.UTC
+	now = func() time.Time { return fixtureNow }
+	os.Exit(m.Run())
+}
+
+// fixtureData is the deterministic data the rendering tests load
+func fixtureData() dataLoadedMsg {
+	ago := func(d time.Duration) time.Time { return fixtureNow.Add(-d) }
+	perm := func(raw string, approved, denied int, last time.Duration, projects ...string) types.PermissionStats {
+		counts := make(map[string]int, len(projects))
+		for _, p := range projects {
+			counts[p] = approved + denied
+		}
+		return types.PermissionStats{
+			Permission:    parser.ParsePermission(raw),
+			Count:         approved + denied,
+			Tokens:        (approved + denied) * 2300,
+			Approved:      approved,
+			Denied:        denied,
+			FirstSeen:     ago(30 * 24 * time.Hour),
+			LastSeen:      ago(last),
+			Projects:      projects,
+			ProjectCounts: counts,
+			Samples:       []string{strings.TrimSuffix(strings.TrimPrefix(raw, "Bash("), ":*)") + " --version"},
+		}
+	}
+
+	permissions := []types.PermissionStats{
+		perm("Read", 120, 0, time.Minute, "/work/app", "/work/api"),
+		perm("Bash(git:*)", 38, 2, 2*time.Hour, "/work/app"),
+		perm("Bash(npm:*)", 12, 0, 26*time.Hour, "/work/app"),
+		perm("WebFetch(domain:github.com)", 1, 4, 3*24*time.Hour, "/work/api"),
+		perm("mcp__github__create_issue", 3, 0, 10*24*time.Hour, "/work/api"),
+	}
+	// Enough Bash variants to scroll on short terminals
+	for i := 0; i < 20; i++ {
+		permissions = append(permissions, perm(fmt.Sprintf("Bash(tool%02d:*)", i), 10-i/2, i%3, time.Duration(i+1)*time.Hour, "/work/app"))
+	}
+
+	// Bash(tool03:*), often run from the home directory
+	permissions[8].Outside = 4
+	permissions[8].OutsideDirs = []string{"/nonexistent/home", "/"}
+	// Bash(git:*), partly run unprompted under bypassPermissions
+	permissions[1].AutoApproved = 6
+	permissions[1].Count += 6
+	permissions[1].Slowest = []types.SlowCall{
+		{Permission: "Bash(git:*)", Sample: "git push origin main", Duration: 84 * time.Second, Time: ago(2 * time.Hour)},
+		{Permission: "Bash(git:*)", Sample: "git fetch --all", Duration: 6200 * time.Millisecond, Time: ago(5 * time.Hour)},
+	}
+
+	userApproved := []string{"Read"}
+	projectSettings := map[string][]string{"/work/app": {"Bash(npm:*)"}}
+	for i := range permissions {
+		permissions[i].ApprovedAt = parser.GetProjectsApprovalLevel(permissions[i].Permission.Raw, permissions[i].Projects, userApproved, projectSettings)
+	}
+
+	// An Edit that left the workspace
+	edit := perm("Edit", 4, 0, time.Hour, "/work/app")
+	edit.OutsideWrites = []types.OutsideWrite{
+		{Project: "/work/app", File: "/nonexistent/home/.zshrc", Count: 3},
+		{Project: "/work/app", File: "/etc/hosts", Count: 1},
+	}
+	agentUsage := []types.AgentUsageStats{{
+		AgentType:   "Explore",
+		Permissions: []types.PermissionStats{perm("Read", 30, 0, time.Hour, "/work/app"), edit, perm("Bash(git:*)", 4, 0, time.Hour, "/work/app")},
+		TotalCalls:  38,
+		TotalTokens: 38 * 2300,
+		Slowest:     []types.SlowCall{{Permission: "Bash(git:*)", Sample: "git log --stat", Duration: 2300 * time.Millisecond, Time: ago(time.Hour)}},
+		LastSeen:    ago(time.Hour),
+		Sessions:    2,
+		Projects:    []string{"/work/app"},
+		Invocations: []types.AgentInvocation{
+			{AgentType: "Explore", Session: "5f1c2d3e-aaaa", Project: "/work/app", Time: ago(time.Hour), Description: "Find the config loader", Prompt: "Look through the repository for where the configuration file is read and list every option it accepts."},
+			{AgentType: "Explore", Session: "9b8a7c6d-bbbb", Project: "/work/app", Time: ago(50 * time.Hour), Description: "Map the test layout"},
+		},
+	}}
+
+	return dataLoadedMsg{
+		permissions:      permissions,
+		permissionGroups: parser.GroupPermissions(permissions),
+		agentUsage:       agentUsage,
+		userApproved:     userApproved,
+		projectSettings:  projectSettings,
+		permissionModes:  map[string]parser.PermissionMode{"/work/app": {Mode: parser.ModeAcceptEdits}},
+		sessionCount:     3,
+	}
+}
+
+// newTestModel returns a model of width x height with no user state, loaded
+// with the fixture data unless loading is set
+func newTestModel(t *testing.T, width, height int, loading bool) Model {
+	t.Helper()
+	// A home without a .claude directory, at a path the golden files can
+	// contain
+	t.Setenv("HOME", "/nonexistent/home")
+
+	m := NewModel()
+	m.projectPath = "/work/app"
+	m = send(m, tea.WindowSizeMsg{Width: width, Height: height})
+	if loading {
+		m.loadingStarted = fixtureNow.Add(-5 * time.Second)
+		return send(m, loadingProgressMsg{progress: parser.Progress{Stage: "Scanning sessions", Project: "/work/app", Session: "5f1c2d3e", Done: 3, Total: 10}})
+	}
+	return send(m, fixtureData())
+}
+
+// send updates the model with msg, dropping any command
+func send(m Model, msg tea.Msg) Model {
+	next, _ := m.Update(msg)
+	return next.(Model)
+}
+
+// keys turns key names into key messages: "enter", "tab", "esc", "ctrl+l"
+// or runes
+func keys(names ...string) []tea.KeyMsg {
+	msgs := make([]tea.KeyMsg, len(names))
+	for i, name := range names {
+		switch name {
+		case "enter":
+			msgs[i] = tea.KeyMsg{Type: tea.KeyEnter}
+		case "tab":
+			msgs[i] = tea.KeyMsg{Type: tea.KeyTab}
+		case "esc":
+			msgs[i] = tea.KeyMsg{Type: tea.KeyEsc}
+		case "ctrl+l":
+			msgs[i] = tea.KeyMsg{Type: tea.KeyCtrlL}
+		default:
+			msgs[i] = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(name)}
+		}
+	}
+	return msgs
+}
+
+func TestRenderGolden(t *testing.T) {
+	sizes := []struct{ width, height int }{{80, 24}, {100, 30}, {120, 40}}
+	screens := []struct {
+		name    string
+		loading bool
+		keys    []tea.KeyMsg
+	}{
+		{name: "loading", loading: true},
+		{name: "frequency"},
+		{name: "frequency-expanded", keys: keys("enter", "j")},
+		{name: "frequency-scrolled", keys: keys("enter", "G")},
+		{name: "detail", keys: keys("enter", "j", "enter")},
+		{name: "apply", keys: keys("enter", "j", "enter", "enter")},
+		{name: "matrix", keys: keys("tab")},
+		{name: "agent-modal", keys: keys("tab", "enter")},
+		{name: "agent-invocations", keys: keys("tab", "enter", "S")},
+		{name: "review-queue", keys: keys("I")},
+		{name: "staged", keys: keys("enter", "j", "m", "j", "m")},
+		{name: "staged-apply", keys: keys("m", "M")},
+		{name: "apply-here", keys: keys("enter", "j", "P")},
+		{name: "quick-filters", keys: keys("2", "3")},
+		{name: "columns", keys: keys("C", "j", "j", "j", " ")},
+		{name: "name-scroll", keys: keys("j", "j", "j", "l")},
+		{name: "count", keys: keys("enter", "3", "j", "z", "z")},
+		{name: "toast", keys: keys("M")},
+		{name: "notifications", keys: keys("M", "I", "esc", "ctrl+l")},
+		{name: "outside-writes", keys: keys("W")},
+		{name: "outside-files", keys: keys("W", "enter")},
+		{name: "templates", keys: keys("T")},
+		{name: "template-preview", keys: keys("T", "j", "j", "enter", "j")},
+		{name: "compare", keys: keys("X", "node frontend", "enter", "j", " ")},
+		{name: "pattern-tester", keys: keys("w")},
+		{name: "help", keys: keys("?")},
+	}
+
+	for _, size := range sizes {
+		for _, screen := range screens {
+			name := fmt.Sprintf("%s-%dx%d", screen.name, size.width, size.height)
+			t.Run(name, func(t *testing.T) {
+				m := newTestModel(t, size.width, size.height, screen.loading)
+				for _, k := range screen.keys {
+					m = send(m, k)
+				}
+				// Time out a count still waiting for its motion
+				m = send(m, countTimeoutMsg{seq: m.countSeq})
+				view := m.View()
+
+				checkFits(t, view, size.width, size.height)
+				checkGolden(t, name, view)
+			})
+		}
+	}
+}
+
+// checkFits fails if a rendered line is wider than the terminal, or there
+// are more lines than it is tall
+func checkFits(t *testing.T, view string, width, height int) {
+	t.Helper()
+	lines := strings.Split(view, "\n")
+	if len(lines) > height {
+		t.Errorf("rendered %d lines on a %d-line terminal", len(lines), height)
+	}
+	for i, line := range lines {
+		if w := ansi.StringWidth(line); w > width {
+			t.Errorf("line %d is %d cells wide on a %d-cell terminal: %q", i+1, w, width, ansi.Strip(line))
+		}
+	}
+}
+
+// checkGolden compares view with testdata/golden/<name>.golden, or rewrites
+// the file with -update
+func checkGolden(t *testing.T, name, view string) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(view), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test ./internal -run TestRenderGolden -update to create it)", err)
+	}
+	if view != string(want) {
+		t.Errorf("%s differs from the golden file; rerun with -update if the change is intended\n--- got\n%s\n--- want\n%s", name, ansi.Strip(view), ansi.Strip(string(want)))
+	}
+}
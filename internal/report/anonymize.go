@@ -0,0 +1,198 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/server"
+	"github.com/b-open-io/claude-perms/internal/types"
+)
+
+// pathPattern matches the path-like words of a permission, rule or message:
+// runs of characters around a slash, up to quotes, brackets, spaces, shell
+// operators and the colon of a Bash prefix rule
+var pathPattern = regexp.MustCompile(`[^\s'"(),;|&<>=:]*/[^\s'"(),;|&<>=:]*`)
+
+// anonymizer replaces paths and host names with salted hashes, mapping equal
+// inputs to equal outputs so the data keeps its structure
+type anonymizer struct {
+	salt string
+}
+
+// hash returns a short salted hash of s
+func (a anonymizer) hash(s string) string {
+	sum := sha256.Sum256([]byte(a.salt + "\x00" + s))
+	return hex.EncodeToString(sum[:4])
+}
+
+// segment hashes one path component, keeping ".", "..", "~", globs and a
+// short file extension, so Read(//x/**) and *.go rules stay recognizable
+func (a anonymizer) segment(seg string) string {
+	switch {
+	case seg == "", seg == ".", seg == "..", seg == "~", strings.ContainsAny(seg, "*?["), strings.HasPrefix(seg, "$"):
+		return seg
+	}
+	if ext := path.Ext(seg); len(ext) > 1 && len(ext) <= 6 && len(ext) < len(seg) {
+		return a.hash(strings.TrimSuffix(seg, ext)) + ext
+	}
+	return a.hash(seg)
+}
+
+// path hashes every component of p, so paths that share a parent still do
+func (a anonymizer) path(p string) string {
+	if p == "" {
+		return ""
+	}
+	segs := strings.Split(p, "/")
+	for i, seg := range segs {
+		segs[i] = a.segment(seg)
+	}
+	return strings.Join(segs, "/")
+}
+
+// text hashes the paths in a permission, rule or message, leaving the rest
+func (a anonymizer) text(s string) string {
+	return pathPattern.ReplaceAllStringFunc(s, a.path)
+}
+
+// texts applies text to each of ss
+func (a anonymizer) texts(ss []string) []string {
+	return mapStrings(ss, a.text)
+}
+
+// mapStrings returns f applied to each of ss
+func mapStrings(ss []string, f func(string) string) []string {
+	if ss == nil {
+		return nil
+	}
+	out := make([]string, len(ss))
+	for i, s := range ss {
+		out[i] = f(s)
+	}
+	return out
+}
+
+// slowCalls hashes the permissions of calls and drops their samples, which
+// are free text like the permissions' samples
+func (a anonymizer) slowCalls(calls []types.SlowCall) []types.SlowCall {
+	if calls == nil {
+		return nil
+	}
+	out := make([]types.SlowCall, len(calls))
+	for i, c := range calls {
+		c.Permission = a.text(c.Permission)
+		c.Sample = ""
+		out[i] = c
+	}
+	return out
+}
+
+// outsideWrites hashes the project and file paths of writes
+func (a anonymizer) outsideWrites(writes []types.OutsideWrite) []types.OutsideWrite {
+	if writes == nil {
+		return nil
+	}
+	out := make([]types.OutsideWrite, len(writes))
+	for i, w := range writes {
+		out[i] = types.OutsideWrite{Project: a.path(w.Project), File: a.path(w.File), Count: w.Count}
+	}
+	return out
+}
+
+// counts hashes the keys of a count map with key
+func counts(m map[string]int, key func(string) string) map[string]int {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]int, len(m))
+	for k, n := range m {
+		out[key(k)] += n
+	}
+	return out
+}
+
+// Anonymize returns a copy of d that can be shared in a bug report or a
+// security review: project and settings paths, paths inside permissions and
+// rules, and host names are replaced by hashes salted with salt, while
+// counts, dates, tool types and the shape of every path are kept. Samples,
+// including those of the slowest calls, inputs and notes are dropped, as free text can't be scrubbed reliably. The same
+// salt gives the same hashes, so anonymized exports can be compared.
+func Anonymize(d *Data, salt string) *Data {
+	a := anonymizer{salt: salt}
+	host := func(h string) string { return "host-" + a.hash(h) }
+
+	snap := *d.Snapshot
+	snap.Permissions = make([]server.Permission, len(d.Permissions))
+	for i, p := range d.Permissions {
+		p.Permission = a.text(p.Permission)
+		p.Scope = a.text(p.Scope)
+		p.Projects = counts(p.Projects, a.path)
+		p.Hosts = counts(p.Hosts, host)
+		p.Samples = nil
+		p.Inputs = nil
+		p.Slowest = a.slowCalls(p.Slowest)
+		p.OutsideWrites = a.outsideWrites(p.OutsideWrites)
+		p.Note = ""
+		snap.Permissions[i] = p
+	}
+
+	snap.Agents = make([]server.Agent, len(d.Agents))
+	for i, ag := range d.Agents {
+		ag.Projects = mapStrings(ag.Projects, a.path)
+		ag.Permissions = append([]server.AgentPermission(nil), ag.Permissions...)
+		for j := range ag.Permissions {
+			ag.Permissions[j].Permission = a.text(ag.Permissions[j].Permission)
+		}
+		ag.Declared = a.texts(ag.Declared)
+		ag.Allow = a.texts(ag.Allow)
+		ag.Deny = a.texts(ag.Deny)
+		ag.Undeclared = a.texts(ag.Undeclared)
+		ag.Slowest = a.slowCalls(ag.Slowest)
+		ag.Note = ""
+		snap.Agents[i] = ag
+	}
+
+	snap.Skills = make([]server.Skill, len(d.Skills))
+	for i, sk := range d.Skills {
+		sk.Declared = a.texts(sk.Declared)
+		sk.Allow = a.texts(sk.Allow)
+		sk.Deny = a.texts(sk.Deny)
+		snap.Skills[i] = sk
+	}
+
+	snap.Projects = make([]server.Project, len(d.Projects))
+	for i, p := range d.Projects {
+		p.Path = a.path(p.Path)
+		p.Settings = a.path(p.Settings)
+		p.AllowRules = a.texts(p.AllowRules)
+		snap.Projects[i] = p
+	}
+
+	snap.Warnings = make([]parser.Warning, len(d.Warnings))
+	for i, w := range d.Warnings {
+		w.File = a.path(w.File)
+		w.Reason = a.text(w.Reason)
+		snap.Warnings[i] = w
+	}
+
+	return &Data{
+		Snapshot:     &snap,
+		UserSettings: a.path(d.UserSettings),
+		UserAllow:    a.texts(d.UserAllow),
+		UserDeny:     a.texts(d.UserDeny),
+	}
+}
+
+// WriteJSON writes d as indented JSON, the same shape the web dashboard's
+// API serves plus the user settings
+func WriteJSON(w io.Writer, d *Data) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
@@ -0,0 +1,48 @@
+package report
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+	"time"
+)
+
+//go:embed report.html
+var htmlSource string
+
+// topPermissions is how many permissions the usage chart shows
+const topPermissions = 25
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Local().Format("2006-01-02 15:04")
+	},
+	// percent scales n against the largest value in a chart, for bar widths
+	"percent": func(n, max int) float64 {
+		if max == 0 {
+			return 0
+		}
+		return float64(n) * 100 / float64(max)
+	},
+}).Parse(htmlSource))
+
+// WriteHTML writes d as a self-contained HTML page: no scripts and no
+// external resources, so it can be mailed or attached to a review as-is
+func WriteHTML(w io.Writer, d *Data) error {
+	top := d.Top(topPermissions)
+	maxCount := 0
+	if len(top) > 0 {
+		maxCount = top[0].Count
+	}
+	return htmlTemplate.Execute(w, map[string]any{
+		"Data":     d,
+		"Totals":   d.Totals(),
+		"Coverage": d.Coverage(),
+		"Top":      top,
+		"MaxCount": maxCount,
+		"Denials":  d.Denials(),
+	})
+}
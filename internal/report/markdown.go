@@ -0,0 +1,157 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/server"
+)
+
+// MarkdownOptions tunes a Markdown report
+type MarkdownOptions struct {
+	Top     int       // How many of the most used permissions to list
+	Since   time.Time // List permissions first used at or after this; zero leaves the section out
+	Project string    // Project whose shared settings the allow rules are proposed for
+	MinUses int       // Uses in the project a permission needs before it is proposed
+}
+
+// Proposal is the allow rules proposed for a project's shared settings
+// (.claude/settings.json), with the change they make to it
+type Proposal struct {
+	Path  string
+	New   bool // The settings file doesn't exist yet
+	Rules []server.Permission
+	Diff  []parser.DiffLine
+}
+
+// Propose picks the permissions worth allowing in project's shared
+// settings: used there at least minUses times, never denied, and matched by
+// no allow, ask or deny rule in any settings layer the project reads
+func (d *Data) Propose(project string, minUses int) (Proposal, error) {
+	p := Proposal{Path: filepath.Join(project, ".claude", "settings.json")}
+	layers, _ := parser.LoadSettingsLayers(project)
+	for _, perm := range d.Permissions {
+		if perm.Projects[parser.CanonicalProject(project)] < minUses || perm.Denied > 0 || ruled(perm.Permission, layers) {
+			continue
+		}
+		p.Rules = append(p.Rules, perm)
+	}
+	if len(p.Rules) == 0 {
+		return p, nil
+	}
+	sort.SliceStable(p.Rules, func(i, j int) bool {
+		return p.Rules[i].Permission < p.Rules[j].Permission
+	})
+
+	rules := make([]string, 0, len(p.Rules))
+	for _, r := range p.Rules {
+		rules = append(rules, r.Permission)
+	}
+	before, after, err := parser.MergePermissions(p.Path, rules)
+	if err != nil {
+		return p, err
+	}
+	p.New = len(before) == 0
+	p.Diff = parser.DiffContents(before, after)
+	return p, nil
+}
+
+// ruled reports whether any rule in layers matches perm
+func ruled(perm string, layers []parser.LayerRules) bool {
+	for _, l := range layers {
+		for _, rules := range [][]string{l.Allow, l.Ask, l.Deny} {
+			if len(parser.MatchingRules(perm, rules)) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WriteMarkdown writes a short summary of d for a pull request that
+// updates a project's permission settings: the headline numbers, the most
+// used permissions, those first seen since opts.Since, and the proposed
+// allow rules as a diff
+func WriteMarkdown(w io.Writer, d *Data, opts MarkdownOptions) error {
+	var b strings.Builder
+	t := d.Totals()
+	allowed := 0.0
+	for _, c := range d.Coverage() {
+		if c.Level != "none" {
+			allowed += c.Share
+		}
+	}
+	b.WriteString("## Claude Code permission usage\n\n")
+	fmt.Fprintf(&b, "%d tool calls across %d permissions in %d projects, %d denied and %d run without a prompt by the permission mode. Settings allow %.0f%% of calls.\n",
+		t.Calls, t.Permissions, t.Projects, t.Denied, t.Auto, allowed)
+
+	b.WriteString("\n### Most used\n\n")
+	writeMarkdownTable(&b, d.Top(opts.Top), func(p server.Permission) string {
+		return fmt.Sprintf("%d | %d | %d | %s", p.Count, p.AutoApproved, p.Denied, p.Approval)
+	}, "Uses | Auto | Denied | Allowed in", "---: | ---: | ---: | ---")
+
+	if !opts.Since.IsZero() {
+		fmt.Fprintf(&b, "\n### New since %s\n\n", opts.Since.Local().Format("2006-01-02"))
+		writeMarkdownTable(&b, d.FirstSeenSince(opts.Since), func(p server.Permission) string {
+			return fmt.Sprintf("%s | %d | %s", p.FirstSeen.Local().Format("2006-01-02"), p.Count, p.Approval)
+		}, "First used | Uses | Allowed in", "--- | ---: | ---")
+	}
+
+	if opts.Project != "" {
+		proposal, err := d.Propose(opts.Project, opts.MinUses)
+		if err != nil {
+			return err
+		}
+		b.WriteString("\n### Proposed allow rules\n\n")
+		if len(proposal.Rules) == 0 {
+			fmt.Fprintf(&b, "_Nothing to propose for `%s`: no permission used there at least %d times is both never denied and not yet covered by a rule._\n", opts.Project, opts.MinUses)
+		} else {
+			fmt.Fprintf(&b, "Used at least %d times in `%s`, never denied, and not covered by any settings rule:\n\n", opts.MinUses, opts.Project)
+			from := proposal.Path
+			if proposal.New {
+				from = "/dev/null"
+			}
+			fmt.Fprintf(&b, "```diff\n--- %s\n+++ %s\n", from, proposal.Path)
+			for _, l := range proposal.Diff {
+				fmt.Fprintf(&b, "%c%s\n", l.Status, l.Text)
+			}
+			b.WriteString("```\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// FirstSeenSince returns the permissions first used at or after since,
+// newest first
+func (d *Data) FirstSeenSince(since time.Time) []server.Permission {
+	var perms []server.Permission
+	for _, p := range d.Permissions {
+		if !p.FirstSeen.Before(since) {
+			perms = append(perms, p)
+		}
+	}
+	sort.SliceStable(perms, func(i, j int) bool {
+		return perms[i].FirstSeen.After(perms[j].FirstSeen)
+	})
+	return perms
+}
+
+// writeMarkdownTable writes perms as a table with the permission first;
+// cells renders the remaining columns of a row
+func writeMarkdownTable(b *strings.Builder, perms []server.Permission, cells func(server.Permission) string, header, align string) {
+	if len(perms) == 0 {
+		b.WriteString("_None._\n")
+		return
+	}
+	fmt.Fprintf(b, "| Permission | %s |\n| --- | %s |\n", header, align)
+	for _, p := range perms {
+		fmt.Fprintf(b, "| `%s` | %s |\n", strings.ReplaceAll(p.Permission, "|", `\|`), cells(p))
+	}
+}
@@ -0,0 +1,108 @@
+// Package report renders permission usage as a standalone document for
+// people who won't run the TUI, such as security reviewers. It reads the
+// same data as the web dashboard.
+package report
+
+import (
+	"sort"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/server"
+)
+
+// Data is everything a report covers, loaded at one point in time
+type Data struct {
+	*server.Snapshot
+	UserSettings string   `json:"user_settings"` // Path of the user settings file
+	UserAllow    []string `json:"user_allow"`    // Its allow rules
+	UserDeny     []string `json:"user_deny"`     // Its deny rules
+}
+
+// Load scans the session logs and settings files for a report
+func Load() (*Data, error) {
+	snap, err := server.Load()
+	if err != nil {
+		return nil, err
+	}
+	d := &Data{Snapshot: snap, UserSettings: parser.UserSettingsPath()}
+	d.UserAllow, d.UserDeny, err = parser.ReadSettingsRules(d.UserSettings)
+	if err != nil {
+		d.Warnings = append(d.Warnings, parser.Warning{File: d.UserSettings, Reason: "unreadable settings: " + err.Error()})
+	}
+	return d, nil
+}
+
+// Coverage is how much observed usage the settings allow at one level
+type Coverage struct {
+	Level       string // "user", "project" or "none"
+	Permissions int
+	Calls       int
+	Share       float64 // Of all calls, 0-100
+}
+
+// Coverage breaks the observed permissions down by where settings allow
+// them, most permissive level first
+func (d *Data) Coverage() []Coverage {
+	levels := []Coverage{{Level: "user"}, {Level: "project"}, {Level: "none"}}
+	total := 0
+	for _, p := range d.Permissions {
+		for i := range levels {
+			if levels[i].Level == p.Approval {
+				levels[i].Permissions++
+				levels[i].Calls += p.Count
+			}
+		}
+		total += p.Count
+	}
+	if total > 0 {
+		for i := range levels {
+			levels[i].Share = float64(levels[i].Calls) * 100 / float64(total)
+		}
+	}
+	return levels
+}
+
+// Totals are the headline numbers of a report
+type Totals struct {
+	Permissions int
+	Calls       int
+	Denied      int
+	Auto        int // Calls the permission mode ran without a prompt
+	Agents      int
+	Projects    int
+}
+
+// Totals sums the snapshot
+func (d *Data) Totals() Totals {
+	t := Totals{Permissions: len(d.Permissions), Agents: len(d.Agents), Projects: len(d.Projects)}
+	for _, p := range d.Permissions {
+		t.Calls += p.Count
+		t.Denied += p.Denied
+		t.Auto += p.AutoApproved
+	}
+	return t
+}
+
+// Top returns the n most used permissions
+func (d *Data) Top(n int) []server.Permission {
+	perms := append([]server.Permission(nil), d.Permissions...)
+	sort.SliceStable(perms, func(i, j int) bool {
+		return perms[i].Count > perms[j].Count
+	})
+	return perms[:min(n, len(perms))]
+}
+
+// Denials returns the permissions the user denied at least once, most
+// denied first
+func (d *Data) Denials() []server.Permission {
+	var perms []server.Permission
+	for _, p := range d.Permissions {
+		if p.Denied > 0 {
+			perms = append(perms, p)
+		}
+	}
+	sort.SliceStable(perms, func(i, j int) bool {
+		return perms[i].Denied > perms[j].Denied
+	})
+	return perms
+}
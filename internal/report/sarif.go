@@ -0,0 +1,147 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+
+	"github.com/b-open-io/claude-perms/internal/insights"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifInfoURI = "https://github.com/b-open-io/claude-perms"
+)
+
+// The subset of SARIF 2.1.0 that code-scanning dashboards read
+type (
+	sarifLog struct {
+		Version string     `json:"version"`
+		Schema  string     `json:"$schema"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID                   string       `json:"id"`
+		Name                 string       `json:"name"`
+		ShortDescription     sarifMessage `json:"shortDescription"`
+		FullDescription      sarifMessage `json:"fullDescription"`
+		DefaultConfiguration struct {
+			Level string `json:"level"`
+		} `json:"defaultConfiguration"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifResult struct {
+		RuleID     string          `json:"ruleId"`
+		RuleIndex  int             `json:"ruleIndex"`
+		Level      string          `json:"level"`
+		Message    sarifMessage    `json:"message"`
+		Locations  []sarifLocation `json:"locations,omitempty"`
+		Properties map[string]any  `json:"properties,omitempty"`
+	}
+	sarifLocation struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI string `json:"uri"`
+			} `json:"artifactLocation"`
+			Region *sarifRegion `json:"region,omitempty"`
+		} `json:"physicalLocation"`
+	}
+	sarifRegion struct {
+		StartLine int `json:"startLine"`
+	}
+)
+
+// WriteSARIF writes risks as a SARIF 2.1.0 log with a result per risk, so
+// security tooling can show them next to other code-scanning findings.
+// Results point at the settings file and line for settings rules, and at
+// the latest session log for tool uses.
+func WriteSARIF(w io.Writer, risks []insights.Risk) error {
+	driver := sarifDriver{Name: "perms", InformationURI: sarifInfoURI}
+	index := make(map[string]int)
+	for i, r := range insights.RiskRules() {
+		rule := sarifRule{
+			ID:               r.ID,
+			Name:             r.Name,
+			ShortDescription: sarifMessage{Text: r.Name},
+			FullDescription:  sarifMessage{Text: r.Description},
+		}
+		rule.DefaultConfiguration.Level = string(r.Severity)
+		driver.Rules = append(driver.Rules, rule)
+		index[r.ID] = i
+	}
+
+	results := make([]sarifResult, 0, len(risks))
+	for _, r := range risks {
+		result := sarifResult{
+			RuleID:    r.Rule.ID,
+			RuleIndex: index[r.Rule.ID],
+			Level:     string(r.Rule.Severity),
+			Message:   sarifMessage{Text: riskMessage(r)},
+			Properties: map[string]any{
+				"permission": r.Permission,
+			},
+		}
+		if r.Project != "" {
+			result.Properties["project"] = r.Project
+		}
+		if r.Count > 0 {
+			result.Properties["count"] = r.Count
+			result.Properties["lastSeen"] = r.LastSeen.UTC()
+		}
+		if r.File != "" {
+			var loc sarifLocation
+			loc.PhysicalLocation.ArtifactLocation.URI = fileURI(r.File)
+			if r.Line > 0 {
+				loc.PhysicalLocation.Region = &sarifRegion{StartLine: r.Line}
+			}
+			result.Locations = []sarifLocation{loc}
+		}
+		results = append(results, result)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	})
+}
+
+// riskMessage describes one risk for a SARIF result
+func riskMessage(r insights.Risk) string {
+	if r.Sample == "" {
+		return fmt.Sprintf("Settings allow %s without prompting", r.Permission)
+	}
+	msg := fmt.Sprintf("%s: %s", r.Permission, r.Sample)
+	if r.Count > 1 {
+		msg += fmt.Sprintf(" (%d uses)", r.Count)
+	}
+	if r.Project != "" {
+		msg += " in " + r.Project
+	}
+	return msg
+}
+
+// fileURI turns an absolute path into a file:// URI
+func fileURI(path string) string {
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+	return u.String()
+}
@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/b-open-io/claude-perms/internal/state"
+	"github.com/b-open-io/claude-perms/internal/types"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// toggleSinceReview switches the Frequency view between every permission
+// and only those that changed since the last review
+func (m Model) toggleSinceReview() (tea.Model, tea.Cmd) {
+	if !m.state.Review.Done() {
+		m.notify(toastInfo, "No review yet: press %s to mark the current counts as reviewed", primaryKey(m.keys.MarkReviewed))
+		return m, m.toastTickCmd()
+	}
+
+	m.sinceReview = !m.sinceReview
+	m.freqList.top()
+	m.regroupPermissions()
+
+	if m.sinceReview {
+		m.notify(toastInfo, "Showing %d permission(s) changed since the review %s", m.listedPermissions(), formatRelativeTime(m.state.Review.At))
+	} else {
+		m.notify(toastInfo, "Showing all permissions")
+	}
+	return m, m.toastTickCmd()
+}
+
+// markReviewed records every permission's current counts as the review
+// watermark. Like pins, the watermark is not saved in demo mode.
+func (m Model) markReviewed() (tea.Model, tea.Cmd) {
+	counts := make(map[string]state.ReviewCount, len(m.permissions))
+	for _, p := range m.permissions {
+		counts[p.Permission.Raw] = state.ReviewCount{Approved: p.Approved, Denied: p.Denied}
+	}
+	m.state.Review.Mark(now(), counts)
+	if m.sinceReview {
+		m.regroupPermissions()
+		m.freqList.top()
+	}
+
+	m.notify(toastSuccess, "Marked %d permission(s) reviewed; press %s to see only what changes", len(counts), primaryKey(m.keys.SinceReview))
+	if !m.demo {
+		if err := m.state.Save(); err != nil {
+			m.logger.Debug("saving state failed", "err", err)
+			m.notify(toastError, "Could not save review: %v", err)
+		}
+	}
+	return m, m.toastTickCmd()
+}
+
+// listedPermissions counts the permissions the Frequency view lists, which
+// with the since-review filter on are the ones that changed
+func (m Model) listedPermissions() int {
+	n := 0
+	for _, g := range m.permissionGroups {
+		n += len(g.Children)
+	}
+	return n
+}
+
+// reviewDelta labels how a permission changed since the last review: "new",
+// or its change in uses such as "+12"
+func (m Model) reviewDelta(p types.PermissionStats) string {
+	if _, ok := m.state.Review.Counts[p.Permission.Raw]; !ok {
+		return "new"
+	}
+	delta, _ := m.state.Review.Since(p.Permission.Raw, p.Approved, p.Denied)
+	return fmt.Sprintf("%+d", delta)
+}
@@ -0,0 +1,89 @@
+package internal
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// searching reports whether n and N move between filter matches: in the
+// Frequency view while a filter is applied
+func (m Model) searching() bool {
+	return m.activeView == ViewFrequency && m.filterInput.Value() != ""
+}
+
+// filterMatch is the position of a permission the filter matches
+type filterMatch struct {
+	group, child int
+}
+
+// before reports whether the match comes before the row at group and child
+// in the Frequency list, a group's own row (child -1) before its permissions
+func (f filterMatch) before(group, child int) bool {
+	return f.group < group || f.group == group && f.child < child
+}
+
+// after reports whether the match comes after row in the Frequency list
+func (f filterMatch) after(row filterMatch) bool {
+	return !f.before(row.group, row.child) && f != row
+}
+
+// filterMatches returns the permissions the filter matches, in list order,
+// including those of collapsed groups
+func (m Model) filterMatches() []filterMatch {
+	query := m.filterInput.Value()
+	var matches []filterMatch
+	for gi, g := range m.permissionGroups {
+		for ci, p := range g.Children {
+			if containsIgnoreCase(p.Permission.Raw, query) {
+				matches = append(matches, filterMatch{gi, ci})
+			}
+		}
+	}
+	return matches
+}
+
+// nextMatch moves the cursor count matches forward or back, expanding the
+// group of the match it stops on. Like vim's n and N it wraps around the
+// list, saying so.
+func (m Model) nextMatch(forward bool, count int) (tea.Model, tea.Cmd) {
+	matches := m.filterMatches()
+	if len(matches) == 0 {
+		m.notify(toastInfo, "No permission matches %q", m.filterInput.Value())
+		return m, m.toastTickCmd()
+	}
+
+	var at filterMatch
+	at.group, at.child = m.freqCursor()
+	wrapped := false
+	for range max(count, 1) {
+		i := 0
+		if forward {
+			for i < len(matches) && !matches[i].after(at) {
+				i++
+			}
+			if i == len(matches) {
+				i, wrapped = 0, true
+			}
+		} else {
+			i = len(matches) - 1
+			for i >= 0 && !matches[i].before(at.group, at.child) {
+				i--
+			}
+			if i < 0 {
+				i, wrapped = len(matches)-1, true
+			}
+		}
+		at = matches[i]
+	}
+	m.permissionGroups[at.group].Expanded = true
+	m.selectFreqRow(at.group, at.child)
+
+	if wrapped && forward {
+		m.notify(toastInfo, "Search wrapped to the top")
+		return m, m.toastTickCmd()
+	}
+	if wrapped {
+		m.notify(toastInfo, "Search wrapped to the bottom")
+		return m, m.toastTickCmd()
+	}
+	return m, nil
+}
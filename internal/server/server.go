@@ -0,0 +1,378 @@
+// Package server serves permission stats as a JSON API and a small web
+// dashboard, for viewing permission posture from a browser without a TTY.
+// It only reads: nothing it serves can change a settings file.
+package server
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/insights"
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/state"
+	"github.com/b-open-io/claude-perms/internal/types"
+)
+
+//go:embed index.html
+var indexHTML []byte
+
+// refreshInterval is how long loaded data is served before the logs are
+// rescanned. Rescans read the stats cache, so they are cheap.
+const refreshInterval = time.Minute
+
+// Permission is one row of /api/permissions
+type Permission struct {
+	Permission    string               `json:"permission"`
+	Type          string               `json:"type"`
+	Scope         string               `json:"scope,omitempty"`
+	Count         int                  `json:"count"`
+	Tokens        int                  `json:"tokens"` // Tokens of the messages that made the calls
+	Approved      int                  `json:"approved"`
+	Denied        int                  `json:"denied"`
+	AutoApproved  int                  `json:"auto_approved,omitempty"` // Calls the session's permission mode ran without a prompt
+	FirstSeen     time.Time            `json:"first_seen"`
+	LastSeen      time.Time            `json:"last_seen"`
+	Approval      string               `json:"approval"`        // "user", "project" or "none"
+	Projects      map[string]int       `json:"projects"`        // Uses per project path
+	Hosts         map[string]int       `json:"hosts,omitempty"` // Uses per host, when hosts are configured
+	Samples       []string             `json:"samples,omitempty"`
+	Inputs        map[string]int       `json:"inputs,omitempty"`         // Uses per query or pattern, for tools whose rules take no scope
+	Slowest       []types.SlowCall     `json:"slowest,omitempty"`        // Longest-running calls, durations in nanoseconds
+	Outside       int                  `json:"outside,omitempty"`        // Bash calls run outside the project directory
+	OutsideWrites []types.OutsideWrite `json:"outside_writes,omitempty"` // Files Write and Edit calls modified outside their project
+	Note          string               `json:"note,omitempty"`           // Annotation added in the TUI
+}
+
+// AgentPermission is one permission an agent used
+type AgentPermission struct {
+	Permission string `json:"permission"`
+	Count      int    `json:"count"`
+}
+
+// Agent is one row of /api/agents
+type Agent struct {
+	Agent       string            `json:"agent"`
+	TotalCalls  int               `json:"total_calls"`
+	TotalTokens int               `json:"total_tokens"`
+	Sessions    int               `json:"sessions"`
+	LastSeen    time.Time         `json:"last_seen"`
+	Projects    []string          `json:"projects"`
+	Permissions []AgentPermission `json:"permissions"`
+	Declared    []string          `json:"declared,omitempty"`       // Tools in the agent's definition, if found
+	Allow       []string          `json:"declared_allow,omitempty"` // Its permissions.allow rules
+	Deny        []string          `json:"declared_deny,omitempty"`  // Its permissions.deny rules
+	Undeclared  []string          `json:"undeclared,omitempty"`     // Used, but covered by neither the tools nor the allow rules
+	Slowest     []types.SlowCall  `json:"slowest,omitempty"`        // Longest-running calls, durations in nanoseconds
+	Note        string            `json:"note,omitempty"`           // Annotation added in the TUI
+}
+
+// Skill is one row of /api/skills
+type Skill struct {
+	Skill    string   `json:"skill"`
+	Plugin   string   `json:"plugin,omitempty"`
+	Version  string   `json:"version,omitempty"`
+	Declared []string `json:"declared"`
+	Allow    []string `json:"declared_allow,omitempty"` // permissions.allow rules
+	Deny     []string `json:"declared_deny,omitempty"`  // permissions.deny rules
+}
+
+// Project is one row of /api/projects
+type Project struct {
+	Path        string   `json:"path"`
+	Calls       int      `json:"calls"`
+	Permissions int      `json:"permissions"` // Distinct permissions used
+	Unapproved  int      `json:"unapproved"`  // Of those, how many neither settings file allows
+	AllowRules  []string `json:"allow_rules"` // The project's own allow rules
+	Settings    string   `json:"settings"`
+}
+
+// Snapshot is everything the server serves, loaded at one point in time
+type Snapshot struct {
+	LoadedAt    time.Time        `json:"loaded_at"`
+	Permissions []Permission     `json:"permissions"`
+	Agents      []Agent          `json:"agents"`
+	Skills      []Skill          `json:"skills"`
+	Projects    []Project        `json:"projects"`
+	Warnings    []parser.Warning `json:"warnings"`
+}
+
+// Load scans the session logs and settings files into a snapshot
+func Load() (*Snapshot, error) {
+	parser.TakeWarnings()
+
+	stats, err := parser.LoadAllPermissionStatsWithCache(nil)
+	if err != nil {
+		return nil, err
+	}
+	var settingsWarnings []parser.Warning
+	userApproved, err := parser.LoadUserSettings()
+	if err != nil {
+		settingsWarnings = append(settingsWarnings, parser.Warning{File: parser.UserSettingsPath(), Reason: "unreadable settings: " + err.Error()})
+	}
+	projectSettings, warns := parser.LoadDiscoveredProjectSettings()
+	settingsWarnings = append(settingsWarnings, warns...)
+	agentUsage, _ := parser.LoadAgentUsageStats(nil)
+	agents, _ := parser.LoadAllAgents()
+	skills, _ := parser.LoadAllSkills()
+
+	warnings, _ := parser.TakeWarnings()
+	saved, err := state.Load()
+	if err != nil {
+		warnings = append(warnings, parser.Warning{File: state.Path(), Reason: "unreadable notes: " + err.Error()})
+	}
+	notes := saved.Notes
+	s := &Snapshot{
+		LoadedAt:    time.Now(),
+		Permissions: make([]Permission, 0, len(stats)),
+		Agents:      make([]Agent, 0, len(agentUsage)),
+		Skills:      make([]Skill, 0, len(skills)),
+		Projects:    []Project{},
+		Warnings:    append(settingsWarnings, warnings...),
+	}
+
+	projects := make(map[string]*Project)
+	for _, st := range stats {
+		level := parser.GetProjectsApprovalLevel(st.Permission.Raw, st.Projects, userApproved, projectSettings)
+		s.Permissions = append(s.Permissions, Permission{
+			Permission:    st.Permission.Raw,
+			Type:          st.Permission.Type,
+			Scope:         st.Permission.Scope,
+			Count:         st.Count,
+			Tokens:        st.Tokens,
+			Approved:      st.Approved,
+			Denied:        st.Denied,
+			AutoApproved:  st.AutoApproved,
+			FirstSeen:     st.FirstSeen,
+			LastSeen:      st.LastSeen,
+			Approval:      approvalName(level),
+			Projects:      st.ProjectCounts,
+			Hosts:         st.HostCounts,
+			Samples:       st.Samples,
+			Inputs:        st.Inputs,
+			Slowest:       st.Slowest,
+			Outside:       st.Outside,
+			OutsideWrites: st.OutsideWrites,
+			Note:          notes.Permission(st.Permission.Raw),
+		})
+
+		for path, n := range st.ProjectCounts {
+			p := projects[path]
+			if p == nil {
+				p = &Project{
+					Path:       path,
+					AllowRules: projectSettings[path],
+					Settings:   parser.ProjectSettingsPath(path),
+				}
+				projects[path] = p
+			}
+			p.Calls += n
+			p.Permissions++
+			if parser.GetApprovalLevel(st.Permission.Raw, userApproved, projectSettings[path]) == types.NotApproved {
+				p.Unapproved++
+			}
+		}
+	}
+	for _, p := range projects {
+		s.Projects = append(s.Projects, *p)
+	}
+	sort.Slice(s.Projects, func(i, j int) bool {
+		if s.Projects[i].Calls != s.Projects[j].Calls {
+			return s.Projects[i].Calls > s.Projects[j].Calls
+		}
+		return s.Projects[i].Path < s.Projects[j].Path
+	})
+
+	declared := make(map[string]types.AgentPermissions)
+	for _, a := range agents {
+		name := a.Name
+		if a.Plugin != "" {
+			name = a.Plugin + ":" + a.Name
+		}
+		declared[name] = a
+	}
+	for _, u := range agentUsage {
+		def, ok := declared[u.AgentType]
+		agent := Agent{
+			Agent:       u.AgentType,
+			TotalCalls:  u.TotalCalls,
+			TotalTokens: u.TotalTokens,
+			Sessions:    u.Sessions,
+			LastSeen:    u.LastSeen,
+			Projects:    u.Projects,
+			Slowest:     u.Slowest,
+			Note:        notes.Agent(u.AgentType),
+		}
+		if ok {
+			agent.Declared = permissionStrings(def.Permissions)
+			agent.Allow = permissionStrings(def.Allow)
+			agent.Deny = permissionStrings(def.Deny)
+			for _, p := range insights.AgentDrift(def, u).Undeclared {
+				agent.Undeclared = append(agent.Undeclared, p.Permission.Raw)
+			}
+		}
+		for _, p := range u.Permissions {
+			agent.Permissions = append(agent.Permissions, AgentPermission{Permission: p.Permission.Raw, Count: p.Count})
+		}
+		s.Agents = append(s.Agents, agent)
+	}
+
+	for _, sk := range skills {
+		s.Skills = append(s.Skills, Skill{
+			Skill:    sk.Name,
+			Plugin:   sk.Plugin,
+			Version:  sk.Version,
+			Declared: permissionStrings(sk.Permissions),
+			Allow:    permissionStrings(sk.Allow),
+			Deny:     permissionStrings(sk.Deny),
+		})
+	}
+	return s, nil
+}
+
+// approvalName names an approval level in the API
+func approvalName(level types.ApprovalLevel) string {
+	switch level {
+	case types.ApprovedUser:
+		return "user"
+	case types.ApprovedProject:
+		return "project"
+	default:
+		return "none"
+	}
+}
+
+// permissionStrings returns the raw strings of declared permissions
+func permissionStrings(perms []types.Permission) []string {
+	out := make([]string, 0, len(perms))
+	for _, p := range perms {
+		out = append(out, p.Raw)
+	}
+	return out
+}
+
+// Server answers dashboard and API requests from a periodically refreshed
+// snapshot
+type Server struct {
+	load   func() (*Snapshot, error)
+	logger *slog.Logger
+
+	mu       sync.Mutex
+	snapshot *Snapshot
+}
+
+// New returns a server that loads its data with load, e.g. Load
+func New(load func() (*Snapshot, error), logger *slog.Logger) *Server {
+	return &Server{load: load, logger: logger}
+}
+
+// Handler routes the dashboard and the JSON API. It only answers requests
+// addressed to the server by a loopback name or the IP address they came in
+// on, so a web page can't read the stats by pointing its own host name at
+// this machine (DNS rebinding).
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handleIndex)
+	mux.HandleFunc("GET /api/permissions", s.handleAPI(func(snap *Snapshot) any { return snap.Permissions }))
+	mux.HandleFunc("GET /api/agents", s.handleAPI(func(snap *Snapshot) any { return snap.Agents }))
+	mux.HandleFunc("GET /api/skills", s.handleAPI(func(snap *Snapshot) any { return snap.Skills }))
+	mux.HandleFunc("GET /api/projects", s.handleAPI(func(snap *Snapshot) any { return snap.Projects }))
+	mux.HandleFunc("GET /api/warnings", s.handleAPI(func(snap *Snapshot) any { return snap.Warnings }))
+	mux.HandleFunc("GET /api/status", s.handleAPI(func(snap *Snapshot) any {
+		return map[string]any{
+			"loaded_at":   snap.LoadedAt,
+			"permissions": len(snap.Permissions),
+			"agents":      len(snap.Agents),
+			"projects":    len(snap.Projects),
+			"warnings":    len(snap.Warnings),
+		}
+	}))
+	return checkHost(mux)
+}
+
+// checkHost rejects requests whose Host header isn't localhost, 127.0.0.1,
+// [::1] or the IP address of the connection's local end, with the port the
+// server listens on
+func checkHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowedHost(r) {
+			http.Error(w, "unknown host "+r.Host, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost reports whether r's Host names this server
+func allowedHost(r *http.Request) bool {
+	local, ok := r.Context().Value(http.LocalAddrContextKey).(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	host, port, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		// No port: the scheme's default
+		host, port = strings.Trim(r.Host, "[]"), "80"
+	}
+	if port != strconv.Itoa(local.Port) {
+		return false
+	}
+	switch host {
+	case "localhost", "127.0.0.1", "::1":
+		return true
+	}
+	return net.ParseIP(host).Equal(local.IP)
+}
+
+// current returns the snapshot, reloading it if it is older than
+// refreshInterval or refresh is set
+func (s *Server) current(refresh bool) (*Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.snapshot != nil && !refresh && time.Since(s.snapshot.LoadedAt) < refreshInterval {
+		return s.snapshot, nil
+	}
+	snap, err := s.load()
+	if err != nil {
+		if s.snapshot != nil {
+			// Keep serving the last good data
+			s.logger.Warn("reloading stats failed", "err", err)
+			return s.snapshot, nil
+		}
+		return nil, err
+	}
+	s.snapshot = snap
+	return snap, nil
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(indexHTML)
+}
+
+// handleAPI serves part of the snapshot as JSON. ?refresh=1 rescans first.
+func (s *Server) handleAPI(part func(*Snapshot) any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		snap, err := s.current(r.URL.Query().Get("refresh") != "")
+		if err != nil {
+			s.logger.Error("loading stats failed", "err", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(part(snap)); err != nil {
+			s.logger.Debug("writing response failed", "path", r.URL.Path, "err", err)
+		}
+	}
+}
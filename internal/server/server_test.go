@@ -0,0 +1,100 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServerReloadsAndKeepsLastGoodSnapshot(t *testing.T) {
+	loads := 0
+	fail := false
+	srv := New(func() (*Snapshot, error) {
+		loads++
+		if fail {
+			return nil, errors.New("boom")
+		}
+		return &Snapshot{
+			LoadedAt:    time.Now(),
+			Permissions: []Permission{{Permission: "Bash(git status:*)", Count: loads}},
+		}, nil
+	}, slog.New(slog.DiscardHandler))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	get := func(path string) []Permission {
+		t.Helper()
+		res, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(res.Body)
+			t.Fatalf("GET %s: %d %s", path, res.StatusCode, body)
+		}
+		var perms []Permission
+		if err := json.NewDecoder(res.Body).Decode(&perms); err != nil {
+			t.Fatal(err)
+		}
+		return perms
+	}
+
+	if got := get("/api/permissions"); len(got) != 1 || got[0].Count != 1 {
+		t.Fatalf("first load = %+v", got)
+	}
+	if get("/api/permissions"); loads != 1 {
+		t.Errorf("fresh snapshot reloaded: %d loads", loads)
+	}
+	if got := get("/api/permissions?refresh=1"); got[0].Count != 2 {
+		t.Errorf("refresh served %+v, want the reloaded snapshot", got)
+	}
+
+	fail = true
+	if got := get("/api/permissions?refresh=1"); got[0].Count != 2 {
+		t.Errorf("failed reload served %+v, want the last good snapshot", got)
+	}
+}
+
+func TestServerRejectsForeignHosts(t *testing.T) {
+	srv := New(func() (*Snapshot, error) {
+		return &Snapshot{LoadedAt: time.Now()}, nil
+	}, slog.New(slog.DiscardHandler))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+
+	tests := []struct {
+		host string
+		want int
+	}{
+		{"localhost:" + port, http.StatusOK},
+		{"127.0.0.1:" + port, http.StatusOK},
+		{"[::1]:" + port, http.StatusOK},
+		{"attacker.example:" + port, http.StatusForbidden}, // A rebound host name
+		{"localhost:1", http.StatusForbidden},
+		{"localhost", http.StatusForbidden}, // Port 80, not the server's
+		{"10.0.0.1:" + port, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest("GET", ts.URL+"/api/status", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Host = tt.host
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != tt.want {
+			t.Errorf("Host %q: status %d, want %d", tt.host, res.StatusCode, tt.want)
+		}
+	}
+}
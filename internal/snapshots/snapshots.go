@@ -0,0 +1,172 @@
+// Package snapshots keeps copies of settings files over time, taken whenever
+// this tool writes one or on demand, so changes can be reviewed and rolled
+// back.
+package snapshots
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// indexFile lists every snapshot, one JSON object per line, oldest first
+const indexFile = "index.jsonl"
+
+// Snapshot is one saved copy of a settings file
+type Snapshot struct {
+	ID       string    `json:"id"`       // Name of the copy in the snapshot directory
+	Settings string    `json:"settings"` // Settings file the copy was taken of
+	Time     time.Time `json:"time"`
+	Reason   string    `json:"reason"`            // What prompted it, e.g. "allow Bash(git:*)"
+	Missing  bool      `json:"missing,omitempty"` // The settings file did not exist
+}
+
+// dirOverride replaces the default snapshot directory when set
+var dirOverride string
+
+// Dir returns the snapshot directory (~/.claude/perms-history)
+func Dir() string {
+	if dirOverride != "" {
+		return dirOverride
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".claude", "perms-history")
+}
+
+// SetDir stores snapshots in dir instead of the default. An empty dir
+// restores the default.
+func SetDir(dir string) {
+	dirOverride = dir
+}
+
+// Record snapshots a settings file around a write: its previous contents, if
+// they changed since the last snapshot (e.g. edited by hand), then the new
+// contents. A nil before or after means the file did not exist.
+func Record(settingsPath string, before, after []byte, reason string) error {
+	if _, err := save(settingsPath, before, "before "+reason); err != nil {
+		return err
+	}
+	_, err := save(settingsPath, after, reason)
+	return err
+}
+
+// Take snapshots the current contents of a settings file. It reports false
+// if the contents match the latest snapshot of that file, in which case
+// nothing is saved.
+func Take(settingsPath, reason string) (bool, error) {
+	data, err := os.ReadFile(settingsPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return false, err
+		}
+		data = nil
+	}
+	return save(settingsPath, data, reason)
+}
+
+// save stores data as the next snapshot of settingsPath unless it matches
+// the latest one
+func save(settingsPath string, data []byte, reason string) (bool, error) {
+	all, err := List()
+	if err != nil {
+		return false, err
+	}
+	for _, s := range all {
+		if s.Settings != settingsPath {
+			continue
+		}
+		latest, err := Content(s)
+		if err == nil && s.Missing == (data == nil) && bytes.Equal(latest, data) {
+			return false, nil
+		}
+		break
+	}
+
+	dir := Dir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return false, fmt.Errorf("create snapshot directory: %w", err)
+	}
+
+	now := time.Now()
+	s := Snapshot{
+		ID:       fmt.Sprintf("%s-%08x.json", now.UTC().Format("20060102-150405.000000000"), crc32.ChecksumIEEE([]byte(settingsPath))),
+		Settings: settingsPath,
+		Time:     now,
+		Reason:   reason,
+		Missing:  data == nil,
+	}
+	if err := os.WriteFile(filepath.Join(dir, s.ID), data, 0600); err != nil {
+		return false, fmt.Errorf("write snapshot: %w", err)
+	}
+
+	line, err := json.Marshal(s)
+	if err != nil {
+		return false, err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, indexFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return false, fmt.Errorf("open snapshot index: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return false, fmt.Errorf("write snapshot index: %w", err)
+	}
+	return true, nil
+}
+
+// List returns every snapshot, newest first. Index lines that don't parse
+// are skipped.
+func List() ([]Snapshot, error) {
+	f, err := os.Open(filepath.Join(Dir(), indexFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var all []Snapshot
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var s Snapshot
+		if err := json.Unmarshal(scanner.Bytes(), &s); err != nil || s.ID == "" {
+			continue
+		}
+		all = append(all, s)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].Time.After(all[j].Time)
+	})
+	return all, nil
+}
+
+// Content returns the saved copy of a snapshot; nil if the settings file
+// did not exist when it was taken
+func Content(s Snapshot) ([]byte, error) {
+	if s.Missing {
+		return nil, nil
+	}
+	return os.ReadFile(filepath.Join(Dir(), filepath.Base(s.ID)))
+}
+
+// Previous returns the snapshot of the same settings file taken just before
+// all[i], where all is ordered as returned by List
+func Previous(all []Snapshot, i int) (Snapshot, bool) {
+	for j := i + 1; j < len(all); j++ {
+		if all[j].Settings == all[i].Settings {
+			return all[j], true
+		}
+	}
+	return Snapshot{}, false
+}
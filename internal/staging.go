@@ -0,0 +1,342 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/types"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// stagedMarker flags a staged permission in the Frequency view
+	stagedMarker = "◆ "
+
+	// stagedPanelWidth is the width of the staging panel beside the
+	// Frequency list, shown when the list keeps stagedPanelMinList columns
+	stagedPanelWidth   = 34
+	stagedPanelMinList = 80
+
+	// stagedPreviewFiles caps the diffs the staging modal shows at once
+	stagedPreviewFiles = 3
+)
+
+// stagedTarget is one settings file a staged apply writes, with the staged
+// permissions that go into it
+type stagedTarget struct {
+	path    string
+	project string // "" for user settings
+	perms   []string
+}
+
+// isStaged reports whether raw is in the staging area
+func (m Model) isStaged(raw string) bool {
+	return slices.Contains(m.staged, raw)
+}
+
+// toggleStage stages the selected permission, or takes it out of the
+// staging area. On a group row it stages every permission of the group no
+// settings file allows yet, or unstages them all if they already are.
+func (m Model) toggleStage() (tea.Model, tea.Cmd) {
+	gi, ci := m.freqCursor()
+	if gi >= len(m.permissionGroups) {
+		return m, nil
+	}
+	group := m.permissionGroups[gi]
+
+	var perms []string
+	if ci >= 0 && ci < len(group.Children) {
+		perms = []string{group.Children[ci].Permission.Raw}
+	} else {
+		for _, child := range group.Children {
+			if child.ApprovedAt == types.NotApproved {
+				perms = append(perms, child.Permission.Raw)
+			}
+		}
+		if len(perms) == 0 {
+			m.notify(toastInfo, "Every permission in %s is already allowed", group.Type)
+			return m, m.toastTickCmd()
+		}
+	}
+
+	unstage := true
+	for _, p := range perms {
+		if !m.isStaged(p) {
+			unstage = false
+			break
+		}
+	}
+	for _, p := range perms {
+		switch {
+		case unstage:
+			m.staged = slices.DeleteFunc(slices.Clone(m.staged), func(s string) bool { return s == p })
+		case !m.isStaged(p):
+			m.staged = append(m.staged, p)
+		}
+	}
+	return m, nil
+}
+
+// openStaged opens the modal that applies the staging area
+func (m Model) openStaged() (tea.Model, tea.Cmd) {
+	if len(m.staged) == 0 {
+		m.notify(toastInfo, "Nothing staged: press %s on permissions to stage them", primaryKey(m.keys.Stage))
+		return m, m.toastTickCmd()
+	}
+	m.showStaged = true
+	m.stagedCursor = 0
+	return m, nil
+}
+
+// stagedTargets returns the settings files applying the staging area
+// writes: user settings, or with projects set, the settings of each project
+// that used a staged permission, sorted by path
+func (m Model) stagedTargets(projects bool) []stagedTarget {
+	if !projects {
+		return []stagedTarget{{path: parser.UserSettingsPath(), perms: m.staged}}
+	}
+
+	byProject := make(map[string][]string)
+	for _, raw := range m.staged {
+		// A rule staged from the pattern tester goes to the projects of
+		// the permissions it matches
+		used := []string{raw}
+		if !slices.ContainsFunc(m.permissions, func(p types.PermissionStats) bool { return p.Permission.Raw == raw }) {
+			used = nil
+			for _, c := range parser.TryPattern(raw, m.permissions).Matched {
+				used = append(used, c.Permission)
+			}
+		}
+		projects := make(map[string]bool)
+		for _, p := range m.permissions {
+			if !slices.Contains(used, p.Permission.Raw) {
+				continue
+			}
+			for _, project := range p.Projects {
+				if !projects[project] {
+					projects[project] = true
+					byProject[project] = append(byProject[project], raw)
+				}
+			}
+		}
+	}
+	targets := make([]stagedTarget, 0, len(byProject))
+	for project, perms := range byProject {
+		targets = append(targets, stagedTarget{path: parser.ProjectSettingsPath(project), project: project, perms: perms})
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].path < targets[j].path })
+	return targets
+}
+
+// handleStagedKeys handles the staging modal: pick user or project
+// settings, then apply
+func (m Model) handleStagedKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.ForceQuit):
+		return m, tea.Quit
+
+	case key.Matches(msg, m.keys.Back, m.keys.Quit):
+		m.showStaged = false
+
+	case key.Matches(msg, m.keys.Up):
+		m.stagedCursor = 0
+
+	case key.Matches(msg, m.keys.Down):
+		m.stagedCursor = 1
+
+	case key.Matches(msg, m.keys.Select):
+		return m.applyStaged()
+
+	case key.Matches(msg, m.keys.Dismiss):
+		m.showStaged = false
+		m.notify(toastSuccess, "Unstaged %d permission(s)", len(m.staged))
+		m.staged = nil
+		return m, m.toastTickCmd()
+	}
+	return m, nil
+}
+
+// applyStaged writes the staging area to the chosen settings, one write per
+// file, and empties it. A dry run reports and keeps it.
+func (m Model) applyStaged() (tea.Model, tea.Cmd) {
+	if m.writeBlockedReason() != "" {
+		return m.writeBlocked()
+	}
+	toProjects := m.stagedCursor == 1
+	targets := m.stagedTargets(toProjects)
+	if len(targets) == 0 {
+		m.notify(toastWarn, "No project recorded for the staged permissions")
+		return m, m.toastTickCmd()
+	}
+
+	var written, files int
+	var results []*parser.ApplyResult
+	for _, t := range targets {
+		var err error
+		results, err = m.writePermissions(t.path, t.perms)
+		if err != nil {
+			// Files before this one were written; a retry leaves them be
+			return m, m.failWrite(t.path, err, Model.applyStaged)
+		}
+		changed := false
+		for _, r := range results {
+			if r.WasNew {
+				written++
+				changed = true
+			}
+			switch {
+			case !toProjects:
+				if !r.DryRun {
+					m.userApproved = append(m.userApproved, r.Permission)
+				}
+				m.recordApply("user", r)
+			default:
+				if !r.DryRun {
+					m.projectSettings[t.project] = append(m.projectSettings[t.project], r.Permission)
+				}
+				m.recordApply("project", r)
+			}
+		}
+		if changed {
+			files++
+		}
+	}
+
+	switch {
+	case len(targets) == 1:
+		m.setApplyToastBatch(parser.BatchResult(targets[0].path, results))
+	case m.dryRun:
+		m.notify(toastInfo, "Dry run: %d permission(s) would be written to %d file(s)", written, files)
+	case written == 0:
+		m.notify(toastInfo, "Applied: Already allowed in all %d project settings files", len(targets))
+	default:
+		m.notify(toastSuccess, "Applied: %d permission(s) written to %d files", written, files)
+	}
+	if !m.dryRun {
+		m.staged = nil
+	}
+	m.showStaged = false
+	return m, m.toastTickCmd()
+}
+
+// renderStagedModal renders the staged permissions, where to apply them and
+// the combined diff of every file that would change
+func (m Model) renderStagedModal() string {
+	modalWidth := min(max(m.width*85/100, 50), 80)
+	inner := modalWidth - 8 // border + padding + indent
+
+	var b strings.Builder
+	b.WriteString(styles.ModalTitle.Render(fmt.Sprintf("Apply %d Staged Permission(s)", len(m.staged))))
+	b.WriteString("\n\n")
+
+	// The rest of the modal takes about 20 lines; the list and the diffs
+	// share what is left
+	room := max(m.height-20, stagedPreviewFiles*3)
+	listed := len(m.staged)
+	if limit := max(room/4, 2); listed > limit {
+		listed = limit - 1 // and a line for the rest
+	}
+	for _, raw := range m.staged[:listed] {
+		b.WriteString("  " + styles.HelpKey.Render(truncateString(raw, inner)) + "\n")
+	}
+	if n := len(m.staged) - listed; n > 0 {
+		b.WriteString(styles.HelpDesc.Render(fmt.Sprintf("  +%d more", n)) + "\n")
+		listed++
+	}
+	room -= listed
+	b.WriteString("\n" + m.renderWriteModeNotice())
+
+	projectTargets := m.stagedTargets(true)
+	options := []string{
+		"Apply to User (all projects)",
+		fmt.Sprintf("Apply to the projects that used them (%d file(s))", len(projectTargets)),
+	}
+	for i, opt := range options {
+		switch {
+		case i == m.stagedCursor:
+			b.WriteString(styles.ListItemSelected.Render("> " + opt))
+		case m.writeBlockedReason() != "":
+			b.WriteString(styles.StatusPending.Render("  " + opt))
+		default:
+			b.WriteString(styles.ListItem.Render("  " + opt))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+
+	targets := projectTargets
+	if m.stagedCursor == 0 {
+		targets = m.stagedTargets(false)
+	}
+	shown := targets[:min(len(targets), stagedPreviewFiles)]
+	for _, t := range shown {
+		diffLines, allExist, err := parser.PreviewPermissionDiff(t.path, t.perms)
+		if err != nil {
+			b.WriteString(renderDiffPreviewError(t.path, err))
+		} else {
+			b.WriteString(renderDiffPreview(t.path, capDiff(diffLines, max(room/len(shown)-1, 2)), allExist, 74))
+		}
+	}
+	if n := len(targets) - stagedPreviewFiles; n > 0 {
+		b.WriteString(styles.HelpDesc.Render(fmt.Sprintf("  … and %d more file(s)", n)) + "\n")
+	}
+	if m.stagedCursor == 0 {
+		b.WriteString(m.renderCoverage(m.staged, ""))
+	}
+
+	b.WriteString("\n" + renderHints(m.contextBindings()))
+	return styles.Modal.Width(modalWidth).Render(b.String())
+}
+
+// capDiff shortens a diff preview to at most n lines, the last of which
+// says how many were left out
+func capDiff(lines []parser.DiffLine, n int) []parser.DiffLine {
+	if len(lines) <= n {
+		return lines
+	}
+	capped := append([]parser.DiffLine(nil), lines[:n-1]...)
+	return append(capped, parser.DiffLine{Status: ' ', Text: fmt.Sprintf("… %d more lines", len(lines)-n+1)})
+}
+
+// showStagedPanel reports whether the Frequency view has room for the
+// staging panel beside its list
+func (m Model) showStagedPanel() bool {
+	return len(m.staged) > 0 && m.width-stagedPanelWidth >= stagedPanelMinList
+}
+
+// renderStagedPanel renders the staging area as a column height lines tall
+// for the side of the Frequency view
+func (m Model) renderStagedPanel(height int) string {
+	inner := stagedPanelWidth - 3 // border + gap
+	lines := []string{
+		styles.ListHeader.UnsetPaddingLeft().Render(fmt.Sprintf("Staged (%d)", len(m.staged))),
+		"",
+	}
+
+	// Leave room for the hint lines under the list
+	room := max(height-len(lines)-3, 1)
+	for i, raw := range m.staged {
+		if i == room-1 && len(m.staged) > room {
+			lines = append(lines, styles.HelpDesc.Render(fmt.Sprintf("+%d more", len(m.staged)-i)))
+			break
+		}
+		lines = append(lines, truncateString(raw, inner))
+	}
+	lines = append(lines, "",
+		styles.HelpKey.Render(primaryKey(m.keys.ApplyStaged))+" apply…",
+		styles.HelpKey.Render(primaryKey(m.keys.Stage))+" unstage")
+
+	border := styles.ListHeader.UnsetPaddingLeft().Render("│")
+	for i := range lines {
+		lines[i] = border + " " + lines[i]
+	}
+	for len(lines) < height {
+		lines = append(lines, border)
+	}
+	return lipgloss.NewStyle().Width(stagedPanelWidth).Render(strings.Join(lines[:height], "\n"))
+}
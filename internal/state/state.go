@@ -0,0 +1,255 @@
+// Package state persists the small bits of TUI state that should survive a
+// restart, such as pinned permissions and agents, the notes attached to
+// them, where the last review ended, the review queue's progress and the
+// project each kind of permission was last applied to. Unlike the config
+// file it is written by perms itself, never edited by hand.
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// State is everything kept in the state file
+type State struct {
+	Pins    Pins    `json:"pins"`
+	Notes   Notes   `json:"notes"`
+	Review  Review  `json:"review"`
+	Queue   Queue   `json:"queue"`
+	Targets Targets `json:"targets"`
+}
+
+// Pins lists what the user pinned to the top of each list
+type Pins struct {
+	Permissions []string `json:"permissions,omitempty"` // Raw permission strings
+	Groups      []string `json:"groups,omitempty"`      // Permission types, e.g. "Bash"
+	Agents      []string `json:"agents,omitempty"`      // Agent types
+}
+
+// pathOverride replaces the default location when set
+var pathOverride string
+
+// Path returns the state file location (~/.claude/perms-state.json)
+func Path() string {
+	if pathOverride != "" {
+		return pathOverride
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".claude", "perms-state.json")
+}
+
+// SetPath keeps state in path instead of the default. An empty path
+// restores the default.
+func SetPath(path string) {
+	pathOverride = path
+}
+
+// Load reads the state file. A missing file is an empty state.
+func Load() (*State, error) {
+	var s State
+	data, err := os.ReadFile(Path())
+	if os.IsNotExist(err) {
+		return &s, nil
+	}
+	if err != nil {
+		return &s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return &State{}, fmt.Errorf("parse %s: %w", Path(), err)
+	}
+	return &s, nil
+}
+
+// Save writes the state file, replacing it atomically
+func (s *State) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := Path()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// toggle adds s to list, or removes it if present, and reports whether it
+// is now in the list
+func toggle(list *[]string, s string) bool {
+	for i, existing := range *list {
+		if existing == s {
+			*list = append((*list)[:i], (*list)[i+1:]...)
+			return false
+		}
+	}
+	*list = append(*list, s)
+	return true
+}
+
+// contains reports whether list holds s
+func contains(list []string, s string) bool {
+	for _, existing := range list {
+		if existing == s {
+			return true
+		}
+	}
+	return false
+}
+
+// TogglePermission pins or unpins a permission and reports whether it is
+// now pinned
+func (p *Pins) TogglePermission(raw string) bool { return toggle(&p.Permissions, raw) }
+
+// ToggleGroup pins or unpins a permission group by type
+func (p *Pins) ToggleGroup(permType string) bool { return toggle(&p.Groups, permType) }
+
+// ToggleAgent pins or unpins an agent type
+func (p *Pins) ToggleAgent(agentType string) bool { return toggle(&p.Agents, agentType) }
+
+// Permission reports whether a permission is pinned
+func (p Pins) Permission(raw string) bool { return contains(p.Permissions, raw) }
+
+// Group reports whether a permission group is pinned
+func (p Pins) Group(permType string) bool { return contains(p.Groups, permType) }
+
+// Agent reports whether an agent type is pinned
+func (p Pins) Agent(agentType string) bool { return contains(p.Agents, agentType) }
+
+// Notes holds free-text annotations, e.g. "approved for release tooling
+// only, revisit Q3"
+type Notes struct {
+	Permissions map[string]string `json:"permissions,omitempty"` // By raw permission string
+	Agents      map[string]string `json:"agents,omitempty"`      // By agent type
+}
+
+// setNote stores note under key, or removes it if note is blank
+func setNote(notes *map[string]string, key, note string) {
+	note = strings.TrimSpace(note)
+	if note == "" {
+		delete(*notes, key)
+		return
+	}
+	if *notes == nil {
+		*notes = make(map[string]string)
+	}
+	(*notes)[key] = note
+}
+
+// SetPermission sets a permission's note; a blank note removes it
+func (n *Notes) SetPermission(raw, note string) { setNote(&n.Permissions, raw, note) }
+
+// SetAgent sets an agent's note; a blank note removes it
+func (n *Notes) SetAgent(agentType, note string) { setNote(&n.Agents, agentType, note) }
+
+// Permission returns a permission's note, or "" if it has none
+func (n Notes) Permission(raw string) string { return n.Permissions[raw] }
+
+// Agent returns an agent's note, or "" if it has none
+func (n Notes) Agent(agentType string) string { return n.Agents[agentType] }
+
+// Review is the watermark left when a review was last finished: when, and
+// each permission's counts at the time, so the next review only has to deal
+// with what changed since
+type Review struct {
+	At     time.Time              `json:"at,omitempty"`
+	Counts map[string]ReviewCount `json:"counts,omitempty"` // By raw permission string
+}
+
+// ReviewCount is a permission's use counts when it was reviewed
+type ReviewCount struct {
+	Approved int `json:"approved"`
+	Denied   int `json:"denied"`
+}
+
+// Mark records counts as reviewed at t, replacing the previous watermark
+func (r *Review) Mark(t time.Time, counts map[string]ReviewCount) {
+	r.At = t
+	r.Counts = counts
+}
+
+// Done reports whether a review has ever been finished
+func (r Review) Done() bool { return !r.At.IsZero() }
+
+// Since returns how many more uses a permission has than at the last review
+// and whether its counts changed. A permission the review didn't see is new
+// and all its uses count.
+func (r Review) Since(raw string, approved, denied int) (delta int, changed bool) {
+	prev, ok := r.Counts[raw]
+	if !ok {
+		return approved + denied, true
+	}
+	delta = approved + denied - prev.Approved - prev.Denied
+	return delta, approved != prev.Approved || denied != prev.Denied
+}
+
+// Queue decisions the review queue keeps across runs
+const (
+	QueueDeny   = "deny"   // Added to the deny list
+	QueueIgnore = "ignore" // Left alone for good
+	QueueSkip   = "skip"   // Passed over until the queue comes round again
+)
+
+// Queue is the review queue's progress: the permissions it has shown that
+// weren't allowed, so a review resumes where it stopped. Allowed
+// permissions need no record; settings say they're done.
+type Queue struct {
+	Decisions map[string]string `json:"decisions,omitempty"` // By raw permission string
+}
+
+// Decide records the decision for a permission
+func (q *Queue) Decide(raw, decision string) {
+	if q.Decisions == nil {
+		q.Decisions = make(map[string]string)
+	}
+	q.Decisions[raw] = decision
+}
+
+// Decision returns what was decided for a permission, or "" if it hasn't
+// come up yet
+func (q Queue) Decision(raw string) string { return q.Decisions[raw] }
+
+// ClearSkipped forgets the skipped permissions, so the queue shows them
+// again, and returns how many there were
+func (q *Queue) ClearSkipped() int {
+	n := 0
+	for raw, d := range q.Decisions {
+		if d == QueueSkip {
+			delete(q.Decisions, raw)
+			n++
+		}
+	}
+	return n
+}
+
+// Targets remembers the project each permission type was last applied to
+// from a project list, so the common target can be preselected
+type Targets struct {
+	Projects map[string]string `json:"projects,omitempty"` // By permission type, e.g. "Bash"
+}
+
+// Remember records project as where permType was last applied
+func (t *Targets) Remember(permType, project string) {
+	if t.Projects == nil {
+		t.Projects = make(map[string]string)
+	}
+	t.Projects[permType] = project
+}
+
+// Project returns the project permType was last applied to, or "" if none
+func (t Targets) Project(permType string) string { return t.Projects[permType] }
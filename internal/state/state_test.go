@@ -0,0 +1,103 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStateRoundTrip(t *testing.T) {
+	SetPath(filepath.Join(t.TempDir(), "state.json"))
+	defer SetPath("")
+
+	s, err := Load()
+	if err != nil {
+		t.Fatalf("missing file: %v", err)
+	}
+	if !s.Pins.TogglePermission("Bash(git:*)") || !s.Pins.ToggleAgent("reviewer") {
+		t.Fatal("first toggle should pin")
+	}
+	if s.Pins.ToggleAgent("reviewer") {
+		t.Fatal("second toggle should unpin")
+	}
+	s.Notes.SetPermission("Bash(git:*)", "  release tooling only ")
+	s.Notes.SetAgent("reviewer", "temporary")
+	s.Notes.SetAgent("reviewer", " ")
+	s.Targets.Remember("Bash", "/work/app")
+	s.Targets.Remember("Bash", "/work/api")
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Pins.Permission("Bash(git:*)") || loaded.Pins.Agent("reviewer") {
+		t.Errorf("loaded pins = %+v", loaded.Pins)
+	}
+	if got := loaded.Notes.Permission("Bash(git:*)"); got != "release tooling only" {
+		t.Errorf("permission note = %q", got)
+	}
+	if _, ok := loaded.Notes.Agents["reviewer"]; ok {
+		t.Error("blank note should remove the agent's note")
+	}
+	if got := loaded.Targets.Project("Bash"); got != "/work/api" {
+		t.Errorf("last Bash project = %q, want the latest", got)
+	}
+	if got := loaded.Targets.Project("Read"); got != "" {
+		t.Errorf("last Read project = %q, want none", got)
+	}
+
+	os.WriteFile(Path(), []byte("{"), 0644)
+	if s, err := Load(); err == nil || len(s.Pins.Permissions) != 0 {
+		t.Errorf("corrupt file: err %v, pins %+v", err, s.Pins)
+	}
+}
+
+func TestReviewSince(t *testing.T) {
+	var r Review
+	if r.Done() {
+		t.Fatal("zero review should not be done")
+	}
+	r.Mark(time.Now(), map[string]ReviewCount{
+		"Bash(git:*)": {Approved: 10, Denied: 1},
+		"Read":        {Approved: 4},
+	})
+
+	for _, tc := range []struct {
+		raw              string
+		approved, denied int
+		delta            int
+		changed          bool
+	}{
+		{"Bash(git:*)", 10, 1, 0, false},
+		{"Bash(git:*)", 13, 1, 3, true},
+		{"Read", 4, 2, 2, true},
+		{"WebFetch", 5, 0, 5, true},
+	} {
+		delta, changed := r.Since(tc.raw, tc.approved, tc.denied)
+		if delta != tc.delta || changed != tc.changed {
+			t.Errorf("Since(%s, %d, %d) = %d, %v; want %d, %v", tc.raw, tc.approved, tc.denied, delta, changed, tc.delta, tc.changed)
+		}
+	}
+}
+
+func TestQueue(t *testing.T) {
+	var q Queue
+	if q.Decision("Bash(git:*)") != "" {
+		t.Fatal("zero queue should have no decisions")
+	}
+	q.Decide("Bash(git:*)", QueueDeny)
+	q.Decide("Bash(npm:*)", QueueSkip)
+	q.Decide("WebFetch", QueueSkip)
+	q.Decide("Read", QueueIgnore)
+
+	if n := q.ClearSkipped(); n != 2 {
+		t.Errorf("ClearSkipped() = %d, want 2", n)
+	}
+	if q.Decision("Bash(npm:*)") != "" || q.Decision("Bash(git:*)") != QueueDeny || q.Decision("Read") != QueueIgnore {
+		t.Errorf("decisions after ClearSkipped = %v, want only the deny and ignore kept", q.Decisions)
+	}
+}
@@ -0,0 +1,517 @@
+// Package store keeps parsed tool_use events in a local bbolt database, so
+// large histories are parsed once and then updated incrementally: a sync
+// re-parses only the logs whose size or mtime changed. Events are stored
+// raw and aggregated at query time, so queries can be narrowed to a time
+// range, project or agent without touching the JSONL logs.
+package store
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/types"
+)
+
+// formatVersion is bumped when the stored layout or event parsing changes;
+// a store with another version is rebuilt from scratch
+const formatVersion = "14"
+
+// flushEvery is how many parsed logs are written per transaction
+const flushEvery = 100
+
+var (
+	metaBucket   = []byte("meta")   // version, last sync
+	filesBucket  = []byte("files")  // log path -> fileRecord
+	eventsBucket = []byte("events") // log path -> bucket of seq -> ToolEvent
+)
+
+// Path returns the default store location (~/.claude/perms-events.db)
+func Path() string {
+	return filepath.Join(parser.ClaudeDir(), "perms-events.db")
+}
+
+// fileRecord describes one ingested log
+type fileRecord struct {
+	Hash        string                  `json:"hash"` // mtime:size when parsed
+	Project     string                  `json:"project"`
+	Agent       bool                    `json:"agent,omitempty"`
+	Events      int                     `json:"events"`
+	Invocations []types.AgentInvocation `json:"invocations,omitempty"` // Task calls that started subagents, from session logs
+	Warnings    []parser.Warning        `json:"warnings,omitempty"`    // Replayed on every sync
+
+	// The rejection markers and redaction patterns it was parsed with, kept
+	// per log as directories are synced with different configs
+	Rejection string `json:"rejection"`
+	Redaction string `json:"redaction"`
+}
+
+// Store is an open event database. It is not safe for concurrent use, and
+// only one process can have it open at a time.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens or creates the store at path. A store written by another
+// format version is emptied, to be rebuilt by the next Sync.
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("%s is in use by another perms process", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(metaBucket)
+		if meta != nil && string(meta.Get([]byte("version"))) == formatVersion {
+			return nil
+		}
+		return reset(tx)
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// reset drops every bucket and recreates them empty
+func reset(tx *bolt.Tx) error {
+	for _, name := range [][]byte{metaBucket, filesBucket, eventsBucket} {
+		if err := tx.DeleteBucket(name); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+			return err
+		}
+		if _, err := tx.CreateBucket(name); err != nil {
+			return err
+		}
+	}
+	return tx.Bucket(metaBucket).Put([]byte("version"), []byte(formatVersion))
+}
+
+// SyncResult summarizes a Sync
+type SyncResult struct {
+	Logs     int // Logs found under the projects directory
+	Parsed   int // Logs that were new or changed and were (re-)parsed
+	Removed  int // Logs that no longer exist and were dropped
+	Events   int // Events added by the parsed logs
+	Warnings []parser.Warning
+}
+
+// pendingLog is a parsed log waiting to be written
+type pendingLog struct {
+	path   string
+	record fileRecord
+	events []parser.ToolEvent
+}
+
+// Sync brings the store up to date with the logs under projectsDir,
+// parsing only new and changed logs. Logs of other directories synced
+// before, e.g. the demo's, are kept; a missing directory drops its logs.
+func (s *Store) Sync(projectsDir string, progress chan<- parser.Progress) (SyncResult, error) {
+	var result SyncResult
+	dir, err := filepath.Abs(projectsDir)
+	if err != nil {
+		return result, err
+	}
+	logs, err := parser.ListLogFiles(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return result, err
+	}
+	result.Logs = len(logs)
+
+	known := make(map[string]fileRecord)
+	rejection, redaction := parser.RejectionFingerprint(), parser.RedactionFingerprint()
+	err = s.db.View(func(tx *bolt.Tx) error {
+		// Session outcomes depend on the rejection markers, and every log's
+		// samples and prompts on the redaction patterns
+		return tx.Bucket(filesBucket).ForEach(func(k, v []byte) error {
+			var rec fileRecord
+			if json.Unmarshal(v, &rec) != nil || (!rec.Agent && rec.Rejection != rejection) || rec.Redaction != redaction {
+				return nil // Treated as unknown, so it is parsed again
+			}
+			known[string(k)] = rec
+			return nil
+		})
+	})
+	if err != nil {
+		return result, err
+	}
+
+	seen := make(map[string]bool, len(logs))
+	var pending []pendingLog
+	for i, log := range logs {
+		seen[log.Path] = true
+		send(progress, parser.Progress{
+			Stage:   "Updating event store",
+			Project: log.Project,
+			Session: logID(log.Path),
+			Done:    i,
+			Total:   len(logs),
+		})
+
+		hash, err := fileHash(log.Path)
+		if err != nil {
+			continue
+		}
+		if rec, ok := known[log.Path]; ok && rec.Hash == hash {
+			result.Warnings = append(result.Warnings, rec.Warnings...)
+			continue
+		}
+
+		p := pendingLog{path: log.Path, record: fileRecord{Hash: hash, Project: log.Project, Agent: log.Agent, Rejection: rejection, Redaction: redaction}}
+		if log.Agent {
+			p.events, p.record.Warnings, err = parser.ParseAgentEvents(log.Path)
+			if err != nil {
+				p.record.Warnings = []parser.Warning{{File: log.Path, Reason: "unreadable agent log: " + err.Error()}}
+			}
+		} else {
+			p.events, p.record.Warnings, err = parser.ParseSessionEvents(log.Path, log.Time)
+			if err != nil {
+				p.record.Warnings = []parser.Warning{{File: log.Path, Reason: "unreadable session log: " + err.Error()}}
+			}
+			p.record.Invocations = parser.AgentInvocations(log.Path, log.Project)
+		}
+		p.record.Events = len(p.events)
+		result.Warnings = append(result.Warnings, p.record.Warnings...)
+		result.Parsed++
+		result.Events += len(p.events)
+
+		pending = append(pending, p)
+		if len(pending) >= flushEvery {
+			if err := s.write(pending); err != nil {
+				return result, err
+			}
+			pending = pending[:0]
+		}
+	}
+	if err := s.write(pending); err != nil {
+		return result, err
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		files, events := tx.Bucket(filesBucket), tx.Bucket(eventsBucket)
+		var gone [][]byte
+		files.ForEach(func(k, _ []byte) error {
+			if under(string(k), dir) && !seen[string(k)] {
+				gone = append(gone, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		for _, k := range gone {
+			if err := files.Delete(k); err != nil {
+				return err
+			}
+			if err := events.DeleteBucket(k); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+				return err
+			}
+		}
+		result.Removed = len(gone)
+
+		return tx.Bucket(metaBucket).Put([]byte("synced"), []byte(time.Now().UTC().Format(time.RFC3339)))
+	})
+	return result, err
+}
+
+// write replaces the stored events of each parsed log in one transaction
+func (s *Store) write(logs []pendingLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		files, events := tx.Bucket(filesBucket), tx.Bucket(eventsBucket)
+		for _, l := range logs {
+			key := []byte(l.path)
+			if err := events.DeleteBucket(key); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+				return err
+			}
+			b, err := events.CreateBucket(key)
+			if err != nil {
+				return err
+			}
+			for i, e := range l.events {
+				data, err := json.Marshal(e)
+				if err != nil {
+					return err
+				}
+				if err := b.Put(seqKey(i), data); err != nil {
+					return err
+				}
+			}
+
+			data, err := json.Marshal(l.record)
+			if err != nil {
+				return err
+			}
+			if err := files.Put(key, data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Filter narrows a query. Zero fields match everything.
+type Filter struct {
+	Dir     string    // Projects directory the logs were synced from
+	Since   time.Time // Events at or after
+	Until   time.Time // Events before
+	Project string    // Decoded project path
+	Agent   string    // Subagent type; only that agent's events
+}
+
+// Event is a stored tool_use with where it happened
+type Event struct {
+	parser.ToolEvent
+	Project string `json:"project"`
+	Log     string `json:"log"`             // Session or agent log path
+	Agent   string `json:"agent,omitempty"` // Subagent type ("Unknown" if unmapped); empty in the main session
+}
+
+// Events returns the stored events matching f, log by log in path order
+func (s *Store) Events(f Filter) ([]Event, error) {
+	var out []Event
+	err := s.each(f, func(l parser.LogEvents) {
+		for _, e := range l.Events {
+			out = append(out, Event{ToolEvent: e, Project: l.Project, Log: l.Path, Agent: l.AgentType})
+		}
+	})
+	return out, err
+}
+
+// Logs returns the stored events matching f grouped by log, in the form
+// parser.LoadLogEvents returns them
+func (s *Store) Logs(f Filter) ([]parser.LogEvents, error) {
+	var logs []parser.LogEvents
+	err := s.each(f, func(l parser.LogEvents) {
+		logs = append(logs, l)
+	})
+	return logs, err
+}
+
+// PermissionStats aggregates the main-session events matching f the way the
+// session loaders do
+func (s *Store) PermissionStats(f Filter) ([]types.PermissionStats, error) {
+	var logs []parser.LogEvents
+	err := s.each(f, func(l parser.LogEvents) {
+		if l.AgentType == "" {
+			logs = append(logs, l)
+		}
+	})
+	return parser.AggregatePermissionStats(logs), err
+}
+
+// AgentUsageStats aggregates the agent-log events matching f the way
+// parser.LoadAgentUsageStats does, with the invocations from the session logs
+func (s *Store) AgentUsageStats(f Filter) ([]types.AgentUsageStats, error) {
+	var logs []parser.LogEvents
+	err := s.each(f, func(l parser.LogEvents) {
+		logs = append(logs, l)
+	})
+	return parser.AggregateAgentUsage(logs), err
+}
+
+// each calls fn with the matching events of every log that has any, a call
+// a resumed session repeats in the first log that has it
+func (s *Store) each(f Filter, fn func(parser.LogEvents)) error {
+	var logs []parser.LogEvents
+	err := s.db.View(func(tx *bolt.Tx) error {
+		files, events := tx.Bucket(filesBucket), tx.Bucket(eventsBucket)
+
+		// Agent logs are attributed through the Task calls in session logs,
+		// which may have been ingested in any order
+		records := make(map[string]fileRecord)
+		agentTypes := make(map[string]string)
+		err := files.ForEach(func(k, v []byte) error {
+			var rec fileRecord
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return fmt.Errorf("store record %s: %w", k, err)
+			}
+			records[string(k)] = rec
+			for _, inv := range rec.Invocations {
+				if inv.AgentID != "" {
+					agentTypes[inv.AgentID] = inv.AgentType
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		dir := ""
+		if f.Dir != "" {
+			var err error
+			if dir, err = filepath.Abs(f.Dir); err != nil {
+				return err
+			}
+		}
+		return files.ForEach(func(k, _ []byte) error {
+			if dir != "" && !under(string(k), dir) {
+				return nil
+			}
+			rec := records[string(k)]
+			// Grouping may have changed since the log was stored
+			l := parser.LogEvents{Path: string(k), Project: parser.CanonicalProject(rec.Project)}
+			if rec.Agent {
+				l.AgentType = agentTypes[parser.AgentID(l.Path)]
+				if l.AgentType == "" {
+					l.AgentType = "Unknown"
+				}
+			}
+			if (f.Project != "" && l.Project != f.Project) || (f.Agent != "" && l.AgentType != f.Agent) {
+				return nil
+			}
+			l.Invocations = parser.FilterInvocations(rec.Invocations, l.Project, f.Since, f.Until)
+
+			b := events.Bucket(k)
+			if b == nil {
+				return nil
+			}
+			err := b.ForEach(func(_, v []byte) error {
+				var e parser.ToolEvent
+				if err := json.Unmarshal(v, &e); err != nil {
+					return fmt.Errorf("store event in %s: %w", k, err)
+				}
+				if (!f.Since.IsZero() && e.Time.Before(f.Since)) || (!f.Until.IsZero() && !e.Time.Before(f.Until)) {
+					return nil
+				}
+				l.Events = append(l.Events, e)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if len(l.Events) > 0 {
+				logs = append(logs, l)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	parser.DropRepeatedCalls(logs)
+	for _, l := range logs {
+		if len(l.Events) > 0 {
+			fn(l)
+		}
+	}
+	return nil
+}
+
+// Info describes the store for diagnostics
+type Info struct {
+	Path   string
+	Size   int64
+	Logs   int
+	Events int
+	Synced time.Time // Zero if never synced
+}
+
+// Info returns the store's size and contents
+func (s *Store) Info() (Info, error) {
+	info := Info{Path: s.db.Path()}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		info.Size = tx.Size()
+		info.Synced, _ = time.Parse(time.RFC3339, string(tx.Bucket(metaBucket).Get([]byte("synced"))))
+		return tx.Bucket(filesBucket).ForEach(func(_, v []byte) error {
+			var rec fileRecord
+			if json.Unmarshal(v, &rec) == nil {
+				info.Logs++
+				info.Events += rec.Events
+			}
+			return nil
+		})
+	})
+	return info, err
+}
+
+// Source adapts a store to parser.StatsSource: every load syncs the store,
+// then aggregates all of its events
+type Source struct {
+	Store *Store
+}
+
+// PermissionStats implements parser.StatsSource
+func (src Source) PermissionStats(projectsDir string, progress chan<- parser.Progress) ([]types.PermissionStats, error) {
+	if err := src.sync(projectsDir, progress); err != nil {
+		return nil, err
+	}
+	return src.Store.PermissionStats(Filter{Dir: projectsDir})
+}
+
+// AgentUsageStats implements parser.StatsSource
+func (src Source) AgentUsageStats(projectsDir string, progress chan<- parser.Progress) ([]types.AgentUsageStats, error) {
+	// Normally the permission load has just synced, so this only stats the
+	// logs; its warnings were recorded then and are not recorded twice
+	if _, err := src.Store.Sync(projectsDir, progress); err != nil {
+		return nil, err
+	}
+	return src.Store.AgentUsageStats(Filter{Dir: projectsDir})
+}
+
+func (src Source) sync(projectsDir string, progress chan<- parser.Progress) error {
+	result, err := src.Store.Sync(projectsDir, progress)
+	parser.RecordWarnings(result.Warnings)
+	return err
+}
+
+// fileHash identifies a log's contents by mtime and size, like the JSON cache
+func fileHash(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size()), nil
+}
+
+// under reports whether the log path is under the directory dir
+func under(path, dir string) bool {
+	return strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// seqKey orders events within a log
+func seqKey(i int) []byte {
+	var k [8]byte
+	binary.BigEndian.PutUint64(k[:], uint64(i))
+	return k[:]
+}
+
+// logID returns a log's session or agent ID, truncated for display
+func logID(path string) string {
+	id := strings.TrimSuffix(filepath.Base(path), ".jsonl")
+	if len(id) > 12 {
+		return id[:12] + "..."
+	}
+	return id
+}
+
+// send delivers a progress update without blocking
+func send(progress chan<- parser.Progress, p parser.Progress) {
+	if progress == nil {
+		return
+	}
+	select {
+	case progress <- p:
+	default:
+	}
+}
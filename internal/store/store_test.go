@@ -0,0 +1,202 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+)
+
+// copyProjects copies the test logs somewhere they can be changed
+func copyProjects(t *testing.T) (projectsDir, sessionLog string) {
+	t.Helper()
+	projectsDir = filepath.Join(t.TempDir(), "projects")
+	dir := filepath.Join(projectsDir, "-test-project")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"sessions-index.json", "session-001.jsonl"} {
+		data, err := os.ReadFile(filepath.Join("../../testdata/projects/-test-project", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return projectsDir, filepath.Join(dir, "session-001.jsonl")
+}
+
+func TestStoreMatchesLoaderAndSyncsIncrementally(t *testing.T) {
+	projectsDir, sessionLog := copyProjects(t)
+	s, err := Open(filepath.Join(t.TempDir(), "events.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	result, err := s.Sync(projectsDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Parsed != 1 || result.Events == 0 {
+		t.Fatalf("first sync = %+v, want the session parsed", result)
+	}
+
+	want, err := parser.LoadAllPermissionStatsFrom(projectsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.PermissionStats(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("store has %d permissions, loader %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Permission.Raw != want[i].Permission.Raw || got[i].Count != want[i].Count ||
+			got[i].Approved != want[i].Approved || got[i].Denied != want[i].Denied {
+			t.Errorf("position %d: store %+v, loader %+v", i, got[i], want[i])
+		}
+	}
+
+	if result, _ := s.Sync(projectsDir, nil); result.Parsed != 0 {
+		t.Errorf("unchanged sync parsed %d logs", result.Parsed)
+	}
+
+	// An appended tool_use re-parses just that log
+	f, err := os.OpenFile(sessionLog, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"type": "assistant", "timestamp": "2026-02-01T09:00:00Z", "message": {"role": "assistant", "content": [{"type": "tool_use", "id": "toolu_99", "name": "Bash", "input": {"command": "make test"}}]}}` + "\n")
+	f.Close()
+	if result, _ := s.Sync(projectsDir, nil); result.Parsed != 1 {
+		t.Errorf("sync after append parsed %d logs, want 1", result.Parsed)
+	}
+
+	recent, err := s.Events(Filter{Since: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 1 || recent[0].Permission != "Bash(make:*)" || recent[0].Project != "/test/project" {
+		t.Errorf("events since Feb 1 = %+v, want only the appended one", recent)
+	}
+
+	os.Remove(sessionLog)
+	os.Remove(filepath.Join(filepath.Dir(sessionLog), "sessions-index.json"))
+	if result, _ := s.Sync(projectsDir, nil); result.Removed != 1 {
+		t.Errorf("sync after delete removed %d logs, want 1", result.Removed)
+	}
+	if info, _ := s.Info(); info.Logs != 0 || info.Events != 0 {
+		t.Errorf("store still has %d logs and %d events", info.Logs, info.Events)
+	}
+}
+
+func TestStoreCountsForkedSessionCallsOnce(t *testing.T) {
+	projectsDir, sessionLog := copyProjects(t)
+	s, err := Open(filepath.Join(t.TempDir(), "events.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if _, err := s.Sync(projectsDir, nil); err != nil {
+		t.Fatal(err)
+	}
+	want, err := s.Events(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A forked session's log starts with a copy of the one it forked. Without
+	// the index, which doesn't list it, the logs are read directly.
+	os.Remove(filepath.Join(filepath.Dir(sessionLog), "sessions-index.json"))
+	data, err := os.ReadFile(sessionLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(filepath.Dir(sessionLog), "session-002.jsonl"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if result, _ := s.Sync(projectsDir, nil); result.Parsed != 1 {
+		t.Fatalf("sync after fork parsed %d logs, want 1", result.Parsed)
+	}
+	got, err := s.Events(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Errorf("store has %d events after the fork, want %d", len(got), len(want))
+	}
+}
+
+func TestSyncKeepsOtherProjectsDirs(t *testing.T) {
+	dirA, _ := copyProjects(t)
+	dirB, _ := copyProjects(t)
+	s, err := Open(filepath.Join(t.TempDir(), "events.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if _, err := s.Sync(dirA, nil); err != nil {
+		t.Fatal(err)
+	}
+	want, err := s.Events(Filter{Dir: dirA})
+	if err != nil || len(want) == 0 {
+		t.Fatalf("events of A = %d, %v; want some", len(want), err)
+	}
+
+	// Syncing another directory, e.g. the demo's, leaves A's logs alone
+	if result, err := s.Sync(dirB, nil); err != nil || result.Removed != 0 {
+		t.Fatalf("sync of B = %+v, %v; want nothing removed", result, err)
+	}
+	got, err := s.Events(Filter{Dir: dirA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Errorf("A has %d events after syncing B, want %d", len(got), len(want))
+	}
+	if b, _ := s.Events(Filter{Dir: dirB}); len(b) != len(want) {
+		t.Errorf("B has %d events, want %d", len(b), len(want))
+	}
+	if result, _ := s.Sync(dirA, nil); result.Parsed != 0 {
+		t.Errorf("sync of A again parsed %d logs, want none", result.Parsed)
+	}
+}
+
+func TestSyncReparsesOtherDirsAfterPatternChange(t *testing.T) {
+	dirA, _ := copyProjects(t)
+	dirB, _ := copyProjects(t)
+	s, err := Open(filepath.Join(t.TempDir(), "events.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	t.Cleanup(func() { parser.SetRedactPatterns(nil) })
+
+	if _, err := s.Sync(dirA, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := parser.SetRedactPatterns([]string{`hunter2`}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Sync(dirB, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// A's logs were redacted with the old patterns, which syncing B must
+	// not make look current
+	result, err := s.Sync(dirA, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Parsed != result.Logs || result.Logs == 0 {
+		t.Errorf("sync of A after the patterns changed parsed %d of %d logs, want all", result.Parsed, result.Logs)
+	}
+}
@@ -1,24 +1,159 @@
 package internal
 
-import "github.com/charmbracelet/lipgloss"
-
-// Colors
-var (
-	ColorPrimary   = lipgloss.Color("12")  // Blue
-	ColorSecondary = lipgloss.Color("244") // Gray
-	ColorSuccess   = lipgloss.Color("10")  // Green
-	ColorWarning   = lipgloss.Color("11")  // Yellow
-	ColorMuted     = lipgloss.Color("240") // Dark gray
-	ColorHighlight = lipgloss.Color("14")  // Cyan
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 )
 
+// Theme is a named color palette that all styles are derived from
+type Theme struct {
+	Name      string
+	Primary   lipgloss.TerminalColor // Titles, active tab, modal borders
+	Secondary lipgloss.TerminalColor // Headers, status bar, inactive tabs
+	Success   lipgloss.TerminalColor // Approved status, added diff lines
+	Warning   lipgloss.TerminalColor // Warnings
+	Error     lipgloss.TerminalColor // Errors, removed diff lines
+	Muted     lipgloss.TerminalColor // Line numbers, pending status
+	Highlight lipgloss.TerminalColor // Selected rows, key hints
+	Title     lipgloss.TerminalColor // Title bar text (drawn on Primary)
+}
+
+// DarkTheme is the default palette for dark terminal backgrounds
+func DarkTheme() Theme {
+	return Theme{
+		Name:      "dark",
+		Primary:   lipgloss.Color("12"),  // Blue
+		Secondary: lipgloss.Color("244"), // Gray
+		Success:   lipgloss.Color("10"),  // Green
+		Warning:   lipgloss.Color("11"),  // Yellow
+		Error:     lipgloss.Color("9"),   // Red
+		Muted:     lipgloss.Color("240"), // Dark gray
+		Highlight: lipgloss.Color("14"),  // Cyan
+		Title:     lipgloss.Color("15"),  // White
+	}
+}
+
+// LightTheme is a palette readable on light terminal backgrounds
+func LightTheme() Theme {
+	return Theme{
+		Name:      "light",
+		Primary:   lipgloss.Color("4"),   // Dark blue
+		Secondary: lipgloss.Color("238"), // Dark gray
+		Success:   lipgloss.Color("28"),  // Dark green
+		Warning:   lipgloss.Color("130"), // Dark orange
+		Error:     lipgloss.Color("124"), // Dark red
+		Muted:     lipgloss.Color("245"), // Mid gray
+		Highlight: lipgloss.Color("25"),  // Deep blue
+		Title:     lipgloss.Color("15"),  // White
+	}
+}
+
+// HighContrastTheme uses only bright base colors for maximum legibility
+func HighContrastTheme() Theme {
+	return Theme{
+		Name:      "high-contrast",
+		Primary:   lipgloss.Color("15"), // Bright white
+		Secondary: lipgloss.Color("15"),
+		Success:   lipgloss.Color("10"),
+		Warning:   lipgloss.Color("11"),
+		Error:     lipgloss.Color("9"),
+		Muted:     lipgloss.Color("7"), // Light gray
+		Highlight: lipgloss.Color("11"),
+		Title:     lipgloss.Color("0"), // Black on white
+	}
+}
+
+// NoColorTheme disables all colors, relying on bold/underline/reverse only
+func NoColorTheme() Theme {
+	return Theme{
+		Name:      "none",
+		Primary:   lipgloss.NoColor{},
+		Secondary: lipgloss.NoColor{},
+		Success:   lipgloss.NoColor{},
+		Warning:   lipgloss.NoColor{},
+		Error:     lipgloss.NoColor{},
+		Muted:     lipgloss.NoColor{},
+		Highlight: lipgloss.NoColor{},
+		Title:     lipgloss.NoColor{},
+	}
+}
+
+// ThemeByName returns the built-in theme with the given name
+func ThemeByName(name string) (Theme, bool) {
+	switch name {
+	case "", "dark":
+		return DarkTheme(), true
+	case "light":
+		return LightTheme(), true
+	case "high-contrast":
+		return HighContrastTheme(), true
+	case "none", "no-color":
+		return NoColorTheme(), true
+	}
+	return DarkTheme(), false
+}
+
+// WithOverrides returns a copy of the theme with individual colors replaced.
+// Keys are role names (primary, secondary, success, ...); values are ANSI
+// color numbers ("12") or hex colors ("#ff8800", "#f80").
+func (t Theme) WithOverrides(colors map[string]string) (Theme, error) {
+	roles := map[string]*lipgloss.TerminalColor{
+		"primary":   &t.Primary,
+		"secondary": &t.Secondary,
+		"success":   &t.Success,
+		"warning":   &t.Warning,
+		"error":     &t.Error,
+		"muted":     &t.Muted,
+		"highlight": &t.Highlight,
+		"title":     &t.Title,
+	}
+	for role, value := range colors {
+		c, ok := roles[role]
+		if !ok {
+			return t, fmt.Errorf("unknown color role %q (want %s)", role, strings.Join(slices.Sorted(maps.Keys(roles)), ", "))
+		}
+		if !validColor(value) {
+			return t, fmt.Errorf("invalid %s color %q (want an ANSI number from 0 to 255 or a hex color like #ff8800)", role, value)
+		}
+		*c = lipgloss.Color(value)
+	}
+	return t, nil
+}
+
+// validColor reports whether value is an ANSI color number or a #rgb or
+// #rrggbb hex color
+func validColor(value string) bool {
+	if n, err := strconv.Atoi(value); err == nil {
+		return n >= 0 && n <= 255
+	}
+	hex, ok := strings.CutPrefix(value, "#")
+	if !ok || (len(hex) != 3 && len(hex) != 6) {
+		return false
+	}
+	_, err := strconv.ParseUint(hex, 16, 32)
+	return err == nil
+}
+
+// NoColorRequested reports whether the NO_COLOR convention is in effect
+// (https://no-color.org: any non-empty value disables color)
+func NoColorRequested() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
+
 // Styles holds all the lipgloss styles for the TUI
 type Styles struct {
 	// Layout
-	App         lipgloss.Style
-	TitleBar    lipgloss.Style
-	StatusBar   lipgloss.Style
-	Content     lipgloss.Style
+	App       lipgloss.Style
+	TitleBar  lipgloss.Style
+	StatusBar lipgloss.Style
+	Content   lipgloss.Style
 
 	// Tabs
 	Tab         lipgloss.Style
@@ -31,38 +166,55 @@ type Styles struct {
 	ListHeader       lipgloss.Style
 
 	// Columns
-	ColCount    lipgloss.Style
-	ColPerm     lipgloss.Style
-	ColTime     lipgloss.Style
-	ColStatus   lipgloss.Style
+	ColCount  lipgloss.Style
+	ColPerm   lipgloss.Style
+	ColTime   lipgloss.Style
+	ColStatus lipgloss.Style
 
 	// Modal
-	Modal       lipgloss.Style
-	ModalTitle  lipgloss.Style
-	ModalBox    lipgloss.Style
+	Modal      lipgloss.Style
+	ModalTitle lipgloss.Style
+	ModalBox   lipgloss.Style
 
 	// Status indicators
 	StatusApproved lipgloss.Style
 	StatusPending  lipgloss.Style
+	StatusWarning  lipgloss.Style
+	Toast          lipgloss.Style // Success
+	ToastInfo      lipgloss.Style
+	ToastWarn      lipgloss.Style
+	ToastError     lipgloss.Style
+	Error          lipgloss.Style
+
+	// Diff preview
+	DiffAdded   lipgloss.Style
+	DiffRemoved lipgloss.Style
+	DiffLineNum lipgloss.Style
+	DiffPath    lipgloss.Style
+
+	// Loading screen
+	LoadingTitle   lipgloss.Style
+	LoadingStatus  lipgloss.Style
+	LoadingSession lipgloss.Style
 
 	// Help
 	HelpKey  lipgloss.Style
 	HelpDesc lipgloss.Style
 }
 
-// DefaultStyles returns the default style configuration
-func DefaultStyles() Styles {
-	return Styles{
+// NewStyles builds the style set from a theme
+func NewStyles(t Theme) Styles {
+	s := Styles{
 		App: lipgloss.NewStyle(),
 
 		TitleBar: lipgloss.NewStyle().
 			Bold(true).
 			Padding(0, 1).
-			Background(ColorPrimary).
-			Foreground(lipgloss.Color("15")),
+			Background(t.Primary).
+			Foreground(t.Title),
 
 		StatusBar: lipgloss.NewStyle().
-			Foreground(ColorSecondary).
+			Foreground(t.Secondary).
 			Padding(0, 1),
 
 		Content: lipgloss.NewStyle().
@@ -74,24 +226,24 @@ func DefaultStyles() Styles {
 		TabActive: lipgloss.NewStyle().
 			Padding(0, 2).
 			Bold(true).
-			Foreground(ColorPrimary).
+			Foreground(t.Primary).
 			Underline(true),
 
 		TabInactive: lipgloss.NewStyle().
 			Padding(0, 2).
-			Foreground(ColorSecondary),
+			Foreground(t.Secondary),
 
 		ListItem: lipgloss.NewStyle().
 			PaddingLeft(2),
 
 		ListItemSelected: lipgloss.NewStyle().
 			PaddingLeft(0).
-			Foreground(ColorHighlight).
+			Foreground(t.Highlight).
 			Bold(true),
 
 		ListHeader: lipgloss.NewStyle().
 			Bold(true).
-			Foreground(ColorSecondary).
+			Foreground(t.Secondary).
 			PaddingLeft(2),
 
 		ColCount: lipgloss.NewStyle().
@@ -103,44 +255,122 @@ func DefaultStyles() Styles {
 
 		ColTime: lipgloss.NewStyle().
 			Width(10).
-			Foreground(ColorMuted),
+			Foreground(t.Muted),
 
 		ColStatus: lipgloss.NewStyle().
 			Width(8),
 
 		Modal: lipgloss.NewStyle().
 			Border(lipgloss.RoundedBorder()).
-			BorderForeground(ColorPrimary).
+			BorderForeground(t.Primary).
 			Padding(1, 2),
 
 		ModalTitle: lipgloss.NewStyle().
 			Bold(true).
-			Foreground(ColorPrimary).
+			Foreground(t.Primary).
 			MarginBottom(1),
 
 		ModalBox: lipgloss.NewStyle().
 			Border(lipgloss.NormalBorder()).
-			BorderForeground(ColorSecondary).
+			BorderForeground(t.Secondary).
 			Padding(0, 1).
 			MarginTop(1),
 
 		StatusApproved: lipgloss.NewStyle().
-			Foreground(ColorSuccess),
+			Foreground(t.Success),
 
 		StatusPending: lipgloss.NewStyle().
-			Foreground(ColorMuted),
+			Foreground(t.Muted),
+
+		StatusWarning: lipgloss.NewStyle().
+			Foreground(t.Warning),
+
+		Toast: lipgloss.NewStyle().
+			Foreground(t.Success).
+			Bold(true).
+			Padding(0, 1),
+
+		ToastInfo: lipgloss.NewStyle().
+			Foreground(t.Highlight).
+			Bold(true).
+			Padding(0, 1),
+
+		ToastWarn: lipgloss.NewStyle().
+			Foreground(t.Warning).
+			Bold(true).
+			Padding(0, 1),
+
+		ToastError: lipgloss.NewStyle().
+			Foreground(t.Error).
+			Bold(true).
+			Padding(0, 1),
+
+		Error: lipgloss.NewStyle().
+			Foreground(t.Error),
+
+		DiffAdded: lipgloss.NewStyle().
+			Foreground(t.Success),
+
+		DiffRemoved: lipgloss.NewStyle().
+			Foreground(t.Error),
+
+		DiffLineNum: lipgloss.NewStyle().
+			Foreground(t.Muted),
+
+		DiffPath: lipgloss.NewStyle().
+			Foreground(t.Secondary).
+			Italic(true),
+
+		LoadingTitle: lipgloss.NewStyle().
+			Foreground(t.Primary).
+			Bold(true),
+
+		LoadingStatus: lipgloss.NewStyle().
+			Foreground(t.Secondary).
+			Italic(true),
+
+		LoadingSession: lipgloss.NewStyle().
+			Foreground(t.Muted).
+			Italic(true),
 
 		HelpKey: lipgloss.NewStyle().
-			Foreground(ColorHighlight).
+			Foreground(t.Highlight).
 			Bold(true),
 
 		HelpDesc: lipgloss.NewStyle().
-			Foreground(ColorSecondary),
+			Foreground(t.Secondary),
 	}
+
+	// Without color the selected row needs another visual cue
+	if _, ok := t.Highlight.(lipgloss.NoColor); ok {
+		s.ListItemSelected = s.ListItemSelected.Reverse(true)
+		s.TitleBar = s.TitleBar.Reverse(true)
+	}
+
+	return s
+}
+
+// DefaultStyles returns the default style configuration
+func DefaultStyles() Styles {
+	return NewStyles(DarkTheme())
 }
 
-// Global styles instance
-var styles = DefaultStyles()
+// Global theme and styles instance
+var (
+	theme  = DarkTheme()
+	styles = NewStyles(theme)
+)
+
+// SetTheme switches the active theme and rebuilds the global styles.
+// The no-color theme also downgrades the renderer so no escape codes
+// for color are emitted at all.
+func SetTheme(t Theme) {
+	if t.Name == "none" {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+	theme = t
+	styles = NewStyles(t)
+}
 
 // GetStyles returns the global styles
 func GetStyles() Styles {
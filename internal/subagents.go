@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/b-open-io/claude-perms/internal/types"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// toggleSubagents switches the Frequency view between the main sessions'
+// tool calls and those plus every subagent's
+func (m Model) toggleSubagents() (tea.Model, tea.Cmd) {
+	m.withSubagents = !m.withSubagents
+	gi, _ := m.freqCursor()
+	m.regroupPermissions()
+	m.selectFreqRow(gi, -1)
+
+	if m.withSubagents {
+		m.notify(toastInfo, "Counting subagent tool calls too (agent logs have no allow/deny outcomes)")
+	} else {
+		m.notify(toastInfo, "Counting main session tool calls only")
+	}
+	return m, m.toastTickCmd()
+}
+
+// attributionText summarizes who made a permission's calls, e.g. "main 30 ·
+// subagents 12 · unknown 1", or "" when the main session made them all
+func attributionText(p types.PermissionStats) string {
+	if p.SubagentCount() == 0 {
+		return ""
+	}
+	text := fmt.Sprintf("main %d · subagents %d", p.ByAttribution[types.AttributedMain], p.ByAttribution[types.AttributedAgent])
+	if unknown := p.ByAttribution[types.AttributedUnknown]; unknown > 0 {
+		text += fmt.Sprintf(" · unknown %d", unknown)
+	}
+	return text
+}
@@ -0,0 +1,218 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/audit"
+	"github.com/b-open-io/claude-perms/internal/parser"
+)
+
+// SessionAction records a change made through the TUI during this session
+type SessionAction struct {
+	Time       time.Time `json:"time"`
+	Action     string    `json:"action"`     // "allow", "deny", "remove" or "tools"
+	Permission string    `json:"permission"` // Raw permission string, or the tools line for "tools"
+	Scope      string    `json:"scope"`      // "user", "project", "agent" or "command"
+	File       string    `json:"file"`       // Settings or agent file written
+	Line       int       `json:"line,omitempty"`
+	Changed    bool      `json:"changed"`           // False if the entry already existed (or, for a removal, was already gone)
+	DryRun     bool      `json:"dry_run,omitempty"` // Nothing was written; the action shows what would change
+}
+
+// recordApply appends an apply result to the session action log
+func (m *Model) recordApply(scope string, result *parser.ApplyResult) {
+	m.record(SessionAction{
+		Time:       time.Now(),
+		Action:     "allow",
+		Permission: result.Permission,
+		Scope:      scope,
+		File:       result.FilePath,
+		Line:       result.LineNumber,
+		Changed:    result.WasNew,
+		DryRun:     result.DryRun,
+	})
+}
+
+// recordDeny logs a permission added to the user deny list
+func (m *Model) recordDeny(result *parser.ApplyResult) {
+	m.record(SessionAction{
+		Time:       time.Now(),
+		Action:     "deny",
+		Permission: result.Permission,
+		Scope:      "user",
+		File:       result.FilePath,
+		Line:       result.LineNumber,
+		Changed:    result.WasNew,
+		DryRun:     result.DryRun,
+	})
+}
+
+// recordRemove logs an allow rule removed from user settings, or from
+// project's settings if project is set
+func (m *Model) recordRemove(project string, result *parser.ApplyResult) {
+	scope := "user"
+	if project != "" {
+		scope = "project"
+	}
+	m.record(SessionAction{
+		Time:       time.Now(),
+		Action:     "remove",
+		Permission: result.Permission,
+		Scope:      scope,
+		File:       result.FilePath,
+		Line:       result.LineNumber,
+		Changed:    result.WasNew,
+		DryRun:     result.DryRun,
+	})
+}
+
+// recordRuleSet logs the rules a template added to and removed from user
+// or project settings
+func (m *Model) recordRuleSet(scope string, result *parser.RuleSetResult) {
+	for _, list := range []struct {
+		action string
+		rules  []string
+	}{{"allow", result.Allowed}, {"deny", result.Denied}, {"remove", result.Removed}} {
+		for _, r := range list.rules {
+			m.record(SessionAction{
+				Time:       time.Now(),
+				Action:     list.action,
+				Permission: r,
+				Scope:      scope,
+				File:       result.FilePath,
+				Changed:    true,
+				DryRun:     result.DryRun,
+			})
+		}
+	}
+}
+
+// record appends a to the session action log and, unless it was a dry
+// run, the persistent audit log
+func (m *Model) record(a SessionAction) {
+	m.actions = append(m.actions, a)
+	if a.DryRun {
+		return
+	}
+	m.audit(audit.Entry{
+		Time:       a.Time,
+		Action:     a.Action,
+		Permission: a.Permission,
+		Scope:      a.Scope,
+		File:       a.File,
+		Changed:    a.Changed,
+	})
+}
+
+// audit appends e to the audit log. A failure is logged rather than shown:
+// the settings change it describes has already been made.
+func (m *Model) audit(e audit.Entry) {
+	if err := audit.Append(e); err != nil {
+		m.logger.Warn("audit log write failed", "err", err)
+	}
+}
+
+// Actions returns every change made during the session, in order
+func (m Model) Actions() []SessionAction {
+	return m.actions
+}
+
+// FormatSummary renders the session actions for the terminal or scripts.
+// format is "text" or "json".
+func FormatSummary(actions []SessionAction, format string) (string, error) {
+	switch format {
+	case "json":
+		if actions == nil {
+			actions = []SessionAction{}
+		}
+		data, err := json.MarshalIndent(actions, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(data) + "\n", nil
+	case "", "text":
+		return formatSummaryText(actions), nil
+	}
+	return "", CheckSummaryFormat(format)
+}
+
+// CheckSummaryFormat returns an error if FormatSummary doesn't know format,
+// so a bad flag is reported before the session rather than after it
+func CheckSummaryFormat(format string) error {
+	switch format {
+	case "", "text", "json":
+		return nil
+	}
+	return fmt.Errorf("unknown summary format %q (want text or json)", format)
+}
+
+func formatSummaryText(actions []SessionAction) string {
+	if len(actions) == 0 {
+		return "perms: no changes made\n"
+	}
+
+	var b strings.Builder
+	added, removed, edited, planned := 0, 0, 0, 0
+	files := make(map[string]int)
+	for _, a := range actions {
+		switch {
+		case a.Changed && a.DryRun:
+			planned++
+		case a.Changed && a.Action == "remove":
+			removed++
+			files[a.File]++
+		case a.Changed && a.Action == "tools":
+			edited++
+			files[a.File]++
+		case a.Changed:
+			added++
+			files[a.File]++
+		}
+	}
+
+	fmt.Fprintf(&b, "perms: %d rule(s) added", added)
+	if removed > 0 {
+		fmt.Fprintf(&b, ", %d removed", removed)
+	}
+	if edited > 0 {
+		fmt.Fprintf(&b, ", %d agent tools line(s) set", edited)
+	}
+	fmt.Fprintf(&b, ", %d file(s) touched", len(files))
+	if planned > 0 {
+		fmt.Fprintf(&b, ", %d planned in dry run", planned)
+	}
+	b.WriteString("\n")
+	for _, a := range actions {
+		status := "+"
+		switch {
+		case !a.Changed:
+			status = "="
+		case a.DryRun:
+			status = "~"
+		case a.Action == "remove":
+			status = "-"
+		}
+		loc := a.File
+		if a.Line > 0 {
+			loc = fmt.Sprintf("%s:%d", a.File, a.Line)
+		}
+		fmt.Fprintf(&b, "  %s %-6s %-7s %-40s %s\n", status, a.Action, a.Scope, a.Permission, loc)
+	}
+
+	if len(files) > 0 {
+		paths := make([]string, 0, len(files))
+		for f := range files {
+			paths = append(paths, f)
+		}
+		sort.Strings(paths)
+		b.WriteString("files:\n")
+		for _, f := range paths {
+			fmt.Fprintf(&b, "  %s (%d)\n", f, files[f])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,273 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/templates"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxVisibleTemplates is how many templates the picker lists at once
+const maxVisibleTemplates = 10
+
+// openTemplates opens the template picker, reading the user's templates
+// afresh so edits to them show without a restart
+func (m Model) openTemplates() (tea.Model, tea.Cmd) {
+	all, warns := templates.Load(templates.Dir())
+	m.templates = all
+	m.showTemplates = true
+	m.templatePicked = false
+	m.templateProject = false
+	m.templateReplace = false
+	m.templateList.top()
+	if len(warns) > 0 {
+		m.notify(toastWarn, "Skipped %d template file(s): %s", len(warns), warns[0])
+		return m, m.toastTickCmd()
+	}
+	return m, nil
+}
+
+// selectedTemplate returns the template under the picker's cursor
+func (m Model) selectedTemplate() (templates.Template, bool) {
+	if m.templateList.cursor >= len(m.templates) {
+		return templates.Template{}, false
+	}
+	return m.templates[m.templateList.cursor], true
+}
+
+// templatePath returns the settings file the template is applied to
+func (m Model) templatePath() string {
+	if m.templateProject {
+		return parser.ProjectSettingsPath(m.projectPath)
+	}
+	return parser.UserSettingsPath()
+}
+
+// handleTemplatesKeys moves through the templates and, after Enter on one,
+// picks where to apply it and how to settle its conflicts
+func (m Model) handleTemplatesKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	n := len(m.templates)
+	switch {
+	case key.Matches(msg, m.keys.ForceQuit):
+		return m, tea.Quit
+
+	case m.templatePicked && key.Matches(msg, m.keys.Back):
+		m.templatePicked = false
+
+	case key.Matches(msg, m.keys.Back, m.keys.Quit, m.keys.Templates):
+		m.showTemplates = false
+
+	case m.templatePicked && key.Matches(msg, m.keys.Up):
+		m.templateProject = false
+
+	case m.templatePicked && key.Matches(msg, m.keys.Down):
+		m.templateProject = m.projectPath != ""
+
+	case m.templatePicked && key.Matches(msg, m.keys.Toggle):
+		m.templateReplace = !m.templateReplace
+
+	case m.templatePicked && key.Matches(msg, m.keys.Select):
+		return m.applyTemplate()
+
+	case key.Matches(msg, m.keys.Down):
+		m.templateList.down(n, maxVisibleTemplates)
+
+	case key.Matches(msg, m.keys.Up):
+		m.templateList.up(n, maxVisibleTemplates)
+
+	case key.Matches(msg, m.keys.Top):
+		m.templateList.top()
+
+	case key.Matches(msg, m.keys.Bottom):
+		m.templateList.bottom(n, maxVisibleTemplates)
+
+	case key.Matches(msg, m.keys.Select):
+		if _, ok := m.selectedTemplate(); ok {
+			m.templatePicked = true
+		}
+	}
+	return m, nil
+}
+
+// writeRuleSet applies set to the settings file at path, or in a dry run
+// reports what that would change
+func (m Model) writeRuleSet(path string, set parser.RuleSet, replace bool) (*parser.RuleSetResult, error) {
+	if m.dryRun {
+		_, _, result, err := parser.PlanRuleSet(path, set, replace)
+		return result, err
+	}
+	return parser.WriteRuleSet(path, set, replace)
+}
+
+// applyTemplate writes the selected template to the chosen settings file in
+// one write
+func (m Model) applyTemplate() (tea.Model, tea.Cmd) {
+	if m.writeBlockedReason() != "" {
+		return m.writeBlocked()
+	}
+	t, ok := m.selectedTemplate()
+	if !ok {
+		return m, nil
+	}
+	path := m.templatePath()
+	result, err := m.writeRuleSet(path, t.Rules(), m.templateReplace)
+	if err != nil {
+		return m, m.failWrite(path, err, Model.applyTemplate)
+	}
+
+	scope := "user"
+	if m.templateProject {
+		scope = "project"
+	}
+	m.recordRuleSet(scope, result)
+	if !result.DryRun {
+		if m.templateProject {
+			m.projectSettings[m.projectPath] = withRuleSet(m.projectSettings[m.projectPath], result)
+		} else {
+			m.userApproved = withRuleSet(m.userApproved, result)
+		}
+	}
+
+	skipped := ""
+	if n := len(result.Conflicts); n > 0 && !result.Replaced {
+		skipped = fmt.Sprintf(", %d conflicting skipped", n)
+	}
+	added := fmt.Sprintf("%d allowed, %d denied", len(result.Allowed), len(result.Denied))
+	if n := len(result.Removed); n > 0 {
+		added += fmt.Sprintf(", %d of the file's replaced", n)
+	}
+	added += skipped
+	switch {
+	case !result.Changed():
+		m.notify(toastInfo, "%s: every rule is already in %s%s", t.Name, shortenPath(path), skipped)
+	case result.DryRun:
+		m.notify(toastInfo, "Dry run: %s would change %s: %s", t.Name, shortenPath(path), added)
+	default:
+		m.notify(toastSuccess, "Applied %s to %s: %s", t.Name, shortenPath(path), added)
+	}
+	m.finishModal()
+	return m, m.toastTickCmd()
+}
+
+// withRuleSet returns the allow rules of a settings file as they are after
+// applying a rule set to it
+func withRuleSet(allow []string, result *parser.RuleSetResult) []string {
+	kept := slices.DeleteFunc(slices.Clone(allow), func(r string) bool { return slices.Contains(result.Removed, r) })
+	return append(kept, result.Allowed...)
+}
+
+// renderTemplatesModal renders the template list or, after one is picked,
+// where it goes, its conflicts with that file and the diff of applying it
+func (m Model) renderTemplatesModal() string {
+	modalWidth := min(max(m.width*85/100, 50), 90)
+	inner := modalWidth - 6 // border + padding
+
+	t, ok := m.selectedTemplate()
+	if m.templatePicked && ok {
+		return styles.Modal.Width(modalWidth).Render(m.renderTemplatePreview(t, inner))
+	}
+
+	var b strings.Builder
+	b.WriteString(styles.ModalTitle.Render("Settings Templates"))
+	b.WriteString("\n\n")
+	b.WriteString(styles.HelpDesc.Render(truncateString("  Add your own as JSON files in "+shortenPath(templates.Dir()), inner)) + "\n\n")
+
+	nameWidth := 0
+	for _, t := range m.templates {
+		nameWidth = max(nameWidth, len([]rune(t.Name)))
+	}
+	start, end := m.templateList.window(len(m.templates), maxVisibleTemplates)
+	for i := start; i < end; i++ {
+		t := m.templates[i]
+		line := fmt.Sprintf("%-*s  %3d allow  %2d deny  %s", nameWidth, t.Name, len(t.Allow), len(t.Deny), t.Description)
+		if t.Path != "" {
+			line += " (yours)"
+		}
+		if i == m.templateList.cursor {
+			b.WriteString(styles.ListItemSelected.Render(truncateString("> "+line, inner)) + "\n")
+		} else {
+			b.WriteString(truncateString("  "+line, inner) + "\n")
+		}
+	}
+	if len(m.templates) > maxVisibleTemplates {
+		b.WriteString(styles.HelpDesc.Render(fmt.Sprintf("  %d of %d", m.templateList.cursor+1, len(m.templates))) + "\n")
+	}
+
+	b.WriteString("\n" + renderHints(m.contextBindings()))
+	return styles.Modal.Width(modalWidth).Render(b.String())
+}
+
+// renderTemplatePreview renders the picked template's targets, conflicts and
+// diff
+func (m Model) renderTemplatePreview(t templates.Template, inner int) string {
+	var b strings.Builder
+	b.WriteString(styles.ModalTitle.Render("Apply Template: " + t.Name))
+	b.WriteString("\n\n")
+	if t.Description != "" {
+		b.WriteString(truncateString("  "+t.Description, inner) + "\n")
+	}
+	b.WriteString(styles.HelpDesc.Render(fmt.Sprintf("  %d allow and %d deny rule(s)", len(t.Allow), len(t.Deny))) + "\n\n")
+	b.WriteString(m.renderWriteModeNotice())
+
+	options := []string{"Apply to User (all projects)"}
+	if m.projectPath != "" {
+		options = append(options, "Apply to this project ("+m.projectPath+")")
+	}
+	for i, opt := range options {
+		switch {
+		case (i == 1) == m.templateProject:
+			b.WriteString(styles.ListItemSelected.Render("> " + opt))
+		case m.writeBlockedReason() != "":
+			b.WriteString(styles.StatusPending.Render("  " + opt))
+		default:
+			b.WriteString(styles.ListItem.Render("  " + opt))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+
+	path := m.templatePath()
+	before, after, result, err := parser.PlanRuleSet(path, t.Rules(), m.templateReplace)
+	if err != nil {
+		b.WriteString(renderDiffPreviewError(path, err))
+		b.WriteString("\n" + renderHints(m.contextBindings()))
+		return b.String()
+	}
+
+	if len(result.Conflicts) > 0 {
+		how := "skipped"
+		if m.templateReplace {
+			how = "replace the file's rules"
+		}
+		b.WriteString(styles.StatusPending.Render(fmt.Sprintf("  %d conflict(s) with the file's rules: %s", len(result.Conflicts), how)) + "\n")
+		for i, c := range result.Conflicts {
+			if i == 4 && len(result.Conflicts) > 5 {
+				b.WriteString(styles.HelpDesc.Render(fmt.Sprintf("    +%d more", len(result.Conflicts)-i)) + "\n")
+				break
+			}
+			line := "allow " + c.Rule + ", denied by " + strings.Join(c.Existing, ", ")
+			if c.Deny {
+				line = "deny " + c.Rule + ", overrules allow " + strings.Join(c.Existing, ", ")
+			}
+			b.WriteString(truncateString("    "+line, inner) + "\n")
+		}
+		b.WriteString("\n")
+	}
+
+	room := max(m.height-24, 4)
+	b.WriteString(renderDiffPreview(path, capDiff(parser.DiffContents(before, after), room), !result.Changed(), 74))
+	if len(result.Allowed) > 0 {
+		project := ""
+		if m.templateProject {
+			project = m.projectPath
+		}
+		b.WriteString(m.renderCoverage(result.Allowed, project))
+	}
+
+	b.WriteString("\n" + renderHints(m.contextBindings()))
+	return b.String()
+}
@@ -0,0 +1,148 @@
+// Package templates provides curated sets of allow and deny rules for a kind
+// of work, e.g. Go development, that are applied to a settings file in one
+// go. A few ship built in; users add their own, or replace a built-in one, as
+// JSON files in the templates directory.
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+)
+
+// Template is a named set of rules
+type Template struct {
+	Name        string   `json:"name"` // Defaults to the file name
+	Description string   `json:"description,omitempty"`
+	Allow       []string `json:"allow,omitempty"`
+	Deny        []string `json:"deny,omitempty"`
+
+	Path string `json:"-"` // File it was read from; "" for a built-in template
+}
+
+// Rules returns the template's rules for applying to a settings file
+func (t Template) Rules() parser.RuleSet {
+	return parser.RuleSet{Allow: t.Allow, Deny: t.Deny}
+}
+
+// Builtin are the templates that ship with perms
+var Builtin = []Template{
+	{
+		Name:        "Go development",
+		Description: "Build, test and lint Go modules; read git history",
+		Allow: []string{
+			"Bash(go build:*)", "Bash(go test:*)", "Bash(go vet:*)", "Bash(go mod tidy:*)", "Bash(gofmt:*)",
+			"Bash(golangci-lint run:*)", "Bash(git status:*)", "Bash(git diff:*)", "Bash(git log:*)",
+			"Read", "Glob", "Grep",
+		},
+		Deny: []string{"Bash(git push --force:*)", "Read(./.env)"},
+	},
+	{
+		Name:        "Node frontend",
+		Description: "Run package scripts, type-check, lint and format",
+		Allow: []string{
+			"Bash(npm run:*)", "Bash(npm test:*)", "Bash(npm install:*)", "Bash(npx tsc:*)", "Bash(npx eslint:*)",
+			"Bash(npx prettier:*)", "Bash(git status:*)", "Bash(git diff:*)", "Bash(git log:*)",
+			"Read", "Glob", "Grep",
+		},
+		Deny: []string{"Bash(npm publish:*)", "Read(./.env)", "Read(./.env.*)"},
+	},
+	{
+		Name:        "Ops/terraform",
+		Description: "Plan infrastructure and inspect clusters, never change them",
+		Allow: []string{
+			"Bash(terraform fmt:*)", "Bash(terraform validate:*)", "Bash(terraform init:*)", "Bash(terraform plan:*)",
+			"Bash(kubectl get:*)", "Bash(kubectl describe:*)", "Bash(kubectl logs:*)", "Bash(helm template:*)",
+			"Read", "Glob", "Grep",
+		},
+		Deny: []string{
+			"Bash(terraform apply:*)", "Bash(terraform destroy:*)", "Bash(kubectl apply:*)", "Bash(kubectl delete:*)",
+			"Read(~/.aws/credentials)",
+		},
+	},
+}
+
+// dirOverride replaces the default templates directory when set
+var dirOverride string
+
+// Dir returns the directory of user templates (~/.claude/perms-templates)
+func Dir() string {
+	if dirOverride != "" {
+		return dirOverride
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".claude", "perms-templates")
+}
+
+// SetDir reads user templates from dir instead of the default. An empty dir
+// restores the default.
+func SetDir(dir string) {
+	dirOverride = dir
+}
+
+// Load returns the built-in templates followed by the *.json templates in
+// dir, by name. A user template named like a built-in one replaces it. A
+// file that can't be read, or has no rules, is reported and left out; a
+// missing dir holds no templates.
+func Load(dir string) ([]Template, []parser.Warning) {
+	all := append([]Template(nil), Builtin...)
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	sort.Strings(paths)
+
+	var user []Template
+	var warns []parser.Warning
+	for _, path := range paths {
+		t, err := read(path)
+		if err != nil {
+			warns = append(warns, parser.Warning{File: path, Reason: err.Error()})
+			continue
+		}
+		replaced := false
+		for i := range all {
+			if strings.EqualFold(all[i].Name, t.Name) {
+				all[i], replaced = t, true
+				break
+			}
+		}
+		if !replaced {
+			user = append(user, t)
+		}
+	}
+	sort.SliceStable(user, func(i, j int) bool { return strings.ToLower(user[i].Name) < strings.ToLower(user[j].Name) })
+	return append(all, user...), warns
+}
+
+// read reads a template file
+func read(path string) (Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Template{}, err
+	}
+	var t Template
+	if err := json.Unmarshal(data, &t); err != nil {
+		return Template{}, fmt.Errorf("invalid template: %w", err)
+	}
+	if len(t.Allow)+len(t.Deny) == 0 {
+		return Template{}, fmt.Errorf("template has no allow or deny rules")
+	}
+	if t.Name == "" {
+		t.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+	}
+	t.Path = path
+	return t, nil
+}
+
+// Find returns the template of all named name, ignoring case
+func Find(all []Template, name string) (Template, bool) {
+	for _, t := range all {
+		if strings.EqualFold(t.Name, strings.TrimSpace(name)) {
+			return t, true
+		}
+	}
+	return Template{}, false
+}
@@ -0,0 +1,43 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadAddsAndReplacesTemplates(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"rust.json":   `{"description":"Cargo","allow":["Bash(cargo build:*)","Bash(cargo test:*)"]}`,
+		"go.json":     `{"name":"go development","allow":["Bash(go:*)"],"deny":["Bash(go get:*)"]}`,
+		"empty.json":  `{"name":"Empty"}`,
+		"broken.json": `{"allow":`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, warns := Load(dir)
+	if len(warns) != 2 {
+		t.Errorf("warnings = %v, want the empty and broken files", warns)
+	}
+	if len(all) != len(Builtin)+1 {
+		t.Fatalf("loaded %d templates, want the built-in ones and rust", len(all))
+	}
+	if all[0].Name != "go development" || all[0].Path == "" || len(all[0].Deny) != 1 {
+		t.Errorf("first template = %+v, want the user's Go development in place of the built-in", all[0])
+	}
+	if last := all[len(all)-1]; last.Name != "rust" || len(last.Rules().Allow) != 2 {
+		t.Errorf("last template = %+v, want rust named after its file", last)
+	}
+}
+
+func TestLoadMissingDir(t *testing.T) {
+	all, warns := Load(filepath.Join(t.TempDir(), "none"))
+	if len(all) != len(Builtin) || len(warns) != 0 {
+		t.Errorf("Load = %d templates, %v; want only the built-in ones", len(all), warns)
+	}
+}
@@ -0,0 +1,244 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// toastLevel is how a toast is colored and how long it stays up
+type toastLevel int
+
+const (
+	toastInfo toastLevel = iota
+	toastSuccess
+	toastWarn
+	toastError
+)
+
+// toastTicks is how many seconds a toast of each level stays up; errors
+// stay longest since their keys (see writeFailure) only work meanwhile
+var toastTicks = map[toastLevel]int{
+	toastInfo:    3,
+	toastSuccess: 4,
+	toastWarn:    5,
+	toastError:   10,
+}
+
+// toastIcons mark the level of each toast in the history
+var toastIcons = map[toastLevel]string{
+	toastInfo:    "·",
+	toastSuccess: "✓",
+	toastWarn:    "!",
+	toastError:   "✗",
+}
+
+// toastLogLimit caps the toasts kept for the history popup
+const toastLogLimit = 200
+
+// toast is a message shown in place of the status bar, or in a panel above
+// it when it doesn't fit on one line
+type toast struct {
+	level   toastLevel
+	text    string
+	at      time.Time
+	ticks   int  // Seconds left on screen once it is the one showing
+	failure bool // Reports the write kept in Model.writeFailure
+}
+
+// toastTickMsg counts down the toast showing. seq tells a current countdown
+// from those a later toast replaced.
+type toastTickMsg struct {
+	seq int
+}
+
+// notify queues a toast. Toasts show one at a time, each for the time its
+// level allows or until a key is pressed, and are kept for the history.
+func (m *Model) notify(level toastLevel, format string, args ...any) {
+	text := format
+	if len(args) > 0 {
+		text = fmt.Sprintf(format, args...)
+	}
+	t := toast{level: level, text: text, at: now(), ticks: toastTicks[level]}
+	if m.toastWraps(t) {
+		t.ticks += 2 // A longer read
+	}
+	m.toasts = append(m.toasts, t)
+	m.toastLog = append(m.toastLog, t)
+	if n := len(m.toastLog) - toastLogLimit; n > 0 {
+		m.toastLog = m.toastLog[n:]
+	}
+	m.toastSeq++
+}
+
+// toastTickCmd starts the countdown of the toast showing. A new toast
+// restarts it, so only the latest countdown runs.
+func (m Model) toastTickCmd() tea.Cmd {
+	seq := m.toastSeq
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return toastTickMsg{seq: seq}
+	})
+}
+
+// currentToast returns the toast showing, or nil
+func (m Model) currentToast() *toast {
+	if len(m.toasts) == 0 {
+		return nil
+	}
+	return &m.toasts[0]
+}
+
+// tickToast counts the toast showing down, moving on to the next one when
+// its time is up
+func (m Model) tickToast(msg toastTickMsg) (tea.Model, tea.Cmd) {
+	if msg.seq != m.toastSeq || len(m.toasts) == 0 {
+		return m, nil
+	}
+	if m.toasts[0].failure && m.writeFailure != nil && m.writeFailure.editing {
+		return m, m.toastTickCmd()
+	}
+	m.toasts[0].ticks--
+	if m.toasts[0].ticks <= 0 {
+		m.dismissToast()
+	}
+	if len(m.toasts) == 0 {
+		return m, nil
+	}
+	return m, m.toastTickCmd()
+}
+
+// dismissToast drops the toast showing, and with it the failed write it
+// reported
+func (m *Model) dismissToast() {
+	if len(m.toasts) == 0 {
+		return
+	}
+	if m.toasts[0].failure {
+		m.writeFailure = nil
+	}
+	m.toasts = append([]toast(nil), m.toasts[1:]...)
+}
+
+// toastStyle returns the style of a toast's level
+func toastStyle(level toastLevel) lipgloss.Style {
+	switch level {
+	case toastInfo:
+		return styles.ToastInfo
+	case toastWarn:
+		return styles.ToastWarn
+	case toastError:
+		return styles.ToastError
+	}
+	return styles.Toast
+}
+
+// toastText returns a toast's text with the number of toasts waiting behind
+// it
+func (m Model) toastText(t toast) string {
+	if n := len(m.toasts) - 1; n > 0 {
+		return fmt.Sprintf("%s  (+%d more)", t.text, n)
+	}
+	return t.text
+}
+
+// toastWraps reports whether a toast is too long for the status bar and
+// shows in a panel above it instead
+func (m Model) toastWraps(t toast) bool {
+	return strings.Contains(t.text, "\n") || ansi.StringWidth(t.text)+12 > m.width-2
+}
+
+// renderToast renders a one-line toast in place of the status bar
+func (m Model) renderToast(t toast) string {
+	msg := truncateString(m.toastText(t), m.width-2)
+	padding := max(m.width-lipgloss.Width(msg)-2, 0)
+	return toastStyle(t.level).Render(msg + strings.Repeat(" ", padding))
+}
+
+// renderToastPanel renders a toast too long for the status bar as a
+// bordered panel of wrapped lines
+func (m Model) renderToastPanel(t toast) string {
+	width := max(m.width-4, 10) // border + padding
+	text := lipgloss.NewStyle().Width(width).Render(m.toastText(t))
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(toastStyle(t.level).GetForeground()).
+		Padding(0, 1).
+		Width(m.width - 2).
+		Render(toastStyle(t.level).UnsetPadding().Render(text))
+}
+
+// overlayToastPanel replaces the last lines of content with a panel for a
+// toast too long for the status bar
+func (m Model) overlayToastPanel(content string, t toast) string {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	panel := strings.Split(m.renderToastPanel(t), "\n")
+	keep := max(len(lines)-len(panel), 0)
+	return strings.Join(append(lines[:keep], panel...), "\n") + "\n"
+}
+
+// handleToastLogKeys scrolls and closes the notification history
+func (m Model) handleToastLogKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.ForceQuit):
+		return m, tea.Quit
+
+	case key.Matches(msg, m.keys.Back, m.keys.Quit, m.keys.Notifications):
+		m.showToastLog = false
+
+	case key.Matches(msg, m.keys.Down):
+		if m.toastLogScroll < len(m.toastLog)-1 {
+			m.toastLogScroll++
+		}
+
+	case key.Matches(msg, m.keys.Up):
+		if m.toastLogScroll > 0 {
+			m.toastLogScroll--
+		}
+	}
+	return m, nil
+}
+
+// renderToastLog renders this session's toasts, newest first, from the
+// scroll position down
+func (m Model) renderToastLog() string {
+	modalWidth := min(max(m.width*85/100, 50), 80)
+	inner := modalWidth - 8 // border + padding + indent
+
+	var b strings.Builder
+	b.WriteString(styles.ModalTitle.Render("Notifications"))
+	b.WriteString("\n\n")
+
+	if len(m.toastLog) == 0 {
+		b.WriteString(styles.HelpDesc.Render("  Nothing yet this session") + "\n")
+	}
+
+	room := max(m.height-10, 3)
+	lines := 0
+	for i := len(m.toastLog) - 1 - m.toastLogScroll; i >= 0 && lines < room; i-- {
+		t := m.toastLog[i]
+		prefix := styles.HelpDesc.Render(t.at.Format("15:04:05")) + " " + toastStyle(t.level).UnsetPadding().Render(toastIcons[t.level]) + " "
+		wrapped := strings.Split(lipgloss.NewStyle().Width(inner-11).Render(t.text), "\n")
+		for j, line := range wrapped {
+			if lines == room {
+				break
+			}
+			if j == 0 {
+				b.WriteString("  " + prefix + line + "\n")
+			} else {
+				b.WriteString("  " + strings.Repeat(" ", 11) + line + "\n")
+			}
+			lines++
+		}
+	}
+	if m.toastLogScroll > 0 || len(m.toastLog) > 0 && lines == room {
+		b.WriteString(styles.HelpDesc.Render(fmt.Sprintf("  (%d of %d)", m.toastLogScroll+1, len(m.toastLog))) + "\n")
+	}
+
+	b.WriteString("\n" + renderHints(m.contextBindings()))
+	return styles.Modal.Width(modalWidth).Render(b.String())
+}
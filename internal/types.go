@@ -1,8 +1,19 @@
 package internal
 
 import (
+	"log/slog"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/audit"
+	"github.com/b-open-io/claude-perms/internal/insights"
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/snapshots"
+	"github.com/b-open-io/claude-perms/internal/state"
+	"github.com/b-open-io/claude-perms/internal/templates"
 	"github.com/b-open-io/claude-perms/internal/types"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
 )
 
 // ViewType represents the active view in the TUI
@@ -11,9 +22,20 @@ type ViewType int
 const (
 	ViewFrequency ViewType = iota
 	ViewMatrix
+	ViewDiagnostics
+	ViewSnapshots
+	ViewHistory
+	ViewStale
+	ViewReconcile
+	ViewPolicy
 	ViewHelp
+
+	viewCount = int(ViewHelp) + 1
 )
 
+// viewNames are the tab labels, indexed by ViewType
+var viewNames = [viewCount]string{"Frequency", "Matrix", "Diagnostics", "Snapshots", "History", "Stale", "Reconcile", "Policy", "Help"}
+
 // ApplyModalMode represents the current mode within the apply modal
 type ApplyModalMode int
 
@@ -27,27 +49,39 @@ type Model struct {
 	// View state
 	activeView     ViewType
 	showApplyModal bool
-	isLoading      bool        // Shows loading indicator during initial data scan
-	loadingStatus  string      // Current project path being loaded
-	loadingSession string      // Current session ID being scanned
-	progressChan   chan string // Channel for streaming progress updates
+	showDetail     bool                 // Permission detail modal (opened before the apply flow)
+	detailCursor   int                  // Selected agent in the detail modal's agent list
+	skipDetails    bool                 // Enter goes straight to the apply flow
+	showFullHelp   bool                 // Full keyboard help overlay (toggled with ?)
+	isLoading      bool                 // Shows loading indicator during initial data scan
+	loading        parser.Progress      // Latest progress update from the loader
+	loadingStarted time.Time            // When the scan began, for elapsed time
+	progressChan   chan parser.Progress // Channel for streaming progress updates
+	spinner        spinner.Model        // Loading spinner
 
 	// Apply modal state
 	applyModalMode    ApplyModalMode
 	applyOptionCursor int // 0=User, 1=Project
-	projectListCursor int // Index in project list
+	projectList       listState // Cursor and scroll of the project list
+
+	// Filter narrowing the project list of the apply and agent modals
+	projectFilter     textinput.Model
+	filteringProjects bool // Typing into projectFilter
 
 	// Data
 	permissions []types.PermissionStats
 	agents      []types.AgentPermissions
 	skills      []types.SkillPermissions
+	commands    []types.CommandPermissions // Slash commands that allow tools
 
 	// Agent usage stats (from session logs)
-	agentUsage []types.AgentUsageStats
+	agentUsage    []types.AgentUsageStats
+	subagentUsage []types.AgentUsageStats // agentUsage without slash commands, whose calls the main session made
 
 	// Approved permissions from settings
 	userApproved    []string
-	projectApproved []string
+	projectSettings map[string][]string
+	permissionModes map[string]parser.PermissionMode // Per project path, the working directory included
 
 	// Current project path
 	projectPath string
@@ -62,22 +96,172 @@ type Model struct {
 
 	// Hierarchical view (Frequency)
 	permissionGroups []types.PermissionGroup
-	groupCursor      int // Which group is selected
-	childCursor      int // Which child within expanded group (-1 if on group)
-	freqScroll       int // Scroll offset for frequency viewport
+	freqList         listState // Cursor and scroll over the groups and expanded groups' permissions
+	freqSort         freqSort
+	sinceReview      bool // Only list permissions changed since the last review
+	withSubagents    bool // Count subagent tool calls in the Frequency view too
+	quickFilters     [quickFilterCount]bool // Quick filters that are on, by quickFilter
+	freqColumns      [freqColumnCount]bool  // Columns shown, by freqColumn
+	nameScroll       int    // Columns the selected row's permission name is scrolled by
+	nameScrollRow    string // The text of the row nameScroll applies to
+
+	// Vim-style key sequences
+	countKeys      []tea.KeyMsg // Digits typed as a count, waiting for a motion
+	countSeq       int          // Bumped per digit so only the latest count times out
+	replayingCount bool         // Digits of a count are being handled as plain keys
+	centerPending  bool         // z was pressed, waiting for the second z
+	topPending     bool         // A count went to a row with g, swallow the second g
+
+	// Column picker
+	showColumns   bool
+	columnsCursor int
+	columnsBefore [freqColumnCount]bool // The columns when it opened, to save only a change
+
+	// Writes outside the workspace modal
+	showOutside    bool
+	outsideList    listState // Cursor and scroll of the project list
+	outsideProject string    // Project whose files are listed, "" for the project list
+	outsideFiles   listState // Cursor and scroll of the file list
+
+	// Settings template picker
+	showTemplates   bool
+	templates       []templates.Template
+	templateList    listState // Cursor and scroll of the template list
+	templatePicked  bool      // The selected template's preview shows
+	templateProject bool      // Apply to the current project's settings rather than user settings
+	templateReplace bool      // Replace the file's conflicting rules rather than skip the template's
 
 	// Matrix view state
-	matrixCursor     int  // Cursor position in agent/skill list
-	matrixScroll     int  // Scroll offset for viewport
+	matrixList       listState // Cursor and scroll of the agent/skill list
 	showAgentModal   bool // Show agent detail modal
 	selectedAgentIdx int  // Index of agent for detail modal
+	matrixByPlugin   bool // List plugins instead of agents and commands
+	showPluginModal  bool // Show the selected plugin's rollup
+	plugins          []insights.PluginRollup
+	pluginInstall    parser.InstalledPlugin     // Installed versions of the plugin in the modal
+	pluginChanges    []parser.DeclarationChange // What the latest version changed from the one before
 
 	// Agent detail modal state
-	agentModalCursor    int    // Cursor in permission list
+	agentModalList      listState // Cursor of the permission list
 	agentModalSelected  []bool // Which permissions are selected (toggled)
-	agentModalMode      int    // 0=permission select, 1=scope select, 2=project select
+	agentModalMode      int    // 0=permission select, 1=scope select, 2=project select, 3=tools line, 4=edit tools, 5=invocations
 	agentModalScope     int    // 0=user, 1=project
-	agentModalProjCursor int   // Cursor in project list
+	agentModalProjList  listState // Cursor of the project list
+	agentToolsFile      string // Markdown file defining the agent, "" for built-in agents
+	agentEditTools      []string // Tools offered in the edit tools list: declared, then used
+	agentEditChecked    []bool   // Which of them the file should list
+	agentEditDeclared   int      // How many of agentEditTools the file lists now
+	agentEditCursor     int      // Cursor in the edit tools list
+	agentInvocations    listState // Cursor and scroll of the invocations list
+
+	// Suggestions for permissions the user keeps denying
+	denyStreaks      []insights.DenyStreak
+	dismissedStreaks map[string]bool
+
+	// Non-fatal load problems (Diagnostics view)
+	warnings        []parser.Warning
+	warningsDropped int // Warnings beyond the parser's limit
+	diagList        listState
+
+	// Settings snapshots
+	snapshots      []snapshots.Snapshot
+	snapshotsErr   error
+	snapList       listState
+	snapMarked     string // ID of the snapshot to compare from, "" to use the previous one
+	showSnapDiff   bool   // Diff modal for the selected snapshot
+	snapDiffScroll int
+
+	// Audit log (History view)
+	history        []audit.Entry // Newest first
+	historySkipped int           // Unreadable log lines
+	historyErr     error
+	histList       listState
+
+	// Allow rules no recent tool_use matched (Stale view)
+	staleWindow       time.Duration
+	staleAllows       []insights.StaleAllow
+	staleList         listState
+	showRemoveConfirm bool // Confirming removal of the selected rule
+
+	// Settings rules and where they came from (Reconcile view)
+	grants           []parser.Grant // Permission changes found in session logs
+	reconciled       []insights.Reconciled
+	reconUnexplained int // Rules perms didn't write
+	reconList        listState
+
+	// Settings rules that break the team policy (Policy view)
+	policyPath   string // From the config; "" if no policy is set
+	policy       *insights.Policy
+	policyErr    error
+	violations   []insights.Violation
+	policyList   listState
+
+	// Empty-state screen (no session logs found)
+	onboarding       bool
+	onboardingCursor int
+	choosingDir      bool            // Typing a projects directory
+	dirInput         textinput.Model // Projects directory entry
+	onboardingErr    string
+
+	// Demo mode: bundled data, settings files are never written
+	demo    bool
+	demoDir string // Temporary directory holding the extracted demo data
+
+	// Read-only mode: settings files are never written
+	readOnly bool
+
+	// Dry-run mode: apply and deny report what would change without writing
+	dryRun bool
+
+	// Persistent TUI state (pins, notes), saved to ~/.claude/perms-state.json
+	state *state.State
+
+	// Review queue: unapproved permissions, one at a time
+	showQueue  bool
+	queue      []string       // Raw permissions of this pass, most used first
+	queueIdx   int            // Position in queue; len(queue) when the pass is done
+	queueTally map[string]int // What this pass did, by outcome: allowed, denied, ignored, skipped
+
+	// Staging area: permissions picked across groups to apply in one batch
+	staged       []string // Raw permissions, in the order they were staged
+	showStaged   bool
+	stagedCursor int // 0: user settings, 1: the projects that used them
+
+	// Confirm step of applying a permission to the current project
+	showApplyHere bool
+
+	// Note editor
+	editingNote bool
+	noteInput   textinput.Model
+	noteAgent   bool   // Editing an agent's note rather than a permission's
+	noteTarget  string // Raw permission or agent type being annotated
+
+	// Check modal: would a tool call run, prompt or be denied
+	showCheck   bool
+	checkInput  textinput.Model
+	checkResult *parser.Resolution // nil until Enter is pressed
+
+	// Pattern tester: which recorded calls a candidate rule matches
+	showTester  bool
+	testerInput textinput.Model
+
+	// Settings comparison with a teammate's file or a template
+	showCompare     bool
+	compareInput    textinput.Model // Their settings file, or a template name
+	compareTheirs   string          // What user settings were compared with; "" while it is typed
+	compareErr      string
+	compareDiffs    []parser.RuleDifference
+	compareList     listState    // Cursor and scroll of the differences
+	compareSelected map[int]bool // Differences marked to adopt, by index
+
+	// Contexts to return to after following links between views
+	navStack []navEntry
+
+	// Key bindings
+	keys KeyMap
+
+	// Debug logger (discards unless --debug is given)
+	logger *slog.Logger
 
 	// Dimensions
 	width  int
@@ -86,7 +270,16 @@ type Model struct {
 	// Error state
 	err error
 
-	// Toast notification (shown after apply)
-	toastMessage string // Multi-line message to show
-	toastTicks   int    // Remaining ticks before auto-dismiss
+	// Changes made during this session, for the exit summary
+	actions []SessionAction
+
+	// Toast notifications (shown after apply)
+	toasts         []toast // Waiting to show, the first one showing
+	toastLog       []toast // This session's toasts, oldest first
+	toastSeq       int     // Identifies the latest countdown; see toastTickCmd
+	showToastLog   bool
+	toastLogScroll int // Toasts skipped from the newest in the history
+
+	// Failed settings write, retryable while its toast shows
+	writeFailure *writeFailure
 }
@@ -1,6 +1,10 @@
 package types
 
-import "time"
+import (
+	"slices"
+	"sort"
+	"time"
+)
 
 // Permission represents a parsed permission string
 type Permission struct {
@@ -11,13 +15,108 @@ type Permission struct {
 
 // PermissionStats tracks usage statistics for a permission
 type PermissionStats struct {
-	Permission Permission
-	Count      int
-	Approved   int // tool_results where is_error != true
-	Denied     int // tool_results where user rejected
-	LastSeen   time.Time
-	Projects   []string // Project paths where this permission was requested
-	ApprovedAt ApprovalLevel
+	Permission   Permission
+	Count        int
+	Tokens       int         // Tokens of the assistant messages that made the calls, shared between a message's calls
+	Approved     int         // tool_results where is_error != true, besides those counted in AutoApproved
+	Denied       int         // tool_results where user rejected
+	DeniedAt     []time.Time // When each denial happened
+	AutoApproved int         // tool_results of calls the session's permission mode ran without a prompt, e.g. edits under acceptEdits
+	FirstSeen    time.Time
+	LastSeen     time.Time
+	Projects     []string // Project paths where this permission was requested
+	ApprovedAt   ApprovalLevel
+
+	ProjectCounts map[string]int      // Uses per project path
+	HostCounts    map[string]int      // Uses per host the logs came from; empty unless hosts are configured
+	Samples       []string            // A few distinct example inputs (commands, paths, URLs)
+	Inputs        map[string]int      // Uses per input of a tool whose rules take no scope, such as a WebSearch query
+	Variants      map[string]int      // Uses per raw permission string merged into this one by normalization
+	ByAttribution map[Attribution]int // Uses per who made them
+	Slowest       []SlowCall          // Its longest-running calls, longest first
+	Outside       int                 // Bash calls that ran outside the directory their session started in
+	OutsideDirs   []string            // A few of the directories they ran in
+	OutsideWrites []OutsideWrite      // Files its Write and Edit calls modified outside the project
+}
+
+// OutsideWrite is a file that Write or Edit calls modified outside the
+// directory their session started in, the workspace
+type OutsideWrite struct {
+	Project string `json:"project"` // Project of the sessions
+	File    string `json:"file"`
+	Count   int    `json:"count"` // Calls that modified it
+}
+
+// AddOutsideWrites returns writes with more added, counts of the same file
+// in the same project summed. writes is not modified.
+func AddOutsideWrites(writes []OutsideWrite, more ...OutsideWrite) []OutsideWrite {
+	if len(more) == 0 {
+		return writes
+	}
+	merged := append(make([]OutsideWrite, 0, len(writes)+len(more)), writes...)
+	for _, w := range more {
+		i := slices.IndexFunc(merged, func(o OutsideWrite) bool { return o.Project == w.Project && o.File == w.File })
+		if i < 0 {
+			merged = append(merged, w)
+		} else {
+			merged[i].Count += w.Count
+		}
+	}
+	return merged
+}
+
+// SlowCall is one of the longest-running tool calls of a permission or an
+// agent: the time from its tool_use to its tool_result, which includes any
+// wait for the user to approve it
+type SlowCall struct {
+	Permission string        `json:"permission"`
+	Sample     string        `json:"sample,omitempty"` // The command, path or URL
+	Duration   time.Duration `json:"duration"`
+	Time       time.Time     `json:"time"` // When the call was made
+}
+
+// MaxSlowCalls is how many of the slowest calls are kept
+const MaxSlowCalls = 5
+
+// AddSlowCalls returns the MaxSlowCalls longest of slowest and calls,
+// longest first. slowest is not modified.
+func AddSlowCalls(slowest []SlowCall, calls ...SlowCall) []SlowCall {
+	if len(calls) == 0 {
+		return slowest
+	}
+	merged := append(append(make([]SlowCall, 0, len(slowest)+len(calls)), slowest...), calls...)
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Duration > merged[j].Duration
+	})
+	return merged[:min(len(merged), MaxSlowCalls)]
+}
+
+// Attribution says who made a tool call
+type Attribution string
+
+const (
+	AttributedMain    Attribution = "main"    // The main session
+	AttributedAgent   Attribution = "agent"   // A subagent of a known type
+	AttributedUnknown Attribution = "unknown" // A subagent no session log names the type of
+)
+
+// SubagentCount returns the uses made by subagents, of known type or not
+func (p PermissionStats) SubagentCount() int {
+	return p.ByAttribution[AttributedAgent] + p.ByAttribution[AttributedUnknown]
+}
+
+// ApprovalRate returns the share of decided uses that were approved rather
+// than denied, and false if none were decided either way
+func (p PermissionStats) ApprovalRate() (float64, bool) {
+	return approvalRate(p.Approved, p.Denied)
+}
+
+// approvalRate returns approved / (approved + denied)
+func approvalRate(approved, denied int) (float64, bool) {
+	if approved+denied == 0 {
+		return 0, false
+	}
+	return float64(approved) / float64(approved+denied), true
 }
 
 // ApprovalLevel indicates where a permission is approved
@@ -46,7 +145,15 @@ type AgentPermissions struct {
 	Plugin      string // Plugin name if from a plugin, empty otherwise
 	Version     string // Plugin version (e.g., "1.0.20")
 	FilePath    string
-	Permissions []Permission
+	Permissions []Permission // The tools list
+	Allow       []Permission // Rules in a permissions.allow block
+	Deny        []Permission // Rules in a permissions.deny block
+}
+
+// Declares reports whether the agent's frontmatter declares any tools or
+// permission rules
+func (a AgentPermissions) Declares() bool {
+	return len(a.Permissions)+len(a.Allow)+len(a.Deny) > 0
 }
 
 // SkillPermissions holds permissions declared by a skill
@@ -55,17 +162,69 @@ type SkillPermissions struct {
 	Plugin      string
 	Version     string // Plugin version (e.g., "1.0.20")
 	FilePath    string
-	Permissions []Permission
+	Permissions []Permission // The tools list
+	Allow       []Permission // Rules in a permissions.allow block
+	Deny        []Permission // Rules in a permissions.deny block
+}
+
+// Declares reports whether the skill's frontmatter declares any tools or
+// permission rules
+func (s SkillPermissions) Declares() bool {
+	return len(s.Permissions)+len(s.Allow)+len(s.Deny) > 0
+}
+
+// CommandPermissions holds the tools a slash command allows
+type CommandPermissions struct {
+	Name        string // Command name without the slash, e.g. "review"
+	Plugin      string // Plugin name if from a plugin, empty otherwise
+	Version     string // Plugin version (e.g., "1.0.20")
+	FilePath    string
+	Permissions []Permission // The allowed-tools list
+}
+
+// Invocation returns the command as typed in a session, e.g. "/review" or
+// "/my-plugin:review"
+func (c CommandPermissions) Invocation() string {
+	if c.Plugin != "" {
+		return "/" + c.Plugin + ":" + c.Name
+	}
+	return "/" + c.Name
 }
 
 // AgentUsageStats tracks actual permission usage by an agent type
 type AgentUsageStats struct {
-	AgentType   string            // "Explore", "bopen-tools:devops-specialist", etc.
+	AgentType   string            // "Explore", "bopen-tools:devops-specialist", etc.; "/review" for a slash command
 	Permissions []PermissionStats // Actual tool_uses from this agent
 	TotalCalls  int               // Sum of all permission counts
+	TotalTokens int               // Sum of all permission tokens
+	Slowest     []SlowCall        // Its longest-running calls, longest first
 	LastSeen    time.Time         // Most recent activity
 	Sessions    int               // Number of sessions this agent ran in
 	Projects    []string          // Projects where this agent was used
+	Invocations []AgentInvocation // The latest Task calls that started this agent, newest first
+	Orphans     []OrphanAgent     // Logs no Task call could be matched to, newest first; only for "Unknown"
+}
+
+// AgentInvocation is a Task call in a session log that started a subagent
+type AgentInvocation struct {
+	AgentID     string    `json:"agentId,omitempty"` // Names the agent-<id>.jsonl log; empty until the result is logged
+	AgentType   string    `json:"agentType"`
+	Session     string    `json:"session"`           // ID of the session that made the call
+	Slug        string    `json:"slug,omitempty"`    // Slug of that session, which its agents' logs share
+	Project     string    `json:"project,omitempty"` // Decoded project path of the session
+	Time        time.Time `json:"time"`
+	Description string    `json:"description,omitempty"`
+	Prompt      string    `json:"prompt,omitempty"`
+}
+
+// OrphanAgent is an agent log whose agentId no Task call named and that no
+// Task call could be matched to otherwise
+type OrphanAgent struct {
+	Path    string    `json:"path"`
+	Project string    `json:"project,omitempty"`
+	Session string    `json:"session,omitempty"` // Session the log names or is kept under, if any
+	Start   time.Time `json:"start"`
+	Prompt  string    `json:"prompt,omitempty"` // First user message of the log
 }
 
 // Settings represents the settings.local.json structure
@@ -81,26 +240,63 @@ type PermissionSettings struct {
 
 // AgentFrontmatter represents the YAML frontmatter of an agent file
 type AgentFrontmatter struct {
-	Name        string      `yaml:"name"`
-	Description string      `yaml:"description"`
-	Tools       interface{} `yaml:"tools"` // Can be []string or comma-separated string
+	Name        string                 `yaml:"name"`
+	Description string                 `yaml:"description"`
+	Tools       interface{}            `yaml:"tools"` // Can be []string or comma-separated string
+	Permissions FrontmatterPermissions `yaml:"permissions"`
 }
 
 // SkillFrontmatter represents the YAML frontmatter of a skill file
 type SkillFrontmatter struct {
-	Name        string      `yaml:"name"`
-	Description string      `yaml:"description"`
-	Tools       interface{} `yaml:"tools"` // Can be []string or comma-separated string
+	Name        string                 `yaml:"name"`
+	Description string                 `yaml:"description"`
+	Tools       interface{}            `yaml:"tools"` // Can be []string or comma-separated string
+	Permissions FrontmatterPermissions `yaml:"permissions"`
+}
+
+// CommandFrontmatter represents the YAML frontmatter of a slash command
+// file
+type CommandFrontmatter struct {
+	Description  string      `yaml:"description"`
+	AllowedTools interface{} `yaml:"allowed-tools"` // Can be []string or comma-separated string
+}
+
+// FrontmatterPermissions is a structured permissions block in agent or skill
+// frontmatter, with the same allow and deny lists as a settings file
+type FrontmatterPermissions struct {
+	Allow interface{} `yaml:"allow"` // Can be []string or comma-separated string
+	Deny  interface{} `yaml:"deny"`
 }
 
 // PermissionGroup represents a permission type with its children
 type PermissionGroup struct {
-	Type          string            // "Bash", "Read", etc.
-	TotalCount    int               // Sum of all children counts
-	TotalApproved int               // Sum of all children approved counts
-	TotalDenied   int               // Sum of all children denied counts
-	LastSeen      time.Time         // Most recent across all children
-	Children      []PermissionStats // Individual permissions like Bash(curl:*)
-	Expanded      bool              // UI state: is this group expanded?
-	ApprovedAt    ApprovalLevel     // Highest approval level among children
+	Type              string            // "Bash", "Read", etc.
+	TotalCount        int               // Sum of all children counts
+	TotalApproved     int               // Sum of all children approved counts
+	TotalDenied       int               // Sum of all children denied counts
+	TotalAutoApproved int               // Sum of all children auto-approved counts
+	TotalTokens       int               // Sum of all children tokens
+	FirstSeen         time.Time         // Earliest across all children
+	LastSeen          time.Time         // Most recent across all children
+	Children          []PermissionStats // Individual permissions like Bash(curl:*)
+	Expanded          bool              // UI state: is this group expanded?
+	ApprovedAt        ApprovalLevel     // Highest approval level among children
+}
+
+// ApprovalRate returns the share of the group's decided uses that were
+// approved, and false if none were decided either way
+func (g PermissionGroup) ApprovalRate() (float64, bool) {
+	return approvalRate(g.TotalApproved, g.TotalDenied)
+}
+
+// ProjectCount returns how many projects used any of the group's
+// permissions
+func (g PermissionGroup) ProjectCount() int {
+	projects := make(map[string]bool)
+	for _, c := range g.Children {
+		for _, p := range c.Projects {
+			projects[p] = true
+		}
+	}
+	return len(projects)
 }
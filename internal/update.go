@@ -1,40 +1,34 @@
 package internal
 
 import (
-	"log"
-	"os"
+	"fmt"
 	"strings"
+	"unicode"
 
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-var debugLog *os.File
-
-func init() {
-	var err error
-	debugLog, err = os.OpenFile("/tmp/perms-debug.log", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
-	if err == nil {
-		log.SetOutput(debugLog)
-	}
-}
-
 // Update implements tea.Model
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	log.Printf("Update received: %T", msg)
+	m.logger.Debug("update", "msg", fmt.Sprintf("%T", msg))
 
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
-		log.Printf("WindowSize: %dx%d", msg.Width, msg.Height)
+		m.logger.Debug("window resized", "width", msg.Width, "height", msg.Height)
 		m.width = msg.Width
 		m.height = msg.Height
+		m.clampLists()
 		return m, nil
 
 	case loadDataMsg:
-		log.Printf("loadDataMsg received, starting load with progress...")
+		m.logger.Debug("starting data load", "project", m.projectPath)
 		// Create channels
-		progress := make(chan string, 100)
+		progress := make(chan parser.Progress, 100)
 		result := make(chan dataLoadedMsg, 1)
 		m.progressChan = progress
+		m.loadingStarted = now()
 
 		// Start loading in background goroutine
 		projectPath := m.projectPath
@@ -53,21 +47,25 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		)
 
 	case loadingProgressMsg:
-		// Parse prefix to determine if it's a session or project update
-		if strings.HasPrefix(msg.status, "session:") {
-			m.loadingSession = strings.TrimPrefix(msg.status, "session:")
-		} else {
-			m.loadingStatus = msg.status
-		}
-		log.Printf("Loading: %s", msg.status)
+		m.loading = msg.progress
+		m.logger.Debug("loading", "stage", msg.progress.Stage, "done", msg.progress.Done, "total", msg.progress.Total, "project", msg.progress.Project)
 		if m.isLoading && m.progressChan != nil {
 			return m, progressReader(m.progressChan)
 		}
 		return m, nil
 
+	case spinner.TickMsg:
+		if !m.isLoading {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
 	case dataLoadedMsg:
-		log.Printf("dataLoadedMsg: err=%v, perms=%d, groups=%d, agents=%d, skills=%d",
-			msg.err, len(msg.permissions), len(msg.permissionGroups), len(msg.agents), len(msg.skills))
+		m.logger.Debug("data loaded", "err", msg.err, "permissions", len(msg.permissions),
+			"groups", len(msg.permissionGroups), "agents", len(msg.agents), "skills", len(msg.skills), "commands", len(msg.commands),
+			"warnings", len(msg.warnings))
 		m.isLoading = false
 		if msg.err != nil {
 			m.err = msg.err
@@ -75,30 +73,64 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		m.permissions = msg.permissions
 		m.permissionGroups = msg.permissionGroups
+		sortGroups(m.permissionGroups, m.freqSort, m.state.Pins)
 		m.agents = msg.agents
 		m.skills = msg.skills
-		m.agentUsage = msg.agentUsage
+		m.commands = msg.commands
+		m.subagentUsage = msg.agentUsage
+		// Slash commands share the Matrix with agents, named "/review"
+		m.agentUsage = append(msg.agentUsage, msg.commandUsage...)
+		sortAgentUsage(m.agentUsage, m.state.Pins)
+		m.loadPlugins()
 		m.userApproved = msg.userApproved
-		m.projectApproved = msg.projectApproved
+		m.projectSettings = msg.projectSettings
+		m.permissionModes = msg.permissionModes
+		if m.sinceReview || m.withSubagents || m.quickFiltering() {
+			m.regroupPermissions()
+		}
+		m.denyStreaks = msg.denyStreaks
+		m.findStaleAllows()
+		m.grants = msg.grants
+		switch m.activeView {
+		case ViewReconcile:
+			m.loadReconcile()
+		case ViewPolicy:
+			m.loadPolicy()
+		}
+		m.warnings = msg.warnings
+		m.warningsDropped = msg.warningsDropped
+		m.onboarding = msg.sessionCount == 0
+		m.onboardingCursor = 0
 		m.clampCursor()
-		log.Printf("Model updated: %d permissions, %d groups loaded", len(m.permissions), len(m.permissionGroups))
+		m.clampLists()
 		return m, nil
 
 	case toastTickMsg:
-		if m.toastTicks > 0 {
-			m.toastTicks--
-			if m.toastTicks > 0 {
-				return m, toastTickCmd()
-			}
-			m.toastMessage = ""
-		}
-		return m, nil
+		return m.tickToast(msg)
+
+	case countTimeoutMsg:
+		return m.flushCount(msg)
+
+	case editorFinishedMsg:
+		return m.editorFinished(msg)
 
 	case tea.KeyMsg:
-		// Any keypress dismisses the toast
-		if m.toastTicks > 0 {
-			m.toastTicks = 0
-			m.toastMessage = ""
+		// A failed write's toast takes its retry and edit keys
+		if t := m.currentToast(); t != nil && t.failure && m.writeFailure != nil {
+			if model, cmd, ok := m.handleWriteFailureKeys(msg); ok {
+				return model, cmd
+			}
+		}
+		// Any other keypress dismisses the toast showing, bringing up the
+		// next one
+		if m.currentToast() != nil {
+			m.dismissToast()
+			m.toastSeq++
+			if len(m.toasts) > 0 {
+				tick := m.toastTickCmd()
+				model, cmd := m.handleKeyboard(msg)
+				return model, tea.Batch(cmd, tick)
+			}
 		}
 		return m.handleKeyboard(msg)
 
@@ -106,6 +138,31 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handleMouse(msg)
 	}
 
+	// Cursor blink for the note editor
+	if m.editingNote {
+		var cmd tea.Cmd
+		m.noteInput, cmd = m.noteInput.Update(msg)
+		return m, cmd
+	}
+
+	if m.showCheck {
+		var cmd tea.Cmd
+		m.checkInput, cmd = m.checkInput.Update(msg)
+		return m, cmd
+	}
+
+	if m.showTester {
+		var cmd tea.Cmd
+		m.testerInput, cmd = m.testerInput.Update(msg)
+		return m, cmd
+	}
+
+	if m.showCompare && m.compareTheirs == "" {
+		var cmd tea.Cmd
+		m.compareInput, cmd = m.compareInput.Update(msg)
+		return m, cmd
+	}
+
 	// Handle text input updates when filtering
 	if m.filtering {
 		var cmd tea.Cmd
@@ -170,3 +227,22 @@ func contains(s, substr string) bool {
 	}
 	return false
 }
+
+// sanitizePaste strips control characters from pasted runes. Newlines and
+// tabs become single spaces so a multi-line paste still reads as one query.
+func sanitizePaste(runes []rune) []rune {
+	out := make([]rune, 0, len(runes))
+	for _, r := range runes {
+		switch {
+		case r == '\n' || r == '\r' || r == '\t':
+			if len(out) > 0 && out[len(out)-1] != ' ' {
+				out = append(out, ' ')
+			}
+		case unicode.IsControl(r):
+			continue
+		default:
+			out = append(out, r)
+		}
+	}
+	return []rune(strings.TrimSpace(string(out)))
+}
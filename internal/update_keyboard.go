@@ -1,22 +1,123 @@
 package internal
 
 import (
-	"fmt"
 	"os"
-	"os/exec"
-	"runtime"
 
 	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/types"
+	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 // handleKeyboard processes keyboard input
 func (m Model) handleKeyboard(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// Empty-state screen
+	if m.onboarding {
+		return m.handleOnboardingKeys(msg)
+	}
+
+	// Full help overlay swallows keys until closed
+	if m.showFullHelp {
+		switch {
+		case key.Matches(msg, m.keys.ForceQuit):
+			return m, tea.Quit
+		case key.Matches(msg, m.keys.Help, m.keys.Back, m.keys.Quit):
+			m.showFullHelp = false
+		}
+		return m, nil
+	}
+
+	// The note editor takes every key, including those of the modal it was
+	// opened from
+	if m.editingNote {
+		return m.handleNoteKeys(msg)
+	}
+
+	// So does the check modal's input
+	if m.showCheck {
+		return m.handleCheckKeys(msg)
+	}
+
+	// And the comparison modal's, while what to compare with is typed
+	if m.showCompare {
+		return m.handleCompareKeys(msg)
+	}
+
+	// And the pattern tester's
+	if m.showTester {
+		return m.handleTesterKeys(msg)
+	}
+
+	if !m.filtering && key.Matches(msg, m.keys.Help) {
+		m.showFullHelp = true
+		return m, nil
+	}
+
+	// The notification history opens over anything else
+	if m.showToastLog {
+		return m.handleToastLogKeys(msg)
+	}
+	if !m.filtering && key.Matches(msg, m.keys.Notifications) {
+		m.showToastLog = true
+		m.toastLogScroll = 0
+		return m, nil
+	}
+
+	// Handle the writes outside the workspace
+	if m.showOutside {
+		return m.handleOutsideWritesKeys(msg)
+	}
+
+	// Handle the template picker
+	if m.showTemplates {
+		return m.handleTemplatesKeys(msg)
+	}
+
+	// Handle review queue
+	if m.showQueue {
+		return m.handleQueueKeys(msg)
+	}
+
+	// Handle staging modal
+	if m.showStaged {
+		return m.handleStagedKeys(msg)
+	}
+
+	// Handle the confirm step of applying to the current project
+	if m.showApplyHere {
+		return m.handleApplyHereKeys(msg)
+	}
+
+	// Handle the column picker
+	if m.showColumns {
+		return m.handleColumnsKeys(msg)
+	}
+
+	// Handle plugin modal
+	if m.showPluginModal {
+		return m.handlePluginModalKeys(msg)
+	}
+
 	// Handle agent detail modal
 	if m.showAgentModal {
 		return m.handleAgentModalKeys(msg)
 	}
 
+	// Handle permission detail modal
+	if m.showDetail {
+		return m.handleDetailKeys(msg)
+	}
+
+	// Handle snapshot diff modal
+	if m.showSnapDiff {
+		return m.handleSnapDiffKeys(msg)
+	}
+
+	// Handle stale rule removal modal
+	if m.showRemoveConfirm {
+		return m.handleRemoveConfirmKeys(msg)
+	}
+
 	// Handle apply modal keys
 	if m.showApplyModal {
 		return m.handleModalKeys(msg)
@@ -27,103 +128,221 @@ func (m Model) handleKeyboard(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleFilterKeys(msg)
 	}
 
+	// Pasted text outside the filter is never interpreted as key presses
+	if msg.Paste {
+		return m, nil
+	}
+
+	// Counts (5j) and zz
+	if model, cmd, ok := m.handleKeySequence(msg); ok {
+		return model, cmd
+	}
+
 	// Normal mode keys
-	switch msg.String() {
-	case "q", "ctrl+c":
+	switch {
+	case key.Matches(msg, m.keys.Quit, m.keys.ForceQuit):
 		return m, tea.Quit
 
-	case "j", "down":
-		switch m.activeView {
-		case ViewFrequency:
-			m.navigateDown()
-		case ViewMatrix:
-			m.navigateMatrixDown()
-		}
+	case key.Matches(msg, m.keys.Down):
+		m.moveDown()
 		return m, nil
 
-	case "k", "up":
-		switch m.activeView {
-		case ViewFrequency:
-			m.navigateUp()
-		case ViewMatrix:
-			m.navigateMatrixUp()
-		}
+	case key.Matches(msg, m.keys.Up):
+		m.moveUp()
 		return m, nil
 
-	case "g", "home":
-		switch m.activeView {
-		case ViewFrequency:
-			m.groupCursor = 0
-			m.childCursor = -1
-			m.freqScroll = 0
-		case ViewMatrix:
-			m.matrixCursor = 0
-			m.matrixScroll = 0
-		}
+	case key.Matches(msg, m.keys.PageDown):
+		m.page(m.pageSize())
 		return m, nil
 
-	case "G", "end":
-		switch m.activeView {
-		case ViewFrequency:
-			m.groupCursor = len(m.permissionGroups) - 1
-			if m.groupCursor < 0 {
-				m.groupCursor = 0
-			}
-			m.childCursor = -1
-			m.updateFreqScroll()
-		case ViewMatrix:
-			maxIdx := m.matrixListLen() - 1
-			if maxIdx < 0 {
-				maxIdx = 0
-			}
-			m.matrixCursor = maxIdx
-			// Scroll to show cursor
-			_, contentHeight := m.calculateLayout()
-			viewportHeight := contentHeight - 5
-			if m.matrixCursor >= viewportHeight {
-				m.matrixScroll = m.matrixCursor - viewportHeight + 1
-			}
-		}
+	case key.Matches(msg, m.keys.PageUp):
+		m.page(-m.pageSize())
+		return m, nil
+
+	case key.Matches(msg, m.keys.HalfPageDown):
+		m.page(max(m.pageSize()/2, 1))
+		return m, nil
+
+	case key.Matches(msg, m.keys.HalfPageUp):
+		m.page(-max(m.pageSize()/2, 1))
+		return m, nil
+
+	case key.Matches(msg, m.keys.Top):
+		m.moveTop()
+		return m, nil
+
+	case key.Matches(msg, m.keys.Bottom):
+		m.moveBottom()
 		return m, nil
 
-	case "enter", " ":
+	case m.activeView == ViewSnapshots && key.Matches(msg, m.keys.Toggle, m.keys.Select, m.keys.Snapshot):
+		return m.handleSnapshotKeys(msg)
+
+	case key.Matches(msg, m.keys.Select):
 		switch m.activeView {
 		case ViewFrequency:
 			// If on a group, toggle expand
-			if m.childCursor == -1 && m.groupCursor < len(m.permissionGroups) {
-				m.permissionGroups[m.groupCursor].Expanded = !m.permissionGroups[m.groupCursor].Expanded
+			gi, ci := m.freqCursor()
+			if ci == -1 && gi < len(m.permissionGroups) {
+				m.permissionGroups[gi].Expanded = !m.permissionGroups[gi].Expanded
+				m.clampLists()
 			}
-			// If on a child, show modal
-			if m.childCursor >= 0 {
-				m.resetApplyModalState()
-				m.showApplyModal = true
+			// If on a child, show details (or go straight to apply)
+			if ci >= 0 {
+				if m.skipDetails {
+					m.resetApplyModalState()
+					m.showApplyModal = true
+				} else {
+					m.showDetail = true
+					m.detailCursor = 0
+				}
 			}
 		case ViewMatrix:
-			if len(m.agentUsage) > 0 && m.matrixCursor < len(m.agentUsage) {
-				m.selectedAgentIdx = m.matrixCursor
+			if m.matrixByPlugin {
+				m.openPluginModal()
+			} else if len(m.agentUsage) > 0 && m.matrixList.cursor < len(m.agentUsage) {
+				m.selectedAgentIdx = m.matrixList.cursor
 				m.showAgentModal = true
 				// Initialize selection state
-				m.agentModalSelected = make([]bool, len(m.agentUsage[m.matrixCursor].Permissions))
-				m.agentModalCursor = 0
+				m.agentModalSelected = make([]bool, len(m.agentUsage[m.matrixList.cursor].Permissions))
+				m.agentModalList.top()
 				m.agentModalMode = AgentModalModePermissions
 			}
 		}
 		return m, nil
 
-	case "tab":
-		m.activeView = (m.activeView + 1) % 3
+	case key.Matches(msg, m.keys.NextView):
+		m.switchView(ViewType((int(m.activeView) + 1) % viewCount))
+		return m, nil
+
+	case key.Matches(msg, m.keys.PrevView):
+		m.switchView(ViewType((int(m.activeView) + viewCount - 1) % viewCount))
+		return m, nil
+
+	case m.activeView == ViewMatrix && key.Matches(msg, m.keys.Group):
+		return m.togglePluginGrouping()
+
+	case key.Matches(msg, m.keys.Sort):
+		if m.activeView == ViewFrequency {
+			m.cycleFreqSort()
+		}
+		return m, nil
+
+	case m.activeView == ViewFrequency && key.Matches(msg, m.keys.ScrollLeft):
+		return m.scrollName(-nameScrollStep)
+
+	case m.activeView == ViewFrequency && key.Matches(msg, m.keys.ScrollRight):
+		return m.scrollName(nameScrollStep)
+
+	case m.activeView == ViewFrequency && key.Matches(msg, m.keys.Columns):
+		return m.openColumns()
+
+	case m.activeView == ViewFrequency && key.Matches(msg, m.keys.Subagents):
+		return m.toggleSubagents()
+
+	case m.activeView == ViewFrequency && key.Matches(msg, m.keys.SinceReview):
+		return m.toggleSinceReview()
+
+	case m.activeView == ViewFrequency && key.Matches(msg, m.keys.MarkReviewed):
+		return m.markReviewed()
+
+	case m.activeView == ViewFrequency && key.Matches(msg, m.keys.Queue):
+		return m.openQueue()
+
+	case m.activeView == ViewFrequency && key.Matches(msg, m.keys.Stage):
+		return m.toggleStage()
+
+	case m.activeView == ViewFrequency && key.Matches(msg, m.keys.ApplyStaged):
+		return m.openStaged()
+
+	case m.activeView == ViewFrequency && key.Matches(msg, m.keys.ApplyHere):
+		return m.openApplyHere()
+
+	case m.activeView == ViewFrequency && key.Matches(msg, m.keys.RecentOnly):
+		return m.toggleQuickFilter(quickRecent)
+
+	case m.activeView == ViewFrequency && key.Matches(msg, m.keys.UnapprovedOnly):
+		return m.toggleQuickFilter(quickUnapproved)
+
+	case m.activeView == ViewFrequency && key.Matches(msg, m.keys.DeniedOnly):
+		return m.toggleQuickFilter(quickDenied)
+
+	case m.activeView == ViewStale && key.Matches(msg, m.keys.Remove):
+		if m.staleList.cursor < len(m.staleAllows) {
+			m.showRemoveConfirm = true
+		}
 		return m, nil
 
-	case "shift+tab":
-		m.activeView = (m.activeView + 2) % 3
+	case m.activeView == ViewReconcile && key.Matches(msg, m.keys.Select, m.keys.Jump):
+		m.jumpToReconciled()
+		return m, m.toastTickCmd()
+
+	case m.activeView == ViewPolicy && key.Matches(msg, m.keys.Select, m.keys.Jump):
+		m.jumpToViolation()
+		return m, m.toastTickCmd()
+
+	case key.Matches(msg, m.keys.Pin):
+		return m.togglePin()
+
+	case m.searching() && key.Matches(msg, m.keys.NextMatch, m.keys.PrevMatch):
+		return m.nextMatch(key.Matches(msg, m.keys.NextMatch), 1)
+
+	case key.Matches(msg, m.keys.Note):
+		return m.startNoteFromList()
+
+	case key.Matches(msg, m.keys.Check):
+		var invocation string
+		if m.activeView == ViewFrequency {
+			invocation = checkInvocation(m.selectedPermission())
+		}
+		return m, m.openCheck(invocation)
+
+	case key.Matches(msg, m.keys.Copy, m.keys.CopySnippet):
+		return m.copySelected(key.Matches(msg, m.keys.CopySnippet))
+
+	case key.Matches(msg, m.keys.OutsideWrites):
+		return m.openOutsideWrites()
+
+	case key.Matches(msg, m.keys.Templates):
+		return m.openTemplates()
+
+	case key.Matches(msg, m.keys.Compare):
+		return m, m.openCompare()
+
+	case key.Matches(msg, m.keys.Tester):
+		var rule string
+		if m.activeView == ViewFrequency {
+			if perm := m.selectedPermission(); perm != nil {
+				rule = perm.Permission.Raw
+			}
+		}
+		return m, m.openTester(rule)
+
+	case key.Matches(msg, m.keys.Apply, m.keys.Deny, m.keys.Dismiss):
+		if m.activeView == ViewFrequency {
+			return m.handleStreakKeys(msg)
+		}
 		return m, nil
 
-	case "/":
+	case key.Matches(msg, m.keys.DryRun):
+		m.dryRun = !m.dryRun
+		if m.dryRun {
+			m.notify(toastInfo, "Dry run on: apply and deny only report what would change")
+		} else {
+			m.notify(toastInfo, "Dry run off: apply and deny write settings files")
+		}
+		return m, m.toastTickCmd()
+
+	case key.Matches(msg, m.keys.Filter):
 		m.filtering = true
 		m.filterInput.Focus()
 		return m, nil
 
-	case "esc":
+	case key.Matches(msg, m.keys.Back):
+		// Esc clears an active filter first, then returns from a followed link
+		if m.filterInput.Value() == "" && m.popNav() {
+			return m, nil
+		}
 		m.filterInput.SetValue("")
 		m.filteredIndices = nil
 		m.clampCursor()
@@ -135,13 +354,18 @@ func (m Model) handleKeyboard(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 // handleFilterKeys processes keys while in filter mode
 func (m Model) handleFilterKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "enter", "esc":
+	// Bracketed paste arrives as a single message; insert it in one go
+	if msg.Paste {
+		return m.handleFilterPaste(msg)
+	}
+
+	switch {
+	case msg.Type == tea.KeyEnter, msg.Type == tea.KeyEsc:
 		m.filtering = false
 		m.filterInput.Blur()
 		return m, nil
 
-	case "ctrl+c":
+	case key.Matches(msg, m.keys.ForceQuit):
 		return m, tea.Quit
 	}
 
@@ -152,6 +376,71 @@ func (m Model) handleFilterKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// handleFilterPaste inserts pasted text into the filter input atomically,
+// stripping control characters so escape sequences can't leak into the query.
+func (m Model) handleFilterPaste(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	runes := sanitizePaste(msg.Runes)
+	if len(runes) == 0 {
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.filterInput, cmd = m.filterInput.Update(tea.KeyMsg{
+		Type:  tea.KeyRunes,
+		Runes: runes,
+		Paste: true,
+	})
+	m.applyFilter()
+	return m, cmd
+}
+
+// handleDetailKeys processes keys while the permission detail modal is open
+func (m Model) handleDetailKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.ForceQuit):
+		return m, tea.Quit
+
+	case key.Matches(msg, m.keys.Select, m.keys.Apply):
+		m.openApplyFromDetail()
+
+	case key.Matches(msg, m.keys.Down):
+		if m.detailCursor < len(m.detailAgents(m.selectedPermission()))-1 {
+			m.detailCursor++
+		}
+
+	case key.Matches(msg, m.keys.Up):
+		if m.detailCursor > 0 {
+			m.detailCursor--
+		}
+
+	case key.Matches(msg, m.keys.Jump):
+		agents := m.detailAgents(m.selectedPermission())
+		if m.detailCursor >= len(agents) {
+			m.setLinkToast("No agent used this permission")
+			return m, m.toastTickCmd()
+		}
+		m.jumpToAgent(agents[m.detailCursor].label)
+
+	case key.Matches(msg, m.keys.Note):
+		if perm := m.selectedPermission(); perm != nil {
+			return m, m.startNote(false, perm.Permission.Raw)
+		}
+
+	case key.Matches(msg, m.keys.Check):
+		return m, m.openCheck(checkInvocation(m.selectedPermission()))
+
+	case key.Matches(msg, m.keys.Copy, m.keys.CopySnippet):
+		return m.copySelected(key.Matches(msg, m.keys.CopySnippet))
+
+	case key.Matches(msg, m.keys.ApplyHere):
+		return m.openApplyHere()
+
+	case key.Matches(msg, m.keys.Back, m.keys.Quit):
+		m.closeModal()
+	}
+	return m, nil
+}
+
 // handleModalKeys processes keys while modal is open
 func (m Model) handleModalKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch m.applyModalMode {
@@ -164,30 +453,34 @@ func (m Model) handleModalKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m Model) handleOptionSelectKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "esc", "q":
-		m.showApplyModal = false
-		m.resetApplyModalState()
+	switch {
+	case key.Matches(msg, m.keys.Back, m.keys.Quit):
+		m.closeModal()
 		return m, nil
-	case "j", "down":
+	case key.Matches(msg, m.keys.Down):
 		if m.applyOptionCursor < 1 {
 			m.applyOptionCursor++
 		}
 		return m, nil
-	case "k", "up":
+	case key.Matches(msg, m.keys.Up):
 		if m.applyOptionCursor > 0 {
 			m.applyOptionCursor--
 		}
 		return m, nil
-	case "enter", " ":
+	case key.Matches(msg, m.keys.Select):
 		if m.applyOptionCursor == 0 {
 			return m.applyToUser()
 		}
-		// Switch to project selection mode
+		// Switch to project selection mode, starting on the project this
+		// type of permission was last applied to
 		m.applyModalMode = ApplyModeProjectSelect
-		m.projectListCursor = 0
+		m.projectList.top()
+		if perm := m.selectedPermission(); perm != nil {
+			m.projectList.moveTo(m.lastProjectIndex(perm.Projects, perm.Permission.Type), len(perm.Projects), projectListHeight)
+		}
+		m.clearProjectFilter()
 		return m, nil
-	case "ctrl+c":
+	case key.Matches(msg, m.keys.ForceQuit):
 		return m, tea.Quit
 	}
 	return m, nil
@@ -198,115 +491,127 @@ func (m Model) handleProjectSelectKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	if perm == nil {
 		return m, nil
 	}
-	maxIdx := len(perm.Projects) - 1
+	if cmd, ok := m.handleProjectFilterKeys(msg, &m.projectList); ok {
+		return m, cmd
+	}
+	n := len(m.visibleProjects(perm.Projects))
 
-	switch msg.String() {
-	case "esc":
+	switch {
+	case key.Matches(msg, m.keys.Back):
 		m.applyModalMode = ApplyModeOptionSelect // Back to options
 		return m, nil
-	case "q":
-		m.showApplyModal = false
-		m.resetApplyModalState()
+	case key.Matches(msg, m.keys.Filter):
+		return m, m.startProjectFilter()
+	case key.Matches(msg, m.keys.Quit):
+		m.closeModal()
 		return m, nil
-	case "j", "down":
-		if m.projectListCursor < maxIdx {
-			m.projectListCursor++
-		}
+	case key.Matches(msg, m.keys.Down):
+		m.projectList.down(n, projectListHeight)
 		return m, nil
-	case "k", "up":
-		if m.projectListCursor > 0 {
-			m.projectListCursor--
-		}
+	case key.Matches(msg, m.keys.Up):
+		m.projectList.up(n, projectListHeight)
 		return m, nil
-	case "enter", " ":
+	case key.Matches(msg, m.keys.Select):
 		return m.applyToProject()
-	case "ctrl+c":
+	case key.Matches(msg, m.keys.ForceQuit):
 		return m, tea.Quit
 	}
 	return m, nil
 }
 
+// writePermission adds permission to the allow or deny list of the settings
+// file at path; in dry-run mode it only reports what would change
+func (m Model) writePermission(path, permission string, deny bool) (*parser.ApplyResult, error) {
+	if m.dryRun {
+		return parser.PlanPermissionWrite(path, permission, deny)
+	}
+	return parser.WritePermissionToSettingsFile(path, permission, deny)
+}
+
+// writePermissions adds permissions to the allow list of the settings file
+// at path in one write; in dry-run mode it only reports what would change
+func (m Model) writePermissions(path string, permissions []string) ([]*parser.ApplyResult, error) {
+	if m.dryRun {
+		return parser.PlanPermissionsWrite(path, permissions)
+	}
+	return parser.WritePermissionsToSettingsFile(path, permissions)
+}
+
+// writeSelected adds permissions to the user settings, or given a project
+// its settings, in one write with one result; in dry-run mode it only
+// reports what would change
+func (m Model) writeSelected(projectPath string, permissions []string) (*parser.ApplyResult, error) {
+	if m.dryRun {
+		path := parser.UserSettingsPath()
+		if projectPath != "" {
+			path = parser.ProjectSettingsPath(projectPath)
+		}
+		results, err := parser.PlanPermissionsWrite(path, permissions)
+		if err != nil {
+			return nil, err
+		}
+		return parser.BatchResult(path, results), nil
+	}
+	if projectPath == "" {
+		return parser.WritePermissionsToUserSettings(permissions)
+	}
+	return parser.WritePermissionsToProjectSettings(projectPath, permissions)
+}
+
 func (m Model) applyToUser() (tea.Model, tea.Cmd) {
+	if m.writeBlockedReason() != "" {
+		return m.writeBlocked()
+	}
 	perm := m.selectedPermission()
 	if perm == nil {
 		return m, nil
 	}
 
-	result, err := parser.WritePermissionToUserSettings(perm.Permission.Raw)
+	result, err := m.writePermission(parser.UserSettingsPath(), perm.Permission.Raw, false)
 	if err != nil {
-		m.err = err
-		return m, nil
+		return m, m.failWrite(parser.UserSettingsPath(), err, Model.applyToUser)
 	}
 
-	m.userApproved = append(m.userApproved, perm.Permission.Raw)
-	m.showApplyModal = false
-	m.resetApplyModalState()
+	if !result.DryRun {
+		m.userApproved = append(m.userApproved, perm.Permission.Raw)
+	}
+	m.recordApply("user", result)
+	m.finishModal()
 	m.setApplyToast(result)
-	return m, toastTickCmd()
+	return m, m.toastTickCmd()
 }
 
 func (m Model) applyToProject() (tea.Model, tea.Cmd) {
+	if m.writeBlockedReason() != "" {
+		return m.writeBlocked()
+	}
 	perm := m.selectedPermission()
-	if perm == nil || m.projectListCursor >= len(perm.Projects) {
+	if perm == nil {
 		return m, nil
 	}
-
-	projectPath := perm.Projects[m.projectListCursor]
-	result, err := parser.WritePermissionToProjectSettings(projectPath, perm.Permission.Raw)
-	if err != nil {
-		m.err = err
+	projects := m.visibleProjects(perm.Projects)
+	if m.projectList.cursor >= len(projects) {
 		return m, nil
 	}
 
-	m.projectApproved = append(m.projectApproved, perm.Permission.Raw)
-	m.showApplyModal = false
-	m.resetApplyModalState()
-	m.setApplyToast(result)
-	return m, toastTickCmd()
-}
-
-// generateUserCommand creates the command to add permission at user level
-func generateUserCommand(permission string) string {
-	return fmt.Sprintf(`# Add to ~/.claude/settings.local.json under "permissions.allow":
-"%s"`, permission)
-}
-
-// generateProjectCommand creates the command to add permission at project level
-func generateProjectCommand(permission string) string {
-	return fmt.Sprintf(`# Add to .claude/settings.local.json under "permissions.allow":
-"%s"`, permission)
-}
-
-// copyToClipboard copies text to system clipboard
-func copyToClipboard(text string) {
-	var cmd *exec.Cmd
-
-	switch runtime.GOOS {
-	case "darwin":
-		cmd = exec.Command("pbcopy")
-	case "linux":
-		// Try xclip first, then xsel
-		if _, err := exec.LookPath("xclip"); err == nil {
-			cmd = exec.Command("xclip", "-selection", "clipboard")
-		} else {
-			cmd = exec.Command("xsel", "--clipboard", "--input")
-		}
-	default:
-		return
+	projectPath := projects[m.projectList.cursor]
+	if m.projectAllowsAll(projectPath, []string{perm.Permission.Raw}) {
+		m.setLinkToast("Already allowed in %s", parser.ProjectSettingsPath(projectPath))
+		return m, m.toastTickCmd()
 	}
-
-	stdin, err := cmd.StdinPipe()
+	result, err := m.writePermission(parser.ProjectSettingsPath(projectPath), perm.Permission.Raw, false)
 	if err != nil {
-		return
+		return m, m.failWrite(parser.ProjectSettingsPath(projectPath), err, Model.applyToProject)
 	}
 
-	if err := cmd.Start(); err != nil {
-		return
+	if !result.DryRun {
+		m.projectSettings[projectPath] = append(m.projectSettings[projectPath], perm.Permission.Raw)
 	}
-
-	stdin.Write([]byte(text))
-	stdin.Close()
-	cmd.Wait()
+	m.recordApply("project", result)
+	m.rememberProject(perm.Permission.Type, projectPath)
+	m.finishModal()
+	m.setApplyToast(result)
+	return m, m.toastTickCmd()
 }
 
 // writeToStderr writes a message to stderr (for debugging)
@@ -323,6 +628,12 @@ func (m Model) handleAgentModalKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleAgentScopeKeys(msg)
 	case AgentModalModeProject:
 		return m.handleAgentProjectKeys(msg)
+	case AgentModalModeTools:
+		return m.handleAgentToolsKeys(msg)
+	case AgentModalModeEditTools:
+		return m.handleAgentEditToolsKeys(msg)
+	case AgentModalModeInvocations:
+		return m.handleAgentInvocationKeys(msg)
 	}
 	return m, nil
 }
@@ -334,34 +645,33 @@ func (m Model) handleAgentPermissionKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	agent := m.agentUsage[m.selectedAgentIdx]
 	maxIdx := len(agent.Permissions) - 1
 
-	switch msg.String() {
-	case "esc", "q":
-		m.showAgentModal = false
-		m.resetAgentModalState()
+	switch {
+	case key.Matches(msg, m.keys.Back, m.keys.Quit):
+		m.closeModal()
 		return m, nil
 
-	case "j", "down":
-		if m.agentModalCursor < maxIdx {
-			m.agentModalCursor++
-		}
+	case key.Matches(msg, m.keys.Note):
+		return m, m.startNote(true, agent.AgentType)
+
+	// The list is shown whole, so its height is its length
+	case key.Matches(msg, m.keys.Down):
+		m.agentModalList.down(maxIdx+1, maxIdx+1)
 		return m, nil
 
-	case "k", "up":
-		if m.agentModalCursor > 0 {
-			m.agentModalCursor--
-		}
+	case key.Matches(msg, m.keys.Up):
+		m.agentModalList.up(maxIdx+1, maxIdx+1)
 		return m, nil
 
-	case " ": // Spacebar to toggle
-		if m.agentModalCursor <= maxIdx {
-			for len(m.agentModalSelected) <= m.agentModalCursor {
+	case key.Matches(msg, m.keys.Toggle):
+		if i := m.agentModalList.cursor; i <= maxIdx {
+			for len(m.agentModalSelected) <= i {
 				m.agentModalSelected = append(m.agentModalSelected, false)
 			}
-			m.agentModalSelected[m.agentModalCursor] = !m.agentModalSelected[m.agentModalCursor]
+			m.agentModalSelected[i] = !m.agentModalSelected[i]
 		}
 		return m, nil
 
-	case "a", "A": // Apply selected
+	case key.Matches(msg, m.keys.Apply):
 		hasSelected := false
 		for _, sel := range m.agentModalSelected {
 			if sel {
@@ -375,7 +685,39 @@ func (m Model) handleAgentPermissionKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
-	case "ctrl+c":
+	case key.Matches(msg, m.keys.Tools):
+		m.agentModalMode = AgentModalModeTools
+		m.agentToolsFile = parser.FindAgentFile(agent.AgentType)
+		return m, nil
+
+	case key.Matches(msg, m.keys.EditTools):
+		m.agentToolsFile = parser.FindAgentFile(agent.AgentType)
+		if m.agentToolsFile == "" {
+			m.setLinkToast("%s is built in and has no %s file to edit", agent.AgentType, toolsFileKind(agent.AgentType))
+			return m, m.toastTickCmd()
+		}
+		if err := m.openEditTools(agent); err != nil {
+			m.logger.Debug("reading agent tools failed", "path", m.agentToolsFile, "err", err)
+			m.notify(toastError, "Could not read the tools of %s: %v", shortenPath(m.agentToolsFile), err)
+			return m, m.toastTickCmd()
+		}
+		return m, nil
+
+	case key.Matches(msg, m.keys.Invocations):
+		return m.openInvocations(agent)
+
+	case key.Matches(msg, m.keys.Jump):
+		if m.agentModalList.cursor > maxIdx {
+			return m, nil
+		}
+		raw := agent.Permissions[m.agentModalList.cursor].Permission.Raw
+		if !m.jumpToPermission(raw) {
+			m.setLinkToast("%s was not used outside agents", raw)
+			return m, m.toastTickCmd()
+		}
+		return m, nil
+
+	case key.Matches(msg, m.keys.ForceQuit):
 		return m, tea.Quit
 	}
 
@@ -383,33 +725,38 @@ func (m Model) handleAgentPermissionKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m Model) handleAgentScopeKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "esc":
+	switch {
+	case key.Matches(msg, m.keys.Back):
 		m.agentModalMode = AgentModalModePermissions
 		return m, nil
 
-	case "j", "down":
+	case key.Matches(msg, m.keys.Down):
 		if m.agentModalScope < 1 {
 			m.agentModalScope++
 		}
 		return m, nil
 
-	case "k", "up":
+	case key.Matches(msg, m.keys.Up):
 		if m.agentModalScope > 0 {
 			m.agentModalScope--
 		}
 		return m, nil
 
-	case "enter", " ":
+	case key.Matches(msg, m.keys.Select):
 		if m.agentModalScope == 0 {
 			return m.applySelectedToUser()
-		} else {
-			m.agentModalMode = AgentModalModeProject
-			m.agentModalProjCursor = 0
 		}
+		m.agentModalMode = AgentModalModeProject
+		m.agentModalProjList.top()
+		if m.selectedAgentIdx < len(m.agentUsage) {
+			agent := m.agentUsage[m.selectedAgentIdx]
+			n := len(agent.Projects)
+			m.agentModalProjList.moveTo(m.lastProjectIndex(agent.Projects, selectionType(m.agentModalSelection(agent))), n, n)
+		}
+		m.clearProjectFilter()
 		return m, nil
 
-	case "ctrl+c":
+	case key.Matches(msg, m.keys.ForceQuit):
 		return m, tea.Quit
 	}
 
@@ -421,112 +768,124 @@ func (m Model) handleAgentProjectKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 	agent := m.agentUsage[m.selectedAgentIdx]
-	maxIdx := len(agent.Projects) - 1
+	if cmd, ok := m.handleProjectFilterKeys(msg, &m.agentModalProjList); ok {
+		return m, cmd
+	}
+	n := len(m.visibleProjects(agent.Projects))
 
-	switch msg.String() {
-	case "esc":
+	switch {
+	case key.Matches(msg, m.keys.Back):
 		m.agentModalMode = AgentModalModeScope
 		return m, nil
 
-	case "j", "down":
-		if m.agentModalProjCursor < maxIdx {
-			m.agentModalProjCursor++
-		}
+	case key.Matches(msg, m.keys.Filter):
+		return m, m.startProjectFilter()
+
+	// The list is shown whole, so its height is its length
+	case key.Matches(msg, m.keys.Down):
+		m.agentModalProjList.down(n, n)
 		return m, nil
 
-	case "k", "up":
-		if m.agentModalProjCursor > 0 {
-			m.agentModalProjCursor--
-		}
+	case key.Matches(msg, m.keys.Up):
+		m.agentModalProjList.up(n, n)
 		return m, nil
 
-	case "enter", " ":
+	case key.Matches(msg, m.keys.Select):
 		return m.applySelectedToProject()
 
-	case "ctrl+c":
+	case key.Matches(msg, m.keys.ForceQuit):
 		return m, tea.Quit
 	}
 
 	return m, nil
 }
 
+// agentModalSelection returns the permissions checked in the agent modal
+func (m Model) agentModalSelection(agent types.AgentUsageStats) []string {
+	var selected []string
+	for i, perm := range agent.Permissions {
+		if i < len(m.agentModalSelected) && m.agentModalSelected[i] {
+			selected = append(selected, perm.Permission.Raw)
+		}
+	}
+	return selected
+}
+
 func (m Model) applySelectedToUser() (tea.Model, tea.Cmd) {
+	if m.writeBlockedReason() != "" {
+		return m.writeBlocked()
+	}
 	if m.selectedAgentIdx >= len(m.agentUsage) {
 		return m, nil
 	}
-	agent := m.agentUsage[m.selectedAgentIdx]
-
-	var lastResult *parser.ApplyResult
-	applied := 0
-	for i, perm := range agent.Permissions {
-		if i < len(m.agentModalSelected) && m.agentModalSelected[i] {
-			result, err := parser.WritePermissionToUserSettings(perm.Permission.Raw)
-			if err != nil {
-				m.err = err
-				return m, nil
-			}
-			m.userApproved = append(m.userApproved, perm.Permission.Raw)
-			lastResult = result
-			applied++
-		}
+	selected := m.agentModalSelection(m.agentUsage[m.selectedAgentIdx])
+	if len(selected) == 0 {
+		return m, nil
 	}
 
-	m.showAgentModal = false
-	m.resetAgentModalState()
-	if lastResult != nil {
-		if applied == 1 {
-			m.setApplyToast(lastResult)
-		} else {
-			m.setApplyToastBatch(lastResult.FilePath, applied)
-		}
-		return m, toastTickCmd()
+	result, err := m.writeSelected("", selected)
+	if err != nil {
+		return m, m.failWrite(parser.UserSettingsPath(), err, Model.applySelectedToUser)
 	}
-	return m, nil
+	if !result.DryRun {
+		m.userApproved = append(m.userApproved, selected...)
+	}
+	m.recordApply("user", result)
+
+	m.finishModal()
+	m.setApplyToastBatch(result)
+	return m, m.toastTickCmd()
 }
 
 func (m Model) applySelectedToProject() (tea.Model, tea.Cmd) {
+	if m.writeBlockedReason() != "" {
+		return m.writeBlocked()
+	}
 	if m.selectedAgentIdx >= len(m.agentUsage) {
 		return m, nil
 	}
 	agent := m.agentUsage[m.selectedAgentIdx]
 
-	if m.agentModalProjCursor >= len(agent.Projects) {
+	projects := m.visibleProjects(agent.Projects)
+	if m.agentModalProjList.cursor >= len(projects) {
 		return m, nil
 	}
-	projectPath := agent.Projects[m.agentModalProjCursor]
+	projectPath := projects[m.agentModalProjList.cursor]
 
-	var lastResult *parser.ApplyResult
-	applied := 0
-	for i, perm := range agent.Permissions {
-		if i < len(m.agentModalSelected) && m.agentModalSelected[i] {
-			result, err := parser.WritePermissionToProjectSettings(projectPath, perm.Permission.Raw)
-			if err != nil {
-				m.err = err
-				return m, nil
-			}
-			m.projectApproved = append(m.projectApproved, perm.Permission.Raw)
-			lastResult = result
-			applied++
-		}
+	selected := m.agentModalSelection(agent)
+	if len(selected) == 0 {
+		return m, nil
+	}
+	if m.projectAllowsAll(projectPath, selected) {
+		m.setLinkToast("Already allowed in %s", parser.ProjectSettingsPath(projectPath))
+		return m, m.toastTickCmd()
 	}
 
-	m.showAgentModal = false
-	m.resetAgentModalState()
-	if lastResult != nil {
-		if applied == 1 {
-			m.setApplyToast(lastResult)
-		} else {
-			m.setApplyToastBatch(lastResult.FilePath, applied)
-		}
-		return m, toastTickCmd()
+	result, err := m.writeSelected(projectPath, selected)
+	if err != nil {
+		return m, m.failWrite(parser.ProjectSettingsPath(projectPath), err, Model.applySelectedToProject)
 	}
-	return m, nil
+	if !result.DryRun {
+		m.projectSettings[projectPath] = append(m.projectSettings[projectPath], selected...)
+	}
+	m.recordApply("project", result)
+	m.rememberProject(selectionType(selected), projectPath)
+
+	m.finishModal()
+	m.setApplyToastBatch(result)
+	return m, m.toastTickCmd()
 }
 
 func (m *Model) resetAgentModalState() {
-	m.agentModalCursor = 0
+	m.agentModalList.top()
 	m.agentModalSelected = nil
 	m.agentModalMode = AgentModalModePermissions
 	m.agentModalScope = 0
-	m.agentModalProjCursor = 0
+	m.agentModalProjList.top()
+	m.clearProjectFilter()
+	m.agentToolsFile = ""
+	m.agentEditTools = nil
+	m.agentEditChecked = nil
+	m.agentEditDeclared = 0
+	m.agentEditCursor = 0
 }
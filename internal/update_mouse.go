@@ -1,11 +1,25 @@
 package internal
 
-import tea "github.com/charmbracelet/bubbletea"
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Screen rows where the lists start: title, tabs, then the view's header
+// and separator (the Matrix view adds a column header)
+const (
+	listStartY   = 4
+	matrixStartY = 5
+)
 
 // handleMouse processes mouse input
 func (m Model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
-	// Ignore mouse in modal
-	if m.showApplyModal {
+	// Text inputs own the keyboard; don't feed them synthesized keys
+	if m.isLoading || m.err != nil || m.filtering || m.choosingDir {
 		return m, nil
 	}
 
@@ -16,65 +30,512 @@ func (m Model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
 		}
 
 	case tea.MouseButtonWheelUp:
-		m.cursor--
-		m.clampCursor()
-		return m, nil
+		return m.press(m.keys.Up)
 
 	case tea.MouseButtonWheelDown:
-		m.cursor++
-		m.clampCursor()
-		return m, nil
+		return m.press(m.keys.Down)
 	}
 
 	return m, nil
 }
 
-// handleLeftClick processes left mouse clicks
+// press runs the keyboard handler for b, so the wheel and clicks behave
+// exactly like the keys they stand in for in every view and modal
+func (m Model) press(b key.Binding) (tea.Model, tea.Cmd) {
+	keys := b.Keys()
+	if !b.Enabled() || len(keys) == 0 {
+		return m, nil
+	}
+	// A KeyRunes message stringifies to its runes, so this matches named
+	// keys like "down" as well as single characters
+	return m.handleKeyboard(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(keys[0])})
+}
+
+// handleLeftClick processes left mouse clicks. Clicking a row selects it;
+// clicking the selected row again activates it like enter.
 func (m Model) handleLeftClick(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case m.onboarding:
+		return m, nil
+
+	case m.showFullHelp:
+		if !m.modalContains(m.renderFullHelp(), msg.X, msg.Y) {
+			m.showFullHelp = false
+		}
+		return m, nil
+
+	case m.showToastLog:
+		if !m.modalContains(m.renderToastLog(), msg.X, msg.Y) {
+			m.showToastLog = false
+		}
+		return m, nil
+
+	case m.showOutside:
+		return m.handleOutsideWritesClick(msg)
+
+	case m.showTemplates:
+		return m.handleTemplatesClick(msg)
+
+	case m.showCompare:
+		return m.handleCompareClick(msg)
+
+	case m.showTester:
+		if !m.modalContains(m.renderTesterModal(), msg.X, msg.Y) {
+			m.testerInput.Blur()
+			m.showTester = false
+		}
+		return m, nil
+
+	case m.showAgentModal:
+		return m.handleAgentModalClick(msg)
+
+	case m.showPluginModal:
+		if !m.modalContains(m.renderPluginModal(), msg.X, msg.Y) {
+			m.closeModal()
+		}
+		return m, nil
+
+	case m.showQueue:
+		if !m.modalContains(m.renderQueueModal(), msg.X, msg.Y) {
+			m.showQueue = false
+		}
+		return m, nil
+
+	case m.showStaged:
+		if !m.modalContains(m.renderStagedModal(), msg.X, msg.Y) {
+			m.showStaged = false
+		}
+		return m, nil
+
+	case m.showApplyHere:
+		if !m.modalContains(m.renderApplyHereModal(), msg.X, msg.Y) {
+			m.closeModal()
+		}
+		return m, nil
+
+	case m.showColumns:
+		if !m.modalContains(m.renderColumnsModal(), msg.X, msg.Y) {
+			return m.closeColumns()
+		}
+		return m, nil
+
+	case m.showDetail:
+		return m.handleDetailClick(msg)
+
+	case m.showApplyModal:
+		return m.handleApplyModalClick(msg)
+
+	case m.showSnapDiff:
+		if !m.modalContains(m.renderSnapDiffModal(), msg.X, msg.Y) {
+			m.closeModal()
+		}
+		return m, nil
+
+	case m.showRemoveConfirm:
+		if !m.modalContains(m.renderRemoveConfirmModal(), msg.X, msg.Y) {
+			m.closeModal()
+		}
+		return m, nil
+
+	case m.showCheck:
+		if !m.modalContains(m.renderCheckModal(), msg.X, msg.Y) {
+			m.closeModal()
+		}
+		return m, nil
+	}
+
 	// Check if clicking on tab bar (row 1)
 	if msg.Y == 1 {
 		return m.handleTabClick(msg)
 	}
 
-	// Check if clicking on list items
-	// List starts at row 3 (0: title, 1: tabs, 2: header)
-	listStartY := 3
+	switch m.activeView {
+	case ViewFrequency:
+		barY := listStartY - 2 // The banners follow the title and tab bars
+		if len(m.pendingStreaks()) > 0 {
+			barY++
+		}
+		if m.quickFiltering() && msg.Y == barY {
+			return m.handleQuickFilterClick(msg.X)
+		}
+		return m.handleFreqClick(msg.Y - listStartY - m.freqBannerLines())
+	case ViewMatrix:
+		return m.handleMatrixClick(msg.Y)
+	case ViewDiagnostics:
+		return m.handleDiagClick(msg.Y - listStartY)
+	case ViewSnapshots:
+		return m.handleSnapClick(msg.Y - listStartY)
+	case ViewHistory:
+		return m.handleHistClick(msg.Y - listStartY)
+	case ViewStale:
+		return m.handleStaleClick(msg.Y - listStartY)
+	case ViewReconcile:
+		return m.handleReconClick(msg.Y - listStartY)
+	case ViewPolicy:
+		return m.handlePolicyClick(msg.Y - listStartY)
+	}
+
+	return m, nil
+}
+
+// handleTabClick processes clicks on the tab bar
+func (m Model) handleTabClick(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	// Walk the rendered tabs (separated by one space) to find the one under x
+	x := 0
+	for i := 0; i < viewCount; i++ {
+		w := lipgloss.Width(m.renderTab(ViewType(i)))
+		if msg.X >= x && msg.X < x+w {
+			m.switchView(ViewType(i))
+			break
+		}
+		x += w + 1
+	}
+
+	return m, nil
+}
+
+// handleFreqClick selects the clicked group or permission, or activates
+// it if it was already selected
+func (m Model) handleFreqClick(row int) (tea.Model, tea.Cmd) {
+	idx, ok := m.freqList.rowAt(row, m.freqTotalLines(), m.freqViewportHeight())
+	if !ok {
+		return m, nil
+	}
+	if idx == m.freqList.cursor {
+		return m.press(m.keys.Select)
+	}
+	m.freqList.cursor = idx
+	return m, nil
+}
+
+// handleMatrixClick maps a screen row to a Matrix row, allowing for the
+// "(^ N more)" line shown when scrolled
+func (m Model) handleMatrixClick(y int) (tea.Model, tea.Cmd) {
 	_, contentHeight := m.calculateLayout()
+	start := matrixStartY
+	viewportHeight := contentHeight - 3
+	if m.matrixList.scroll > 0 {
+		start++
+		viewportHeight--
+	}
+
+	row := y - start
+	if row < 0 || row >= viewportHeight {
+		return m, nil
+	}
+	idx := m.matrixList.scroll + row
+	if idx >= m.matrixListLen() {
+		return m, nil
+	}
+
+	if idx == m.matrixList.cursor {
+		return m.press(m.keys.Select)
+	}
+	m.matrixList.cursor = idx
+	return m, nil
+}
+
+// handleDiagClick selects the clicked warning
+func (m Model) handleDiagClick(row int) (tea.Model, tea.Cmd) {
+	if idx, ok := m.diagList.rowAt(row, len(m.warnings), m.diagViewportHeight()); ok {
+		m.diagList.cursor = idx
+	}
+	return m, nil
+}
+
+// handleSnapClick selects the clicked snapshot; clicking the selected one
+// opens its diff
+func (m Model) handleSnapClick(row int) (tea.Model, tea.Cmd) {
+	idx, ok := m.snapList.rowAt(row, len(m.snapshots), m.snapViewportHeight())
+	if !ok {
+		return m, nil
+	}
+	if idx == m.snapList.cursor {
+		return m.press(m.keys.Select)
+	}
+	m.snapList.cursor = idx
+	return m, nil
+}
+
+// handleHistClick selects the clicked audit entry
+func (m Model) handleHistClick(row int) (tea.Model, tea.Cmd) {
+	if idx, ok := m.histList.rowAt(row, len(m.history), m.histViewportHeight()); ok {
+		m.histList.cursor = idx
+	}
+	return m, nil
+}
+
+// handleStaleClick selects the clicked row of the Stale view
+func (m Model) handleStaleClick(row int) (tea.Model, tea.Cmd) {
+	if idx, ok := m.staleList.rowAt(row, len(m.staleAllows), m.staleViewportHeight()); ok {
+		m.staleList.cursor = idx
+	}
+	return m, nil
+}
+
+// handleReconClick selects the clicked row of the Reconcile view
+func (m Model) handleReconClick(row int) (tea.Model, tea.Cmd) {
+	if idx, ok := m.reconList.rowAt(row, len(m.reconciled), m.reconViewportHeight()); ok {
+		m.reconList.cursor = idx
+	}
+	return m, nil
+}
+
+// handlePolicyClick selects the clicked row of the Policy view
+func (m Model) handlePolicyClick(row int) (tea.Model, tea.Cmd) {
+	if idx, ok := m.policyList.rowAt(row, len(m.violations), m.policyViewportHeight()); ok {
+		m.policyList.cursor = idx
+	}
+	return m, nil
+}
+
+// handleDetailClick selects an agent in the detail modal; clicking the
+// selected agent follows the link to it
+func (m Model) handleDetailClick(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	modal := m.renderDetailModal()
+	if !m.modalContains(modal, msg.X, msg.Y) {
+		m.closeModal()
+		return m, nil
+	}
+	offset, ok := m.modalListOffset(modal, msg.Y)
+	if !ok {
+		return m, nil
+	}
+
+	// Only the rows inside the agents window; not the "+N more" line
+	start := 0
+	if m.detailCursor >= detailListLimit {
+		start = m.detailCursor - detailListLimit + 1
+	}
+	n := len(m.detailAgents(m.selectedPermission()))
+	if end := start + detailListLimit; end < n {
+		n = end
+	}
+	if m.detailCursor+offset < start {
+		return m, nil
+	}
+
+	if clickRow(&m.detailCursor, n, offset) {
+		return m.press(m.keys.Jump)
+	}
+	return m, nil
+}
 
-	if msg.Y >= listStartY && msg.Y < listStartY+contentHeight {
-		// Calculate which item was clicked
-		clickedIndex := msg.Y - listStartY
-		perms := m.visiblePermissions()
+// handleOutsideWritesClick selects a project or file; clicking the
+// selected project lists its files
+func (m Model) handleOutsideWritesClick(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	modal := m.renderOutsideWritesModal()
+	if !m.modalContains(modal, msg.X, msg.Y) {
+		m.showOutside = false
+		return m, nil
+	}
+	offset, ok := m.modalListOffset(modal, msg.Y)
+	if !ok {
+		return m, nil
+	}
+	l := &m.outsideList
+	if m.outsideProject != "" {
+		l = &m.outsideFiles
+	}
+	n := m.outsideRows()
+	if start, end := l.window(n, maxVisibleWrites); l.cursor+offset < start || l.cursor+offset >= end {
+		return m, nil
+	}
+	if clickRow(&l.cursor, n, offset) && m.outsideProject == "" {
+		return m.press(m.keys.Select)
+	}
+	return m, nil
+}
 
-		if clickedIndex >= 0 && clickedIndex < len(perms) {
-			if m.cursor == clickedIndex {
-				// Double-click effect: open modal
-				m.showApplyModal = true
-			} else {
-				m.cursor = clickedIndex
-			}
+// handleTemplatesClick selects a template or where to apply it; clicking
+// the selected row again previews or applies it
+func (m Model) handleTemplatesClick(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	modal := m.renderTemplatesModal()
+	if !m.modalContains(modal, msg.X, msg.Y) {
+		m.showTemplates = false
+		return m, nil
+	}
+	offset, ok := m.modalListOffset(modal, msg.Y)
+	if !ok {
+		return m, nil
+	}
+	if m.templatePicked {
+		cursor, targets := 0, 1
+		if m.templateProject {
+			cursor = 1
 		}
+		if m.projectPath != "" {
+			targets = 2
+		}
+		activate := clickRow(&cursor, targets, offset)
+		m.templateProject = cursor == 1
+		if activate {
+			return m.press(m.keys.Select)
+		}
+		return m, nil
 	}
+	n := len(m.templates)
+	if start, end := m.templateList.window(n, maxVisibleTemplates); m.templateList.cursor+offset < start || m.templateList.cursor+offset >= end {
+		return m, nil
+	}
+	if clickRow(&m.templateList.cursor, n, offset) {
+		return m.press(m.keys.Select)
+	}
+	return m, nil
+}
 
+// handleCompareClick selects a difference; clicking the selected one marks
+// it to adopt
+func (m Model) handleCompareClick(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	modal := m.renderCompareModal()
+	if !m.modalContains(modal, msg.X, msg.Y) {
+		m.compareInput.Blur()
+		m.showCompare = false
+		return m, nil
+	}
+	offset, ok := m.modalListOffset(modal, msg.Y)
+	if !ok || m.compareTheirs == "" {
+		return m, nil
+	}
+	n := len(m.compareDiffs)
+	if start, end := m.compareList.window(n, maxVisibleDifferences); m.compareList.cursor+offset < start || m.compareList.cursor+offset >= end {
+		return m, nil
+	}
+	if clickRow(&m.compareList.cursor, n, offset) {
+		return m.press(m.keys.Toggle)
+	}
 	return m, nil
 }
 
-// handleTabClick processes clicks on the tab bar
-func (m Model) handleTabClick(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
-	// Tab positions (approximate):
-	// [Frequency] starts around x=1
-	// [Matrix] starts around x=14
-	// [Help] starts around x=24
+// handleApplyModalClick picks an apply option or project
+func (m Model) handleApplyModalClick(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	modal := m.renderApplyModal()
+	if !m.modalContains(modal, msg.X, msg.Y) {
+		m.closeModal()
+		return m, nil
+	}
+	offset, ok := m.modalListOffset(modal, msg.Y)
+	if !ok {
+		return m, nil
+	}
 
-	x := msg.X
+	var activate bool
+	switch m.applyModalMode {
+	case ApplyModeOptionSelect:
+		activate = clickRow(&m.applyOptionCursor, 2, offset)
+	case ApplyModeProjectSelect:
+		if perm := m.selectedPermission(); perm != nil {
+			activate = clickRow(&m.projectList.cursor, len(m.visibleProjects(perm.Projects)), offset)
+		}
+	}
+
+	if activate {
+		return m.press(m.keys.Select)
+	}
+	return m, nil
+}
 
-	if x >= 1 && x < 13 {
-		m.activeView = ViewFrequency
-	} else if x >= 13 && x < 23 {
-		m.activeView = ViewMatrix
-	} else if x >= 23 && x < 31 {
-		m.activeView = ViewHelp
+// handleAgentModalClick moves the agent modal cursor; clicking the selected
+// permission or tool toggles it, and clicking the selected scope or project
+// applies
+func (m Model) handleAgentModalClick(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	modal := m.renderAgentDetailModal()
+	if !m.modalContains(modal, msg.X, msg.Y) {
+		m.closeModal()
+		return m, nil
+	}
+	offset, ok := m.modalListOffset(modal, msg.Y)
+	if !ok || m.selectedAgentIdx >= len(m.agentUsage) {
+		return m, nil
+	}
+	agent := m.agentUsage[m.selectedAgentIdx]
+
+	switch m.agentModalMode {
+	case AgentModalModePermissions:
+		if clickRow(&m.agentModalList.cursor, len(agent.Permissions), offset) {
+			return m.press(m.keys.Toggle)
+		}
+	case AgentModalModeScope:
+		if clickRow(&m.agentModalScope, 2, offset) {
+			return m.press(m.keys.Select)
+		}
+	case AgentModalModeProject:
+		if clickRow(&m.agentModalProjList.cursor, len(m.visibleProjects(agent.Projects)), offset) {
+			return m.press(m.keys.Select)
+		}
+	case AgentModalModeEditTools:
+		if clickRow(&m.agentEditCursor, len(m.agentEditTools), offset) {
+			return m.press(m.keys.Toggle)
+		}
+	case AgentModalModeInvocations:
+		clickRow(&m.agentInvocations.cursor, invocationRows(agent), offset)
 	}
 
 	return m, nil
 }
+
+// clickRow moves cursor by offset within a list of n rows. It reports
+// whether the click landed on the row that was already selected.
+func clickRow(cursor *int, n, offset int) bool {
+	idx := *cursor + offset
+	if idx < 0 || idx >= n {
+		return false
+	}
+	*cursor = idx
+	return offset == 0
+}
+
+// modalTop returns the screen row of the first line of a modal placed by
+// centerOverlay
+func (m Model) modalTop(modalHeight int) int {
+	topPadding := (m.height - 1 - modalHeight) / 2
+	if topPadding < 0 {
+		topPadding = 0
+	}
+	return 1 + topPadding
+}
+
+// modalContains reports whether screen position (x, y) is on the modal
+func (m Model) modalContains(modal string, x, y int) bool {
+	lines := strings.Split(modal, "\n")
+	row := y - m.modalTop(len(lines))
+	if row < 0 || row >= len(lines) {
+		return false
+	}
+	return x >= 0 && x < lipgloss.Width(lines[row])
+}
+
+// modalListOffset returns how many rows screen row y is below the modal's
+// cursor row (the one marked "> "). It reports false unless y is on the
+// same block of consecutive lines as the cursor, i.e. on the same list.
+func (m Model) modalListOffset(modal string, y int) (int, bool) {
+	lines := strings.Split(modal, "\n")
+	row := y - m.modalTop(len(lines))
+	if row < 0 || row >= len(lines) {
+		return 0, false
+	}
+
+	cursor := -1
+	for i, line := range lines {
+		if strings.HasPrefix(modalLineText(line), "> ") {
+			cursor = i
+			break
+		}
+	}
+	if cursor < 0 {
+		return 0, false
+	}
+
+	lo, hi := min(row, cursor), max(row, cursor)
+	for i := lo; i <= hi; i++ {
+		if modalLineText(lines[i]) == "" {
+			return 0, false
+		}
+	}
+	return row - cursor, true
+}
+
+// modalLineText strips styling, the border and the indent from a modal line
+func modalLineText(line string) string {
+	return strings.Trim(ansi.Strip(line), "│ ")
+}
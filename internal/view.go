@@ -2,10 +2,13 @@ package internal
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/b-open-io/claude-perms/internal/parser"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
 // View implements tea.Model
@@ -18,6 +21,10 @@ func (m Model) View() string {
 		return fmt.Sprintf("Error: %v\n\nPress q to quit.", m.err)
 	}
 
+	if m.onboarding {
+		return m.renderOnboarding()
+	}
+
 	var b strings.Builder
 
 	// Title bar
@@ -34,18 +41,91 @@ func (m Model) View() string {
 		b.WriteString(m.renderFrequencyView())
 	case ViewMatrix:
 		b.WriteString(m.renderMatrixView())
+	case ViewDiagnostics:
+		b.WriteString(m.renderDiagnosticsView())
+	case ViewSnapshots:
+		b.WriteString(m.renderSnapshotsView())
+	case ViewHistory:
+		b.WriteString(m.renderHistoryView())
+	case ViewStale:
+		b.WriteString(m.renderStaleView())
+	case ViewReconcile:
+		b.WriteString(m.renderReconcileView())
+	case ViewPolicy:
+		b.WriteString(m.renderPolicyView())
 	case ViewHelp:
 		b.WriteString(m.renderHelpView())
 	}
 
-	// Status bar (toast overrides when active)
-	if m.toastTicks > 0 && m.toastMessage != "" {
-		b.WriteString(m.renderToast())
-	} else {
+	// Status bar (a short toast overrides it, a long one shows above it)
+	switch t := m.currentToast(); {
+	case t == nil:
 		b.WriteString(m.renderStatusBar())
+	case m.toastWraps(*t):
+		content := m.overlayToastPanel(b.String(), *t)
+		b.Reset()
+		b.WriteString(content + m.renderStatusBar())
+	default:
+		b.WriteString(m.renderToast(*t))
 	}
 
 	// Modal overlays
+	if m.showFullHelp {
+		return m.centerOverlay(m.renderFullHelp())
+	}
+
+	if m.showToastLog {
+		return m.centerOverlay(m.renderToastLog())
+	}
+
+	if m.showDetail {
+		return m.centerOverlay(m.renderDetailModal())
+	}
+
+	if m.showOutside {
+		return m.centerOverlay(m.renderOutsideWritesModal())
+	}
+
+	if m.showTester {
+		return m.centerOverlay(m.renderTesterModal())
+	}
+
+	if m.showCompare {
+		return m.centerOverlay(m.renderCompareModal())
+	}
+
+	if m.showTemplates {
+		return m.centerOverlay(m.renderTemplatesModal())
+	}
+
+	if m.showQueue {
+		return m.centerOverlay(m.renderQueueModal())
+	}
+
+	if m.showStaged {
+		return m.centerOverlay(m.renderStagedModal())
+	}
+
+	if m.showApplyHere {
+		return m.centerOverlay(m.renderApplyHereModal())
+	}
+
+	if m.showColumns {
+		return m.centerOverlay(m.renderColumnsModal())
+	}
+
+	if m.showSnapDiff {
+		return m.centerOverlay(m.renderSnapDiffModal())
+	}
+
+	if m.showRemoveConfirm {
+		return m.centerOverlay(m.renderRemoveConfirmModal())
+	}
+
+	if m.showCheck {
+		return m.centerOverlay(m.renderCheckModal())
+	}
+
 	if m.showApplyModal {
 		return m.renderWithModal(b.String())
 	}
@@ -54,6 +134,10 @@ func (m Model) View() string {
 		return m.renderWithAgentModal(b.String())
 	}
 
+	if m.showPluginModal {
+		return m.centerOverlay(m.renderPluginModal())
+	}
+
 	return b.String()
 }
 
@@ -61,9 +145,29 @@ func (m Model) View() string {
 func (m Model) renderTitleBar() string {
 	s := styles.TitleBar
 	title := "Permission Analyzer"
+	if m.demo {
+		title += " (demo)"
+	} else if archive := parser.Archive(); archive != "" {
+		title += " (read-only: " + filepath.Base(archive) + ")"
+	} else if m.readOnly {
+		title += " (read-only)"
+	}
+	if m.dryRun {
+		title += " (dry run)"
+	}
+	if host := parser.HostFilter(); host != "" {
+		title += " (host: " + host + ")"
+	}
+	if mode := m.projectMode(m.projectPath); mode != "" {
+		title += " (mode: " + mode + ")"
+	}
+	if crumbs := m.breadcrumb(); len(crumbs) > 0 {
+		title += "  " + strings.Join(crumbs, " › ")
+	}
+	title = truncateString(title, m.width-2)
 
 	// Fill to width
-	padding := m.width - len(title) - 2
+	padding := m.width - lipgloss.Width(title) - 2
 	if padding < 0 {
 		padding = 0
 	}
@@ -71,30 +175,70 @@ func (m Model) renderTitleBar() string {
 	return s.Render(title + strings.Repeat(" ", padding))
 }
 
+// projectMode returns the permission mode a project's sessions start in,
+// or "" for the default mode
+func (m Model) projectMode(project string) string {
+	if mode, ok := m.permissionModes[project]; ok && mode.Mode != parser.ModeDefault {
+		return mode.Mode
+	}
+	return ""
+}
+
 // renderTabBar renders the tab navigation
 func (m Model) renderTabBar() string {
-	tabs := []string{"Frequency", "Matrix", "Help"}
-	var parts []string
+	parts := make([]string, viewCount)
+	for i := range parts {
+		parts[i] = m.renderTab(ViewType(i))
+	}
+	return truncateString(strings.Join(parts, " "), m.width)
+}
 
-	for i, tab := range tabs {
-		var style lipgloss.Style
-		if ViewType(i) == m.activeView {
-			style = styles.TabActive
-		} else {
-			style = styles.TabInactive
-		}
-		parts = append(parts, style.Render("["+tab+"]"))
+// renderTab renders a single tab label
+func (m Model) renderTab(v ViewType) string {
+	style := styles.TabInactive
+	if v == m.activeView {
+		style = styles.TabActive
+	}
+	pad, brackets := m.tabLayout()
+	label := m.tabLabel(v)
+	if brackets {
+		label = "[" + label + "]"
 	}
+	return style.Padding(0, pad).Render(label)
+}
 
-	return strings.Join(parts, " ")
+// tabLabel returns a tab's text, with the warning count on Diagnostics
+func (m Model) tabLabel(v ViewType) string {
+	label := viewNames[v]
+	if v == ViewDiagnostics && len(m.warnings) > 0 {
+		label += fmt.Sprintf(" (%d)", len(m.warnings)+m.warningsDropped)
+	}
+	return label
+}
+
+// tabLayout returns the horizontal padding around each tab and whether
+// labels are bracketed, narrowed so the tab bar fits the terminal width when
+// it can. Without brackets the active tab is told apart by its style alone.
+func (m Model) tabLayout() (pad int, brackets bool) {
+	labels := 0
+	for i := 0; i < viewCount; i++ {
+		labels += lipgloss.Width(m.tabLabel(ViewType(i)))
+	}
+	labels += viewCount - 1 // separators
+	for pad := 2; pad >= 0; pad-- {
+		if labels+2*viewCount+2*pad*viewCount <= m.width {
+			return pad, true
+		}
+	}
+	return 0, false
 }
 
 // renderDiffPreview renders a colored diff preview for the modal
 func renderDiffPreview(filePath string, diffLines []parser.DiffLine, allExist bool, maxWidth int) string {
-	addedStyle := lipgloss.NewStyle().Foreground(ColorSuccess)
-	removedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("9")) // Red
-	lineNumStyle := lipgloss.NewStyle().Foreground(ColorMuted)
-	pathStyle := lipgloss.NewStyle().Foreground(ColorSecondary).Italic(true)
+	addedStyle := styles.DiffAdded
+	removedStyle := styles.DiffRemoved
+	lineNumStyle := styles.DiffLineNum
+	pathStyle := styles.DiffPath
 
 	var b strings.Builder
 
@@ -125,9 +269,8 @@ func renderDiffPreview(filePath string, diffLines []parser.DiffLine, allExist bo
 
 		text := dl.Text
 		// Truncate long lines
-		maxText := contentWidth - len(prefix) - 2
-		if maxText > 0 && len(text) > maxText {
-			text = text[:maxText-1] + "…"
+		if maxText := contentWidth - len(prefix) - 2; maxText > 0 {
+			text = truncateString(text, maxText)
 		}
 
 		lineNum := lineNumStyle.Render(prefix)
@@ -147,8 +290,8 @@ func renderDiffPreview(filePath string, diffLines []parser.DiffLine, allExist bo
 }
 
 func renderDiffPreviewError(filePath string, err error) string {
-	pathStyle := lipgloss.NewStyle().Foreground(ColorSecondary).Italic(true)
-	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	pathStyle := styles.DiffPath
+	errorStyle := styles.Error
 
 	var b strings.Builder
 	b.WriteString(pathStyle.Render(filePath))
@@ -158,54 +301,104 @@ func renderDiffPreviewError(filePath string, err error) string {
 	return b.String()
 }
 
-// renderToast renders a success toast notification in place of the status bar
-func (m Model) renderToast() string {
-	toastStyle := lipgloss.NewStyle().
-		Foreground(ColorSuccess).
-		Bold(true).
-		Padding(0, 1)
-
-	msg := "Applied: " + m.toastMessage
-	padding := m.width - len(msg) - 2
-	if padding < 0 {
-		padding = 0
-	}
-
-	return toastStyle.Render(msg + strings.Repeat(" ", padding))
-}
-
 // renderStatusBar renders the bottom status bar
 func (m Model) renderStatusBar() string {
 	var left, right string
 
 	if m.filtering {
 		left = "Filter: " + m.filterInput.View()
+	} else if m.editingNote {
+		left = "Note for " + truncateString(m.noteTarget, 30) + ": " + m.noteInput.View()
 	} else {
 		switch m.activeView {
 		case ViewFrequency:
 			perms := m.visiblePermissions()
-			if len(perms) > 0 {
+			if m.sinceReview {
+				left = fmt.Sprintf("%d changed since the review %s", m.listedPermissions(), formatRelativeTime(m.state.Review.At))
+			} else if len(perms) > 0 {
 				left = fmt.Sprintf("%d/%d permissions", m.cursor+1, len(perms))
 			} else {
 				left = "No permissions found"
 			}
+			if m.freqSort != sortByUses {
+				left += "  sorted by " + freqSortNames[m.freqSort]
+			}
+			if m.withSubagents {
+				left += "  incl. subagents"
+			}
+			if m.quickFiltering() {
+				left += "  only " + m.quickFilterText()
+			}
+			if n := len(m.staged); n > 0 {
+				left += fmt.Sprintf("  %d staged", n)
+			}
 		case ViewMatrix:
-			if len(m.agentUsage) > 0 {
-				left = fmt.Sprintf("%d/%d agents", m.matrixCursor+1, len(m.agentUsage))
+			if m.matrixByPlugin {
+				left = fmt.Sprintf("%d/%d plugins", min(m.matrixList.cursor+1, len(m.plugins)), len(m.plugins))
+			} else if len(m.agentUsage) > 0 {
+				left = fmt.Sprintf("%d/%d agents", m.matrixList.cursor+1, len(m.agentUsage))
 			} else if len(m.agents) > 0 {
-				left = fmt.Sprintf("%d/%d agents", m.matrixCursor+1, len(m.agents))
+				left = fmt.Sprintf("%d/%d agents", m.matrixList.cursor+1, len(m.agents))
 			} else {
 				left = "No agents found"
 			}
+		case ViewDiagnostics:
+			if len(m.warnings) > 0 {
+				left = fmt.Sprintf("%d/%d warnings", m.diagList.cursor+1, len(m.warnings))
+			} else {
+				left = "No warnings"
+			}
+		case ViewSnapshots:
+			if len(m.snapshots) > 0 {
+				left = fmt.Sprintf("%d/%d snapshots", m.snapList.cursor+1, len(m.snapshots))
+			} else {
+				left = "No snapshots"
+			}
+		case ViewHistory:
+			if len(m.history) > 0 {
+				left = fmt.Sprintf("%d/%d changes", m.histList.cursor+1, len(m.history))
+			} else {
+				left = "No changes recorded"
+			}
+		case ViewStale:
+			if len(m.staleAllows) > 0 {
+				left = fmt.Sprintf("%d/%d stale rules", m.staleList.cursor+1, len(m.staleAllows))
+			} else {
+				left = "No stale rules"
+			}
+		case ViewReconcile:
+			if len(m.reconciled) > 0 {
+				left = fmt.Sprintf("%d/%d rules", m.reconList.cursor+1, len(m.reconciled))
+			} else {
+				left = "No rules"
+			}
+		case ViewPolicy:
+			if len(m.violations) > 0 {
+				left = fmt.Sprintf("%d/%d violations", m.policyList.cursor+1, len(m.violations))
+			} else {
+				left = "No violations"
+			}
 		case ViewHelp:
 			left = "Help"
 		}
+		if badge := m.warningBadge(); badge != "" && m.activeView != ViewDiagnostics {
+			left += "  " + badge
+		}
+		if keys := m.pendingKeysText(); keys != "" {
+			left += "  " + keys
+		}
 	}
 
-	right = "j/k: nav  Enter: details  Tab: view  /: filter  q: quit"
+	right = hintsText(m.contextBindings())
+
+	// Drop hints that don't fit rather than wrapping the status bar
+	leftWidth := lipgloss.Width(left)
+	if avail := m.width - leftWidth - 3; lipgloss.Width(right) > avail {
+		right = truncateString(right, avail)
+	}
 
 	// Calculate spacing
-	spacing := m.width - len(left) - len(right) - 2
+	spacing := m.width - leftWidth - lipgloss.Width(right) - 2
 	if spacing < 1 {
 		spacing = 1
 	}
@@ -213,32 +406,23 @@ func (m Model) renderStatusBar() string {
 	return styles.StatusBar.Render(left + strings.Repeat(" ", spacing) + right)
 }
 
-// renderHelpView renders the help view
+// renderHelpView renders the help view, generated from the active key bindings
 func (m Model) renderHelpView() string {
 	_, contentHeight := m.calculateLayout()
 
-	help := []struct{ key, desc string }{
-		{"j/k, ↓/↑", "Navigate list"},
-		{"g/G", "Go to first/last"},
-		{"Enter", "Open apply modal"},
-		{"Tab", "Switch views"},
-		{"/", "Filter permissions"},
-		{"Esc", "Clear filter"},
-		{"q", "Quit"},
-		{"", ""},
-		{"In modal:", ""},
-		{"u", "Copy user-level command"},
-		{"p", "Copy project-level command"},
-		{"Esc", "Close modal"},
-	}
-
 	var lines []string
-	for _, h := range help {
-		if h.key == "" {
+	for i, section := range m.keys.helpSections() {
+		if i > 0 {
 			lines = append(lines, "")
-		} else {
-			key := styles.HelpKey.Render(fmt.Sprintf("%12s", h.key))
-			desc := styles.HelpDesc.Render("  " + h.desc)
+		}
+		lines = append(lines, styles.ListHeader.Render(section.title))
+		for _, b := range section.bindings {
+			if !b.Enabled() {
+				continue
+			}
+			h := b.Help()
+			key := styles.HelpKey.Render(padLeft(h.Key, 12))
+			desc := styles.HelpDesc.Render("  " + h.Desc)
 			lines = append(lines, key+desc)
 		}
 	}
@@ -254,87 +438,141 @@ func (m Model) renderHelpView() string {
 // renderWithModal overlays the modal on top of the main content
 func (m Model) renderWithModal(background string) string {
 	// Instead of overlay, just center the modal in available space
-	modal := m.renderApplyModal()
+	return m.centerOverlay(m.renderApplyModal())
+}
 
+// centerOverlay vertically centers a rendered modal below the title bar,
+// which stays visible so the breadcrumb shows where the modal was opened
+func (m Model) centerOverlay(modal string) string {
 	// Calculate centering
 	modalLines := strings.Split(modal, "\n")
 	modalHeight := len(modalLines)
 
-	// Pad vertically to center
-	topPadding := (m.height - modalHeight) / 2
+	// A modal taller than the space under the title bar loses its last
+	// lines but keeps its bottom border
+	if room := m.height - 1; modalHeight > room && room > 1 {
+		modalLines = append(modalLines[:room-1], modalLines[modalHeight-1])
+		modal = strings.Join(modalLines, "\n")
+		modalHeight = room
+	}
+
+	// Pad vertically to center in the space under the title bar
+	topPadding := (m.height - 1 - modalHeight) / 2
 	if topPadding < 0 {
 		topPadding = 0
 	}
 
 	var result strings.Builder
+	result.WriteString(m.renderTitleBar())
+	result.WriteString("\n")
 	for i := 0; i < topPadding; i++ {
 		result.WriteString("\n")
 	}
 	result.WriteString(modal)
 
-	return result.String()
+	// The toast showing stays in view at the bottom
+	t := m.currentToast()
+	if t == nil || m.height < 2 {
+		return result.String()
+	}
+	lines := strings.Split(result.String(), "\n")
+	for len(lines) < m.height {
+		lines = append(lines, "")
+	}
+	lines = lines[:m.height]
+	if m.toastWraps(*t) {
+		return strings.TrimSuffix(m.overlayToastPanel(strings.Join(lines, "\n"), *t), "\n")
+	}
+	lines[m.height-1] = m.renderToast(*t)
+	return strings.Join(lines, "\n")
 }
 
-// truncateString truncates a string to maxLen characters
+// truncateString truncates a string to maxLen terminal cells, so wide
+// characters (CJK, emoji) count double and ANSI styling is preserved
 // Following Golden Rule #2: Never auto-wrap in bordered panels
 func truncateString(s string, maxLen int) string {
 	if maxLen < 1 {
 		return ""
 	}
-	if len(s) <= maxLen {
+	if ansi.StringWidth(s) <= maxLen {
 		return s
 	}
 	if maxLen < 2 {
-		return s[:maxLen]
+		return ansi.Truncate(s, maxLen, "")
 	}
-	return s[:maxLen-1] + "…"
+	return ansi.Truncate(s, maxLen, "…")
 }
 
-// padRight pads a string to the specified width
+// padRight pads a string to the specified display width
 func padRight(s string, width int) string {
-	if len(s) >= width {
+	w := ansi.StringWidth(s)
+	if w >= width {
 		return s
 	}
-	return s + strings.Repeat(" ", width-len(s))
+	return s + strings.Repeat(" ", width-w)
 }
 
-// padLeft pads a string on the left to the specified width
+// padLeft pads a string on the left to the specified display width
 func padLeft(s string, width int) string {
-	if len(s) >= width {
+	w := ansi.StringWidth(s)
+	if w >= width {
 		return s
 	}
-	return strings.Repeat(" ", width-len(s)) + s
+	return strings.Repeat(" ", width-w) + s
 }
 
 // renderLoadingScreen renders a centered loading indicator with streaming status
 func (m Model) renderLoadingScreen() string {
-	titleStyle := lipgloss.NewStyle().
-		Foreground(ColorPrimary).
-		Bold(true)
-
-	statusStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("244")). // Gray
-		Italic(true)
+	titleStyle := styles.LoadingTitle
+	statusStyle := styles.LoadingStatus
+	sessionStyle := styles.LoadingSession
 
-	sessionStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("240")). // Darker gray
-		Italic(true)
+	title := m.spinner.View() + " " + titleStyle.Render("Loading Permission History...")
 
-	title := titleStyle.Render("Loading Permission History...")
-
-	// Build content with project path and session ID
+	// Build content: stage with counts, progress bar, project path and session ID
 	var content string
 	content = title
 
-	if m.loadingStatus != "" {
-		content += "\n" + statusStyle.Render(m.loadingStatus)
+	p := m.loading
+	if p.Stage != "" {
+		stage := p.Stage
+		if p.Total > 0 {
+			stage += fmt.Sprintf("  %d/%d", p.Done, p.Total)
+		}
+		content += "\n\n" + statusStyle.Render(stage)
+	}
+
+	if p.Total > 0 {
+		content += "\n" + renderProgressBar(p.Done, p.Total, 40)
 	}
 
-	if m.loadingSession != "" {
-		content += "\n" + sessionStyle.Render(m.loadingSession)
+	if p.Project != "" {
+		content += "\n" + statusStyle.Render(truncateString(p.Project, m.width-4))
+	}
+
+	if p.Session != "" {
+		content += "\n" + sessionStyle.Render(p.Session)
+	}
+
+	if !m.loadingStarted.IsZero() {
+		elapsed := now().Sub(m.loadingStarted).Round(time.Second)
+		content += "\n\n" + sessionStyle.Render(fmt.Sprintf("%s elapsed  •  %s to quit", elapsed, primaryKey(m.keys.Quit)))
 	}
 
 	return lipgloss.Place(m.width, m.height,
 		lipgloss.Center, lipgloss.Center,
 		content)
 }
+
+// renderProgressBar draws a fixed-width text progress bar
+func renderProgressBar(done, total, width int) string {
+	if total <= 0 || width <= 0 {
+		return ""
+	}
+	filled := done * width / total
+	if filled > width {
+		filled = width
+	}
+	return styles.StatusApproved.Render(strings.Repeat("█", filled)) +
+		styles.StatusPending.Render(strings.Repeat("░", width-filled))
+}
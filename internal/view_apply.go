@@ -48,11 +48,16 @@ func (m Model) renderOptionSelect() string {
 
 	var b strings.Builder
 
+	b.WriteString(m.renderWriteModeNotice())
+
 	options := []string{"Apply to User (all projects)", "Apply to Project..."}
 	for i, opt := range options {
-		if i == m.applyOptionCursor {
+		switch {
+		case i == m.applyOptionCursor:
 			b.WriteString(styles.ListItemSelected.Render("> " + opt))
-		} else {
+		case m.writeBlockedReason() != "":
+			b.WriteString(styles.StatusPending.Render("  " + opt))
+		default:
 			b.WriteString(styles.ListItem.Render("  " + opt))
 		}
 		b.WriteString("\n")
@@ -69,66 +74,106 @@ func (m Model) renderOptionSelect() string {
 			} else {
 				b.WriteString(renderDiffPreview(filePath, diffLines, allExist, 74))
 			}
+			b.WriteString(m.renderCoverage([]string{perm.Permission.Raw}, ""))
 		}
 		// Project level shows after project selection, so no preview here
 	}
 
-	b.WriteString(fmt.Sprintf("\n%s nav  %s confirm  %s cancel",
-		styles.HelpKey.Render("j/k"),
-		styles.HelpKey.Render("Enter"),
-		styles.HelpKey.Render("Esc")))
+	b.WriteString("\n" + renderHints(m.contextBindings()))
 
 	return b.String()
 }
 
+// projectListHeight is how many projects the apply modal lists at a time
+const projectListHeight = 6
+
 func (m Model) renderProjectSelect(perm *types.PermissionStats) string {
 	var b strings.Builder
+	b.WriteString(m.renderWriteModeNotice())
 	b.WriteString("  Select project:\n\n")
 
-	maxVisible := 6
-	start := 0
-	if m.projectListCursor >= maxVisible {
-		start = m.projectListCursor - maxVisible + 1
-	}
-	end := start + maxVisible
-	if end > len(perm.Projects) {
-		end = len(perm.Projects)
-	}
+	projects := m.visibleProjects(perm.Projects)
+	b.WriteString(m.renderProjectFilter(len(projects), len(perm.Projects)))
 
+	start, end := m.projectList.window(len(projects), projectListHeight)
 	for i := start; i < end; i++ {
-		display := shortenPath(perm.Projects[i])
-		if i == m.projectListCursor {
-			b.WriteString(styles.ListItemSelected.Render("> " + display))
-		} else {
-			b.WriteString(styles.ListItem.Render("  " + display))
-		}
+		b.WriteString(m.renderProjectOption(projects[i], shortenPath(projects[i]),
+			[]string{perm.Permission.Raw}, i == m.projectList.cursor))
 		b.WriteString("\n")
 	}
 
-	if len(perm.Projects) > maxVisible {
-		b.WriteString(fmt.Sprintf("\n  (%d/%d)\n", m.projectListCursor+1, len(perm.Projects)))
+	if len(projects) > projectListHeight {
+		b.WriteString(fmt.Sprintf("\n  (%d/%d)\n", m.projectList.cursor+1, len(projects)))
 	}
 
 	// Show diff preview for the selected project
-	if m.projectListCursor < len(perm.Projects) {
+	if m.projectList.cursor < len(projects) {
 		b.WriteString("\n")
-		projectPath := perm.Projects[m.projectListCursor]
+		projectPath := projects[m.projectList.cursor]
 		filePath, diffLines, allExist, err := parser.PreviewProjectDiff(projectPath, []string{perm.Permission.Raw})
 		if err != nil {
 			b.WriteString(renderDiffPreviewError(filePath, err))
 		} else {
 			b.WriteString(renderDiffPreview(filePath, diffLines, allExist, 74))
 		}
+		b.WriteString(m.renderCoverage([]string{perm.Permission.Raw}, projectPath))
 	}
 
-	b.WriteString(fmt.Sprintf("\n%s nav  %s apply  %s back",
-		styles.HelpKey.Render("j/k"),
-		styles.HelpKey.Render("Enter"),
-		styles.HelpKey.Render("Esc")))
+	b.WriteString("\n" + renderHints(m.contextBindings()))
 
 	return b.String()
 }
 
+// renderProjectOption renders one entry of a project list, greyed out and
+// marked when the project's settings already allow all of perms
+func (m Model) renderProjectOption(project, display string, perms []string, selected bool) string {
+	allowed := m.projectAllowsAll(project, perms)
+	if allowed {
+		display += "  ✓ already allowed"
+	}
+	switch {
+	case selected:
+		return styles.ListItemSelected.Render("> " + display)
+	case allowed, m.writeBlockedReason() != "":
+		return styles.StatusPending.Render("  " + display)
+	default:
+		return styles.ListItem.Render("  " + display)
+	}
+}
+
+// renderWriteModeNotice explains why the apply actions that follow are
+// disabled, or that they only report in dry-run mode
+func (m Model) renderWriteModeNotice() string {
+	if m.dryRun {
+		return styles.StatusPending.Render("  Dry run: nothing is written; the exit summary lists what would change") + "\n\n"
+	}
+	reason := m.writeBlockedReason()
+	if reason == "" {
+		return ""
+	}
+	return styles.Error.Render("  "+reason) + "\n\n"
+}
+
+// renderCoverage reports what adding rules would have allowed, replayed
+// against the loaded history; project limits it to one project's calls
+func (m Model) renderCoverage(rules []string, project string) string {
+	c := parser.SimulateAllow(rules, m.permissions, project)
+	if c.Calls == 0 {
+		return styles.StatusPending.Render("  Would not have covered any recorded calls") + "\n"
+	}
+
+	text := fmt.Sprintf("  Would have covered %d calls", c.Calls)
+	if project == "" {
+		text += fmt.Sprintf(" across %d project(s)", c.Projects)
+	}
+	if c.Prompts > 0 {
+		text += fmt.Sprintf(", skipping %d prompts", c.Prompts)
+	} else {
+		text += ", all already allowed"
+	}
+	return styles.StatusApproved.Render(text) + "\n"
+}
+
 func shortenPath(path string) string {
 	parts := strings.Split(path, "/")
 	if len(parts) <= 2 {
@@ -0,0 +1,239 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/types"
+)
+
+// detailListLimit caps each breakdown list in the detail modal
+const detailListLimit = 5
+
+// countRow is a label with a usage count, for the breakdown lists
+type countRow struct {
+	label string
+	count int
+}
+
+// sortedCounts orders a count map by count descending, then label
+func sortedCounts(counts map[string]int) []countRow {
+	rows := make([]countRow, 0, len(counts))
+	for label, n := range counts {
+		rows = append(rows, countRow{label, n})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].count != rows[j].count {
+			return rows[i].count > rows[j].count
+		}
+		return rows[i].label < rows[j].label
+	})
+	return rows
+}
+
+// agentCounts returns how often each agent type used a permission
+func (m Model) agentCounts(raw string) map[string]int {
+	counts := make(map[string]int)
+	for _, agent := range m.agentUsage {
+		for _, p := range agent.Permissions {
+			if p.Permission.Raw == raw {
+				counts[agent.AgentType] += p.Count
+			}
+		}
+	}
+	return counts
+}
+
+// formatTimestamp formats an absolute time with its relative age
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return t.Local().Format("2006-01-02 15:04") + "  (" + formatRelativeTime(t) + ")"
+}
+
+// renderDetailModal renders the full metadata for the selected permission
+func (m Model) renderDetailModal() string {
+	perm := m.selectedPermission()
+	if perm == nil {
+		return ""
+	}
+
+	modalWidth := m.width * 85 / 100
+	if modalWidth > 80 {
+		modalWidth = 80
+	}
+	if modalWidth < 50 {
+		modalWidth = 50
+	}
+	inner := modalWidth - 8 // border + padding + indent
+
+	var b strings.Builder
+	b.WriteString(styles.ModalTitle.Render("Permission Details"))
+	b.WriteString("\n\n")
+	b.WriteString("  " + styles.HelpKey.Render(perm.Permission.Raw) + "\n\n")
+
+	field := func(label, value string) {
+		b.WriteString("  " + styles.HelpDesc.Render(fmt.Sprintf("%-11s", label)) + value + "\n")
+	}
+	field("First seen", formatTimestamp(perm.FirstSeen))
+	field("Last seen", formatTimestamp(perm.LastSeen))
+	uses := fmt.Sprintf("%d  (%s approved, %s denied", perm.Count,
+		styles.StatusApproved.Render(fmt.Sprint(perm.Approved)),
+		styles.Error.Render(fmt.Sprint(perm.Denied)))
+	if perm.AutoApproved > 0 {
+		uses += fmt.Sprintf(", %d run without a prompt by the permission mode", perm.AutoApproved)
+	}
+	field("Uses", uses+")")
+	if rateText, rateStyle := formatApprovalRate(perm.ApprovalRate()); rateText != "-" {
+		field("Approval", rateStyle.Render(rateText))
+	}
+	if perm.Outside > 0 {
+		dirs := make([]string, len(perm.OutsideDirs))
+		for i, d := range perm.OutsideDirs {
+			dirs[i] = shortenPath(d)
+		}
+		text := fmt.Sprintf("%d of %d calls ran outside the project, in %s", perm.Outside, perm.Count, strings.Join(dirs, ", "))
+		if ranOutside(*perm) {
+			text = styles.StatusWarning.Render(outsideMarker + " " + text)
+		}
+		field("Outside", truncateString(text, inner-11))
+	}
+	if perm.Tokens > 0 {
+		field("Tokens", fmt.Sprintf("%s  (%s per use)", formatTokens(perm.Tokens), formatTokens(perm.Tokens/max(perm.Count, 1))))
+	}
+	if made := attributionText(*perm); made != "" {
+		field("Made by", made)
+	}
+	b.WriteString(m.renderNoteField(false, perm.Permission.Raw, inner))
+
+	// section renders a breakdown list; cursor marks the selectable row (-1 for none)
+	section := func(title string, rows []countRow, cursor int) {
+		b.WriteString("\n  " + styles.ListHeader.UnsetPaddingLeft().Render(title) + "\n")
+		if len(rows) == 0 {
+			b.WriteString(styles.StatusPending.Render("    none") + "\n")
+			return
+		}
+
+		// Keep the cursor row inside the visible window
+		start := 0
+		if cursor >= detailListLimit {
+			start = cursor - detailListLimit + 1
+		}
+		for i := start; i < len(rows); i++ {
+			if i == start+detailListLimit {
+				b.WriteString(styles.StatusPending.Render(fmt.Sprintf("    +%d more", len(rows)-i)) + "\n")
+				break
+			}
+			label := truncateString(rows[i].label, inner-8)
+			row := fmt.Sprintf("%s%6d", padRight(label, inner-8), rows[i].count)
+			if i == cursor {
+				b.WriteString("  " + styles.ListItemSelected.Render("> "+row) + "\n")
+			} else {
+				b.WriteString("    " + row + "\n")
+			}
+		}
+	}
+	// Raw forms merged into this permission by scope normalization
+	if v := perm.Variants; len(v) > 0 && v[perm.Permission.Raw] != perm.Count {
+		section("Logged as", sortedCounts(v), -1)
+	}
+	// A project's mode decides whether its rules are consulted at all
+	projects := sortedCounts(perm.ProjectCounts)
+	for i, row := range projects {
+		if mode := m.projectMode(row.label); mode != "" {
+			projects[i].label += "  (" + mode + ")"
+		}
+	}
+	section("Projects", projects, -1)
+	if len(perm.HostCounts) > 0 {
+		section("Hosts", sortedCounts(perm.HostCounts), -1)
+	}
+	section("Agents", m.detailAgents(perm), m.detailCursor)
+
+	// A rule for a tool without scopes allows any input, so show what it
+	// was used for rather than a few examples
+	if len(perm.Inputs) > 0 {
+		title, noun := "Top patterns", "pattern"
+		if perm.Permission.Type == "WebSearch" {
+			title, noun = "Top queries", "query"
+		}
+		section(title, sortedCounts(perm.Inputs), -1)
+		b.WriteString(styles.HelpDesc.Render(truncateString(fmt.Sprintf("    A %s rule allows any %s, not just these", perm.Permission.Type, noun), inner)) + "\n")
+	} else {
+		b.WriteString("\n  " + styles.ListHeader.UnsetPaddingLeft().Render("Sample inputs") + "\n")
+		if len(perm.Samples) == 0 {
+			b.WriteString(styles.StatusPending.Render("    none recorded") + "\n")
+		}
+		for _, s := range perm.Samples {
+			b.WriteString("    " + truncateString(s, inner) + "\n")
+		}
+	}
+
+	if len(perm.Slowest) > 0 {
+		b.WriteString("\n  " + styles.ListHeader.UnsetPaddingLeft().Render("Slowest calls") + "\n")
+		b.WriteString(renderSlowCalls(perm.Slowest, false, inner))
+	}
+
+	b.WriteString("\n  " + styles.ListHeader.UnsetPaddingLeft().Render("Approval sources") + "\n")
+	b.WriteString(m.renderApprovalSources(perm, inner))
+
+	b.WriteString("\n" + renderHints(m.contextBindings()))
+
+	return styles.Modal.Width(modalWidth).Render(b.String())
+}
+
+// renderSlowCalls lists the slowest calls, longest first, each with its
+// duration and input, and its permission with withPermission
+func renderSlowCalls(calls []types.SlowCall, withPermission bool, width int) string {
+	var b strings.Builder
+	for _, c := range calls {
+		what := c.Sample
+		if withPermission {
+			what = c.Permission + "  " + styles.HelpDesc.Render(c.Sample)
+		}
+		b.WriteString(truncateString(fmt.Sprintf("    %7s  %s", formatDuration(c.Duration), what), width) + "\n")
+	}
+	return b.String()
+}
+
+// renderApprovalSources lists the settings files and rules that allow perm
+func (m Model) renderApprovalSources(perm *types.PermissionStats, width int) string {
+	var b strings.Builder
+	type source struct {
+		path     string
+		approved []string
+	}
+	sources := []source{{parser.UserSettingsPath(), m.userApproved}}
+	// The projects the permission was used in, then the working directory
+	projects := append(append([]string(nil), perm.Projects...), m.projectPath)
+	seen := make(map[string]bool)
+	for _, p := range projects {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		sources = append(sources, source{parser.ProjectSettingsPath(p), m.projectSettings[p]})
+	}
+
+	found := false
+	for _, src := range sources {
+		rules := parser.MatchingRules(perm.Permission.Raw, src.approved)
+		if len(rules) == 0 {
+			continue
+		}
+		found = true
+		b.WriteString("    " + styles.DiffPath.Render(truncateString(src.path, width)) + "\n")
+		for _, r := range rules {
+			b.WriteString("      " + styles.StatusApproved.Render("✓ "+r) + "\n")
+		}
+	}
+
+	if !found {
+		b.WriteString(styles.StatusPending.Render("    not approved in user or project settings") + "\n")
+	}
+	return b.String()
+}
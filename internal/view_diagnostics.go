@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diagViewportHeight returns how many warning rows fit under the header
+func (m Model) diagViewportHeight() int {
+	_, contentHeight := m.calculateLayout()
+	h := contentHeight - 2 // header + separator
+	if h < 1 {
+		h = 1
+	}
+	return h
+}
+
+// warningBadge returns the status bar badge for load warnings, or "" if none
+func (m Model) warningBadge() string {
+	n := len(m.warnings) + m.warningsDropped
+	switch n {
+	case 0:
+		return ""
+	case 1:
+		return "⚠ 1 warning"
+	default:
+		return fmt.Sprintf("⚠ %d warnings", n)
+	}
+}
+
+// renderDiagnosticsView lists the non-fatal problems found while loading
+func (m Model) renderDiagnosticsView() string {
+	_, contentHeight := m.calculateLayout()
+
+	var lines []string
+
+	header := "No problems found while loading"
+	if len(m.warnings) > 0 {
+		header = fmt.Sprintf("%d files or lines were skipped while loading; stats may be incomplete", len(m.warnings)+m.warningsDropped)
+	}
+	lines = append(lines, padRight(truncateString(header, m.width-4), m.width-4))
+	lines = append(lines, strings.Repeat("─", m.width-4))
+
+	start, endIdx := m.diagList.window(len(m.warnings), m.diagViewportHeight())
+	for i := start; i < endIdx; i++ {
+		text := truncateString(m.warnings[i].String(), m.width-6)
+		if i == m.diagList.cursor {
+			lines = append(lines, styles.ListItemSelected.Render("> "+text))
+		} else {
+			lines = append(lines, styles.ListItem.Render(styles.Error.Render(text)))
+		}
+	}
+
+	if m.warningsDropped > 0 && endIdx == len(m.warnings) {
+		lines = append(lines, styles.ListItem.Render(fmt.Sprintf("... %d more not shown", m.warningsDropped)))
+	}
+
+	// Pad to exact content height
+	for len(lines) < contentHeight {
+		lines = append(lines, "")
+	}
+
+	return strings.Join(lines[:contentHeight], "\n") + "\n"
+}
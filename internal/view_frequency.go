@@ -2,28 +2,48 @@ package internal
 
 import (
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
 	"github.com/b-open-io/claude-perms/internal/types"
+	"github.com/charmbracelet/lipgloss"
 )
 
-// calculateFreqColumns returns responsive column widths for the frequency view.
+// calculateFreqColumns returns responsive widths for the frequency view's
+// columns, 0 for those not shown, and the width of the permission column.
 // Uses weight-based sizing so the permission name column fills available space.
-// Returns: allowWidth, denyWidth, permWidth, lastWidth, statusWidth
-func (m Model) calculateFreqColumns() (allowWidth, denyWidth, permWidth, lastWidth, statusWidth int) {
-	const cursorWidth = 2  // "> " or "  "
-	const columnGaps = 8   // 2-space gap between each of the 5 columns (4 gaps * 2)
-	const contentPad = 4   // Content area padding
+func (m Model) calculateFreqColumns() (widths [freqColumnCount]int, permWidth int) {
+	const cursorWidth = 2 // "> " or "  "
+	const contentPad = 4  // Content area padding
 
 	// Base column widths
-	allowWidth = 7  // right-aligned number
-	denyWidth = 5   // right-aligned number (typically smaller)
-	lastWidth = 10  // relative time
-	statusWidth = 8 // "✓ user", "○", etc.
-
-	fixedWidth := cursorWidth + allowWidth + denyWidth + lastWidth + statusWidth + columnGaps + contentPad
-	permWidth = m.width - fixedWidth
+	base := [freqColumnCount]int{
+		colAllow:    7,  // right-aligned number
+		colAuto:     4,  // right-aligned number, as wide as its title
+		colDeny:     5,  // right-aligned number (typically smaller)
+		colRate:     5,  // approval rate, "100%"
+		colProjects: 8,  // project count, as wide as its title
+		colTokens:   6,  // token count, "12.3M"
+		colFirst:    10, // relative time
+		colLast:     10, // relative time
+		colStatus:   8,  // "✓ user", "○", etc.
+	}
+	for c, on := range m.freqColumns {
+		if on {
+			widths[c] = base[c]
+		}
+	}
+	fixedWidth := func() int {
+		w := cursorWidth + contentPad
+		for _, width := range widths {
+			if width > 0 {
+				w += width + 2 // 2-space gap to the next column
+			}
+		}
+		return w
+	}
+	permWidth = m.width - fixedWidth()
 
 	// On wide terminals, give data columns more room
 	extra := permWidth - 45
@@ -32,40 +52,80 @@ func (m Model) calculateFreqColumns() (allowWidth, denyWidth, permWidth, lastWid
 		if bonus > 4 {
 			bonus = 4
 		}
-		allowWidth += bonus
-		lastWidth += bonus
-		statusWidth += bonus
-		fixedWidth = cursorWidth + allowWidth + denyWidth + lastWidth + statusWidth + columnGaps + contentPad
-		permWidth = m.width - fixedWidth
+		for _, c := range []freqColumn{colAllow, colLast, colStatus} {
+			if widths[c] > 0 {
+				widths[c] += bonus
+			}
+		}
+		permWidth = m.width - fixedWidth()
 	}
 
+	// On narrow terminals, narrow the time columns down to "just now"
+	// before the permission column goes below its minimum
+	for permWidth < 20 && (widths[colFirst] > 8 || widths[colLast] > 8) {
+		if widths[colFirst] >= widths[colLast] {
+			widths[colFirst]--
+		} else {
+			widths[colLast]--
+		}
+		permWidth++
+	}
+	// Then drop the Auto column, whose count the details show too
+	if permWidth < 20 && widths[colAuto] > 0 {
+		permWidth += widths[colAuto] + 2
+		widths[colAuto] = 0
+	}
 	if permWidth < 20 {
 		permWidth = 20
 	}
 
-	return allowWidth, denyWidth, permWidth, lastWidth, statusWidth
+	return widths, permWidth
 }
 
-// freqVisualLine returns the visual line index (0-based) of the current cursor position
-// within the flattened list of groups + expanded children.
-func (m Model) freqVisualLine() int {
-	line := 0
+// freqRow returns the group shown at line of the flattened list of groups
+// and expanded groups' permissions, and which of its permissions, or -1
+// for the group's own row. Past the end the group is
+// len(m.permissionGroups).
+func (m Model) freqRow(line int) (gi, ci int) {
 	for gi, group := range m.permissionGroups {
-		if gi == m.groupCursor && m.childCursor == -1 {
-			return line
+		if line == 0 {
+			return gi, -1
 		}
-		line++ // group header
+		line-- // group header
 
 		if group.Expanded {
-			for ci := range group.Children {
-				if gi == m.groupCursor && ci == m.childCursor {
-					return line
-				}
-				line++
+			if line < len(group.Children) {
+				return gi, line
 			}
+			line -= len(group.Children)
+		}
+	}
+	return len(m.permissionGroups), -1
+}
+
+// freqCursor returns the group and permission (-1 on the group's row) the
+// Frequency cursor is on
+func (m Model) freqCursor() (gi, ci int) {
+	return m.freqRow(m.freqList.cursor)
+}
+
+// freqLine returns the line of a group's row (ci == -1), or of one of its
+// permissions, in the flattened list
+func (m Model) freqLine(gi, ci int) int {
+	line := 0
+	for _, group := range m.permissionGroups[:min(gi, len(m.permissionGroups))] {
+		line++ // group header
+		if group.Expanded {
+			line += len(group.Children)
 		}
 	}
-	return line
+	return line + ci + 1
+}
+
+// selectFreqRow moves the Frequency cursor to a group's row (ci == -1), or
+// to one of its permissions, which must be in an expanded group
+func (m *Model) selectFreqRow(gi, ci int) {
+	m.freqList.moveTo(m.freqLine(gi, ci), m.freqTotalLines(), m.freqViewportHeight())
 }
 
 // freqTotalLines returns the total number of visible lines in the frequency list.
@@ -80,18 +140,36 @@ func (m Model) freqTotalLines() int {
 	return total
 }
 
-// renderFrequencyView renders the permission frequency list with viewport scrolling
+// renderFrequencyView renders the permission frequency list, with the
+// staging panel beside it when permissions are staged and there is room
 func (m Model) renderFrequencyView() string {
+	if !m.showStagedPanel() {
+		return m.renderFrequencyList()
+	}
 	_, contentHeight := m.calculateLayout()
+	list := m
+	list.width -= stagedPanelWidth
+	return lipgloss.JoinHorizontal(lipgloss.Top,
+		strings.TrimSuffix(list.renderFrequencyList(), "\n"),
+		m.renderStagedPanel(contentHeight)) + "\n"
+}
 
-	// Reserve 2 lines for header and separator
-	listHeight := contentHeight - 2
-	if listHeight < 1 {
-		listHeight = 1
-	}
+// renderFrequencyList renders the permission frequency list with viewport scrolling
+func (m Model) renderFrequencyList() string {
+	_, contentHeight := m.calculateLayout()
+
+	// Reserve lines for the suggestion banner, header and separator
+	listHeight := m.freqViewportHeight()
 
 	var lines []string
 
+	if banner := m.renderStreakBanner(); banner != "" {
+		lines = append(lines, banner)
+	}
+	if bar := m.renderQuickFilterBar(); bar != "" {
+		lines = append(lines, bar)
+	}
+
 	// Header
 	lines = append(lines, m.renderFrequencyHeader())
 	lines = append(lines, styles.ListHeader.Render(strings.Repeat("─", m.width-4)))
@@ -99,37 +177,21 @@ func (m Model) renderFrequencyView() string {
 	// Build all visible rows, then slice to viewport
 	var allRows []string
 
+	cursorGroup, cursorChild := m.freqCursor()
 	for gi, group := range m.permissionGroups {
-		isGroupSelected := gi == m.groupCursor && m.childCursor == -1
-		expandChar := "▶"
-		if group.Expanded {
-			expandChar = "▼"
-		}
-
-		allRows = append(allRows, m.renderGroupRow(group, expandChar, isGroupSelected))
+		isGroupSelected := gi == cursorGroup && cursorChild == -1
+		allRows = append(allRows, m.renderGroupRow(group, expandMarker(group), isGroupSelected))
 
 		if group.Expanded {
 			for ci, child := range group.Children {
-				isChildSelected := gi == m.groupCursor && ci == m.childCursor
+				isChildSelected := gi == cursorGroup && ci == cursorChild
 				allRows = append(allRows, m.renderChildRow(child, isChildSelected))
 			}
 		}
 	}
 
-	// Apply scroll offset
-	scrollOffset := m.freqScroll
-	if scrollOffset > len(allRows) {
-		scrollOffset = len(allRows)
-	}
-
-	endIdx := scrollOffset + listHeight
-	if endIdx > len(allRows) {
-		endIdx = len(allRows)
-	}
-
-	for i := scrollOffset; i < endIdx; i++ {
-		lines = append(lines, allRows[i])
-	}
+	start, end := m.freqList.window(len(allRows), listHeight)
+	lines = append(lines, allRows[start:end]...)
 
 	// Pad remaining lines to fill content area
 	for len(lines) < contentHeight {
@@ -141,56 +203,74 @@ func (m Model) renderFrequencyView() string {
 
 // renderFrequencyHeader renders the column headers
 func (m Model) renderFrequencyHeader() string {
-	allowWidth, denyWidth, permWidth, lastWidth, statusWidth := m.calculateFreqColumns()
-
-	allow := padLeft("Allow", allowWidth)
-	deny := padLeft("Deny", denyWidth)
-	perm := padRight("Permission", permWidth)
-	last := padLeft("Last", lastWidth)
-	status := padLeft("Status", statusWidth)
-
-	header := fmt.Sprintf("  %s  %s  %s  %s  %s", allow, deny, perm, last, status)
+	widths, permWidth := m.calculateFreqColumns()
+	header := "  " + freqCells(widths, permWidth, freqColumnTitles, "Permission")
 	header = padRight(header, m.width-4)
 	return styles.ListHeader.Render(header)
 }
 
+// freqCells lays out a row's cells in the columns shown: numbers and times
+// right-aligned, the permission left-aligned before the first time column
+func freqCells(widths [freqColumnCount]int, permWidth int, cells [freqColumnCount]string, perm string) string {
+	var parts []string
+	for c := range freqColumnCount {
+		if c == colFirst {
+			parts = append(parts, padRight(truncateString(perm, permWidth), permWidth))
+		}
+		if widths[c] > 0 {
+			parts = append(parts, padLeft(cells[c], widths[c]))
+		}
+	}
+	return strings.Join(parts, "  ")
+}
+
 // renderFreqRow builds a frequency row with responsive column widths and full-width padding.
 // Status styling is applied AFTER truncation/padding to avoid ANSI escape codes being
 // cut mid-sequence by truncateString, which would leak color into subsequent rows.
-func (m Model) renderFreqRow(allowText, denyText, permText, timeText, statusText string, selected bool, statusApproved bool) string {
-	allowWidth, denyWidth, permWidth, lastWidth, statusWidth := m.calculateFreqColumns()
-
-	allow := padLeft(allowText, allowWidth)
-	deny := padLeft(denyText, denyWidth)
-	perm := padRight(truncateString(permText, permWidth), permWidth)
-	last := padLeft(timeText, lastWidth)
-	status := padLeft(statusText, statusWidth)
+func (m Model) renderFreqRow(cells [freqColumnCount]string, permText string, rateStyle lipgloss.Style, selected bool, statusApproved bool) string {
+	widths, permWidth := m.calculateFreqColumns()
 
 	cursor := "  "
 	if selected {
 		cursor = "> "
+		permText = m.scrolledName(permText)
 	}
 
 	// Build row with plain text only — no ANSI codes yet
-	row := fmt.Sprintf("%s%s  %s  %s  %s  %s", cursor, allow, deny, perm, last, status)
+	row := cursor + freqCells(widths, permWidth, cells, permText)
 
-	// Truncate and pad using plain byte lengths (safe since no ANSI codes)
+	// Truncate and pad by display width (safe since no ANSI codes)
 	maxWidth := m.width - 2
 	row = truncateString(row, maxWidth)
 	row = padRight(row, maxWidth)
 
 	// Now apply coloring. Replace the plain status text at the end with styled version.
-	plainStatus := padLeft(statusText, statusWidth)
-	var styledStatus string
-	if statusApproved {
-		styledStatus = styles.StatusApproved.Render(plainStatus)
-	} else {
-		styledStatus = styles.StatusPending.Render(plainStatus)
+	if widths[colStatus] > 0 {
+		plainStatus := padLeft(cells[colStatus], widths[colStatus])
+		var styledStatus string
+		if statusApproved {
+			styledStatus = styles.StatusApproved.Render(plainStatus)
+		} else {
+			styledStatus = styles.StatusPending.Render(plainStatus)
+		}
+		// Find the last occurrence of the plain status and replace it with styled
+		idx := strings.LastIndex(row, plainStatus)
+		if idx >= 0 {
+			row = row[:idx] + styledStatus + row[idx+len(plainStatus):]
+		}
 	}
-	// Find the last occurrence of the plain status and replace it with styled
-	idx := strings.LastIndex(row, plainStatus)
-	if idx >= 0 {
-		row = row[:idx] + styledStatus + row[idx+len(plainStatus):]
+	// The rate follows the plain-ASCII count columns, so its offset is known
+	if widths[colRate] > 0 {
+		rate := padLeft(cells[colRate], widths[colRate])
+		rateIdx := len(cursor)
+		for _, c := range []freqColumn{colAllow, colAuto, colDeny} {
+			if widths[c] > 0 {
+				rateIdx += len(padLeft(cells[c], widths[c])) + 2
+			}
+		}
+		if rateIdx <= len(row) && strings.HasPrefix(row[rateIdx:], rate) {
+			row = row[:rateIdx] + rateStyle.Render(rate) + row[rateIdx+len(rate):]
+		}
 	}
 
 	if selected {
@@ -202,12 +282,9 @@ func (m Model) renderFreqRow(allowText, denyText, permText, timeText, statusText
 func (m Model) renderGroupRow(g types.PermissionGroup, expandChar string, selected bool) string {
 	allowText := fmt.Sprintf("%d", g.TotalApproved)
 	denyText := fmt.Sprintf("%d", g.TotalDenied)
+	name := m.groupRowName(g, expandChar)
 
-	name := fmt.Sprintf("%s %s", expandChar, g.Type)
-	if len(g.Children) > 1 {
-		name += fmt.Sprintf(" (%d variants)", len(g.Children))
-	}
-
+	firstText := formatRelativeTime(g.FirstSeen)
 	timeText := formatRelativeTime(g.LastSeen)
 	approved := g.ApprovedAt > types.NotApproved
 
@@ -218,13 +295,17 @@ func (m Model) renderGroupRow(g types.PermissionGroup, expandChar string, select
 		statusText = "○"
 	}
 
-	return m.renderFreqRow(allowText, denyText, name, timeText, statusText, selected, approved)
+	rateText, rateStyle := formatApprovalRate(g.ApprovalRate())
+	cells := [freqColumnCount]string{allowText, fmt.Sprint(g.TotalAutoApproved), denyText, rateText, fmt.Sprint(g.ProjectCount()), formatTokens(g.TotalTokens), firstText, timeText, statusText}
+	return m.renderFreqRow(cells, name, rateStyle, selected, approved)
 }
 
 func (m Model) renderChildRow(p types.PermissionStats, selected bool) string {
 	allowText := fmt.Sprintf("%d", p.Approved)
 	denyText := fmt.Sprintf("%d", p.Denied)
-	name := "    " + p.Permission.Raw
+	name := m.childRowName(p)
+
+	firstText := formatRelativeTime(p.FirstSeen)
 	timeText := formatRelativeTime(p.LastSeen)
 	approved := p.ApprovedAt > types.NotApproved
 
@@ -235,7 +316,117 @@ func (m Model) renderChildRow(p types.PermissionStats, selected bool) string {
 		statusText = "○"
 	}
 
-	return m.renderFreqRow(allowText, denyText, name, timeText, statusText, selected, approved)
+	rateText, rateStyle := formatApprovalRate(p.ApprovalRate())
+	cells := [freqColumnCount]string{allowText, fmt.Sprint(p.AutoApproved), denyText, rateText, fmt.Sprint(len(p.Projects)), formatTokens(p.Tokens), firstText, timeText, statusText}
+	return m.renderFreqRow(cells, name, rateStyle, selected, approved)
+}
+
+// expandMarker shows whether a group row is expanded
+func expandMarker(g types.PermissionGroup) string {
+	if g.Expanded {
+		return "▼"
+	}
+	return "▶"
+}
+
+// groupRowName returns the text of a group row's permission column
+func (m Model) groupRowName(g types.PermissionGroup, expandChar string) string {
+	name := fmt.Sprintf("%s %s", expandChar, g.Type)
+	if m.state.Pins.Group(g.Type) {
+		name = fmt.Sprintf("%s %s%s", expandChar, pinMarker, g.Type)
+	}
+	if len(g.Children) > 1 {
+		name += fmt.Sprintf(" (%d variants)", len(g.Children))
+	}
+	for _, p := range g.Children {
+		if ranOutside(p) {
+			name += " " + outsideMarker
+			break
+		}
+	}
+	return name
+}
+
+// outsideMarker flags a Bash permission whose commands often ran outside the
+// project directory, and its group
+const outsideMarker = "⚠"
+
+// outsideShare is the share of a permission's calls that must have run
+// outside the project directory for it to be flagged
+const outsideShare = 0.2
+
+// ranOutside reports whether a Bash permission's commands frequently ran
+// outside the directory their session started in, such as / or the home
+// directory, where a broad wildcard grant reaches much further
+func ranOutside(p types.PermissionStats) bool {
+	return p.Outside > 0 && float64(p.Outside) >= outsideShare*float64(p.Count)
+}
+
+// childRowName returns the text of a permission row's permission column
+func (m Model) childRowName(p types.PermissionStats) string {
+	indent := "    "
+	if m.isStaged(p.Permission.Raw) {
+		indent = "  " + stagedMarker
+	}
+	name := indent + p.Permission.Raw
+	if m.state.Pins.Permission(p.Permission.Raw) {
+		name = indent + pinMarker + p.Permission.Raw
+	}
+	if ranOutside(p) {
+		name += " " + outsideMarker
+	}
+	if m.sinceReview {
+		name += "  (" + m.reviewDelta(p) + ")"
+	}
+	if n := p.SubagentCount(); m.withSubagents && n > 0 {
+		name += fmt.Sprintf("  (%d by subagents)", n)
+	}
+	return name
+}
+
+// formatApprovalRate formats an approval rate as a percentage, colored
+// green when nearly always approved, red when mostly denied and yellow in
+// between. Permissions with no decided uses show "-".
+func formatApprovalRate(rate float64, decided bool) (string, lipgloss.Style) {
+	if !decided {
+		return "-", styles.StatusPending
+	}
+	pct := int(math.Round(rate * 100))
+	text := fmt.Sprintf("%d%%", pct)
+	switch {
+	case pct >= 90:
+		return text, styles.StatusApproved
+	case pct >= 50:
+		return text, styles.StatusWarning
+	default:
+		return text, styles.Error
+	}
+}
+
+// formatTokens formats a token count compactly, e.g. "950", "12.3k", "4.1M"
+func formatTokens(n int) string {
+	switch {
+	case n < 1000:
+		return fmt.Sprint(n)
+	case n < 999_950: // Rounds to "1000.0k" from here
+		return fmt.Sprintf("%.1fk", float64(n)/1000)
+	default:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	}
+}
+
+// formatDuration formats how long a call ran, e.g. "850ms", "4.2s", "1m30s"
+func formatDuration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	case d < time.Minute:
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
 }
 
 // formatRelativeTime formats a time as relative (e.g., "2h ago", "3d ago")
@@ -244,8 +435,7 @@ func formatRelativeTime(t time.Time) string {
 		return "unknown"
 	}
 
-	now := time.Now()
-	diff := now.Sub(t)
+	diff := now().Sub(t)
 
 	switch {
 	case diff < time.Minute:
@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/audit"
+)
+
+// loadHistory rereads the audit log, newest entry first
+func (m *Model) loadHistory() {
+	var entries []audit.Entry
+	entries, m.historySkipped, m.historyErr = audit.Read()
+	m.history = make([]audit.Entry, len(entries))
+	for i, e := range entries {
+		m.history[len(entries)-1-i] = e
+	}
+	m.histList.clamp(len(m.history), m.histViewportHeight())
+}
+
+// histViewportHeight returns how many audit rows fit under the header
+func (m Model) histViewportHeight() int {
+	_, contentHeight := m.calculateLayout()
+	return max(contentHeight-2, 1) // header + separator
+}
+
+// renderHistoryView lists the changes recorded in the audit log
+func (m Model) renderHistoryView() string {
+	_, contentHeight := m.calculateLayout()
+	width := m.width - 4
+
+	var lines []string
+
+	header := fmt.Sprintf("%d changes recorded in %s", len(m.history), audit.Path())
+	switch {
+	case m.historyErr != nil:
+		header = "Could not read the audit log: " + m.historyErr.Error()
+	case len(m.history) == 0:
+		header = "No changes recorded yet — every allow, deny and restore made with perms is logged here"
+	case m.historySkipped > 0:
+		header += fmt.Sprintf(" (%d unreadable lines skipped)", m.historySkipped)
+	}
+	lines = append(lines, padRight(truncateString(header, width), width))
+	lines = append(lines, strings.Repeat("─", width))
+
+	start, end := m.histList.window(len(m.history), m.histViewportHeight())
+	for i := start; i < end; i++ {
+		text := truncateString(formatAuditEntry(m.history[i]), width-2)
+		if i == m.histList.cursor {
+			lines = append(lines, styles.ListItemSelected.Render("> "+text))
+		} else {
+			lines = append(lines, styles.ListItem.Render(text))
+		}
+	}
+
+	for len(lines) < contentHeight {
+		lines = append(lines, "")
+	}
+
+	return strings.Join(lines[:contentHeight], "\n") + "\n"
+}
+
+// formatAuditEntry renders one audit entry as a History row. Entries that
+// changed nothing (the rule already existed) are marked "=", removals "-".
+func formatAuditEntry(e audit.Entry) string {
+	status := "+"
+	switch {
+	case !e.Changed:
+		status = "="
+	case e.Action == "remove":
+		status = "-"
+	}
+	what := e.Permission
+	if what == "" {
+		what = e.Note
+	}
+	return fmt.Sprintf("%s %s  %s %s %s %s %s",
+		status,
+		e.Time.Local().Format("2006-01-02 15:04"),
+		padRight(truncateString(e.User, 10), 10),
+		padRight(e.Action, 7),
+		padRight(e.Scope, 7),
+		padRight(truncateString(what, 36), 36),
+		settingsLabel(e.File))
+}
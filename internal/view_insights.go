@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/b-open-io/claude-perms/internal/insights"
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/types"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// pendingStreaks returns the deny streaks still worth suggesting: not
+// dismissed and not yet allowed in settings
+func (m Model) pendingStreaks() []insights.DenyStreak {
+	var pending []insights.DenyStreak
+	for _, s := range m.denyStreaks {
+		if m.dismissedStreaks[s.Permission] {
+			continue
+		}
+		if m.approvalLevel(s.Permission, m.permissionProjects(s.Permission)) != types.NotApproved {
+			continue
+		}
+		pending = append(pending, s)
+	}
+	return pending
+}
+
+// permissionProjects returns the projects raw was used in
+func (m Model) permissionProjects(raw string) []string {
+	for _, p := range m.permissions {
+		if p.Permission.Raw == raw {
+			return p.Projects
+		}
+	}
+	return nil
+}
+
+// freqBannerLines returns how many lines, the deny streak suggestion and
+// the quick filter bar, sit above the Frequency view's column header
+func (m Model) freqBannerLines() int {
+	n := 0
+	if len(m.pendingStreaks()) > 0 {
+		n++
+	}
+	if m.quickFiltering() {
+		n++
+	}
+	return n
+}
+
+// renderStreakBanner renders the top deny streak as a one-line suggestion
+func (m Model) renderStreakBanner() string {
+	pending := m.pendingStreaks()
+	if len(pending) == 0 {
+		return ""
+	}
+	s := pending[0]
+
+	text := fmt.Sprintf("⚠ You denied %s %d times %s — %s allow… · %s deny · %s dismiss",
+		s.Permission, s.Denials, s.Period(),
+		primaryKey(m.keys.Apply), primaryKey(m.keys.Deny), primaryKey(m.keys.Dismiss))
+	if len(pending) > 1 {
+		text += fmt.Sprintf("  (+%d more)", len(pending)-1)
+	}
+	return styles.Error.Render(padRight(truncateString(text, m.width-4), m.width-4))
+}
+
+// handleStreakKeys acts on the suggested deny streak from the Frequency view
+func (m Model) handleStreakKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	pending := m.pendingStreaks()
+	if len(pending) == 0 {
+		return m, nil
+	}
+	raw := pending[0].Permission
+
+	switch {
+	case key.Matches(msg, m.keys.Apply):
+		// Allow goes through the usual apply flow so the user picks a scope
+		if !m.jumpToPermission(raw) {
+			m.setLinkToast("%s is no longer in the list", raw)
+			return m, m.toastTickCmd()
+		}
+		m.resetApplyModalState()
+		m.showApplyModal = true
+
+	case key.Matches(msg, m.keys.Deny):
+		if m.writeBlockedReason() != "" {
+			return m.writeBlocked()
+		}
+		result, err := m.writePermission(parser.UserSettingsPath(), raw, true)
+		if err != nil {
+			return m, m.failWrite(parser.UserSettingsPath(), err, func(m Model) (tea.Model, tea.Cmd) {
+				return m.handleStreakKeys(msg)
+			})
+		}
+		m.recordDeny(result)
+		m.dismissStreak(raw)
+		where := result.FilePath
+		if result.LineNumber > 0 {
+			where += fmt.Sprintf(":%d", result.LineNumber)
+		}
+		if result.DryRun {
+			m.notify(toastInfo, "Dry run: would deny %s in %s", raw, where)
+		} else {
+			m.notify(toastSuccess, "Denied: %s in %s", raw, where)
+		}
+		return m, m.toastTickCmd()
+
+	case key.Matches(msg, m.keys.Dismiss):
+		m.dismissStreak(raw)
+	}
+
+	return m, nil
+}
+
+// dismissStreak hides the suggestion for a permission for the rest of the session
+func (m *Model) dismissStreak(raw string) {
+	if m.dismissedStreaks == nil {
+		m.dismissedStreaks = make(map[string]bool)
+	}
+	m.dismissedStreaks[raw] = true
+	m.clampLists()
+}
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/b-open-io/claude-perms/internal/insights"
 	"github.com/b-open-io/claude-perms/internal/parser"
 	"github.com/b-open-io/claude-perms/internal/types"
+	"github.com/charmbracelet/lipgloss"
 )
 
 // Agent modal modes
@@ -13,6 +15,9 @@ const (
 	AgentModalModePermissions = iota
 	AgentModalModeScope
 	AgentModalModeProject
+	AgentModalModeTools
+	AgentModalModeEditTools
+	AgentModalModeInvocations
 )
 
 // calculateMatrixColumns returns responsive column widths based on terminal width
@@ -62,7 +67,11 @@ func (m Model) renderMatrixColumnHeader() string {
 	nameWidth, declWidth, callsWidth, lastWidth, statusWidth := m.calculateMatrixColumns()
 
 	// Build header with column names
-	agent := padRight("Agent", nameWidth)
+	first := "Agent"
+	if m.matrixByPlugin {
+		first = "Plugin"
+	}
+	agent := padRight(first, nameWidth)
 	decl := padLeft("Decl", declWidth)
 	calls := padLeft("Calls", callsWidth)
 	last := padLeft("Last", lastWidth)
@@ -73,8 +82,29 @@ func (m Model) renderMatrixColumnHeader() string {
 	return styles.ListHeader.Render(header)
 }
 
-// getDeclaredPermCount finds declared permission count for an agent type
-func (m Model) getDeclaredPermCount(agentType string) int {
+// isCommand reports whether a Matrix row is a slash command rather than
+// an agent
+func isCommand(agentType string) bool {
+	return strings.HasPrefix(agentType, "/")
+}
+
+// declaredAgent finds the definition of an agent type. A slash command's
+// allowed tools are returned as the agent's tools.
+func (m Model) declaredAgent(agentType string) (types.AgentPermissions, bool) {
+	if isCommand(agentType) {
+		for _, cmd := range m.commands {
+			if cmd.Invocation() == agentType {
+				return types.AgentPermissions{
+					Name:        cmd.Name,
+					Plugin:      cmd.Plugin,
+					Version:     cmd.Version,
+					FilePath:    cmd.FilePath,
+					Permissions: cmd.Permissions,
+				}, true
+			}
+		}
+		return types.AgentPermissions{}, false
+	}
 	for _, agent := range m.agents {
 		// Match by name (with or without plugin prefix)
 		fullName := agent.Name
@@ -82,34 +112,31 @@ func (m Model) getDeclaredPermCount(agentType string) int {
 			fullName = agent.Plugin + ":" + agent.Name
 		}
 		if fullName == agentType || agent.Name == agentType {
-			return len(agent.Permissions)
+			return agent, true
 		}
 	}
-	return 0
+	return types.AgentPermissions{}, false
+}
+
+// getDeclaredPermCount finds declared permission count for an agent type:
+// its tools plus the rules of its permissions block
+func (m Model) getDeclaredPermCount(agentType string) int {
+	agent, _ := m.declaredAgent(agentType)
+	return len(agent.Permissions) + len(agent.Allow) + len(agent.Deny)
+}
+
+// agentDrift compares an agent's declarations with its usage
+func (m Model) agentDrift(agent types.AgentUsageStats) insights.Drift {
+	declared, _ := m.declaredAgent(agent.AgentType)
+	return insights.AgentDrift(declared, agent)
 }
 
-// countApprovedPerms counts how many permissions are approved
-func (m Model) countApprovedPerms(perms []types.PermissionStats) int {
+// countApprovedPerms counts how many of an agent's permissions are approved
+// in user settings or in the settings of every project the agent ran in
+func (m Model) countApprovedPerms(agent types.AgentUsageStats) int {
 	count := 0
-	for _, p := range perms {
-		approved := false
-		// Check if permission is in user approved list
-		for _, a := range m.userApproved {
-			if a == p.Permission.Raw {
-				approved = true
-				break
-			}
-		}
-		// Only check project approved if not already found
-		if !approved {
-			for _, a := range m.projectApproved {
-				if a == p.Permission.Raw {
-					approved = true
-					break
-				}
-			}
-		}
-		if approved {
+	for _, p := range agent.Permissions {
+		if m.approvalLevel(p.Permission.Raw, agent.Projects) != types.NotApproved {
 			count++
 		}
 	}
@@ -121,7 +148,11 @@ func (m Model) renderAgentUsageRow(agent types.AgentUsageStats, selected bool) s
 	nameWidth, declWidth, callsWidth, lastWidth, statusWidth := m.calculateMatrixColumns()
 
 	// Agent name (truncated if needed)
-	name := truncateString(agent.AgentType, nameWidth)
+	name := agent.AgentType
+	if m.state.Pins.Agent(agent.AgentType) {
+		name = pinMarker + name
+	}
+	name = truncateString(name, nameWidth)
 	name = padRight(name, nameWidth)
 
 	// Declared permission count
@@ -140,7 +171,7 @@ func (m Model) renderAgentUsageRow(agent types.AgentUsageStats, selected bool) s
 	if permCount == 0 {
 		statusText = "-"
 	} else {
-		approvedCount := m.countApprovedPerms(agent.Permissions)
+		approvedCount := m.countApprovedPerms(agent)
 		if approvedCount == permCount {
 			statusText = "all"
 		} else {
@@ -175,12 +206,27 @@ func (m Model) renderMatrixView() string {
 	var lines []string
 
 	// Section header (ensure single line)
-	agentCount := len(m.agentUsage)
-	if agentCount == 0 {
+	// Commands that allow tools but were never run have no usage row
+	agentCount := 0
+	commands := make(map[string]bool)
+	for _, cmd := range m.commands {
+		commands[cmd.Invocation()] = true
+	}
+	for _, a := range m.agentUsage {
+		if isCommand(a.AgentType) {
+			commands[a.AgentType] = true
+		} else {
+			agentCount++
+		}
+	}
+	if len(m.agentUsage) == 0 {
 		agentCount = len(m.agents)
 	}
 	skillCount := len(m.skills)
-	headerText := fmt.Sprintf("Agents: %d | Skills: %d", agentCount, skillCount)
+	headerText := fmt.Sprintf("Agents: %d | Skills: %d | Commands: %d", agentCount, skillCount, len(commands))
+	if m.matrixByPlugin {
+		headerText = fmt.Sprintf("Plugins: %d | Agents: %d | Skills: %d | Commands: %d", len(m.plugins), agentCount, skillCount, len(commands))
+	}
 	lines = append(lines, padRight(truncateString(headerText, m.width-4), m.width-4))
 	lines = append(lines, strings.Repeat("─", m.width-4))
 
@@ -194,10 +240,32 @@ func (m Model) renderMatrixView() string {
 	}
 
 	// Use stored scroll offset (maintained by navigation methods)
-	scrollOffset := m.matrixScroll
+	scrollOffset := m.matrixList.scroll
 
 	// Prefer agent usage data if available, otherwise fall back to declared agents
-	if len(m.agentUsage) > 0 {
+	if m.matrixByPlugin && len(m.plugins) > 0 {
+		if scrollOffset > 0 {
+			lines = append(lines, fmt.Sprintf("  (^ %d more)", scrollOffset))
+			viewportHeight--
+		}
+
+		endIdx := scrollOffset + viewportHeight
+		if endIdx > len(m.plugins) {
+			endIdx = len(m.plugins)
+		}
+
+		for i := scrollOffset; i < endIdx; i++ {
+			lines = append(lines, m.renderPluginRow(m.plugins[i], i == m.matrixList.cursor))
+		}
+
+		if endIdx < len(m.plugins) {
+			remaining := len(m.plugins) - endIdx
+			lines = append(lines, fmt.Sprintf("  ... %d more (v)", remaining))
+		}
+	} else if m.matrixByPlugin {
+		lines = append(lines, "")
+		lines = append(lines, "  No installed plugins with declared permissions or usage")
+	} else if len(m.agentUsage) > 0 {
 		// Show scroll-up indicator if not at top
 		if scrollOffset > 0 {
 			lines = append(lines, fmt.Sprintf("  (^ %d more)", scrollOffset))
@@ -211,7 +279,7 @@ func (m Model) renderMatrixView() string {
 		}
 
 		for i := scrollOffset; i < endIdx; i++ {
-			isSelected := i == m.matrixCursor
+			isSelected := i == m.matrixList.cursor
 			lines = append(lines, m.renderAgentUsageRow(m.agentUsage[i], isSelected))
 		}
 
@@ -233,7 +301,7 @@ func (m Model) renderMatrixView() string {
 		}
 
 		for i := scrollOffset; i < endIdx; i++ {
-			isSelected := i == m.matrixCursor
+			isSelected := i == m.matrixList.cursor
 			lines = append(lines, renderAgentRowWithCursor(m.agents[i], m.width, isSelected))
 		}
 
@@ -264,7 +332,7 @@ func renderAgentRowWithCursor(agent types.AgentPermissions, width int, selected
 		name = agent.Name
 	}
 
-	permCount := fmt.Sprintf("(%d)", len(agent.Permissions))
+	permCount := fmt.Sprintf("(%d)", len(agent.Permissions)+len(agent.Allow)+len(agent.Deny))
 
 	// Fixed column widths for clean alignment
 	const nameCol = 45 // Agent name column width
@@ -313,7 +381,16 @@ func (m Model) renderAgentDetailModal() string {
 	// Header
 	content.WriteString(styles.ModalTitle.Render(agent.AgentType))
 	content.WriteString("\n")
-	content.WriteString(fmt.Sprintf("  %d total calls across %d sessions\n", agent.TotalCalls, agent.Sessions))
+	if agent.TotalTokens > 0 {
+		content.WriteString(fmt.Sprintf("  %d total calls across %d sessions, %s tokens\n", agent.TotalCalls, agent.Sessions, formatTokens(agent.TotalTokens)))
+	} else {
+		content.WriteString(fmt.Sprintf("  %d total calls across %d sessions\n", agent.TotalCalls, agent.Sessions))
+	}
+	content.WriteString(m.renderNoteField(true, agent.AgentType, modalWidth-8))
+	content.WriteString(m.renderDeclarations(agent, modalWidth-8))
+	if m.agentModalMode == AgentModalModePermissions {
+		content.WriteString(renderRecentTasks(agent, modalWidth-8))
+	}
 	content.WriteString("\n")
 
 	switch m.agentModalMode {
@@ -323,21 +400,82 @@ func (m Model) renderAgentDetailModal() string {
 		content.WriteString(m.renderScopeSelectMode())
 	case AgentModalModeProject:
 		content.WriteString(m.renderProjectSelectMode(agent))
+	case AgentModalModeTools:
+		content.WriteString(m.renderToolsMode(agent))
+	case AgentModalModeEditTools:
+		content.WriteString(m.renderEditToolsMode(agent))
+	case AgentModalModeInvocations:
+		content.WriteString(m.renderInvocationsMode(agent, modalWidth-8))
 	}
 
 	return styles.Modal.Width(modalWidth).Render(content.String())
 }
 
+// renderDeclarations renders what the agent's frontmatter declares, each
+// list in its own line, and how far its usage drifted from it
+func (m Model) renderDeclarations(agent types.AgentUsageStats, width int) string {
+	declared, ok := m.declaredAgent(agent.AgentType)
+	if !ok {
+		return ""
+	}
+	return renderDeclared(declared, m.agentDrift(agent), width)
+}
+
+// renderDeclared renders declared tools, allow and deny rules and a drift
+// summary, for an agent or a whole plugin
+func renderDeclared(declared types.AgentPermissions, d insights.Drift, width int) string {
+	var b strings.Builder
+	list := func(label string, perms []types.Permission, style lipgloss.Style) {
+		if len(perms) == 0 {
+			return
+		}
+		raws := make([]string, len(perms))
+		for i, p := range perms {
+			raws[i] = p.Raw
+		}
+		b.WriteString(style.Render(truncateString(fmt.Sprintf("  %-16s %s", label, strings.Join(raws, ", ")), width)) + "\n")
+	}
+	list("Declared tools:", declared.Permissions, styles.HelpDesc)
+	list("Declared allow:", declared.Allow, styles.StatusApproved)
+	list("Declared deny:", declared.Deny, styles.Error)
+
+	if d.Empty() {
+		b.WriteString(styles.HelpDesc.Render("  Usage matches the declarations") + "\n")
+		return b.String()
+	}
+	var parts []string
+	if n := len(d.Undeclared); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d undeclared", n))
+	}
+	if n := len(d.Denied); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d used despite a deny", n))
+	}
+	if n := len(d.Unused); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d declared, never used", n))
+	}
+	b.WriteString(styles.StatusPending.Render(truncateString("  Drift: "+strings.Join(parts, ", "), width)) + "\n")
+	return b.String()
+}
+
 // renderPermissionSelectMode renders the permission multi-select list
 func (m Model) renderPermissionSelectMode(agent types.AgentUsageStats) string {
 	var content strings.Builder
 
+	drift := m.agentDrift(agent)
+	tags := make(map[string]string)
+	for _, p := range drift.Undeclared {
+		tags[p.Permission.Raw] = "undeclared"
+	}
+	for _, p := range drift.Denied {
+		tags[p.Permission.Raw] = "agent denies"
+	}
+
 	content.WriteString("  Permissions requested by this agent:\n\n")
 
 	selectedCount := 0
 	for i, perm := range agent.Permissions {
 		isSelected := i < len(m.agentModalSelected) && m.agentModalSelected[i]
-		isCursor := i == m.agentModalCursor
+		isCursor := i == m.agentModalList.cursor
 
 		checkbox := "[ ]"
 		if isSelected {
@@ -352,9 +490,12 @@ func (m Model) renderPermissionSelectMode(agent types.AgentUsageStats) string {
 
 		permName := truncateString(perm.Permission.Raw, 30)
 		calls := fmt.Sprintf("%d calls", perm.Count)
-		status := m.getPermissionApprovalStatus(perm.Permission.Raw)
+		status := m.getPermissionApprovalStatus(perm.Permission.Raw, agent.Projects)
 
-		line := fmt.Sprintf("%s%s %-30s %10s  %s", cursor, checkbox, permName, calls, status)
+		line := fmt.Sprintf("%s%s %s %10s  %s", cursor, checkbox, padRight(permName, 30), calls, status)
+		if tag := tags[perm.Permission.Raw]; tag != "" {
+			line += "  " + styles.StatusPending.Render(tag)
+		}
 
 		if isCursor {
 			content.WriteString(styles.ListItemSelected.Render(line))
@@ -365,11 +506,7 @@ func (m Model) renderPermissionSelectMode(agent types.AgentUsageStats) string {
 	}
 
 	content.WriteString(fmt.Sprintf("\n  %d selected\n\n", selectedCount))
-	content.WriteString(fmt.Sprintf("  %s Toggle  %s Navigate  %s Apply  %s Close",
-		styles.HelpKey.Render("Space"),
-		styles.HelpKey.Render("j/k"),
-		styles.HelpKey.Render("A"),
-		styles.HelpKey.Render("Esc")))
+	content.WriteString("  " + renderHints(m.contextBindings()))
 
 	return content.String()
 }
@@ -383,14 +520,9 @@ func (m Model) renderScopeSelectMode() string {
 
 	var content strings.Builder
 
-	// Collect selected permission strings
-	var selectedPerms []string
-	for i, perm := range agent.Permissions {
-		if i < len(m.agentModalSelected) && m.agentModalSelected[i] {
-			selectedPerms = append(selectedPerms, perm.Permission.Raw)
-		}
-	}
+	selectedPerms := m.agentModalSelection(agent)
 
+	content.WriteString(m.renderWriteModeNotice())
 	content.WriteString(fmt.Sprintf("  Apply %d permissions to:\n\n", len(selectedPerms)))
 
 	userCursor := "  "
@@ -404,19 +536,17 @@ func (m Model) renderScopeSelectMode() string {
 	userLine := fmt.Sprintf("%sUser level (~/.claude/settings.local.json)", userCursor)
 	projLine := fmt.Sprintf("%sProject level", projCursor)
 
-	if m.agentModalScope == 0 {
-		content.WriteString(styles.ListItemSelected.Render(userLine))
-	} else {
-		content.WriteString(userLine)
-	}
-	content.WriteString("\n")
-
-	if m.agentModalScope == 1 {
-		content.WriteString(styles.ListItemSelected.Render(projLine))
-	} else {
-		content.WriteString(projLine)
+	for i, line := range []string{userLine, projLine} {
+		switch {
+		case i == m.agentModalScope:
+			content.WriteString(styles.ListItemSelected.Render(line))
+		case m.writeBlockedReason() != "":
+			content.WriteString(styles.StatusPending.Render(line))
+		default:
+			content.WriteString(line)
+		}
+		content.WriteString("\n")
 	}
-	content.WriteString("\n")
 
 	// Diff preview for current scope selection
 	if m.agentModalScope == 0 && len(selectedPerms) > 0 {
@@ -427,12 +557,10 @@ func (m Model) renderScopeSelectMode() string {
 		} else {
 			content.WriteString(renderDiffPreview(filePath, diffLines, allExist, 74))
 		}
+		content.WriteString(m.renderCoverage(selectedPerms, ""))
 	}
 
-	content.WriteString(fmt.Sprintf("\n  %s Select  %s Navigate  %s Back",
-		styles.HelpKey.Render("Enter"),
-		styles.HelpKey.Render("j/k"),
-		styles.HelpKey.Render("Esc")))
+	content.WriteString("\n  " + renderHints(m.contextBindings()))
 
 	return content.String()
 }
@@ -441,84 +569,48 @@ func (m Model) renderScopeSelectMode() string {
 func (m Model) renderProjectSelectMode(agent types.AgentUsageStats) string {
 	var content strings.Builder
 
-	// Collect selected permission strings
-	var selectedPerms []string
-	for i, perm := range agent.Permissions {
-		if i < len(m.agentModalSelected) && m.agentModalSelected[i] {
-			selectedPerms = append(selectedPerms, perm.Permission.Raw)
-		}
-	}
+	selectedPerms := m.agentModalSelection(agent)
 
+	content.WriteString(m.renderWriteModeNotice())
 	content.WriteString("  Select project:\n\n")
 
-	for i, proj := range agent.Projects {
-		cursor := "  "
-		if i == m.agentModalProjCursor {
-			cursor = "> "
-		}
-
-		line := fmt.Sprintf("%s%s", cursor, proj)
+	projects := m.visibleProjects(agent.Projects)
+	content.WriteString(m.renderProjectFilter(len(projects), len(agent.Projects)))
 
-		if i == m.agentModalProjCursor {
-			content.WriteString(styles.ListItemSelected.Render(line))
-		} else {
-			content.WriteString(line)
-		}
+	for i, proj := range projects {
+		content.WriteString(m.renderProjectOption(proj, proj, selectedPerms, i == m.agentModalProjList.cursor))
 		content.WriteString("\n")
 	}
 
 	// Diff preview for the selected project
-	if m.agentModalProjCursor < len(agent.Projects) && len(selectedPerms) > 0 {
+	if m.agentModalProjList.cursor < len(projects) && len(selectedPerms) > 0 {
 		content.WriteString("\n")
-		projectPath := agent.Projects[m.agentModalProjCursor]
+		projectPath := projects[m.agentModalProjList.cursor]
 		filePath, diffLines, allExist, err := parser.PreviewProjectDiff(projectPath, selectedPerms)
 		if err != nil {
 			content.WriteString(renderDiffPreviewError(filePath, err))
 		} else {
 			content.WriteString(renderDiffPreview(filePath, diffLines, allExist, 74))
 		}
+		content.WriteString(m.renderCoverage(selectedPerms, projectPath))
 	}
 
-	content.WriteString(fmt.Sprintf("\n  %s Select  %s Navigate  %s Back",
-		styles.HelpKey.Render("Enter"),
-		styles.HelpKey.Render("j/k"),
-		styles.HelpKey.Render("Esc")))
+	content.WriteString("\n  " + renderHints(m.contextBindings()))
 
 	return content.String()
 }
 
 // getPermissionApprovalStatus returns approval status string for a permission
-func (m Model) getPermissionApprovalStatus(permRaw string) string {
-	for _, approved := range m.userApproved {
-		if approved == permRaw {
-			return styles.StatusApproved.Render("✓ user")
-		}
+// used in the given projects
+func (m Model) getPermissionApprovalStatus(permRaw string, projects []string) string {
+	level := m.approvalLevel(permRaw, projects)
+	if level == types.NotApproved {
+		return styles.StatusPending.Render(level.String())
 	}
-	for _, approved := range m.projectApproved {
-		if approved == permRaw {
-			return styles.StatusApproved.Render("✓ proj")
-		}
-	}
-	return styles.StatusPending.Render("○")
+	return styles.StatusApproved.Render(level.String())
 }
 
 // renderWithAgentModal overlays the agent detail modal
 func (m Model) renderWithAgentModal(_ string) string {
-	modal := m.renderAgentDetailModal()
-
-	modalLines := strings.Split(modal, "\n")
-	modalHeight := len(modalLines)
-
-	topPadding := (m.height - modalHeight) / 2
-	if topPadding < 0 {
-		topPadding = 0
-	}
-
-	var result strings.Builder
-	for i := 0; i < topPadding; i++ {
-		result.WriteString("\n")
-	}
-	result.WriteString(modal)
-
-	return result.String()
+	return m.centerOverlay(m.renderAgentDetailModal())
 }
@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/insights"
+)
+
+// loadPolicy rereads the policy file and checks the user settings and every
+// known project's settings against it
+func (m *Model) loadPolicy() {
+	m.violations = nil
+	m.policy, m.policyErr = nil, nil
+	if m.policyPath == "" {
+		return
+	}
+	m.policy, m.policyErr = insights.LoadPolicy(m.policyPath)
+	if m.policyErr != nil {
+		m.logger.Debug("reading policy failed", "path", m.policyPath, "err", m.policyErr)
+		return
+	}
+
+	projects := make([]string, 0, len(m.projectSettings))
+	for p := range m.projectSettings {
+		projects = append(projects, p)
+	}
+	m.violations = m.policy.Lint(m.settingsRules(), projects)
+	m.policyList.clamp(len(m.violations), m.policyViewportHeight())
+}
+
+// policyViewportHeight returns how many violation rows fit under the header
+func (m Model) policyViewportHeight() int {
+	_, contentHeight := m.calculateLayout()
+	return max(contentHeight-2, 1) // header + separator
+}
+
+// jumpToViolation follows the selected violation's rule to its permission
+// in the Frequency view
+func (m *Model) jumpToViolation() {
+	if m.policyList.cursor >= len(m.violations) {
+		return
+	}
+	v := m.violations[m.policyList.cursor]
+	rule := v.Rule
+	if rule == "" {
+		rule = v.Policy.Pattern
+	}
+	if !m.jumpToPermission(rule) {
+		m.setLinkToast("%s was never used in a recorded session", rule)
+	}
+}
+
+// policyErrors counts the violations with error severity
+func (m Model) policyErrors() int {
+	n := 0
+	for _, v := range m.violations {
+		if v.Severity() == insights.SeverityError {
+			n++
+		}
+	}
+	return n
+}
+
+// renderPolicyView lists the settings rules that break the team policy and
+// the required deny rules that are missing
+func (m Model) renderPolicyView() string {
+	_, contentHeight := m.calculateLayout()
+	width := m.width - 4
+
+	var lines []string
+
+	var header string
+	switch {
+	case m.policyPath == "":
+		header = `No policy file: set "policy" in ~/.claude/perms-config.json to a YAML file of forbidden, required and ask_only patterns`
+	case m.policyErr != nil:
+		header = "Policy unreadable: " + m.policyErr.Error()
+	case len(m.violations) == 0:
+		header = "Your settings follow " + m.policyPath
+	default:
+		header = fmt.Sprintf("%d violations of %s (%d errors)", len(m.violations), m.policyPath, m.policyErrors())
+	}
+	lines = append(lines, padRight(truncateString(header, width), width))
+	lines = append(lines, strings.Repeat("─", width))
+
+	start, end := m.policyList.window(len(m.violations), m.policyViewportHeight())
+	for i := start; i < end; i++ {
+		v := m.violations[i]
+		text := truncateString(formatViolation(v), width-2)
+		switch {
+		case i == m.policyList.cursor:
+			lines = append(lines, styles.ListItemSelected.Render("> "+text))
+		case v.Severity() == insights.SeverityError:
+			lines = append(lines, styles.ListItem.Render(text))
+		default:
+			lines = append(lines, styles.HelpDesc.Render(text))
+		}
+	}
+
+	for len(lines) < contentHeight {
+		lines = append(lines, "")
+	}
+
+	return strings.Join(lines[:contentHeight], "\n") + "\n"
+}
+
+// formatViolation renders one violation as a Policy row
+func formatViolation(v insights.Violation) string {
+	return fmt.Sprintf("%-7s %-9s %s %s",
+		v.Severity(), v.Kind,
+		padRight(truncateString(settingsFileLabel(v.File), 22), 22),
+		v.Message())
+}
@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/audit"
+	"github.com/b-open-io/claude-perms/internal/insights"
+	"github.com/b-open-io/claude-perms/internal/parser"
+)
+
+// settingsRules lists the rules of the user settings files and both
+// settings files of every known project
+func (m Model) settingsRules() []insights.SettingsRule {
+	projects := make([]string, 0, len(m.projectSettings))
+	for p := range m.projectSettings {
+		projects = append(projects, p)
+	}
+	rules, warns := insights.LoadSettingsRules(projects)
+	for _, w := range warns {
+		m.logger.Debug("reading settings failed", "path", w.File, "err", w.Reason)
+	}
+	return rules
+}
+
+// loadReconcile rereads the settings files and the audit log and works out
+// where each rule came from
+func (m *Model) loadReconcile() {
+	entries, _, err := audit.Read()
+	if err != nil {
+		m.logger.Debug("reading audit log failed", "err", err)
+	}
+	m.reconciled = insights.Reconcile(m.settingsRules(), m.grants, entries)
+	m.reconUnexplained = 0
+	for _, r := range m.reconciled {
+		if !r.Explained() {
+			m.reconUnexplained++
+		}
+	}
+	m.reconList.clamp(len(m.reconciled), m.reconViewportHeight())
+}
+
+// reconViewportHeight returns how many rule rows fit under the header
+func (m Model) reconViewportHeight() int {
+	_, contentHeight := m.calculateLayout()
+	return max(contentHeight-2, 1) // header + separator
+}
+
+// jumpToReconciled follows the selected rule to its permission in the
+// Frequency view
+func (m *Model) jumpToReconciled() {
+	if m.reconList.cursor >= len(m.reconciled) {
+		return
+	}
+	rule := m.reconciled[m.reconList.cursor].Rule
+	if !m.jumpToPermission(rule) {
+		m.setLinkToast("%s was never used in a recorded session", rule)
+	}
+}
+
+// renderReconcileView lists the settings rules perms didn't write, with
+// the session grant behind each one where there is one
+func (m Model) renderReconcileView() string {
+	_, contentHeight := m.calculateLayout()
+	width := m.width - 4
+
+	var lines []string
+
+	header := fmt.Sprintf("%d rules changed outside perms (%d granted in sessions); rules written with perms are listed last",
+		m.reconUnexplained, m.reconFromSessions())
+	if m.reconUnexplained == 0 {
+		header = "Every rule in your settings files was written with perms"
+	}
+	if len(m.reconciled) == 0 {
+		header = "No rules in your user or project settings files"
+	}
+	lines = append(lines, padRight(truncateString(header, width), width))
+	lines = append(lines, strings.Repeat("─", width))
+
+	start, end := m.reconList.window(len(m.reconciled), m.reconViewportHeight())
+	for i := start; i < end; i++ {
+		r := m.reconciled[i]
+		text := truncateString(formatReconciled(r), width-2)
+		switch {
+		case i == m.reconList.cursor:
+			lines = append(lines, styles.ListItemSelected.Render("> "+text))
+		case r.Explained():
+			lines = append(lines, styles.HelpDesc.Render(text))
+		default:
+			lines = append(lines, styles.ListItem.Render(text))
+		}
+	}
+
+	for len(lines) < contentHeight {
+		lines = append(lines, "")
+	}
+
+	return strings.Join(lines[:contentHeight], "\n") + "\n"
+}
+
+// reconFromSessions counts the unexplained rules granted in sessions
+func (m Model) reconFromSessions() int {
+	n := 0
+	for _, r := range m.reconciled {
+		if r.Origin == insights.OriginSession {
+			n++
+		}
+	}
+	return n
+}
+
+// formatReconciled renders one rule as a Reconcile row
+func formatReconciled(r insights.Reconciled) string {
+	var detail string
+	switch {
+	case r.Origin == insights.OriginPerms:
+		detail = "written with perms " + formatRelativeTime(r.Added)
+	case r.Absent && r.File == "":
+		detail = "granted for one session only " + formatRelativeTime(r.Added)
+	case r.Absent:
+		detail = "granted in a session " + formatRelativeTime(r.Added) + ", no longer in the file"
+	case r.Origin == insights.OriginSession:
+		detail = "granted in a session " + formatRelativeTime(r.Added)
+		if r.Logged != "" {
+			detail += fmt.Sprintf(" (logged as %s)", r.Logged)
+		}
+	default:
+		detail = "not written by perms or a logged session"
+	}
+
+	return fmt.Sprintf("%-8s %-5s %s %s %s",
+		r.Origin, r.List,
+		padRight(truncateString(r.Rule, 40), 40),
+		padRight(truncateString(settingsFileLabel(r.File), 22), 22),
+		detail)
+}
+
+// settingsFileLabel names a settings file briefly: "user", or the project's
+// directory name, with "(local)" for settings.local.json
+func settingsFileLabel(path string) string {
+	if path == "" {
+		return "session only"
+	}
+	label := filepath.Base(filepath.Dir(filepath.Dir(path)))
+	if filepath.Dir(path) == parser.ClaudeDir() {
+		label = "user"
+	}
+	if strings.HasSuffix(path, ".local.json") {
+		label += " (local)"
+	}
+	return label
+}
@@ -0,0 +1,291 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/audit"
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/snapshots"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// loadSnapshots rereads the snapshot index, keeping the cursor in range
+func (m *Model) loadSnapshots() {
+	m.snapshots, m.snapshotsErr = snapshots.List()
+	m.snapList.clamp(len(m.snapshots), m.snapViewportHeight())
+}
+
+// snapViewportHeight returns how many snapshot rows fit under the header
+func (m Model) snapViewportHeight() int {
+	_, contentHeight := m.calculateLayout()
+	return max(contentHeight-2, 1) // header + separator
+}
+
+// snapDiffBase returns the snapshot the selected one is compared against:
+// the marked snapshot, or else the previous snapshot of the same file
+func (m Model) snapDiffBase() (snapshots.Snapshot, bool) {
+	if m.snapMarked != "" {
+		for i, s := range m.snapshots {
+			if s.ID == m.snapMarked && i != m.snapList.cursor {
+				return s, true
+			}
+		}
+	}
+	return snapshots.Previous(m.snapshots, m.snapList.cursor)
+}
+
+// handleSnapshotKeys processes the Snapshots view's own keys
+func (m Model) handleSnapshotKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Snapshot):
+		return m.takeSnapshots()
+
+	case m.snapList.cursor >= len(m.snapshots):
+		return m, nil
+
+	case key.Matches(msg, m.keys.Toggle):
+		id := m.snapshots[m.snapList.cursor].ID
+		if m.snapMarked == id {
+			m.snapMarked = ""
+		} else {
+			m.snapMarked = id
+		}
+
+	case key.Matches(msg, m.keys.Select):
+		m.pushNav()
+		m.navStack[len(m.navStack)-1].modalChain = true
+		m.showSnapDiff = true
+		m.snapDiffScroll = 0
+	}
+	return m, nil
+}
+
+// takeSnapshots snapshots the user settings and the working directory's
+// project settings on demand
+func (m Model) takeSnapshots() (tea.Model, tea.Cmd) {
+	taken := 0
+	for _, path := range []string{parser.UserSettingsPath(), parser.ProjectSettingsPath(m.projectPath)} {
+		ok, err := snapshots.Take(path, "manual snapshot")
+		if err != nil {
+			m.logger.Debug("taking snapshot failed", "path", path, "err", err)
+			m.loadSnapshots()
+			m.notify(toastError, "Could not snapshot %s: %v", shortenPath(path), err)
+			return m, m.toastTickCmd()
+		}
+		if ok {
+			taken++
+		}
+	}
+	m.loadSnapshots()
+
+	if taken == 0 {
+		m.notify(toastInfo, "No changes since the last snapshots")
+	} else {
+		m.notify(toastSuccess, "Saved %d snapshot(s) to %s", taken, snapshots.Dir())
+	}
+	return m, m.toastTickCmd()
+}
+
+// handleSnapDiffKeys processes keys while the snapshot diff modal is open
+func (m Model) handleSnapDiffKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.ForceQuit):
+		return m, tea.Quit
+
+	case key.Matches(msg, m.keys.Down):
+		if _, _, diff, _ := m.snapDiff(); m.snapDiffScroll < len(diff)-m.snapDiffHeight() {
+			m.snapDiffScroll++
+		}
+
+	case key.Matches(msg, m.keys.Up):
+		if m.snapDiffScroll > 0 {
+			m.snapDiffScroll--
+		}
+
+	case key.Matches(msg, m.keys.Restore):
+		return m.restoreSnapshot()
+
+	case key.Matches(msg, m.keys.Back, m.keys.Quit):
+		m.closeModal()
+	}
+	return m, nil
+}
+
+// restoreSnapshot writes the selected snapshot back over its settings file.
+// The replaced contents are snapshotted first, so a restore can be undone.
+func (m Model) restoreSnapshot() (tea.Model, tea.Cmd) {
+	if m.writeBlockedReason() != "" {
+		return m.writeBlocked()
+	}
+	if m.snapList.cursor >= len(m.snapshots) {
+		return m, nil
+	}
+	s := m.snapshots[m.snapList.cursor]
+
+	if m.dryRun {
+		m.finishModal()
+		m.notify(toastInfo, "Dry run: would restore %s from %s", s.Settings, s.Time.Format("Jan 2 15:04"))
+		return m, m.toastTickCmd()
+	}
+
+	data, err := snapshots.Content(s)
+	if err != nil {
+		m.logger.Debug("reading snapshot failed", "settings", s.Settings, "err", err)
+		m.notify(toastError, "Could not read the snapshot of %s: %v", shortenPath(s.Settings), err)
+		return m, m.toastTickCmd()
+	}
+	if err := parser.RestoreSettingsFile(s.Settings, data, "restore "+s.Time.Format("2006-01-02 15:04:05")); err != nil {
+		return m, m.failWrite(s.Settings, err, Model.restoreSnapshot)
+	}
+
+	scope := "project"
+	if s.Settings == parser.UserSettingsPath() {
+		scope = "user"
+	}
+	m.audit(audit.Entry{
+		Action:  "restore",
+		Scope:   scope,
+		File:    s.Settings,
+		Changed: true,
+		Note:    "snapshot of " + s.Time.Format(time.RFC3339),
+	})
+
+	m.finishModal()
+	m.snapMarked = ""
+	m.loadSnapshots()
+	m.snapList.top()
+	m.notify(toastSuccess, "Restored %s from %s", s.Settings, s.Time.Format("Jan 2 15:04"))
+	return m, m.toastTickCmd()
+}
+
+// renderSnapshotsView lists the saved settings snapshots, newest first
+func (m Model) renderSnapshotsView() string {
+	_, contentHeight := m.calculateLayout()
+	width := m.width - 4
+
+	var lines []string
+
+	header := fmt.Sprintf("%d snapshots in %s", len(m.snapshots), snapshots.Dir())
+	switch {
+	case m.snapshotsErr != nil:
+		header = "Could not read snapshots: " + m.snapshotsErr.Error()
+	case len(m.snapshots) == 0:
+		header = fmt.Sprintf("No snapshots yet — settings are snapshotted when perms writes them, or press %s", primaryKey(m.keys.Snapshot))
+	}
+	lines = append(lines, padRight(truncateString(header, width), width))
+	lines = append(lines, strings.Repeat("─", width))
+
+	start, end := m.snapList.window(len(m.snapshots), m.snapViewportHeight())
+	for i := start; i < end; i++ {
+		s := m.snapshots[i]
+		mark := " "
+		if s.ID == m.snapMarked {
+			mark = "◆"
+		}
+		when := padRight(s.Time.Format("Jan 2 15:04:05"), 15)
+		reason := padRight(truncateString(s.Reason, 36), 36)
+		text := fmt.Sprintf("%s %s %s %s", mark, when, reason, settingsLabel(s.Settings))
+		if s.Missing {
+			text += " (absent)"
+		}
+		text = truncateString(text, width-2)
+
+		if i == m.snapList.cursor {
+			lines = append(lines, styles.ListItemSelected.Render("> "+text))
+		} else {
+			lines = append(lines, styles.ListItem.Render(text))
+		}
+	}
+
+	for len(lines) < contentHeight {
+		lines = append(lines, "")
+	}
+
+	return strings.Join(lines[:contentHeight], "\n") + "\n"
+}
+
+// settingsLabel names a settings file by its scope: "user" or the project
+func settingsLabel(path string) string {
+	if path == parser.UserSettingsPath() {
+		return "user"
+	}
+	// <project>/.claude/settings.local.json
+	return shortenPath(filepath.Dir(filepath.Dir(path)))
+}
+
+// snapDiff loads the selected snapshot and its base and diffs them. The
+// diff is nil if they are identical.
+func (m Model) snapDiff() (base snapshots.Snapshot, hasBase bool, diff []parser.DiffLine, err error) {
+	s := m.snapshots[m.snapList.cursor]
+	to, err := snapshots.Content(s)
+	if err != nil {
+		return base, false, nil, err
+	}
+
+	var from []byte
+	if base, hasBase = m.snapDiffBase(); hasBase {
+		if from, err = snapshots.Content(base); err != nil {
+			return base, hasBase, nil, err
+		}
+	}
+	if hasBase && base.Missing == s.Missing && bytes.Equal(from, to) {
+		return base, hasBase, nil, nil
+	}
+	return base, hasBase, parser.DiffContents(from, to), nil
+}
+
+// snapDiffHeight returns how many diff lines the modal shows at once
+func (m Model) snapDiffHeight() int {
+	return max(m.height-16, 5) // Room for the frame, header and hints
+}
+
+// renderSnapDiffModal shows what changed between the base snapshot and the
+// selected one
+func (m Model) renderSnapDiffModal() string {
+	if m.snapList.cursor >= len(m.snapshots) {
+		return ""
+	}
+	s := m.snapshots[m.snapList.cursor]
+
+	modalWidth := min(max(m.width*85/100, 50), 80)
+
+	var b strings.Builder
+	b.WriteString(styles.ModalTitle.Render("Snapshot Diff"))
+	b.WriteString("\n\n")
+
+	base, hasBase, diff, err := m.snapDiff()
+	if hasBase {
+		b.WriteString(fmt.Sprintf("  from %s  %s\n", base.Time.Format("Jan 2 15:04:05"), base.Reason))
+	} else {
+		b.WriteString("  from (no earlier snapshot)\n")
+	}
+	b.WriteString(fmt.Sprintf("  to   %s  %s\n", s.Time.Format("Jan 2 15:04:05"), s.Reason))
+	if hasBase && base.Settings != s.Settings {
+		b.WriteString(styles.StatusPending.Render("  comparing with another file: "+base.Settings) + "\n")
+	}
+	b.WriteString("\n")
+
+	switch {
+	case err != nil:
+		b.WriteString(renderDiffPreviewError(s.Settings, err))
+	case diff == nil:
+		b.WriteString(styles.DiffPath.Render(s.Settings) + "\n")
+		b.WriteString(styles.StatusPending.Render("  (identical)") + "\n")
+	default:
+		height := m.snapDiffHeight()
+		scroll := min(m.snapDiffScroll, max(len(diff)-height, 0))
+		end := min(scroll+height, len(diff))
+		b.WriteString(renderDiffPreview(s.Settings, diff[scroll:end], false, modalWidth-6))
+		if len(diff) > height {
+			b.WriteString(fmt.Sprintf("\n  (lines %d-%d of %d)\n", scroll+1, end, len(diff)))
+		}
+	}
+
+	b.WriteString("\n  " + renderHints(m.contextBindings()))
+	return styles.Modal.Width(modalWidth).Render(b.String())
+}
@@ -0,0 +1,202 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/insights"
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// allowRules lists the allow rules of the user settings and every known
+// project's settings
+func (m Model) allowRules() []insights.AllowRule {
+	var rules []insights.AllowRule
+	for _, r := range m.userApproved {
+		rules = append(rules, insights.AllowRule{Rule: r, File: parser.UserSettingsPath()})
+	}
+
+	projects := make([]string, 0, len(m.projectSettings))
+	for p := range m.projectSettings {
+		projects = append(projects, p)
+	}
+	sort.Strings(projects)
+	for _, p := range projects {
+		for _, r := range m.projectSettings[p] {
+			rules = append(rules, insights.AllowRule{Rule: r, File: parser.ProjectSettingsPath(p), Project: p})
+		}
+	}
+	return rules
+}
+
+// findStaleAllows recomputes the Stale view's rules
+func (m *Model) findStaleAllows() {
+	m.staleAllows = insights.StaleAllows(m.allowRules(), m.permissions, now(), m.staleWindow)
+	m.staleList.clamp(len(m.staleAllows), m.staleViewportHeight())
+}
+
+// staleViewportHeight returns how many rule rows fit under the header
+func (m Model) staleViewportHeight() int {
+	_, contentHeight := m.calculateLayout()
+	return max(contentHeight-2, 1) // header + separator
+}
+
+// staleWindowLabel describes the stale window, e.g. "90 days"
+func (m Model) staleWindowLabel() string {
+	days := int(m.staleWindow / (24 * time.Hour))
+	if days == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", days)
+}
+
+// handleRemoveConfirmKeys processes keys in the remove confirmation modal
+func (m Model) handleRemoveConfirmKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.ForceQuit):
+		return m, tea.Quit
+
+	case key.Matches(msg, m.keys.Select, m.keys.Remove):
+		return m.removeStaleAllow()
+
+	case key.Matches(msg, m.keys.Back, m.keys.Quit):
+		m.closeModal()
+	}
+	return m, nil
+}
+
+// removeStaleAllow removes the selected stale rule from its settings file
+func (m Model) removeStaleAllow() (tea.Model, tea.Cmd) {
+	if m.writeBlockedReason() != "" {
+		return m.writeBlocked()
+	}
+	if m.staleList.cursor >= len(m.staleAllows) {
+		return m, nil
+	}
+	s := m.staleAllows[m.staleList.cursor]
+
+	var result *parser.ApplyResult
+	var err error
+	if m.dryRun {
+		result, err = parser.PlanAllowRemoval(s.File, s.Rule)
+	} else {
+		result, err = parser.RemoveAllowFromSettingsFile(s.File, s.Rule)
+	}
+	if err != nil {
+		return m, m.failWrite(s.File, err, Model.removeStaleAllow)
+	}
+	m.recordRemove(s.Project, result)
+	m.finishModal()
+
+	where := result.FilePath
+	if result.LineNumber > 0 {
+		where += fmt.Sprintf(":%d", result.LineNumber)
+	}
+	if result.DryRun {
+		m.notify(toastInfo, "Dry run: would remove %s from %s", s.Rule, where)
+	} else {
+		m.notify(toastSuccess, "Removed %s from %s", s.Rule, where)
+		if s.Project == "" {
+			m.userApproved = withoutRule(m.userApproved, s.Rule)
+		} else {
+			m.projectSettings[s.Project] = withoutRule(m.projectSettings[s.Project], s.Rule)
+		}
+		m.findStaleAllows()
+	}
+	return m, m.toastTickCmd()
+}
+
+// withoutRule returns rules minus every copy of rule, without modifying
+// the original slice
+func withoutRule(rules []string, rule string) []string {
+	var kept []string
+	for _, r := range rules {
+		if r != rule {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// renderStaleView lists allow rules that no recent tool_use matched
+func (m Model) renderStaleView() string {
+	_, contentHeight := m.calculateLayout()
+	width := m.width - 4
+
+	var lines []string
+
+	header := fmt.Sprintf("%d allow rules unused in the last %s — %s removes one from its settings file",
+		len(m.staleAllows), m.staleWindowLabel(), primaryKey(m.keys.Remove))
+	if len(m.staleAllows) == 0 {
+		header = fmt.Sprintf("Every allow rule in your settings matched a tool call in the last %s", m.staleWindowLabel())
+	}
+	lines = append(lines, padRight(truncateString(header, width), width))
+	lines = append(lines, strings.Repeat("─", width))
+
+	start, end := m.staleList.window(len(m.staleAllows), m.staleViewportHeight())
+	for i := start; i < end; i++ {
+		text := truncateString(formatStaleAllow(m.staleAllows[i]), width-2)
+		if i == m.staleList.cursor {
+			lines = append(lines, styles.ListItemSelected.Render("> "+text))
+		} else {
+			lines = append(lines, styles.ListItem.Render(text))
+		}
+	}
+
+	for len(lines) < contentHeight {
+		lines = append(lines, "")
+	}
+
+	return strings.Join(lines[:contentHeight], "\n") + "\n"
+}
+
+// formatStaleAllow renders one stale rule as a Stale row
+func formatStaleAllow(s insights.StaleAllow) string {
+	last := "never used"
+	if !s.LastUsed.IsZero() {
+		last = "last used " + formatRelativeTime(s.LastUsed)
+	}
+	return fmt.Sprintf("%s %s %s",
+		padRight(truncateString(s.Rule, 44), 44),
+		padRight(last, 20),
+		settingsLabel(s.File))
+}
+
+// renderRemoveConfirmModal previews removing the selected stale rule
+func (m Model) renderRemoveConfirmModal() string {
+	if m.staleList.cursor >= len(m.staleAllows) {
+		return ""
+	}
+	s := m.staleAllows[m.staleList.cursor]
+
+	modalWidth := min(max(m.width*85/100, 50), 80)
+
+	var b strings.Builder
+	b.WriteString(styles.ModalTitle.Render("Remove Stale Rule"))
+	b.WriteString("\n\n")
+	b.WriteString(m.renderWriteModeNotice())
+	b.WriteString("  " + styles.HelpKey.Render(s.Rule) + "\n")
+	if s.LastUsed.IsZero() {
+		b.WriteString("  No recorded tool call ever matched this rule.\n\n")
+	} else {
+		b.WriteString(fmt.Sprintf("  Last matched %s.\n\n", formatTimestamp(s.LastUsed)))
+	}
+
+	diff, err := parser.PreviewAllowRemoval(s.File, s.Rule)
+	switch {
+	case err != nil:
+		b.WriteString(renderDiffPreviewError(s.File, err))
+	case diff == nil:
+		b.WriteString(styles.DiffPath.Render(s.File) + "\n")
+		b.WriteString(styles.StatusPending.Render("  (already removed, no changes)") + "\n")
+	default:
+		b.WriteString(renderDiffPreview(s.File, diff, false, modalWidth-6))
+	}
+
+	b.WriteString("\n  " + renderHints(m.contextBindings()))
+	return styles.Modal.Width(modalWidth).Render(b.String())
+}
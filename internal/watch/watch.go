@@ -0,0 +1,139 @@
+package watch
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+)
+
+// Alert is the first use since watching began of a permission no allow
+// rule covers, in one project
+type Alert struct {
+	Permission string // Normalized permission
+	Project    string
+	Sample     string // The command, path or URL used
+	Log        string // Session or agent log it was recorded in
+	Time       time.Time
+}
+
+// logState is what the watcher last saw of a log
+type logState struct {
+	hash   string // mtime:size
+	events int
+}
+
+// Watcher finds tool uses added to the logs under a projects directory
+// since it started, and reports the ones the settings don't allow. The
+// first Poll only records where each log ends.
+type Watcher struct {
+	dir      string
+	logs     map[string]logState
+	alerted  map[string]bool // Project + permission already reported
+	baseline bool
+	cache    *parser.CacheWriter // Logs parsed are recorded here, if set
+}
+
+// New returns a watcher for the logs under projectsDir
+func New(projectsDir string) *Watcher {
+	return &Watcher{
+		dir:     projectsDir,
+		logs:    make(map[string]logState),
+		alerted: make(map[string]bool),
+	}
+}
+
+// CacheTo records each log a poll parses in cw, keeping the unified cache
+// up to date for the next launch of the TUI. Flushing cw is left to the
+// caller.
+func (w *Watcher) CacheTo(cw *parser.CacheWriter) {
+	w.cache = cw
+}
+
+// Poll rereads the logs that changed since the last poll and returns an
+// alert for each use the settings would prompt for: no allow rule covers
+// it and no deny rule refuses it. Each permission is reported once per
+// project.
+func (w *Watcher) Poll() ([]Alert, error) {
+	files, err := parser.ListLogFiles(w.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var alerts []Alert
+	layers := make(map[string][]parser.LayerRules) // Project -> its settings, read once per poll
+	for _, f := range files {
+		hash, err := fileHash(f.Path)
+		if err != nil {
+			continue
+		}
+		prev, known := w.logs[f.Path]
+		if known && prev.hash == hash {
+			continue
+		}
+
+		var events []parser.ToolEvent
+		var warns []parser.Warning
+		if f.Agent {
+			events, warns, err = parser.ParseAgentEvents(f.Path)
+		} else {
+			events, warns, err = parser.ParseSessionEvents(f.Path, f.Time)
+		}
+		if err != nil {
+			continue
+		}
+		if w.cache != nil {
+			w.cache.Record(f, events, warns)
+		}
+		w.logs[f.Path] = logState{hash: hash, events: len(events)}
+		if !w.baseline {
+			continue
+		}
+		if prev.events > len(events) {
+			prev.events = 0 // Rewritten rather than appended to
+		}
+
+		for _, e := range events[prev.events:] {
+			perm := parser.NormalizePermission(parser.ParsePermission(e.Permission))
+			key := f.Project + "\x00" + perm.Raw
+			if w.alerted[key] {
+				continue
+			}
+			if _, ok := layers[f.Project]; !ok {
+				layers[f.Project], _ = parser.LoadSettingsLayers(f.Project)
+			}
+			if parser.Resolve(invocation(perm.Type, e), layers[f.Project], f.Project).Decision != parser.DecisionAsk {
+				continue
+			}
+			w.alerted[key] = true
+			alerts = append(alerts, Alert{
+				Permission: perm.Raw,
+				Project:    f.Project,
+				Sample:     e.Sample,
+				Log:        f.Path,
+				Time:       e.Time,
+			})
+		}
+	}
+	w.baseline = true
+	return alerts, nil
+}
+
+// invocation is the tool call an event made, as "Tool(input)", for
+// resolving against the settings
+func invocation(tool string, e parser.ToolEvent) string {
+	if e.Sample == "" {
+		return e.Permission
+	}
+	return tool + "(" + e.Sample + ")"
+}
+
+// fileHash identifies a log's contents by mtime and size
+func fileHash(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size()), nil
+}
@@ -0,0 +1,63 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPollAlertsOncePerUnapprovedPermission(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".claude"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	settings := `{"permissions": {"allow": ["Bash(git status)"]}}`
+	if err := os.WriteFile(filepath.Join(home, ".claude", "settings.json"), []byte(settings), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	projectsDir := filepath.Join(t.TempDir(), "projects")
+	dir := filepath.Join(projectsDir, "-test-project")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"sessions-index.json", "session-001.jsonl"} {
+		data, err := os.ReadFile(filepath.Join("../../testdata/projects/-test-project", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := New(projectsDir)
+	if alerts, err := w.Poll(); err != nil || len(alerts) != 0 {
+		t.Fatalf("first Poll = %v, %v; want no alerts for existing uses", alerts, err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, "session-001.jsonl"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`{"type": "assistant", "timestamp": "2026-02-01T09:00:00Z", "cwd": "/test/project", "message": {"role": "assistant", "content": [{"type": "tool_use", "id": "toolu_90", "name": "Bash", "input": {"command": "git status"}}]}}`,
+		`{"type": "assistant", "timestamp": "2026-02-01T09:00:01Z", "cwd": "/test/project", "message": {"role": "assistant", "content": [{"type": "tool_use", "id": "toolu_91", "name": "Bash", "input": {"command": "make test"}}]}}`,
+		`{"type": "assistant", "timestamp": "2026-02-01T09:00:02Z", "cwd": "/test/project", "message": {"role": "assistant", "content": [{"type": "tool_use", "id": "toolu_92", "name": "Bash", "input": {"command": "make lint"}}]}}`,
+	} {
+		f.WriteString(line + "\n")
+	}
+	f.Close()
+
+	alerts, err := w.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 1 || alerts[0].Permission != "Bash(make:*)" || alerts[0].Sample != "make test" || alerts[0].Project != "/test/project" {
+		t.Fatalf("Poll after append = %+v, want one alert for make test", alerts)
+	}
+	if alerts, _ := w.Poll(); len(alerts) != 0 {
+		t.Errorf("unchanged Poll = %+v, want none", alerts)
+	}
+}
@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// writeFailure is a settings write that failed, kept while its toast shows
+// so it can be retried or its file fixed in an editor
+type writeFailure struct {
+	path    string
+	retry   func(Model) (tea.Model, tea.Cmd) // Runs the failed action again
+	editing bool                             // Its file is open in an editor, which holds its toast up
+}
+
+// editorFinishedMsg is sent when the editor opened on a failed write's file
+// exits
+type editorFinishedMsg struct {
+	path string
+	err  error
+}
+
+// failWrite reports a failed write of the settings file at path as an
+// error toast, leaving the TUI and any open modal as they were. retry runs
+// the action again, e.g. once the file has been fixed.
+func (m *Model) failWrite(path string, err error, retry func(Model) (tea.Model, tea.Cmd)) tea.Cmd {
+	m.logger.Debug("settings write failed", "path", path, "err", err)
+	m.writeFailure = &writeFailure{path: path, retry: retry}
+	msg := fmt.Sprintf("Could not write %s: %v", shortenPath(path), err)
+	var locked *parser.LockedError
+	if errors.As(err, &locked) {
+		msg = fmt.Sprintf("%s is busy: %s is writing it, try again once it's done", shortenPath(path), locked.Holder())
+	}
+	m.setWriteFailureToast(msg)
+	return m.toastTickCmd()
+}
+
+// setWriteFailureToast shows msg after the keys that act on the failed
+// write, so a long message is cut rather than the keys. The keys work while
+// it shows.
+func (m *Model) setWriteFailureToast(msg string) {
+	m.toasts = slices.DeleteFunc(m.toasts, func(t toast) bool { return t.failure })
+	m.notify(toastError, "✗ [%s retry, %s edit] %s", primaryKey(m.keys.Retry), primaryKey(m.keys.EditFile), msg)
+
+	// It replaces the toast showing, which a key press would dismiss anyway
+	t := m.toasts[len(m.toasts)-1]
+	t.failure = true
+	m.toasts = append([]toast{t}, m.toasts[:len(m.toasts)-1]...)
+}
+
+// handleWriteFailureKeys handles the retry and edit keys while a failed
+// write's toast shows. It reports false for any other key, which dismisses
+// the toast and forgets the failure.
+func (m Model) handleWriteFailureKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	f := m.writeFailure
+	switch {
+	case key.Matches(msg, m.keys.Retry):
+		m.dismissToast()
+		model, cmd := f.retry(m)
+		return model, cmd, true
+
+	case key.Matches(msg, m.keys.EditFile):
+		cmd, err := editorCmd(f.path)
+		if err != nil {
+			m.setWriteFailureToast(err.Error())
+			return m, m.toastTickCmd(), true
+		}
+		m.writeFailure = &writeFailure{path: f.path, retry: f.retry, editing: true}
+		return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
+			return editorFinishedMsg{path: f.path, err: err}
+		}), true
+	}
+	return m, nil, false
+}
+
+// editorFinished reports whether the file edited after a failed write is a
+// valid settings file now, keeping the failure so it can be retried
+func (m Model) editorFinished(msg editorFinishedMsg) (tea.Model, tea.Cmd) {
+	if m.writeFailure == nil || m.writeFailure.path != msg.path {
+		return m, nil
+	}
+	m.writeFailure = &writeFailure{path: msg.path, retry: m.writeFailure.retry}
+	switch _, _, err := parser.ValidateSettingsFile(msg.path); {
+	case msg.err != nil:
+		m.setWriteFailureToast("Editor failed: " + msg.err.Error())
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		m.setWriteFailureToast(fmt.Sprintf("%s is still invalid: %v", shortenPath(msg.path), err))
+	default:
+		m.setWriteFailureToast(shortenPath(msg.path) + " is valid")
+	}
+	return m, m.toastTickCmd()
+}
+
+// editorCmd returns the command opening path in $VISUAL or $EDITOR, falling
+// back to vi
+func editorCmd(path string) (*exec.Cmd, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		args = []string{"vi"}
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return nil, fmt.Errorf("no editor to open %s: set $EDITOR", shortenPath(path))
+	}
+	return exec.Command(args[0], append(args[1:], path)...), nil
+}
@@ -0,0 +1,189 @@
+// Package claudeperms scans Claude Code session logs for the permissions
+// tools ask for, and reads and edits the allow and deny rules in Claude Code
+// settings files. It is the library behind the perms TUI, for other Go tools
+// — dashboards, bots, hooks — that need the same data.
+//
+// # Stability
+//
+// This package follows semantic versioning from v1.0.0: within a major
+// version, exported identifiers are not removed or changed incompatibly, and
+// minor versions only add to the API. New fields may be added to the
+// exported structs, so construct them with field names. Everything under
+// internal/ is an implementation detail with no such guarantee, including
+// the packages the types below alias.
+//
+// # Concurrency
+//
+// The Load functions share package-level state (warnings and normalization
+// settings) and must not run concurrently. Settings writes take a lock file
+// beside the settings file, so they are safe across goroutines and
+// processes.
+package claudeperms
+
+import (
+	"encoding/json"
+
+	"github.com/b-open-io/claude-perms/internal/parser"
+	"github.com/b-open-io/claude-perms/internal/types"
+)
+
+// Permission is a parsed permission string such as "Bash(git status:*)"
+type Permission = types.Permission
+
+// PermissionStats is how often a permission was requested, approved and
+// denied across session logs
+type PermissionStats = types.PermissionStats
+
+// AgentUsageStats is the permissions a subagent type actually used
+type AgentUsageStats = types.AgentUsageStats
+
+// AgentPermissions is the tools and permission rules an agent definition
+// declares
+type AgentPermissions = types.AgentPermissions
+
+// SkillPermissions is the tools and permission rules a skill definition
+// declares
+type SkillPermissions = types.SkillPermissions
+
+// ApprovalLevel is where a permission is allowed: not at all, in project
+// settings, or in user settings
+type ApprovalLevel = types.ApprovalLevel
+
+// Approval levels, lowest first
+const (
+	NotApproved     = types.NotApproved
+	ApprovedProject = types.ApprovedProject
+	ApprovedUser    = types.ApprovedUser
+)
+
+// Warning is a file or line that was skipped while loading
+type Warning = parser.Warning
+
+// WriteResult describes a rule written to, or planned for, a settings file
+type WriteResult = parser.ApplyResult
+
+// ProjectsDir returns the directory Claude Code keeps session logs in
+// (~/.claude/projects)
+func ProjectsDir() string {
+	return parser.ProjectsDir()
+}
+
+// SetNormalization turns scope normalization on or off. When on (the
+// default), permissions that differ only in how a path was written, such as
+// "Bash(./deploy.sh:*)" and "Bash(bash deploy.sh:*)", are counted as one.
+// aliases maps further permission strings to the one they count as.
+func SetNormalization(enabled bool, aliases map[string]string) {
+	parser.SetNormalization(enabled, aliases)
+}
+
+// SetProjectAliases merges project paths that are really one project in
+// every count and project list. aliases maps a path, or a filepath.Match
+// pattern, to the path it counts as; with byRemote, git worktrees and
+// checkouts sharing an origin remote count as one.
+func SetProjectAliases(aliases map[string]string, byRemote bool) {
+	parser.SetProjectAliases(aliases, byRemote)
+}
+
+// SetHosts labels session logs synced from several machines with the
+// machine they came from. roots maps a path prefix, such as "/Users/me", to
+// a label; the stats loaders then count uses per host in HostCounts.
+func SetHosts(roots map[string]string) {
+	parser.SetHosts(roots)
+}
+
+// LoadStats scans the session logs under projectsDir and returns per-
+// permission stats, most used first, with the files and lines that had to be
+// skipped. Pass ProjectsDir() for Claude Code's own logs.
+func LoadStats(projectsDir string) ([]PermissionStats, []Warning, error) {
+	parser.TakeWarnings() // Drop anything left over from an earlier load
+	stats, err := parser.LoadAllPermissionStatsFrom(projectsDir)
+	warnings, _ := parser.TakeWarnings()
+	return stats, warnings, err
+}
+
+// LoadAgentUsage scans the subagent logs under projectsDir and returns the
+// permissions each agent type used, most active first
+func LoadAgentUsage(projectsDir string) ([]AgentUsageStats, []Warning, error) {
+	parser.TakeWarnings()
+	stats, err := parser.LoadAgentUsageStatsFrom(projectsDir, nil)
+	warnings, _ := parser.TakeWarnings()
+	return stats, warnings, err
+}
+
+// LoadAgents returns the tools and permission rules declared by the agent
+// definitions in ~/.claude/agents and installed plugins
+func LoadAgents() ([]AgentPermissions, error) {
+	return parser.LoadAllAgents()
+}
+
+// LoadSkills returns the tools and permission rules declared by the skills
+// of installed plugins
+func LoadSkills() ([]SkillPermissions, error) {
+	return parser.LoadAllSkills()
+}
+
+// ParsePermission splits a permission string into its tool and scope
+func ParsePermission(raw string) Permission {
+	return parser.ParsePermission(raw)
+}
+
+// PermissionFor returns the permission string Claude Code would ask for
+// for a tool call, e.g. "Bash(git status:*)" for the Bash tool with
+// {"command": "git status --short"}
+func PermissionFor(tool string, input json.RawMessage) string {
+	return parser.ExtractPermissionScope(tool, input)
+}
+
+// Matches reports whether any of rules allows permission. A rule with no
+// scope or a "*" scope, such as "Bash" or "Bash(*)", allows every use of
+// its tool.
+func Matches(permission string, rules []string) bool {
+	return len(parser.MatchingRules(permission, rules)) > 0
+}
+
+// MatchingRules returns the rules that allow permission
+func MatchingRules(permission string, rules []string) []string {
+	return parser.MatchingRules(permission, rules)
+}
+
+// ApprovalLevelOf returns where permission is allowed, given the user and
+// project allow rules
+func ApprovalLevelOf(permission string, userRules, projectRules []string) ApprovalLevel {
+	return parser.GetApprovalLevel(permission, userRules, projectRules)
+}
+
+// UserSettingsPath returns the user settings file perms writes
+// (~/.claude/settings.local.json)
+func UserSettingsPath() string {
+	return parser.UserSettingsPath()
+}
+
+// ProjectSettingsPath returns a project's local settings file
+// (<project>/.claude/settings.local.json)
+func ProjectSettingsPath(project string) string {
+	return parser.ProjectSettingsPath(project)
+}
+
+// ReadRules returns the allow and deny rules in a settings file. A missing
+// file has no rules.
+func ReadRules(path string) (allow, deny []string, err error) {
+	return parser.ReadSettingsRules(path)
+}
+
+// AddAllowRule adds permission to a settings file's allow list, keeping
+// every other setting. Adding a rule that is already there changes nothing
+// and reports WasNew false.
+func AddAllowRule(path, permission string) (*WriteResult, error) {
+	return parser.WritePermissionToSettingsFile(path, permission, false)
+}
+
+// AddDenyRule adds permission to a settings file's deny list
+func AddDenyRule(path, permission string) (*WriteResult, error) {
+	return parser.WritePermissionToSettingsFile(path, permission, true)
+}
+
+// PlanRule reports what AddAllowRule (or AddDenyRule, if deny) would do
+// without writing anything. The result has DryRun set.
+func PlanRule(path, permission string, deny bool) (*WriteResult, error) {
+	return parser.PlanPermissionWrite(path, permission, deny)
+}
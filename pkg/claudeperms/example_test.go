@@ -0,0 +1,67 @@
+package claudeperms_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/b-open-io/claude-perms/pkg/claudeperms"
+)
+
+func ExampleLoadStats() {
+	stats, warnings, err := claudeperms.LoadStats(claudeperms.ProjectsDir())
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, w := range warnings {
+		log.Printf("skipped %s", w)
+	}
+	for _, s := range stats {
+		fmt.Printf("%-40s %5d calls, %d denied\n", s.Permission.Raw, s.Count, s.Denied)
+	}
+}
+
+func ExampleMatches() {
+	rules := []string{"Bash(git:*)", "Read"}
+	fmt.Println(claudeperms.Matches("Bash(git:*)", rules))
+	fmt.Println(claudeperms.Matches("Read", rules))
+	fmt.Println(claudeperms.Matches("Bash(curl:*)", rules))
+	// Output:
+	// true
+	// true
+	// false
+}
+
+func ExamplePermissionFor() {
+	input := json.RawMessage(`{"command": "go test ./..."}`)
+	fmt.Println(claudeperms.PermissionFor("Bash", input))
+	// Output: Bash(go test:*)
+}
+
+func ExampleParsePermission() {
+	p := claudeperms.ParsePermission("WebFetch(domain:github.com)")
+	fmt.Println(p.Type)
+	fmt.Println(p.Scope)
+	// Output:
+	// WebFetch
+	// domain:github.com
+}
+
+func ExampleAddAllowRule() {
+	path := claudeperms.ProjectSettingsPath("/path/to/project")
+
+	// Preview first, then write
+	plan, err := claudeperms.PlanRule(path, "Bash(make:*)", false)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !plan.WasNew {
+		fmt.Println("already allowed")
+		return
+	}
+	result, err := claudeperms.AddAllowRule(path, "Bash(make:*)")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("added at %s:%d\n", result.FilePath, result.LineNumber)
+}